@@ -11,6 +11,7 @@ import (
 
 	"github.com/whiteblock/genesis/pkg/config"
 	"github.com/whiteblock/genesis/pkg/controller"
+	"github.com/whiteblock/genesis/pkg/entity"
 	"github.com/whiteblock/genesis/pkg/file"
 	"github.com/whiteblock/genesis/pkg/handler"
 	handAux "github.com/whiteblock/genesis/pkg/handler/auxillary"
@@ -22,34 +23,82 @@ import (
 	queue "github.com/whiteblock/amqp"
 )
 
-func getRestServer() (controller.RestController, error) {
+func getUseCase(conf config.Config, creds repository.CredentialStore, inventory service.HostInventory,
+	publisher service.StatsPublisher, eventPublisher service.ContainerEventPublisher) (
+	usecase.DockerUseCase, service.DockerService, service.StatsSampler, service.EventWatcher) {
+	remote := file.NewRemoteSources(conf, conf.GetLogger())
+	dockerService := service.NewDockerService(
+		repository.NewDockerRepository(conf.GetLogger()),
+		conf.Docker,
+		remote,
+		creds,
+		conf.GetLogger())
+	sampler := service.NewStatsSampler(
+		conf.Docker.StatsInterval,
+		dockerService.CreateClient2,
+		publisher,
+		conf.GetLogger())
+	watcher := service.NewEventWatcher(
+		conf.Docker.EventRestartLimit,
+		dockerService.CreateClient2,
+		eventPublisher,
+		conf.GetLogger())
+	return usecase.NewBackendUseCase(
+		conf.Backend,
+		map[string]usecase.DockerUseCase{
+			entity.DockerBackend: usecase.NewSchedulingUseCase(
+				usecase.NewWatchingUseCase(
+					usecase.NewSamplingUseCase(
+						usecase.NewDockerUseCase(dockerService, conf.GetLogger()),
+						sampler,
+						conf.GetLogger()),
+					watcher,
+					conf.Docker.EventPolicy,
+					conf.GetLogger()),
+				dockerService,
+				inventory,
+				conf.Inventory.RefreshInterval,
+				conf.GetLogger()),
+			entity.KubernetesBackend: usecase.NewKubernetesUseCase(
+				service.NewKubernetesService(
+					repository.NewKubernetesRepository(conf.Kubernetes, conf.GetLogger()),
+					conf.Kubernetes,
+					remote,
+					conf.GetLogger()),
+				conf.Kubernetes.Namespace,
+				conf.GetLogger()),
+		},
+		conf.GetLogger()), dockerService, sampler, watcher
+}
+
+func getRestServer(inventory service.HostInventory) (controller.RestController, error) {
 	conf, err := config.NewConfig()
 	if err != nil {
 		return nil, err
 	}
 	config.SanityCheck(conf)
+	creds := repository.NewCredentialStore(conf.Docker.CredentialDir)
+	uc, dockerService, sampler, watcher := getUseCase(conf, creds, inventory, nil, nil)
 
 	return controller.NewRestController(
 		conf.GetRestConfig(),
 		handler.NewRestHandler(
 			handAux.NewExecutor(
 				conf.Execution,
-				usecase.NewDockerUseCase(
-					service.NewDockerService(
-						repository.NewDockerRepository(conf.GetLogger()),
-						conf.Docker,
-						file.NewRemoteSources(
-							conf,
-							conf.GetLogger()),
-						conf.GetLogger()),
-					conf.GetLogger()),
+				uc,
+				dockerService,
+				inventory,
+				creds,
+				sampler,
+				watcher,
 				conf.GetLogger()),
+			inventory,
 			conf.GetLogger()),
 		mux.NewRouter(),
 		conf.GetLogger()), nil
 }
 
-func getCommandController() (controller.CommandController, error) {
+func getCommandController(inventory service.HostInventory) (controller.CommandController, error) {
 	conf, err := config.NewConfig()
 	if err != nil {
 		return nil, err
@@ -80,6 +129,7 @@ func getCommandController() (controller.CommandController, error) {
 	}
 
 	queue.AssertUniqueQueues(conf.GetLogger(), complConf, cmdConf, errConf, statusConf)
+	creds := repository.NewCredentialStore(conf.Docker.CredentialDir)
 
 	cmdConn, err := queue.OpenAMQPConnection(cmdConf.Endpoint)
 	if err != nil {
@@ -101,24 +151,25 @@ func getCommandController() (controller.CommandController, error) {
 		return nil, err
 	}
 
+	status := queue.NewAMQPService(statusConf, queue.NewAMQPRepository(statusConn), conf.GetLogger())
+	uc, dockerService, sampler, watcher := getUseCase(conf, creds, inventory, controller.NewStatsPublisher(status),
+		controller.NewContainerEventPublisher(status))
+
 	return controller.NewCommandController(
 		conf,
 		queue.NewAMQPService(cmdConf, queue.NewAMQPRepository(cmdConn), conf.GetLogger()),
 		queue.NewAMQPService(errConf, queue.NewAMQPRepository(errConn), conf.GetLogger()),
 		queue.NewAMQPService(complConf, queue.NewAMQPRepository(complConn), conf.GetLogger()),
-		queue.NewAMQPService(statusConf, queue.NewAMQPRepository(statusConn), conf.GetLogger()),
+		status,
 		handler.NewDeliveryHandler(
 			handAux.NewExecutor(
 				conf.Execution,
-				usecase.NewDockerUseCase(
-					service.NewDockerService(
-						repository.NewDockerRepository(conf.GetLogger()),
-						conf.Docker,
-						file.NewRemoteSources(
-							conf,
-							conf.GetLogger()),
-						conf.GetLogger()),
-					conf.GetLogger()),
+				uc,
+				dockerService,
+				inventory,
+				creds,
+				sampler,
+				watcher,
 				conf.GetLogger()),
 			conf,
 			conf.MaxMessageRetries,
@@ -138,18 +189,19 @@ func main() {
 		os.Exit(0)
 	}
 
-	restServer, err := getRestServer()
+	conf, err := config.NewConfig()
 	if err != nil {
 		panic(err)
 	}
+	inventory := service.NewHostInventory(conf.Inventory, conf.GetLogger())
 
-	conf, err := config.NewConfig()
+	restServer, err := getRestServer(inventory)
 	if err != nil {
 		panic(err)
 	}
 
 	if !conf.LocalMode {
-		cmdCntl, err := getCommandController()
+		cmdCntl, err := getCommandController(inventory)
 		if err != nil {
 			panic(err)
 		}
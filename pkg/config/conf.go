@@ -36,10 +36,14 @@ type Config struct {
 	Verbosity        string            `mapstructure:"verbosity"`
 	FluentDLogging   bool              `mapstructure:"fluentDLogging"`
 	Listen           string            `mapstructure:"listen"`
+	// Backend is where the commands are executed when they do not select a backend themselves
+	Backend string `mapstructure:"backend"`
 
 	Execution   Execution   `mapstructure:"-"`
 	Docker      Docker      `mapstructure:"-"`
+	Kubernetes  Kubernetes  `mapstructure:"-"`
 	FileHandler FileHandler `mapstructure:"-"`
+	Inventory   Inventory   `mapstructure:"-"`
 }
 
 // GetLogger gets a logger according to the config
@@ -112,9 +116,12 @@ func setViperEnvBindings() {
 	viper.BindEnv("commandQueueName", "COMMAND_QUEUE_NAME")
 	viper.BindEnv("errorQueueName", "ERROR_QUEUE_NAME")
 	viper.BindEnv("enableErrorCollection", "ENABLE_ERROR_COLLECTION")
+	viper.BindEnv("backend", "BACKEND")
 	setExecutionBindings(viper.GetViper())
 	setDockerBindings(viper.GetViper())
+	setKubernetesBindings(viper.GetViper())
 	setFileHandlerBindings(viper.GetViper())
+	setInventoryBindings(viper.GetViper())
 }
 
 func setViperDefaults() {
@@ -128,10 +135,13 @@ func setViperDefaults() {
 	viper.SetDefault("listen", "0.0.0.0:8000")
 	viper.SetDefault("localMode", true)
 	viper.SetDefault("errorQueueName", "errors")
+	viper.SetDefault("backend", entity.DockerBackend)
 
 	setExecutionDefaults(viper.GetViper())
 	setDockerDefaults(viper.GetViper())
+	setKubernetesDefaults(viper.GetViper())
 	setFileHandlerDefaults(viper.GetViper())
+	setInventoryDefaults(viper.GetViper())
 }
 
 func init() {
@@ -163,6 +173,16 @@ func NewConfig() (conf Config, err error) {
 		return
 	}
 
+	conf.Kubernetes, err = NewKubernetes(viper.GetViper())
+	if err != nil {
+		return
+	}
+
+	conf.Inventory, err = NewInventory(viper.GetViper())
+	if err != nil {
+		return
+	}
+
 	conf.Docker, err = NewDocker(viper.GetViper())
 	return
 }
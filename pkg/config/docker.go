@@ -7,9 +7,26 @@
 package config
 
 import (
+	"time"
+
 	"github.com/spf13/viper"
 )
 
+// Endpoint is a docker daemon which commands can target by its name
+type Endpoint struct {
+	// Host is the address of the daemon, as tcp://host:port, ssh://user@host or unix:///path
+	Host string `mapstructure:"host"`
+
+	// CACert, Cert and Key are the files of the TLS material of a tcp:// daemon, which is
+	// reached without TLS if they are not given
+	CACert string `mapstructure:"caCert"`
+	Cert   string `mapstructure:"cert"`
+	Key    string `mapstructure:"key"`
+
+	// ServerName is the name the cert of the daemon is for, if it is not for the host
+	ServerName string `mapstructure:"serverName"`
+}
+
 // Docker represents the configuration needed to communicate with docker daemons
 type Docker struct {
 	// LocalMode causes the TLS parameters to be ignored and Genesis
@@ -28,6 +45,104 @@ type Docker struct {
 	GlusterImage string `mapstructure:"dockerGlusterImage"`
 
 	GlusterDriver string `mapstructure:"dockerGlusterDriver"`
+
+	// SharedVolumeBackend is how global volumes are shared between hosts, when the volume does
+	// not choose: gluster, nfs or rsync
+	SharedVolumeBackend string `mapstructure:"dockerSharedVolumeBackend"`
+
+	// NFSImage is the image of the nfs server which serves the volumes of the nfs backend,
+	// it exports the directory given by SHARED_DIRECTORY
+	NFSImage string `mapstructure:"dockerNFSImage"`
+
+	// RsyncImage is the image of the side car which copies the volumes of the rsync backend,
+	// it needs to provide rsync
+	RsyncImage string `mapstructure:"dockerRsyncImage"`
+
+	// RsyncPort is the port the rsync daemons of the rsync backend listen on
+	RsyncPort int `mapstructure:"dockerRsyncPort"`
+
+	// VolumeRoot is the directory of the hosts which holds the data of the docker volumes
+	VolumeRoot string `mapstructure:"dockerVolumeRoot"`
+
+	// VolumeHelperImage is the image of the short lived container which seeds and exports
+	// volumes, it needs to provide tar with gzip
+	VolumeHelperImage string `mapstructure:"dockerVolumeHelperImage"`
+
+	// NetemImage is the image of the side car which applies the network emulation,
+	// it needs to provide a version of tc with JSON output
+	NetemImage string `mapstructure:"dockerNetemImage"`
+
+	// IptablesImage is the image of the side car which partitions the network
+	IptablesImage string `mapstructure:"dockerIptablesImage"`
+
+	// StressImage is the image of the side car which puts load on a container,
+	// it needs to provide stress-ng
+	StressImage string `mapstructure:"dockerStressImage"`
+
+	// FaketimeLib is the path of libfaketime inside of the containers with clock skew
+	FaketimeLib string `mapstructure:"dockerFaketimeLib"`
+
+	// IPAMPools are the IPv4 ranges which the subnets of the networks are allocated from,
+	// when a network is created without one
+	IPAMPools []string `mapstructure:"dockerIPAMPools"`
+
+	// IPAMSubnetSize is the prefix length of the subnets which are allocated from the pools
+	IPAMSubnetSize int `mapstructure:"dockerIPAMSubnetSize"`
+
+	// Runtime is the container runtime of the hosts, it can be overridden per command
+	Runtime string `mapstructure:"dockerRuntime"`
+
+	// Rootless is whether podman runs without root on the hosts
+	Rootless bool `mapstructure:"dockerRootless"`
+
+	// NerdctlPath is the nerdctl binary which drives containerd
+	NerdctlPath string `mapstructure:"dockerNerdctlPath"`
+
+	// ContainerdAddress is the socket of containerd
+	ContainerdAddress string `mapstructure:"dockerContainerdAddress"`
+
+	// ContainerdNamespace is the containerd namespace which the containers are created in
+	ContainerdNamespace string `mapstructure:"dockerContainerdNamespace"`
+
+	// IPAMStateFile is where the address allocations are kept, they are only kept in memory
+	// if it is empty
+	IPAMStateFile string `mapstructure:"dockerIPAMStateFile"`
+
+	// ClientIdleTimeout is how long the client of a host is kept open after it was last used,
+	// so that the following commands can reuse it. Clients are not reused if it is 0.
+	ClientIdleTimeout time.Duration `mapstructure:"dockerClientIdleTimeout"`
+
+	// ClientHealthInterval is how long a client can go without being pinged, before it is
+	// checked again on reuse
+	ClientHealthInterval time.Duration `mapstructure:"dockerClientHealthInterval"`
+
+	// StatsInterval is how often the resource usage of the containers of a test is sampled,
+	// sampling is off when it is zero
+	StatsInterval time.Duration `mapstructure:"dockerStatsInterval"`
+
+	// EventPolicy is what happens when a container of a test crashes, unless the test sets its
+	// own: notify only reports it, fail fails the test, restart starts the container again
+	EventPolicy string `mapstructure:"dockerEventPolicy"`
+
+	// EventRestartLimit is how many times a container is restarted by the restart policy, before
+	// the test is failed instead
+	EventRestartLimit int `mapstructure:"dockerEventRestartLimit"`
+
+	// TLSServerName is the name the certs of the daemons are made for. The certs are checked
+	// against the address of the host if it is empty, and against either if it is not.
+	TLSServerName string `mapstructure:"dockerTLSServerName"`
+
+	// CredentialDir is where the TLS credentials of the running tests are kept, so that they
+	// survive restarts. They are only kept in memory if it is empty.
+	CredentialDir string `mapstructure:"dockerCredentialDir"`
+
+	// Endpoints are the daemons which can be targeted by name instead of by address. They can
+	// only be given in the config file.
+	Endpoints map[string]Endpoint `mapstructure:"dockerEndpoints"`
+
+	// TransportAllowlist are the ssh:// and unix:// addresses which commands may target directly,
+	// rather than by the name of an endpoint. They can only be given in the config file.
+	TransportAllowlist []string `mapstructure:"dockerTransportAllowlist"`
 }
 
 // NewDocker creates a new docker configuration from viper
@@ -66,6 +181,129 @@ func setDockerBindings(v *viper.Viper) error {
 		return err
 	}
 
+	err = v.BindEnv("dockerSharedVolumeBackend", "DOCKER_SHARED_VOLUME_BACKEND")
+	if err != nil {
+		return err
+	}
+
+	err = v.BindEnv("dockerNFSImage", "DOCKER_NFS_IMAGE")
+	if err != nil {
+		return err
+	}
+
+	err = v.BindEnv("dockerRsyncImage", "DOCKER_RSYNC_IMAGE")
+	if err != nil {
+		return err
+	}
+
+	err = v.BindEnv("dockerRsyncPort", "DOCKER_RSYNC_PORT")
+	if err != nil {
+		return err
+	}
+
+	err = v.BindEnv("dockerVolumeRoot", "DOCKER_VOLUME_ROOT")
+	if err != nil {
+		return err
+	}
+	err = v.BindEnv("dockerVolumeHelperImage", "DOCKER_VOLUME_HELPER_IMAGE")
+	if err != nil {
+		return err
+	}
+	err = v.BindEnv("dockerNetemImage", "DOCKER_NETEM_IMAGE")
+	if err != nil {
+		return err
+	}
+
+	err = v.BindEnv("dockerIptablesImage", "DOCKER_IPTABLES_IMAGE")
+	if err != nil {
+		return err
+	}
+
+	err = v.BindEnv("dockerStressImage", "DOCKER_STRESS_IMAGE")
+	if err != nil {
+		return err
+	}
+
+	err = v.BindEnv("dockerFaketimeLib", "DOCKER_FAKETIME_LIB")
+	if err != nil {
+		return err
+	}
+
+	err = v.BindEnv("dockerIPAMPools", "DOCKER_IPAM_POOLS")
+	if err != nil {
+		return err
+	}
+
+	err = v.BindEnv("dockerIPAMSubnetSize", "DOCKER_IPAM_SUBNET_SIZE")
+	if err != nil {
+		return err
+	}
+
+	err = v.BindEnv("dockerIPAMStateFile", "DOCKER_IPAM_STATE_FILE")
+	if err != nil {
+		return err
+	}
+
+	err = v.BindEnv("dockerRuntime", "DOCKER_RUNTIME")
+	if err != nil {
+		return err
+	}
+
+	err = v.BindEnv("dockerRootless", "DOCKER_ROOTLESS")
+	if err != nil {
+		return err
+	}
+
+	err = v.BindEnv("dockerNerdctlPath", "DOCKER_NERDCTL_PATH")
+	if err != nil {
+		return err
+	}
+
+	err = v.BindEnv("dockerContainerdAddress", "DOCKER_CONTAINERD_ADDRESS")
+	if err != nil {
+		return err
+	}
+
+	err = v.BindEnv("dockerContainerdNamespace", "DOCKER_CONTAINERD_NAMESPACE")
+	if err != nil {
+		return err
+	}
+
+	err = v.BindEnv("dockerClientIdleTimeout", "DOCKER_CLIENT_IDLE_TIMEOUT")
+	if err != nil {
+		return err
+	}
+
+	err = v.BindEnv("dockerClientHealthInterval", "DOCKER_CLIENT_HEALTH_INTERVAL")
+	if err != nil {
+		return err
+	}
+
+	err = v.BindEnv("dockerStatsInterval", "DOCKER_STATS_INTERVAL")
+	if err != nil {
+		return err
+	}
+
+	err = v.BindEnv("dockerEventPolicy", "DOCKER_EVENT_POLICY")
+	if err != nil {
+		return err
+	}
+
+	err = v.BindEnv("dockerEventRestartLimit", "DOCKER_EVENT_RESTART_LIMIT")
+	if err != nil {
+		return err
+	}
+
+	err = v.BindEnv("dockerTLSServerName", "DOCKER_TLS_SERVER_NAME")
+	if err != nil {
+		return err
+	}
+
+	err = v.BindEnv("dockerCredentialDir", "DOCKER_CREDENTIAL_DIR")
+	if err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -76,4 +314,29 @@ func setDockerDefaults(v *viper.Viper) {
 	v.SetDefault("dockerDaemonPort", "2376")
 	v.SetDefault("dockerGlusterImage", "gcr.io/whiteblock/gluster:latest")
 	v.SetDefault("dockerGlusterDriver", "glusterfs")
+	v.SetDefault("dockerSharedVolumeBackend", "gluster")
+	v.SetDefault("dockerNFSImage", "itsthenetwork/nfs-server-alpine:latest")
+	v.SetDefault("dockerRsyncImage", "eeacms/rsync:latest")
+	v.SetDefault("dockerRsyncPort", 8873)
+	v.SetDefault("dockerVolumeRoot", "/var/lib/docker/volumes")
+	v.SetDefault("dockerVolumeHelperImage", "alpine:latest")
+	v.SetDefault("dockerNetemImage", "gaiadocker/iproute2:latest")
+	v.SetDefault("dockerIptablesImage", "vimagick/iptables:latest")
+	v.SetDefault("dockerStressImage", "alexeiled/stress-ng:latest")
+	v.SetDefault("dockerFaketimeLib", "/usr/lib/x86_64-linux-gnu/faketime/libfaketime.so.1")
+	v.SetDefault("dockerIPAMPools", []string{"10.128.0.0/9"})
+	v.SetDefault("dockerIPAMSubnetSize", 24)
+	v.SetDefault("dockerIPAMStateFile", "")
+	v.SetDefault("dockerRuntime", "docker")
+	v.SetDefault("dockerRootless", false)
+	v.SetDefault("dockerNerdctlPath", "nerdctl")
+	v.SetDefault("dockerContainerdAddress", "/run/containerd/containerd.sock")
+	v.SetDefault("dockerContainerdNamespace", "genesis")
+	v.SetDefault("dockerClientIdleTimeout", 5*time.Minute)
+	v.SetDefault("dockerClientHealthInterval", 30*time.Second)
+	v.SetDefault("dockerStatsInterval", 10*time.Second)
+	v.SetDefault("dockerEventPolicy", "notify")
+	v.SetDefault("dockerEventRestartLimit", 3)
+	v.SetDefault("dockerTLSServerName", "")
+	v.SetDefault("dockerCredentialDir", "")
 }
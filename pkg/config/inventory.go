@@ -0,0 +1,40 @@
+/**
+ * Copyright 2019 Whiteblock Inc. All rights reserved.
+ * Use of this source code is governed by a BSD-style
+ * license that can be found in the LICENSE file.
+ */
+
+package config
+
+import (
+	"time"
+
+	"github.com/whiteblock/genesis/pkg/entity"
+
+	"github.com/spf13/viper"
+)
+
+// Inventory is the configuration of the hosts which containers are scheduled on, when their
+// commands do not give a target
+type Inventory struct {
+	// Hosts are the hosts which are in the inventory from the start, more can be registered
+	// through the API. They can only be given in the config file.
+	Hosts []entity.HostSpec `mapstructure:"inventoryHosts"`
+
+	// RefreshInterval is how old the facts of a host can get before they are gathered again.
+	// They are only gathered once if it is 0.
+	RefreshInterval time.Duration `mapstructure:"inventoryRefreshInterval"`
+}
+
+// NewInventory creates a new Inventory config from the given viper
+func NewInventory(v *viper.Viper) (out Inventory, err error) {
+	return out, v.Unmarshal(&out)
+}
+
+func setInventoryBindings(v *viper.Viper) error {
+	return v.BindEnv("inventoryRefreshInterval", "INVENTORY_REFRESH_INTERVAL")
+}
+
+func setInventoryDefaults(v *viper.Viper) {
+	v.SetDefault("inventoryRefreshInterval", time.Minute)
+}
@@ -0,0 +1,106 @@
+/**
+ * Copyright 2019 Whiteblock Inc. All rights reserved.
+ * Use of this source code is governed by a BSD-style
+ * license that can be found in the LICENSE file.
+ */
+
+package config
+
+import (
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// Kubernetes represents the configuration needed to execute commands on a Kubernetes cluster
+type Kubernetes struct {
+	// APIServer is the address of the Kubernetes API server
+	APIServer string `mapstructure:"kubernetesAPIServer"`
+
+	// TokenFile is the file holding the bearer token of the service account of Genesis,
+	// it is read for every request since it may be rotated
+	TokenFile string `mapstructure:"kubernetesTokenFile"`
+
+	// CAFile is the certificate authority of the API server
+	CAFile string `mapstructure:"kubernetesCAFile"`
+
+	// Namespace is where the objects of the tests are created
+	Namespace string `mapstructure:"kubernetesNamespace"`
+
+	// Multus causes the networks to be given to the pods as additional interfaces through
+	// Multus, instead of only being enforced with network policies
+	Multus bool `mapstructure:"kubernetesMultus"`
+
+	// StorageClass is the storage class of the volumes, the default one is used if it is empty
+	StorageClass string `mapstructure:"kubernetesStorageClass"`
+
+	// VolumeSize is the amount of storage requested for each volume
+	VolumeSize string `mapstructure:"kubernetesVolumeSize"`
+
+	// NetemImage is the image of the ephemeral container which applies the network emulation
+	NetemImage string `mapstructure:"kubernetesNetemImage"`
+
+	// NetemTimeout is how long the ephemeral container which applies the network emulation
+	// is given to finish
+	NetemTimeout time.Duration `mapstructure:"kubernetesNetemTimeout"`
+}
+
+// NewKubernetes creates a new Kubernetes configuration from viper
+func NewKubernetes(v *viper.Viper) (out Kubernetes, err error) {
+	return out, v.Unmarshal(&out)
+}
+
+func setKubernetesBindings(v *viper.Viper) error {
+	err := v.BindEnv("kubernetesAPIServer", "KUBERNETES_API_SERVER")
+	if err != nil {
+		return err
+	}
+
+	err = v.BindEnv("kubernetesTokenFile", "KUBERNETES_TOKEN_FILE")
+	if err != nil {
+		return err
+	}
+
+	err = v.BindEnv("kubernetesCAFile", "KUBERNETES_CA_FILE")
+	if err != nil {
+		return err
+	}
+
+	err = v.BindEnv("kubernetesNamespace", "KUBERNETES_NAMESPACE")
+	if err != nil {
+		return err
+	}
+
+	err = v.BindEnv("kubernetesMultus", "KUBERNETES_MULTUS")
+	if err != nil {
+		return err
+	}
+
+	err = v.BindEnv("kubernetesStorageClass", "KUBERNETES_STORAGE_CLASS")
+	if err != nil {
+		return err
+	}
+
+	err = v.BindEnv("kubernetesVolumeSize", "KUBERNETES_VOLUME_SIZE")
+	if err != nil {
+		return err
+	}
+
+	err = v.BindEnv("kubernetesNetemImage", "KUBERNETES_NETEM_IMAGE")
+	if err != nil {
+		return err
+	}
+
+	return v.BindEnv("kubernetesNetemTimeout", "KUBERNETES_NETEM_TIMEOUT")
+}
+
+func setKubernetesDefaults(v *viper.Viper) {
+	v.SetDefault("kubernetesAPIServer", "https://kubernetes.default.svc")
+	v.SetDefault("kubernetesTokenFile", "/var/run/secrets/kubernetes.io/serviceaccount/token")
+	v.SetDefault("kubernetesCAFile", "/var/run/secrets/kubernetes.io/serviceaccount/ca.crt")
+	v.SetDefault("kubernetesNamespace", "genesis")
+	v.SetDefault("kubernetesMultus", false)
+	v.SetDefault("kubernetesVolumeSize", "10Gi")
+	v.SetDefault("kubernetesNetemImage", "gaiadocker/iproute2:latest")
+	v.SetDefault("kubernetesNetemTimeout", time.Minute)
+}
@@ -8,7 +8,12 @@ package config
 
 import (
 	"fmt"
+	"net"
+	"net/url"
+	"path/filepath"
 	"regexp"
+
+	"github.com/whiteblock/genesis/pkg/entity"
 )
 
 func assertNotEmpty(s string, errMsg string) {
@@ -23,6 +28,17 @@ func SanityCheck(conf Config) {
 
 	dockerSanityCheck(conf.Docker)
 	log.Info("docker configuration checks passed")
+
+	inventorySanityCheck(conf.Inventory)
+
+	switch conf.Backend {
+	case entity.DockerBackend:
+	case entity.KubernetesBackend:
+		kubernetesSanityCheck(conf.Kubernetes)
+		log.Info("kubernetes configuration checks passed")
+	default:
+		panic(fmt.Sprintf(`unknown backend: "%s"`, conf.Backend))
+	}
 }
 
 var portRegexp = regexp.MustCompile(`[0-9]+`)
@@ -34,8 +50,120 @@ func dockerSanityCheck(conf Docker) {
 	assertNotEmpty(conf.DaemonPort, "invalid docker daemon port given")
 	assertNotEmpty(conf.GlusterImage, "missing gluster image")
 	assertNotEmpty(conf.GlusterDriver, "missing gluster driver")
+	switch conf.SharedVolumeBackend {
+	case entity.GlusterVolumeBackend:
+	case entity.NFSVolumeBackend:
+		assertNotEmpty(conf.NFSImage, "missing nfs image")
+	case entity.RsyncVolumeBackend:
+		assertNotEmpty(conf.RsyncImage, "missing rsync image")
+		assertNotEmpty(conf.VolumeRoot, "missing volume root")
+		if conf.RsyncPort <= 0 {
+			panic(fmt.Sprintf("invalid rsync port: %d", conf.RsyncPort))
+		}
+	default:
+		panic(fmt.Sprintf(`unknown shared volume backend: "%s"`, conf.SharedVolumeBackend))
+	}
+	assertNotEmpty(conf.VolumeHelperImage, "missing volume helper image")
+	assertNotEmpty(conf.NetemImage, "missing netem image")
+	assertNotEmpty(conf.IptablesImage, "missing iptables image")
+	assertNotEmpty(conf.StressImage, "missing stress image")
+	assertNotEmpty(conf.FaketimeLib, "missing faketime lib")
 
 	if !portRegexp.MatchString(conf.DaemonPort) {
 		panic(fmt.Sprintf(`daemon port is invalid: "%s"`, conf.DaemonPort))
 	}
+
+	switch conf.Runtime {
+	case entity.DockerRuntime, entity.PodmanRuntime:
+	case entity.ContainerdRuntime:
+		assertNotEmpty(conf.NerdctlPath, "missing nerdctl path")
+		assertNotEmpty(conf.ContainerdNamespace, "missing containerd namespace")
+	default:
+		panic(fmt.Sprintf(`unknown container runtime: "%s"`, conf.Runtime))
+	}
+
+	if conf.IPAMSubnetSize < 1 || conf.IPAMSubnetSize > 30 {
+		panic(fmt.Sprintf("invalid ipam subnet size: %d", conf.IPAMSubnetSize))
+	}
+	for _, pool := range conf.IPAMPools {
+		_, ipNet, err := net.ParseCIDR(pool)
+		if err != nil || ipNet.IP.To4() == nil {
+			panic(fmt.Sprintf(`ipam pool is not an IPv4 subnet: "%s"`, pool))
+		}
+	}
+	if conf.ClientIdleTimeout < 0 {
+		panic("the client idle timeout cannot be negative")
+	}
+	if conf.ClientHealthInterval < 0 {
+		panic("the client health interval cannot be negative")
+	}
+	if conf.StatsInterval < 0 {
+		panic("the stats interval cannot be negative")
+	}
+	if !entity.IsEventPolicy(conf.EventPolicy) {
+		panic(fmt.Sprintf(`unknown event policy: "%s"`, conf.EventPolicy))
+	}
+	if conf.EventRestartLimit < 0 {
+		panic("the event restart limit cannot be negative")
+	}
+	if len(conf.CredentialDir) > 0 && !filepath.IsAbs(conf.CredentialDir) {
+		panic(fmt.Sprintf("the credential dir must be an absolute path: %s", conf.CredentialDir))
+	}
+	for name, endpoint := range conf.Endpoints {
+		endpointSanityCheck(name, endpoint)
+	}
+	for _, target := range conf.TransportAllowlist {
+		u, err := url.Parse(target)
+		if err != nil || (u.Scheme != "ssh" && u.Scheme != "unix") {
+			panic(fmt.Sprintf("the transport allowlist can only hold ssh:// and unix:// addresses: %s", target))
+		}
+	}
+}
+
+func endpointSanityCheck(name string, endpoint Endpoint) {
+	u, err := url.Parse(endpoint.Host)
+	if err != nil {
+		panic(fmt.Sprintf("invalid host for the endpoint %s: %v", name, err))
+	}
+	switch u.Scheme {
+	case "tcp", "ssh", "unix":
+	default:
+		panic(fmt.Sprintf("the endpoint %s has an unsupported scheme: %s", name, endpoint.Host))
+	}
+	given := 0
+	for _, file := range []string{endpoint.CACert, endpoint.Cert, endpoint.Key} {
+		if len(file) > 0 {
+			given++
+		}
+	}
+	if given != 0 && (given != 3 || u.Scheme != "tcp") {
+		panic(fmt.Sprintf("the endpoint %s needs a ca cert, cert and key, and only for tcp", name))
+	}
+}
+
+func inventorySanityCheck(conf Inventory) {
+	if conf.RefreshInterval < 0 {
+		panic("the inventory refresh interval cannot be negative")
+	}
+	seen := map[string]bool{}
+	for _, host := range conf.Hosts {
+		assertNotEmpty(host.Address, "an inventory host is missing its address")
+		if seen[host.Address] {
+			panic(fmt.Sprintf("the inventory host %s is given more than once", host.Address))
+		}
+		seen[host.Address] = true
+		if host.CPUs < 0 || host.Memory < 0 {
+			panic(fmt.Sprintf("the inventory host %s has a negative capacity", host.Address))
+		}
+	}
+}
+
+func kubernetesSanityCheck(conf Kubernetes) {
+	assertNotEmpty(conf.APIServer, "missing kubernetes api server")
+	assertNotEmpty(conf.Namespace, "missing kubernetes namespace")
+	assertNotEmpty(conf.VolumeSize, "missing kubernetes volume size")
+	assertNotEmpty(conf.NetemImage, "missing kubernetes netem image")
+	if conf.NetemTimeout <= 0 {
+		panic("the kubernetes netem timeout must be positive")
+	}
 }
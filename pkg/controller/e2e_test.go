@@ -0,0 +1,372 @@
+/**
+ * Copyright 2019 Whiteblock Inc. All rights reserved.
+ * Use of this source code is governed by a BSD-style
+ * license that can be found in the LICENSE file.
+ */
+
+package controller
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/whiteblock/genesis/pkg/config"
+	"github.com/whiteblock/genesis/pkg/entity"
+	"github.com/whiteblock/genesis/pkg/fake"
+	"github.com/whiteblock/genesis/pkg/handler"
+	"github.com/whiteblock/genesis/pkg/handler/auxillary"
+	"github.com/whiteblock/genesis/pkg/repository"
+	"github.com/whiteblock/genesis/pkg/service"
+	"github.com/whiteblock/genesis/pkg/usecase"
+
+	"github.com/docker/docker/api/types"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	queue "github.com/whiteblock/amqp"
+	"github.com/whiteblock/definition/command"
+)
+
+// engineService gives out the fake engine instead of connecting to a docker daemon
+type engineService struct {
+	service.DockerService
+	engine *fake.Engine
+}
+
+func (es engineService) CreateClient(cmd command.Command) (entity.Client, error) {
+	return es.engine, nil
+}
+
+func (es engineService) CreateClient2(ip, testID string) (entity.Client, error) {
+	return es.engine, nil
+}
+
+type e2eRemoteSources struct {
+	data []byte
+}
+
+func (ers e2eRemoteSources) GetTarReader(testnetID string, file command.File) (io.Reader, error) {
+	buf := new(bytes.Buffer)
+	tw := tar.NewWriter(buf)
+	err := tw.WriteHeader(&tar.Header{Name: file.Meta.Filename, Mode: 0644, Size: int64(len(ers.data))})
+	if err != nil {
+		return nil, err
+	}
+	_, err = tw.Write(ers.data)
+	if err != nil {
+		return nil, err
+	}
+	return buf, tw.Close()
+}
+
+func (ers e2eRemoteSources) StreamTar(testnetID string, file command.File) (io.ReadCloser, error) {
+	rdr, err := ers.GetTarReader(testnetID, file)
+	return ioutil.NopCloser(rdr), err
+}
+
+func (ers e2eRemoteSources) PutFile(testnetID string, file command.File, content io.Reader) error {
+	_, err := ioutil.ReadAll(content)
+	return err
+}
+
+type e2e struct {
+	engine     *fake.Engine
+	cmds       *fake.Queue
+	errors     *fake.Queue
+	completion *fake.Queue
+	status     *fake.Queue
+}
+
+func newE2E(t *testing.T) (*e2e, func()) {
+	conf := config.Config{
+		QueueMaxConcurrency:   2,
+		EnableErrorCollection: true,
+		Execution: config.Execution{
+			LimitPerTest:      5,
+			ConnectionRetries: 1,
+			TimeLimit:         time.Minute,
+		},
+		Docker: config.Docker{
+			LogDriver:      "json-file",
+			IPAMPools:      []string{"10.128.0.0/9"},
+			IPAMSubnetSize: 24,
+		},
+	}
+	log := logrus.New()
+	log.SetLevel(logrus.WarnLevel)
+
+	env := &e2e{
+		engine:     fake.NewEngine("tcp://127.0.0.1:2376"),
+		cmds:       fake.NewQueue("cmds", 10),
+		errors:     fake.NewQueue("errors", 10),
+		completion: fake.NewQueue("completion", 10),
+		status:     fake.NewQueue("status", 100),
+	}
+	creds := repository.NewCredentialStore("")
+	serv := engineService{
+		DockerService: service.NewDockerService(repository.NewDockerRepository(log), conf.Docker,
+			e2eRemoteSources{data: []byte(`{"peers":[]}`)}, creds, log),
+		engine: env.engine,
+	}
+	sampler := service.NewStatsSampler(time.Millisecond, serv.CreateClient2, NewStatsPublisher(env.status), log)
+	watcher := service.NewEventWatcher(1, serv.CreateClient2, NewContainerEventPublisher(env.status), log)
+	control := NewCommandController(conf, env.cmds, env.errors, env.completion, env.status,
+		handler.NewDeliveryHandler(
+			auxillary.NewExecutor(conf.Execution,
+				usecase.NewWatchingUseCase(
+					usecase.NewSamplingUseCase(usecase.NewDockerUseCase(serv, log), sampler, log),
+					watcher, entity.NotifyEventPolicy, log),
+				serv, service.NewHostInventory(conf.Inventory, log), creds, sampler, watcher, log),
+			conf, 3, log),
+		log)
+	go control.Start()
+	return env, func() {
+		env.cmds.Close()
+	}
+}
+
+func order(orderType command.OrderType, payload interface{}) command.Command {
+	return command.Command{
+		ID:     uuid.New().String(),
+		Target: command.Target{IP: "127.0.0.1"},
+		Order:  command.Order{Type: orderType, Payload: payload},
+	}
+}
+
+// run sends the instructions, and waits for them to complete. It gives the id of the test.
+func (env *e2e) run(t *testing.T, cmds ...[]command.Command) string {
+	testID := uuid.New().String()
+	for _, round := range cmds {
+		for i := range round {
+			// the definition puts the test id in the meta, so it ends up on the containers
+			if round[i].Meta == nil {
+				round[i].Meta = map[string]string{}
+			}
+			round[i].Meta[command.TestIDKey] = testID
+		}
+	}
+	inst := command.Instructions{
+		ID:           testID,
+		OrgID:        "org",
+		DefinitionID: "def",
+		Commands:     cmds,
+	}
+	msg, err := queue.CreateMessage(inst)
+	require.NoError(t, err)
+	require.NoError(t, env.cmds.Send(msg))
+
+	done, err := env.completion.Consume()
+	require.NoError(t, err)
+	select {
+	case <-done:
+	case <-time.After(30 * time.Second):
+		t.Fatal("the instructions never completed")
+	}
+	return testID
+}
+
+func TestEndToEnd_Testnet(t *testing.T) {
+	env, done := newE2E(t)
+	defer done()
+	env.engine.OnRun("task", func(name string, cmd []string) (int, []byte) {
+		time.Sleep(50 * time.Millisecond) // long enough for node0 to be sampled
+		return 0, []byte("finished")
+	})
+
+	testID := env.run(t,
+		[]command.Command{order(command.Createnetwork, map[string]interface{}{"name": "testnet"})},
+		[]command.Command{
+			order(command.Createcontainer, map[string]interface{}{
+				"name": "node0", "image": "alpine", "network": "testnet", "cpus": "1", "memory": "1GB"}),
+			order(command.Createcontainer, map[string]interface{}{
+				"name": "task", "image": "task", "network": "testnet", "cpus": "1", "memory": "1GB"}),
+		},
+		[]command.Command{order(command.Putfileincontainer, map[string]interface{}{
+			"container": "node0",
+			"file":      map[string]interface{}{"destination": "/etc/", "meta": map[string]string{"filename": "conf.json"}},
+		})},
+		[]command.Command{
+			order(command.Startcontainer, map[string]interface{}{"name": "node0"}),
+			order(command.Startcontainer, map[string]interface{}{"name": "task", "attach": true,
+				"timeout": "10s"}),
+		},
+	)
+
+	ctx := context.Background()
+	net, err := env.engine.NetworkInspect(ctx, "testnet", types.NetworkInspectOptions{})
+	require.NoError(t, err)
+	assert.Len(t, net.Containers, 2)
+	require.Len(t, net.IPAM.Config, 1)
+	assert.Equal(t, "10.128.0.0/24", net.IPAM.Config[0].Subnet)
+
+	node, err := env.engine.ContainerInspect(ctx, "node0")
+	require.NoError(t, err)
+	assert.True(t, node.State.Running)
+	require.Contains(t, node.NetworkSettings.Networks, "testnet")
+	assert.Contains(t, []string{"10.128.0.2", "10.128.0.3"}, node.NetworkSettings.Networks["testnet"].IPAddress)
+
+	task, err := env.engine.ContainerInspect(ctx, "task")
+	require.NoError(t, err)
+	assert.Equal(t, "exited", task.State.Status)
+
+	data, ok := env.engine.File("node0", "/etc/conf.json")
+	require.True(t, ok)
+	assert.Equal(t, `{"peers":[]}`, string(data))
+
+	sampled := false
+	for _, msg := range env.status.Published() {
+		if msg.Headers["type"] != StatsMessageType {
+			continue
+		}
+		var samples []entity.ContainerStats
+		require.NoError(t, json.Unmarshal(msg.Body, &samples))
+		for _, sample := range samples {
+			sampled = sampled || sample.Container == "node0"
+		}
+	}
+	assert.True(t, sampled, "the resource usage of the containers is sent on the status queue")
+
+	var summary *entity.TestStatsSummary
+	for _, msg := range env.status.Published() {
+		if msg.Headers["type"] == StatsSummaryMessageType {
+			summary = &entity.TestStatsSummary{}
+			require.NoError(t, json.Unmarshal(msg.Body, summary))
+		}
+	}
+	require.NotNil(t, summary, "the summary of the resource usage is sent on the status queue")
+	assert.Equal(t, testID, summary.TestID)
+	assert.Contains(t, summary.Containers, "node0")
+
+	// each round but the last one is requeued as the next round
+	assert.Len(t, env.cmds.Rejected(), 3)
+	assert.Len(t, env.cmds.Acked(), 1)
+	assert.Len(t, env.errors.Published(), 0)
+}
+
+func TestEndToEnd_RollsBackOnFatalError(t *testing.T) {
+	env, done := newE2E(t)
+	defer done()
+	env.engine.FailNext("NetworkConnect", fmt.Errorf("Error response from daemon: network sandbox join failed"))
+
+	env.run(t,
+		[]command.Command{
+			order(command.Createnetwork, map[string]interface{}{"name": "net1"}),
+			order(command.Createnetwork, map[string]interface{}{"name": "net2"}),
+		},
+		[]command.Command{order(command.Createcontainer, map[string]interface{}{
+			"name": "node0", "image": "alpine", "network": "net1", "cpus": "1", "memory": "1GB",
+			"networks": []map[string]string{{"network": "net2"}}})},
+		[]command.Command{order(command.Startcontainer, map[string]interface{}{"name": "node0"})},
+	)
+
+	_, err := env.engine.ContainerInspect(context.Background(), "node0")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "No such container")
+
+	require.Eventually(t, func() bool { return len(env.errors.Published()) == 1 }, 5*time.Second,
+		10*time.Millisecond)
+	var res struct {
+		Meta map[string]interface{}
+	}
+	require.NoError(t, json.Unmarshal(env.errors.Published()[0].Body, &res))
+	assert.Equal(t, "node0", res.Meta["name"])
+	assert.Equal(t, "net2", res.Meta["failedNetwork"])
+	assert.Equal(t, true, res.Meta["rolledBack"])
+}
+
+func TestEndToEnd_FailsOnCrash(t *testing.T) {
+	env, done := newE2E(t)
+	defer done()
+	env.engine.OnRun("task", func(name string, cmd []string) (int, []byte) {
+		env.engine.Crash("node0", 137, true)
+		time.Sleep(50 * time.Millisecond) // long enough for the crash to be seen
+		return 0, nil
+	})
+	failing := func(cmd command.Command) command.Command {
+		cmd.Meta = map[string]string{entity.EventPolicyKey: entity.FailEventPolicy}
+		return cmd
+	}
+
+	env.run(t,
+		[]command.Command{
+			failing(order(command.Createcontainer, map[string]interface{}{
+				"name": "node0", "image": "alpine", "cpus": "1", "memory": "1GB"})),
+			failing(order(command.Createcontainer, map[string]interface{}{
+				"name": "task", "image": "task", "cpus": "1", "memory": "1GB"})),
+		},
+		[]command.Command{failing(order(command.Startcontainer, map[string]interface{}{"name": "node0"}))},
+		[]command.Command{failing(order(command.Startcontainer, map[string]interface{}{"name": "task",
+			"attach": true, "timeout": "10s"}))},
+		[]command.Command{failing(order(command.Createcontainer, map[string]interface{}{
+			"name": "node1", "image": "alpine", "cpus": "1", "memory": "1GB"}))},
+	)
+
+	_, err := env.engine.ContainerInspect(context.Background(), "node1")
+	assert.Error(t, err, "the commands of the failed test are not run")
+
+	require.Eventually(t, func() bool { return len(env.errors.Published()) == 1 }, 5*time.Second,
+		10*time.Millisecond)
+	var res struct {
+		Meta map[string]interface{}
+	}
+	require.NoError(t, json.Unmarshal(env.errors.Published()[0].Body, &res))
+	require.Contains(t, res.Meta, "crash")
+	crash := res.Meta["crash"].(map[string]interface{})
+	assert.Equal(t, "node0", crash["container"])
+	assert.Equal(t, float64(137), crash["exitCode"])
+	assert.Equal(t, true, crash["oomKilled"])
+
+	reported := false
+	for _, msg := range env.status.Published() {
+		if msg.Headers["type"] != ContainerEventMessageType {
+			continue
+		}
+		var event entity.ContainerEvent
+		require.NoError(t, json.Unmarshal(msg.Body, &event))
+		reported = reported || (event.Container == "node0" && event.Action == entity.DieContainerEvent)
+	}
+	assert.True(t, reported, "the crash is sent on the status queue")
+}
+
+func TestEndToEnd_FailsOnCrashInLastRound(t *testing.T) {
+	env, done := newE2E(t)
+	defer done()
+	env.engine.OnRun("task", func(name string, cmd []string) (int, []byte) {
+		env.engine.Crash("node0", 2, false)
+		time.Sleep(50 * time.Millisecond) // long enough for the crash to be seen
+		return 0, nil
+	})
+	failing := func(cmd command.Command) command.Command {
+		cmd.Meta = map[string]string{entity.EventPolicyKey: entity.FailEventPolicy}
+		return cmd
+	}
+
+	env.run(t,
+		[]command.Command{
+			failing(order(command.Createcontainer, map[string]interface{}{
+				"name": "node0", "image": "alpine", "cpus": "1", "memory": "1GB"})),
+			failing(order(command.Createcontainer, map[string]interface{}{
+				"name": "task", "image": "task", "cpus": "1", "memory": "1GB"})),
+		},
+		[]command.Command{failing(order(command.Startcontainer, map[string]interface{}{"name": "node0"}))},
+		[]command.Command{failing(order(command.Startcontainer, map[string]interface{}{"name": "task",
+			"attach": true, "timeout": "10s"}))},
+	)
+
+	require.Eventually(t, func() bool { return len(env.errors.Published()) == 1 }, 5*time.Second,
+		10*time.Millisecond, "the test fails rather than completing")
+	var res struct {
+		Meta map[string]interface{}
+	}
+	require.NoError(t, json.Unmarshal(env.errors.Published()[0].Body, &res))
+	require.Contains(t, res.Meta, "crash")
+	assert.Equal(t, "node0", res.Meta["crash"].(map[string]interface{})["container"])
+}
@@ -0,0 +1,38 @@
+/**
+ * Copyright 2019 Whiteblock Inc. All rights reserved.
+ * Use of this source code is governed by a BSD-style
+ * license that can be found in the LICENSE file.
+ */
+
+package controller
+
+import (
+	"github.com/whiteblock/genesis/pkg/entity"
+	"github.com/whiteblock/genesis/pkg/service"
+
+	queue "github.com/whiteblock/amqp"
+)
+
+// ContainerEventMessageType is the type header of the messages on the status queue which carry
+// the events of the containers, such as crashes
+const ContainerEventMessageType = "containerEvent"
+
+type containerEventPublisher struct {
+	status queue.AMQPService
+}
+
+// NewContainerEventPublisher creates a ContainerEventPublisher which sends the events to the
+// status queue
+func NewContainerEventPublisher(status queue.AMQPService) service.ContainerEventPublisher {
+	return &containerEventPublisher{status: status}
+}
+
+// Publish sends the event to the status queue
+func (cep containerEventPublisher) Publish(event entity.ContainerEvent) error {
+	msg, err := queue.CreateMessage(event)
+	if err != nil {
+		return err
+	}
+	msg.Headers["type"] = ContainerEventMessageType
+	return cep.status.Send(msg)
+}
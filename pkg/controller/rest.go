@@ -46,6 +46,9 @@ func (rc restController) Start() {
 
 	rc.mux.HandleFunc("/command", rc.hand.AddCommands).Methods("POST")
 	rc.mux.HandleFunc("/health", rc.hand.HealthCheck).Methods("GET")
+	rc.mux.HandleFunc("/hosts", rc.hand.ListHosts).Methods("GET")
+	rc.mux.HandleFunc("/hosts", rc.hand.RegisterHost).Methods("POST")
+	rc.mux.HandleFunc("/hosts/{address}", rc.hand.RemoveHost).Methods("DELETE")
 
 	rc.log.WithFields(logrus.Fields{"socket": rc.conf.Listen}).Info("listening for requests")
 	rc.log.Fatal(http.ListenAndServe(rc.conf.Listen, removeTrailingSlash(rc.mux)))
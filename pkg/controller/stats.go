@@ -0,0 +1,53 @@
+/**
+ * Copyright 2019 Whiteblock Inc. All rights reserved.
+ * Use of this source code is governed by a BSD-style
+ * license that can be found in the LICENSE file.
+ */
+
+package controller
+
+import (
+	"github.com/whiteblock/genesis/pkg/entity"
+	"github.com/whiteblock/genesis/pkg/service"
+
+	queue "github.com/whiteblock/amqp"
+)
+
+const (
+	// StatsMessageType is the type header of the messages on the status queue which carry samples
+	// of the resource usage of containers, rather than the status of a test
+	StatsMessageType = "containerStats"
+
+	// StatsSummaryMessageType is the type header of the messages on the status queue which carry
+	// the summary of the resource usage of the containers of a test, once it is over
+	StatsSummaryMessageType = "containerStatsSummary"
+)
+
+type statsPublisher struct {
+	status queue.AMQPService
+}
+
+// NewStatsPublisher creates a StatsPublisher which sends the samples and summaries to the status queue
+func NewStatsPublisher(status queue.AMQPService) service.StatsPublisher {
+	return &statsPublisher{status: status}
+}
+
+// Publish sends the samples to the status queue, as one message
+func (sp statsPublisher) Publish(samples []entity.ContainerStats) error {
+	msg, err := queue.CreateMessage(samples)
+	if err != nil {
+		return err
+	}
+	msg.Headers["type"] = StatsMessageType
+	return sp.status.Send(msg)
+}
+
+// PublishSummary sends the summary to the status queue
+func (sp statsPublisher) PublishSummary(summary entity.TestStatsSummary) error {
+	msg, err := queue.CreateMessage(summary)
+	if err != nil {
+		return err
+	}
+	msg.Headers["type"] = StatsSummaryMessageType
+	return sp.status.Send(msg)
+}
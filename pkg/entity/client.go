@@ -10,16 +10,21 @@ import (
 	"context"
 	"io"
 	"net/http"
+	"time"
 
 	"github.com/docker/docker/api/types"
 	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/events"
 	"github.com/docker/docker/api/types/filters"
 	"github.com/docker/docker/api/types/network"
 	"github.com/docker/docker/api/types/swarm"
 	"github.com/docker/docker/api/types/volume"
 )
 
-// Client is an interface which contains the needed methods from the docker client
+// Client is the interface between Genesis and the container runtime of a host, for what is not
+// part of the Engine, such as execs, logs, events, images and swarm. It is a subset of the docker
+// engine API, which podman serves as well. For containerd, it is translated into the commands
+// of nerdctl. The containers, networks and volumes are created through the Engine of the client.
 type Client interface {
 	// Close the transport used by the client
 	Close() error
@@ -49,9 +54,19 @@ type Client interface {
 	// ContainerInspect returns the container information.
 	ContainerInspect(ctx context.Context, containerID string) (types.ContainerJSON, error)
 
+	// ContainerKill terminates the container process but does not remove the container from the docker host.
+	ContainerKill(ctx context.Context, containerID, signal string) error
+
 	// ContainerList returns the list of containers in the docker host.
 	ContainerList(ctx context.Context, options types.ContainerListOptions) ([]types.Container, error)
 
+	// ContainerLogs returns the logs generated by a container in an io.ReadCloser.
+	// It's up to the caller to close the stream.
+	ContainerLogs(ctx context.Context, container string, options types.ContainerLogsOptions) (io.ReadCloser, error)
+
+	// ContainerPause pauses the main process of a given container without terminating it.
+	ContainerPause(ctx context.Context, containerID string) error
+
 	// ContainerRemove kills and removes a container from the docker host.
 	ContainerRemove(ctx context.Context, containerID string, options types.ContainerRemoveOptions) error
 
@@ -61,6 +76,22 @@ type Client interface {
 	// ContainerStatPath returns Stat information about a path inside the container filesystem.
 	ContainerStatPath(ctx context.Context, containerID, path string) (types.ContainerPathStat, error)
 
+	// ContainerStats returns near realtime stats for a given container.
+	// It's up to the caller to close the io.ReadCloser returned.
+	ContainerStats(ctx context.Context, containerID string, stream bool) (types.ContainerStats, error)
+
+	// ContainerStop stops a container. In case the container fails to stop
+	// gracefully within a time frame specified by the timeout argument,
+	// it is forcefully terminated (killed).
+	ContainerStop(ctx context.Context, containerID string, timeout *time.Duration) error
+
+	// ContainerUnpause resumes the process execution within a container
+	ContainerUnpause(ctx context.Context, containerID string) error
+
+	// ContainerUpdate updates resources of a container
+	ContainerUpdate(ctx context.Context, containerID string,
+		updateConfig container.UpdateConfig) (container.ContainerUpdateOKBody, error)
+
 	// ContainerWait waits until the specified container is in a certain state indicated by the given condition, either "not-running" (default), "next-exit", or "removed".
 	ContainerWait(ctx context.Context, containerID string,
 		condition container.WaitCondition) (<-chan container.ContainerWaitOKBody, <-chan error)
@@ -69,9 +100,16 @@ type Client interface {
 	CopyToContainer(ctx context.Context, containerID, dstPath string, content io.Reader,
 		options types.CopyToContainerOptions) error
 
+	// CopyFromContainer gets the content from the container and returns it as a Reader for a TAR archive
+	CopyFromContainer(ctx context.Context, containerID, srcPath string) (io.ReadCloser, types.ContainerPathStat, error)
+
 	// DaemonHost returns the host address used by the client
 	DaemonHost() string
 
+	// Events returns a stream of events in the daemon. It's up to the caller to cancel the context
+	// to stop the stream.
+	Events(ctx context.Context, options types.EventsOptions) (<-chan events.Message, <-chan error)
+
 	// HTTPClient returns a copy of the HTTP client bound to the server
 	HTTPClient() *http.Client
 
@@ -84,6 +122,9 @@ type Client interface {
 	//ImagePull is used to pull a docker image
 	ImagePull(ctx context.Context, refStr string, options types.ImagePullOptions) (io.ReadCloser, error)
 
+	// Info returns information about the docker server, such as its cpus and memory
+	Info(ctx context.Context) (types.Info, error)
+
 	// NetworkCreate sends a request to the docker daemon to create a network
 	NetworkCreate(ctx context.Context, name string, options types.NetworkCreate) (types.NetworkCreateResponse, error)
 
@@ -102,11 +143,32 @@ type Client interface {
 	// NetworkList lists the networks known to the docker daemon
 	NetworkList(ctx context.Context, options types.NetworkListOptions) ([]types.NetworkResource, error)
 
+	// NodeInspectWithRaw returns the node information.
+	NodeInspectWithRaw(ctx context.Context, nodeID string) (swarm.Node, []byte, error)
+
+	// NodeUpdate updates a Node.
+	NodeUpdate(ctx context.Context, nodeID string, version swarm.Version, node swarm.NodeSpec) error
+
 	// Ping pings the server and returns the value of the "Docker-Experimental", "Builder-Version",
 	// "OS-Type" & "API-Version" headers. It attempts to use a HEAD request on the endpoint, but
 	// falls back to GET if HEAD is not supported by the daemon.
 	Ping(ctx context.Context) (types.Ping, error)
 
+	// ServiceCreate creates a new Service.
+	ServiceCreate(ctx context.Context, service swarm.ServiceSpec,
+		options types.ServiceCreateOptions) (types.ServiceCreateResponse, error)
+
+	// ServiceInspectWithRaw returns the service information and the raw data.
+	ServiceInspectWithRaw(ctx context.Context, serviceID string,
+		options types.ServiceInspectOptions) (swarm.Service, []byte, error)
+
+	// ServiceRemove kills and removes a service.
+	ServiceRemove(ctx context.Context, serviceID string) error
+
+	// ServiceUpdate updates a Service. The version number is required to avoid conflicting writes.
+	ServiceUpdate(ctx context.Context, serviceID string, version swarm.Version, service swarm.ServiceSpec,
+		options types.ServiceUpdateOptions) (types.ServiceUpdateResponse, error)
+
 	// SwarmInit initializes the swarm.
 	SwarmInit(ctx context.Context, req swarm.InitRequest) (string, error)
 
@@ -116,6 +178,12 @@ type Client interface {
 	// SwarmInspect inspects the swarm.
 	SwarmInspect(ctx context.Context) (swarm.Swarm, error)
 
+	// SwarmLeave leaves the swarm.
+	SwarmLeave(ctx context.Context, force bool) error
+
+	// TaskList returns the list of tasks.
+	TaskList(ctx context.Context, options types.TaskListOptions) ([]swarm.Task, error)
+
 	// VolumeCreate creates a volume in the docker host.
 	VolumeCreate(ctx context.Context, options volume.VolumeCreateBody) (types.Volume, error)
 
@@ -0,0 +1,50 @@
+/**
+ * Copyright 2019 Whiteblock Inc. All rights reserved.
+ * Use of this source code is governed by a BSD-style
+ * license that can be found in the LICENSE file.
+ */
+
+package entity
+
+import (
+	"strconv"
+
+	"github.com/whiteblock/definition/command"
+)
+
+// ClockSkew represents how the clock of a container differs from the real one
+type ClockSkew struct {
+	// Offset is how far the clock is ahead of the real time, it is behind if negative
+	Offset command.Duration `json:"offset"`
+	// Rate is how fast the clock runs compared to the real one, so that it drifts over time.
+	// The clock does not drift if it is 0 or 1.
+	Rate float64 `json:"rate"`
+}
+
+// Spec gives the clock skew in the format read by libfaketime
+func (cs ClockSkew) Spec() string {
+	out := strconv.FormatFloat(cs.Offset.Seconds(), 'f', -1, 64) + "s"
+	if cs.Offset.Duration >= 0 {
+		out = "+" + out
+	}
+	if cs.Rate != 0 && cs.Rate != 1 {
+		out += " x" + strconv.FormatFloat(cs.Rate, 'f', -1, 64)
+	}
+	return out
+}
+
+// SkewedContainer is a container which is created with a clock that can be shifted. The image
+// needs to provide libfaketime, and it only affects dynamically linked binaries.
+type SkewedContainer struct {
+	Container
+	// Clock is the initial clock skew
+	Clock ClockSkew `json:"clock"`
+}
+
+// ContainerClock represents a change to the clock skew of a container
+type ContainerClock struct {
+	// Container is the name of a container created as a SkewedContainer
+	Container string `json:"container"`
+	// Clock is the new clock skew, which replaces the previous one
+	Clock ClockSkew `json:"clock"`
+}
@@ -0,0 +1,50 @@
+/**
+ * Copyright 2019 Whiteblock Inc. All rights reserved.
+ * Use of this source code is governed by a BSD-style
+ * license that can be found in the LICENSE file.
+ */
+
+package entity
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/whiteblock/definition/command"
+)
+
+func TestClockSkew_Spec(t *testing.T) {
+	var tests = []struct {
+		skew     ClockSkew
+		expected string
+	}{
+		{
+			skew:     ClockSkew{},
+			expected: "+0s",
+		},
+		{
+			skew:     ClockSkew{Offset: command.Duration{Time: command.Time{Duration: 90 * time.Second}}},
+			expected: "+90s",
+		},
+		{
+			skew:     ClockSkew{Offset: command.Duration{Time: command.Time{Duration: -1500 * time.Millisecond}}},
+			expected: "-1.5s",
+		},
+		{
+			skew:     ClockSkew{Offset: command.Duration{Time: command.Time{Duration: time.Hour}}, Rate: 1.01},
+			expected: "+3600s x1.01",
+		},
+		{
+			skew:     ClockSkew{Rate: 1},
+			expected: "+0s",
+		},
+	}
+
+	for i, tt := range tests {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			assert.Equal(t, tt.expected, tt.skew.Spec())
+		})
+	}
+}
@@ -0,0 +1,128 @@
+/**
+ * Copyright 2019 Whiteblock Inc. All rights reserved.
+ * Use of this source code is governed by a BSD-style
+ * license that can be found in the LICENSE file.
+ */
+
+package entity
+
+import (
+	"fmt"
+
+	"github.com/whiteblock/definition/command"
+)
+
+// HelperLabel marks the containers which Genesis runs for its own use, such as the volume helpers.
+// They carry the labels of their test, but they are not nodes of it.
+const HelperLabel = "genesisHelper"
+
+// IsHelper checks whether the labels are those of a container which Genesis runs for its own use
+func IsHelper(labels map[string]string) bool {
+	_, ok := labels[HelperLabel]
+	return ok
+}
+
+// Container is a container, with the options which Genesis supports on top of the definition
+type Container struct {
+	command.Container
+	// Endpoint holds the settings of the container on its network
+	Endpoint
+	// IPv6 is the IPv6 address of the container in its network
+	IPv6 string `json:"ipv6,omitempty"`
+	// DNS are the nameservers of the container, instead of the embedded DNS server
+	DNS []string `json:"dns,omitempty"`
+	// DNSSearch are the search domains of the container, so that the names of peers can be shortened
+	DNSSearch []string `json:"dnsSearch,omitempty"`
+	// DNSOptions are any options for the resolver of the container
+	DNSOptions []string `json:"dnsOptions,omitempty"`
+	// Networks are the additional networks of the container, which are all attached
+	// before the container is started
+	Networks []NetworkAttachment `json:"networks,omitempty"`
+}
+
+// NetworkAttachment is an additional network of a container
+type NetworkAttachment struct {
+	// Network is the name of the network
+	Network string `json:"network"`
+	// IP is the IP address of the container in the network
+	IP string `json:"ip,omitempty"`
+	// IPv6 is the IPv6 address of the container in the network
+	IPv6 string `json:"ipv6,omitempty"`
+	Endpoint
+}
+
+// ContainerNetwork gives the attachment of the container to the network
+func (na NetworkAttachment) ContainerNetwork(containerName string) ContainerNetwork {
+	return ContainerNetwork{
+		ContainerNetwork: command.ContainerNetwork{
+			Container: containerName,
+			Network:   na.Network,
+			IP:        na.IP,
+		},
+		Endpoint: na.Endpoint,
+		IPv6:     na.IPv6,
+	}
+}
+
+// GetNetworks gives the names of all of the networks of the container
+func (c Container) GetNetworks() []string {
+	out := []string{}
+	if len(c.Network) > 0 {
+		out = append(out, c.Network)
+	}
+	for _, attachment := range c.Networks {
+		out = append(out, attachment.Network)
+	}
+	return out
+}
+
+// WithPrimaryNetwork gives the container with the first of its additional networks as its
+// network, if it was not given one, so that it is not put on the default network instead
+func (c Container) WithPrimaryNetwork() Container {
+	if len(c.Network) > 0 || len(c.Networks) == 0 {
+		return c
+	}
+	first := c.Networks[0]
+	c.Network = first.Network
+	c.IP = first.IP
+	c.IPv6 = first.IPv6
+	c.Endpoint = first.Endpoint
+	c.Networks = c.Networks[1:]
+	return c
+}
+
+// PortSpecs gives the ports of the container which are published on the host
+func (c Container) PortSpecs() []PortSpec {
+	out := []PortSpec{}
+	for hostPort, containerPort := range c.TCPPorts {
+		out = append(out, PortSpec{HostIP: "0.0.0.0", HostPort: hostPort,
+			ContainerPort: containerPort, Protocol: "tcp"})
+	}
+	for hostPort, containerPort := range c.UDPPorts {
+		out = append(out, PortSpec{HostIP: "0.0.0.0", HostPort: hostPort,
+			ContainerPort: containerPort, Protocol: "udp"})
+	}
+	return out
+}
+
+// MountSpecs gives the volumes and the host volumes of the container. The host volumes are
+// mounted from /var/lib/docker/genesis/{container}/{key}.
+func (c Container) MountSpecs() []MountSpec {
+	out := []MountSpec{}
+	for _, vol := range c.Volumes {
+		out = append(out, MountSpec{
+			Type:     VolumeMountType,
+			Source:   vol.Name,
+			Target:   vol.Directory,
+			ReadOnly: vol.ReadOnly,
+		})
+	}
+	for key, target := range c.HostVolumes {
+		out = append(out, MountSpec{
+			Type:   BindMountType,
+			Source: fmt.Sprintf("/var/lib/docker/genesis/%s/%s", c.Name, key),
+			Target: target,
+		})
+	}
+	return out
+}
@@ -0,0 +1,92 @@
+/**
+ * Copyright 2019 Whiteblock Inc. All rights reserved.
+ * Use of this source code is governed by a BSD-style
+ * license that can be found in the LICENSE file.
+ */
+
+package entity
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	// EventPolicyKey is the key in the meta of a command which sets what happens when a
+	// container of its test crashes
+	EventPolicyKey = "eventPolicy"
+
+	// NotifyEventPolicy only reports the crashes of the containers
+	NotifyEventPolicy = "notify"
+
+	// FailEventPolicy fails the test when one of its containers crashes
+	FailEventPolicy = "fail"
+
+	// RestartEventPolicy starts the containers again when they crash
+	RestartEventPolicy = "restart"
+)
+
+// IsEventPolicy checks whether the policy is one of the known ones
+func IsEventPolicy(policy string) bool {
+	switch policy {
+	case NotifyEventPolicy, FailEventPolicy, RestartEventPolicy:
+		return true
+	}
+	return false
+}
+
+const (
+	// DieContainerEvent is when the main process of a container exits
+	DieContainerEvent = "die"
+
+	// OOMContainerEvent is when a process of a container is killed for running out of memory
+	OOMContainerEvent = "oom"
+
+	// HealthContainerEvent is when the health of a container changes
+	HealthContainerEvent = "health_status"
+
+	// RestartContainerEvent is when a crashed container was started again, by the restart policy
+	RestartContainerEvent = "restart"
+)
+
+// ContainerEvent is something which happened to a container of a test, after it was started
+type ContainerEvent struct {
+	TestID    string    `json:"testID"`
+	Host      string    `json:"host"`
+	Container string    `json:"container"`
+	Action    string    `json:"action"`
+	Time      time.Time `json:"time"`
+
+	ExitCode  int    `json:"exitCode"`
+	OOMKilled bool   `json:"oomKilled"`
+	Health    string `json:"health,omitempty"`
+	Restarts  int    `json:"restarts,omitempty"`
+}
+
+// Crashed checks whether the container stopped on its own with an error, or became unhealthy
+func (ce ContainerEvent) Crashed() bool {
+	switch ce.Action {
+	case DieContainerEvent:
+		return ce.ExitCode != 0 || ce.OOMKilled
+	case HealthContainerEvent:
+		return ce.Health == "unhealthy"
+	}
+	return false
+}
+
+// Result gives the fatal result of the test which the crash failed
+func (ce ContainerEvent) Result() Result {
+	return NewFatalResult(ce.Error()).InjectMeta(map[string]interface{}{"crash": ce})
+}
+
+// Error describes the crash
+func (ce ContainerEvent) Error() string {
+	switch {
+	case ce.Action == HealthContainerEvent:
+		return fmt.Sprintf("container %s on %s became %s", ce.Container, ce.Host, ce.Health)
+	case ce.OOMKilled:
+		return fmt.Sprintf("container %s on %s ran out of memory and exited with code %d",
+			ce.Container, ce.Host, ce.ExitCode)
+	}
+	return fmt.Sprintf("container %s on %s exited with code %d", ce.Container, ce.Host, ce.ExitCode)
+}
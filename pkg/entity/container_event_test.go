@@ -0,0 +1,69 @@
+/**
+ * Copyright 2019 Whiteblock Inc. All rights reserved.
+ * Use of this source code is governed by a BSD-style
+ * license that can be found in the LICENSE file.
+ */
+
+package entity
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestContainerEvent_Crashed(t *testing.T) {
+	var tests = []struct {
+		event    ContainerEvent
+		crashed  bool
+		expected string
+	}{
+		{
+			event:    ContainerEvent{Container: "node0", Host: "10.0.0.2", Action: DieContainerEvent},
+			crashed:  false,
+			expected: "container node0 on 10.0.0.2 exited with code 0",
+		},
+		{
+			event:    ContainerEvent{Container: "node0", Host: "10.0.0.2", Action: DieContainerEvent, ExitCode: 2},
+			crashed:  true,
+			expected: "container node0 on 10.0.0.2 exited with code 2",
+		},
+		{
+			event: ContainerEvent{Container: "node0", Host: "10.0.0.2", Action: DieContainerEvent,
+				ExitCode: 137, OOMKilled: true},
+			crashed:  true,
+			expected: "container node0 on 10.0.0.2 ran out of memory and exited with code 137",
+		},
+		{
+			event:    ContainerEvent{Container: "node0", Host: "10.0.0.2", Action: OOMContainerEvent},
+			crashed:  false,
+			expected: "container node0 on 10.0.0.2 exited with code 0",
+		},
+		{
+			event:    ContainerEvent{Container: "node0", Host: "10.0.0.2", Action: HealthContainerEvent, Health: "unhealthy"},
+			crashed:  true,
+			expected: "container node0 on 10.0.0.2 became unhealthy",
+		},
+		{
+			event:    ContainerEvent{Container: "node0", Host: "10.0.0.2", Action: HealthContainerEvent, Health: "healthy"},
+			crashed:  false,
+			expected: "container node0 on 10.0.0.2 became healthy",
+		},
+	}
+
+	for i, tt := range tests {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			assert.Equal(t, tt.crashed, tt.event.Crashed())
+			assert.Equal(t, tt.expected, tt.event.Error())
+		})
+	}
+}
+
+func TestIsEventPolicy(t *testing.T) {
+	assert.True(t, IsEventPolicy(NotifyEventPolicy))
+	assert.True(t, IsEventPolicy(FailEventPolicy))
+	assert.True(t, IsEventPolicy(RestartEventPolicy))
+	assert.False(t, IsEventPolicy(""))
+	assert.False(t, IsEventPolicy("ignore"))
+}
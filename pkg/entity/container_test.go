@@ -0,0 +1,47 @@
+/**
+ * Copyright 2019 Whiteblock Inc. All rights reserved.
+ * Use of this source code is governed by a BSD-style
+ * license that can be found in the LICENSE file.
+ */
+
+package entity
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/whiteblock/definition/command"
+)
+
+func TestContainer_WithPrimaryNetwork(t *testing.T) {
+	cntr := Container{
+		Container: command.Container{Name: "validator-1"},
+		Networks: []NetworkAttachment{
+			{Network: "net-a", IP: "10.0.0.2", Endpoint: Endpoint{Aliases: []string{"v1"}}},
+			{Network: "net-b", IPv6: "fd00::2"},
+		},
+	}
+	assert.Equal(t, []string{"net-a", "net-b"}, cntr.GetNetworks())
+
+	primary := cntr.WithPrimaryNetwork()
+	assert.Equal(t, "net-a", primary.Network)
+	assert.Equal(t, "10.0.0.2", primary.IP)
+	assert.Equal(t, []string{"v1"}, primary.Aliases)
+	assert.Equal(t, []NetworkAttachment{{Network: "net-b", IPv6: "fd00::2"}}, primary.Networks)
+	assert.Equal(t, []string{"net-a", "net-b"}, primary.GetNetworks())
+
+	cntr.Network = "net-c"
+	assert.Equal(t, cntr, cntr.WithPrimaryNetwork())
+	assert.Equal(t, []string{"net-c", "net-a", "net-b"}, cntr.GetNetworks())
+}
+
+func TestNetworkAttachment_ContainerNetwork(t *testing.T) {
+	attachment := NetworkAttachment{Network: "net-a", IP: "10.0.0.2", IPv6: "fd00::2",
+		Endpoint: Endpoint{MacAddress: "02:42:ac:11:00:03"}}
+	cn := attachment.ContainerNetwork("validator-1")
+	assert.Equal(t, "validator-1", cn.Container)
+	assert.Equal(t, "net-a", cn.Network)
+	assert.Equal(t, "10.0.0.2", cn.IP)
+	assert.Equal(t, "fd00::2", cn.IPv6)
+	assert.Equal(t, attachment.Endpoint, cn.Endpoint)
+}
@@ -0,0 +1,59 @@
+/**
+ * Copyright 2019 Whiteblock Inc. All rights reserved.
+ * Use of this source code is governed by a BSD-style
+ * license that can be found in the LICENSE file.
+ */
+
+package entity
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+
+	"github.com/whiteblock/definition/command"
+)
+
+// Credentials are the PEM encoded TLS credentials used to connect to the docker daemons of a test
+type Credentials struct {
+	CACert     []byte
+	ClientCert []byte
+	ClientKey  []byte
+}
+
+// NewCredentials takes the credentials of the client out of the authentication of the instructions,
+// they are empty if it does not have them
+func NewCredentials(auth command.Authentication) Credentials {
+	if auth.CACert == nil || auth.ClientCert == nil || auth.ClientKey == nil {
+		return Credentials{}
+	}
+	return Credentials{
+		CACert:     auth.CACertPEM(),
+		ClientCert: auth.ClientCertPEM(),
+		ClientKey:  auth.ClientPKPEM(),
+	}
+}
+
+// Empty returns true if any of the credentials are missing
+func (creds Credentials) Empty() bool {
+	return len(creds.CACert) == 0 || len(creds.ClientCert) == 0 || len(creds.ClientKey) == 0
+}
+
+// Fingerprint identifies the credentials, so that clients made with other credentials are never
+// mistaken for ones made with these
+func (creds Credentials) Fingerprint() string {
+	if creds.Empty() {
+		return ""
+	}
+	hash := sha256.New()
+	hash.Write(creds.CACert)
+	hash.Write(creds.ClientCert)
+	hash.Write(creds.ClientKey)
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// Wipe overwrites the private key in memory
+func (creds Credentials) Wipe() {
+	for i := range creds.ClientKey {
+		creds.ClientKey[i] = 0
+	}
+}
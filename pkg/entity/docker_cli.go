@@ -11,4 +11,6 @@ type DockerCli struct {
 	Client
 	Labels map[string]string
 	TestID string
+	// IP is the address of the docker host the client is connected to
+	IP string
 }
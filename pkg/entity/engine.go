@@ -0,0 +1,192 @@
+/**
+ * Copyright 2019 Whiteblock Inc. All rights reserved.
+ * Use of this source code is governed by a BSD-style
+ * license that can be found in the LICENSE file.
+ */
+
+package entity
+
+import (
+	"context"
+	"errors"
+)
+
+// The kinds of errors which an Engine gives back, whatever runtime it drives
+var (
+	// ErrAlreadyExists is when what is created already exists, or a container is already
+	// attached to the network
+	ErrAlreadyExists = errors.New("already exists")
+
+	// ErrNotFound is when what is acted on does not exist, or a container is not attached
+	// to the network
+	ErrNotFound = errors.New("not found")
+)
+
+// EngineError is an error of a runtime, which is of one of the kinds above
+type EngineError struct {
+	Kind error
+	Err  error
+}
+
+func (ee EngineError) Error() string {
+	return ee.Err.Error()
+}
+
+// Unwrap gives the error of the runtime
+func (ee EngineError) Unwrap() error {
+	return ee.Err
+}
+
+// Is checks whether the error is of the given kind
+func (ee EngineError) Is(target error) bool {
+	return target == ee.Kind
+}
+
+// The types of the mounts of a container
+const (
+	VolumeMountType = "volume"
+	BindMountType   = "bind"
+)
+
+// MountSpec is a volume or a directory of the host which is mounted into a container
+type MountSpec struct {
+	// Type is VolumeMountType or BindMountType
+	Type string
+	// Source is the name of the volume, or the path on the host
+	Source   string
+	Target   string
+	ReadOnly bool
+}
+
+// PortSpec is a port of a container which is published on the host
+type PortSpec struct {
+	HostIP        string
+	HostPort      int
+	ContainerPort int
+	// Protocol is tcp or udp
+	Protocol string
+}
+
+// EndpointSpec is how a container is attached to a network
+type EndpointSpec struct {
+	Network    string
+	IPv4       string
+	IPv6       string
+	MacAddress string
+	Aliases    []string
+	Links      []string
+}
+
+// ContainerSpec is what a container is created from
+type ContainerSpec struct {
+	Name       string
+	Hostname   string
+	Domainname string
+	Image      string
+	// Entrypoint is the command of the container along with its arguments
+	Entrypoint []string
+	// Env are the environment variables, as KEY=value
+	Env    []string
+	Labels map[string]string
+
+	NanoCPUs   int64
+	Memory     int64
+	CapAdd     []string
+	Privileged bool
+	AutoRemove bool
+
+	Ports      []PortSpec
+	Mounts     []MountSpec
+	DNS        []string
+	DNSSearch  []string
+	DNSOptions []string
+
+	LogDriver  string
+	LogOptions map[string]string
+
+	// Network is the network the container is created on, if it has its own network namespace
+	Network *EndpointSpec
+	// NetworkMode is the network namespace the container joins instead, such as host or
+	// container:<name>
+	NetworkMode string
+	// PidMode is the pid namespace the container joins, such as container:<name>
+	PidMode string
+	// VolumesFrom is a container whose volumes are mounted into this one as well
+	VolumesFrom string
+}
+
+// NetworkSpec is what a network is created from
+type NetworkSpec struct {
+	Name   string
+	Driver string
+	// Global networks span the hosts of a swarm
+	Global      bool
+	Internal    bool
+	EnableIPv6  bool
+	Labels      map[string]string
+	IPAMDriver  string
+	IPAMOptions map[string]string
+	Subnets     []Subnet
+	// BridgeName is the name of the bridge interface of a bridge network on the host
+	BridgeName string
+	// Parent is the host interface of a macvlan or ipvlan network
+	Parent string
+	// Mode is the macvlan or ipvlan mode
+	Mode string
+	// MTU is the MTU of the network, the default of the runtime is used if it is 0
+	MTU int
+	// Encrypted turns on the encryption of the traffic of an overlay network
+	Encrypted bool
+	// Options are any other options of the driver, which are given to it as they are
+	Options map[string]string
+}
+
+// VolumeSpec is what a volume is created from
+type VolumeSpec struct {
+	Name   string
+	Labels map[string]string
+	// Driver is the volume driver, the default of the runtime is used if it is not given
+	Driver string
+	// Options are the options of the volume driver, such as the type, device and o of a local
+	// volume which mounts a remote filesystem
+	Options map[string]string
+}
+
+// Engine is the runtime neutral interface between the service and the container runtime of a host,
+// for the lifecycle of the containers, networks and volumes of the tests. Each runtime has an
+// adapter of its own. The errors of the runtime are given back as an EngineError when they are of
+// a known kind.
+type Engine interface {
+	// CreateContainer creates the container, without starting it
+	CreateContainer(ctx context.Context, spec ContainerSpec) error
+
+	// StartContainer starts the container
+	StartContainer(ctx context.Context, name string) error
+
+	// RemoveContainer stops and removes the container, along with its anonymous volumes if asked to
+	RemoveContainer(ctx context.Context, name string, volumes bool) error
+
+	// CreateNetwork creates the network
+	CreateNetwork(ctx context.Context, spec NetworkSpec) error
+
+	// RemoveNetwork removes the network
+	RemoveNetwork(ctx context.Context, name string) error
+
+	// ConnectNetwork attaches the container to the network of the endpoint
+	ConnectNetwork(ctx context.Context, container string, endpoint EndpointSpec) error
+
+	// DisconnectNetwork detaches the container from the network
+	DisconnectNetwork(ctx context.Context, container string, network string) error
+
+	// CreateVolume creates the volume
+	CreateVolume(ctx context.Context, spec VolumeSpec) error
+
+	// RemoveVolume removes the volume
+	RemoveVolume(ctx context.Context, name string) error
+}
+
+// EngineProvider is a Client which has an Engine for the runtime it is connected to. The docker
+// adapter is used for any other Client.
+type EngineProvider interface {
+	Engine() Engine
+}
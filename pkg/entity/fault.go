@@ -0,0 +1,59 @@
+/**
+ * Copyright 2019 Whiteblock Inc. All rights reserved.
+ * Use of this source code is governed by a BSD-style
+ * license that can be found in the LICENSE file.
+ */
+
+package entity
+
+import (
+	"github.com/whiteblock/definition/command"
+)
+
+// KillContainer represents a signal sent to the main process of a container
+type KillContainer struct {
+	// Name is the name of the container
+	Name string `json:"name"`
+	// Signal is the signal to send, such as SIGTERM. Docker sends SIGKILL if it is not given.
+	Signal string `json:"signal"`
+}
+
+// RestartContainer represents a container which is stopped, and then started again after a delay
+type RestartContainer struct {
+	// Name is the name of the container
+	Name string `json:"name"`
+	// Delay is how long the container stays down
+	Delay command.Duration `json:"delay"`
+	// Timeout is how long the container is given to stop before it is killed,
+	// the default of docker is used if it is not given
+	Timeout command.Duration `json:"timeout"`
+}
+
+// UpdateResources represents a change to the resource limits of a running container
+type UpdateResources struct {
+	// Name is the name of the container
+	Name string `json:"name"`
+	// Cpus is the new cpu limit, it is left as it is if not given
+	Cpus string `json:"cpus"`
+	// Memory is the new memory limit, it is left as it is if not given
+	Memory string `json:"memory"`
+}
+
+// Stress represents load which is put on a container by a side car sharing its namespaces
+type Stress struct {
+	// Container is the name of the container
+	Container string `json:"container"`
+	// CPU is the number of workers spinning on the cpu
+	CPU int `json:"cpu"`
+	// IO is the number of workers calling sync
+	IO int `json:"io"`
+	// HDD is the number of workers writing to and removing temporary files
+	HDD int `json:"hdd"`
+	// HDDBytes is how much each hdd worker writes, such as "1G"
+	HDDBytes string `json:"hddBytes"`
+	// Path is the directory the hdd workers write in. If it is given, the volumes of the
+	// container are mounted in the side car, so that it can be one of them.
+	Path string `json:"path"`
+	// Duration is how long the load lasts
+	Duration command.Duration `json:"duration"`
+}
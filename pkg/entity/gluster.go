@@ -0,0 +1,31 @@
+/**
+ * Copyright 2019 Whiteblock Inc. All rights reserved.
+ * Use of this source code is governed by a BSD-style
+ * license that can be found in the LICENSE file.
+ */
+
+package entity
+
+// GlusterVolume is the state of the gluster volume behind a global volume
+type GlusterVolume struct {
+	Name     string `json:"name"`
+	Status   string `json:"status"`
+	Replicas int    `json:"replicas"`
+
+	// Allowed are the addresses which may mount the volume
+	Allowed []string       `json:"allowed,omitempty"`
+	Bricks  []GlusterBrick `json:"bricks"`
+}
+
+// GlusterBrick is one of the replicas of a gluster volume
+type GlusterBrick struct {
+	Host      string `json:"host"`
+	Path      string `json:"path"`
+	Online    bool   `json:"online"`
+	Port      int    `json:"port,omitempty"`
+	Pid       int    `json:"pid,omitempty"`
+	SizeTotal uint64 `json:"sizeTotal,omitempty"`
+	SizeFree  uint64 `json:"sizeFree,omitempty"`
+	Device    string `json:"device,omitempty"`
+	FsName    string `json:"fsName,omitempty"`
+}
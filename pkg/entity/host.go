@@ -0,0 +1,182 @@
+/**
+ * Copyright 2019 Whiteblock Inc. All rights reserved.
+ * Use of this source code is governed by a BSD-style
+ * license that can be found in the LICENSE file.
+ */
+
+package entity
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/whiteblock/definition/command"
+)
+
+const (
+	// HostKey is the key in the meta of a result which holds the host a container was placed on
+	HostKey = "host"
+
+	// SpreadKey is the key in the meta of a command which puts the container in a group, whose
+	// containers are spread across the hosts
+	SpreadKey = "spread"
+
+	// AffinityKey is the key in the meta of a command which lists the containers, separated by
+	// commas, which the container must be placed with
+	AffinityKey = "affinity"
+
+	// AntiAffinityKey is the key in the meta of a command which lists the containers, separated
+	// by commas, which the container must not be placed with
+	AntiAffinityKey = "antiAffinity"
+
+	// HostLabelsKey is the key in the meta of a command which gives the labels, as k=v separated
+	// by commas, which the host of the container must have
+	HostLabelsKey = "hostLabels"
+)
+
+// HostSpec is a host which containers can be placed on. The CPUs and memory are how much of the
+// host containers can use, they are taken from the host itself if they are not given.
+type HostSpec struct {
+	Address string            `json:"address" mapstructure:"address"`
+	CPUs    float64           `json:"cpus,omitempty" mapstructure:"cpus"`
+	Memory  int64             `json:"memory,omitempty" mapstructure:"memory"`
+	Labels  map[string]string `json:"labels,omitempty" mapstructure:"labels"`
+}
+
+// HostFacts are what was gathered from the daemon of a host
+type HostFacts struct {
+	CPUs     float64   `json:"cpus"`
+	Memory   int64     `json:"memory"`
+	Images   []string  `json:"images,omitempty"`
+	Gathered time.Time `json:"gathered"`
+	Error    string    `json:"error,omitempty"`
+}
+
+// Reservation is what a container placed on a host asked for
+type Reservation struct {
+	TestID    string  `json:"testID"`
+	Container string  `json:"container"`
+	Group     string  `json:"group,omitempty"`
+	CPUs      float64 `json:"cpus"`
+	Memory    int64   `json:"memory"`
+}
+
+// Host is a host of the inventory, along with what is known about it
+type Host struct {
+	HostSpec
+	Facts        HostFacts     `json:"facts"`
+	Reservations []Reservation `json:"reservations"`
+}
+
+// Capacity gives how much of the host containers can use
+func (host Host) Capacity() (cpus float64, memory int64) {
+	cpus, memory = host.Facts.CPUs, host.Facts.Memory
+	if host.CPUs > 0 {
+		cpus = host.CPUs
+	}
+	if host.Memory > 0 {
+		memory = host.Memory
+	}
+	return
+}
+
+// Free gives how much of the host has not been reserved yet
+func (host Host) Free() (cpus float64, memory int64) {
+	cpus, memory = host.Capacity()
+	for _, res := range host.Reservations {
+		cpus -= res.CPUs
+		memory -= res.Memory
+	}
+	return
+}
+
+// HasImage checks whether the image was on the host when its facts were gathered
+func (host Host) HasImage(image string) bool {
+	if !strings.Contains(image, ":") && !strings.Contains(image, "@") {
+		image += ":latest"
+	}
+	for _, img := range host.Facts.Images {
+		if img == image || strings.HasSuffix(img, "/"+image) {
+			return true
+		}
+	}
+	return false
+}
+
+// Holds checks whether the container of the test is placed on the host
+func (host Host) Holds(testID string, container string) bool {
+	for _, res := range host.Reservations {
+		if res.TestID == testID && res.Container == container {
+			return true
+		}
+	}
+	return false
+}
+
+// InGroup counts the containers of the group of the test which are placed on the host
+func (host Host) InGroup(testID string, group string) int {
+	out := 0
+	for _, res := range host.Reservations {
+		if res.TestID == testID && res.Group == group {
+			out++
+		}
+	}
+	return out
+}
+
+// PlacementRequest is what a container needs from the host it is placed on
+type PlacementRequest struct {
+	Reservation
+	Image        string
+	Affinity     []string
+	AntiAffinity []string
+	HostLabels   map[string]string
+}
+
+// NewPlacementRequest gets the placement request of the container from the command creating it
+func NewPlacementRequest(cmd command.Command, container command.Container) (PlacementRequest, error) {
+	out := PlacementRequest{
+		Reservation: Reservation{
+			TestID:    cmd.TestID(),
+			Container: container.Name,
+			Group:     cmd.Meta[SpreadKey],
+		},
+		Image:        container.Image,
+		Affinity:     splitList(cmd.Meta[AffinityKey]),
+		AntiAffinity: splitList(cmd.Meta[AntiAffinityKey]),
+		HostLabels:   map[string]string{},
+	}
+	var err error
+	if len(container.Cpus) > 0 {
+		out.CPUs, err = strconv.ParseFloat(container.Cpus, 64)
+		if err != nil {
+			return out, fmt.Errorf("invalid cpus \"%s\"", container.Cpus)
+		}
+	}
+	if len(container.Memory) > 0 {
+		out.Memory, err = container.GetMemory()
+		if err != nil {
+			return out, fmt.Errorf("invalid memory \"%s\"", container.Memory)
+		}
+	}
+	for _, label := range splitList(cmd.Meta[HostLabelsKey]) {
+		pair := strings.SplitN(label, "=", 2)
+		if len(pair) != 2 {
+			return out, fmt.Errorf("invalid host label \"%s\", expected key=value", label)
+		}
+		out.HostLabels[pair[0]] = pair[1]
+	}
+	return out, nil
+}
+
+func splitList(list string) []string {
+	out := []string{}
+	for _, item := range strings.Split(list, ",") {
+		if item = strings.TrimSpace(item); len(item) > 0 {
+			out = append(out, item)
+		}
+	}
+	return out
+}
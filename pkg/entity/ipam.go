@@ -0,0 +1,268 @@
+/**
+ * Copyright 2019 Whiteblock Inc. All rights reserved.
+ * Use of this source code is governed by a BSD-style
+ * license that can be found in the LICENSE file.
+ */
+
+package entity
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+)
+
+// NetworkAllocation is the subnet given to a network of a test, along with the addresses given
+// to the containers on it
+type NetworkAllocation struct {
+	Subnet  string `json:"subnet"`
+	Gateway string `json:"gateway"`
+	// Addresses maps the name of each container on the network to its address
+	Addresses map[string]string `json:"addresses"`
+	// Hosts are the hosts the network was created on, as networks of the same name may be
+	// created on each host
+	Hosts []string `json:"hosts,omitempty"`
+}
+
+// IPAMState is every allocation made by Genesis, by test and then by network. Only IPv4 is
+// managed, IPv6 addresses are left to the daemon.
+type IPAMState map[string]map[string]*NetworkAllocation
+
+func ipToUint(ip net.IP) uint32 {
+	return binary.BigEndian.Uint32(ip.To4())
+}
+
+func uintToIP(n uint32) net.IP {
+	out := make(net.IP, net.IPv4len)
+	binary.BigEndian.PutUint32(out, n)
+	return out
+}
+
+// parseIPv4Subnet parses the subnet, giving back nil if it is not an IPv4 subnet
+func parseIPv4Subnet(subnet string) *net.IPNet {
+	_, ipNet, err := net.ParseCIDR(subnet)
+	if err != nil || ipNet.IP.To4() == nil {
+		return nil
+	}
+	return ipNet
+}
+
+// subnetBounds gives the network and broadcast addresses of the subnet
+func subnetBounds(ipNet *net.IPNet) (uint32, uint32) {
+	ones, bits := ipNet.Mask.Size()
+	first := ipToUint(ipNet.IP)
+	return first, first + (1 << uint(bits-ones)) - 1
+}
+
+func overlaps(a *net.IPNet, b *net.IPNet) bool {
+	return a.Contains(b.IP) || b.Contains(a.IP)
+}
+
+// Network gets the allocation of the network of the test, if there is one
+func (s IPAMState) Network(testID string, network string) (*NetworkAllocation, bool) {
+	alloc, ok := s[testID][network]
+	return alloc, ok
+}
+
+func (s IPAMState) put(testID string, network string, alloc *NetworkAllocation) *NetworkAllocation {
+	if _, ok := s[testID]; !ok {
+		s[testID] = map[string]*NetworkAllocation{}
+	}
+	s[testID][network] = alloc
+	return alloc
+}
+
+// inUse checks whether the subnet overlaps with any of the subnets which have been given out,
+// only checking the given test if it is not empty
+func (s IPAMState) inUse(ipNet *net.IPNet, testID string) bool {
+	for id, networks := range s {
+		if len(testID) > 0 && id != testID {
+			continue
+		}
+		for _, alloc := range networks {
+			other := parseIPv4Subnet(alloc.Subnet)
+			if other != nil && overlaps(ipNet, other) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// AllocateSubnet gives the network of the test the first free subnet of the given size from the
+// pools. A subnet is free if it does not overlap with any subnet of any test, so that tests which
+// share hosts never collide. If the network already has a subnet, it is given back.
+func (s IPAMState) AllocateSubnet(testID string, network string, pools []string,
+	size int) (*NetworkAllocation, error) {
+
+	if alloc, ok := s.Network(testID, network); ok {
+		return alloc, nil
+	}
+	for _, pool := range pools {
+		poolNet := parseIPv4Subnet(pool)
+		if poolNet == nil {
+			return nil, fmt.Errorf("invalid IPv4 address pool \"%s\"", pool)
+		}
+		ones, _ := poolNet.Mask.Size()
+		if size < ones || size > 30 {
+			continue
+		}
+		first, last := subnetBounds(poolNet)
+		step := uint32(1) << uint(32-size)
+		for start := uint64(first); start+uint64(step)-1 <= uint64(last); start += uint64(step) {
+			candidate := &net.IPNet{IP: uintToIP(uint32(start)), Mask: net.CIDRMask(size, 32)}
+			if s.inUse(candidate, "") {
+				continue
+			}
+			return s.put(testID, network, &NetworkAllocation{
+				Subnet:    candidate.String(),
+				Gateway:   uintToIP(uint32(start) + 1).String(),
+				Addresses: map[string]string{},
+			}), nil
+		}
+	}
+	return nil, fmt.Errorf("no free /%d subnet left in the address pools", size)
+}
+
+// ReserveSubnet records a subnet which was chosen for the network of the test, so that addresses
+// can be given out from it. It fails if the subnet overlaps with another network of the same test.
+// Anything other than an IPv4 subnet is not managed, so nil is given back for it.
+func (s IPAMState) ReserveSubnet(testID string, network string, subnet string,
+	gateway string) (*NetworkAllocation, error) {
+
+	ipNet := parseIPv4Subnet(subnet)
+	if ipNet == nil {
+		return nil, nil
+	}
+	if len(gateway) == 0 {
+		first, _ := subnetBounds(ipNet)
+		gateway = uintToIP(first + 1).String()
+	}
+	if alloc, ok := s.Network(testID, network); ok {
+		if alloc.Subnet == ipNet.String() {
+			return alloc, nil
+		}
+		return nil, fmt.Errorf("network \"%s\" already has the subnet %s", network, alloc.Subnet)
+	}
+	if s.inUse(ipNet, testID) {
+		return nil, fmt.Errorf("subnet %s overlaps with another network", subnet)
+	}
+	return s.put(testID, network, &NetworkAllocation{
+		Subnet:    ipNet.String(),
+		Gateway:   gateway,
+		Addresses: map[string]string{},
+	}), nil
+}
+
+// AssignAddress gives the container an address on the network of the test. The given address is
+// reserved if there is one, otherwise the first free address of the subnet is chosen. If the network
+// is not managed, the given address is passed back unchanged.
+func (s IPAMState) AssignAddress(testID string, network string, container string,
+	ip string) (string, error) {
+
+	alloc, ok := s.Network(testID, network)
+	if !ok {
+		return ip, nil
+	}
+	if current, ok := alloc.Addresses[container]; ok && (len(ip) == 0 || ip == current) {
+		return current, nil
+	}
+	ipNet := parseIPv4Subnet(alloc.Subnet)
+	if ipNet == nil {
+		return ip, nil
+	}
+	taken := map[string]string{alloc.Gateway: "the gateway"}
+	for name, addr := range alloc.Addresses {
+		if name != container {
+			taken[addr] = name
+		}
+	}
+	first, last := subnetBounds(ipNet)
+
+	if len(ip) > 0 {
+		addr := net.ParseIP(ip)
+		if addr == nil || addr.To4() == nil || !ipNet.Contains(addr) {
+			return "", fmt.Errorf("address %s is not in the subnet %s of network \"%s\"",
+				ip, alloc.Subnet, network)
+		}
+		if n := ipToUint(addr); n == first || n == last {
+			return "", fmt.Errorf("address %s is reserved in the subnet %s", ip, alloc.Subnet)
+		}
+		if owner, ok := taken[addr.String()]; ok {
+			return "", fmt.Errorf("address %s is already in use by %s", ip, owner)
+		}
+		alloc.Addresses[container] = addr.String()
+		return addr.String(), nil
+	}
+
+	for n := first + 1; n < last; n++ {
+		addr := uintToIP(n).String()
+		if _, ok := taken[addr]; ok {
+			continue
+		}
+		alloc.Addresses[container] = addr
+		return addr, nil
+	}
+	return "", fmt.Errorf("no free address left in the subnet %s of network \"%s\"", alloc.Subnet, network)
+}
+
+// ReleaseAddress releases the address of the container on the network of the test
+func (s IPAMState) ReleaseAddress(testID string, network string, container string) {
+	if alloc, ok := s.Network(testID, network); ok {
+		delete(alloc.Addresses, container)
+	}
+}
+
+// ReleaseContainer releases the addresses of the container on every network of the test
+func (s IPAMState) ReleaseContainer(testID string, container string) {
+	for _, alloc := range s[testID] {
+		delete(alloc.Addresses, container)
+	}
+}
+
+// ReleaseTest releases the subnets of all of the networks of the test, along with all of their addresses
+func (s IPAMState) ReleaseTest(testID string) {
+	delete(s, testID)
+}
+
+// AddHost records that the network of the test was created on the host, giving back whether
+// it was not there already. Nothing is recorded if the network is not managed.
+func (s IPAMState) AddHost(testID string, network string, host string) bool {
+	alloc, ok := s.Network(testID, network)
+	if !ok {
+		return false
+	}
+	for _, existing := range alloc.Hosts {
+		if existing == host {
+			return false
+		}
+	}
+	alloc.Hosts = append(alloc.Hosts, host)
+	return true
+}
+
+// ReleaseHost records that the network of the test was removed from the host. The subnet and the
+// addresses on it are only released once the network is not left on any host.
+func (s IPAMState) ReleaseHost(testID string, network string, host string) {
+	alloc, ok := s.Network(testID, network)
+	if !ok {
+		return
+	}
+	for i, existing := range alloc.Hosts {
+		if existing == host {
+			alloc.Hosts = append(alloc.Hosts[:i], alloc.Hosts[i+1:]...)
+			break
+		}
+	}
+	if len(alloc.Hosts) == 0 {
+		s.ReleaseNetwork(testID, network)
+	}
+}
+
+// ReleaseNetwork releases the subnet of the network of the test, along with all of the addresses on it
+func (s IPAMState) ReleaseNetwork(testID string, network string) {
+	delete(s[testID], network)
+	if len(s[testID]) == 0 {
+		delete(s, testID)
+	}
+}
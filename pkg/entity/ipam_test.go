@@ -0,0 +1,149 @@
+/**
+ * Copyright 2019 Whiteblock Inc. All rights reserved.
+ * Use of this source code is governed by a BSD-style
+ * license that can be found in the LICENSE file.
+ */
+
+package entity
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIPAMState_AllocateSubnet(t *testing.T) {
+	state := IPAMState{}
+	pools := []string{"10.0.0.0/23", "10.1.0.0/24"}
+
+	alloc, err := state.AllocateSubnet("test1", "net-a", pools, 24)
+	require.NoError(t, err)
+	assert.Equal(t, "10.0.0.0/24", alloc.Subnet)
+	assert.Equal(t, "10.0.0.1", alloc.Gateway)
+
+	again, err := state.AllocateSubnet("test1", "net-a", pools, 24)
+	require.NoError(t, err)
+	assert.Equal(t, alloc, again)
+
+	alloc, err = state.AllocateSubnet("test2", "net-a", pools, 24)
+	require.NoError(t, err)
+	assert.Equal(t, "10.0.1.0/24", alloc.Subnet)
+
+	alloc, err = state.AllocateSubnet("test2", "net-b", pools, 24)
+	require.NoError(t, err)
+	assert.Equal(t, "10.1.0.0/24", alloc.Subnet)
+
+	_, err = state.AllocateSubnet("test2", "net-c", pools, 24)
+	assert.Error(t, err)
+
+	state.ReleaseNetwork("test1", "net-a")
+	_, ok := state["test1"]
+	assert.False(t, ok)
+	alloc, err = state.AllocateSubnet("test2", "net-c", pools, 24)
+	require.NoError(t, err)
+	assert.Equal(t, "10.0.0.0/24", alloc.Subnet)
+
+	_, err = state.AllocateSubnet("test3", "net-a", []string{"fd00::/64"}, 24)
+	assert.Error(t, err)
+}
+
+func TestIPAMState_ReserveSubnet(t *testing.T) {
+	state := IPAMState{}
+	alloc, err := state.ReserveSubnet("test1", "net-a", "10.2.0.0/16", "")
+	require.NoError(t, err)
+	assert.Equal(t, "10.2.0.1", alloc.Gateway)
+
+	_, err = state.ReserveSubnet("test1", "net-a", "10.2.0.0/16", "")
+	assert.NoError(t, err)
+	_, err = state.ReserveSubnet("test1", "net-a", "10.3.0.0/16", "")
+	assert.Error(t, err)
+	_, err = state.ReserveSubnet("test1", "net-b", "10.2.5.0/24", "")
+	assert.Error(t, err)
+	_, err = state.ReserveSubnet("test2", "net-b", "10.2.5.0/24", "10.2.5.254")
+	assert.NoError(t, err)
+
+	alloc, err = state.ReserveSubnet("test1", "net-c", "fd00::/64", "")
+	assert.NoError(t, err)
+	assert.Nil(t, alloc)
+
+	_, err = state.AllocateSubnet("test3", "net-a", []string{"10.2.0.0/15"}, 16)
+	require.NoError(t, err)
+	assert.Equal(t, "10.3.0.0/16", state["test3"]["net-a"].Subnet)
+}
+
+func TestIPAMState_AssignAddress(t *testing.T) {
+	state := IPAMState{}
+	_, err := state.ReserveSubnet("test1", "net-a", "10.0.0.0/29", "")
+	require.NoError(t, err)
+
+	ip, err := state.AssignAddress("test1", "net-b", "node-1", "")
+	assert.NoError(t, err)
+	assert.Equal(t, "", ip)
+
+	ip, err = state.AssignAddress("test1", "net-a", "node-1", "")
+	require.NoError(t, err)
+	assert.Equal(t, "10.0.0.2", ip)
+
+	ip, err = state.AssignAddress("test1", "net-a", "node-1", "")
+	require.NoError(t, err)
+	assert.Equal(t, "10.0.0.2", ip)
+
+	_, err = state.AssignAddress("test1", "net-a", "node-2", "10.0.0.2")
+	assert.Error(t, err)
+	_, err = state.AssignAddress("test1", "net-a", "node-2", "10.0.0.1")
+	assert.Error(t, err)
+	_, err = state.AssignAddress("test1", "net-a", "node-2", "10.0.0.7")
+	assert.Error(t, err)
+	_, err = state.AssignAddress("test1", "net-a", "node-2", "10.0.1.2")
+	assert.Error(t, err)
+
+	ip, err = state.AssignAddress("test1", "net-a", "node-2", "10.0.0.6")
+	require.NoError(t, err)
+	assert.Equal(t, "10.0.0.6", ip)
+
+	for i, expected := range []string{"10.0.0.3", "10.0.0.4", "10.0.0.5"} {
+		ip, err = state.AssignAddress("test1", "net-a", string(rune('a'+i)), "")
+		require.NoError(t, err)
+		assert.Equal(t, expected, ip)
+	}
+	_, err = state.AssignAddress("test1", "net-a", "node-3", "")
+	assert.Error(t, err)
+
+	state.ReleaseContainer("test1", "node-1")
+	ip, err = state.AssignAddress("test1", "net-a", "node-3", "")
+	require.NoError(t, err)
+	assert.Equal(t, "10.0.0.2", ip)
+
+	state.ReleaseAddress("test1", "net-a", "node-3")
+	_, ok := state["test1"]["net-a"].Addresses["node-3"]
+	assert.False(t, ok)
+
+	state.ReleaseTest("test1")
+	assert.NotContains(t, state, "test1")
+}
+
+func TestIPAMState_ReleaseHost(t *testing.T) {
+	state := IPAMState{}
+	_, err := state.AllocateSubnet("test1", "net-a", []string{"10.0.0.0/16"}, 24)
+	require.NoError(t, err)
+	assert.True(t, state.AddHost("test1", "net-a", "10.1.1.1"))
+	assert.True(t, state.AddHost("test1", "net-a", "10.1.1.2"))
+	assert.False(t, state.AddHost("test1", "net-a", "10.1.1.2"))
+	assert.False(t, state.AddHost("test1", "net-b", "10.1.1.1"))
+	_, err = state.AssignAddress("test1", "net-a", "node-1", "")
+	require.NoError(t, err)
+
+	state.ReleaseHost("test1", "net-a", "10.1.1.1")
+	alloc, ok := state.Network("test1", "net-a")
+	require.True(t, ok, "the network is still on the other host")
+	assert.Equal(t, map[string]string{"node-1": "10.0.0.2"}, alloc.Addresses)
+
+	state.ReleaseHost("test1", "net-a", "10.1.1.3")
+	_, ok = state.Network("test1", "net-a")
+	assert.True(t, ok)
+
+	state.ReleaseHost("test1", "net-a", "10.1.1.2")
+	_, ok = state.Network("test1", "net-a")
+	assert.False(t, ok)
+}
@@ -0,0 +1,246 @@
+/**
+ * Copyright 2019 Whiteblock Inc. All rights reserved.
+ * Use of this source code is governed by a BSD-style
+ * license that can be found in the LICENSE file.
+ */
+
+package entity
+
+const (
+	// BackendKey is the key in the meta of a command which selects the backend it is executed on
+	BackendKey = "backend"
+
+	// DockerBackend executes the commands against docker daemons
+	DockerBackend = "docker"
+
+	// KubernetesBackend executes the commands against a Kubernetes cluster
+	KubernetesBackend = "kubernetes"
+)
+
+// KubeCli is the target of a command on Kubernetes, with extras such as labels
+type KubeCli struct {
+	Namespace string
+	Labels    map[string]string
+	TestID    string
+}
+
+// The following are the parts of the Kubernetes API objects which Genesis uses
+
+// ObjectMeta is the metadata of a Kubernetes object
+type ObjectMeta struct {
+	Name        string            `json:"name"`
+	Namespace   string            `json:"namespace,omitempty"`
+	Labels      map[string]string `json:"labels,omitempty"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+// TypeMeta is the kind of a Kubernetes object
+type TypeMeta struct {
+	APIVersion string `json:"apiVersion,omitempty"`
+	Kind       string `json:"kind,omitempty"`
+}
+
+// Pod is a group of containers which share a network namespace
+type Pod struct {
+	TypeMeta
+	Metadata ObjectMeta `json:"metadata"`
+	Spec     PodSpec    `json:"spec"`
+	Status   PodStatus  `json:"status,omitempty"`
+}
+
+// PodSpec is the specification of a pod
+type PodSpec struct {
+	Containers          []KubeContainer        `json:"containers"`
+	EphemeralContainers []KubeContainer        `json:"ephemeralContainers,omitempty"`
+	Volumes             []KubeVolume           `json:"volumes,omitempty"`
+	RestartPolicy       string                 `json:"restartPolicy,omitempty"`
+	ImagePullSecrets    []LocalObjectReference `json:"imagePullSecrets,omitempty"`
+}
+
+// PodStatus is the observed state of a pod
+type PodStatus struct {
+	Phase             string            `json:"phase,omitempty"`
+	PodIP             string            `json:"podIP,omitempty"`
+	ContainerStatuses []ContainerStatus `json:"containerStatuses,omitempty"`
+
+	EphemeralContainerStatuses []ContainerStatus `json:"ephemeralContainerStatuses,omitempty"`
+}
+
+// ContainerStatus is the observed state of a container of a pod
+type ContainerStatus struct {
+	Name  string         `json:"name"`
+	State ContainerState `json:"state"`
+}
+
+// ContainerState is the state of a container, only the terminated state is used
+type ContainerState struct {
+	Terminated *ContainerStateTerminated `json:"terminated,omitempty"`
+}
+
+// ContainerStateTerminated is the state of a container which has exited
+type ContainerStateTerminated struct {
+	ExitCode int    `json:"exitCode"`
+	Reason   string `json:"reason,omitempty"`
+}
+
+// KubeContainer is a container of a pod
+type KubeContainer struct {
+	Name            string               `json:"name"`
+	Image           string               `json:"image"`
+	Command         []string             `json:"command,omitempty"`
+	Args            []string             `json:"args,omitempty"`
+	Env             []EnvVar             `json:"env,omitempty"`
+	Ports           []ContainerPort      `json:"ports,omitempty"`
+	Resources       ResourceRequirements `json:"resources,omitempty"`
+	VolumeMounts    []VolumeMount        `json:"volumeMounts,omitempty"`
+	SecurityContext *KubeSecurityContext `json:"securityContext,omitempty"`
+	TargetContainer string               `json:"targetContainerName,omitempty"`
+}
+
+// EnvVar is an environment variable of a container
+type EnvVar struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// ContainerPort is a port which is exposed by a container
+type ContainerPort struct {
+	ContainerPort int    `json:"containerPort"`
+	HostPort      int    `json:"hostPort,omitempty"`
+	Protocol      string `json:"protocol,omitempty"`
+}
+
+// ResourceRequirements are the resource limits of a container
+type ResourceRequirements struct {
+	Limits   map[string]string `json:"limits,omitempty"`
+	Requests map[string]string `json:"requests,omitempty"`
+}
+
+// VolumeMount is where a volume is mounted in a container
+type VolumeMount struct {
+	Name      string `json:"name"`
+	MountPath string `json:"mountPath"`
+	SubPath   string `json:"subPath,omitempty"`
+	ReadOnly  bool   `json:"readOnly,omitempty"`
+}
+
+// KubeSecurityContext are the privileges of a container
+type KubeSecurityContext struct {
+	Privileged   *bool             `json:"privileged,omitempty"`
+	Capabilities *KubeCapabilities `json:"capabilities,omitempty"`
+}
+
+// KubeCapabilities are the capabilities added to a container
+type KubeCapabilities struct {
+	Add []string `json:"add,omitempty"`
+}
+
+// KubeVolume is a volume of a pod
+type KubeVolume struct {
+	Name                  string                    `json:"name"`
+	PersistentVolumeClaim *PersistentVolumeClaimRef `json:"persistentVolumeClaim,omitempty"`
+	ConfigMap             *ConfigMapVolumeSource    `json:"configMap,omitempty"`
+	HostPath              *HostPathVolumeSource     `json:"hostPath,omitempty"`
+}
+
+// PersistentVolumeClaimRef refers to a persistent volume claim from a pod
+type PersistentVolumeClaimRef struct {
+	ClaimName string `json:"claimName"`
+	ReadOnly  bool   `json:"readOnly,omitempty"`
+}
+
+// ConfigMapVolumeSource mounts a config map into a pod
+type ConfigMapVolumeSource struct {
+	Name        string `json:"name"`
+	DefaultMode *int64 `json:"defaultMode,omitempty"`
+}
+
+// HostPathVolumeSource mounts a directory of the node into a pod
+type HostPathVolumeSource struct {
+	Path string `json:"path"`
+	Type string `json:"type,omitempty"`
+}
+
+// LocalObjectReference refers to another object in the same namespace
+type LocalObjectReference struct {
+	Name string `json:"name"`
+}
+
+// ConfigMap holds data which can be mounted into pods
+type ConfigMap struct {
+	TypeMeta
+	Metadata   ObjectMeta        `json:"metadata"`
+	Data       map[string]string `json:"data,omitempty"`
+	BinaryData map[string][]byte `json:"binaryData,omitempty"`
+}
+
+// Secret holds sensitive data, such as registry credentials
+type Secret struct {
+	TypeMeta
+	Metadata   ObjectMeta        `json:"metadata"`
+	Type       string            `json:"type,omitempty"`
+	StringData map[string]string `json:"stringData,omitempty"`
+}
+
+// PersistentVolumeClaim is a request for storage
+type PersistentVolumeClaim struct {
+	TypeMeta
+	Metadata ObjectMeta                `json:"metadata"`
+	Spec     PersistentVolumeClaimSpec `json:"spec"`
+}
+
+// PersistentVolumeClaimSpec is the specification of a persistent volume claim
+type PersistentVolumeClaimSpec struct {
+	AccessModes      []string             `json:"accessModes"`
+	StorageClassName *string              `json:"storageClassName,omitempty"`
+	Resources        ResourceRequirements `json:"resources"`
+}
+
+// NetworkPolicy controls which pods can reach each other
+type NetworkPolicy struct {
+	TypeMeta
+	Metadata ObjectMeta        `json:"metadata"`
+	Spec     NetworkPolicySpec `json:"spec"`
+}
+
+// NetworkPolicySpec is the specification of a network policy
+type NetworkPolicySpec struct {
+	PodSelector LabelSelector              `json:"podSelector"`
+	Ingress     []NetworkPolicyIngressRule `json:"ingress"`
+	PolicyTypes []string                   `json:"policyTypes,omitempty"`
+}
+
+// NetworkPolicyIngressRule allows traffic from the given peers
+type NetworkPolicyIngressRule struct {
+	From []NetworkPolicyPeer `json:"from"`
+}
+
+// NetworkPolicyPeer selects the pods which traffic is allowed from
+type NetworkPolicyPeer struct {
+	PodSelector *LabelSelector `json:"podSelector,omitempty"`
+}
+
+// LabelSelector selects objects by their labels
+type LabelSelector struct {
+	MatchLabels map[string]string `json:"matchLabels,omitempty"`
+}
+
+// NetworkAttachmentDefinition is an additional network interface which Multus gives to pods
+type NetworkAttachmentDefinition struct {
+	TypeMeta
+	Metadata ObjectMeta                      `json:"metadata"`
+	Spec     NetworkAttachmentDefinitionSpec `json:"spec"`
+}
+
+// NetworkAttachmentDefinitionSpec holds the CNI configuration of the network
+type NetworkAttachmentDefinitionSpec struct {
+	Config string `json:"config"`
+}
+
+// MultusNetwork is an entry of the networks annotation of a pod, which selects a
+// NetworkAttachmentDefinition
+type MultusNetwork struct {
+	Name string   `json:"name"`
+	IPs  []string `json:"ips,omitempty"`
+	MAC  string   `json:"mac,omitempty"`
+}
@@ -0,0 +1,47 @@
+/**
+ * Copyright 2019 Whiteblock Inc. All rights reserved.
+ * Use of this source code is governed by a BSD-style
+ * license that can be found in the LICENSE file.
+ */
+
+package entity
+
+import (
+	"github.com/whiteblock/definition/command"
+)
+
+// LinkEmulation represents network impairments which are applied only to the traffic
+// going from a container to specific peers on a network
+type LinkEmulation struct {
+	// Container is the container whose outgoing traffic is impaired
+	Container string `json:"container"`
+	// Network is the network shared by the container and its peers
+	Network string `json:"network"`
+	// Links are the impairments for each peer, the container of each link being the destination
+	Links []command.Netconf `json:"links"`
+}
+
+// EmulationStep is a set of network conditions which is applied at an offset from the
+// start of an emulation schedule
+type EmulationStep struct {
+	// Offset is the time after the start of the schedule at which the step is applied
+	Offset command.Duration `json:"offset"`
+	// Netconf are the conditions to apply, the container and network come from the schedule
+	Netconf command.Netconf `json:"netconf"`
+}
+
+// EmulationSchedule represents network conditions which change over time. Genesis
+// applies each step on its own, so the timing does not depend on the queue.
+type EmulationSchedule struct {
+	// Name identifies the schedule within the test, so that it can be canceled
+	Name string `json:"name"`
+	// Container is the container whose traffic is impaired
+	Container string `json:"container"`
+	// Network is the network the conditions are applied on
+	Network string `json:"network"`
+	// Steps are the conditions to apply, each replacing the one before it
+	Steps []EmulationStep `json:"steps"`
+	// Restore is the offset at which the emulation is removed. If it is not given, the
+	// conditions of the last step remain until they are removed.
+	Restore command.Duration `json:"restore"`
+}
@@ -0,0 +1,131 @@
+/**
+ * Copyright 2019 Whiteblock Inc. All rights reserved.
+ * Use of this source code is governed by a BSD-style
+ * license that can be found in the LICENSE file.
+ */
+
+package entity
+
+import (
+	"net"
+
+	"github.com/docker/docker/api/types/network"
+	"github.com/whiteblock/definition/command"
+)
+
+// Subnet is an address range of a network
+type Subnet struct {
+	// Subnet is the range in CIDR notation, it can be IPv4 or IPv6
+	Subnet string `json:"subnet"`
+	// Gateway is the gateway of the subnet
+	Gateway string `json:"gateway"`
+	// IPRange is the part of the subnet which addresses are allocated from
+	IPRange string `json:"ipRange"`
+}
+
+// IsIPv6 checks whether the subnet is an IPv6 range
+func (s Subnet) IsIPv6() bool {
+	ip, _, err := net.ParseCIDR(s.Subnet)
+	return err == nil && ip.To4() == nil
+}
+
+// The network drivers which Genesis knows the options of, any other driver is given its options as is
+const (
+	BridgeDriver  = "bridge"
+	OverlayDriver = "overlay"
+	MacvlanDriver = "macvlan"
+	IpvlanDriver  = "ipvlan"
+)
+
+// Network is a network, with the options which Genesis supports on top of the definition
+type Network struct {
+	command.Network
+	// EnableIPv6 turns on IPv6 for the network, it is turned on anyway when there is an IPv6 subnet
+	EnableIPv6 bool `json:"enableIPv6"`
+	// Subnets are the subnets of the network besides the one from the definition,
+	// such as the IPv6 subnet of a dual-stack network
+	Subnets []Subnet `json:"subnets"`
+	// Driver is the network driver. If it is not given, it is overlay for global networks and bridge otherwise.
+	Driver string `json:"driver"`
+	// Parent is the host interface of a macvlan or ipvlan network
+	Parent string `json:"parent"`
+	// Mode is the macvlan or ipvlan mode, such as bridge or l3
+	Mode string `json:"mode"`
+	// MTU is the MTU of a bridge or overlay network, the default of docker is used if it is 0
+	MTU int `json:"mtu"`
+	// Internal networks are not connected to anything outside of the network
+	Internal bool `json:"internal"`
+	// Encrypted turns on the encryption of the traffic of an overlay network
+	Encrypted bool `json:"encrypted"`
+	// IPAMDriver is the IPAM driver of the network, it is the default one if not given
+	IPAMDriver string `json:"ipamDriver"`
+	// IPAMOptions are the options given to the IPAM driver
+	IPAMOptions map[string]string `json:"ipamOptions"`
+	// Options are any other options for the network driver
+	Options map[string]string `json:"options"`
+}
+
+// GetDriver gives the network driver, taking the default into account
+func (n Network) GetDriver() string {
+	if len(n.Driver) > 0 {
+		return n.Driver
+	}
+	if n.Global {
+		return OverlayDriver
+	}
+	return BridgeDriver
+}
+
+// AllSubnets gives all of the subnets of the network
+func (n Network) AllSubnets() []Subnet {
+	out := []Subnet{}
+	if len(n.Subnet) > 0 || len(n.Gateway) > 0 || len(n.Subnets) == 0 {
+		out = append(out, Subnet{Subnet: n.Subnet, Gateway: n.Gateway})
+	}
+	return append(out, n.Subnets...)
+}
+
+// HasIPv6 checks whether the network has IPv6 enabled or any IPv6 subnets
+func (n Network) HasIPv6() bool {
+	if n.EnableIPv6 {
+		return true
+	}
+	for _, subnet := range n.AllSubnets() {
+		if subnet.IsIPv6() {
+			return true
+		}
+	}
+	return false
+}
+
+// IPAMConfig gives the IPAM configuration for each of the subnets of the network
+func (n Network) IPAMConfig() []network.IPAMConfig {
+	out := []network.IPAMConfig{}
+	for _, subnet := range n.AllSubnets() {
+		out = append(out, network.IPAMConfig{
+			Subnet:  subnet.Subnet,
+			Gateway: subnet.Gateway,
+			IPRange: subnet.IPRange,
+		})
+	}
+	return out
+}
+
+// Endpoint holds the settings of a container on a network, besides its addresses
+type Endpoint struct {
+	// Aliases are extra names which the container can be reached by on the network
+	Aliases []string `json:"aliases,omitempty"`
+	// MacAddress is the MAC address of the container on the network
+	MacAddress string `json:"macAddress,omitempty"`
+	// Links are other containers which can be reached by an alias, given as "name" or "name:alias"
+	Links []string `json:"links,omitempty"`
+}
+
+// ContainerNetwork is a container being attached to a network, with the options which
+// Genesis supports on top of the definition
+type ContainerNetwork struct {
+	command.ContainerNetwork
+	Endpoint
+	// IPv6 is the IPv6 address of the container in the network
+	IPv6 string `json:"ipv6,omitempty"`
+}
@@ -0,0 +1,36 @@
+/**
+ * Copyright 2019 Whiteblock Inc. All rights reserved.
+ * Use of this source code is governed by a BSD-style
+ * license that can be found in the LICENSE file.
+ */
+
+package entity
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/whiteblock/definition/command"
+)
+
+func TestNetwork_AllSubnets(t *testing.T) {
+	assert.Equal(t, []Subnet{{}}, Network{}.AllSubnets())
+
+	net := Network{
+		Network: command.Network{Subnet: "10.1.0.0/16", Gateway: "10.1.0.1"},
+		Subnets: []Subnet{{Subnet: "fd00::/64"}},
+	}
+	assert.Equal(t, []Subnet{{Subnet: "10.1.0.0/16", Gateway: "10.1.0.1"}, {Subnet: "fd00::/64"}},
+		net.AllSubnets())
+	assert.Len(t, net.IPAMConfig(), 2)
+
+	net.Subnet = ""
+	net.Gateway = ""
+	assert.Equal(t, []Subnet{{Subnet: "fd00::/64"}}, net.AllSubnets())
+}
+
+func TestNetwork_HasIPv6(t *testing.T) {
+	assert.False(t, Network{Network: command.Network{Subnet: "10.1.0.0/16"}}.HasIPv6())
+	assert.True(t, Network{EnableIPv6: true}.HasIPv6())
+	assert.True(t, Network{Subnets: []Subnet{{Subnet: "fd00::/64"}}}.HasIPv6())
+}
@@ -0,0 +1,90 @@
+/**
+ * Copyright 2019 Whiteblock Inc. All rights reserved.
+ * Use of this source code is governed by a BSD-style
+ * license that can be found in the LICENSE file.
+ */
+
+package entity
+
+import (
+	"github.com/whiteblock/definition/command"
+)
+
+// These are the order types which are specific to Genesis, on top of the ones
+// provided by the definition
+const (
+	// Linkemulation applies network emulation per destination, payload will be LinkEmulation
+	Linkemulation = command.OrderType("linkemulation")
+
+	// Changeemulation changes the parameters of the applied emulation, payload will be Netconf
+	Changeemulation = command.OrderType("changeemulation")
+
+	// Removeemulation removes the applied emulation, payload will be Netconf
+	Removeemulation = command.OrderType("removeemulation")
+
+	// Showemulation lists the qdiscs applied to a container, payload will be ContainerNetwork
+	Showemulation = command.OrderType("showemulation")
+
+	// Scheduleemulation applies network emulation which changes over time, payload will be EmulationSchedule
+	Scheduleemulation = command.OrderType("scheduleemulation")
+
+	// Cancelemulationschedule stops a schedule and removes its emulation, payload will be SimpleName
+	Cancelemulationschedule = command.OrderType("cancelemulationschedule")
+
+	// Partitionnetwork splits containers into groups which cannot reach each other, payload will be Partition
+	Partitionnetwork = command.OrderType("partitionnetwork")
+
+	// Healpartition lets the containers of a partition reach each other again, payload will be Partition
+	Healpartition = command.OrderType("healpartition")
+
+	// Pausecontainer freezes all of the processes of a container, payload will be SimpleName
+	Pausecontainer = command.OrderType("pausecontainer")
+
+	// Unpausecontainer resumes a paused container, payload will be SimpleName
+	Unpausecontainer = command.OrderType("unpausecontainer")
+
+	// Killcontainer sends a signal to the main process of a container, payload will be KillContainer
+	Killcontainer = command.OrderType("killcontainer")
+
+	// Restartcontainer stops a container and starts it again after a delay, payload will be RestartContainer
+	Restartcontainer = command.OrderType("restartcontainer")
+
+	// Updatecontainer changes the resource limits of a running container, payload will be UpdateResources
+	Updatecontainer = command.OrderType("updatecontainer")
+
+	// Stresscontainer puts cpu and io load on a container, payload will be Stress
+	Stresscontainer = command.OrderType("stresscontainer")
+
+	// Createskewedcontainer creates a container whose clock can be shifted, payload will be SkewedContainer
+	Createskewedcontainer = command.OrderType("createskewedcontainer")
+
+	// Changeclock changes the clock skew of a container, payload will be ContainerClock
+	Changeclock = command.OrderType("changeclock")
+
+	// Leaveswarm makes the hosts leave the swarm they are in, payload will be SetupSwarm
+	Leaveswarm = command.OrderType("leaveswarm")
+
+	// Createservice creates a swarm service, payload will be SwarmService
+	Createservice = command.OrderType("createservice")
+
+	// Updateservice replaces the container of a swarm service with a new one, payload will be SwarmService
+	Updateservice = command.OrderType("updateservice")
+
+	// Scaleservice changes how many tasks a swarm service runs, payload will be ScaleService
+	Scaleservice = command.OrderType("scaleservice")
+
+	// Removeservice removes a swarm service along with its tasks, payload will be SimpleName
+	Removeservice = command.OrderType("removeservice")
+
+	// Removevolumeshare removes what the volume share put on the hosts, payload will be VolumeShare
+	Removevolumeshare = command.OrderType("removevolumeshare")
+
+	// Resizevolume adds a global volume to more hosts or removes it from some, payload will be VolumeResize
+	Resizevolume = command.OrderType("resizevolume")
+
+	// Volumestatus gives the state of a global volume, payload will be Volume
+	Volumestatus = command.OrderType("volumestatus")
+
+	// Exportvolume archives the contents of a volume to the file source, payload will be VolumeExport
+	Exportvolume = command.OrderType("exportvolume")
+)
@@ -0,0 +1,25 @@
+/**
+ * Copyright 2019 Whiteblock Inc. All rights reserved.
+ * Use of this source code is governed by a BSD-style
+ * license that can be found in the LICENSE file.
+ */
+
+package entity
+
+// Partition represents containers split into groups, where the containers of a group
+// can only reach each other. Traffic to anything outside of the partition is unaffected.
+type Partition struct {
+	// Network is the network the containers are partitioned on
+	Network string `json:"network"`
+	// Groups are the names of the containers in each group
+	Groups [][]string `json:"groups"`
+}
+
+// Containers gives the names of all of the containers in the partition
+func (p Partition) Containers() []string {
+	out := []string{}
+	for _, group := range p.Groups {
+		out = append(out, group...)
+	}
+	return out
+}
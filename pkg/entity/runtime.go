@@ -0,0 +1,44 @@
+/**
+ * Copyright 2019 Whiteblock Inc. All rights reserved.
+ * Use of this source code is governed by a BSD-style
+ * license that can be found in the LICENSE file.
+ */
+
+package entity
+
+import (
+	"errors"
+	"fmt"
+)
+
+const (
+	// RuntimeKey is the key in the meta of a command which selects the container runtime
+	// of the host it is executed on
+	RuntimeKey = "runtime"
+
+	// DockerRuntime is the docker engine
+	DockerRuntime = "docker"
+
+	// PodmanRuntime is podman, through its docker compatible API
+	PodmanRuntime = "podman"
+
+	// ContainerdRuntime is containerd, driven through nerdctl
+	ContainerdRuntime = "containerd"
+)
+
+// NotSupportedError is given back when a container runtime is asked to do something
+// it cannot do, such as joining a swarm on podman. Retrying the command will not help.
+type NotSupportedError struct {
+	Runtime   string
+	Operation string
+}
+
+func (nse NotSupportedError) Error() string {
+	return fmt.Sprintf("%s is not supported by the %s runtime", nse.Operation, nse.Runtime)
+}
+
+// IsNotSupported checks whether the error is from the runtime not supporting the operation
+func IsNotSupported(err error) bool {
+	var nse NotSupportedError
+	return errors.As(err, &nse)
+}
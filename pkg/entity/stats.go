@@ -0,0 +1,198 @@
+/**
+ * Copyright 2019 Whiteblock Inc. All rights reserved.
+ * Use of this source code is governed by a BSD-style
+ * license that can be found in the LICENSE file.
+ */
+
+package entity
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+	"time"
+
+	"github.com/docker/docker/api/types"
+)
+
+// ContainerStats is a sample of the resource usage of a container of a test
+type ContainerStats struct {
+	TestID    string    `json:"testID"`
+	Host      string    `json:"host"`
+	Container string    `json:"container"`
+	Time      time.Time `json:"time"`
+
+	// CPUPercent is the share of one cpu the container used since the last sample, it may go
+	// above 100 when the container uses more than one cpu
+	CPUPercent  float64 `json:"cpuPercent"`
+	MemoryBytes uint64  `json:"memoryBytes"`
+	MemoryLimit uint64  `json:"memoryLimit"`
+
+	// The network and block io are the totals since the container started
+	NetworkRxBytes  uint64 `json:"networkRxBytes"`
+	NetworkTxBytes  uint64 `json:"networkTxBytes"`
+	BlockReadBytes  uint64 `json:"blockReadBytes"`
+	BlockWriteBytes uint64 `json:"blockWriteBytes"`
+}
+
+// NewContainerStats creates a sample from the stats docker gives for the container
+func NewContainerStats(stats types.StatsJSON) ContainerStats {
+	out := ContainerStats{
+		Time:        stats.Read,
+		MemoryBytes: stats.MemoryStats.Usage - stats.MemoryStats.Stats["cache"],
+		MemoryLimit: stats.MemoryStats.Limit,
+	}
+	if stats.MemoryStats.Stats["cache"] > stats.MemoryStats.Usage {
+		out.MemoryBytes = stats.MemoryStats.Usage
+	}
+
+	cpuDelta := float64(stats.CPUStats.CPUUsage.TotalUsage) - float64(stats.PreCPUStats.CPUUsage.TotalUsage)
+	systemDelta := float64(stats.CPUStats.SystemUsage) - float64(stats.PreCPUStats.SystemUsage)
+	cpus := float64(stats.CPUStats.OnlineCPUs)
+	if cpus == 0 {
+		cpus = float64(len(stats.CPUStats.CPUUsage.PercpuUsage))
+	}
+	if cpuDelta > 0 && systemDelta > 0 {
+		out.CPUPercent = cpuDelta / systemDelta * cpus * 100
+	}
+
+	for _, network := range stats.Networks {
+		out.NetworkRxBytes += network.RxBytes
+		out.NetworkTxBytes += network.TxBytes
+	}
+	for _, entry := range stats.BlkioStats.IoServiceBytesRecursive {
+		switch entry.Op {
+		case "Read", "read":
+			out.BlockReadBytes += entry.Value
+		case "Write", "write":
+			out.BlockWriteBytes += entry.Value
+		}
+	}
+	return out
+}
+
+// StatSummary summarizes the values of a measure over the samples
+type StatSummary struct {
+	P50 float64 `json:"p50"`
+	P95 float64 `json:"p95"`
+	Max float64 `json:"max"`
+}
+
+// NewStatSummary summarizes the values, with the nearest rank percentiles
+func NewStatSummary(values []float64) StatSummary {
+	if len(values) == 0 {
+		return StatSummary{}
+	}
+	sorted := append([]float64{}, values...)
+	sort.Float64s(sorted)
+	rank := func(pct float64) float64 {
+		return sorted[int(math.Ceil(pct/100*float64(len(sorted))))-1]
+	}
+	return StatSummary{P50: rank(50), P95: rank(95), Max: sorted[len(sorted)-1]}
+}
+
+// ContainerStatsSummary summarizes the resource usage of a container over a test. The network
+// and block io are rates, in bytes per second between samples.
+type ContainerStatsSummary struct {
+	Host    string `json:"host"`
+	Samples int    `json:"samples"`
+
+	CPUPercent     StatSummary `json:"cpuPercent"`
+	MemoryBytes    StatSummary `json:"memoryBytes"`
+	NetworkRxRate  StatSummary `json:"networkRxRate"`
+	NetworkTxRate  StatSummary `json:"networkTxRate"`
+	BlockReadRate  StatSummary `json:"blockReadRate"`
+	BlockWriteRate StatSummary `json:"blockWriteRate"`
+}
+
+// TestStatsSummary summarizes the resource usage of the containers of a test once it is over
+type TestStatsSummary struct {
+	TestID     string                           `json:"testID"`
+	Containers map[string]ContainerStatsSummary `json:"containers"`
+}
+
+// StatsReservoirSize is the most values of each measure of a container which are kept to
+// estimate the percentiles, beyond it a uniform sample of them is kept
+const StatsReservoirSize = 1024
+
+// statReservoir keeps a uniform sample of the values of a measure, and the max of all of them
+type statReservoir struct {
+	seen   int
+	max    float64
+	values []float64
+}
+
+func (sr *statReservoir) add(val float64) {
+	sr.seen++
+	if sr.seen == 1 || val > sr.max {
+		sr.max = val
+	}
+	if len(sr.values) < StatsReservoirSize {
+		sr.values = append(sr.values, val)
+	} else if i := rand.Intn(sr.seen); i < StatsReservoirSize {
+		sr.values[i] = val
+	}
+}
+
+func (sr statReservoir) summary() StatSummary {
+	out := NewStatSummary(sr.values)
+	out.Max = sr.max
+	return out
+}
+
+// ContainerStatsAccumulator summarizes the samples of a container as they are taken, with a
+// bounded amount of them kept
+type ContainerStatsAccumulator struct {
+	host    string
+	samples int
+	last    ContainerStats
+
+	cpu, mem, rx, tx, read, write statReservoir
+}
+
+// Add adds the sample, the samples must be added in order
+func (csa *ContainerStatsAccumulator) Add(sample ContainerStats) {
+	rate := func(cur, prev uint64, secs float64) float64 {
+		if cur < prev { // the container was restarted, so its counters were too
+			return float64(cur) / secs
+		}
+		return float64(cur-prev) / secs
+	}
+	csa.cpu.add(sample.CPUPercent)
+	csa.mem.add(float64(sample.MemoryBytes))
+	if csa.samples > 0 {
+		prev := csa.last
+		if secs := sample.Time.Sub(prev.Time).Seconds(); secs > 0 {
+			csa.rx.add(rate(sample.NetworkRxBytes, prev.NetworkRxBytes, secs))
+			csa.tx.add(rate(sample.NetworkTxBytes, prev.NetworkTxBytes, secs))
+			csa.read.add(rate(sample.BlockReadBytes, prev.BlockReadBytes, secs))
+			csa.write.add(rate(sample.BlockWriteBytes, prev.BlockWriteBytes, secs))
+		}
+	}
+	csa.samples++
+	csa.host = sample.Host
+	csa.last = sample
+}
+
+// Summary summarizes the samples added so far
+func (csa ContainerStatsAccumulator) Summary() ContainerStatsSummary {
+	return ContainerStatsSummary{
+		Host:           csa.host,
+		Samples:        csa.samples,
+		CPUPercent:     csa.cpu.summary(),
+		MemoryBytes:    csa.mem.summary(),
+		NetworkRxRate:  csa.rx.summary(),
+		NetworkTxRate:  csa.tx.summary(),
+		BlockReadRate:  csa.read.summary(),
+		BlockWriteRate: csa.write.summary(),
+	}
+}
+
+// NewContainerStatsSummary summarizes the samples of a container, which must be in order
+func NewContainerStatsSummary(samples []ContainerStats) ContainerStatsSummary {
+	var acc ContainerStatsAccumulator
+	for _, sample := range samples {
+		acc.Add(sample)
+	}
+	return acc.Summary()
+}
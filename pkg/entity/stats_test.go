@@ -0,0 +1,116 @@
+/**
+ * Copyright 2019 Whiteblock Inc. All rights reserved.
+ * Use of this source code is governed by a BSD-style
+ * license that can be found in the LICENSE file.
+ */
+
+package entity
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewStatSummary(t *testing.T) {
+	var tests = []struct {
+		values   []float64
+		expected StatSummary
+	}{
+		{
+			values:   nil,
+			expected: StatSummary{},
+		},
+		{
+			values:   []float64{3},
+			expected: StatSummary{P50: 3, P95: 3, Max: 3},
+		},
+		{
+			values:   []float64{5, 1, 4, 2, 3},
+			expected: StatSummary{P50: 3, P95: 5, Max: 5},
+		},
+		{
+			values: []float64{20, 19, 18, 17, 16, 15, 14, 13, 12, 11,
+				10, 9, 8, 7, 6, 5, 4, 3, 2, 1},
+			expected: StatSummary{P50: 10, P95: 19, Max: 20},
+		},
+	}
+
+	for i, tt := range tests {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			assert.Equal(t, tt.expected, NewStatSummary(tt.values))
+		})
+	}
+}
+
+func TestNewContainerStats(t *testing.T) {
+	now := time.Now()
+	var stats types.StatsJSON
+	stats.Read = now
+	stats.CPUStats.CPUUsage.TotalUsage = 300
+	stats.CPUStats.SystemUsage = 2000
+	stats.CPUStats.OnlineCPUs = 2
+	stats.PreCPUStats.CPUUsage.TotalUsage = 100
+	stats.PreCPUStats.SystemUsage = 1000
+	stats.MemoryStats = types.MemoryStats{Usage: 1000, Limit: 4000, Stats: map[string]uint64{"cache": 200}}
+	stats.Networks = map[string]types.NetworkStats{
+		"eth0": {RxBytes: 10, TxBytes: 20},
+		"eth1": {RxBytes: 1, TxBytes: 2},
+	}
+	stats.BlkioStats.IoServiceBytesRecursive = []types.BlkioStatEntry{
+		{Op: "Read", Value: 100},
+		{Op: "Write", Value: 50},
+		{Op: "Total", Value: 150},
+	}
+
+	assert.Equal(t, ContainerStats{
+		Time:            now,
+		CPUPercent:      40,
+		MemoryBytes:     800,
+		MemoryLimit:     4000,
+		NetworkRxBytes:  11,
+		NetworkTxBytes:  22,
+		BlockReadBytes:  100,
+		BlockWriteBytes: 50,
+	}, NewContainerStats(stats))
+}
+
+func TestNewContainerStatsSummary(t *testing.T) {
+	start := time.Now()
+	samples := []ContainerStats{
+		{Host: "10.0.0.2", Time: start, CPUPercent: 10, MemoryBytes: 100, NetworkRxBytes: 0},
+		{Host: "10.0.0.2", Time: start.Add(2 * time.Second), CPUPercent: 30, MemoryBytes: 300,
+			NetworkRxBytes: 200, BlockWriteBytes: 20},
+		{Host: "10.0.0.2", Time: start.Add(4 * time.Second), CPUPercent: 20, MemoryBytes: 200,
+			NetworkRxBytes: 100, BlockWriteBytes: 60},
+	}
+
+	summary := NewContainerStatsSummary(samples)
+	assert.Equal(t, "10.0.0.2", summary.Host)
+	assert.Equal(t, 3, summary.Samples)
+	assert.Equal(t, StatSummary{P50: 20, P95: 30, Max: 30}, summary.CPUPercent)
+	assert.Equal(t, StatSummary{P50: 200, P95: 300, Max: 300}, summary.MemoryBytes)
+	assert.Equal(t, StatSummary{P50: 50, P95: 100, Max: 100}, summary.NetworkRxRate,
+		"the counters start over when the container restarts")
+	assert.Equal(t, StatSummary{P50: 10, P95: 20, Max: 20}, summary.BlockWriteRate)
+
+	assert.Equal(t, ContainerStatsSummary{}, NewContainerStatsSummary(nil))
+}
+
+func TestContainerStatsAccumulator(t *testing.T) {
+	start := time.Now()
+	var acc ContainerStatsAccumulator
+	for i := 0; i < 10*StatsReservoirSize; i++ {
+		acc.Add(ContainerStats{Host: "10.0.0.2", Time: start.Add(time.Duration(i) * time.Second),
+			MemoryBytes: uint64(i)})
+	}
+	assert.Len(t, acc.mem.values, StatsReservoirSize, "only a bounded amount of the samples are kept")
+
+	summary := acc.Summary()
+	assert.Equal(t, 10*StatsReservoirSize, summary.Samples)
+	assert.Equal(t, float64(10*StatsReservoirSize-1), summary.MemoryBytes.Max)
+	assert.InDelta(t, 5*StatsReservoirSize, summary.MemoryBytes.P50, StatsReservoirSize)
+}
@@ -0,0 +1,88 @@
+/**
+ * Copyright 2019 Whiteblock Inc. All rights reserved.
+ * Use of this source code is governed by a BSD-style
+ * license that can be found in the LICENSE file.
+ */
+
+package entity
+
+import (
+	"github.com/whiteblock/definition/command"
+)
+
+// Swarm is the setup of a docker swarm, with the options which Genesis supports on top of the definition
+type Swarm struct {
+	command.SetupSwarm
+	// Managers is how many of the hosts, starting from the first, are managers of the swarm.
+	// There is one manager if it is not given.
+	Managers int `json:"managers,omitempty"`
+	// Labels are the labels of the nodes, by the host they are on
+	Labels map[string]map[string]string `json:"labels,omitempty"`
+}
+
+// GetManagers gives the hosts which are managers of the swarm
+func (s Swarm) GetManagers() []string {
+	if s.Managers <= 0 {
+		return s.Hosts[:1]
+	}
+	return s.Hosts[:s.Managers]
+}
+
+// GetWorkers gives the hosts which are workers of the swarm
+func (s Swarm) GetWorkers() []string {
+	return s.Hosts[len(s.GetManagers()):]
+}
+
+// SwarmService is a service of a docker swarm, which runs replicas of the container across the
+// nodes of the swarm. The service is named after the container.
+type SwarmService struct {
+	command.Container
+	// Replicas is how many tasks of the container the service runs
+	Replicas uint64 `json:"replicas"`
+	// Constraints are the placement constraints of the tasks, such as node.labels.zone==east
+	Constraints []string `json:"constraints,omitempty"`
+	// Networks are the additional networks of the tasks, on top of the network of the container
+	Networks []string `json:"networks,omitempty"`
+	// UpdatePolicy is how the tasks are replaced when the service is updated
+	UpdatePolicy UpdatePolicy `json:"updatePolicy,omitempty"`
+}
+
+// GetNetworks gives the names of all of the networks of the tasks of the service
+func (ss SwarmService) GetNetworks() []string {
+	out := []string{}
+	if len(ss.Network) > 0 {
+		out = append(out, ss.Network)
+	}
+	return append(out, ss.Networks...)
+}
+
+// UpdatePolicy is how the tasks of a service are replaced when it is updated
+type UpdatePolicy struct {
+	// Parallelism is how many tasks are replaced at a time, all of them at once if it is 0
+	Parallelism uint64 `json:"parallelism,omitempty"`
+	// Delay is how long to wait between replacing each batch of tasks
+	Delay command.Duration `json:"delay,omitempty"`
+	// FailureAction is what to do when a task fails to be replaced, pause, continue or rollback
+	FailureAction string `json:"failureAction,omitempty"`
+	// Order is whether the old task is stopped first, stop-first, or the new one is started first,
+	// start-first
+	Order string `json:"order,omitempty"`
+}
+
+// ScaleService changes how many tasks a service runs
+type ScaleService struct {
+	// Name is the name of the service
+	Name string `json:"name"`
+	// Replicas is how many tasks of the container the service runs
+	Replicas uint64 `json:"replicas"`
+}
+
+// ServiceTask is the state of a task of a swarm service
+type ServiceTask struct {
+	ID           string `json:"id"`
+	Slot         int    `json:"slot"`
+	Node         string `json:"node,omitempty"`
+	State        string `json:"state"`
+	DesiredState string `json:"desiredState"`
+	Error        string `json:"error,omitempty"`
+}
@@ -0,0 +1,80 @@
+/**
+ * Copyright 2019 Whiteblock Inc. All rights reserved.
+ * Use of this source code is governed by a BSD-style
+ * license that can be found in the LICENSE file.
+ */
+
+package entity
+
+import (
+	"github.com/whiteblock/definition/command"
+)
+
+const (
+	// GlusterVolumeBackend replicates a global volume across its hosts with glusterfs
+	GlusterVolumeBackend = "gluster"
+
+	// NFSVolumeBackend serves a global volume from an nfs server on its first host
+	NFSVolumeBackend = "nfs"
+
+	// RsyncVolumeBackend copies a snapshot of a global volume from its first host to the others,
+	// which suits data that is read far more than it is written
+	RsyncVolumeBackend = "rsync"
+)
+
+// Volume is a docker volume, which is shared by its hosts if it is global
+type Volume struct {
+	command.Volume
+
+	// Backend is how a global volume is shared, the backend of the config is used if it is not given
+	Backend string `json:"backend,omitempty"`
+
+	// Seed is an archive from the file source which is extracted into the new volume. It may be
+	// a tar, or a gzipped tar if its name ends with .gz or .tgz.
+	Seed *command.File `json:"seed,omitempty"`
+}
+
+// VolumeShare prepares the hosts to share global volumes
+type VolumeShare struct {
+	command.VolumeShare
+
+	// Backend is which backend to prepare the hosts for, the backend of the config is used if
+	// it is not given
+	Backend string `json:"backend,omitempty"`
+}
+
+// VolumeResize changes which hosts a global volume is on
+type VolumeResize struct {
+	// Name is the name of the global volume
+	Name string `json:"name"`
+
+	// Backend is how the global volume is shared
+	Backend string `json:"backend,omitempty"`
+
+	// Add are the hosts to put the volume on. They must already be in the volume share.
+	Add []string `json:"add,omitempty"`
+
+	// Remove are the hosts to take the volume off of
+	Remove []string `json:"remove,omitempty"`
+}
+
+// SharedVolume is the state of a global volume which is not backed by gluster
+type SharedVolume struct {
+	Name    string `json:"name"`
+	Backend string `json:"backend"`
+
+	// Source is the host which holds the data which the other hosts see
+	Source string `json:"source"`
+
+	// Hosts is whether each of the hosts has the docker volume
+	Hosts map[string]bool `json:"hosts"`
+}
+
+// VolumeExport archives the contents of a volume to the file source
+type VolumeExport struct {
+	// Name is the name of the volume
+	Name string `json:"name"`
+
+	// File is where the gzipped tar of the volume goes in the file source
+	File command.File `json:"file"`
+}
@@ -0,0 +1,172 @@
+/**
+ * Copyright 2019 Whiteblock Inc. All rights reserved.
+ * Use of this source code is governed by a BSD-style
+ * license that can be found in the LICENSE file.
+ */
+
+package fake
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/streadway/amqp"
+	queue "github.com/whiteblock/amqp"
+	"github.com/whiteblock/amqp/config"
+	"github.com/whiteblock/amqp/externals"
+)
+
+// ErrClosed is returned when sending to a queue which has been closed
+var ErrClosed = errors.New("the queue is closed")
+
+// Queue is an in-memory AMQP queue. Everything sent to it is delivered to its consumer, and it
+// keeps track of which deliveries were acknowledged or rejected.
+type Queue struct {
+	conf       config.Config
+	mux        sync.Mutex
+	deliveries chan amqp.Delivery
+	tag        uint64
+	closed     bool
+	published  []amqp.Publishing
+	acked      []uint64
+	rejected   []uint64
+}
+
+var _ queue.AMQPService = (*Queue)(nil)
+
+// NewQueue creates a new in-memory queue, which holds up to size messages which have not been
+// consumed yet
+func NewQueue(name string, size int) *Queue {
+	return &Queue{
+		conf:       config.Config{QueueName: name},
+		deliveries: make(chan amqp.Delivery, size),
+	}
+}
+
+// Consume gives the deliveries of the queue
+func (q *Queue) Consume() (<-chan amqp.Delivery, error) {
+	return q.deliveries, nil
+}
+
+// Send puts the message on the queue
+func (q *Queue) Send(pub amqp.Publishing) error {
+	q.mux.Lock()
+	if q.closed {
+		q.mux.Unlock()
+		return ErrClosed
+	}
+	q.tag++
+	q.published = append(q.published, pub)
+	msg := amqp.Delivery{
+		Acknowledger:    q,
+		Headers:         pub.Headers,
+		ContentType:     pub.ContentType,
+		ContentEncoding: pub.ContentEncoding,
+		DeliveryMode:    pub.DeliveryMode,
+		Priority:        pub.Priority,
+		CorrelationId:   pub.CorrelationId,
+		ReplyTo:         pub.ReplyTo,
+		Expiration:      pub.Expiration,
+		MessageId:       pub.MessageId,
+		Timestamp:       pub.Timestamp,
+		Type:            pub.Type,
+		DeliveryTag:     q.tag,
+		RoutingKey:      q.conf.QueueName,
+		Body:            pub.Body,
+	}
+	q.mux.Unlock()
+	q.deliveries <- msg
+	return nil
+}
+
+// Requeue rejects the old message and puts the new one on the queue
+func (q *Queue) Requeue(oldMsg amqp.Delivery, newMsg amqp.Publishing) error {
+	err := oldMsg.Reject(false)
+	if err != nil {
+		return err
+	}
+	return q.Send(newMsg)
+}
+
+// CreateQueue does nothing, the queue always exists
+func (q *Queue) CreateQueue() error {
+	return nil
+}
+
+// CreateExchange does nothing, the queue only uses the default exchange
+func (q *Queue) CreateExchange() error {
+	return nil
+}
+
+// Channel is not available, the queue has no connection
+func (q *Queue) Channel() (externals.AMQPChannel, error) {
+	return nil, errors.New("the queue has no channel")
+}
+
+// Config returns the configuration of the queue
+func (q *Queue) Config() config.Config {
+	return q.conf
+}
+
+// Close stops the queue, which ends the consumption of it
+func (q *Queue) Close() {
+	q.mux.Lock()
+	defer q.mux.Unlock()
+	if q.closed {
+		return
+	}
+	q.closed = true
+	close(q.deliveries)
+}
+
+// Published gives all of the messages sent to the queue, in order
+func (q *Queue) Published() []amqp.Publishing {
+	q.mux.Lock()
+	defer q.mux.Unlock()
+	out := make([]amqp.Publishing, len(q.published))
+	copy(out, q.published)
+	return out
+}
+
+// Acked gives the tags of the deliveries which were acknowledged
+func (q *Queue) Acked() []uint64 {
+	q.mux.Lock()
+	defer q.mux.Unlock()
+	return append([]uint64{}, q.acked...)
+}
+
+// Rejected gives the tags of the deliveries which were rejected or not acknowledged
+func (q *Queue) Rejected() []uint64 {
+	q.mux.Lock()
+	defer q.mux.Unlock()
+	return append([]uint64{}, q.rejected...)
+}
+
+// Ack acknowledges the delivery
+func (q *Queue) Ack(tag uint64, multiple bool) error {
+	q.mux.Lock()
+	defer q.mux.Unlock()
+	q.acked = append(q.acked, tag)
+	return nil
+}
+
+// Nack negatively acknowledges the delivery, putting it back on the queue if asked to
+func (q *Queue) Nack(tag uint64, multiple bool, requeue bool) error {
+	return q.Reject(tag, requeue)
+}
+
+// Reject rejects the delivery, putting it back on the queue if asked to
+func (q *Queue) Reject(tag uint64, requeue bool) error {
+	q.mux.Lock()
+	q.rejected = append(q.rejected, tag)
+	if !requeue {
+		q.mux.Unlock()
+		return nil
+	}
+	var pub amqp.Publishing
+	if tag > 0 && int(tag) <= len(q.published) {
+		pub = q.published[tag-1]
+	}
+	q.mux.Unlock()
+	return q.Send(pub)
+}
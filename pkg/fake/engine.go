@@ -0,0 +1,1556 @@
+/**
+ * Copyright 2019 Whiteblock Inc. All rights reserved.
+ * Use of this source code is governed by a BSD-style
+ * license that can be found in the LICENSE file.
+ */
+
+package fake
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/whiteblock/genesis/pkg/entity"
+
+	"github.com/docker/distribution/reference"
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/events"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/api/types/mount"
+	"github.com/docker/docker/api/types/network"
+	timetypes "github.com/docker/docker/api/types/time"
+	"github.com/docker/docker/api/types/volume"
+	"github.com/docker/docker/pkg/stdcopy"
+)
+
+// Process is what runs in a fake container, either as its main process or as an exec. It is
+// given the container and the command, and gives back the exit code and the output.
+type Process func(container string, cmd []string) (int, []byte)
+
+// defaultDirs are the directories which every container starts out with
+var defaultDirs = []string{"/", "/bin", "/etc", "/home", "/opt", "/root", "/tmp", "/usr", "/var"}
+
+type fakeContainer struct {
+	id         string
+	name       string
+	created    time.Time
+	config     container.Config
+	hostConfig container.HostConfig
+	networks   map[string]*network.EndpointSettings
+	running    bool
+	paused     bool
+	exitCode   int
+	logs       []byte
+	dirs       map[string]bool
+	files      map[string][]byte
+	execs      [][]string
+	exited     chan struct{}
+	stats      types.StatsJSON
+	oomKilled  bool
+	health     string
+}
+
+// eventSubscriber is a stream of events which was asked for with Events
+type eventSubscriber struct {
+	filters filters.Args
+	msgs    chan events.Message
+	errs    chan error
+}
+
+// subscriptionWaiter is waiting for the engine to have some amount of streams of events open
+type subscriptionWaiter struct {
+	streams int
+	done    chan struct{}
+}
+
+type fakeNetwork struct {
+	resource types.NetworkResource
+}
+
+type fakeExec struct {
+	container string
+	cmd       []string
+	started   bool
+	exitCode  int
+	output    []byte
+}
+
+// Engine is an in-memory docker engine. It keeps the containers, networks, volumes and images
+// which are created through it, and gives back the same errors as the docker daemon, so that
+// the service can be tested end-to-end without docker.
+type Engine struct {
+	host       string
+	mux        sync.Mutex
+	containers map[string]*fakeContainer
+	networks   map[string]*fakeNetwork
+	volumes    map[string]*types.Volume
+	images     map[string]types.ImageSummary
+	execs      map[string]*fakeExec
+	programs   map[string]Process
+	commands   map[string]Process
+	failures   map[string][]error
+	events     map[*eventSubscriber]bool
+	history    []events.Message
+	waiters    []subscriptionWaiter
+	cluster    *cluster
+	nodeID     string
+	cpus       int
+	memory     int64
+}
+
+var _ entity.Client = (*Engine)(nil)
+
+// NewEngine creates a new, empty, fake engine for the given host, with the default networks
+// which docker creates
+func NewEngine(host string) *Engine {
+	e := &Engine{
+		host:       host,
+		containers: map[string]*fakeContainer{},
+		networks:   map[string]*fakeNetwork{},
+		volumes:    map[string]*types.Volume{},
+		images:     map[string]types.ImageSummary{},
+		execs:      map[string]*fakeExec{},
+		programs:   map[string]Process{},
+		commands:   map[string]Process{},
+		failures:   map[string][]error{},
+		events:     map[*eventSubscriber]bool{},
+		cpus:       4,
+		memory:     8 << 30,
+	}
+	for _, name := range []string{"bridge", "host", "none"} {
+		driver := name
+		if name == "none" {
+			driver = "null"
+		}
+		id := newID()
+		e.networks[id] = &fakeNetwork{resource: types.NetworkResource{
+			Name:       name,
+			ID:         id,
+			Created:    time.Now(),
+			Scope:      "local",
+			Driver:     driver,
+			Containers: map[string]types.EndpointResource{},
+			Options:    map[string]string{},
+			Labels:     map[string]string{},
+		}}
+	}
+	return e
+}
+
+func newID() string {
+	buf := make([]byte, 32)
+	rand.Read(buf)
+	return hex.EncodeToString(buf)
+}
+
+// OnRun sets the main process of the containers of the given image. Without one, containers
+// run until they are stopped.
+func (e *Engine) OnRun(image string, proc Process) {
+	e.mux.Lock()
+	defer e.mux.Unlock()
+	e.programs[normalizeImage(image)] = proc
+}
+
+// OnExec sets what happens when the given binary is executed in a container. Without one,
+// commands succeed with no output.
+func (e *Engine) OnExec(binary string, proc Process) {
+	e.mux.Lock()
+	defer e.mux.Unlock()
+	e.commands[binary] = proc
+}
+
+// FailNext makes the next call to the given method of the client return the error
+func (e *Engine) FailNext(method string, err error) {
+	e.mux.Lock()
+	defer e.mux.Unlock()
+	e.failures[method] = append(e.failures[method], err)
+}
+
+// AddImage makes the engine have the given image, as if it was already pulled
+func (e *Engine) AddImage(image string) {
+	e.mux.Lock()
+	defer e.mux.Unlock()
+	e.addImage(image)
+}
+
+// SetResources sets the cpus and memory which the engine reports the host to have
+func (e *Engine) SetResources(cpus int, memory int64) {
+	e.mux.Lock()
+	defer e.mux.Unlock()
+	e.cpus = cpus
+	e.memory = memory
+}
+
+// Crash makes the main process of the container exit with the code, as if it failed on its own.
+// An out of memory kill is announced with an oom event first, as docker does.
+func (e *Engine) Crash(containerName string, code int, oom bool) error {
+	e.mux.Lock()
+	defer e.mux.Unlock()
+	cntr, err := e.container(containerName)
+	if err != nil {
+		return err
+	}
+	if !cntr.running {
+		return fmt.Errorf("container %s is not running", containerName)
+	}
+	cntr.oomKilled = oom
+	if oom {
+		e.emit(cntr, "oom", nil)
+	}
+	e.exit(cntr, code)
+	return nil
+}
+
+// SetHealth sets the health status of the container, and announces it with a health_status event
+func (e *Engine) SetHealth(containerName string, health string) error {
+	e.mux.Lock()
+	defer e.mux.Unlock()
+	cntr, err := e.container(containerName)
+	if err != nil {
+		return err
+	}
+	cntr.health = health
+	e.emit(cntr, "health_status: "+health, nil)
+	return nil
+}
+
+// Execs gives the commands which were executed in the container, in order
+func (e *Engine) Execs(containerName string) [][]string {
+	e.mux.Lock()
+	defer e.mux.Unlock()
+	cntr, err := e.container(containerName)
+	if err != nil {
+		return nil
+	}
+	out := make([][]string, len(cntr.execs))
+	copy(out, cntr.execs)
+	return out
+}
+
+// SetStats sets the stats which the engine gives for the container
+func (e *Engine) SetStats(containerName string, stats types.StatsJSON) error {
+	e.mux.Lock()
+	defer e.mux.Unlock()
+	cntr, err := e.container(containerName)
+	if err != nil {
+		return err
+	}
+	cntr.stats = stats
+	return nil
+}
+
+// File gives the contents of the file in the container, if it is there
+func (e *Engine) File(containerName, filePath string) ([]byte, bool) {
+	e.mux.Lock()
+	defer e.mux.Unlock()
+	cntr, err := e.container(containerName)
+	if err != nil {
+		return nil, false
+	}
+	data, ok := cntr.files[path.Clean(filePath)]
+	return data, ok
+}
+
+// injected pops the failure set for the method, if there is one. The lock must be held.
+func (e *Engine) injected(method string) error {
+	errs := e.failures[method]
+	if len(errs) == 0 {
+		return nil
+	}
+	e.failures[method] = errs[1:]
+	return errs[0]
+}
+
+func normalizeImage(image string) string {
+	ref, err := reference.ParseNormalizedNamed(image)
+	if err != nil {
+		return image
+	}
+	return reference.TagNameOnly(ref).String()
+}
+
+func (e *Engine) addImage(image string) {
+	name := normalizeImage(image)
+	if _, ok := e.images[name]; ok {
+		return
+	}
+	e.images[name] = types.ImageSummary{
+		ID:       "sha256:" + newID(),
+		Created:  time.Now().Unix(),
+		RepoTags: []string{name},
+		Labels:   map[string]string{},
+	}
+}
+
+// container finds the container by its name, its id, or a unique prefix of its id. The lock
+// must be held.
+func (e *Engine) container(ref string) (*fakeContainer, error) {
+	name := strings.TrimPrefix(ref, "/")
+	if cntr, ok := e.containers[name]; ok {
+		return cntr, nil
+	}
+	var found *fakeContainer
+	for _, cntr := range e.containers {
+		if cntr.name == name {
+			return cntr, nil
+		}
+		if len(name) > 0 && strings.HasPrefix(cntr.id, name) {
+			if found != nil {
+				return nil, fmt.Errorf("Error response from daemon: multiple IDs found with provided prefix: %s", ref)
+			}
+			found = cntr
+		}
+	}
+	if found == nil {
+		return nil, fmt.Errorf("Error: No such container: %s", ref)
+	}
+	return found, nil
+}
+
+// network finds the network by its name, its id, or a unique prefix of its id. The lock must
+// be held.
+func (e *Engine) network(ref string) (*fakeNetwork, error) {
+	if net, ok := e.networks[ref]; ok {
+		return net, nil
+	}
+	var found *fakeNetwork
+	for _, net := range e.networks {
+		if net.resource.Name == ref {
+			return net, nil
+		}
+		if len(ref) > 0 && strings.HasPrefix(net.resource.ID, ref) {
+			found = net
+		}
+	}
+	if found == nil {
+		return nil, fmt.Errorf("Error: No such network: %s", ref)
+	}
+	return found, nil
+}
+
+func (e *Engine) hasDir(cntr *fakeContainer, dir string) bool {
+	dir = path.Clean(dir)
+	if cntr.dirs[dir] {
+		return true
+	}
+	for file := range cntr.files {
+		if strings.HasPrefix(file, dir+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// exit marks the container as no longer running. The lock must be held.
+func (e *Engine) exit(cntr *fakeContainer, code int) {
+	if !cntr.running {
+		return
+	}
+	cntr.running = false
+	cntr.paused = false
+	cntr.exitCode = code
+	close(cntr.exited)
+	e.emit(cntr, "die", map[string]string{"exitCode": fmt.Sprint(code)})
+	if cntr.hostConfig.AutoRemove {
+		e.remove(cntr)
+	}
+}
+
+// remove gets rid of the container and its endpoints. The lock must be held.
+func (e *Engine) remove(cntr *fakeContainer) {
+	for netName := range cntr.networks {
+		if net, err := e.network(netName); err == nil {
+			delete(net.resource.Containers, cntr.id)
+		}
+	}
+	delete(e.containers, cntr.id)
+}
+
+// emit sends the event of the container to the streams which it matches. A stream which has
+// fallen behind misses the event. The event is kept for the streams which ask for the events
+// since some time. The lock must be held.
+func (e *Engine) emit(cntr *fakeContainer, action string, attributes map[string]string) {
+	attrs := map[string]string{"name": cntr.name, "image": cntr.config.Image}
+	for key, value := range cntr.config.Labels {
+		attrs[key] = value
+	}
+	for key, value := range attributes {
+		attrs[key] = value
+	}
+	now := time.Now()
+	msg := events.Message{
+		Status:   action,
+		ID:       cntr.id,
+		From:     cntr.config.Image,
+		Type:     events.ContainerEventType,
+		Action:   action,
+		Actor:    events.Actor{ID: cntr.id, Attributes: attrs},
+		Scope:    "local",
+		Time:     now.Unix(),
+		TimeNano: now.UnixNano(),
+	}
+	e.history = append(e.history, msg)
+	for sub := range e.events {
+		if !eventMatches(sub.filters, msg) {
+			continue
+		}
+		select {
+		case sub.msgs <- msg:
+		default:
+		}
+	}
+}
+
+// eventMatches checks the event against the filters, an event filter also matches the actions
+// which carry a status, such as "health_status: unhealthy" for "health_status"
+func eventMatches(args filters.Args, msg events.Message) bool {
+	if args.Contains("type") && !args.ExactMatch("type", string(msg.Type)) {
+		return false
+	}
+	if args.Contains("event") && !args.ExactMatch("event", msg.Action) &&
+		!args.ExactMatch("event", strings.SplitN(msg.Action, ":", 2)[0]) {
+		return false
+	}
+	if args.Contains("container") && !args.ExactMatch("container", msg.Actor.Attributes["name"]) &&
+		!args.ExactMatch("container", msg.ID) {
+		return false
+	}
+	return args.MatchKVList("label", msg.Actor.Attributes)
+}
+
+// connect adds the container to the network. The lock must be held.
+func (e *Engine) connect(cntr *fakeContainer, net *fakeNetwork, config *network.EndpointSettings) {
+	settings := &network.EndpointSettings{}
+	if config != nil {
+		copied := *config
+		settings = &copied
+	}
+	settings.NetworkID = net.resource.ID
+	settings.EndpointID = newID()
+	if settings.IPAMConfig != nil && len(settings.IPAddress) == 0 {
+		settings.IPAddress = settings.IPAMConfig.IPv4Address
+		settings.GlobalIPv6Address = settings.IPAMConfig.IPv6Address
+	}
+	cntr.networks[net.resource.Name] = settings
+	net.resource.Containers[cntr.id] = types.EndpointResource{
+		Name:        cntr.name,
+		EndpointID:  settings.EndpointID,
+		MacAddress:  settings.MacAddress,
+		IPv4Address: settings.IPAddress,
+		IPv6Address: settings.GlobalIPv6Address,
+	}
+}
+
+// matches checks the labels and the name against the filters given
+func matches(args filters.Args, name string, labels map[string]string) bool {
+	if args.Contains("name") && !args.Match("name", name) {
+		return false
+	}
+	return args.MatchKVList("label", labels)
+}
+
+// Close the transport used by the client
+func (e *Engine) Close() error {
+	return nil
+}
+
+// ContainerAttach attaches to the output of the container
+func (e *Engine) ContainerAttach(ctx context.Context, containerName string,
+	options types.ContainerAttachOptions) (types.HijackedResponse, error) {
+	e.mux.Lock()
+	defer e.mux.Unlock()
+	if err := e.injected("ContainerAttach"); err != nil {
+		return types.HijackedResponse{}, err
+	}
+	cntr, err := e.container(containerName)
+	if err != nil {
+		return types.HijackedResponse{}, err
+	}
+	return hijacked(cntr.logs), nil
+}
+
+func hijacked(output []byte) types.HijackedResponse {
+	conn, other := net.Pipe()
+	other.Close()
+	buf := new(bytes.Buffer)
+	stdcopy.NewStdWriter(buf, stdcopy.Stdout).Write(output)
+	return types.HijackedResponse{Conn: conn, Reader: bufio.NewReader(buf)}
+}
+
+// ContainerCreate creates a new container, with the image which must already be on the engine
+func (e *Engine) ContainerCreate(ctx context.Context, config *container.Config, hostConfig *container.HostConfig,
+	networkingConfig *network.NetworkingConfig, containerName string) (container.ContainerCreateCreatedBody, error) {
+	e.mux.Lock()
+	defer e.mux.Unlock()
+	if err := e.injected("ContainerCreate"); err != nil {
+		return container.ContainerCreateCreatedBody{}, err
+	}
+	if config == nil {
+		config = &container.Config{}
+	}
+	if hostConfig == nil {
+		hostConfig = &container.HostConfig{}
+	}
+	if _, ok := e.images[normalizeImage(config.Image)]; !ok {
+		return container.ContainerCreateCreatedBody{}, fmt.Errorf("Error: No such image: %s", config.Image)
+	}
+	id := newID()
+	if len(containerName) == 0 {
+		containerName = id[:12]
+	}
+	if existing, err := e.container(containerName); err == nil && existing.name == containerName {
+		return container.ContainerCreateCreatedBody{}, fmt.Errorf("Error response from daemon: Conflict. "+
+			"The container name \"/%s\" is already in use by container \"%s\". You have to remove "+
+			"(or rename) that container to be able to reuse that name.", containerName, existing.id)
+	}
+	endpoints := map[string]*network.EndpointSettings{"bridge": nil}
+	if networkingConfig != nil && len(networkingConfig.EndpointsConfig) > 0 {
+		endpoints = networkingConfig.EndpointsConfig
+	}
+	nets := map[string]*fakeNetwork{}
+	for name := range endpoints {
+		net, err := e.network(name)
+		if err != nil {
+			return container.ContainerCreateCreatedBody{},
+				fmt.Errorf("Error response from daemon: network %s not found", name)
+		}
+		nets[name] = net
+	}
+	for _, mnt := range hostConfig.Mounts {
+		if mnt.Type != mount.TypeVolume || len(mnt.Source) == 0 {
+			continue
+		}
+		if _, ok := e.volumes[mnt.Source]; !ok {
+			e.volumes[mnt.Source] = &types.Volume{Name: mnt.Source, Driver: "local", Scope: "local",
+				Mountpoint: "/var/lib/docker/volumes/" + mnt.Source + "/_data", Labels: map[string]string{}}
+		}
+	}
+
+	cntr := &fakeContainer{
+		id:         id,
+		name:       containerName,
+		created:    time.Now(),
+		config:     *config,
+		hostConfig: *hostConfig,
+		networks:   map[string]*network.EndpointSettings{},
+		dirs:       map[string]bool{},
+		files:      map[string][]byte{},
+		exited:     make(chan struct{}),
+	}
+	for _, dir := range defaultDirs {
+		cntr.dirs[dir] = true
+	}
+	for name, settings := range endpoints {
+		e.connect(cntr, nets[name], settings)
+	}
+	e.containers[id] = cntr
+	return container.ContainerCreateCreatedBody{ID: id}, nil
+}
+
+// ContainerExecAttach runs the exec process and attaches to its output
+func (e *Engine) ContainerExecAttach(ctx context.Context, execID string,
+	config types.ExecStartCheck) (types.HijackedResponse, error) {
+	err := e.ContainerExecStart(ctx, execID, config)
+	if err != nil {
+		return types.HijackedResponse{}, err
+	}
+	e.mux.Lock()
+	defer e.mux.Unlock()
+	return hijacked(e.execs[execID].output), nil
+}
+
+// ContainerExecCreate creates a new exec process in a running container
+func (e *Engine) ContainerExecCreate(ctx context.Context, containerName string,
+	config types.ExecConfig) (types.IDResponse, error) {
+	e.mux.Lock()
+	defer e.mux.Unlock()
+	if err := e.injected("ContainerExecCreate"); err != nil {
+		return types.IDResponse{}, err
+	}
+	cntr, err := e.container(containerName)
+	if err != nil {
+		return types.IDResponse{}, err
+	}
+	if !cntr.running {
+		return types.IDResponse{}, fmt.Errorf("Error response from daemon: Container %s is not running", cntr.id)
+	}
+	if cntr.paused {
+		return types.IDResponse{}, fmt.Errorf("Error response from daemon: Container %s is paused, "+
+			"unpause the container before exec", cntr.id)
+	}
+	id := newID()
+	e.execs[id] = &fakeExec{container: cntr.id, cmd: config.Cmd}
+	return types.IDResponse{ID: id}, nil
+}
+
+// ContainerExecInspect returns information about the exec process
+func (e *Engine) ContainerExecInspect(ctx context.Context, execID string) (types.ContainerExecInspect, error) {
+	e.mux.Lock()
+	defer e.mux.Unlock()
+	if err := e.injected("ContainerExecInspect"); err != nil {
+		return types.ContainerExecInspect{}, err
+	}
+	exec, ok := e.execs[execID]
+	if !ok {
+		return types.ContainerExecInspect{}, fmt.Errorf("Error: No such exec instance: %s", execID)
+	}
+	return types.ContainerExecInspect{
+		ExecID:      execID,
+		ContainerID: exec.container,
+		Running:     false,
+		ExitCode:    exec.exitCode,
+	}, nil
+}
+
+// ContainerExecStart runs the exec process to completion
+func (e *Engine) ContainerExecStart(ctx context.Context, execID string, config types.ExecStartCheck) error {
+	e.mux.Lock()
+	if err := e.injected("ContainerExecStart"); err != nil {
+		e.mux.Unlock()
+		return err
+	}
+	exec, ok := e.execs[execID]
+	if !ok {
+		e.mux.Unlock()
+		return fmt.Errorf("Error response from daemon: No such exec instance: %s", execID)
+	}
+	if exec.started {
+		e.mux.Unlock()
+		return fmt.Errorf("Error response from daemon: Error: Exec command %s has already run", execID)
+	}
+	exec.started = true
+	cntr, err := e.container(exec.container)
+	if err != nil {
+		e.mux.Unlock()
+		return err
+	}
+	cntr.execs = append(cntr.execs, exec.cmd)
+	var proc Process
+	if len(exec.cmd) > 0 {
+		proc = e.commands[exec.cmd[0]]
+	}
+	name := cntr.name
+	e.mux.Unlock()
+
+	if proc == nil {
+		return nil
+	}
+	code, output := proc(name, exec.cmd)
+
+	e.mux.Lock()
+	defer e.mux.Unlock()
+	exec.exitCode = code
+	exec.output = output
+	return nil
+}
+
+// ContainerInspect returns the container information
+func (e *Engine) ContainerInspect(ctx context.Context, containerName string) (types.ContainerJSON, error) {
+	e.mux.Lock()
+	defer e.mux.Unlock()
+	if err := e.injected("ContainerInspect"); err != nil {
+		return types.ContainerJSON{}, err
+	}
+	cntr, err := e.container(containerName)
+	if err != nil {
+		return types.ContainerJSON{}, err
+	}
+	hostConfig := cntr.hostConfig
+	config := cntr.config
+	networks := map[string]*network.EndpointSettings{}
+	for name, settings := range cntr.networks {
+		copied := *settings
+		networks[name] = &copied
+	}
+	return types.ContainerJSON{
+		ContainerJSONBase: &types.ContainerJSONBase{
+			ID:      cntr.id,
+			Created: cntr.created.Format(time.RFC3339Nano),
+			Name:    "/" + cntr.name,
+			Image:   normalizeImage(cntr.config.Image),
+			State: &types.ContainerState{
+				Status:    status(cntr),
+				Running:   cntr.running,
+				Paused:    cntr.paused,
+				ExitCode:  cntr.exitCode,
+				OOMKilled: cntr.oomKilled,
+				Health:    health(cntr),
+			},
+			HostConfig: &hostConfig,
+		},
+		Config:          &config,
+		NetworkSettings: &types.NetworkSettings{Networks: networks},
+	}, nil
+}
+
+func health(cntr *fakeContainer) *types.Health {
+	if len(cntr.health) == 0 {
+		return nil
+	}
+	return &types.Health{Status: cntr.health}
+}
+
+func status(cntr *fakeContainer) string {
+	switch {
+	case cntr.paused:
+		return "paused"
+	case cntr.running:
+		return "running"
+	}
+	select {
+	case <-cntr.exited:
+		return "exited"
+	default:
+		return "created"
+	}
+}
+
+// ContainerKill kills the main process of the container
+func (e *Engine) ContainerKill(ctx context.Context, containerName, signal string) error {
+	e.mux.Lock()
+	defer e.mux.Unlock()
+	if err := e.injected("ContainerKill"); err != nil {
+		return err
+	}
+	cntr, err := e.container(containerName)
+	if err != nil {
+		return err
+	}
+	if !cntr.running {
+		return fmt.Errorf("Error response from daemon: Cannot kill container: %s: Container %s is not running",
+			containerName, cntr.id)
+	}
+	e.emit(cntr, "kill", map[string]string{"signal": "9"})
+	e.exit(cntr, 137)
+	return nil
+}
+
+// ContainerList returns the containers, only the running ones unless all of them are asked for
+func (e *Engine) ContainerList(ctx context.Context, options types.ContainerListOptions) ([]types.Container, error) {
+	e.mux.Lock()
+	defer e.mux.Unlock()
+	if err := e.injected("ContainerList"); err != nil {
+		return nil, err
+	}
+	out := []types.Container{}
+	for _, cntr := range e.containers {
+		if !options.All && !cntr.running {
+			continue
+		}
+		if !matches(options.Filters, cntr.name, cntr.config.Labels) {
+			continue
+		}
+		settings := map[string]*network.EndpointSettings{}
+		for name, endpoint := range cntr.networks {
+			copied := *endpoint
+			settings[name] = &copied
+		}
+		out = append(out, types.Container{
+			ID:              cntr.id,
+			Names:           []string{"/" + cntr.name},
+			Image:           cntr.config.Image,
+			Created:         cntr.created.Unix(),
+			Labels:          cntr.config.Labels,
+			State:           status(cntr),
+			NetworkSettings: &types.SummaryNetworkSettings{Networks: settings},
+		})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Names[0] < out[j].Names[0] })
+	return out, nil
+}
+
+// ContainerLogs returns the output of the main process of the container, multiplexed the same
+// way as the daemon does it
+func (e *Engine) ContainerLogs(ctx context.Context, containerName string,
+	options types.ContainerLogsOptions) (io.ReadCloser, error) {
+	e.mux.Lock()
+	defer e.mux.Unlock()
+	if err := e.injected("ContainerLogs"); err != nil {
+		return nil, err
+	}
+	cntr, err := e.container(containerName)
+	if err != nil {
+		return nil, err
+	}
+	buf := new(bytes.Buffer)
+	if options.ShowStdout {
+		stdcopy.NewStdWriter(buf, stdcopy.Stdout).Write(cntr.logs)
+	}
+	return ioutil.NopCloser(buf), nil
+}
+
+// ContainerPause pauses the container
+func (e *Engine) ContainerPause(ctx context.Context, containerName string) error {
+	e.mux.Lock()
+	defer e.mux.Unlock()
+	if err := e.injected("ContainerPause"); err != nil {
+		return err
+	}
+	cntr, err := e.container(containerName)
+	if err != nil {
+		return err
+	}
+	if !cntr.running {
+		return fmt.Errorf("Error response from daemon: Container %s is not running", cntr.id)
+	}
+	if cntr.paused {
+		return fmt.Errorf("Error response from daemon: Container %s is already paused", cntr.id)
+	}
+	cntr.paused = true
+	return nil
+}
+
+// ContainerRemove removes the container, a running container is only removed by force
+func (e *Engine) ContainerRemove(ctx context.Context, containerName string,
+	options types.ContainerRemoveOptions) error {
+	e.mux.Lock()
+	defer e.mux.Unlock()
+	if err := e.injected("ContainerRemove"); err != nil {
+		return err
+	}
+	cntr, err := e.container(containerName)
+	if err != nil {
+		return err
+	}
+	if cntr.running {
+		if !options.Force {
+			return fmt.Errorf("Error response from daemon: You cannot remove a running container %s. "+
+				"Stop the container before attempting removal or force remove", cntr.id)
+		}
+		e.emit(cntr, "kill", map[string]string{"signal": "9"})
+		e.exit(cntr, 137)
+	}
+	e.remove(cntr)
+	return nil
+}
+
+// ContainerStart starts the container, and its main process if the image has one
+func (e *Engine) ContainerStart(ctx context.Context, containerName string, options types.ContainerStartOptions) error {
+	e.mux.Lock()
+	defer e.mux.Unlock()
+	if err := e.injected("ContainerStart"); err != nil {
+		return err
+	}
+	cntr, err := e.container(containerName)
+	if err != nil {
+		return fmt.Errorf("Error response from daemon: No such container: %s", containerName)
+	}
+	if cntr.running {
+		return nil
+	}
+	cntr.running = true
+	cntr.oomKilled = false
+	cntr.exited = make(chan struct{})
+	e.emit(cntr, "start", nil)
+	proc, ok := e.programs[normalizeImage(cntr.config.Image)]
+	if !ok {
+		return nil
+	}
+	cmd := append(append([]string{}, cntr.config.Entrypoint...), cntr.config.Cmd...)
+	exited := cntr.exited
+	go func(name string) {
+		code, output := proc(name, cmd)
+		e.mux.Lock()
+		defer e.mux.Unlock()
+		select {
+		case <-exited: // it was stopped before it finished
+			return
+		default:
+		}
+		cntr.logs = append(cntr.logs, output...)
+		e.exit(cntr, code)
+	}(cntr.name)
+	return nil
+}
+
+// ContainerStatPath returns information on a path inside of the container
+func (e *Engine) ContainerStatPath(ctx context.Context, containerName, filePath string) (types.ContainerPathStat, error) {
+	e.mux.Lock()
+	defer e.mux.Unlock()
+	if err := e.injected("ContainerStatPath"); err != nil {
+		return types.ContainerPathStat{}, err
+	}
+	cntr, err := e.container(containerName)
+	if err != nil {
+		return types.ContainerPathStat{}, err
+	}
+	clean := path.Clean(filePath)
+	if data, ok := cntr.files[clean]; ok {
+		return types.ContainerPathStat{Name: path.Base(clean), Size: int64(len(data)), Mode: 0644,
+			Mtime: time.Now()}, nil
+	}
+	if e.hasDir(cntr, clean) {
+		return types.ContainerPathStat{Name: path.Base(clean), Size: 4096, Mode: os.ModeDir | 0755,
+			Mtime: time.Now()}, nil
+	}
+	return types.ContainerPathStat{}, fmt.Errorf("Error: No such container:path: %s:%s", containerName, filePath)
+}
+
+// ContainerStats gives the stats set for the container, read at the time of the call
+func (e *Engine) ContainerStats(ctx context.Context, containerName string, stream bool) (types.ContainerStats, error) {
+	e.mux.Lock()
+	defer e.mux.Unlock()
+	if err := e.injected("ContainerStats"); err != nil {
+		return types.ContainerStats{}, err
+	}
+	cntr, err := e.container(containerName)
+	if err != nil {
+		return types.ContainerStats{}, err
+	}
+	stats := cntr.stats
+	stats.Name = "/" + cntr.name
+	stats.ID = cntr.id
+	if stats.Read.IsZero() {
+		stats.Read = time.Now()
+	}
+	data, err := json.Marshal(stats)
+	if err != nil {
+		return types.ContainerStats{}, err
+	}
+	return types.ContainerStats{Body: ioutil.NopCloser(bytes.NewReader(data)), OSType: "linux"}, nil
+}
+
+// ContainerStop stops the container
+func (e *Engine) ContainerStop(ctx context.Context, containerName string, timeout *time.Duration) error {
+	e.mux.Lock()
+	defer e.mux.Unlock()
+	if err := e.injected("ContainerStop"); err != nil {
+		return err
+	}
+	cntr, err := e.container(containerName)
+	if err != nil {
+		return fmt.Errorf("Error response from daemon: No such container: %s", containerName)
+	}
+	if cntr.running {
+		e.emit(cntr, "kill", map[string]string{"signal": "15"})
+	}
+	e.exit(cntr, 0)
+	return nil
+}
+
+// ContainerUnpause unpauses the container
+func (e *Engine) ContainerUnpause(ctx context.Context, containerName string) error {
+	e.mux.Lock()
+	defer e.mux.Unlock()
+	if err := e.injected("ContainerUnpause"); err != nil {
+		return err
+	}
+	cntr, err := e.container(containerName)
+	if err != nil {
+		return err
+	}
+	if !cntr.paused {
+		return fmt.Errorf("Error response from daemon: Container %s is not paused", cntr.id)
+	}
+	cntr.paused = false
+	return nil
+}
+
+// ContainerUpdate replaces the resource limits of the container
+func (e *Engine) ContainerUpdate(ctx context.Context, containerName string,
+	updateConfig container.UpdateConfig) (container.ContainerUpdateOKBody, error) {
+	e.mux.Lock()
+	defer e.mux.Unlock()
+	if err := e.injected("ContainerUpdate"); err != nil {
+		return container.ContainerUpdateOKBody{}, err
+	}
+	cntr, err := e.container(containerName)
+	if err != nil {
+		return container.ContainerUpdateOKBody{}, err
+	}
+	if updateConfig.NanoCPUs != 0 {
+		cntr.hostConfig.NanoCPUs = updateConfig.NanoCPUs
+	}
+	if updateConfig.Memory != 0 {
+		cntr.hostConfig.Memory = updateConfig.Memory
+	}
+	if updateConfig.MemorySwap != 0 {
+		cntr.hostConfig.MemorySwap = updateConfig.MemorySwap
+	}
+	if updateConfig.PidsLimit != nil {
+		cntr.hostConfig.PidsLimit = updateConfig.PidsLimit
+	}
+	if len(updateConfig.RestartPolicy.Name) > 0 {
+		cntr.hostConfig.RestartPolicy = updateConfig.RestartPolicy
+	}
+	return container.ContainerUpdateOKBody{Warnings: []string{}}, nil
+}
+
+// ContainerWait waits for the container to stop running, or to be removed
+func (e *Engine) ContainerWait(ctx context.Context, containerName string,
+	condition container.WaitCondition) (<-chan container.ContainerWaitOKBody, <-chan error) {
+	resChan := make(chan container.ContainerWaitOKBody, 1)
+	errChan := make(chan error, 1)
+
+	e.mux.Lock()
+	defer e.mux.Unlock()
+	if err := e.injected("ContainerWait"); err != nil {
+		errChan <- err
+		return resChan, errChan
+	}
+	cntr, err := e.container(containerName)
+	if err != nil {
+		errChan <- fmt.Errorf("Error response from daemon: No such container: %s", containerName)
+		return resChan, errChan
+	}
+	if !cntr.running && condition != container.WaitConditionNextExit {
+		resChan <- container.ContainerWaitOKBody{StatusCode: int64(cntr.exitCode)}
+		return resChan, errChan
+	}
+	exited := cntr.exited
+	if !cntr.running {
+		exited = make(chan struct{}) // the next exit needs the container to be started again
+	}
+	go func() {
+		select {
+		case <-exited:
+			e.mux.Lock()
+			code := cntr.exitCode
+			e.mux.Unlock()
+			resChan <- container.ContainerWaitOKBody{StatusCode: int64(code)}
+		case <-ctx.Done():
+			errChan <- ctx.Err()
+		}
+	}()
+	return resChan, errChan
+}
+
+// CopyToContainer extracts the tar archive into the directory in the container
+func (e *Engine) CopyToContainer(ctx context.Context, containerName, dstPath string, content io.Reader,
+	options types.CopyToContainerOptions) error {
+	e.mux.Lock()
+	defer e.mux.Unlock()
+	if err := e.injected("CopyToContainer"); err != nil {
+		return err
+	}
+	cntr, err := e.container(containerName)
+	if err != nil {
+		return err
+	}
+	dst := path.Clean(dstPath)
+	if _, isFile := cntr.files[dst]; isFile || !e.hasDir(cntr, dst) {
+		return fmt.Errorf("Error response from daemon: Could not find the file %s in container %s",
+			dstPath, containerName)
+	}
+	rdr := tar.NewReader(content)
+	for {
+		hdr, err := rdr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("Error response from daemon: %v", err)
+		}
+		target := path.Join(dst, hdr.Name)
+		if hdr.Typeflag == tar.TypeDir {
+			cntr.dirs[target] = true
+			continue
+		}
+		if !options.AllowOverwriteDirWithFile && e.hasDir(cntr, target) {
+			return fmt.Errorf("Error response from daemon: cannot overwrite directory %q with non-directory %q",
+				target, hdr.Name)
+		}
+		data, err := ioutil.ReadAll(rdr)
+		if err != nil {
+			return fmt.Errorf("Error response from daemon: %v", err)
+		}
+		cntr.files[target] = data
+	}
+}
+
+// CopyFromContainer gives a tar archive of the file or directory in the container
+func (e *Engine) CopyFromContainer(ctx context.Context, containerName,
+	srcPath string) (io.ReadCloser, types.ContainerPathStat, error) {
+	e.mux.Lock()
+	defer e.mux.Unlock()
+	if err := e.injected("CopyFromContainer"); err != nil {
+		return nil, types.ContainerPathStat{}, err
+	}
+	cntr, err := e.container(containerName)
+	if err != nil {
+		return nil, types.ContainerPathStat{}, err
+	}
+	src := path.Clean(srcPath)
+	files := []string{}
+	for file := range cntr.files {
+		if file == src || strings.HasPrefix(file, src+"/") {
+			files = append(files, file)
+		}
+	}
+	if len(files) == 0 && !e.hasDir(cntr, src) {
+		return nil, types.ContainerPathStat{}, fmt.Errorf(
+			"Error: No such container:path: %s:%s", containerName, srcPath)
+	}
+	sort.Strings(files)
+
+	buf := new(bytes.Buffer)
+	wtr := tar.NewWriter(buf)
+	for _, file := range files {
+		data := cntr.files[file]
+		err = wtr.WriteHeader(&tar.Header{
+			Name: path.Join(path.Base(src), strings.TrimPrefix(file, src)),
+			Mode: 0644,
+			Size: int64(len(data)),
+		})
+		if err != nil {
+			return nil, types.ContainerPathStat{}, err
+		}
+		wtr.Write(data)
+	}
+	wtr.Close()
+	stat := types.ContainerPathStat{Name: path.Base(src), Mode: os.ModeDir | 0755, Mtime: time.Now()}
+	if data, ok := cntr.files[src]; ok {
+		stat = types.ContainerPathStat{Name: path.Base(src), Size: int64(len(data)), Mode: 0644, Mtime: time.Now()}
+	}
+	return ioutil.NopCloser(buf), stat, nil
+}
+
+// DaemonHost returns the host which the engine is pretending to be
+func (e *Engine) DaemonHost() string {
+	return e.host
+}
+
+// Events streams the events of the containers which match the filters, until the context is done.
+// The events since the given time are sent first.
+func (e *Engine) Events(ctx context.Context, options types.EventsOptions) (<-chan events.Message, <-chan error) {
+	errs := make(chan error, 1)
+	e.mux.Lock()
+	defer e.mux.Unlock()
+	if err := e.injected("Events"); err != nil {
+		errs <- err
+		return make(chan events.Message), errs
+	}
+	sub := &eventSubscriber{filters: options.Filters, msgs: make(chan events.Message, 100+len(e.history)),
+		errs: errs}
+	if len(options.Since) > 0 {
+		sec, nsec, err := timetypes.ParseTimestamps(options.Since, 0)
+		if err != nil {
+			errs <- err
+			return make(chan events.Message), errs
+		}
+		since := time.Unix(sec, nsec).UnixNano()
+		for _, msg := range e.history {
+			if msg.TimeNano >= since && eventMatches(sub.filters, msg) {
+				sub.msgs <- msg
+			}
+		}
+	}
+	e.events[sub] = true
+	waiting := e.waiters[:0]
+	for _, waiter := range e.waiters {
+		if len(e.events) >= waiter.streams {
+			close(waiter.done)
+		} else {
+			waiting = append(waiting, waiter)
+		}
+	}
+	e.waiters = waiting
+	go func() {
+		<-ctx.Done()
+		e.mux.Lock()
+		delete(e.events, sub)
+		e.mux.Unlock()
+		select {
+		case errs <- ctx.Err():
+		default: // the stream was already broken
+		}
+	}()
+	return sub.msgs, errs
+}
+
+// BreakEvents ends every open stream of events with an error, as if the connection to the
+// daemon was lost
+func (e *Engine) BreakEvents() {
+	e.mux.Lock()
+	defer e.mux.Unlock()
+	for sub := range e.events {
+		delete(e.events, sub)
+		sub.errs <- fmt.Errorf("the connection to %s was lost", e.host)
+	}
+}
+
+// Subscribed gives a channel which is closed once the engine has at least the given amount of
+// streams of events open, so that events are only made once they will be seen
+func (e *Engine) Subscribed(streams int) <-chan struct{} {
+	e.mux.Lock()
+	defer e.mux.Unlock()
+	done := make(chan struct{})
+	if len(e.events) >= streams {
+		close(done)
+	} else {
+		e.waiters = append(e.waiters, subscriptionWaiter{streams: streams, done: done})
+	}
+	return done
+}
+
+// HTTPClient returns a plain HTTP client, since the engine is not reached over HTTP
+func (e *Engine) HTTPClient() *http.Client {
+	return &http.Client{}
+}
+
+// ImageList returns the images on the engine
+func (e *Engine) ImageList(ctx context.Context, options types.ImageListOptions) ([]types.ImageSummary, error) {
+	e.mux.Lock()
+	defer e.mux.Unlock()
+	if err := e.injected("ImageList"); err != nil {
+		return nil, err
+	}
+	out := []types.ImageSummary{}
+	for name, img := range e.images {
+		if !matches(options.Filters, name, img.Labels) {
+			continue
+		}
+		out = append(out, img)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].RepoTags[0] < out[j].RepoTags[0] })
+	return out, nil
+}
+
+// ImageLoad loads the images named in the manifest of the archive
+func (e *Engine) ImageLoad(ctx context.Context, input io.Reader, quiet bool) (types.ImageLoadResponse, error) {
+	e.mux.Lock()
+	defer e.mux.Unlock()
+	if err := e.injected("ImageLoad"); err != nil {
+		return types.ImageLoadResponse{}, err
+	}
+	rdr := tar.NewReader(input)
+	loaded := []string{}
+	for {
+		hdr, err := rdr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return types.ImageLoadResponse{}, fmt.Errorf("Error response from daemon: %v", err)
+		}
+		if hdr.Name != "manifest.json" {
+			continue
+		}
+		var manifest []struct {
+			RepoTags []string
+		}
+		err = json.NewDecoder(rdr).Decode(&manifest)
+		if err != nil {
+			return types.ImageLoadResponse{}, fmt.Errorf("Error response from daemon: %v", err)
+		}
+		for _, entry := range manifest {
+			for _, tag := range entry.RepoTags {
+				e.addImage(tag)
+				loaded = append(loaded, tag)
+			}
+		}
+	}
+	if len(loaded) == 0 {
+		return types.ImageLoadResponse{}, fmt.Errorf("Error response from daemon: " +
+			"open /var/lib/docker/tmp/docker-import/repositories: no such file or directory")
+	}
+	buf := new(bytes.Buffer)
+	for _, tag := range loaded {
+		json.NewEncoder(buf).Encode(map[string]string{"stream": "Loaded image: " + tag + "\n"})
+	}
+	return types.ImageLoadResponse{Body: ioutil.NopCloser(buf), JSON: true}, nil
+}
+
+// ImagePull pulls the image, any image can be pulled
+func (e *Engine) ImagePull(ctx context.Context, refStr string, options types.ImagePullOptions) (io.ReadCloser, error) {
+	e.mux.Lock()
+	defer e.mux.Unlock()
+	if err := e.injected("ImagePull"); err != nil {
+		return nil, err
+	}
+	if _, err := reference.ParseNormalizedNamed(refStr); err != nil {
+		return nil, fmt.Errorf("Error response from daemon: invalid reference format")
+	}
+	e.addImage(refStr)
+	buf := new(bytes.Buffer)
+	json.NewEncoder(buf).Encode(map[string]string{
+		"status": "Status: Downloaded newer image for " + normalizeImage(refStr)})
+	return ioutil.NopCloser(buf), nil
+}
+
+// NetworkCreate creates a network, the name must not be taken
+func (e *Engine) NetworkCreate(ctx context.Context, name string,
+	options types.NetworkCreate) (types.NetworkCreateResponse, error) {
+	e.mux.Lock()
+	defer e.mux.Unlock()
+	if err := e.injected("NetworkCreate"); err != nil {
+		return types.NetworkCreateResponse{}, err
+	}
+	for _, net := range e.networks {
+		if net.resource.Name == name {
+			return types.NetworkCreateResponse{},
+				fmt.Errorf("Error response from daemon: network with name %s already exists", name)
+		}
+	}
+	if options.Driver == entity.OverlayDriver && e.cluster == nil {
+		return types.NetworkCreateResponse{}, fmt.Errorf("Error response from daemon: This node is not a " +
+			"swarm manager. Use \"docker swarm init\" or \"docker swarm join\" to connect this node to swarm " +
+			"and try again.")
+	}
+	driver := options.Driver
+	if len(driver) == 0 {
+		driver = entity.BridgeDriver
+	}
+	scope := options.Scope
+	if len(scope) == 0 {
+		scope = "local"
+	}
+	res := types.NetworkResource{
+		Name:       name,
+		ID:         newID(),
+		Created:    time.Now(),
+		Scope:      scope,
+		Driver:     driver,
+		EnableIPv6: options.EnableIPv6,
+		Internal:   options.Internal,
+		Attachable: options.Attachable,
+		Containers: map[string]types.EndpointResource{},
+		Options:    options.Options,
+		Labels:     options.Labels,
+	}
+	if options.IPAM != nil {
+		res.IPAM = *options.IPAM
+	}
+	e.networks[res.ID] = &fakeNetwork{resource: res}
+	return types.NetworkCreateResponse{ID: res.ID}, nil
+}
+
+// NetworkConnect connects the container to the network
+func (e *Engine) NetworkConnect(ctx context.Context, networkID, containerName string,
+	config *network.EndpointSettings) error {
+	e.mux.Lock()
+	defer e.mux.Unlock()
+	if err := e.injected("NetworkConnect"); err != nil {
+		return err
+	}
+	net, err := e.network(networkID)
+	if err != nil {
+		return fmt.Errorf("Error response from daemon: network %s not found", networkID)
+	}
+	cntr, err := e.container(containerName)
+	if err != nil {
+		return fmt.Errorf("Error response from daemon: No such container: %s", containerName)
+	}
+	if _, ok := cntr.networks[net.resource.Name]; ok {
+		return fmt.Errorf("Error response from daemon: endpoint with name %s already exists in network %s: "+
+			"container %s is already attached to network %s", cntr.name, net.resource.Name, cntr.name,
+			net.resource.Name)
+	}
+	e.connect(cntr, net, config)
+	return nil
+}
+
+// NetworkDisconnect disconnects the container from the network
+func (e *Engine) NetworkDisconnect(ctx context.Context, networkID, containerName string, force bool) error {
+	e.mux.Lock()
+	defer e.mux.Unlock()
+	if err := e.injected("NetworkDisconnect"); err != nil {
+		return err
+	}
+	net, err := e.network(networkID)
+	if err != nil {
+		return fmt.Errorf("Error response from daemon: network %s not found", networkID)
+	}
+	cntr, err := e.container(containerName)
+	if err != nil {
+		return fmt.Errorf("Error response from daemon: No such container: %s", containerName)
+	}
+	if _, ok := cntr.networks[net.resource.Name]; !ok {
+		return fmt.Errorf("Error response from daemon: container %s is not connected to the network %s",
+			cntr.id, net.resource.Name)
+	}
+	delete(cntr.networks, net.resource.Name)
+	delete(net.resource.Containers, cntr.id)
+	return nil
+}
+
+func copyNetwork(net *fakeNetwork) types.NetworkResource {
+	out := net.resource
+	out.Containers = map[string]types.EndpointResource{}
+	for id, endpoint := range net.resource.Containers {
+		out.Containers[id] = endpoint
+	}
+	return out
+}
+
+// NetworkInspect returns the network, along with the containers on it
+func (e *Engine) NetworkInspect(ctx context.Context, networkID string,
+	options types.NetworkInspectOptions) (types.NetworkResource, error) {
+	e.mux.Lock()
+	defer e.mux.Unlock()
+	if err := e.injected("NetworkInspect"); err != nil {
+		return types.NetworkResource{}, err
+	}
+	net, err := e.network(networkID)
+	if err != nil {
+		return types.NetworkResource{}, err
+	}
+	return copyNetwork(net), nil
+}
+
+// NetworkRemove removes the network, it must not have any containers on it
+func (e *Engine) NetworkRemove(ctx context.Context, networkID string) error {
+	e.mux.Lock()
+	defer e.mux.Unlock()
+	if err := e.injected("NetworkRemove"); err != nil {
+		return err
+	}
+	net, err := e.network(networkID)
+	if err != nil {
+		return fmt.Errorf("Error: No such network: %s", networkID)
+	}
+	switch net.resource.Name {
+	case "bridge", "host", "none":
+		return fmt.Errorf("Error response from daemon: %s is a pre-defined network and cannot be removed",
+			net.resource.Name)
+	}
+	if len(net.resource.Containers) > 0 {
+		return fmt.Errorf("Error response from daemon: error while removing network: network %s id %s "+
+			"has active endpoints", net.resource.Name, net.resource.ID)
+	}
+	delete(e.networks, net.resource.ID)
+	return nil
+}
+
+// NetworkList lists the networks
+func (e *Engine) NetworkList(ctx context.Context, options types.NetworkListOptions) ([]types.NetworkResource, error) {
+	e.mux.Lock()
+	defer e.mux.Unlock()
+	if err := e.injected("NetworkList"); err != nil {
+		return nil, err
+	}
+	out := []types.NetworkResource{}
+	for _, net := range e.networks {
+		if !matches(options.Filters, net.resource.Name, net.resource.Labels) {
+			continue
+		}
+		out = append(out, copyNetwork(net))
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out, nil
+}
+
+// Ping always reaches the engine, unless a failure is set for it
+func (e *Engine) Ping(ctx context.Context) (types.Ping, error) {
+	e.mux.Lock()
+	defer e.mux.Unlock()
+	if err := e.injected("Ping"); err != nil {
+		return types.Ping{}, err
+	}
+	return types.Ping{APIVersion: "1.40", OSType: "linux"}, nil
+}
+
+// Info gives the resources of the host, and how many containers and images it has
+func (e *Engine) Info(ctx context.Context) (types.Info, error) {
+	e.mux.Lock()
+	defer e.mux.Unlock()
+	if err := e.injected("Info"); err != nil {
+		return types.Info{}, err
+	}
+	return types.Info{
+		Name:       e.host,
+		NCPU:       e.cpus,
+		MemTotal:   e.memory,
+		Containers: len(e.containers),
+		Images:     len(e.images),
+		OSType:     "linux",
+		Swarm:      e.swarmInfo(),
+	}, nil
+}
+
+// VolumeCreate creates the volume, or gives back the one which already has the name
+func (e *Engine) VolumeCreate(ctx context.Context, options volume.VolumeCreateBody) (types.Volume, error) {
+	e.mux.Lock()
+	defer e.mux.Unlock()
+	if err := e.injected("VolumeCreate"); err != nil {
+		return types.Volume{}, err
+	}
+	name := options.Name
+	if len(name) == 0 {
+		name = newID()
+	}
+	if vol, ok := e.volumes[name]; ok {
+		return *vol, nil
+	}
+	driver := options.Driver
+	if len(driver) == 0 {
+		driver = "local"
+	}
+	vol := &types.Volume{
+		Name:       name,
+		Driver:     driver,
+		Scope:      "local",
+		Labels:     options.Labels,
+		Options:    options.DriverOpts,
+		Mountpoint: "/var/lib/docker/volumes/" + name + "/_data",
+		CreatedAt:  time.Now().Format(time.RFC3339),
+	}
+	e.volumes[name] = vol
+	return *vol, nil
+}
+
+// VolumeList lists the volumes
+func (e *Engine) VolumeList(ctx context.Context, filter filters.Args) (volume.VolumeListOKBody, error) {
+	e.mux.Lock()
+	defer e.mux.Unlock()
+	if err := e.injected("VolumeList"); err != nil {
+		return volume.VolumeListOKBody{}, err
+	}
+	out := volume.VolumeListOKBody{Volumes: []*types.Volume{}, Warnings: []string{}}
+	for name, vol := range e.volumes {
+		if !matches(filter, name, vol.Labels) {
+			continue
+		}
+		copied := *vol
+		out.Volumes = append(out.Volumes, &copied)
+	}
+	sort.Slice(out.Volumes, func(i, j int) bool { return out.Volumes[i].Name < out.Volumes[j].Name })
+	return out, nil
+}
+
+// VolumeRemove removes the volume, it must not be used by any containers
+func (e *Engine) VolumeRemove(ctx context.Context, volumeID string, force bool) error {
+	e.mux.Lock()
+	defer e.mux.Unlock()
+	if err := e.injected("VolumeRemove"); err != nil {
+		return err
+	}
+	if _, ok := e.volumes[volumeID]; !ok {
+		if force {
+			return nil
+		}
+		return fmt.Errorf("Error: No such volume: %s", volumeID)
+	}
+	users := []string{}
+	for _, cntr := range e.containers {
+		for _, mnt := range cntr.hostConfig.Mounts {
+			if mnt.Type == mount.TypeVolume && mnt.Source == volumeID {
+				users = append(users, cntr.id)
+			}
+		}
+	}
+	if len(users) > 0 {
+		sort.Strings(users)
+		return fmt.Errorf("Error response from daemon: remove %s: volume is in use - [%s]",
+			volumeID, strings.Join(users, ", "))
+	}
+	delete(e.volumes, volumeID)
+	return nil
+}
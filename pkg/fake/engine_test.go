@@ -0,0 +1,346 @@
+/**
+ * Copyright 2019 Whiteblock Inc. All rights reserved.
+ * Use of this source code is governed by a BSD-style
+ * license that can be found in the LICENSE file.
+ */
+
+package fake
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/api/types/mount"
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/docker/api/types/volume"
+	"github.com/docker/docker/pkg/stdcopy"
+	"github.com/streadway/amqp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEngine_ContainerLifecycle(t *testing.T) {
+	e := NewEngine("tcp://127.0.0.1:2376")
+	ctx := context.Background()
+
+	_, err := e.ContainerCreate(ctx, &container.Config{Image: "alpine"}, nil, nil, "test")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "No such image: alpine")
+
+	rd, err := e.ImagePull(ctx, "alpine", types.ImagePullOptions{})
+	require.NoError(t, err)
+	rd.Close()
+	imgs, err := e.ImageList(ctx, types.ImageListOptions{})
+	require.NoError(t, err)
+	require.Len(t, imgs, 1)
+	assert.Equal(t, []string{"docker.io/library/alpine:latest"}, imgs[0].RepoTags)
+
+	created, err := e.ContainerCreate(ctx, &container.Config{Image: "alpine",
+		Labels: map[string]string{"test": "1"}}, nil, nil, "test")
+	require.NoError(t, err)
+
+	_, err = e.ContainerCreate(ctx, &container.Config{Image: "alpine"}, nil, nil, "test")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "already in use by container \""+created.ID+"\"")
+
+	cntrs, err := e.ContainerList(ctx, types.ContainerListOptions{})
+	require.NoError(t, err)
+	assert.Len(t, cntrs, 0)
+
+	require.NoError(t, e.ContainerStart(ctx, "test", types.ContainerStartOptions{}))
+	cntrs, err = e.ContainerList(ctx, types.ContainerListOptions{
+		Filters: filters.NewArgs(filters.Arg("label", "test=1"))})
+	require.NoError(t, err)
+	require.Len(t, cntrs, 1)
+	assert.Equal(t, []string{"/test"}, cntrs[0].Names)
+
+	info, err := e.ContainerInspect(ctx, created.ID[:12])
+	require.NoError(t, err)
+	assert.True(t, info.State.Running)
+	assert.Contains(t, info.NetworkSettings.Networks, "bridge")
+
+	err = e.ContainerRemove(ctx, "test", types.ContainerRemoveOptions{})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "You cannot remove a running container")
+
+	require.NoError(t, e.ContainerRemove(ctx, "test", types.ContainerRemoveOptions{Force: true}))
+	_, err = e.ContainerInspect(ctx, "test")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "No such container")
+}
+
+func TestEngine_RunAndWait(t *testing.T) {
+	e := NewEngine("")
+	ctx := context.Background()
+	e.AddImage("task")
+	e.OnRun("task", func(name string, cmd []string) (int, []byte) {
+		return 3, []byte(name + " ran " + cmd[0])
+	})
+	_, err := e.ContainerCreate(ctx, &container.Config{Image: "task", Cmd: []string{"work"}}, nil, nil, "job")
+	require.NoError(t, err)
+	require.NoError(t, e.ContainerStart(ctx, "job", types.ContainerStartOptions{}))
+
+	resChan, errChan := e.ContainerWait(ctx, "job", container.WaitConditionNotRunning)
+	select {
+	case res := <-resChan:
+		assert.Equal(t, int64(3), res.StatusCode)
+	case err := <-errChan:
+		t.Fatal(err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("the container never exited")
+	}
+
+	rd, err := e.ContainerLogs(ctx, "job", types.ContainerLogsOptions{ShowStdout: true})
+	require.NoError(t, err)
+	stdout := new(bytes.Buffer)
+	_, err = stdcopy.StdCopy(stdout, ioutil.Discard, rd)
+	require.NoError(t, err)
+	assert.Equal(t, "job ran work", stdout.String())
+}
+
+func TestEngine_Events(t *testing.T) {
+	e := NewEngine("")
+	ctx, cancel := context.WithCancel(context.Background())
+	e.AddImage("alpine")
+	for _, name := range []string{"node0", "other"} {
+		_, err := e.ContainerCreate(ctx, &container.Config{Image: "alpine",
+			Labels: map[string]string{"test": name}}, nil, nil, name)
+		require.NoError(t, err)
+	}
+	subscribed := e.Subscribed(1)
+	select {
+	case <-subscribed:
+		t.Fatal("nothing has subscribed yet")
+	default:
+	}
+	msgs, errs := e.Events(ctx, types.EventsOptions{Filters: filters.NewArgs(
+		filters.Arg("label", "test=node0"),
+		filters.Arg("event", "die"),
+		filters.Arg("event", "oom"),
+		filters.Arg("event", "health_status"),
+	)})
+
+	<-subscribed
+	<-e.Subscribed(1)
+	for _, name := range []string{"node0", "other"} {
+		require.NoError(t, e.ContainerStart(ctx, name, types.ContainerStartOptions{}))
+		require.NoError(t, e.SetHealth(name, "unhealthy"))
+		require.NoError(t, e.Crash(name, 137, true))
+	}
+	var actions []string
+	for i := 0; i < 3; i++ {
+		select {
+		case msg := <-msgs:
+			assert.Equal(t, "node0", msg.Actor.Attributes["name"])
+			actions = append(actions, msg.Action)
+			if msg.Action == "die" {
+				assert.Equal(t, "137", msg.Actor.Attributes["exitCode"])
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("missing an event")
+		}
+	}
+	assert.Equal(t, []string{"health_status: unhealthy", "oom", "die"}, actions)
+
+	info, err := e.ContainerInspect(ctx, "node0")
+	require.NoError(t, err)
+	assert.True(t, info.State.OOMKilled)
+	assert.Equal(t, "unhealthy", info.State.Health.Status)
+
+	cancel()
+	assert.Equal(t, context.Canceled, <-errs)
+}
+
+func TestEngine_Exec(t *testing.T) {
+	e := NewEngine("")
+	ctx := context.Background()
+	e.AddImage("alpine")
+	e.OnExec("false", func(string, []string) (int, []byte) { return 1, nil })
+	_, err := e.ContainerCreate(ctx, &container.Config{Image: "alpine"}, nil, nil, "test")
+	require.NoError(t, err)
+
+	_, err = e.ContainerExecCreate(ctx, "test", types.ExecConfig{Cmd: []string{"true"}})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "is not running")
+
+	require.NoError(t, e.ContainerStart(ctx, "test", types.ContainerStartOptions{}))
+	for cmd, code := range map[string]int{"true": 0, "false": 1} {
+		id, err := e.ContainerExecCreate(ctx, "test", types.ExecConfig{Cmd: []string{cmd}})
+		require.NoError(t, err)
+		require.NoError(t, e.ContainerExecStart(ctx, id.ID, types.ExecStartCheck{}))
+		res, err := e.ContainerExecInspect(ctx, id.ID)
+		require.NoError(t, err)
+		assert.False(t, res.Running)
+		assert.Equal(t, code, res.ExitCode)
+	}
+	assert.Len(t, e.Execs("test"), 2)
+}
+
+func TestEngine_CopyToContainer(t *testing.T) {
+	e := NewEngine("")
+	ctx := context.Background()
+	e.AddImage("alpine")
+	_, err := e.ContainerCreate(ctx, &container.Config{Image: "alpine"}, nil, nil, "test")
+	require.NoError(t, err)
+
+	buf := new(bytes.Buffer)
+	tw := tar.NewWriter(buf)
+	require.NoError(t, tw.WriteHeader(&tar.Header{Name: "conf", Typeflag: tar.TypeDir, Mode: 0755}))
+	require.NoError(t, tw.WriteHeader(&tar.Header{Name: "conf/a.json", Mode: 0644, Size: 2}))
+	_, err = tw.Write([]byte("{}"))
+	require.NoError(t, err)
+	require.NoError(t, tw.Close())
+
+	err = e.CopyToContainer(ctx, "test", "/missing", bytes.NewReader(buf.Bytes()), types.CopyToContainerOptions{})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "Could not find the file /missing")
+
+	require.NoError(t, e.CopyToContainer(ctx, "test", "/etc", buf, types.CopyToContainerOptions{}))
+	data, ok := e.File("test", "/etc/conf/a.json")
+	require.True(t, ok)
+	assert.Equal(t, "{}", string(data))
+
+	stat, err := e.ContainerStatPath(ctx, "test", "/etc/conf")
+	require.NoError(t, err)
+	assert.True(t, stat.Mode.IsDir())
+	stat, err = e.ContainerStatPath(ctx, "test", "/etc/conf/a.json")
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), stat.Size)
+}
+
+func TestEngine_Networks(t *testing.T) {
+	e := NewEngine("")
+	ctx := context.Background()
+	e.AddImage("alpine")
+
+	_, err := e.NetworkCreate(ctx, "testnet", types.NetworkCreate{Labels: map[string]string{"a": "b"}})
+	require.NoError(t, err)
+	_, err = e.NetworkCreate(ctx, "testnet", types.NetworkCreate{})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "already exists")
+
+	_, err = e.ContainerCreate(ctx, &container.Config{Image: "alpine"}, nil, &network.NetworkingConfig{
+		EndpointsConfig: map[string]*network.EndpointSettings{"nope": {}}}, "test")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "network nope not found")
+
+	_, err = e.ContainerCreate(ctx, &container.Config{Image: "alpine"}, nil, nil, "test")
+	require.NoError(t, err)
+	require.NoError(t, e.NetworkConnect(ctx, "testnet", "test", &network.EndpointSettings{
+		IPAMConfig: &network.EndpointIPAMConfig{IPv4Address: "10.0.0.2"}}))
+	err = e.NetworkConnect(ctx, "testnet", "test", nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "is already attached to network")
+
+	net, err := e.NetworkInspect(ctx, "testnet", types.NetworkInspectOptions{})
+	require.NoError(t, err)
+	require.Len(t, net.Containers, 1)
+	for _, endpoint := range net.Containers {
+		assert.Equal(t, "10.0.0.2", endpoint.IPv4Address)
+	}
+
+	err = e.NetworkRemove(ctx, "testnet")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "has active endpoints")
+
+	require.NoError(t, e.NetworkDisconnect(ctx, "testnet", "test", true))
+	err = e.NetworkDisconnect(ctx, "testnet", "test", true)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "is not connected to the network")
+
+	nets, err := e.NetworkList(ctx, types.NetworkListOptions{
+		Filters: filters.NewArgs(filters.Arg("label", "a=b"))})
+	require.NoError(t, err)
+	require.Len(t, nets, 1)
+	require.NoError(t, e.NetworkRemove(ctx, "testnet"))
+}
+
+func TestEngine_Volumes(t *testing.T) {
+	e := NewEngine("")
+	ctx := context.Background()
+	e.AddImage("alpine")
+
+	_, err := e.VolumeCreate(ctx, volume.VolumeCreateBody{Name: "data"})
+	require.NoError(t, err)
+	_, err = e.ContainerCreate(ctx, &container.Config{Image: "alpine"}, &container.HostConfig{
+		Mounts: []mount.Mount{{Type: mount.TypeVolume, Source: "data", Target: "/data"}}}, nil, "test")
+	require.NoError(t, err)
+
+	err = e.VolumeRemove(ctx, "data", false)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "volume is in use")
+
+	require.NoError(t, e.ContainerRemove(ctx, "test", types.ContainerRemoveOptions{}))
+	require.NoError(t, e.VolumeRemove(ctx, "data", false))
+	err = e.VolumeRemove(ctx, "data", false)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "No such volume")
+}
+
+func TestEngine_FailNext(t *testing.T) {
+	e := NewEngine("")
+	e.FailNext("Ping", fmt.Errorf("Cannot connect to the Docker daemon"))
+	_, err := e.Ping(context.Background())
+	assert.Error(t, err)
+	_, err = e.Ping(context.Background())
+	assert.NoError(t, err)
+}
+
+func TestQueue(t *testing.T) {
+	q := NewQueue("test", 10)
+	msgs, err := q.Consume()
+	require.NoError(t, err)
+
+	require.NoError(t, q.Send(amqp.Publishing{Body: []byte("1")}))
+	msg := <-msgs
+	assert.Equal(t, "1", string(msg.Body))
+
+	require.NoError(t, q.Requeue(msg, amqp.Publishing{Body: []byte("2")}))
+	msg = <-msgs
+	assert.Equal(t, "2", string(msg.Body))
+	require.NoError(t, msg.Ack(false))
+
+	assert.Equal(t, []uint64{1}, q.Rejected())
+	assert.Equal(t, []uint64{2}, q.Acked())
+	assert.Len(t, q.Published(), 2)
+
+	q.Close()
+	_, open := <-msgs
+	assert.False(t, open)
+	assert.Equal(t, ErrClosed, q.Send(amqp.Publishing{}))
+}
+
+func TestEngine_Events_Since(t *testing.T) {
+	e := NewEngine("10.0.0.2")
+	ctx := context.Background()
+	e.AddImage("alpine")
+	_, err := e.ContainerCreate(ctx, &container.Config{Image: "alpine"}, nil, nil, "node0")
+	require.NoError(t, err)
+	require.NoError(t, e.ContainerStart(ctx, "node0", types.ContainerStartOptions{}))
+	require.NoError(t, e.Crash("node0", 1, false))
+	since := time.Now()
+	require.NoError(t, e.ContainerStart(ctx, "node0", types.ContainerStartOptions{}))
+	require.NoError(t, e.Crash("node0", 2, false))
+
+	msgs, errs := e.Events(ctx, types.EventsOptions{
+		Since:   fmt.Sprintf("%d.%09d", since.Unix(), since.Nanosecond()),
+		Filters: filters.NewArgs(filters.Arg("event", "die")),
+	})
+	select {
+	case msg := <-msgs:
+		assert.Equal(t, "2", msg.Actor.Attributes["exitCode"], "only the events since the time are sent")
+	case <-time.After(5 * time.Second):
+		t.Fatal("missing an event")
+	}
+
+	e.BreakEvents()
+	assert.EqualError(t, <-errs, "the connection to 10.0.0.2 was lost")
+}
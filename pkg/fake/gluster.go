@@ -0,0 +1,318 @@
+/**
+ * Copyright 2019 Whiteblock Inc. All rights reserved.
+ * Use of this source code is governed by a BSD-style
+ * license that can be found in the LICENSE file.
+ */
+
+package fake
+
+import (
+	"encoding/xml"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Gluster is a trusted storage pool, which runs the gluster commands executed in the containers
+// of the engines it serves. It keeps the peers and volumes, and fails the commands which
+// glusterd would refuse.
+type Gluster struct {
+	mux     sync.Mutex
+	peers   map[string]bool
+	offline map[string]bool
+	volumes map[string]*glusterVolume
+}
+
+type glusterVolume struct {
+	started  bool
+	replicas int
+	bricks   []string
+	options  map[string]string
+}
+
+type glusterOutput struct {
+	XMLName   xml.Name         `xml:"cliOutput"`
+	OpRet     int              `xml:"opRet"`
+	OpErrno   int              `xml:"opErrno"`
+	OpErrstr  string           `xml:"opErrstr"`
+	VolInfo   *glusterVolInfo  `xml:"volInfo,omitempty"`
+	VolStatus *glusterVolStats `xml:"volStatus,omitempty"`
+}
+
+type glusterVolInfo struct {
+	Volumes []glusterInfoVolume `xml:"volumes>volume"`
+}
+
+type glusterInfoVolume struct {
+	Name         string          `xml:"name"`
+	StatusStr    string          `xml:"statusStr"`
+	BrickCount   int             `xml:"brickCount"`
+	ReplicaCount int             `xml:"replicaCount"`
+	Bricks       []string        `xml:"bricks>brick>name"`
+	Options      []glusterOption `xml:"options>option"`
+}
+
+type glusterOption struct {
+	Name  string `xml:"name"`
+	Value string `xml:"value"`
+}
+
+type glusterVolStats struct {
+	Volumes []glusterStatusVolume `xml:"volumes>volume"`
+}
+
+type glusterStatusVolume struct {
+	VolName   string              `xml:"volName"`
+	NodeCount int                 `xml:"nodeCount"`
+	Nodes     []glusterStatusNode `xml:"node"`
+}
+
+type glusterStatusNode struct {
+	Hostname  string `xml:"hostname"`
+	Path      string `xml:"path"`
+	Status    int    `xml:"status"`
+	Port      string `xml:"port"`
+	Pid       int    `xml:"pid"`
+	SizeTotal uint64 `xml:"sizeTotal"`
+	SizeFree  uint64 `xml:"sizeFree"`
+	Device    string `xml:"device"`
+	FsName    string `xml:"fsName"`
+}
+
+// NewGluster creates a new, empty, trusted storage pool
+func NewGluster() *Gluster {
+	return &Gluster{
+		peers:   map[string]bool{},
+		offline: map[string]bool{},
+		volumes: map[string]*glusterVolume{},
+	}
+}
+
+// Serve makes the pool run the gluster commands which are executed on the engines
+func (g *Gluster) Serve(engines ...*Engine) {
+	for _, e := range engines {
+		e.OnExec("gluster", g.run)
+	}
+}
+
+// Peers gives the hosts which have been probed into the pool
+func (g *Gluster) Peers() []string {
+	g.mux.Lock()
+	defer g.mux.Unlock()
+	out := []string{}
+	for peer := range g.peers {
+		out = append(out, peer)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// Bricks gives the bricks of the volume, and whether it exists
+func (g *Gluster) Bricks(volume string) ([]string, bool) {
+	g.mux.Lock()
+	defer g.mux.Unlock()
+	vol, ok := g.volumes[volume]
+	if !ok {
+		return nil, false
+	}
+	return append([]string{}, vol.bricks...), true
+}
+
+// SetOffline makes the bricks on the host be reported as offline
+func (g *Gluster) SetOffline(host string, offline bool) {
+	g.mux.Lock()
+	defer g.mux.Unlock()
+	g.offline[host] = offline
+}
+
+func glusterFail(format string, args ...interface{}) (int, []byte) {
+	return 1, []byte(fmt.Sprintf(format, args...) + "\n")
+}
+
+func (g *Gluster) run(container string, cmd []string) (int, []byte) {
+	g.mux.Lock()
+	defer g.mux.Unlock()
+	args := []string{}
+	asXML := false
+	for _, arg := range cmd[1:] {
+		switch arg {
+		case "--mode=script":
+		case "--xml":
+			asXML = true
+		default:
+			args = append(args, arg)
+		}
+	}
+	if len(args) < 2 {
+		return glusterFail("unrecognized command")
+	}
+	switch args[0] + " " + args[1] {
+	case "peer probe":
+		g.peers[args[2]] = true
+		return 0, []byte("peer probe: success\n")
+	case "peer detach":
+		if !g.peers[args[2]] {
+			return glusterFail("peer detach: failed: %s is not part of cluster", args[2])
+		}
+		delete(g.peers, args[2])
+		return 0, []byte("peer detach: success\n")
+	}
+	if args[0] != "volume" || len(args) < 3 {
+		return glusterFail("unrecognized command")
+	}
+	name := args[2]
+	vol, exists := g.volumes[name]
+	if args[1] == "create" {
+		if exists {
+			return glusterFail("volume create: %s: failed: Volume %s already exists", name, name)
+		}
+		replicas, bricks, err := replicaBricks(args[3:])
+		if err != nil {
+			return glusterFail("volume create: %s: failed: %v", name, err)
+		}
+		g.volumes[name] = &glusterVolume{replicas: replicas, bricks: bricks, options: map[string]string{}}
+		return 0, []byte(fmt.Sprintf("volume create: %s: success\n", name))
+	}
+	if !exists {
+		return glusterFail("Volume %s does not exist", name)
+	}
+	switch args[1] {
+	case "info":
+		return g.output(asXML, &glusterOutput{VolInfo: &glusterVolInfo{
+			Volumes: []glusterInfoVolume{vol.info(name)}}})
+	case "status":
+		if !vol.started {
+			return glusterFail("Volume %s is not started", name)
+		}
+		return g.output(asXML, &glusterOutput{VolStatus: &glusterVolStats{
+			Volumes: []glusterStatusVolume{g.status(name, vol)}}})
+	case "start":
+		if vol.started {
+			return glusterFail("volume start: %s: failed: Volume %s already started", name, name)
+		}
+		vol.started = true
+	case "stop":
+		if !vol.started {
+			return glusterFail("volume stop: %s: failed: Volume %s is not in the started state", name, name)
+		}
+		vol.started = false
+	case "delete":
+		if vol.started {
+			return glusterFail("volume delete: %s: failed: Volume %s has been started. "+
+				"Volume needs to be stopped before deletion.", name, name)
+		}
+		delete(g.volumes, name)
+	case "set":
+		if len(args) != 5 {
+			return glusterFail("Usage: volume set <VOLNAME> <KEY> <VALUE>")
+		}
+		vol.options[args[3]] = args[4]
+	case "add-brick", "remove-brick":
+		replicas, bricks, err := replicaBricks(args[3:])
+		if err != nil {
+			return glusterFail("volume %s: failed: %v", args[1], err)
+		}
+		if args[1] == "add-brick" {
+			vol.bricks = append(vol.bricks, bricks...)
+		} else {
+			for _, brick := range bricks {
+				found := false
+				for i := range vol.bricks {
+					if vol.bricks[i] == brick {
+						vol.bricks = append(vol.bricks[:i], vol.bricks[i+1:]...)
+						found = true
+						break
+					}
+				}
+				if !found {
+					return glusterFail("volume remove-brick: failed: Incorrect brick %s for volume %s",
+						brick, name)
+				}
+			}
+		}
+		if replicas != len(vol.bricks) {
+			return glusterFail("volume %s: failed: replica count %d does not match the %d bricks",
+				args[1], replicas, len(vol.bricks))
+		}
+		vol.replicas = replicas
+	default:
+		return glusterFail("unrecognized command")
+	}
+	return 0, []byte(fmt.Sprintf("volume %s: %s: success\n", args[1], name))
+}
+
+// replicaBricks parses the "replica <count> <bricks>... [force]" arguments
+func replicaBricks(args []string) (int, []string, error) {
+	if len(args) < 2 || args[0] != "replica" {
+		return 0, nil, fmt.Errorf("expected the replica count")
+	}
+	replicas, err := strconv.Atoi(args[1])
+	if err != nil {
+		return 0, nil, err
+	}
+	bricks := []string{}
+	for _, arg := range args[2:] {
+		if arg == "force" {
+			continue
+		}
+		if !strings.Contains(arg, ":/") {
+			return 0, nil, fmt.Errorf("wrong brick type: %s, use <HOSTNAME>:<export-dir-abs-path>", arg)
+		}
+		bricks = append(bricks, arg)
+	}
+	return replicas, bricks, nil
+}
+
+func (vol glusterVolume) info(name string) glusterInfoVolume {
+	out := glusterInfoVolume{
+		Name:         name,
+		StatusStr:    "Created",
+		BrickCount:   len(vol.bricks),
+		ReplicaCount: vol.replicas,
+		Bricks:       vol.bricks,
+	}
+	if vol.started {
+		out.StatusStr = "Started"
+	}
+	for key, value := range vol.options {
+		out.Options = append(out.Options, glusterOption{Name: key, Value: value})
+	}
+	sort.Slice(out.Options, func(i, j int) bool { return out.Options[i].Name < out.Options[j].Name })
+	return out
+}
+
+func (g *Gluster) status(name string, vol *glusterVolume) glusterStatusVolume {
+	out := glusterStatusVolume{VolName: name, NodeCount: len(vol.bricks)}
+	for i, brick := range vol.bricks {
+		parts := strings.SplitN(brick, ":", 2)
+		node := glusterStatusNode{
+			Hostname:  parts[0],
+			Path:      parts[1],
+			Status:    1,
+			Port:      fmt.Sprint(49152 + i),
+			Pid:       100 + i,
+			SizeTotal: 10 << 30,
+			SizeFree:  8 << 30,
+			Device:    "/dev/sda1",
+			FsName:    "ext4",
+		}
+		if g.offline[parts[0]] {
+			node.Status, node.Port, node.Pid = 0, "N/A", -1
+		}
+		out.Nodes = append(out.Nodes, node)
+	}
+	return out
+}
+
+func (g *Gluster) output(asXML bool, out *glusterOutput) (int, []byte) {
+	if !asXML {
+		return 0, []byte(fmt.Sprintf("%+v\n", *out))
+	}
+	data, err := xml.Marshal(out)
+	if err != nil {
+		return glusterFail("%v", err)
+	}
+	return 0, append([]byte(xml.Header), data...)
+}
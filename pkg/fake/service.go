@@ -0,0 +1,292 @@
+/**
+ * Copyright 2019 Whiteblock Inc. All rights reserved.
+ * Use of this source code is governed by a BSD-style
+ * license that can be found in the LICENSE file.
+ */
+
+package fake
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/swarm"
+)
+
+// manager gives the cluster the engine manages, the lock of the engine must be held
+func (e *Engine) manager() (*cluster, error) {
+	if e.cluster == nil || !e.cluster.isManager(e.nodeID) {
+		return nil, errNotManager
+	}
+	return e.cluster, nil
+}
+
+// service finds the service by its ID or name. The lock of the cluster must be held.
+func (c *cluster) service(idOrName string) (*swarm.Service, error) {
+	for _, svc := range c.services {
+		if svc.ID == idOrName || svc.Spec.Name == idOrName {
+			return svc, nil
+		}
+	}
+	return nil, fmt.Errorf("Error: No such service: %s", idOrName)
+}
+
+// satisfies checks the node against placement constraints, such as node.labels.zone==east
+func satisfies(node *swarm.Node, constraints []string) bool {
+	for _, constraint := range constraints {
+		equal := true
+		pair := strings.SplitN(constraint, "!=", 2)
+		if len(pair) == 2 {
+			equal = false
+		} else {
+			pair = strings.SplitN(constraint, "==", 2)
+		}
+		if len(pair) != 2 {
+			return false
+		}
+		key, expected := strings.TrimSpace(pair[0]), strings.TrimSpace(pair[1])
+		actual := ""
+		switch {
+		case key == "node.id":
+			actual = node.ID
+		case key == "node.hostname":
+			actual = node.Description.Hostname
+		case key == "node.role":
+			actual = string(node.Spec.Role)
+		case strings.HasPrefix(key, "node.labels."):
+			actual = node.Spec.Labels[strings.TrimPrefix(key, "node.labels.")]
+		}
+		if (actual == expected) != equal {
+			return false
+		}
+	}
+	return true
+}
+
+// reconcile replaces the tasks of the service which run an older spec, and adds or removes
+// tasks until the service has as many as it asks for. Tasks go to the nodes which satisfy the
+// constraints and run the fewest tasks of the service. The lock of the cluster must be held.
+func (c *cluster) reconcile(svc *swarm.Service) {
+	replicas := uint64(1)
+	if svc.Spec.Mode.Replicated != nil && svc.Spec.Mode.Replicated.Replicas != nil {
+		replicas = *svc.Spec.Mode.Replicated.Replicas
+	}
+	load := map[string]int{}
+	slots := map[int]*swarm.Task{}
+	for _, task := range c.tasks {
+		if task.ServiceID != svc.ID || task.DesiredState != swarm.TaskStateRunning {
+			continue
+		}
+		if uint64(task.Slot) > replicas || !reflect.DeepEqual(task.Spec, svc.Spec.TaskTemplate) {
+			task.DesiredState = swarm.TaskStateShutdown
+			task.Status.State = swarm.TaskStateShutdown
+			continue
+		}
+		slots[task.Slot] = task
+		load[task.NodeID]++
+	}
+	for slot := 1; uint64(slot) <= replicas; slot++ {
+		if _, ok := slots[slot]; ok {
+			continue
+		}
+		task := &swarm.Task{
+			ID:           newID()[:25],
+			Meta:         swarm.Meta{Version: swarm.Version{Index: 1}, CreatedAt: time.Now()},
+			Spec:         svc.Spec.TaskTemplate,
+			ServiceID:    svc.ID,
+			Slot:         slot,
+			DesiredState: swarm.TaskStateRunning,
+			Status:       swarm.TaskStatus{Timestamp: time.Now(), State: swarm.TaskStatePending},
+		}
+		nodeID := c.leastLoaded(svc, load)
+		if len(nodeID) == 0 {
+			task.Status.Err = fmt.Sprintf("no suitable node (scheduling constraints not satisfied on %d nodes)",
+				len(c.nodes))
+		} else {
+			task.NodeID = nodeID
+			task.Status.State = swarm.TaskStateRunning
+			load[nodeID]++
+		}
+		c.tasks[task.ID] = task
+	}
+}
+
+// leastLoaded gives the node which can run tasks of the service and runs the fewest of them
+func (c *cluster) leastLoaded(svc *swarm.Service, load map[string]int) string {
+	ids := []string{}
+	for id, node := range c.nodes {
+		if node.Spec.Availability != swarm.NodeAvailabilityActive {
+			continue
+		}
+		if svc.Spec.TaskTemplate.Placement != nil &&
+			!satisfies(node, svc.Spec.TaskTemplate.Placement.Constraints) {
+			continue
+		}
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		if load[ids[i]] != load[ids[j]] {
+			return load[ids[i]] < load[ids[j]]
+		}
+		return ids[i] < ids[j]
+	})
+	if len(ids) == 0 {
+		return ""
+	}
+	return ids[0]
+}
+
+// ServiceCreate creates a service in the swarm which the engine manages, and starts its tasks
+func (e *Engine) ServiceCreate(ctx context.Context, spec swarm.ServiceSpec,
+	options types.ServiceCreateOptions) (types.ServiceCreateResponse, error) {
+
+	e.mux.Lock()
+	defer e.mux.Unlock()
+	if err := e.injected("ServiceCreate"); err != nil {
+		return types.ServiceCreateResponse{}, err
+	}
+	c, err := e.manager()
+	if err != nil {
+		return types.ServiceCreateResponse{}, err
+	}
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	if _, err := c.service(spec.Name); err == nil {
+		return types.ServiceCreateResponse{}, fmt.Errorf("Error response from daemon: rpc error: " +
+			"code = AlreadyExists desc = name conflicts with an existing object")
+	}
+	svc := &swarm.Service{
+		ID:   newID()[:25],
+		Meta: swarm.Meta{Version: swarm.Version{Index: 1}, CreatedAt: time.Now(), UpdatedAt: time.Now()},
+		Spec: spec,
+	}
+	c.services[svc.ID] = svc
+	c.reconcile(svc)
+	return types.ServiceCreateResponse{ID: svc.ID}, nil
+}
+
+// ServiceInspectWithRaw inspects a service of the swarm which the engine manages
+func (e *Engine) ServiceInspectWithRaw(ctx context.Context, serviceID string,
+	options types.ServiceInspectOptions) (swarm.Service, []byte, error) {
+
+	e.mux.Lock()
+	defer e.mux.Unlock()
+	if err := e.injected("ServiceInspectWithRaw"); err != nil {
+		return swarm.Service{}, nil, err
+	}
+	c, err := e.manager()
+	if err != nil {
+		return swarm.Service{}, nil, err
+	}
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	svc, err := c.service(serviceID)
+	if err != nil {
+		return swarm.Service{}, nil, err
+	}
+	return *svc, nil, nil
+}
+
+// ServiceUpdate updates a service of the swarm which the engine manages, if it is still at the
+// given version, and replaces its tasks
+func (e *Engine) ServiceUpdate(ctx context.Context, serviceID string, version swarm.Version,
+	spec swarm.ServiceSpec, options types.ServiceUpdateOptions) (types.ServiceUpdateResponse, error) {
+
+	e.mux.Lock()
+	defer e.mux.Unlock()
+	if err := e.injected("ServiceUpdate"); err != nil {
+		return types.ServiceUpdateResponse{}, err
+	}
+	c, err := e.manager()
+	if err != nil {
+		return types.ServiceUpdateResponse{}, err
+	}
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	svc, err := c.service(serviceID)
+	if err != nil {
+		return types.ServiceUpdateResponse{}, err
+	}
+	if svc.Version.Index != version.Index {
+		return types.ServiceUpdateResponse{}, fmt.Errorf("Error response from daemon: rpc error: " +
+			"code = Unknown desc = update out of sequence")
+	}
+	previous := svc.Spec
+	svc.PreviousSpec = &previous
+	svc.Spec = spec
+	svc.Version.Index++
+	svc.UpdatedAt = time.Now()
+	c.reconcile(svc)
+	return types.ServiceUpdateResponse{}, nil
+}
+
+// ServiceRemove removes a service of the swarm which the engine manages, along with its tasks
+func (e *Engine) ServiceRemove(ctx context.Context, serviceID string) error {
+	e.mux.Lock()
+	defer e.mux.Unlock()
+	if err := e.injected("ServiceRemove"); err != nil {
+		return err
+	}
+	c, err := e.manager()
+	if err != nil {
+		return err
+	}
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	svc, err := c.service(serviceID)
+	if err != nil {
+		return err
+	}
+	delete(c.services, svc.ID)
+	for id, task := range c.tasks {
+		if task.ServiceID == svc.ID {
+			delete(c.tasks, id)
+		}
+	}
+	return nil
+}
+
+// TaskList lists the tasks of the swarm which the engine manages, the service and desired-state
+// filters are supported
+func (e *Engine) TaskList(ctx context.Context, options types.TaskListOptions) ([]swarm.Task, error) {
+	e.mux.Lock()
+	defer e.mux.Unlock()
+	if err := e.injected("TaskList"); err != nil {
+		return nil, err
+	}
+	c, err := e.manager()
+	if err != nil {
+		return nil, err
+	}
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	services := map[string]bool{}
+	for _, name := range options.Filters.Get("service") {
+		if svc, err := c.service(name); err == nil {
+			services[svc.ID] = true
+		}
+	}
+	out := []swarm.Task{}
+	for _, task := range c.tasks {
+		if options.Filters.Contains("service") && !services[task.ServiceID] {
+			continue
+		}
+		if options.Filters.Contains("desired-state") &&
+			!options.Filters.ExactMatch("desired-state", string(task.DesiredState)) {
+			continue
+		}
+		out = append(out, *task)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Slot != out[j].Slot {
+			return out[i].Slot < out[j].Slot
+		}
+		return out[i].CreatedAt.Before(out[j].CreatedAt)
+	})
+	return out, nil
+}
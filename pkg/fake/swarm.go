@@ -0,0 +1,252 @@
+/**
+ * Copyright 2019 Whiteblock Inc. All rights reserved.
+ * Use of this source code is governed by a BSD-style
+ * license that can be found in the LICENSE file.
+ */
+
+package fake
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/docker/docker/api/types/swarm"
+)
+
+var errNotManager = fmt.Errorf("Error response from daemon: This node is not a swarm manager. " +
+	"Use \"docker swarm init\" or \"docker swarm join\" to connect this node to swarm and try again.")
+
+var errInSwarm = fmt.Errorf("Error response from daemon: This node is already part of a swarm. " +
+	"Use \"docker swarm leave\" to leave this swarm and join another one.")
+
+// clusters are the swarms of all of the engines, by their join tokens, so that an engine can join
+// the swarm of another one
+var clusters = struct {
+	sync.Mutex
+	byToken map[string]*cluster
+}{byToken: map[string]*cluster{}}
+
+// cluster is a swarm, which is shared by the engines which are in it
+type cluster struct {
+	mux      sync.Mutex
+	swarm    swarm.Swarm
+	nodes    map[string]*swarm.Node
+	services map[string]*swarm.Service
+	tasks    map[string]*swarm.Task
+}
+
+func newCluster(spec swarm.Spec) *cluster {
+	out := &cluster{
+		swarm: swarm.Swarm{
+			ClusterInfo: swarm.ClusterInfo{ID: newID()[:25], Spec: spec},
+			JoinTokens: swarm.JoinTokens{
+				Worker:  "SWMTKN-1-" + newID()[:50] + "-" + newID()[:25],
+				Manager: "SWMTKN-1-" + newID()[:50] + "-" + newID()[:25],
+			},
+		},
+		nodes:    map[string]*swarm.Node{},
+		services: map[string]*swarm.Service{},
+		tasks:    map[string]*swarm.Task{},
+	}
+	clusters.Lock()
+	defer clusters.Unlock()
+	clusters.byToken[out.swarm.JoinTokens.Worker] = out
+	clusters.byToken[out.swarm.JoinTokens.Manager] = out
+	return out
+}
+
+// add adds a node to the cluster, and gives its ID
+func (c *cluster) add(hostname string, addr string, role swarm.NodeRole) string {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	id := newID()[:25]
+	c.nodes[id] = &swarm.Node{
+		ID:          id,
+		Meta:        swarm.Meta{Version: swarm.Version{Index: 1}, CreatedAt: time.Now()},
+		Spec:        swarm.NodeSpec{Role: role, Availability: swarm.NodeAvailabilityActive},
+		Description: swarm.NodeDescription{Hostname: hostname},
+		Status:      swarm.NodeStatus{State: swarm.NodeStateReady, Addr: addr},
+	}
+	return id
+}
+
+// remove takes the node out of the cluster, the cluster can no longer be joined once it is empty
+func (c *cluster) remove(id string) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	delete(c.nodes, id)
+	if len(c.nodes) > 0 {
+		return
+	}
+	clusters.Lock()
+	defer clusters.Unlock()
+	delete(clusters.byToken, c.swarm.JoinTokens.Worker)
+	delete(clusters.byToken, c.swarm.JoinTokens.Manager)
+}
+
+func (c *cluster) isManager(id string) bool {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	node, ok := c.nodes[id]
+	return ok && node.Spec.Role == swarm.NodeRoleManager
+}
+
+// swarmInfo gives the state of the engine in its swarm, only managers know which cluster it is.
+// The lock must be held.
+func (e *Engine) swarmInfo() swarm.Info {
+	if e.cluster == nil {
+		return swarm.Info{LocalNodeState: swarm.LocalNodeStateInactive}
+	}
+	info := swarm.Info{
+		NodeID:           e.nodeID,
+		LocalNodeState:   swarm.LocalNodeStateActive,
+		ControlAvailable: e.cluster.isManager(e.nodeID),
+	}
+	e.cluster.mux.Lock()
+	defer e.cluster.mux.Unlock()
+	info.NodeAddr = e.cluster.nodes[e.nodeID].Status.Addr
+	for _, node := range e.cluster.nodes {
+		if node.Spec.Role == swarm.NodeRoleManager {
+			info.RemoteManagers = append(info.RemoteManagers, swarm.Peer{NodeID: node.ID, Addr: node.Status.Addr})
+		}
+	}
+	info.Nodes = len(e.cluster.nodes)
+	info.Managers = len(info.RemoteManagers)
+	if info.ControlAvailable {
+		info.Cluster = &swarm.ClusterInfo{ID: e.cluster.swarm.ID}
+	}
+	return info
+}
+
+// SwarmInit makes the engine the manager of a new swarm
+func (e *Engine) SwarmInit(ctx context.Context, req swarm.InitRequest) (string, error) {
+	e.mux.Lock()
+	defer e.mux.Unlock()
+	if err := e.injected("SwarmInit"); err != nil {
+		return "", err
+	}
+	if e.cluster != nil {
+		return "", errInSwarm
+	}
+	e.cluster = newCluster(req.Spec)
+	e.nodeID = e.cluster.add(e.host, req.AdvertiseAddr, swarm.NodeRoleManager)
+	return e.nodeID, nil
+}
+
+// SwarmJoin joins the engine to the swarm of the engine which gave out the token
+func (e *Engine) SwarmJoin(ctx context.Context, req swarm.JoinRequest) error {
+	e.mux.Lock()
+	defer e.mux.Unlock()
+	if err := e.injected("SwarmJoin"); err != nil {
+		return err
+	}
+	if e.cluster != nil {
+		return errInSwarm
+	}
+	clusters.Lock()
+	joining, ok := clusters.byToken[req.JoinToken]
+	clusters.Unlock()
+	if !strings.HasPrefix(req.JoinToken, "SWMTKN-1-") || !ok {
+		return fmt.Errorf("Error response from daemon: invalid join token")
+	}
+	role := swarm.NodeRoleWorker
+	if req.JoinToken == joining.swarm.JoinTokens.Manager {
+		role = swarm.NodeRoleManager
+	}
+	e.cluster = joining
+	e.nodeID = joining.add(e.host, req.AdvertiseAddr, role)
+	return nil
+}
+
+// SwarmInspect inspects the swarm which the engine manages
+func (e *Engine) SwarmInspect(ctx context.Context) (swarm.Swarm, error) {
+	e.mux.Lock()
+	defer e.mux.Unlock()
+	if err := e.injected("SwarmInspect"); err != nil {
+		return swarm.Swarm{}, err
+	}
+	if e.cluster == nil || !e.cluster.isManager(e.nodeID) {
+		return swarm.Swarm{}, errNotManager
+	}
+	return e.cluster.swarm, nil
+}
+
+// SwarmLeave takes the engine out of its swarm, a manager only leaves when it is forced to
+func (e *Engine) SwarmLeave(ctx context.Context, force bool) error {
+	e.mux.Lock()
+	defer e.mux.Unlock()
+	if err := e.injected("SwarmLeave"); err != nil {
+		return err
+	}
+	if e.cluster == nil {
+		return fmt.Errorf("Error response from daemon: This node is not part of a swarm")
+	}
+	if e.cluster.isManager(e.nodeID) && !force {
+		return fmt.Errorf("Error response from daemon: You are attempting to leave the swarm on a " +
+			"node that is participating as a manager. Use `--force` to suppress this message.")
+	}
+	e.cluster.remove(e.nodeID)
+	e.cluster = nil
+	e.nodeID = ""
+	return nil
+}
+
+// NodeInspectWithRaw inspects a node of the swarm which the engine manages
+func (e *Engine) NodeInspectWithRaw(ctx context.Context, nodeID string) (swarm.Node, []byte, error) {
+	e.mux.Lock()
+	defer e.mux.Unlock()
+	if err := e.injected("NodeInspectWithRaw"); err != nil {
+		return swarm.Node{}, nil, err
+	}
+	if e.cluster == nil || !e.cluster.isManager(e.nodeID) {
+		return swarm.Node{}, nil, errNotManager
+	}
+	e.cluster.mux.Lock()
+	defer e.cluster.mux.Unlock()
+	node, ok := e.cluster.nodes[nodeID]
+	if !ok {
+		return swarm.Node{}, nil, fmt.Errorf("Error: No such node: %s", nodeID)
+	}
+	out := *node
+	out.Spec.Labels = copyLabels(node.Spec.Labels)
+	return out, nil, nil
+}
+
+// NodeUpdate updates a node of the swarm which the engine manages, if it is still at the given version
+func (e *Engine) NodeUpdate(ctx context.Context, nodeID string, version swarm.Version,
+	spec swarm.NodeSpec) error {
+
+	e.mux.Lock()
+	defer e.mux.Unlock()
+	if err := e.injected("NodeUpdate"); err != nil {
+		return err
+	}
+	if e.cluster == nil || !e.cluster.isManager(e.nodeID) {
+		return errNotManager
+	}
+	e.cluster.mux.Lock()
+	defer e.cluster.mux.Unlock()
+	node, ok := e.cluster.nodes[nodeID]
+	if !ok {
+		return fmt.Errorf("Error: No such node: %s", nodeID)
+	}
+	if node.Version.Index != version.Index {
+		return fmt.Errorf("Error response from daemon: update out of sequence")
+	}
+	node.Spec = spec
+	node.Spec.Labels = copyLabels(spec.Labels)
+	node.Version.Index++
+	node.UpdatedAt = time.Now()
+	return nil
+}
+
+func copyLabels(labels map[string]string) map[string]string {
+	out := map[string]string{}
+	for key, value := range labels {
+		out[key] = value
+	}
+	return out
+}
@@ -13,10 +13,12 @@ import (
 	"fmt"
 	"io"
 	"io/ioutil"
+	"net"
 	"net/http"
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/whiteblock/genesis/pkg/config"
 
@@ -27,16 +29,34 @@ import (
 //RemoteSources represents a remote file source
 type RemoteSources interface {
 	GetTarReader(testnetID string, file command.File) (io.Reader, error)
+
+	// StreamTar gives the file as a tar archive which is streamed rather than held in memory,
+	// for files which may be large. It must be closed once it has been read.
+	StreamTar(testnetID string, file command.File) (io.ReadCloser, error)
+
+	PutFile(testnetID string, file command.File, content io.Reader) error
 }
 
 type remoteSources struct {
-	log  logrus.Ext1FieldLogger
-	conf config.Config
+	log    logrus.Ext1FieldLogger
+	conf   config.Config
+	stream *http.Client
 }
 
 //NewRemoteSources creates a new instance of RemoteSources
 func NewRemoteSources(conf config.Config, log logrus.Ext1FieldLogger) RemoteSources {
-	return &remoteSources{conf: conf, log: log}
+	return &remoteSources{conf: conf, log: log, stream: newStreamClient(conf.FileHandler.APITimeout)}
+}
+
+// newStreamClient creates the client for the transfers which may be large, for which only
+// connecting and waiting for the response are bounded by the timeout, not the transfer itself
+func newStreamClient(timeout time.Duration) *http.Client {
+	return &http.Client{Transport: &http.Transport{
+		Proxy:                 http.ProxyFromEnvironment,
+		DialContext:           (&net.Dialer{Timeout: timeout}).DialContext,
+		TLSHandshakeTimeout:   timeout,
+		ResponseHeaderTimeout: timeout,
+	}}
 }
 
 func (rf remoteSources) getTarHeader(file command.File, size int64) *tar.Header {
@@ -136,3 +156,156 @@ func (rf remoteSources) GetTarReader(testnetID string, file command.File) (io.Re
 	return &buf, nil
 
 }
+
+// cancelCloser is the body of a response, which cancels its request once it is closed
+type cancelCloser struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (cc cancelCloser) Close() error {
+	defer cc.cancel()
+	return cc.ReadCloser.Close()
+}
+
+// spooled is a temporary file which is removed once it is closed
+type spooled struct {
+	*os.File
+}
+
+func (s spooled) Close() error {
+	defer os.Remove(s.Name())
+	return s.File.Close()
+}
+
+// open opens the file without reading it, giving its size, which is -1 if it is not known
+func (rf remoteSources) open(testnetID string, file command.File) (io.ReadCloser, int64, error) {
+	if rf.conf.LocalMode {
+		f, err := os.Open(file.ID)
+		if err != nil {
+			return nil, 0, err
+		}
+		info, err := f.Stat()
+		if err != nil {
+			f.Close()
+			return nil, 0, err
+		}
+		return f, info.Size(), nil
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	req, err := rf.getRequest(ctx, testnetID, file.ID)
+	if err != nil {
+		cancel()
+		return nil, 0, err
+	}
+	resp, err := rf.stream.Do(req)
+	if err != nil {
+		cancel()
+		return nil, 0, err
+	}
+	if resp.StatusCode != 200 {
+		defer cancel()
+		rf.log.WithFields(logrus.Fields{
+			"file":       file.ID,
+			"code":       resp.StatusCode,
+			"definition": testnetID}).Warn("got back a non-200 http code")
+		res, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		return nil, 0, fmt.Errorf(string(res))
+	}
+	return cancelCloser{ReadCloser: resp.Body, cancel: cancel}, resp.ContentLength, nil
+}
+
+// spool copies the content to a temporary file, for when its size is not known up front
+func spool(content io.ReadCloser) (io.ReadCloser, int64, error) {
+	defer content.Close()
+	f, err := ioutil.TempFile("", "genesis-file-")
+	if err != nil {
+		return nil, 0, err
+	}
+	out := spooled{File: f}
+	size, err := io.Copy(f, content)
+	if err == nil {
+		_, err = f.Seek(0, io.SeekStart)
+	}
+	if err != nil {
+		out.Close()
+		return nil, 0, err
+	}
+	return out, size, nil
+}
+
+// StreamTar fetches the file from the file handler service and streams it as a tar archive.
+// The size of the file is needed for its tar header, so a file whose size is not given by the
+// service is first copied to disk.
+func (rf remoteSources) StreamTar(testnetID string, file command.File) (io.ReadCloser, error) {
+	content, size, err := rf.open(testnetID, file)
+	if err != nil {
+		return nil, err
+	}
+	if size < 0 {
+		rf.log.WithField("file", file.ID).Debug("the size of the file is unknown, spooling it to disk")
+		content, size, err = spool(content)
+		if err != nil {
+			return nil, err
+		}
+	}
+	pr, pw := io.Pipe()
+	go func() {
+		defer content.Close()
+		tw := tar.NewWriter(pw)
+		err := tw.WriteHeader(rf.getTarHeader(file, size))
+		if err == nil {
+			_, err = io.CopyN(tw, content, size)
+		}
+		if err == nil {
+			err = tw.Close()
+		}
+		rf.log.WithFields(logrus.Fields{
+			"file":  file.ID,
+			"dest":  file.Destination,
+			"bytes": size,
+			"error": err,
+		}).Debug("finished streaming a file")
+		pw.CloseWithError(err)
+	}()
+	return pr, nil
+}
+
+// PutFile uploads the contents to the file handler service as the file, or writes them to the
+// path given by its id in local mode
+func (rf remoteSources) PutFile(testnetID string, file command.File, content io.Reader) error {
+	if rf.conf.LocalMode {
+		rf.log.Info("writing a file locally")
+		f, err := os.Create(file.ID)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		_, err = io.Copy(f, content)
+		return err
+	}
+	req, err := http.NewRequest("PUT",
+		fmt.Sprintf("%s/api/v1/files/definitions/%s/%s", rf.conf.FileHandler.APIEndpoint, testnetID, file.ID),
+		content)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/octet-stream")
+
+	resp, err := rf.stream.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		rf.log.WithFields(logrus.Fields{
+			"file":       file.ID,
+			"code":       resp.StatusCode,
+			"definition": testnetID}).Warn("got back a non-2xx http code")
+		res, _ := ioutil.ReadAll(resp.Body)
+		return fmt.Errorf(string(res))
+	}
+	rf.log.WithFields(logrus.Fields{"file": file.ID}).Debug("uploaded a file")
+	return nil
+}
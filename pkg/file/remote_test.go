@@ -0,0 +1,132 @@
+/**
+ * Copyright 2019 Whiteblock Inc. All rights reserved.
+ * Use of this source code is governed by a BSD-style
+ * license that can be found in the LICENSE file.
+ */
+
+package file
+
+import (
+	"archive/tar"
+	"bytes"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/whiteblock/genesis/pkg/config"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/whiteblock/definition/command"
+	"github.com/whiteblock/utility/common"
+)
+
+func TestRemoteSources_StreamTar(t *testing.T) {
+	content := strings.Repeat("block", 1<<16)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/chunked") {
+			w.(http.Flusher).Flush() // the size is not known up front
+		}
+		w.Write([]byte(content))
+	}))
+	defer server.Close()
+
+	var conf config.Config
+	conf.FileHandler.APIEndpoint = server.URL
+	rs := NewRemoteSources(conf, logrus.New())
+
+	for _, id := range []string{"sized", "chunked"} {
+		rdr, err := rs.StreamTar("def", command.File{ID: id, Destination: "/tmp/",
+			Meta: common.Metadata{Filename: "chain.tar"}})
+		require.NoError(t, err)
+
+		tr := tar.NewReader(rdr)
+		hdr, err := tr.Next()
+		require.NoError(t, err)
+		assert.Equal(t, "chain.tar", hdr.Name)
+		assert.Equal(t, int64(len(content)), hdr.Size)
+		data, err := ioutil.ReadAll(tr)
+		require.NoError(t, err)
+		assert.True(t, bytes.Equal([]byte(content), data))
+		require.NoError(t, rdr.Close())
+	}
+}
+
+// slowReader gives its content in two halves, with a delay before the second one
+type slowReader struct {
+	data  []byte
+	delay time.Duration
+	read  int
+}
+
+func (sr *slowReader) Read(p []byte) (int, error) {
+	if sr.read == len(sr.data) {
+		return 0, io.EOF
+	}
+	if sr.read > 0 {
+		time.Sleep(sr.delay)
+	}
+	end := len(sr.data)
+	if sr.read == 0 {
+		end /= 2
+	}
+	n := copy(p, sr.data[sr.read:end])
+	sr.read += n
+	return n, nil
+}
+
+func TestRemoteSources_SlowTransfer(t *testing.T) {
+	content := strings.Repeat("block", 1<<12)
+	var uploaded []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPut {
+			uploaded, _ = ioutil.ReadAll(r.Body)
+			return
+		}
+		w.Header().Set("Content-Length", strconv.Itoa(len(content)))
+		w.Write([]byte(content[:len(content)/2]))
+		w.(http.Flusher).Flush()
+		time.Sleep(150 * time.Millisecond)
+		w.Write([]byte(content[len(content)/2:]))
+	}))
+	defer server.Close()
+
+	var conf config.Config
+	conf.FileHandler.APIEndpoint = server.URL
+	conf.FileHandler.APITimeout = 50 * time.Millisecond
+	rs := NewRemoteSources(conf, logrus.New())
+
+	rdr, err := rs.StreamTar("def", command.File{ID: "slow", Destination: "/tmp/chain.tar"})
+	require.NoError(t, err)
+	tr := tar.NewReader(rdr)
+	_, err = tr.Next()
+	require.NoError(t, err)
+	data, err := ioutil.ReadAll(tr)
+	require.NoError(t, err)
+	assert.Equal(t, content, string(data))
+	require.NoError(t, rdr.Close())
+
+	err = rs.PutFile("def", command.File{ID: "slow"},
+		&slowReader{data: []byte(content), delay: 150 * time.Millisecond})
+	require.NoError(t, err)
+	assert.Equal(t, content, string(uploaded))
+}
+
+func TestRemoteSources_StreamTar_Error(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("no such file"))
+	}))
+	defer server.Close()
+
+	var conf config.Config
+	conf.FileHandler.APIEndpoint = server.URL
+	_, err := NewRemoteSources(conf, logrus.New()).StreamTar("def", command.File{ID: "missing"})
+	assert.EqualError(t, err, "no such file")
+}
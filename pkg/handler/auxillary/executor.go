@@ -9,17 +9,15 @@ package auxillary
 import (
 	"context"
 	"fmt"
-	"io/ioutil"
-	"os"
-	"path/filepath"
 	"strings"
 	"time"
 
 	"github.com/whiteblock/genesis/pkg/config"
 	"github.com/whiteblock/genesis/pkg/entity"
+	"github.com/whiteblock/genesis/pkg/repository"
+	"github.com/whiteblock/genesis/pkg/service"
 	"github.com/whiteblock/genesis/pkg/usecase"
 
-	"github.com/innodv/errors/await"
 	"github.com/sirupsen/logrus"
 	"github.com/whiteblock/definition/command"
 	"golang.org/x/sync/semaphore"
@@ -28,13 +26,27 @@ import (
 // Executor handles the  processing of mutliple commands
 type Executor interface {
 	ExecuteCommands(cmds []command.Command) entity.Result
+	// Prepare stores the TLS credentials of the instructions
 	Prepare(inst *command.Instructions) error
+	// Cleanup wipes the TLS credentials of the test once it is over, stops following the
+	// events of its containers and releases the addresses and host capacity given to them
+	Cleanup(testID string) error
+	// Failure gives the crash of a container which failed the test, if there was one
+	Failure(testID string) *entity.ContainerEvent
+	// Summarize stops sampling the resource usage of the containers of the test, and gives the
+	// summary of it by container
+	Summarize(testID string) map[string]entity.ContainerStatsSummary
 }
 
 type executor struct {
-	usecase usecase.DockerUseCase
-	conf    config.Execution
-	log     logrus.Ext1FieldLogger
+	usecase   usecase.DockerUseCase
+	docker    service.DockerService
+	inventory service.HostInventory
+	creds     repository.CredentialStore
+	sampler   service.StatsSampler
+	watcher   service.EventWatcher
+	conf      config.Execution
+	log       logrus.Ext1FieldLogger
 }
 
 // ErrDockerConnFailed is the error for when the docker daemon is unreachable
@@ -45,37 +57,42 @@ var ErrDockerConnFailed = entity.NewFatalResult("could not connect to docker")
 func NewExecutor(
 	conf config.Execution,
 	usecase usecase.DockerUseCase,
+	docker service.DockerService,
+	inventory service.HostInventory,
+	creds repository.CredentialStore,
+	sampler service.StatsSampler,
+	watcher service.EventWatcher,
 	log logrus.Ext1FieldLogger) Executor {
-	return &executor{usecase: usecase, conf: conf, log: log}
+	return &executor{usecase: usecase, docker: docker, inventory: inventory, creds: creds, sampler: sampler, watcher: watcher,
+		conf: conf, log: log}
 }
 
 func (exec executor) Prepare(inst *command.Instructions) error {
-	dir := filepath.Join("/tmp", inst.ID)
-	_, err := os.Lstat(dir)
-	if err == nil { //already exists
+	creds := entity.NewCredentials(inst.Auth)
+	if creds.Empty() { // local mode does not need any
 		return nil
 	}
-	err = os.Mkdir(dir, 0755)
-	if err != nil {
-		return err
-	}
-	exec.log.WithFields(logrus.Fields{
-		"testID": inst.ID,
-		"dir":    dir,
-	}).Info("creating the tls auth files")
-	errChan := make(chan error)
-	go func() {
-		errChan <- ioutil.WriteFile(filepath.Join(dir, "ca.cert"), inst.Auth.CACertPEM(), 0644)
-	}()
-
-	go func() {
-		errChan <- ioutil.WriteFile(filepath.Join(dir, "client.cert"), inst.Auth.ClientCertPEM(), 0644)
-	}()
-
-	go func() {
-		errChan <- ioutil.WriteFile(filepath.Join(dir, "client.key"), inst.Auth.ClientPKPEM(), 0644)
-	}()
-	return await.AwaitErrors(errChan, 3)
+	defer creds.Wipe()
+	return exec.creds.Put(inst.ID, creds)
+}
+
+func (exec executor) Cleanup(testID string) error {
+	exec.log.WithField("testID", testID).Debug("no longer following the events of the containers")
+	exec.watcher.Stop(testID)
+	exec.log.WithField("testID", testID).Debug("releasing the clients, the addresses and the host capacity")
+	exec.docker.ReleaseTest(testID)
+	exec.inventory.ReleaseTest(testID)
+	exec.log.WithField("testID", testID).Debug("wiping the tls credentials")
+	return exec.creds.Remove(testID)
+}
+
+func (exec executor) Failure(testID string) *entity.ContainerEvent {
+	return exec.watcher.Failure(testID)
+}
+
+func (exec executor) Summarize(testID string) map[string]entity.ContainerStatsSummary {
+	exec.log.WithField("testID", testID).Debug("summarizing the resource usage")
+	return exec.sampler.Stop(testID)
 }
 
 func (exec executor) ExecuteCommands(cmds []command.Command) entity.Result {
@@ -103,6 +103,11 @@ func (dh deliveryHandler) process(msg amqp.Delivery,
 		return dh.destructMsg(inst), entity.NewFatalResult(err)
 	}
 	result = dh.aux.ExecuteCommands(cmds)
+	if crash := dh.failure(inst); crash != nil && !result.IsFatal() {
+		dh.log.WithFields(logrus.Fields{"testID": inst.ID, "crash": *crash}).Error(
+			"a container crashed, failing the test")
+		result = crash.Result()
+	}
 	if result.IsDelayed() {
 		inst.Next()
 		out, err = queue.GetNextMessage(msg, inst)
@@ -155,6 +160,38 @@ func (dh deliveryHandler) process(msg amqp.Delivery,
 	return
 }
 
+// cleanup wipes what was kept for the test, once nothing more will be run for it
+func (dh deliveryHandler) cleanup(inst *command.Instructions) {
+	if len(inst.ID) == 0 {
+		return
+	}
+	err := dh.aux.Cleanup(inst.ID)
+	if err != nil {
+		dh.log.WithFields(logrus.Fields{"testID": inst.ID, "error": err}).Error(
+			"failed to wipe the tls credentials")
+	}
+}
+
+// failure gives the crash which failed the test while its commands ran, if there was one
+func (dh deliveryHandler) failure(inst *command.Instructions) *entity.ContainerEvent {
+	if len(inst.ID) == 0 {
+		return nil
+	}
+	return dh.aux.Failure(inst.ID)
+}
+
+// summarize stops sampling the resource usage of the test once nothing more will be run for it,
+// which sends out the summary of it on the status queue, and attaches the summary to its result
+func (dh deliveryHandler) summarize(inst *command.Instructions, result *entity.Result) {
+	if len(inst.ID) == 0 {
+		return
+	}
+	stats := dh.aux.Summarize(inst.ID)
+	if len(stats) > 0 {
+		*result = result.InjectMeta(map[string]interface{}{"stats": stats})
+	}
+}
+
 func (dh deliveryHandler) isDebugMode(inst *command.Instructions) bool {
 	if dh.conf.Execution.DebugMode {
 		return true
@@ -189,6 +226,12 @@ func (dh deliveryHandler) Process(msg amqp.Delivery) (out amqp.Publishing,
 		result = result.Trap()
 		out.Headers["x-delay"] = int32(dh.conf.Execution.DMCompletionDelay.Milliseconds())
 	}
+	if result.IsAllDone() || result.IsFatal() {
+		dh.summarize(&inst, &result)
+		dh.cleanup(&inst)
+	} else if result.IsTrap() {
+		dh.summarize(&inst, &result) // nothing more is run, though the containers are left up
+	}
 
 	if result.IsAllDone() || result.IsTrap() || result.IsFatal() || result.IsIgnore() {
 		stat.Finished = true
@@ -52,6 +52,30 @@ func TestDeliveryHandler_Process_Successful(t *testing.T) {
 
 }
 
+func TestDeliveryHandler_Process_Summary(t *testing.T) {
+	stats := map[string]entity.ContainerStatsSummary{"node0": {Host: "10.0.0.2", Samples: 3}}
+	aux := new(auxMocks.Executor)
+	aux.On("Prepare", mock.Anything).Return(nil).Once()
+	aux.On("ExecuteCommands", mock.Anything).Return(entity.NewSuccessResult()).Once()
+	aux.On("Failure", "test1").Return(nil).Once()
+	aux.On("Summarize", "test1").Return(stats).Once()
+	aux.On("Cleanup", "test1").Return(nil).Once()
+
+	dh := NewDeliveryHandler(aux, config.Config{}, 1, logrus.New())
+	body, err := json.Marshal(command.Instructions{ID: "test1", Commands: [][]command.Command{{command.Command{
+		Order:  command.Order{Type: "createContainer", Payload: map[string]interface{}{}},
+		Target: command.Target{IP: "127.0.0.1"},
+	}}}})
+	require.NoError(t, err)
+
+	_, _, res := dh.Process(amqp.Delivery{Body: body})
+	require.NoError(t, res.Error)
+	assert.True(t, res.IsAllDone())
+	assert.Equal(t, stats, res.Meta["stats"], "the summary of the resource usage is in the result")
+
+	aux.AssertExpectations(t)
+}
+
 func TestDeliveryHandler_Process_Unsuccessful(t *testing.T) {
 	aux := new(auxMocks.Executor)
 
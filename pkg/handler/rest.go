@@ -15,8 +15,10 @@ import (
 	"github.com/whiteblock/definition/command"
 	"github.com/whiteblock/genesis/pkg/entity"
 	"github.com/whiteblock/genesis/pkg/handler/auxillary"
+	"github.com/whiteblock/genesis/pkg/service"
 	util "github.com/whiteblock/utility/utils"
 
+	"github.com/gorilla/mux"
 	"github.com/sirupsen/logrus"
 )
 
@@ -28,19 +30,28 @@ type RestHandler interface {
 	AddCommands(w http.ResponseWriter, r *http.Request)
 	//HealthCheck handles the reporting of the current health of this service
 	HealthCheck(w http.ResponseWriter, r *http.Request)
+	//ListHosts handles the listing of the hosts of the inventory
+	ListHosts(w http.ResponseWriter, r *http.Request)
+	//RegisterHost handles the addition of a host to the inventory
+	RegisterHost(w http.ResponseWriter, r *http.Request)
+	//RemoveHost handles the removal of a host from the inventory
+	RemoveHost(w http.ResponseWriter, r *http.Request)
 }
 
 type restHandler struct {
-	aux auxillary.Executor
-	log logrus.Ext1FieldLogger
+	aux       auxillary.Executor
+	inventory service.HostInventory
+	log       logrus.Ext1FieldLogger
 }
 
 //NewRestHandler creates a new rest handler
-func NewRestHandler(aux auxillary.Executor, log logrus.Ext1FieldLogger) RestHandler {
+func NewRestHandler(aux auxillary.Executor, inventory service.HostInventory,
+	log logrus.Ext1FieldLogger) RestHandler {
 	log.Debug("creating a new rest handler")
 	out := &restHandler{
-		aux: aux,
-		log: log,
+		aux:       aux,
+		inventory: inventory,
+		log:       log,
 	}
 	return out
 }
@@ -83,6 +94,13 @@ func (rh *restHandler) process(inst *command.Instructions) (result entity.Result
 	}
 
 	result = rh.aux.ExecuteCommands(cmds)
+	if len(inst.ID) > 0 && !result.IsFatal() {
+		if crash := rh.aux.Failure(inst.ID); crash != nil {
+			rh.log.WithFields(logrus.Fields{"testID": inst.ID, "crash": *crash}).Error(
+				"a container crashed, failing the test")
+			result = crash.Result()
+		}
+	}
 
 	if result.IsFatal() {
 		rh.log.WithFields(logrus.Fields{"result": result, "error": result.Error.Error(),
@@ -97,10 +115,11 @@ func (rh *restHandler) process(inst *command.Instructions) (result entity.Result
 		rh.log.WithField("result", result).Debug("propogating the trap")
 	} else if isLastOne && result.IsSuccess() {
 		if inst.NeverTerminate() {
-			return result.Trap()
+			result = result.Trap()
+		} else {
+			rh.log.Debug("creating completion message")
+			result = entity.NewAllDoneResult()
 		}
-		rh.log.Debug("creating completion message")
-		result = entity.NewAllDoneResult()
 	} else if result.IsSuccess() {
 		result = entity.NewRequeueResult()
 		rh.log.WithField("remaining", len(inst.Commands)).Debug("creating message for next round")
@@ -114,6 +133,17 @@ func (rh *restHandler) process(inst *command.Instructions) (result entity.Result
 	} else {
 		rh.log.WithField("result", result).Debug("something went wrong, getting kickback message")
 	}
+	if (result.IsAllDone() || result.IsFatal()) && len(inst.ID) > 0 {
+		if stats := rh.aux.Summarize(inst.ID); len(stats) > 0 {
+			result = result.InjectMeta(map[string]interface{}{"stats": stats})
+		}
+		if err := rh.aux.Cleanup(inst.ID); err != nil {
+			rh.log.WithFields(logrus.Fields{"testID": inst.ID, "error": err}).Error(
+				"failed to clean up after the test")
+		}
+	} else if result.IsTrap() && len(inst.ID) > 0 {
+		rh.aux.Summarize(inst.ID) // nothing more is run, though the containers are left up
+	}
 	return
 }
 
@@ -125,6 +155,51 @@ func (rh *restHandler) HealthCheck(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+//ListHosts handles the listing of the hosts of the inventory
+func (rh *restHandler) ListHosts(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	err := json.NewEncoder(w).Encode(rh.inventory.Hosts())
+	if err != nil {
+		rh.log.Error(err)
+	}
+}
+
+//RegisterHost handles the addition of a host to the inventory
+func (rh *restHandler) RegisterHost(w http.ResponseWriter, r *http.Request) {
+	var spec entity.HostSpec
+	defer r.Body.Close()
+	err := json.NewDecoder(r.Body).Decode(&spec)
+	if err != nil {
+		http.Error(w, util.LogError(err).Error(), 400)
+		return
+	}
+	err = rh.inventory.Register(spec)
+	if errors.Is(err, service.ErrHostExists) {
+		http.Error(w, err.Error(), 409)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), 400)
+		return
+	}
+	w.WriteHeader(201)
+	w.Write([]byte("Success"))
+}
+
+//RemoveHost handles the removal of a host from the inventory
+func (rh *restHandler) RemoveHost(w http.ResponseWriter, r *http.Request) {
+	err := rh.inventory.Deregister(mux.Vars(r)["address"])
+	if errors.Is(err, service.ErrHostNotFound) {
+		http.Error(w, err.Error(), 404)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), 409)
+		return
+	}
+	w.Write([]byte("Success"))
+}
+
 func (rh *restHandler) run(inst *command.Instructions) {
 	retries := 0
 	for {
@@ -17,11 +17,15 @@ import (
 
 	"github.com/whiteblock/definition/command"
 	auxMocks "github.com/whiteblock/genesis/mocks/pkg/handler/auxillary"
+	"github.com/whiteblock/genesis/pkg/config"
 	"github.com/whiteblock/genesis/pkg/entity"
+	"github.com/whiteblock/genesis/pkg/service"
 
+	"github.com/gorilla/mux"
 	"github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 )
 
 var testCommands = command.Instructions{Commands: [][]command.Command{{
@@ -60,7 +64,7 @@ func TestRestHandler(t *testing.T) {
 		runChan <- cmds
 	}).Times(len(testCommands.Commands))
 
-	rh := NewRestHandler(aux, logrus.New())
+	rh := NewRestHandler(aux, nil, logrus.New())
 
 	recorder := httptest.NewRecorder()
 	go rh.AddCommands(recorder, req)
@@ -94,7 +98,7 @@ func TestRestHandler_Requeue(t *testing.T) {
 
 	}).Times(len(testCommands.Commands) * (maxRetries + 1))
 
-	rh := NewRestHandler(aux, logrus.New())
+	rh := NewRestHandler(aux, nil, logrus.New())
 
 	recorder := httptest.NewRecorder()
 	go rh.AddCommands(recorder, req)
@@ -128,7 +132,7 @@ func TestRestHandler_Fatal(t *testing.T) { //testCommands
 
 	}).Times(len(testCommands.Commands))
 
-	rh := NewRestHandler(aux, logrus.New())
+	rh := NewRestHandler(aux, nil, logrus.New())
 
 	recorder := httptest.NewRecorder()
 	rh.AddCommands(recorder, req)
@@ -147,9 +151,39 @@ func TestRestHandler_HealthCheck(t *testing.T) {
 	req, err := http.NewRequest("GET", "/health", bytes.NewReader([]byte{}))
 	assert.NoError(t, err)
 
-	rh := NewRestHandler(nil, logrus.New())
+	rh := NewRestHandler(nil, nil, logrus.New())
 	recorder := httptest.NewRecorder()
 	rh.HealthCheck(recorder, req)
 
 	assert.Equal(t, "OK", recorder.Body.String())
 }
+
+func TestRestHandler_Hosts(t *testing.T) {
+	inventory := service.NewHostInventory(config.Inventory{}, logrus.New())
+	rh := NewRestHandler(nil, inventory, logrus.New())
+	router := mux.NewRouter()
+	router.HandleFunc("/hosts", rh.ListHosts).Methods("GET")
+	router.HandleFunc("/hosts", rh.RegisterHost).Methods("POST")
+	router.HandleFunc("/hosts/{address}", rh.RemoveHost).Methods("DELETE")
+
+	serve := func(method, url, body string) *httptest.ResponseRecorder {
+		req, err := http.NewRequest(method, url, bytes.NewReader([]byte(body)))
+		require.NoError(t, err)
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, req)
+		return recorder
+	}
+
+	assert.Equal(t, 201, serve("POST", "/hosts", `{"address":"10.0.0.2","cpus":4}`).Code)
+	assert.Equal(t, 409, serve("POST", "/hosts", `{"address":"10.0.0.2"}`).Code)
+	assert.Equal(t, 400, serve("POST", "/hosts", `{"cpus":4}`).Code)
+
+	var hosts []entity.Host
+	require.NoError(t, json.Unmarshal(serve("GET", "/hosts", "").Body.Bytes(), &hosts))
+	require.Len(t, hosts, 1)
+	assert.Equal(t, float64(4), hosts[0].CPUs)
+
+	assert.Equal(t, 404, serve("DELETE", "/hosts/10.0.0.3", "").Code)
+	assert.Equal(t, 200, serve("DELETE", "/hosts/10.0.0.2", "").Code)
+	assert.Empty(t, inventory.Hosts())
+}
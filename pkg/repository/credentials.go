@@ -0,0 +1,208 @@
+/**
+ * Copyright 2019 Whiteblock Inc. All rights reserved.
+ * Use of this source code is governed by a BSD-style
+ * license that can be found in the LICENSE file.
+ */
+
+package repository
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/whiteblock/genesis/pkg/entity"
+
+	"github.com/pkg/errors"
+)
+
+// ErrNoCredentials is returned when there are no credentials for a test
+var ErrNoCredentials = errors.New("no tls credentials for the test")
+
+// CredentialStore holds the TLS credentials of each test, for as long as the test is running
+type CredentialStore interface {
+	//Put stores the credentials of the test, replacing any it already had
+	Put(testID string, creds entity.Credentials) error
+
+	//Get gives a copy of the credentials of the test, or ErrNoCredentials if there are none
+	Get(testID string) (entity.Credentials, error)
+
+	//Remove wipes the credentials of the test
+	Remove(testID string) error
+}
+
+const (
+	credentialDirMode  = 0700
+	credentialFileMode = 0600
+
+	caCertFile     = "ca.cert"
+	clientCertFile = "client.cert"
+	clientKeyFile  = "client.key"
+)
+
+type credentialStore struct {
+	dir   string
+	mux   sync.Mutex
+	creds map[string]entity.Credentials
+}
+
+// NewCredentialStore creates a new CredentialStore. If a directory is given, the credentials are
+// also written under it, readable only by the owner, so that they survive restarts. Otherwise they
+// are only kept in memory.
+func NewCredentialStore(dir string) CredentialStore {
+	return &credentialStore{dir: dir, creds: map[string]entity.Credentials{}}
+}
+
+func (cs *credentialStore) testDir(testID string) (string, error) {
+	if len(testID) == 0 || testID != filepath.Base(testID) || testID == ".." {
+		return "", fmt.Errorf("invalid test id %q", testID)
+	}
+	return filepath.Join(cs.dir, testID), nil
+}
+
+// Put stores the credentials of the test, replacing any it already had
+func (cs *credentialStore) Put(testID string, creds entity.Credentials) error {
+	if creds.Empty() {
+		return errors.New("the tls credentials are incomplete")
+	}
+	dir, err := cs.testDir(testID)
+	if err != nil {
+		return err
+	}
+	creds = copyCredentials(creds)
+
+	cs.mux.Lock()
+	defer cs.mux.Unlock()
+	old, exists := cs.creds[testID]
+	if exists && old.Fingerprint() == creds.Fingerprint() {
+		creds.Wipe()
+		return nil
+	}
+	if len(cs.dir) > 0 {
+		err = cs.write(dir, creds)
+		if err != nil {
+			creds.Wipe()
+			return err
+		}
+	}
+	if exists {
+		old.Wipe()
+	}
+	cs.creds[testID] = creds
+	return nil
+}
+
+func (cs *credentialStore) write(dir string, creds entity.Credentials) error {
+	err := os.MkdirAll(cs.dir, credentialDirMode)
+	if err != nil {
+		return errors.Wrap(err, "failed to create the credential directory")
+	}
+	err = os.Mkdir(dir, credentialDirMode)
+	if err != nil && !os.IsExist(err) {
+		return errors.Wrap(err, "failed to create the credential directory of the test")
+	}
+	err = os.Chmod(dir, credentialDirMode)
+	if err != nil {
+		return err
+	}
+	files := map[string][]byte{
+		caCertFile:     creds.CACert,
+		clientCertFile: creds.ClientCert,
+		clientKeyFile:  creds.ClientKey,
+	}
+	for name, data := range files {
+		path := filepath.Join(dir, name)
+		tmp := path + ".tmp"
+		err = ioutil.WriteFile(tmp, data, credentialFileMode)
+		if err == nil {
+			err = os.Chmod(tmp, credentialFileMode)
+		}
+		if err == nil {
+			err = os.Rename(tmp, path)
+		}
+		if err != nil {
+			os.Remove(tmp)
+			return errors.Wrapf(err, "failed to write %s", name)
+		}
+	}
+	return nil
+}
+
+// Get gives a copy of the credentials of the test, or ErrNoCredentials if there are none
+func (cs *credentialStore) Get(testID string) (entity.Credentials, error) {
+	dir, err := cs.testDir(testID)
+	if err != nil {
+		return entity.Credentials{}, err
+	}
+	cs.mux.Lock()
+	defer cs.mux.Unlock()
+	if creds, exists := cs.creds[testID]; exists {
+		return copyCredentials(creds), nil
+	}
+	if len(cs.dir) == 0 {
+		return entity.Credentials{}, ErrNoCredentials
+	}
+	creds, err := cs.read(dir)
+	if err != nil {
+		return entity.Credentials{}, err
+	}
+	cs.creds[testID] = creds
+	return copyCredentials(creds), nil
+}
+
+func (cs *credentialStore) read(dir string) (creds entity.Credentials, err error) {
+	stat, err := os.Stat(dir)
+	if os.IsNotExist(err) {
+		return creds, ErrNoCredentials
+	}
+	if err != nil {
+		return creds, err
+	}
+	if stat.Mode().Perm()&^credentialDirMode != 0 {
+		return creds, fmt.Errorf("%s is accessible by other users", dir)
+	}
+	for name, out := range map[string]*[]byte{
+		caCertFile:     &creds.CACert,
+		clientCertFile: &creds.ClientCert,
+		clientKeyFile:  &creds.ClientKey,
+	} {
+		*out, err = ioutil.ReadFile(filepath.Join(dir, name))
+		if os.IsNotExist(err) {
+			creds.Wipe()
+			return entity.Credentials{}, ErrNoCredentials
+		}
+		if err != nil {
+			creds.Wipe()
+			return entity.Credentials{}, err
+		}
+	}
+	return creds, nil
+}
+
+// Remove wipes the credentials of the test
+func (cs *credentialStore) Remove(testID string) error {
+	dir, err := cs.testDir(testID)
+	if err != nil {
+		return err
+	}
+	cs.mux.Lock()
+	defer cs.mux.Unlock()
+	if creds, exists := cs.creds[testID]; exists {
+		creds.Wipe()
+		delete(cs.creds, testID)
+	}
+	if len(cs.dir) == 0 {
+		return nil
+	}
+	return os.RemoveAll(dir)
+}
+
+func copyCredentials(creds entity.Credentials) entity.Credentials {
+	return entity.Credentials{
+		CACert:     append([]byte{}, creds.CACert...),
+		ClientCert: append([]byte{}, creds.ClientCert...),
+		ClientKey:  append([]byte{}, creds.ClientKey...),
+	}
+}
@@ -0,0 +1,95 @@
+/**
+ * Copyright 2019 Whiteblock Inc. All rights reserved.
+ * Use of this source code is governed by a BSD-style
+ * license that can be found in the LICENSE file.
+ */
+
+package repository
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/whiteblock/genesis/pkg/entity"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testCredentials() entity.Credentials {
+	return entity.Credentials{CACert: []byte("ca"), ClientCert: []byte("cert"), ClientKey: []byte("key")}
+}
+
+func TestCredentialStore_InMemory(t *testing.T) {
+	store := NewCredentialStore("")
+	_, err := store.Get("test1")
+	assert.Equal(t, ErrNoCredentials, err)
+
+	creds := testCredentials()
+	require.NoError(t, store.Put("test1", creds))
+	creds.Wipe()
+
+	got, err := store.Get("test1")
+	require.NoError(t, err)
+	assert.Equal(t, testCredentials(), got)
+	got.Wipe()
+	got, err = store.Get("test1")
+	require.NoError(t, err)
+	assert.Equal(t, testCredentials(), got, "the store gives out copies")
+
+	require.NoError(t, store.Remove("test1"))
+	_, err = store.Get("test1")
+	assert.Equal(t, ErrNoCredentials, err)
+	assert.NoError(t, store.Remove("test1"))
+}
+
+func TestCredentialStore_Invalid(t *testing.T) {
+	store := NewCredentialStore("")
+	assert.Error(t, store.Put("test1", entity.Credentials{CACert: []byte("ca")}))
+	for _, testID := range []string{"", "..", "../test1", "a/b"} {
+		assert.Error(t, store.Put(testID, testCredentials()), testID)
+	}
+}
+
+func TestCredentialStore_Persisted(t *testing.T) {
+	dir, err := ioutil.TempDir("", "creds")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	root := filepath.Join(dir, "creds")
+	require.NoError(t, NewCredentialStore(root).Put("test1", testCredentials()))
+
+	stat, err := os.Stat(filepath.Join(root, "test1"))
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0700), stat.Mode().Perm())
+	for _, name := range []string{caCertFile, clientCertFile, clientKeyFile} {
+		stat, err = os.Stat(filepath.Join(root, "test1", name))
+		require.NoError(t, err)
+		assert.Equal(t, os.FileMode(0600), stat.Mode().Perm(), name)
+	}
+
+	store := NewCredentialStore(root)
+	got, err := store.Get("test1")
+	require.NoError(t, err)
+	assert.Equal(t, testCredentials(), got)
+
+	require.NoError(t, store.Remove("test1"))
+	_, err = os.Stat(filepath.Join(root, "test1"))
+	assert.True(t, os.IsNotExist(err))
+	_, err = NewCredentialStore(root).Get("test1")
+	assert.Equal(t, ErrNoCredentials, err)
+}
+
+func TestCredentialStore_RefusesOpenDir(t *testing.T) {
+	dir, err := ioutil.TempDir("", "creds")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	require.NoError(t, NewCredentialStore(dir).Put("test1", testCredentials()))
+	require.NoError(t, os.Chmod(filepath.Join(dir, "test1"), 0755))
+
+	_, err = NewCredentialStore(dir).Get("test1")
+	assert.Error(t, err)
+}
@@ -7,9 +7,15 @@
 package repository
 
 import (
+	"bytes"
 	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
 	"fmt"
+	"io"
 	"io/ioutil"
+	"net"
 	"net/http"
 	"strings"
 	"time"
@@ -20,7 +26,7 @@ import (
 	"github.com/docker/distribution/reference"
 	"github.com/docker/docker/api/types"
 	"github.com/docker/docker/client"
-	"github.com/docker/go-connections/tlsconfig"
+	"github.com/docker/docker/pkg/stdcopy"
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
 	def "github.com/whiteblock/definition/command"
@@ -29,13 +35,17 @@ import (
 //DockerRepository provides extra functions for docker service, which could be placed inside of docker
 //service, but would make the testing more difficult
 type DockerRepository interface {
-	//WithTLSClientConfig provides the opt for TLS auth
-	WithTLSClientConfig(cacertPath, certPath, keyPath string) client.Opt
+	//WithTLSClientConfig provides the opt for TLS auth with the given credentials, which only
+	//trusts daemons with a cert for one of the hosts
+	WithTLSClientConfig(creds entity.Credentials, hosts ...string) client.Opt
 
 	//EnsureImagePulled checks if the docker host contains an image and pulls it if it does not
 	EnsureImagePulled(ctx context.Context, cli entity.Client,
 		imageName string, auth def.Credentials) error
 
+	//RegistryAuth encodes the credentials for the registry, for a pull or a swarm service
+	RegistryAuth(auth def.Credentials) string
+
 	//GetContainerByName attempts to find a container with the given name and return information on it.
 	GetContainerByName(ctx context.Context, cli entity.Client, containerName string) (types.Container, error)
 
@@ -47,6 +57,9 @@ type DockerRepository interface {
 
 	//Exec is sort of like docker exec
 	Exec(ctx context.Context, cli entity.Client, containerName string, details entity.Exec) error
+
+	//ExecOutput is Exec, which also gives back what the command wrote to stdout
+	ExecOutput(ctx context.Context, cli entity.Client, containerName string, details entity.Exec) ([]byte, error)
 }
 
 type dockerRepository struct {
@@ -58,16 +71,11 @@ func NewDockerRepository(log logrus.Ext1FieldLogger) DockerRepository {
 	return &dockerRepository{log: log}
 }
 
-func (da dockerRepository) WithTLSClientConfig(cacertPath, certPath, keyPath string) client.Opt {
+// WithTLSClientConfig provides the opt for TLS auth with the given credentials. The certificate of
+// the daemon must be signed by the CA of the credentials, and be for one of the given hosts.
+func (da dockerRepository) WithTLSClientConfig(creds entity.Credentials, hosts ...string) client.Opt {
 	return func(c *client.Client) error {
-		opts := tlsconfig.Options{
-			CAFile:             cacertPath,
-			CertFile:           certPath,
-			KeyFile:            keyPath,
-			ExclusiveRootPools: true,
-			InsecureSkipVerify: true,
-		}
-		config, err := tlsconfig.Client(opts)
+		config, err := tlsClientConfig(creds, hosts)
 		if err != nil {
 			return errors.Wrap(err, "failed to create tls config")
 		}
@@ -79,6 +87,144 @@ func (da dockerRepository) WithTLSClientConfig(cacertPath, certPath, keyPath str
 	}
 }
 
+func tlsClientConfig(creds entity.Credentials, hosts []string) (*tls.Config, error) {
+	cert, err := tls.X509KeyPair(creds.ClientCert, creds.ClientKey)
+	if err != nil {
+		return nil, err
+	}
+	cas, err := parseCerts(creds.CACert)
+	if err != nil {
+		return nil, errors.Wrap(err, "the ca cert is invalid")
+	}
+	return &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+		// The verification is done below instead, as the certs of the daemons may only name
+		// their host in the common name, which the standard verification ignores
+		InsecureSkipVerify: true,
+		VerifyPeerCertificate: func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+			return verifyDaemonCert(rawCerts, cas, hosts)
+		},
+	}, nil
+}
+
+func parseCerts(data []byte) (out []*x509.Certificate, err error) {
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, cert)
+	}
+	if len(out) == 0 {
+		return nil, errors.New("no certificates found")
+	}
+	return out, nil
+}
+
+// verifyDaemonCert checks that the cert chain given by the daemon leads to one of the CAs,
+// and that its cert is for one of the hosts
+func verifyDaemonCert(rawCerts [][]byte, cas []*x509.Certificate, hosts []string) error {
+	if len(rawCerts) == 0 {
+		return errors.New("the daemon did not give a certificate")
+	}
+	certs := make([]*x509.Certificate, len(rawCerts))
+	for i, raw := range rawCerts {
+		cert, err := x509.ParseCertificate(raw)
+		if err != nil {
+			return errors.Wrap(err, "the daemon gave an invalid certificate")
+		}
+		certs[i] = cert
+	}
+	roots := x509.NewCertPool()
+	for _, ca := range cas {
+		roots.AddCert(ca)
+	}
+	intermediates := x509.NewCertPool()
+	for _, cert := range certs[1:] {
+		intermediates.AddCert(cert)
+	}
+	_, err := certs[0].Verify(x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: intermediates,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	})
+	if err != nil && !signedByLegacyCA(certs[0], cas) {
+		return err
+	}
+	for _, host := range hosts {
+		if certIsFor(certs[0], host) {
+			return nil
+		}
+	}
+	return errors.Errorf("the certificate of the daemon is not valid for any of %v", hosts)
+}
+
+// signedByLegacyCA checks the cert against the CAs which do not have basic constraints, which the
+// standard verification refuses to treat as CAs. The CAs generated for the tests are like that.
+func signedByLegacyCA(cert *x509.Certificate, cas []*x509.Certificate) bool {
+	now := time.Now()
+	if now.Before(cert.NotBefore) || now.After(cert.NotAfter) {
+		return false
+	}
+	if len(cert.ExtKeyUsage) > 0 && !hasServerAuth(cert.ExtKeyUsage) {
+		return false
+	}
+	for _, ca := range cas {
+		if ca.BasicConstraintsValid || !bytes.Equal(cert.RawIssuer, ca.RawSubject) {
+			continue
+		}
+		if now.Before(ca.NotBefore) || now.After(ca.NotAfter) {
+			continue
+		}
+		if ca.CheckSignature(cert.SignatureAlgorithm, cert.RawTBSCertificate, cert.Signature) == nil {
+			return true
+		}
+	}
+	return false
+}
+
+func hasServerAuth(usages []x509.ExtKeyUsage) bool {
+	for _, usage := range usages {
+		if usage == x509.ExtKeyUsageServerAuth || usage == x509.ExtKeyUsageAny {
+			return true
+		}
+	}
+	return false
+}
+
+// certIsFor checks the names of the cert against the host, falling back on the common name
+// when the cert does not have any alternative names
+func certIsFor(cert *x509.Certificate, host string) bool {
+	if len(host) == 0 {
+		return false
+	}
+	if cert.VerifyHostname(host) == nil {
+		return true
+	}
+	if len(cert.DNSNames) > 0 || len(cert.IPAddresses) > 0 || len(cert.Subject.CommonName) == 0 {
+		return false
+	}
+	name := strings.ToLower(cert.Subject.CommonName)
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	if name == host {
+		return true
+	}
+	if !strings.HasPrefix(name, "*.") || net.ParseIP(host) != nil {
+		return false
+	}
+	dot := strings.Index(host, ".")
+	return dot > 0 && host[dot:] == name[1:]
+}
+
 //HostHasImage returns true if the docker host has an image matching what was given
 func (da dockerRepository) HostHasImage(ctx context.Context, cli entity.Client, image string) (bool, error) {
 	imgs, err := cli.ImageList(ctx, types.ImageListOptions{All: false})
@@ -100,7 +246,8 @@ func (da dockerRepository) HostHasImage(ctx context.Context, cli entity.Client,
 	return false, nil
 }
 
-func (da dockerRepository) handleCredentials(auth def.Credentials) string {
+//RegistryAuth encodes the credentials for the registry, for a pull or a swarm service
+func (da dockerRepository) RegistryAuth(auth def.Credentials) string {
 	if auth.Empty() {
 		return ""
 	}
@@ -134,7 +281,7 @@ func (da dockerRepository) EnsureImagePulled(ctx context.Context, cli entity.Cli
 	}
 	rd, err := cli.ImagePull(ctx, name, types.ImagePullOptions{
 		Platform:     "Linux",
-		RegistryAuth: da.handleCredentials(auth),
+		RegistryAuth: da.RegistryAuth(auth),
 	})
 	if err != nil {
 		return err
@@ -179,19 +326,20 @@ func (da dockerRepository) GetContainerByName(ctx context.Context, cli entity.Cl
 }
 
 func (da dockerRepository) exec(ctx context.Context, cli entity.Client,
-	containerName string, details entity.Exec) error {
+	containerName string, details entity.Exec, output io.Writer) error {
 
 	da.log.WithFields(logrus.Fields{
 		"command": strings.Join(details.Cmd, " "),
 	}).Debug("executing a command")
+	attach := output != nil
 	idRes, err := cli.ContainerExecCreate(ctx, containerName, types.ExecConfig{
 		User:         "",
 		Privileged:   details.Privileged,
 		Tty:          false,
 		AttachStdin:  false,
-		AttachStderr: false,
-		AttachStdout: false,
-		Detach:       true,
+		AttachStderr: attach,
+		AttachStdout: attach,
+		Detach:       !attach,
 		DetachKeys:   "",
 		Env:          nil,
 		WorkingDir:   "",
@@ -200,9 +348,22 @@ func (da dockerRepository) exec(ctx context.Context, cli entity.Client,
 	if err != nil {
 		return err
 	}
-	err = cli.ContainerExecStart(ctx, idRes.ID, types.ExecStartCheck{})
-	if err != nil {
-		return err
+	stderr := new(bytes.Buffer)
+	if attach {
+		resp, err := cli.ContainerExecAttach(ctx, idRes.ID, types.ExecStartCheck{})
+		if err != nil {
+			return err
+		}
+		_, err = stdcopy.StdCopy(output, stderr, resp.Reader)
+		resp.Close()
+		if err != nil {
+			return err
+		}
+	} else {
+		err = cli.ContainerExecStart(ctx, idRes.ID, types.ExecStartCheck{})
+		if err != nil {
+			return err
+		}
 	}
 	for {
 		res, err := cli.ContainerExecInspect(ctx, idRes.ID)
@@ -211,6 +372,10 @@ func (da dockerRepository) exec(ctx context.Context, cli entity.Client,
 		}
 		if !res.Running {
 			if res.ExitCode != 0 {
+				if stderr.Len() > 0 {
+					return fmt.Errorf(`command "%s" exited with exit code %d: %s`, strings.Join(details.Cmd, " "),
+						res.ExitCode, strings.TrimSpace(stderr.String()))
+				}
 				return fmt.Errorf(`command "%s" exited with exit code %d`, strings.Join(details.Cmd, " "), res.ExitCode)
 			}
 			break
@@ -222,7 +387,24 @@ func (da dockerRepository) exec(ctx context.Context, cli entity.Client,
 
 func (da dockerRepository) Exec(ctx context.Context, cli entity.Client,
 	containerName string, details entity.Exec) error {
-	err := da.exec(ctx, cli, containerName, details)
+	return da.retry(details, func() error {
+		return da.exec(ctx, cli, containerName, details, nil)
+	})
+}
+
+func (da dockerRepository) ExecOutput(ctx context.Context, cli entity.Client,
+	containerName string, details entity.Exec) ([]byte, error) {
+	out := new(bytes.Buffer)
+	err := da.retry(details, func() error {
+		out.Reset()
+		return da.exec(ctx, cli, containerName, details, out)
+	})
+	return out.Bytes(), err
+}
+
+// retry runs the exec until it succeeds, or it runs out of the retries of the details
+func (da dockerRepository) retry(details entity.Exec, exec func() error) error {
+	err := exec()
 	if err == nil {
 		return nil
 	}
@@ -238,7 +420,7 @@ func (da dockerRepository) Exec(ctx context.Context, cli entity.Client,
 			"command": details.Cmd,
 			"attempt": i + 1,
 		}).Debug("retrying a command")
-		err = da.exec(ctx, cli, containerName, details)
+		err = exec()
 		if err == nil {
 			break
 		}
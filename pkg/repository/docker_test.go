@@ -7,14 +7,30 @@
 package repository
 
 import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
 	"fmt"
 	"io/ioutil"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
 	entityMock "github.com/whiteblock/genesis/mocks/pkg/entity"
+	"github.com/whiteblock/genesis/pkg/entity"
+	"github.com/whiteblock/genesis/pkg/fake"
 
 	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
 	"github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
@@ -205,3 +221,153 @@ func TestDockerRepository_GetContainerByName_Failure(t *testing.T) {
 
 	cli.AssertExpectations(t)
 }
+
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+}
+
+// newTestCA creates a CA, a legacy one does not mark itself as a CA, like the ones made by definition
+func newTestCA(t *testing.T, legacy bool) testCA {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "genesis"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		BasicConstraintsValid: !legacy,
+		IsCA:                  !legacy,
+	}
+	raw, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(raw)
+	require.NoError(t, err)
+	return testCA{cert: cert, key: key}
+}
+
+func (ca testCA) pem() []byte {
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ca.cert.Raw})
+}
+
+func (ca testCA) issue(t *testing.T, tmpl *x509.Certificate) tls.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	tmpl.SerialNumber = big.NewInt(time.Now().UnixNano())
+	tmpl.NotBefore = time.Now().Add(-time.Hour)
+	tmpl.NotAfter = time.Now().Add(time.Hour)
+	raw, err := x509.CreateCertificate(rand.Reader, tmpl, ca.cert, &key.PublicKey, ca.key)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+	cert, err := tls.X509KeyPair(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: raw}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}))
+	require.NoError(t, err)
+	return cert
+}
+
+func (ca testCA) credentials(t *testing.T) entity.Credentials {
+	cert := ca.issue(t, &x509.Certificate{
+		Subject:     pkix.Name{CommonName: "client"},
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	})
+	keyDER, err := x509.MarshalECPrivateKey(cert.PrivateKey.(*ecdsa.PrivateKey))
+	require.NoError(t, err)
+	return entity.Credentials{
+		CACert:     ca.pem(),
+		ClientCert: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Certificate[0]}),
+		ClientKey:  pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}),
+	}
+}
+
+// handshake connects to a daemon with the given cert, trusting the given credentials
+func handshake(t *testing.T, server tls.Certificate, creds entity.Credentials, hosts ...string) error {
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+	srv.TLS = &tls.Config{
+		Certificates: []tls.Certificate{server},
+		ClientAuth:   tls.RequireAnyClientCert,
+	}
+	srv.StartTLS()
+	defer srv.Close()
+
+	config, err := tlsClientConfig(creds, hosts)
+	require.NoError(t, err)
+	cli := http.Client{Transport: &http.Transport{TLSClientConfig: config}}
+	res, err := cli.Get(srv.URL)
+	if err != nil {
+		return err
+	}
+	return res.Body.Close()
+}
+
+func TestDockerRepository_ExecOutput(t *testing.T) {
+	engine := fake.NewEngine("10.0.0.2")
+	engine.AddImage("alpine")
+	_, err := engine.ContainerCreate(context.Background(), &container.Config{Image: "alpine"}, nil, nil, "test")
+	require.NoError(t, err)
+	require.NoError(t, engine.ContainerStart(context.Background(), "test", types.ContainerStartOptions{}))
+
+	calls := 0
+	engine.OnExec("hostname", func(string, []string) (int, []byte) {
+		calls++
+		if calls == 1 {
+			return 1, []byte("not yet")
+		}
+		return 0, []byte("test\n")
+	})
+
+	repo := NewDockerRepository(logrus.New())
+	out, err := repo.ExecOutput(context.Background(), engine, "test", entity.Exec{
+		Cmd:     []string{"hostname"},
+		Retries: 1,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "test\n", string(out), "only the output of the last attempt is given back")
+
+	_, err = repo.ExecOutput(context.Background(), engine, "test", entity.Exec{Cmd: []string{"hostname"}})
+	assert.NoError(t, err)
+	calls = 0
+	_, err = repo.ExecOutput(context.Background(), engine, "test", entity.Exec{Cmd: []string{"hostname"}})
+	assert.Error(t, err)
+}
+
+func TestDockerRepository_TLSClientConfig(t *testing.T) {
+	for _, legacy := range []bool{false, true} {
+		ca := newTestCA(t, legacy)
+		creds := ca.credentials(t)
+		serverAuth := []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth}
+
+		byIP := ca.issue(t, &x509.Certificate{ExtKeyUsage: serverAuth,
+			IPAddresses: []net.IP{net.ParseIP("127.0.0.1")}})
+		assert.NoError(t, handshake(t, byIP, creds, "127.0.0.1"))
+		assert.Error(t, handshake(t, byIP, creds, "10.0.0.2"))
+		assert.NoError(t, handshake(t, byIP, creds, "10.0.0.2", "127.0.0.1"))
+
+		byCN := ca.issue(t, &x509.Certificate{ExtKeyUsage: serverAuth,
+			Subject: pkix.Name{CommonName: "*.whiteblock.io"}})
+		assert.Error(t, handshake(t, byCN, creds, "127.0.0.1"))
+		assert.NoError(t, handshake(t, byCN, creds, "127.0.0.1", "daemon.whiteblock.io"))
+		assert.Error(t, handshake(t, byCN, creds, "127.0.0.1", "a.daemon.whiteblock.io"))
+
+		untrusted := newTestCA(t, legacy).issue(t, &x509.Certificate{ExtKeyUsage: serverAuth,
+			IPAddresses: []net.IP{net.ParseIP("127.0.0.1")}})
+		assert.Error(t, handshake(t, untrusted, creds, "127.0.0.1"))
+
+		clientOnly := ca.issue(t, &x509.Certificate{
+			ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+			IPAddresses: []net.IP{net.ParseIP("127.0.0.1")}})
+		assert.Error(t, handshake(t, clientOnly, creds, "127.0.0.1"))
+	}
+}
+
+func TestDockerRepository_TLSClientConfig_InvalidCredentials(t *testing.T) {
+	creds := newTestCA(t, false).credentials(t)
+	creds.CACert = []byte("not a cert")
+	_, err := tlsClientConfig(creds, []string{"127.0.0.1"})
+	assert.Error(t, err)
+
+	creds = newTestCA(t, false).credentials(t)
+	creds.ClientKey = []byte("not a key")
+	_, err = tlsClientConfig(creds, []string{"127.0.0.1"})
+	assert.Error(t, err)
+}
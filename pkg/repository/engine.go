@@ -0,0 +1,285 @@
+/**
+ * Copyright 2019 Whiteblock Inc. All rights reserved.
+ * Use of this source code is governed by a BSD-style
+ * license that can be found in the LICENSE file.
+ */
+
+package repository
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/whiteblock/genesis/pkg/entity"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/mount"
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/docker/api/types/strslice"
+	"github.com/docker/docker/api/types/volume"
+	"github.com/docker/docker/errdefs"
+	"github.com/docker/go-connections/nat"
+)
+
+// engineErrors are the messages of a runtime which mean one of the kinds of errors of an Engine
+type engineErrors []struct {
+	msg  string
+	kind error
+}
+
+// dockerErrors are the messages docker gives back
+var dockerErrors = engineErrors{
+	{msg: "already in use by container", kind: entity.ErrAlreadyExists},
+	{msg: "already exists", kind: entity.ErrAlreadyExists},
+	{msg: "is already attached to network", kind: entity.ErrAlreadyExists},
+	{msg: "no such container", kind: entity.ErrNotFound},
+	{msg: "no such network", kind: entity.ErrNotFound},
+	{msg: "no such volume", kind: entity.ErrNotFound},
+	{msg: "is not connected to the network", kind: entity.ErrNotFound},
+}
+
+// classify gives back the error as an EngineError, if its message is one of the known ones
+func (ee engineErrors) classify(err error) error {
+	if err == nil {
+		return nil
+	}
+	msg := strings.ToLower(err.Error())
+	for _, entry := range ee {
+		if strings.Contains(msg, entry.msg) {
+			return entity.EngineError{Kind: entry.kind, Err: err}
+		}
+	}
+	return err
+}
+
+// dockerEngine is the adapter of the docker engine API
+type dockerEngine struct {
+	cli entity.Client
+}
+
+// NewDockerEngine creates the Engine of a client of the docker engine API
+func NewDockerEngine(cli entity.Client) entity.Engine {
+	return dockerEngine{cli: cli}
+}
+
+func (de dockerEngine) classify(err error) error {
+	switch {
+	case err == nil:
+		return nil
+	case errdefs.IsNotFound(err):
+		return entity.EngineError{Kind: entity.ErrNotFound, Err: err}
+	case errdefs.IsConflict(err):
+		return entity.EngineError{Kind: entity.ErrAlreadyExists, Err: err}
+	}
+	return dockerErrors.classify(err)
+}
+
+// portBindings gives the ports in the form of the docker engine API
+func portBindings(ports []entity.PortSpec) (nat.PortSet, nat.PortMap, error) {
+	if len(ports) == 0 {
+		return nil, nil, nil
+	}
+	specs := []string{}
+	for _, port := range ports {
+		specs = append(specs, fmt.Sprintf("%s:%d:%d/%s", port.HostIP, port.HostPort,
+			port.ContainerPort, port.Protocol))
+	}
+	return nat.ParsePortSpecs(specs)
+}
+
+// endpointSettings gives the settings of the endpoint in the form of the docker engine API
+func endpointSettings(endpoint entity.EndpointSpec) *network.EndpointSettings {
+	return &network.EndpointSettings{
+		IPAMConfig: &network.EndpointIPAMConfig{
+			IPv4Address: endpoint.IPv4,
+			IPv6Address: endpoint.IPv6,
+		},
+		Aliases:    endpoint.Aliases,
+		Links:      endpoint.Links,
+		MacAddress: endpoint.MacAddress,
+	}
+}
+
+// containerConfigs gives the spec in the form of the docker engine API
+func containerConfigs(spec entity.ContainerSpec) (*container.Config, *container.HostConfig,
+	*network.NetworkingConfig, error) {
+
+	portSet, portMap, err := portBindings(spec.Ports)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	config := &container.Config{
+		Hostname:     spec.Hostname,
+		Domainname:   spec.Domainname,
+		ExposedPorts: portSet,
+		Env:          spec.Env,
+		Image:        spec.Image,
+		Labels:       spec.Labels,
+	}
+	if len(spec.Entrypoint) > 0 {
+		config.Entrypoint = strslice.StrSlice(spec.Entrypoint)
+	}
+
+	hostConfig := &container.HostConfig{
+		PortBindings: portMap,
+		AutoRemove:   spec.AutoRemove,
+		NetworkMode:  container.NetworkMode(spec.NetworkMode),
+		PidMode:      container.PidMode(spec.PidMode),
+		Privileged:   spec.Privileged,
+		DNS:          spec.DNS,
+		DNSSearch:    spec.DNSSearch,
+		DNSOptions:   spec.DNSOptions,
+	}
+	if len(spec.CapAdd) > 0 {
+		hostConfig.CapAdd = strslice.StrSlice(spec.CapAdd)
+	}
+	if len(spec.LogDriver) > 0 || len(spec.LogOptions) > 0 {
+		hostConfig.LogConfig = container.LogConfig{Type: spec.LogDriver, Config: spec.LogOptions}
+	}
+	if spec.Mounts != nil {
+		hostConfig.Mounts = []mount.Mount{}
+	}
+	for _, mnt := range spec.Mounts {
+		hostConfig.Mounts = append(hostConfig.Mounts, mount.Mount{
+			Type:     mount.Type(mnt.Type),
+			Source:   mnt.Source,
+			Target:   mnt.Target,
+			ReadOnly: mnt.ReadOnly,
+		})
+	}
+	if len(spec.VolumesFrom) > 0 {
+		hostConfig.VolumesFrom = []string{spec.VolumesFrom}
+	}
+	hostConfig.NanoCPUs = spec.NanoCPUs
+	hostConfig.Memory = spec.Memory
+
+	networkConfig := &network.NetworkingConfig{}
+	if spec.Network != nil {
+		settings := endpointSettings(*spec.Network)
+		settings.NetworkID = spec.Network.Network
+		settings.IPAddress = spec.Network.IPv4
+		settings.GlobalIPv6Address = spec.Network.IPv6
+		networkConfig.EndpointsConfig = map[string]*network.EndpointSettings{spec.Network.Network: settings}
+		// the daemon only takes the mac address of the first network from the container config
+		config.MacAddress = spec.Network.MacAddress
+	}
+	return config, hostConfig, networkConfig, nil
+}
+
+// CreateContainer creates the container
+func (de dockerEngine) CreateContainer(ctx context.Context, spec entity.ContainerSpec) error {
+	config, hostConfig, networkConfig, err := containerConfigs(spec)
+	if err != nil {
+		return err
+	}
+	_, err = de.cli.ContainerCreate(ctx, config, hostConfig, networkConfig, spec.Name)
+	return de.classify(err)
+}
+
+// StartContainer starts the container
+func (de dockerEngine) StartContainer(ctx context.Context, name string) error {
+	return de.classify(de.cli.ContainerStart(ctx, name, types.ContainerStartOptions{}))
+}
+
+// RemoveContainer removes the container, even if it is running
+func (de dockerEngine) RemoveContainer(ctx context.Context, name string, volumes bool) error {
+	return de.classify(de.cli.ContainerRemove(ctx, name, types.ContainerRemoveOptions{
+		RemoveVolumes: volumes,
+		Force:         true,
+	}))
+}
+
+// networkOptions gives the options of the driver of the network, by their docker names
+func networkOptions(spec entity.NetworkSpec) map[string]string {
+	out := map[string]string{}
+	for key, val := range spec.Options {
+		out[key] = val
+	}
+	switch spec.Driver {
+	case entity.BridgeDriver:
+		if len(spec.BridgeName) > 0 {
+			out["com.docker.network.bridge.name"] = spec.BridgeName
+		}
+	case entity.OverlayDriver:
+		if spec.Encrypted {
+			out["encrypted"] = ""
+		}
+	case entity.MacvlanDriver, entity.IpvlanDriver:
+		if len(spec.Parent) > 0 {
+			out["parent"] = spec.Parent
+		}
+		if len(spec.Mode) > 0 {
+			out[spec.Driver+"_mode"] = spec.Mode
+		}
+	}
+	if spec.MTU > 0 {
+		out["com.docker.network.driver.mtu"] = strconv.Itoa(spec.MTU)
+	}
+	return out
+}
+
+// CreateNetwork creates the network, global networks are swarm scoped
+func (de dockerEngine) CreateNetwork(ctx context.Context, spec entity.NetworkSpec) error {
+	ipam := &network.IPAM{Driver: "default", Options: spec.IPAMOptions, Config: []network.IPAMConfig{}}
+	if len(spec.IPAMDriver) > 0 {
+		ipam.Driver = spec.IPAMDriver
+	}
+	for _, subnet := range spec.Subnets {
+		ipam.Config = append(ipam.Config, network.IPAMConfig{
+			Subnet:  subnet.Subnet,
+			Gateway: subnet.Gateway,
+			IPRange: subnet.IPRange,
+		})
+	}
+	scope := "local"
+	if spec.Global {
+		scope = "swarm"
+	}
+	_, err := de.cli.NetworkCreate(ctx, spec.Name, types.NetworkCreate{
+		CheckDuplicate: true,
+		Driver:         spec.Driver,
+		Scope:          scope,
+		Attachable:     true,
+		Ingress:        false,
+		Internal:       spec.Internal,
+		EnableIPv6:     spec.EnableIPv6,
+		Labels:         spec.Labels,
+		IPAM:           ipam,
+		Options:        networkOptions(spec),
+	})
+	return de.classify(err)
+}
+
+// RemoveNetwork removes the network
+func (de dockerEngine) RemoveNetwork(ctx context.Context, name string) error {
+	return de.classify(de.cli.NetworkRemove(ctx, name))
+}
+
+// ConnectNetwork attaches the container to the network
+func (de dockerEngine) ConnectNetwork(ctx context.Context, container string, endpoint entity.EndpointSpec) error {
+	return de.classify(de.cli.NetworkConnect(ctx, endpoint.Network, container, endpointSettings(endpoint)))
+}
+
+// DisconnectNetwork detaches the container from the network, even if it is running
+func (de dockerEngine) DisconnectNetwork(ctx context.Context, container string, network string) error {
+	return de.classify(de.cli.NetworkDisconnect(ctx, network, container, true))
+}
+
+// CreateVolume creates the volume
+func (de dockerEngine) CreateVolume(ctx context.Context, spec entity.VolumeSpec) error {
+	_, err := de.cli.VolumeCreate(ctx, volume.VolumeCreateBody{
+		Name:       spec.Name,
+		Driver:     spec.Driver,
+		DriverOpts: spec.Options,
+		Labels:     spec.Labels,
+	})
+	return de.classify(err)
+}
+
+// RemoveVolume removes the volume, even if it is in use
+func (de dockerEngine) RemoveVolume(ctx context.Context, name string) error {
+	return de.classify(de.cli.VolumeRemove(ctx, name, true))
+}
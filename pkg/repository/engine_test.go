@@ -0,0 +1,78 @@
+/**
+ * Copyright 2019 Whiteblock Inc. All rights reserved.
+ * Use of this source code is governed by a BSD-style
+ * license that can be found in the LICENSE file.
+ */
+
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	entityMock "github.com/whiteblock/genesis/mocks/pkg/entity"
+	"github.com/whiteblock/genesis/pkg/entity"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/mount"
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/go-connections/nat"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDockerEngine_CreateContainer(t *testing.T) {
+	cli := new(entityMock.Client)
+	cli.On("ContainerCreate", mock.Anything, mock.Anything, mock.Anything, mock.Anything, "node").Return(
+		container.ContainerCreateCreatedBody{}, nil).Run(func(args mock.Arguments) {
+		config := args.Get(1).(*container.Config)
+		hostConfig := args.Get(2).(*container.HostConfig)
+		networkConfig := args.Get(3).(*network.NetworkingConfig)
+
+		assert.Equal(t, "02:42:0a:00:00:02", config.MacAddress)
+		assert.Contains(t, config.ExposedPorts, nat.Port("80/tcp"))
+		assert.Equal(t, []nat.PortBinding{{HostIP: "0.0.0.0", HostPort: "8080"}},
+			hostConfig.PortBindings["80/tcp"])
+		assert.Equal(t, []mount.Mount{{Type: mount.TypeVolume, Source: "data", Target: "/data"}},
+			hostConfig.Mounts)
+		assert.Equal(t, container.LogConfig{Type: "journald", Config: map[string]string{"labels": "org"}},
+			hostConfig.LogConfig)
+		require.Contains(t, networkConfig.EndpointsConfig, "net1")
+		assert.Equal(t, "10.0.0.2", networkConfig.EndpointsConfig["net1"].IPAMConfig.IPv4Address)
+	}).Once()
+
+	err := NewDockerEngine(cli).CreateContainer(context.Background(), entity.ContainerSpec{
+		Name:       "node",
+		Image:      "alpine",
+		LogDriver:  "journald",
+		LogOptions: map[string]string{"labels": "org"},
+		Ports:      []entity.PortSpec{{HostIP: "0.0.0.0", HostPort: 8080, ContainerPort: 80, Protocol: "tcp"}},
+		Mounts:     []entity.MountSpec{{Type: entity.VolumeMountType, Source: "data", Target: "/data"}},
+		Network:    &entity.EndpointSpec{Network: "net1", IPv4: "10.0.0.2", MacAddress: "02:42:0a:00:00:02"},
+	})
+	require.NoError(t, err)
+	cli.AssertExpectations(t)
+}
+
+func TestDockerEngine_Errors(t *testing.T) {
+	cli := new(entityMock.Client)
+	cli.On("ContainerRemove", mock.Anything, "gone", types.ContainerRemoveOptions{Force: true}).Return(
+		fmt.Errorf("Error: No such container: gone")).Once()
+	cli.On("NetworkConnect", mock.Anything, "net1", "node", mock.Anything).Return(
+		fmt.Errorf("endpoint with name node already exists in network net1")).Once()
+	cli.On("NetworkRemove", mock.Anything, "net1").Return(fmt.Errorf("something else")).Once()
+
+	engine := NewDockerEngine(cli)
+	err := engine.RemoveContainer(context.Background(), "gone", false)
+	assert.True(t, errors.Is(err, entity.ErrNotFound))
+	err = engine.ConnectNetwork(context.Background(), "node", entity.EndpointSpec{Network: "net1"})
+	assert.True(t, errors.Is(err, entity.ErrAlreadyExists))
+	err = engine.RemoveNetwork(context.Background(), "net1")
+	assert.False(t, errors.Is(err, entity.ErrNotFound))
+	assert.False(t, errors.Is(err, entity.ErrAlreadyExists))
+	cli.AssertExpectations(t)
+}
@@ -0,0 +1,73 @@
+/**
+ * Copyright 2019 Whiteblock Inc. All rights reserved.
+ * Use of this source code is governed by a BSD-style
+ * license that can be found in the LICENSE file.
+ */
+
+package repository
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/whiteblock/genesis/pkg/entity"
+)
+
+// IPAMRepository persists the address allocations made by Genesis, so that they survive restarts
+type IPAMRepository interface {
+	//Load gets the allocations which were saved, or an empty state if none were
+	Load() (entity.IPAMState, error)
+
+	//Save replaces the saved allocations with the given state
+	Save(state entity.IPAMState) error
+}
+
+type ipamRepository struct {
+	path string
+}
+
+// NewIPAMRepository creates a new IPAMRepository which keeps the allocations in the given file.
+// If no file is given, the allocations are only kept in memory.
+func NewIPAMRepository(path string) IPAMRepository {
+	return &ipamRepository{path: path}
+}
+
+// Load gets the allocations which were saved, or an empty state if none were
+func (ir ipamRepository) Load() (entity.IPAMState, error) {
+	state := entity.IPAMState{}
+	if len(ir.path) == 0 {
+		return state, nil
+	}
+	data, err := ioutil.ReadFile(ir.path)
+	if os.IsNotExist(err) {
+		return state, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return state, json.Unmarshal(data, &state)
+}
+
+// Save replaces the saved allocations with the given state. The file is replaced in one step,
+// so that a crash never leaves it half written.
+func (ir ipamRepository) Save(state entity.IPAMState) error {
+	if len(ir.path) == 0 {
+		return nil
+	}
+	data, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	err = os.MkdirAll(filepath.Dir(ir.path), 0755)
+	if err != nil {
+		return err
+	}
+	tmp := ir.path + ".tmp"
+	err = ioutil.WriteFile(tmp, data, 0644)
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp, ir.path)
+}
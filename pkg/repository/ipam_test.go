@@ -0,0 +1,50 @@
+/**
+ * Copyright 2019 Whiteblock Inc. All rights reserved.
+ * Use of this source code is governed by a BSD-style
+ * license that can be found in the LICENSE file.
+ */
+
+package repository
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/whiteblock/genesis/pkg/entity"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIPAMRepository_SaveLoad(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ipam")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	repo := NewIPAMRepository(filepath.Join(dir, "state", "ipam.json"))
+	state, err := repo.Load()
+	require.NoError(t, err)
+	assert.Equal(t, entity.IPAMState{}, state)
+
+	state = entity.IPAMState{"test1": {"net-a": {
+		Subnet:    "10.0.0.0/24",
+		Gateway:   "10.0.0.1",
+		Addresses: map[string]string{"node-1": "10.0.0.2"},
+	}}}
+	require.NoError(t, repo.Save(state))
+
+	loaded, err := NewIPAMRepository(filepath.Join(dir, "state", "ipam.json")).Load()
+	require.NoError(t, err)
+	assert.Equal(t, state, loaded)
+}
+
+func TestIPAMRepository_InMemory(t *testing.T) {
+	repo := NewIPAMRepository("")
+	assert.NoError(t, repo.Save(entity.IPAMState{"test1": {}}))
+
+	state, err := repo.Load()
+	require.NoError(t, err)
+	assert.Equal(t, entity.IPAMState{}, state)
+}
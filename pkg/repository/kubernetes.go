@@ -0,0 +1,206 @@
+/**
+ * Copyright 2019 Whiteblock Inc. All rights reserved.
+ * Use of this source code is governed by a BSD-style
+ * license that can be found in the LICENSE file.
+ */
+
+package repository
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/whiteblock/genesis/pkg/config"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// MergePatch is a JSON merge patch, where lists are replaced as a whole
+	MergePatch = "application/merge-patch+json"
+
+	// StrategicMergePatch is a patch where lists are merged by their keys, it is only supported
+	// by the built in kinds
+	StrategicMergePatch = "application/strategic-merge-patch+json"
+)
+
+// KubernetesError is an error status given back by the Kubernetes API server
+type KubernetesError struct {
+	Code    int    `json:"code"`
+	Reason  string `json:"reason"`
+	Message string `json:"message"`
+}
+
+func (ke KubernetesError) Error() string {
+	return fmt.Sprintf("kubernetes: %s (%d): %s", ke.Reason, ke.Code, ke.Message)
+}
+
+// IsNotFound checks whether the error is from an object which does not exist
+func IsNotFound(err error) bool {
+	ke, ok := err.(KubernetesError)
+	return ok && ke.Code == http.StatusNotFound
+}
+
+// IsAlreadyExists checks whether the error is from an object which already exists
+func IsAlreadyExists(err error) bool {
+	ke, ok := err.(KubernetesError)
+	return ok && ke.Code == http.StatusConflict && ke.Reason == "AlreadyExists"
+}
+
+// KubernetesRepository makes the requests to the Kubernetes API server. The paths are
+// relative to the root of the API, such as "/api/v1/namespaces/genesis/pods".
+type KubernetesRepository interface {
+	//Get gets the object at the path, decoding it into out, or giving the body as it is
+	//if out is a *[]byte, such as for the logs of a container
+	Get(ctx context.Context, path string, out interface{}) error
+
+	//Create creates the object in the collection at the path
+	Create(ctx context.Context, path string, obj interface{}) error
+
+	//Patch applies the patch of the given type to the object at the path
+	Patch(ctx context.Context, path string, patchType string, patch interface{}) error
+
+	//Delete deletes the object or collection at the path
+	Delete(ctx context.Context, path string) error
+}
+
+type kubernetesRepository struct {
+	conf config.Kubernetes
+	log  logrus.Ext1FieldLogger
+
+	once   sync.Once
+	client *http.Client
+	err    error
+}
+
+// NewKubernetesRepository creates a new KubernetesRepository. The credentials are only loaded
+// once they are first needed, so that nothing is required when Kubernetes is not used.
+func NewKubernetesRepository(conf config.Kubernetes, log logrus.Ext1FieldLogger) KubernetesRepository {
+	return &kubernetesRepository{conf: conf, log: log}
+}
+
+func (kr *kubernetesRepository) httpClient() (*http.Client, error) {
+	kr.once.Do(func() {
+		if len(kr.conf.CAFile) == 0 {
+			kr.client = http.DefaultClient
+			return
+		}
+		ca, err := ioutil.ReadFile(kr.conf.CAFile)
+		if err != nil {
+			kr.err = err
+			return
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(ca) {
+			kr.err = fmt.Errorf("no certificates found in \"%s\"", kr.conf.CAFile)
+			return
+		}
+		kr.client = &http.Client{Transport: &http.Transport{
+			Proxy:           http.ProxyFromEnvironment,
+			TLSClientConfig: &tls.Config{RootCAs: pool},
+		}}
+	})
+	return kr.client, kr.err
+}
+
+func (kr *kubernetesRepository) do(ctx context.Context, method string, path string,
+	contentType string, body interface{}, out interface{}) error {
+
+	client, err := kr.httpClient()
+	if err != nil {
+		return err
+	}
+	var rdr io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		rdr = bytes.NewReader(data)
+	}
+	req, err := http.NewRequest(method, strings.TrimSuffix(kr.conf.APIServer, "/")+path, rdr)
+	if err != nil {
+		return err
+	}
+	if ctx != nil {
+		req = req.WithContext(ctx)
+	}
+	raw, isRaw := out.(*[]byte)
+	if isRaw {
+		req.Header.Set("Accept", "*/*")
+	} else {
+		req.Header.Set("Accept", "application/json")
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if len(kr.conf.TokenFile) > 0 {
+		token, err := ioutil.ReadFile(kr.conf.TokenFile)
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		if len(token) > 0 {
+			req.Header.Set("Authorization", "Bearer "+strings.TrimSpace(string(token)))
+		}
+	}
+
+	kr.log.WithFields(logrus.Fields{"method": method, "path": path}).Trace("sending a kubernetes request")
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode >= 300 {
+		status := KubernetesError{Code: resp.StatusCode}
+		if json.Unmarshal(data, &status) != nil || len(status.Message) == 0 {
+			status.Message = string(data)
+		}
+		status.Code = resp.StatusCode
+		return status
+	}
+	if isRaw {
+		*raw = data
+		return nil
+	}
+	if out == nil {
+		return nil
+	}
+	return json.Unmarshal(data, out)
+}
+
+// Get gets the object at the path, decoding it into out, or giving the body as it is
+// if out is a *[]byte
+func (kr *kubernetesRepository) Get(ctx context.Context, path string, out interface{}) error {
+	return kr.do(ctx, http.MethodGet, path, "", nil, out)
+}
+
+// Create creates the object in the collection at the path
+func (kr *kubernetesRepository) Create(ctx context.Context, path string, obj interface{}) error {
+	return kr.do(ctx, http.MethodPost, path, "application/json", obj, nil)
+}
+
+// Patch applies the patch of the given type to the object at the path
+func (kr *kubernetesRepository) Patch(ctx context.Context, path string, patchType string,
+	patch interface{}) error {
+
+	return kr.do(ctx, http.MethodPatch, path, patchType, patch, nil)
+}
+
+// Delete deletes the object or collection at the path
+func (kr *kubernetesRepository) Delete(ctx context.Context, path string) error {
+	return kr.do(ctx, http.MethodDelete, path, "", nil, nil)
+}
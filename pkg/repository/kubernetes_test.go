@@ -0,0 +1,85 @@
+/**
+ * Copyright 2019 Whiteblock Inc. All rights reserved.
+ * Use of this source code is governed by a BSD-style
+ * license that can be found in the LICENSE file.
+ */
+
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/whiteblock/genesis/pkg/config"
+	"github.com/whiteblock/genesis/pkg/entity"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKubernetesRepository(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kube")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	tokenFile := filepath.Join(dir, "token")
+	require.NoError(t, ioutil.WriteFile(tokenFile, []byte("secret\n"), 0600))
+
+	requests := []*http.Request{}
+	bodies := []string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := ioutil.ReadAll(r.Body)
+		requests = append(requests, r)
+		bodies = append(bodies, string(data))
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/api/v1/namespaces/test/pods/missing":
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"kind":"Status","code":404,"reason":"NotFound","message":"pods \"missing\" not found"}`))
+		case r.Method == http.MethodGet:
+			json.NewEncoder(w).Encode(entity.Pod{Metadata: entity.ObjectMeta{Name: "pod1"}})
+		case r.Method == http.MethodPost:
+			w.WriteHeader(http.StatusConflict)
+			w.Write([]byte(`{"kind":"Status","code":409,"reason":"AlreadyExists","message":"exists"}`))
+		default:
+			w.Write([]byte("{}"))
+		}
+	}))
+	defer server.Close()
+
+	repo := NewKubernetesRepository(config.Kubernetes{APIServer: server.URL + "/", TokenFile: tokenFile},
+		logrus.New())
+
+	var pod entity.Pod
+	require.NoError(t, repo.Get(context.Background(), "/api/v1/namespaces/test/pods/pod1", &pod))
+	assert.Equal(t, "pod1", pod.Metadata.Name)
+	assert.Equal(t, "Bearer secret", requests[0].Header.Get("Authorization"))
+
+	err = repo.Get(context.Background(), "/api/v1/namespaces/test/pods/missing", &pod)
+	assert.True(t, IsNotFound(err))
+	assert.False(t, IsAlreadyExists(err))
+
+	err = repo.Create(context.Background(), "/api/v1/namespaces/test/pods", pod)
+	assert.True(t, IsAlreadyExists(err))
+	assert.Equal(t, "application/json", requests[2].Header.Get("Content-Type"))
+
+	require.NoError(t, repo.Patch(context.Background(), "/api/v1/namespaces/test/pods/pod1", MergePatch,
+		map[string]string{"a": "b"}))
+	assert.Equal(t, MergePatch, requests[3].Header.Get("Content-Type"))
+	assert.Equal(t, http.MethodPatch, requests[3].Method)
+	assert.JSONEq(t, `{"a":"b"}`, bodies[3])
+
+	require.NoError(t, repo.Delete(context.Background(), "/api/v1/namespaces/test/pods/pod1"))
+	assert.Equal(t, http.MethodDelete, requests[4].Method)
+}
+
+func TestKubernetesRepository_BadCA(t *testing.T) {
+	repo := NewKubernetesRepository(config.Kubernetes{APIServer: "https://127.0.0.1:1",
+		CAFile: "/nonexistent/ca.crt"}, logrus.New())
+	assert.Error(t, repo.Delete(context.Background(), "/api/v1/namespaces/test/pods/pod1"))
+}
@@ -0,0 +1,993 @@
+/**
+ * Copyright 2019 Whiteblock Inc. All rights reserved.
+ * Use of this source code is governed by a BSD-style
+ * license that can be found in the LICENSE file.
+ */
+
+package repository
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"os/exec"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/whiteblock/genesis/pkg/entity"
+
+	"github.com/docker/cli/cli/connhelper/ssh"
+	"github.com/docker/distribution/reference"
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/events"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/docker/api/types/swarm"
+	"github.com/docker/docker/api/types/volume"
+	"github.com/docker/docker/pkg/stdcopy"
+)
+
+// CommandError is given back by a CommandRunner when the command exits with an error
+type CommandError struct {
+	ExitCode int
+	Stderr   string
+}
+
+func (ce CommandError) Error() string {
+	return fmt.Sprintf("exit status %d: %s", ce.ExitCode, strings.TrimSpace(ce.Stderr))
+}
+
+// CommandRunner runs commands, such as the nerdctl binary
+type CommandRunner interface {
+	//Run runs the command with the given stdin, giving back what it wrote to stdout
+	Run(ctx context.Context, stdin io.Reader, name string, args ...string) ([]byte, error)
+}
+
+type localRunner struct{}
+
+// NewCommandRunner creates a CommandRunner which runs the commands on this machine
+func NewCommandRunner() CommandRunner {
+	return localRunner{}
+}
+
+// Run runs the command with the given stdin, giving back what it wrote to stdout
+func (lr localRunner) Run(ctx context.Context, stdin io.Reader, name string, args ...string) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, name, args...)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdin = stdin
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	err := cmd.Run()
+	if exitErr, ok := err.(*exec.ExitError); ok {
+		return stdout.Bytes(), CommandError{ExitCode: exitErr.ExitCode(), Stderr: stderr.String()}
+	}
+	return stdout.Bytes(), err
+}
+
+// sshRunner runs the commands on another machine, over ssh
+type sshRunner struct {
+	args []string
+}
+
+// NewSSHRunner creates a CommandRunner which runs the commands on the host of the ssh url, such as
+// ssh://user@host:22, with the ssh client of this machine
+func NewSSHRunner(host string) (CommandRunner, error) {
+	spec, err := ssh.ParseURL(host)
+	if err != nil {
+		return nil, err
+	}
+	return sshRunner{args: spec.Args()}, nil
+}
+
+// shellQuote quotes the argument for the shell which ssh runs the command with
+func shellQuote(arg string) string {
+	return "'" + strings.Replace(arg, "'", `'"'"'`, -1) + "'"
+}
+
+// Run runs the command on the host with the given stdin, giving back what it wrote to stdout
+func (sr sshRunner) Run(ctx context.Context, stdin io.Reader, name string, args ...string) ([]byte, error) {
+	quoted := []string{shellQuote(name)}
+	for _, arg := range args {
+		quoted = append(quoted, shellQuote(arg))
+	}
+	sshArgs := append(append([]string{}, sr.args...), "--", strings.Join(quoted, " "))
+	return localRunner{}.Run(ctx, stdin, "ssh", sshArgs...)
+}
+
+// nerdctlExec is an exec which was created, it is only run once it is started
+type nerdctlExec struct {
+	container string
+	config    types.ExecConfig
+	running   bool
+	exitCode  int
+}
+
+// nerdctlClient is the client of containerd, which drives nerdctl, on this machine or over ssh.
+// Containers, networks and volumes are created by its Engine. The rest of the docker engine API
+// is translated into the commands of nerdctl, which takes the same arguments as the docker cli.
+// nerdctl cannot connect containers to networks after they are created, or share volumes
+// between containers.
+type nerdctlClient struct {
+	runner    CommandRunner
+	path      string
+	address   string
+	namespace string
+
+	mux   *sync.Mutex
+	execs map[string]*nerdctlExec
+	next  *int
+}
+
+// NewNerdctlClient creates an entity.Client which drives the containerd at the given address,
+// in the given namespace, with the nerdctl binary at path
+func NewNerdctlClient(runner CommandRunner, path string, address string, namespace string) entity.Client {
+	return nerdctlClient{
+		runner:    runner,
+		path:      path,
+		address:   address,
+		namespace: namespace,
+		mux:       &sync.Mutex{},
+		execs:     map[string]*nerdctlExec{},
+		next:      new(int),
+	}
+}
+
+func notSupported(operation string) error {
+	return entity.NotSupportedError{Runtime: entity.ContainerdRuntime, Operation: operation}
+}
+
+// nerdctlErrors maps the messages of nerdctl onto the ones which docker gives back
+var nerdctlErrors = []struct {
+	nerdctl string
+	docker  string
+}{
+	{nerdctl: "no such container", docker: "No such container"},
+	{nerdctl: "already used by", docker: "already in use by container"},
+	{nerdctl: "name is already used", docker: "already in use by container"},
+}
+
+func (nc nerdctlClient) run(ctx context.Context, stdin io.Reader, args ...string) ([]byte, error) {
+	global := []string{"--namespace", nc.namespace}
+	if len(nc.address) > 0 {
+		global = append(global, "--address", nc.address)
+	}
+	out, err := nc.runner.Run(ctx, stdin, nc.path, append(global, args...)...)
+	if err == nil {
+		return out, nil
+	}
+	msg := strings.ToLower(err.Error())
+	for _, entry := range nerdctlErrors {
+		if strings.Contains(msg, entry.nerdctl) {
+			return out, fmt.Errorf("%s: %w", entry.docker, err)
+		}
+	}
+	return out, err
+}
+
+// runJSON runs the command, decoding each line of its output into a value from next
+func (nc nerdctlClient) runJSON(ctx context.Context, next func() interface{}, args ...string) error {
+	out, err := nc.run(ctx, nil, args...)
+	if err != nil {
+		return err
+	}
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		err = json.Unmarshal(line, next())
+		if err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+// labelArgs gives the labels as arguments, nerdctl prints them back as a comma separated list
+func labelArgs(labels map[string]string) []string {
+	keys := []string{}
+	for key := range labels {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	out := []string{}
+	for _, key := range keys {
+		out = append(out, "--label", key+"="+labels[key])
+	}
+	return out
+}
+
+func parseLabels(raw string) map[string]string {
+	out := map[string]string{}
+	for _, pair := range strings.Split(raw, ",") {
+		parts := strings.SplitN(pair, "=", 2)
+		if len(parts) == 2 {
+			out[parts[0]] = parts[1]
+		}
+	}
+	return out
+}
+
+// Close the transport used by the client, there is none
+func (nc nerdctlClient) Close() error {
+	return nil
+}
+
+// ContainerAttach is not supported, nerdctl can only attach from a terminal
+func (nc nerdctlClient) ContainerAttach(ctx context.Context, container string,
+	options types.ContainerAttachOptions) (types.HijackedResponse, error) {
+	return types.HijackedResponse{}, notSupported("attaching to a container")
+}
+
+// ContainerCreate is not supported, containers are created by the Engine
+func (nc nerdctlClient) ContainerCreate(ctx context.Context, config *container.Config,
+	hostConfig *container.HostConfig, networkingConfig *network.NetworkingConfig,
+	containerName string) (container.ContainerCreateCreatedBody, error) {
+	return container.ContainerCreateCreatedBody{}, notSupported("creating a container through the docker engine API")
+}
+
+// ContainerExecAttach is not supported, the output of an exec is not kept
+func (nc nerdctlClient) ContainerExecAttach(ctx context.Context, execID string,
+	config types.ExecStartCheck) (types.HijackedResponse, error) {
+	return types.HijackedResponse{}, notSupported("attaching to an exec")
+}
+
+// ContainerExecCreate keeps the configuration of the exec, until it is started
+func (nc nerdctlClient) ContainerExecCreate(ctx context.Context, container string,
+	config types.ExecConfig) (types.IDResponse, error) {
+
+	nc.mux.Lock()
+	defer nc.mux.Unlock()
+	*nc.next++
+	id := fmt.Sprintf("exec-%d", *nc.next)
+	nc.execs[id] = &nerdctlExec{container: container, config: config}
+	return types.IDResponse{ID: id}, nil
+}
+
+// ContainerExecInspect returns information about the exec, once it has been run
+func (nc nerdctlClient) ContainerExecInspect(ctx context.Context, execID string) (types.ContainerExecInspect, error) {
+	nc.mux.Lock()
+	defer nc.mux.Unlock()
+	ex, ok := nc.execs[execID]
+	if !ok {
+		return types.ContainerExecInspect{}, fmt.Errorf("No such exec instance: %s", execID)
+	}
+	return types.ContainerExecInspect{
+		ExecID:      execID,
+		ContainerID: ex.container,
+		Running:     ex.running,
+		ExitCode:    ex.exitCode,
+	}, nil
+}
+
+// ContainerExecStart runs the exec until it exits
+func (nc nerdctlClient) ContainerExecStart(ctx context.Context, execID string, config types.ExecStartCheck) error {
+	nc.mux.Lock()
+	ex, ok := nc.execs[execID]
+	if ok {
+		ex.running = true
+	}
+	nc.mux.Unlock()
+	if !ok {
+		return fmt.Errorf("No such exec instance: %s", execID)
+	}
+
+	args := []string{"exec"}
+	if ex.config.Privileged {
+		args = append(args, "--privileged")
+	}
+	for _, env := range ex.config.Env {
+		args = append(args, "--env", env)
+	}
+	args = append(append(args, ex.container), ex.config.Cmd...)
+	_, err := nc.run(ctx, nil, args...)
+
+	nc.mux.Lock()
+	defer nc.mux.Unlock()
+	ex.running = false
+	var cmdErr CommandError
+	if errors.As(err, &cmdErr) {
+		ex.exitCode = cmdErr.ExitCode
+		return nil
+	}
+	return err
+}
+
+// ContainerInspect returns the container information, nerdctl gives it in the format of docker
+func (nc nerdctlClient) ContainerInspect(ctx context.Context, containerID string) (types.ContainerJSON, error) {
+	out, err := nc.run(ctx, nil, "container", "inspect", "--mode=dockercompat", containerID)
+	if err != nil {
+		return types.ContainerJSON{}, err
+	}
+	res := []types.ContainerJSON{}
+	err = json.Unmarshal(out, &res)
+	if err != nil {
+		return types.ContainerJSON{}, err
+	}
+	if len(res) == 0 {
+		return types.ContainerJSON{}, fmt.Errorf("Error: No such container: %s", containerID)
+	}
+	return res[0], nil
+}
+
+// ContainerKill sends the signal to the container
+func (nc nerdctlClient) ContainerKill(ctx context.Context, containerID, signal string) error {
+	_, err := nc.run(ctx, nil, "kill", "--signal", signal, containerID)
+	return err
+}
+
+// ContainerList returns the list of containers
+func (nc nerdctlClient) ContainerList(ctx context.Context,
+	options types.ContainerListOptions) ([]types.Container, error) {
+
+	args := []string{"ps", "--no-trunc", "--format", "{{json .}}"}
+	if options.All {
+		args = append(args, "--all")
+	}
+	type listed struct {
+		ID     string
+		Names  string
+		Image  string
+		Status string
+		Labels string
+	}
+	rows := []*listed{}
+	err := nc.runJSON(ctx, func() interface{} {
+		rows = append(rows, &listed{})
+		return rows[len(rows)-1]
+	}, args...)
+	if err != nil {
+		return nil, err
+	}
+	out := []types.Container{}
+	for _, row := range rows {
+		out = append(out, types.Container{
+			ID:     row.ID,
+			Names:  []string{"/" + row.Names},
+			Image:  row.Image,
+			Status: row.Status,
+			Labels: parseLabels(row.Labels),
+		})
+	}
+	return out, nil
+}
+
+// ContainerLogs returns the logs of the container, multiplexed like the logs from docker.
+// nerdctl writes the logs of the container to its own stdout and stderr, only stdout is kept.
+func (nc nerdctlClient) ContainerLogs(ctx context.Context, container string,
+	options types.ContainerLogsOptions) (io.ReadCloser, error) {
+
+	args := []string{"logs"}
+	if options.Timestamps {
+		args = append(args, "--timestamps")
+	}
+	if len(options.Tail) > 0 {
+		args = append(args, "--tail", options.Tail)
+	}
+	out, err := nc.run(ctx, nil, append(args, container)...)
+	if err != nil {
+		return nil, err
+	}
+	buf := new(bytes.Buffer)
+	if options.ShowStdout {
+		_, err = stdcopy.NewStdWriter(buf, stdcopy.Stdout).Write(out)
+	}
+	return ioutil.NopCloser(buf), err
+}
+
+// ContainerPause pauses the processes of the container
+func (nc nerdctlClient) ContainerPause(ctx context.Context, containerID string) error {
+	_, err := nc.run(ctx, nil, "pause", containerID)
+	return err
+}
+
+// ContainerRemove removes the container
+func (nc nerdctlClient) ContainerRemove(ctx context.Context, containerID string,
+	options types.ContainerRemoveOptions) error {
+
+	args := []string{"rm"}
+	if options.Force {
+		args = append(args, "--force")
+	}
+	if options.RemoveVolumes {
+		args = append(args, "--volumes")
+	}
+	_, err := nc.run(ctx, nil, append(args, containerID)...)
+	return err
+}
+
+// ContainerStart starts the container
+func (nc nerdctlClient) ContainerStart(ctx context.Context, containerID string,
+	options types.ContainerStartOptions) error {
+	_, err := nc.run(ctx, nil, "start", containerID)
+	return err
+}
+
+// ContainerStatPath stats the path from inside of the container, which needs a stat binary
+func (nc nerdctlClient) ContainerStatPath(ctx context.Context, containerID,
+	path string) (types.ContainerPathStat, error) {
+
+	out, err := nc.run(ctx, nil, "exec", containerID, "stat", "-c", "%s %f %Y %n", path)
+	if err != nil {
+		return types.ContainerPathStat{}, err
+	}
+	fields := strings.SplitN(strings.TrimSpace(string(out)), " ", 4)
+	if len(fields) != 4 {
+		return types.ContainerPathStat{}, fmt.Errorf("unexpected output from stat: %s", out)
+	}
+	size, _ := strconv.ParseInt(fields[0], 10, 64)
+	raw, _ := strconv.ParseUint(fields[1], 16, 32)
+	mtime, _ := strconv.ParseInt(fields[2], 10, 64)
+	mode := os.FileMode(raw & 0777)
+	switch raw & 0170000 {
+	case 0040000:
+		mode |= os.ModeDir
+	case 0120000:
+		mode |= os.ModeSymlink
+	}
+	return types.ContainerPathStat{
+		Name:  fields[3],
+		Size:  size,
+		Mode:  mode,
+		Mtime: time.Unix(mtime, 0),
+	}, nil
+}
+
+// ContainerStop stops the container
+func (nc nerdctlClient) ContainerStop(ctx context.Context, containerID string, timeout *time.Duration) error {
+	args := []string{"stop"}
+	if timeout != nil {
+		args = append(args, "--time", strconv.Itoa(int(timeout.Seconds())))
+	}
+	_, err := nc.run(ctx, nil, append(args, containerID)...)
+	return err
+}
+
+// ContainerUnpause resumes the processes of the container
+func (nc nerdctlClient) ContainerUnpause(ctx context.Context, containerID string) error {
+	_, err := nc.run(ctx, nil, "unpause", containerID)
+	return err
+}
+
+// ContainerStats is not supported, nerdctl does not give the stats in the format of docker
+func (nc nerdctlClient) ContainerStats(ctx context.Context, containerID string,
+	stream bool) (types.ContainerStats, error) {
+	return types.ContainerStats{}, notSupported("getting the stats of a container")
+}
+
+// ContainerUpdate updates the cpu and memory limits of the container
+func (nc nerdctlClient) ContainerUpdate(ctx context.Context, containerID string,
+	updateConfig container.UpdateConfig) (container.ContainerUpdateOKBody, error) {
+
+	args := []string{"update"}
+	if updateConfig.NanoCPUs > 0 {
+		args = append(args, "--cpus", strconv.FormatFloat(float64(updateConfig.NanoCPUs)/1e9, 'f', -1, 64))
+	}
+	if updateConfig.Memory > 0 {
+		args = append(args, "--memory", fmt.Sprintf("%db", updateConfig.Memory))
+	}
+	_, err := nc.run(ctx, nil, append(args, containerID)...)
+	return container.ContainerUpdateOKBody{}, err
+}
+
+// ContainerWait waits until the container exits, the condition is ignored
+func (nc nerdctlClient) ContainerWait(ctx context.Context, containerID string,
+	condition container.WaitCondition) (<-chan container.ContainerWaitOKBody, <-chan error) {
+
+	resC := make(chan container.ContainerWaitOKBody, 1)
+	errC := make(chan error, 1)
+	go func() {
+		out, err := nc.run(ctx, nil, "wait", containerID)
+		if err != nil {
+			errC <- err
+			return
+		}
+		code, err := strconv.ParseInt(strings.TrimSpace(string(out)), 10, 64)
+		if err != nil {
+			errC <- err
+			return
+		}
+		resC <- container.ContainerWaitOKBody{StatusCode: code}
+	}()
+	return resC, errC
+}
+
+// CopyToContainer extracts the tar archive into the container, which needs a tar binary
+func (nc nerdctlClient) CopyToContainer(ctx context.Context, containerID, dstPath string,
+	content io.Reader, options types.CopyToContainerOptions) error {
+	_, err := nc.run(ctx, content, "exec", "--interactive", containerID, "tar", "-x", "-C", dstPath)
+	return err
+}
+
+// CopyFromContainer archives the path in the container, which needs a tar binary
+func (nc nerdctlClient) CopyFromContainer(ctx context.Context, containerID,
+	srcPath string) (io.ReadCloser, types.ContainerPathStat, error) {
+
+	stat, err := nc.ContainerStatPath(ctx, containerID, srcPath)
+	if err != nil {
+		return nil, types.ContainerPathStat{}, err
+	}
+	out, err := nc.run(ctx, nil, "exec", containerID, "tar", "-c", "-C", path.Dir(srcPath), path.Base(srcPath))
+	if err != nil {
+		return nil, types.ContainerPathStat{}, err
+	}
+	return ioutil.NopCloser(bytes.NewReader(out)), stat, nil
+}
+
+// DaemonHost returns the address of containerd
+func (nc nerdctlClient) DaemonHost() string {
+	return "unix://" + nc.address
+}
+
+// Events is not supported, the events of nerdctl are the ones of containerd, which do not map
+// onto the ones of docker
+func (nc nerdctlClient) Events(ctx context.Context,
+	options types.EventsOptions) (<-chan events.Message, <-chan error) {
+
+	errC := make(chan error, 1)
+	errC <- notSupported("following the events of the daemon")
+	return make(chan events.Message), errC
+}
+
+// HTTPClient returns the default client, since containerd is not reached over HTTP
+func (nc nerdctlClient) HTTPClient() *http.Client {
+	return http.DefaultClient
+}
+
+// ImageList returns the images, with their names normalized like docker does
+func (nc nerdctlClient) ImageList(ctx context.Context, options types.ImageListOptions) ([]types.ImageSummary, error) {
+	type listed struct {
+		ID         string
+		Repository string
+		Tag        string
+		Digest     string
+	}
+	rows := []*listed{}
+	err := nc.runJSON(ctx, func() interface{} {
+		rows = append(rows, &listed{})
+		return rows[len(rows)-1]
+	}, "images", "--no-trunc", "--format", "{{json .}}")
+	if err != nil {
+		return nil, err
+	}
+	out := []types.ImageSummary{}
+	for _, row := range rows {
+		summary := types.ImageSummary{ID: row.ID}
+		name := row.Repository + ":" + row.Tag
+		summary.RepoTags = append(summary.RepoTags, name)
+		if ref, err := reference.ParseNormalizedNamed(name); err == nil && ref.String() != name {
+			summary.RepoTags = append(summary.RepoTags, ref.String())
+		}
+		if len(row.Digest) > 0 {
+			summary.RepoDigests = append(summary.RepoDigests, row.Repository+"@"+row.Digest)
+		}
+		out = append(out, summary)
+	}
+	return out, nil
+}
+
+// ImageLoad loads the images in the tar archive
+func (nc nerdctlClient) ImageLoad(ctx context.Context, input io.Reader, quiet bool) (types.ImageLoadResponse, error) {
+	out, err := nc.run(ctx, input, "load")
+	return types.ImageLoadResponse{Body: ioutil.NopCloser(bytes.NewReader(out))}, err
+}
+
+// ImagePull pulls the image, logging in to its registry first if credentials are given
+func (nc nerdctlClient) ImagePull(ctx context.Context, refStr string,
+	options types.ImagePullOptions) (io.ReadCloser, error) {
+
+	if len(options.RegistryAuth) > 0 {
+		data, err := base64.URLEncoding.DecodeString(options.RegistryAuth)
+		if err != nil {
+			return nil, err
+		}
+		var auth types.AuthConfig
+		err = json.Unmarshal(data, &auth)
+		if err != nil {
+			return nil, err
+		}
+		ref, err := reference.ParseNormalizedNamed(refStr)
+		if err != nil {
+			return nil, err
+		}
+		_, err = nc.run(ctx, strings.NewReader(auth.Password), "login", "--username", auth.Username,
+			"--password-stdin", reference.Domain(ref))
+		if err != nil {
+			return nil, err
+		}
+	}
+	out, err := nc.run(ctx, nil, "pull", "--quiet", refStr)
+	return ioutil.NopCloser(bytes.NewReader(out)), err
+}
+
+// NetworkCreate is not supported, networks are created by the Engine
+func (nc nerdctlClient) NetworkCreate(ctx context.Context, name string,
+	options types.NetworkCreate) (types.NetworkCreateResponse, error) {
+	return types.NetworkCreateResponse{}, notSupported("creating a network through the docker engine API")
+}
+
+// NetworkConnect is not supported, containers only join networks when they are created
+func (nc nerdctlClient) NetworkConnect(ctx context.Context, networkID, containerID string,
+	config *network.EndpointSettings) error {
+	return notSupported("connecting a created container to a network")
+}
+
+// NetworkDisconnect is not supported, containers keep their networks until they are removed
+func (nc nerdctlClient) NetworkDisconnect(ctx context.Context, networkID, containerID string, force bool) error {
+	return notSupported("disconnecting a container from a network")
+}
+
+// NetworkInspect returns the information of the network, nerdctl gives it in the format of docker
+func (nc nerdctlClient) NetworkInspect(ctx context.Context, networkID string,
+	options types.NetworkInspectOptions) (types.NetworkResource, error) {
+
+	nets, err := nc.inspectNetworks(ctx, networkID)
+	if err != nil {
+		return types.NetworkResource{}, err
+	}
+	if len(nets) == 0 {
+		return types.NetworkResource{}, fmt.Errorf("Error: No such network: %s", networkID)
+	}
+	return nets[0], nil
+}
+
+func (nc nerdctlClient) inspectNetworks(ctx context.Context, names ...string) ([]types.NetworkResource, error) {
+	out, err := nc.run(ctx, nil, append([]string{"network", "inspect", "--mode=dockercompat"}, names...)...)
+	if err != nil {
+		return nil, err
+	}
+	res := []types.NetworkResource{}
+	return res, json.Unmarshal(out, &res)
+}
+
+// NetworkRemove removes the network
+func (nc nerdctlClient) NetworkRemove(ctx context.Context, networkID string) error {
+	_, err := nc.run(ctx, nil, "network", "rm", networkID)
+	return err
+}
+
+// NetworkList lists the networks, with all of their information
+func (nc nerdctlClient) NetworkList(ctx context.Context, options types.NetworkListOptions) ([]types.NetworkResource, error) {
+	out, err := nc.run(ctx, nil, "network", "ls", "--format", "{{.Name}}")
+	if err != nil {
+		return nil, err
+	}
+	names := strings.Fields(string(out))
+	if len(names) == 0 {
+		return []types.NetworkResource{}, nil
+	}
+	return nc.inspectNetworks(ctx, names...)
+}
+
+// Ping checks that nerdctl can reach containerd
+func (nc nerdctlClient) Ping(ctx context.Context) (types.Ping, error) {
+	_, err := nc.run(ctx, nil, "version")
+	return types.Ping{OSType: "linux"}, err
+}
+
+// Info gives the resources of the host, nerdctl prints them in the same form docker does
+func (nc nerdctlClient) Info(ctx context.Context) (types.Info, error) {
+	out, err := nc.run(ctx, nil, "info", "--format", "{{json .}}")
+	if err != nil {
+		return types.Info{}, err
+	}
+	var info types.Info
+	return info, json.Unmarshal(out, &info)
+}
+
+// SwarmInit is not supported, containerd has no swarm
+func (nc nerdctlClient) SwarmInit(ctx context.Context, req swarm.InitRequest) (string, error) {
+	return "", notSupported("swarm")
+}
+
+// SwarmJoin is not supported, containerd has no swarm
+func (nc nerdctlClient) SwarmJoin(ctx context.Context, req swarm.JoinRequest) error {
+	return notSupported("swarm")
+}
+
+// SwarmInspect is not supported, containerd has no swarm
+func (nc nerdctlClient) SwarmInspect(ctx context.Context) (swarm.Swarm, error) {
+	return swarm.Swarm{}, notSupported("swarm")
+}
+
+// SwarmLeave is not supported, containerd has no swarm
+func (nc nerdctlClient) SwarmLeave(ctx context.Context, force bool) error {
+	return notSupported("swarm")
+}
+
+// NodeInspectWithRaw is not supported, containerd has no swarm
+func (nc nerdctlClient) NodeInspectWithRaw(ctx context.Context, nodeID string) (swarm.Node, []byte, error) {
+	return swarm.Node{}, nil, notSupported("swarm")
+}
+
+// NodeUpdate is not supported, containerd has no swarm
+func (nc nerdctlClient) NodeUpdate(ctx context.Context, nodeID string, version swarm.Version,
+	node swarm.NodeSpec) error {
+	return notSupported("swarm")
+}
+
+// ServiceCreate is not supported, containerd has no swarm
+func (nc nerdctlClient) ServiceCreate(ctx context.Context, service swarm.ServiceSpec,
+	options types.ServiceCreateOptions) (types.ServiceCreateResponse, error) {
+	return types.ServiceCreateResponse{}, notSupported("swarm")
+}
+
+// ServiceInspectWithRaw is not supported, containerd has no swarm
+func (nc nerdctlClient) ServiceInspectWithRaw(ctx context.Context, serviceID string,
+	options types.ServiceInspectOptions) (swarm.Service, []byte, error) {
+	return swarm.Service{}, nil, notSupported("swarm")
+}
+
+// ServiceRemove is not supported, containerd has no swarm
+func (nc nerdctlClient) ServiceRemove(ctx context.Context, serviceID string) error {
+	return notSupported("swarm")
+}
+
+// ServiceUpdate is not supported, containerd has no swarm
+func (nc nerdctlClient) ServiceUpdate(ctx context.Context, serviceID string, version swarm.Version,
+	service swarm.ServiceSpec, options types.ServiceUpdateOptions) (types.ServiceUpdateResponse, error) {
+	return types.ServiceUpdateResponse{}, notSupported("swarm")
+}
+
+// TaskList is not supported, containerd has no swarm
+func (nc nerdctlClient) TaskList(ctx context.Context, options types.TaskListOptions) ([]swarm.Task, error) {
+	return nil, notSupported("swarm")
+}
+
+// VolumeCreate is not supported, volumes are created by the Engine
+func (nc nerdctlClient) VolumeCreate(ctx context.Context, options volume.VolumeCreateBody) (types.Volume, error) {
+	return types.Volume{}, notSupported("creating a volume through the docker engine API")
+}
+
+// VolumeList returns the volumes, the filters are ignored
+func (nc nerdctlClient) VolumeList(ctx context.Context, filter filters.Args) (volume.VolumeListOKBody, error) {
+	type listed struct {
+		Name       string
+		Driver     string
+		Mountpoint string
+		Labels     string
+	}
+	rows := []*listed{}
+	err := nc.runJSON(ctx, func() interface{} {
+		rows = append(rows, &listed{})
+		return rows[len(rows)-1]
+	}, "volume", "ls", "--format", "{{json .}}")
+	if err != nil {
+		return volume.VolumeListOKBody{}, err
+	}
+	out := volume.VolumeListOKBody{Volumes: []*types.Volume{}}
+	for _, row := range rows {
+		out.Volumes = append(out.Volumes, &types.Volume{
+			Name:       row.Name,
+			Driver:     row.Driver,
+			Mountpoint: row.Mountpoint,
+			Labels:     parseLabels(row.Labels),
+		})
+	}
+	return out, nil
+}
+
+// VolumeRemove removes the volume
+func (nc nerdctlClient) VolumeRemove(ctx context.Context, volumeID string, force bool) error {
+	args := []string{"volume", "rm"}
+	if force {
+		args = append(args, "--force")
+	}
+	_, err := nc.run(ctx, nil, append(args, volumeID)...)
+	return err
+}
+
+// nerdctlEngineErrors are the messages nerdctl gives back, along with the ones of docker which
+// run rewrites them into
+var nerdctlEngineErrors = append(engineErrors{
+	{msg: "not found", kind: entity.ErrNotFound},
+}, dockerErrors...)
+
+// nerdctlEngine is the adapter of containerd, which drives nerdctl
+type nerdctlEngine struct {
+	nc nerdctlClient
+}
+
+// Engine gives the adapter of containerd, containers can only join networks when they are created
+func (nc nerdctlClient) Engine() entity.Engine {
+	return nerdctlEngine{nc: nc}
+}
+
+// createArgs gives the arguments of nerdctl create for the spec
+func createArgs(spec entity.ContainerSpec) ([]string, error) {
+	if len(spec.VolumesFrom) > 0 {
+		return nil, notSupported("sharing the volumes of another container")
+	}
+	args := []string{"create"}
+	if len(spec.Name) > 0 {
+		args = append(args, "--name", spec.Name)
+	}
+	if len(spec.Hostname) > 0 {
+		args = append(args, "--hostname", spec.Hostname)
+	}
+	for _, env := range spec.Env {
+		args = append(args, "--env", env)
+	}
+	args = append(args, labelArgs(spec.Labels)...)
+	cmd := []string{}
+	if len(spec.Entrypoint) > 0 {
+		args = append(args, "--entrypoint", spec.Entrypoint[0])
+		cmd = spec.Entrypoint[1:]
+	}
+	for _, port := range spec.Ports {
+		mapping := fmt.Sprintf("%d:%d/%s", port.HostPort, port.ContainerPort, port.Protocol)
+		if len(port.HostIP) > 0 {
+			mapping = port.HostIP + ":" + mapping
+		}
+		args = append(args, "--publish", mapping)
+	}
+	if spec.AutoRemove {
+		args = append(args, "--rm")
+	}
+	if len(spec.LogDriver) > 0 {
+		args = append(args, "--log-driver", spec.LogDriver)
+	}
+	for _, mnt := range spec.Mounts {
+		opt := fmt.Sprintf("type=%s,source=%s,target=%s", mnt.Type, mnt.Source, mnt.Target)
+		if mnt.ReadOnly {
+			opt += ",readonly"
+		}
+		args = append(args, "--mount", opt)
+	}
+	for _, dns := range spec.DNS {
+		args = append(args, "--dns", dns)
+	}
+	for _, search := range spec.DNSSearch {
+		args = append(args, "--dns-search", search)
+	}
+	for _, opt := range spec.DNSOptions {
+		args = append(args, "--dns-opt", opt)
+	}
+	if spec.NanoCPUs > 0 {
+		args = append(args, "--cpus", strconv.FormatFloat(float64(spec.NanoCPUs)/1e9, 'f', -1, 64))
+	}
+	if spec.Memory > 0 {
+		args = append(args, "--memory", fmt.Sprintf("%db", spec.Memory))
+	}
+	for _, capability := range spec.CapAdd {
+		args = append(args, "--cap-add", capability)
+	}
+	if spec.Privileged {
+		args = append(args, "--privileged")
+	}
+	if len(spec.PidMode) > 0 {
+		args = append(args, "--pid", spec.PidMode)
+	}
+	switch {
+	case len(spec.NetworkMode) > 0:
+		args = append(args, "--network", spec.NetworkMode)
+	case spec.Network != nil:
+		args = append(args, "--network", spec.Network.Network)
+		if len(spec.Network.IPv4) > 0 {
+			args = append(args, "--ip", spec.Network.IPv4)
+		}
+		if len(spec.Network.IPv6) > 0 {
+			args = append(args, "--ip6", spec.Network.IPv6)
+		}
+		if len(spec.Network.MacAddress) > 0 {
+			args = append(args, "--mac-address", spec.Network.MacAddress)
+		}
+	}
+	args = append(args, spec.Image)
+	return append(args, cmd...), nil
+}
+
+// CreateContainer creates the container
+func (ne nerdctlEngine) CreateContainer(ctx context.Context, spec entity.ContainerSpec) error {
+	args, err := createArgs(spec)
+	if err != nil {
+		return err
+	}
+	_, err = ne.nc.run(ctx, nil, args...)
+	return nerdctlEngineErrors.classify(err)
+}
+
+// StartContainer starts the container
+func (ne nerdctlEngine) StartContainer(ctx context.Context, name string) error {
+	_, err := ne.nc.run(ctx, nil, "start", name)
+	return nerdctlEngineErrors.classify(err)
+}
+
+// RemoveContainer removes the container, even if it is running
+func (ne nerdctlEngine) RemoveContainer(ctx context.Context, name string, volumes bool) error {
+	args := []string{"rm", "--force"}
+	if volumes {
+		args = append(args, "--volumes")
+	}
+	_, err := ne.nc.run(ctx, nil, append(args, name)...)
+	return nerdctlEngineErrors.classify(err)
+}
+
+// CreateNetwork creates the network, there is no swarm so overlay networks are created as
+// bridge networks
+func (ne nerdctlEngine) CreateNetwork(ctx context.Context, spec entity.NetworkSpec) error {
+	if spec.Driver == entity.OverlayDriver {
+		spec.Driver = entity.BridgeDriver
+	}
+	args := []string{"network", "create"}
+	if len(spec.Driver) > 0 {
+		args = append(args, "--driver", spec.Driver)
+	}
+	if len(spec.IPAMDriver) > 0 && spec.IPAMDriver != "default" {
+		args = append(args, "--ipam-driver", spec.IPAMDriver)
+	}
+	for _, subnet := range spec.Subnets {
+		if len(subnet.Subnet) > 0 {
+			args = append(args, "--subnet", subnet.Subnet)
+		}
+		if len(subnet.Gateway) > 0 {
+			args = append(args, "--gateway", subnet.Gateway)
+		}
+		if len(subnet.IPRange) > 0 {
+			args = append(args, "--ip-range", subnet.IPRange)
+		}
+	}
+	if spec.EnableIPv6 {
+		args = append(args, "--ipv6")
+	}
+	// nerdctl takes the options of docker, by their names
+	opts := networkOptions(spec)
+	keys := []string{}
+	for key := range opts {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		args = append(args, "--opt", key+"="+opts[key])
+	}
+	args = append(args, labelArgs(spec.Labels)...)
+	_, err := ne.nc.run(ctx, nil, append(args, spec.Name)...)
+	return nerdctlEngineErrors.classify(err)
+}
+
+// RemoveNetwork removes the network
+func (ne nerdctlEngine) RemoveNetwork(ctx context.Context, name string) error {
+	_, err := ne.nc.run(ctx, nil, "network", "rm", name)
+	return nerdctlEngineErrors.classify(err)
+}
+
+// ConnectNetwork is not supported, containers only join networks when they are created
+func (ne nerdctlEngine) ConnectNetwork(ctx context.Context, container string, endpoint entity.EndpointSpec) error {
+	return notSupported("connecting a created container to a network")
+}
+
+// DisconnectNetwork is not supported, containers stay on their networks until they are removed
+func (ne nerdctlEngine) DisconnectNetwork(ctx context.Context, container string, network string) error {
+	return notSupported("disconnecting a container from a network")
+}
+
+// CreateVolume creates the volume, only the local driver without options is supported
+func (ne nerdctlEngine) CreateVolume(ctx context.Context, spec entity.VolumeSpec) error {
+	if (len(spec.Driver) > 0 && spec.Driver != "local") || len(spec.Options) > 0 {
+		return notSupported("creating a volume with a driver or driver options")
+	}
+	args := append([]string{"volume", "create"}, labelArgs(spec.Labels)...)
+	_, err := ne.nc.run(ctx, nil, append(args, spec.Name)...)
+	return nerdctlEngineErrors.classify(err)
+}
+
+// RemoveVolume removes the volume, even if it is in use
+func (ne nerdctlEngine) RemoveVolume(ctx context.Context, name string) error {
+	_, err := ne.nc.run(ctx, nil, "volume", "rm", "--force", name)
+	return nerdctlEngineErrors.classify(err)
+}
@@ -0,0 +1,197 @@
+/**
+ * Copyright 2019 Whiteblock Inc. All rights reserved.
+ * Use of this source code is governed by a BSD-style
+ * license that can be found in the LICENSE file.
+ */
+
+package repository
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/whiteblock/genesis/pkg/entity"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/pkg/stdcopy"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testRunner struct {
+	calls   [][]string
+	stdin   []string
+	outputs map[string]string
+	errors  map[string]error
+}
+
+func (tr *testRunner) Run(ctx context.Context, stdin io.Reader, name string, args ...string) ([]byte, error) {
+	tr.calls = append(tr.calls, append([]string{name}, args...))
+	if stdin != nil {
+		data, _ := ioutil.ReadAll(stdin)
+		tr.stdin = append(tr.stdin, string(data))
+	}
+	for len(args) > 1 && (args[0] == "--namespace" || args[0] == "--address") {
+		args = args[2:]
+	}
+	key := strings.Join(args, " ")
+	return []byte(tr.outputs[key]), tr.errors[key]
+}
+
+func TestNerdctlEngine_CreateContainer(t *testing.T) {
+	runner := &testRunner{outputs: map[string]string{}}
+	runner.outputs["create --name node --hostname node --env A=1 --label org=wb --entrypoint /bin/sh "+
+		"--log-driver journald --mount type=volume,source=data,target=/data,readonly --cpus 1.5 "+
+		"--memory 1024b --network net1 --ip 10.0.0.2 alpine -c sleep"] = "abc123\n"
+	engine := NewNerdctlClient(runner, "nerdctl", "/run/containerd/containerd.sock", "genesis").(entity.EngineProvider).Engine()
+
+	err := engine.CreateContainer(context.Background(), entity.ContainerSpec{
+		Name:       "node",
+		Hostname:   "node",
+		Env:        []string{"A=1"},
+		Labels:     map[string]string{"org": "wb"},
+		Image:      "alpine",
+		Entrypoint: []string{"/bin/sh", "-c", "sleep"},
+		LogDriver:  "journald",
+		LogOptions: map[string]string{"labels": "org"},
+		Mounts:     []entity.MountSpec{{Type: entity.VolumeMountType, Source: "data", Target: "/data", ReadOnly: true}},
+		NanoCPUs:   1500000000,
+		Memory:     1024,
+		Network:    &entity.EndpointSpec{Network: "net1", IPv4: "10.0.0.2"},
+	})
+	require.NoError(t, err)
+	require.Len(t, runner.calls, 1)
+	assert.Equal(t, []string{"nerdctl", "--namespace", "genesis", "--address", "/run/containerd/containerd.sock"},
+		runner.calls[0][:5])
+
+	err = engine.CreateContainer(context.Background(), entity.ContainerSpec{Name: "sidecar", Image: "alpine",
+		VolumesFrom: "node"})
+	assert.True(t, entity.IsNotSupported(err))
+	assert.True(t, entity.IsNotSupported(engine.ConnectNetwork(context.Background(), "node",
+		entity.EndpointSpec{Network: "net1"})))
+}
+
+func TestNerdctlEngine_Networks(t *testing.T) {
+	runner := &testRunner{outputs: map[string]string{}, errors: map[string]error{
+		"network rm gone": CommandError{ExitCode: 1, Stderr: "network \"gone\" not found"},
+		"rm --force node": CommandError{ExitCode: 1, Stderr: "no such container: node"},
+	}}
+	engine := NewNerdctlClient(runner, "nerdctl", "", "genesis").(entity.EngineProvider).Engine()
+	ctx := context.Background()
+
+	require.NoError(t, engine.CreateNetwork(ctx, entity.NetworkSpec{
+		Name:       "net1",
+		Driver:     entity.OverlayDriver,
+		BridgeName: "net1",
+		MTU:        1400,
+		Subnets:    []entity.Subnet{{Subnet: "10.0.0.0/24", Gateway: "10.0.0.1"}},
+	}))
+	assert.Equal(t, []string{"network", "create", "--driver", "bridge", "--subnet", "10.0.0.0/24",
+		"--gateway", "10.0.0.1", "--opt", "com.docker.network.bridge.name=net1",
+		"--opt", "com.docker.network.driver.mtu=1400", "net1"}, runner.calls[0][3:])
+
+	assert.True(t, errors.Is(engine.RemoveNetwork(ctx, "gone"), entity.ErrNotFound))
+	assert.True(t, errors.Is(engine.RemoveContainer(ctx, "node", false), entity.ErrNotFound))
+}
+
+func TestSSHRunner(t *testing.T) {
+	runner, err := NewSSHRunner("ssh://root@10.0.0.2:2222")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"-l", "root", "-p", "2222", "10.0.0.2"}, runner.(sshRunner).args)
+	assert.Equal(t, `'it'"'"'s'`, shellQuote("it's"))
+
+	_, err = NewSSHRunner("ssh://root@10.0.0.2/path")
+	assert.Error(t, err)
+}
+
+func TestNerdctlClient_Exec(t *testing.T) {
+	runner := &testRunner{
+		outputs: map[string]string{},
+		errors: map[string]error{
+			"exec node false": fmt.Errorf("exec: %w", CommandError{ExitCode: 3}),
+		},
+	}
+	nc := NewNerdctlClient(runner, "nerdctl", "", "genesis")
+
+	for cmd, code := range map[string]int{"true": 0, "false": 3} {
+		id, err := nc.ContainerExecCreate(context.Background(), "node", types.ExecConfig{Cmd: []string{cmd}})
+		require.NoError(t, err)
+		require.NoError(t, nc.ContainerExecStart(context.Background(), id.ID, types.ExecStartCheck{}))
+		res, err := nc.ContainerExecInspect(context.Background(), id.ID)
+		require.NoError(t, err)
+		assert.False(t, res.Running)
+		assert.Equal(t, code, res.ExitCode)
+	}
+}
+
+func TestNerdctlClient_Queries(t *testing.T) {
+	runner := &testRunner{outputs: map[string]string{
+		"ps --no-trunc --format {{json .}} --all": `{"ID":"abc","Names":"node","Image":"alpine","Labels":"org=wb,test=1"}` + "\n",
+		"images --no-trunc --format {{json .}}":   `{"ID":"sha256:1","Repository":"alpine","Tag":"latest"}` + "\n",
+		"network ls --format {{.Name}}":           "bridge\nnet1\n",
+		"network inspect --mode=dockercompat bridge net1": `[{"Name":"bridge"},` +
+			`{"Name":"net1","IPAM":{"Config":[{"Subnet":"10.0.0.0/24"}]}}]`,
+		"logs node": "hello\n",
+		"wait node": "2\n",
+	}, errors: map[string]error{}}
+	runner.errors["rm gone"] = CommandError{ExitCode: 1, Stderr: "no such container: gone"}
+	nc := NewNerdctlClient(runner, "nerdctl", "", "genesis")
+	ctx := context.Background()
+
+	cntrs, err := nc.ContainerList(ctx, types.ContainerListOptions{All: true})
+	require.NoError(t, err)
+	require.Len(t, cntrs, 1)
+	assert.Equal(t, []string{"/node"}, cntrs[0].Names)
+	assert.Equal(t, map[string]string{"org": "wb", "test": "1"}, cntrs[0].Labels)
+
+	imgs, err := nc.ImageList(ctx, types.ImageListOptions{})
+	require.NoError(t, err)
+	require.Len(t, imgs, 1)
+	assert.Contains(t, imgs[0].RepoTags, "docker.io/library/alpine:latest")
+
+	nets, err := nc.NetworkList(ctx, types.NetworkListOptions{})
+	require.NoError(t, err)
+	require.Len(t, nets, 2)
+	assert.Equal(t, "10.0.0.0/24", nets[1].IPAM.Config[0].Subnet)
+
+	rdr, err := nc.ContainerLogs(ctx, "node", types.ContainerLogsOptions{ShowStdout: true})
+	require.NoError(t, err)
+	var stdout, stderr bytes.Buffer
+	_, err = stdcopy.StdCopy(&stdout, &stderr, rdr)
+	require.NoError(t, err)
+	assert.Equal(t, "hello\n", stdout.String())
+
+	resC, errC := nc.ContainerWait(ctx, "node", container.WaitConditionNotRunning)
+	select {
+	case res := <-resC:
+		assert.Equal(t, int64(2), res.StatusCode)
+	case err := <-errC:
+		t.Fatal(err)
+	}
+
+	err = nc.ContainerRemove(ctx, "gone", types.ContainerRemoveOptions{})
+	assert.Contains(t, err.Error(), "No such container")
+
+	err = nc.CopyToContainer(ctx, "node", "/etc", strings.NewReader("archive"), types.CopyToContainerOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"archive"}, runner.stdin)
+
+	runner.outputs["exec node stat -c %s %f %Y %n /tmp/volume.tar.gz"] = "7 81a4 1580000000 /tmp/volume.tar.gz\n"
+	runner.outputs["exec node tar -c -C /tmp volume.tar.gz"] = "tarball"
+	out, stat, err := nc.CopyFromContainer(ctx, "node", "/tmp/volume.tar.gz")
+	require.NoError(t, err)
+	data, _ := ioutil.ReadAll(out)
+	assert.Equal(t, "tarball", string(data))
+	assert.Equal(t, int64(7), stat.Size)
+
+	assert.True(t, entity.IsNotSupported(nc.NetworkConnect(ctx, "net1", "node", nil)))
+	_, errs := nc.Events(ctx, types.EventsOptions{})
+	assert.True(t, entity.IsNotSupported(<-errs))
+}
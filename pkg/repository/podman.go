@@ -0,0 +1,605 @@
+/**
+ * Copyright 2019 Whiteblock Inc. All rights reserved.
+ * Use of this source code is governed by a BSD-style
+ * license that can be found in the LICENSE file.
+ */
+
+package repository
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/whiteblock/genesis/pkg/entity"
+
+	"github.com/docker/distribution/reference"
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/swarm"
+	"github.com/docker/docker/client"
+)
+
+// podmanClient is the client of podman. The containers, networks and volumes are created through
+// its own API, by its Engine. Everything else goes through its docker compatible API, with where it
+// differs from docker smoothed over.
+type podmanClient struct {
+	entity.Client
+	engine entity.Engine
+}
+
+// NewPodmanClient adapts a docker client which is connected to the docker compatible API of podman,
+// along with the engine which drives the native API of the same podman
+func NewPodmanClient(cli entity.Client, engine entity.Engine) entity.Client {
+	return podmanClient{Client: cli, engine: engine}
+}
+
+// Engine gives the adapter of the native API of podman
+func (pc podmanClient) Engine() entity.Engine {
+	return pc.engine
+}
+
+// qualifyImage gives the fully qualified name of the image, podman does not resolve short names
+// to docker hub like docker does, unless it is configured to
+func qualifyImage(image string) string {
+	ref, err := reference.ParseNormalizedNamed(image)
+	if err != nil {
+		return image
+	}
+	return reference.TagNameOnly(ref).String()
+}
+
+// podmanErrors maps the messages of podman onto the ones which docker gives back for the same
+// situation, which the service checks for
+var podmanErrors = []struct {
+	podman string
+	docker string
+}{
+	{podman: "no such container", docker: "No such container"},
+	{podman: "no container with name or id", docker: "No such container"},
+	{podman: "no such network", docker: "No such network"},
+	{podman: "network not found", docker: "No such network"},
+	{podman: "no such volume", docker: "No such volume"},
+	{podman: "is already in use", docker: "already in use by container"},
+	{podman: "already connected to network", docker: "is already attached to network"},
+	{podman: "is not connected to network", docker: "is not connected to the network"},
+}
+
+// podmanError rewrites the error of podman into the one docker gives back, keeping the original
+func podmanError(err error) error {
+	if err == nil {
+		return nil
+	}
+	msg := strings.ToLower(err.Error())
+	for _, entry := range podmanErrors {
+		if strings.Contains(msg, entry.podman) {
+			return fmt.Errorf("%s: %w", entry.docker, err)
+		}
+	}
+	return err
+}
+
+// ContainerInspect returns the container information
+func (pc podmanClient) ContainerInspect(ctx context.Context, containerID string) (types.ContainerJSON, error) {
+	res, err := pc.Client.ContainerInspect(ctx, containerID)
+	return res, podmanError(err)
+}
+
+// ContainerRemove removes the container
+func (pc podmanClient) ContainerRemove(ctx context.Context, containerID string,
+	options types.ContainerRemoveOptions) error {
+	return podmanError(pc.Client.ContainerRemove(ctx, containerID, options))
+}
+
+// ImagePull pulls the image by its fully qualified name
+func (pc podmanClient) ImagePull(ctx context.Context, refStr string,
+	options types.ImagePullOptions) (io.ReadCloser, error) {
+	// podman only takes the platform as os/arch, in lower case
+	options.Platform = strings.ToLower(options.Platform)
+	return pc.Client.ImagePull(ctx, qualifyImage(refStr), options)
+}
+
+// SwarmInit is not supported, podman has no swarm
+func (pc podmanClient) SwarmInit(ctx context.Context, req swarm.InitRequest) (string, error) {
+	return "", entity.NotSupportedError{Runtime: entity.PodmanRuntime, Operation: "swarm"}
+}
+
+// SwarmJoin is not supported, podman has no swarm
+func (pc podmanClient) SwarmJoin(ctx context.Context, req swarm.JoinRequest) error {
+	return entity.NotSupportedError{Runtime: entity.PodmanRuntime, Operation: "swarm"}
+}
+
+// SwarmInspect is not supported, podman has no swarm
+func (pc podmanClient) SwarmInspect(ctx context.Context) (swarm.Swarm, error) {
+	return swarm.Swarm{}, entity.NotSupportedError{Runtime: entity.PodmanRuntime, Operation: "swarm"}
+}
+
+// SwarmLeave is not supported, podman has no swarm
+func (pc podmanClient) SwarmLeave(ctx context.Context, force bool) error {
+	return entity.NotSupportedError{Runtime: entity.PodmanRuntime, Operation: "swarm"}
+}
+
+// NodeInspectWithRaw is not supported, podman has no swarm
+func (pc podmanClient) NodeInspectWithRaw(ctx context.Context, nodeID string) (swarm.Node, []byte, error) {
+	return swarm.Node{}, nil, entity.NotSupportedError{Runtime: entity.PodmanRuntime, Operation: "swarm"}
+}
+
+// NodeUpdate is not supported, podman has no swarm
+func (pc podmanClient) NodeUpdate(ctx context.Context, nodeID string, version swarm.Version,
+	node swarm.NodeSpec) error {
+	return entity.NotSupportedError{Runtime: entity.PodmanRuntime, Operation: "swarm"}
+}
+
+// ServiceCreate is not supported, podman has no swarm
+func (pc podmanClient) ServiceCreate(ctx context.Context, service swarm.ServiceSpec,
+	options types.ServiceCreateOptions) (types.ServiceCreateResponse, error) {
+	return types.ServiceCreateResponse{}, entity.NotSupportedError{Runtime: entity.PodmanRuntime, Operation: "swarm"}
+}
+
+// ServiceInspectWithRaw is not supported, podman has no swarm
+func (pc podmanClient) ServiceInspectWithRaw(ctx context.Context, serviceID string,
+	options types.ServiceInspectOptions) (swarm.Service, []byte, error) {
+	return swarm.Service{}, nil, entity.NotSupportedError{Runtime: entity.PodmanRuntime, Operation: "swarm"}
+}
+
+// ServiceRemove is not supported, podman has no swarm
+func (pc podmanClient) ServiceRemove(ctx context.Context, serviceID string) error {
+	return entity.NotSupportedError{Runtime: entity.PodmanRuntime, Operation: "swarm"}
+}
+
+// ServiceUpdate is not supported, podman has no swarm
+func (pc podmanClient) ServiceUpdate(ctx context.Context, serviceID string, version swarm.Version,
+	service swarm.ServiceSpec, options types.ServiceUpdateOptions) (types.ServiceUpdateResponse, error) {
+	return types.ServiceUpdateResponse{}, entity.NotSupportedError{Runtime: entity.PodmanRuntime, Operation: "swarm"}
+}
+
+// TaskList is not supported, podman has no swarm
+func (pc podmanClient) TaskList(ctx context.Context, options types.TaskListOptions) ([]swarm.Task, error) {
+	return nil, entity.NotSupportedError{Runtime: entity.PodmanRuntime, Operation: "swarm"}
+}
+
+// podmanAPIVersion is the version of the libpod API which is asked for, podman serves the versions
+// before its own as well
+const podmanAPIVersion = "v4.0.0"
+
+// podmanEngineErrors are the messages podman gives back, for when the status does not tell
+var podmanEngineErrors = engineErrors{
+	{msg: "no such container", kind: entity.ErrNotFound},
+	{msg: "no container with name or id", kind: entity.ErrNotFound},
+	{msg: "no such network", kind: entity.ErrNotFound},
+	{msg: "network not found", kind: entity.ErrNotFound},
+	{msg: "no such volume", kind: entity.ErrNotFound},
+	{msg: "is not connected to network", kind: entity.ErrNotFound},
+	{msg: "is already in use", kind: entity.ErrAlreadyExists},
+	{msg: "already exists", kind: entity.ErrAlreadyExists},
+	{msg: "already connected to network", kind: entity.ErrAlreadyExists},
+}
+
+// podmanEngine is the adapter of the native libpod API of podman. Rootless podman cannot create
+// macvlan or ipvlan networks, or publish privileged ports.
+type podmanEngine struct {
+	client   *http.Client
+	base     string
+	rootless bool
+}
+
+// NewPodmanEngine creates the Engine of the podman which serves its API at host, as given to the
+// docker client, such as unix:///run/podman/podman.sock. It is reached with the given http client,
+// which is the one of the docker client for the same host.
+func NewPodmanEngine(httpClient *http.Client, host string, rootless bool) (entity.Engine, error) {
+	u, err := client.ParseHostURL(host)
+	if err != nil {
+		return nil, err
+	}
+	base := ""
+	switch u.Scheme {
+	case "unix", "npipe":
+		// the address is in the dialer of the client, any host will do
+		base = "http://d"
+	case "tcp":
+		base = "http://" + u.Host
+		if transport, ok := httpClient.Transport.(*http.Transport); ok && transport.TLSClientConfig != nil {
+			base = "https://" + u.Host
+		}
+	default:
+		base = u.Scheme + "://" + u.Host
+	}
+	return podmanEngine{client: httpClient, base: base + u.Path, rootless: rootless}, nil
+}
+
+// podmanAPIError is the body podman gives back with an error
+type podmanAPIError struct {
+	Cause   string `json:"cause"`
+	Message string `json:"message"`
+}
+
+// do calls the libpod API, with the body encoded as json if there is one
+func (pe podmanEngine) do(ctx context.Context, method string, path string,
+	query url.Values, body interface{}) error {
+
+	var rdr io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		rdr = bytes.NewReader(data)
+	}
+	target := pe.base + "/" + podmanAPIVersion + "/libpod" + path
+	if len(query) > 0 {
+		target += "?" + query.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, method, target, rdr)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := pe.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < http.StatusMultipleChoices || resp.StatusCode == http.StatusNotModified {
+		_, err = io.Copy(ioutil.Discard, resp.Body)
+		return err
+	}
+
+	data, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	apiErr := podmanAPIError{}
+	msg := strings.TrimSpace(string(data))
+	if json.Unmarshal(data, &apiErr) == nil && len(apiErr.Message) > 0 {
+		msg = apiErr.Message
+	}
+	err = fmt.Errorf("podman: %s %s: %s", method, path, msg)
+	switch resp.StatusCode {
+	case http.StatusNotFound:
+		return entity.EngineError{Kind: entity.ErrNotFound, Err: err}
+	case http.StatusConflict:
+		return entity.EngineError{Kind: entity.ErrAlreadyExists, Err: err}
+	}
+	return podmanEngineErrors.classify(err)
+}
+
+// podmanNamespace is a namespace of a container in the libpod API
+type podmanNamespace struct {
+	NSMode string `json:"nsmode"`
+	Value  string `json:"value,omitempty"`
+}
+
+// namespace gives the namespace of the mode, such as host or container:<name>
+func namespace(mode string) *podmanNamespace {
+	if len(mode) == 0 {
+		return nil
+	}
+	parts := strings.SplitN(mode, ":", 2)
+	if len(parts) == 2 {
+		return &podmanNamespace{NSMode: parts[0], Value: parts[1]}
+	}
+	return &podmanNamespace{NSMode: mode}
+}
+
+// podmanNetworkOptions are the settings of a container on a network in the libpod API
+type podmanNetworkOptions struct {
+	StaticIPs []string `json:"static_ips,omitempty"`
+	StaticMAC string   `json:"static_mac,omitempty"`
+	Aliases   []string `json:"aliases,omitempty"`
+}
+
+func networkOptionsOf(endpoint entity.EndpointSpec) podmanNetworkOptions {
+	out := podmanNetworkOptions{StaticMAC: endpoint.MacAddress, Aliases: endpoint.Aliases}
+	for _, ip := range []string{endpoint.IPv4, endpoint.IPv6} {
+		if len(ip) > 0 {
+			out.StaticIPs = append(out.StaticIPs, ip)
+		}
+	}
+	return out
+}
+
+type podmanPortMapping struct {
+	HostIP        string `json:"host_ip,omitempty"`
+	HostPort      int    `json:"host_port"`
+	ContainerPort int    `json:"container_port"`
+	Protocol      string `json:"protocol"`
+}
+
+type podmanMount struct {
+	Destination string   `json:"destination"`
+	Type        string   `json:"type"`
+	Source      string   `json:"source"`
+	Options     []string `json:"options,omitempty"`
+}
+
+type podmanNamedVolume struct {
+	Name    string
+	Dest    string
+	Options []string
+}
+
+type podmanCPU struct {
+	Quota  int64  `json:"quota"`
+	Period uint64 `json:"period"`
+}
+
+type podmanMemory struct {
+	Limit int64 `json:"limit"`
+}
+
+type podmanResources struct {
+	CPU    *podmanCPU    `json:"cpu,omitempty"`
+	Memory *podmanMemory `json:"memory,omitempty"`
+}
+
+type podmanLogConfig struct {
+	Driver string `json:"driver,omitempty"`
+}
+
+// podmanSpec is the spec generator which containers are created from in the libpod API
+type podmanSpec struct {
+	Name           string                          `json:"name"`
+	Hostname       string                          `json:"hostname,omitempty"`
+	Image          string                          `json:"image"`
+	Entrypoint     []string                        `json:"entrypoint,omitempty"`
+	Env            map[string]string               `json:"env,omitempty"`
+	Labels         map[string]string               `json:"labels,omitempty"`
+	Remove         bool                            `json:"remove,omitempty"`
+	Privileged     bool                            `json:"privileged,omitempty"`
+	CapAdd         []string                        `json:"cap_add,omitempty"`
+	LogConfig      *podmanLogConfig                `json:"log_configuration,omitempty"`
+	PortMappings   []podmanPortMapping             `json:"portmappings,omitempty"`
+	Mounts         []podmanMount                   `json:"mounts,omitempty"`
+	Volumes        []podmanNamedVolume             `json:"volumes,omitempty"`
+	VolumesFrom    []string                        `json:"volumes_from,omitempty"`
+	ResourceLimits *podmanResources                `json:"resource_limits,omitempty"`
+	NetNS          *podmanNamespace                `json:"netns,omitempty"`
+	PidNS          *podmanNamespace                `json:"pidns,omitempty"`
+	Networks       map[string]podmanNetworkOptions `json:"Networks,omitempty"`
+	DNSServers     []string                        `json:"dns_server,omitempty"`
+	DNSSearch      []string                        `json:"dns_search,omitempty"`
+	DNSOptions     []string                        `json:"dns_option,omitempty"`
+}
+
+// cpuPeriod is the period the cpu quota of a container is over, in microseconds
+const cpuPeriod = 100000
+
+// podmanSpecOf gives the spec in the form of the libpod API. The log options are dropped, as
+// podman only knows the options of its own log drivers, and rejects the rest.
+func (pe podmanEngine) podmanSpecOf(spec entity.ContainerSpec) (podmanSpec, error) {
+	out := podmanSpec{
+		Name:       spec.Name,
+		Hostname:   spec.Hostname,
+		Image:      qualifyImage(spec.Image),
+		Entrypoint: spec.Entrypoint,
+		Labels:     spec.Labels,
+		Remove:     spec.AutoRemove,
+		Privileged: spec.Privileged,
+		CapAdd:     spec.CapAdd,
+		NetNS:      namespace(spec.NetworkMode),
+		PidNS:      namespace(spec.PidMode),
+		DNSServers: spec.DNS,
+		DNSSearch:  spec.DNSSearch,
+		DNSOptions: spec.DNSOptions,
+	}
+	if len(spec.Env) > 0 {
+		out.Env = map[string]string{}
+		for _, env := range spec.Env {
+			parts := strings.SplitN(env, "=", 2)
+			if len(parts) == 2 {
+				out.Env[parts[0]] = parts[1]
+			} else {
+				out.Env[parts[0]] = ""
+			}
+		}
+	}
+	if len(spec.LogDriver) > 0 {
+		out.LogConfig = &podmanLogConfig{Driver: spec.LogDriver}
+	}
+	for _, port := range spec.Ports {
+		if pe.rootless && port.HostPort > 0 && port.HostPort < 1024 {
+			return podmanSpec{}, entity.NotSupportedError{
+				Runtime: entity.PodmanRuntime + " (rootless)",
+				Operation: fmt.Sprintf("publishing %d/%s on privileged port %d",
+					port.ContainerPort, port.Protocol, port.HostPort),
+			}
+		}
+		out.PortMappings = append(out.PortMappings, podmanPortMapping(port))
+	}
+	for _, mnt := range spec.Mounts {
+		options := []string{}
+		if mnt.ReadOnly {
+			options = append(options, "ro")
+		}
+		if mnt.Type == entity.VolumeMountType {
+			out.Volumes = append(out.Volumes, podmanNamedVolume{Name: mnt.Source, Dest: mnt.Target,
+				Options: options})
+			continue
+		}
+		out.Mounts = append(out.Mounts, podmanMount{Destination: mnt.Target, Type: mnt.Type,
+			Source: mnt.Source, Options: options})
+	}
+	if len(spec.VolumesFrom) > 0 {
+		out.VolumesFrom = []string{spec.VolumesFrom}
+	}
+	if spec.NanoCPUs > 0 || spec.Memory > 0 {
+		out.ResourceLimits = &podmanResources{}
+	}
+	if spec.NanoCPUs > 0 {
+		out.ResourceLimits.CPU = &podmanCPU{Quota: spec.NanoCPUs * cpuPeriod / 1e9, Period: cpuPeriod}
+	}
+	if spec.Memory > 0 {
+		out.ResourceLimits.Memory = &podmanMemory{Limit: spec.Memory}
+	}
+	if spec.Network != nil && out.NetNS == nil {
+		out.NetNS = &podmanNamespace{NSMode: "bridge"}
+		out.Networks = map[string]podmanNetworkOptions{spec.Network.Network: networkOptionsOf(*spec.Network)}
+	}
+	return out, nil
+}
+
+// CreateContainer creates the container, with the image fully qualified
+func (pe podmanEngine) CreateContainer(ctx context.Context, spec entity.ContainerSpec) error {
+	body, err := pe.podmanSpecOf(spec)
+	if err != nil {
+		return err
+	}
+	return pe.do(ctx, http.MethodPost, "/containers/create", nil, body)
+}
+
+// StartContainer starts the container
+func (pe podmanEngine) StartContainer(ctx context.Context, name string) error {
+	return pe.do(ctx, http.MethodPost, "/containers/"+url.PathEscape(name)+"/start", nil, nil)
+}
+
+// RemoveContainer removes the container, even if it is running
+func (pe podmanEngine) RemoveContainer(ctx context.Context, name string, volumes bool) error {
+	return pe.do(ctx, http.MethodDelete, "/containers/"+url.PathEscape(name), url.Values{
+		"force": {"true"},
+		"v":     {strconv.FormatBool(volumes)},
+	}, nil)
+}
+
+type podmanLeaseRange struct {
+	StartIP string `json:"start_ip"`
+	EndIP   string `json:"end_ip"`
+}
+
+type podmanSubnet struct {
+	Subnet     string            `json:"subnet"`
+	Gateway    string            `json:"gateway,omitempty"`
+	LeaseRange *podmanLeaseRange `json:"lease_range,omitempty"`
+}
+
+// podmanNetwork is what a network is created from in the libpod API
+type podmanNetwork struct {
+	Name             string            `json:"name"`
+	Driver           string            `json:"driver"`
+	NetworkInterface string            `json:"network_interface,omitempty"`
+	Subnets          []podmanSubnet    `json:"subnets,omitempty"`
+	IPv6Enabled      bool              `json:"ipv6_enabled"`
+	Internal         bool              `json:"internal"`
+	Labels           map[string]string `json:"labels,omitempty"`
+	Options          map[string]string `json:"options,omitempty"`
+	IPAMOptions      map[string]string `json:"ipam_options,omitempty"`
+}
+
+// leaseRange gives the first and the last address of the range, which podman takes instead of
+// the range itself
+func leaseRange(ipRange string) (*podmanLeaseRange, error) {
+	if len(ipRange) == 0 {
+		return nil, nil
+	}
+	ip, ipNet, err := net.ParseCIDR(ipRange)
+	if err != nil {
+		return nil, err
+	}
+	first := ip.Mask(ipNet.Mask)
+	last := make(net.IP, len(first))
+	for i := range first {
+		last[i] = first[i] | ^ipNet.Mask[i]
+	}
+	return &podmanLeaseRange{StartIP: first.String(), EndIP: last.String()}, nil
+}
+
+// CreateNetwork creates the network. Podman has no swarm, so global and overlay networks are
+// created as bridge networks on the host.
+func (pe podmanEngine) CreateNetwork(ctx context.Context, spec entity.NetworkSpec) error {
+	driver := spec.Driver
+	if pe.rootless && (driver == entity.MacvlanDriver || driver == entity.IpvlanDriver) {
+		return entity.NotSupportedError{
+			Runtime:   entity.PodmanRuntime + " (rootless)",
+			Operation: fmt.Sprintf("creating a %s network", driver),
+		}
+	}
+	if driver == entity.OverlayDriver {
+		driver = entity.BridgeDriver
+	}
+	body := podmanNetwork{
+		Name:        spec.Name,
+		Driver:      driver,
+		IPv6Enabled: spec.EnableIPv6,
+		Internal:    spec.Internal,
+		Labels:      spec.Labels,
+		Options:     map[string]string{},
+		IPAMOptions: map[string]string{},
+	}
+	switch driver {
+	case entity.BridgeDriver:
+		body.NetworkInterface = spec.BridgeName
+	case entity.MacvlanDriver, entity.IpvlanDriver:
+		body.NetworkInterface = spec.Parent
+		if len(spec.Mode) > 0 {
+			body.Options["mode"] = spec.Mode
+		}
+	}
+	for key, val := range spec.Options {
+		body.Options[key] = val
+	}
+	if spec.MTU > 0 {
+		body.Options["mtu"] = strconv.Itoa(spec.MTU)
+	}
+	for key, val := range spec.IPAMOptions {
+		body.IPAMOptions[key] = val
+	}
+	if len(spec.IPAMDriver) > 0 {
+		body.IPAMOptions["driver"] = spec.IPAMDriver
+	}
+	for _, subnet := range spec.Subnets {
+		if len(subnet.Subnet) == 0 {
+			continue // podman picks a free one
+		}
+		lease, err := leaseRange(subnet.IPRange)
+		if err != nil {
+			return err
+		}
+		body.Subnets = append(body.Subnets, podmanSubnet{Subnet: subnet.Subnet,
+			Gateway: subnet.Gateway, LeaseRange: lease})
+	}
+	return pe.do(ctx, http.MethodPost, "/networks/create", nil, body)
+}
+
+// RemoveNetwork removes the network
+func (pe podmanEngine) RemoveNetwork(ctx context.Context, name string) error {
+	return pe.do(ctx, http.MethodDelete, "/networks/"+url.PathEscape(name), nil, nil)
+}
+
+// ConnectNetwork attaches the container to the network
+func (pe podmanEngine) ConnectNetwork(ctx context.Context, container string, endpoint entity.EndpointSpec) error {
+	body := struct {
+		Container string `json:"container"`
+		podmanNetworkOptions
+	}{Container: container, podmanNetworkOptions: networkOptionsOf(endpoint)}
+	return pe.do(ctx, http.MethodPost, "/networks/"+url.PathEscape(endpoint.Network)+"/connect", nil, body)
+}
+
+// DisconnectNetwork detaches the container from the network, even if it is running
+func (pe podmanEngine) DisconnectNetwork(ctx context.Context, container string, network string) error {
+	body := struct {
+		Container string
+		Force     bool
+	}{Container: container, Force: true}
+	return pe.do(ctx, http.MethodPost, "/networks/"+url.PathEscape(network)+"/disconnect", nil, body)
+}
+
+// CreateVolume creates the volume
+func (pe podmanEngine) CreateVolume(ctx context.Context, spec entity.VolumeSpec) error {
+	body := struct {
+		Name    string
+		Driver  string            `json:",omitempty"`
+		Label   map[string]string `json:",omitempty"`
+		Options map[string]string `json:",omitempty"`
+	}{Name: spec.Name, Driver: spec.Driver, Label: spec.Labels, Options: spec.Options}
+	return pe.do(ctx, http.MethodPost, "/volumes/create", nil, body)
+}
+
+// RemoveVolume removes the volume, even if it is in use
+func (pe podmanEngine) RemoveVolume(ctx context.Context, name string) error {
+	return pe.do(ctx, http.MethodDelete, "/volumes/"+url.PathEscape(name), url.Values{"force": {"true"}}, nil)
+}
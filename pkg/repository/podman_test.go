@@ -0,0 +1,182 @@
+/**
+ * Copyright 2019 Whiteblock Inc. All rights reserved.
+ * Use of this source code is governed by a BSD-style
+ * license that can be found in the LICENSE file.
+ */
+
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	entityMock "github.com/whiteblock/genesis/mocks/pkg/entity"
+	"github.com/whiteblock/genesis/pkg/entity"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/swarm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// podmanServer serves the libpod API, giving back the status for each path, and keeping the
+// bodies of the requests by their path
+func podmanServer(t *testing.T, statuses map[string]int) (*httptest.Server, map[string]map[string]interface{}) {
+	bodies := map[string]map[string]interface{}{}
+	mux := &sync.Mutex{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body := map[string]interface{}{}
+		json.NewDecoder(r.Body).Decode(&body)
+		mux.Lock()
+		bodies[r.Method+" "+r.URL.Path] = body
+		mux.Unlock()
+		status, ok := statuses[r.Method+" "+r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(podmanAPIError{Message: http.StatusText(status)})
+	}))
+	t.Cleanup(srv.Close)
+	return srv, bodies
+}
+
+func TestPodmanEngine_CreateContainer(t *testing.T) {
+	srv, bodies := podmanServer(t, nil)
+	engine, err := NewPodmanEngine(srv.Client(), "tcp://"+srv.Listener.Addr().String(), false)
+	require.NoError(t, err)
+
+	err = engine.CreateContainer(context.Background(), entity.ContainerSpec{
+		Name:       "test",
+		Image:      "alpine",
+		Env:        []string{"A=1"},
+		NanoCPUs:   1500000000,
+		Memory:     1024,
+		LogDriver:  "journald",
+		LogOptions: map[string]string{"labels": "org"},
+		Ports:      []entity.PortSpec{{HostIP: "0.0.0.0", HostPort: 8080, ContainerPort: 80, Protocol: "tcp"}},
+		Mounts: []entity.MountSpec{
+			{Type: entity.VolumeMountType, Source: "data", Target: "/data", ReadOnly: true},
+			{Type: entity.BindMountType, Source: "/srv", Target: "/srv"},
+		},
+		Network: &entity.EndpointSpec{Network: "net1", IPv4: "10.0.0.2", MacAddress: "02:42:0a:00:00:02"},
+	})
+	require.NoError(t, err)
+
+	body := bodies["POST /v4.0.0/libpod/containers/create"]
+	require.NotNil(t, body)
+	assert.Equal(t, "docker.io/library/alpine:latest", body["image"])
+	assert.Equal(t, map[string]interface{}{"A": "1"}, body["env"])
+	assert.Equal(t, map[string]interface{}{"driver": "journald"}, body["log_configuration"])
+	assert.Equal(t, map[string]interface{}{
+		"cpu":    map[string]interface{}{"quota": 150000.0, "period": 100000.0},
+		"memory": map[string]interface{}{"limit": 1024.0},
+	}, body["resource_limits"])
+	assert.Equal(t, map[string]interface{}{"nsmode": "bridge"}, body["netns"])
+	assert.Equal(t, map[string]interface{}{"net1": map[string]interface{}{
+		"static_ips": []interface{}{"10.0.0.2"},
+		"static_mac": "02:42:0a:00:00:02",
+	}}, body["Networks"])
+	assert.Equal(t, []interface{}{map[string]interface{}{
+		"Name": "data", "Dest": "/data", "Options": []interface{}{"ro"}}}, body["volumes"])
+	assert.Equal(t, []interface{}{map[string]interface{}{
+		"destination": "/srv", "type": "bind", "source": "/srv"}}, body["mounts"])
+}
+
+func TestPodmanEngine_Networks(t *testing.T) {
+	srv, bodies := podmanServer(t, nil)
+	engine, err := NewPodmanEngine(srv.Client(), "tcp://"+srv.Listener.Addr().String(), false)
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	require.NoError(t, engine.CreateNetwork(ctx, entity.NetworkSpec{
+		Name:       "net1",
+		Driver:     entity.OverlayDriver,
+		Global:     true,
+		BridgeName: "net1",
+		MTU:        1400,
+		Subnets:    []entity.Subnet{{Subnet: "10.0.0.0/16", Gateway: "10.0.0.1", IPRange: "10.0.1.0/24"}},
+	}))
+	body := bodies["POST /v4.0.0/libpod/networks/create"]
+	assert.Equal(t, entity.BridgeDriver, body["driver"])
+	assert.Equal(t, "net1", body["network_interface"])
+	assert.Equal(t, map[string]interface{}{"mtu": "1400"}, body["options"])
+	assert.Equal(t, []interface{}{map[string]interface{}{
+		"subnet":      "10.0.0.0/16",
+		"gateway":     "10.0.0.1",
+		"lease_range": map[string]interface{}{"start_ip": "10.0.1.0", "end_ip": "10.0.1.255"},
+	}}, body["subnets"])
+
+	require.NoError(t, engine.ConnectNetwork(ctx, "node", entity.EndpointSpec{Network: "net1",
+		IPv4: "10.0.0.3", Aliases: []string{"peer"}}))
+	assert.Equal(t, map[string]interface{}{
+		"container":  "node",
+		"static_ips": []interface{}{"10.0.0.3"},
+		"aliases":    []interface{}{"peer"},
+	}, bodies["POST /v4.0.0/libpod/networks/net1/connect"])
+
+	require.NoError(t, engine.DisconnectNetwork(ctx, "node", "net1"))
+	assert.Equal(t, map[string]interface{}{"Container": "node", "Force": true},
+		bodies["POST /v4.0.0/libpod/networks/net1/disconnect"])
+}
+
+func TestPodmanEngine_Rootless(t *testing.T) {
+	srv, bodies := podmanServer(t, nil)
+	engine, err := NewPodmanEngine(srv.Client(), "tcp://"+srv.Listener.Addr().String(), true)
+	require.NoError(t, err)
+
+	err = engine.CreateContainer(context.Background(), entity.ContainerSpec{Name: "test", Image: "alpine",
+		Ports: []entity.PortSpec{{HostPort: 80, ContainerPort: 80, Protocol: "tcp"}}})
+	assert.True(t, entity.IsNotSupported(err))
+
+	err = engine.CreateNetwork(context.Background(), entity.NetworkSpec{Name: "net", Driver: entity.MacvlanDriver})
+	assert.True(t, entity.IsNotSupported(err))
+	assert.Len(t, bodies, 0)
+}
+
+func TestPodmanEngine_Errors(t *testing.T) {
+	srv, _ := podmanServer(t, map[string]int{
+		"DELETE /v4.0.0/libpod/containers/gone": http.StatusNotFound,
+		"POST /v4.0.0/libpod/volumes/create":    http.StatusConflict,
+		"DELETE /v4.0.0/libpod/networks/net":    http.StatusInternalServerError,
+	})
+	engine, err := NewPodmanEngine(srv.Client(), "tcp://"+srv.Listener.Addr().String(), false)
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	err = engine.RemoveContainer(ctx, "gone", false)
+	assert.True(t, errors.Is(err, entity.ErrNotFound))
+	err = engine.CreateVolume(ctx, entity.VolumeSpec{Name: "data"})
+	assert.True(t, errors.Is(err, entity.ErrAlreadyExists))
+	err = engine.RemoveNetwork(ctx, "net")
+	require.Error(t, err)
+	assert.False(t, errors.Is(err, entity.ErrNotFound))
+	assert.Contains(t, err.Error(), http.StatusText(http.StatusInternalServerError))
+	assert.NoError(t, engine.StartContainer(ctx, "node"))
+}
+
+func TestPodmanClient_Errors(t *testing.T) {
+	cli := new(entityMock.Client)
+	cli.On("ContainerRemove", mock.Anything, "gone", mock.Anything).Return(
+		fmt.Errorf("no container with name or id \"gone\" found: no such container")).Once()
+	cli.On("ContainerInspect", mock.Anything, "other").Return(types.ContainerJSON{},
+		fmt.Errorf("something else")).Once()
+
+	pc := NewPodmanClient(cli, nil)
+	err := pc.ContainerRemove(context.Background(), "gone", types.ContainerRemoveOptions{})
+	assert.Contains(t, err.Error(), "No such container")
+	_, err = pc.ContainerInspect(context.Background(), "other")
+	assert.Equal(t, "something else", err.Error())
+
+	_, err = pc.SwarmInit(context.Background(), swarm.InitRequest{})
+	assert.True(t, entity.IsNotSupported(err))
+	cli.AssertExpectations(t)
+}
@@ -0,0 +1,92 @@
+/**
+ * Copyright 2019 Whiteblock Inc. All rights reserved.
+ * Use of this source code is governed by a BSD-style
+ * license that can be found in the LICENSE file.
+ */
+
+package service
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"path/filepath"
+
+	"github.com/whiteblock/genesis/pkg/entity"
+)
+
+const (
+	// faketimeControlFile is where libfaketime reads the clock skew from in the container
+	faketimeControlFile = "/etc/genesis-faketime"
+
+	// faketimeCacheDuration is how many seconds libfaketime waits before reading the control file again
+	faketimeCacheDuration = "1"
+)
+
+// clockEnv gives the environment which preloads libfaketime, and points it at the control file
+func (ds dockerService) clockEnv(env map[string]string) map[string]string {
+	out := map[string]string{}
+	for key, val := range env {
+		out[key] = val
+	}
+	out["LD_PRELOAD"] = ds.conf.FaketimeLib
+	if preload, ok := env["LD_PRELOAD"]; ok && len(preload) > 0 {
+		out["LD_PRELOAD"] += ":" + preload
+	}
+	out["FAKETIME_TIMESTAMP_FILE"] = faketimeControlFile
+	out["FAKETIME_CACHE_DURATION"] = faketimeCacheDuration
+	// timers and sleeps use the monotonic clock, they should keep working as usual
+	out["DONT_FAKE_MONOTONIC"] = "1"
+	return out
+}
+
+// clockArchive gives a tar archive holding the control file for the clock skew
+func clockArchive(cs entity.ClockSkew) (*bytes.Buffer, error) {
+	spec := []byte(cs.Spec() + "\n")
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	err := tw.WriteHeader(&tar.Header{
+		Name: filepath.Base(faketimeControlFile),
+		Mode: 0644,
+		Size: int64(len(spec)),
+	})
+	if err != nil {
+		return nil, err
+	}
+	_, err = tw.Write(spec)
+	if err != nil {
+		return nil, err
+	}
+	return &buf, tw.Close()
+}
+
+// CreateSkewedContainer creates the container with libfaketime preloaded, and places the control
+// file which holds the initial clock skew
+func (ds dockerService) CreateSkewedContainer(ctx context.Context, cli entity.DockerCli,
+	sc entity.SkewedContainer) entity.Result {
+
+	cntr := sc.Container
+	cntr.Environment = ds.clockEnv(cntr.Environment)
+	res := ds.CreateContainer(ctx, cli, cntr)
+	if !res.IsSuccess() {
+		return res
+	}
+	return ds.ChangeClock(ctx, cli, entity.ContainerClock{Container: cntr.Name, Clock: sc.Clock})
+}
+
+// ChangeClock replaces the control file of the container, libfaketime picks up the new
+// clock skew within a second
+func (ds dockerService) ChangeClock(ctx context.Context, cli entity.DockerCli,
+	cc entity.ContainerClock) entity.Result {
+
+	rdr, err := clockArchive(cc.Clock)
+	if err != nil {
+		return entity.NewErrorResult(err)
+	}
+	err = ds.copyToContainer(ctx, cli, cc.Container, rdr,
+		filepath.Base(faketimeControlFile), faketimeControlFile)
+	return entity.NewResult(err).InjectMeta(map[string]interface{}{
+		"container": cc.Container,
+		"clock":     cc.Clock.Spec(),
+	})
+}
@@ -9,7 +9,9 @@ package service
 import (
 	"context"
 	"crypto/rand"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"strconv"
@@ -23,10 +25,6 @@ import (
 
 	"github.com/docker/docker/api/types"
 	"github.com/docker/docker/api/types/container"
-	"github.com/docker/docker/api/types/network"
-	"github.com/docker/docker/api/types/strslice"
-	"github.com/docker/docker/api/types/swarm"
-	"github.com/docker/docker/api/types/volume"
 	"github.com/docker/docker/client"
 	"github.com/docker/docker/pkg/archive"
 	"github.com/docker/docker/pkg/system"
@@ -39,7 +37,7 @@ type DockerService interface {
 
 	// CreateContainer attempts to create a docker container
 	CreateContainer(ctx context.Context, cli entity.DockerCli,
-		container command.Container) entity.Result
+		container entity.Container) entity.Result
 
 	// StartContainer attempts to start an already created docker container
 	StartContainer(ctx context.Context, cli entity.DockerCli, sc command.StartContainer) entity.Result
@@ -48,21 +46,108 @@ type DockerService interface {
 	RemoveContainer(ctx context.Context, cli entity.DockerCli, names ...string) entity.Result
 
 	// CreateNetwork attempts to create a network
-	CreateNetwork(ctx context.Context, cli entity.DockerCli, net command.Network) entity.Result
+	CreateNetwork(ctx context.Context, cli entity.DockerCli, net entity.Network) entity.Result
 
 	// RemoveNetwork attempts to remove a network
 	RemoveNetwork(ctx context.Context, cli entity.DockerCli, name string) entity.Result
-	AttachNetwork(ctx context.Context, cli entity.DockerCli, cmd command.ContainerNetwork) entity.Result
+	AttachNetwork(ctx context.Context, cli entity.DockerCli, cmd entity.ContainerNetwork) entity.Result
 	DetachNetwork(ctx context.Context, cli entity.DockerCli, network string,
 		container string) entity.Result
-	CreateVolume(ctx context.Context, cli entity.DockerCli, volume command.Volume) entity.Result
+	CreateVolume(ctx context.Context, cli entity.DockerCli, volume entity.Volume) entity.Result
 	RemoveVolume(ctx context.Context, cli entity.DockerCli, name string) entity.Result
 	PlaceFileInContainer(ctx context.Context, cli entity.DockerCli,
 		containerName string, file command.File) entity.Result
 	Emulation(ctx context.Context, cli entity.DockerCli, netem command.Netconf) entity.Result
-	SwarmCluster(ctx context.Context, cli entity.DockerCli, swarm command.SetupSwarm) entity.Result
+
+	// ChangeEmulation changes the parameters of the network emulation which is already applied
+	ChangeEmulation(ctx context.Context, cli entity.DockerCli, netem command.Netconf) entity.Result
+
+	// RemoveEmulation removes any network emulation from the container on the network
+	RemoveEmulation(ctx context.Context, cli entity.DockerCli, netem command.Netconf) entity.Result
+
+	// ShowEmulation lists the qdiscs currently applied to a container
+	ShowEmulation(ctx context.Context, cli entity.DockerCli, cn command.ContainerNetwork) entity.Result
+
+	// LinkEmulation applies network emulation per destination container
+	LinkEmulation(ctx context.Context, cli entity.DockerCli, le entity.LinkEmulation) entity.Result
+
+	// ScheduleEmulation applies network emulation which changes over time, in the background
+	ScheduleEmulation(ctx context.Context, cli entity.DockerCli, sched entity.EmulationSchedule) entity.Result
+
+	// CancelEmulationSchedule stops a running schedule and removes its emulation
+	CancelEmulationSchedule(ctx context.Context, cli entity.DockerCli, name string) entity.Result
+
+	// Partition splits containers into groups which cannot reach each other
+	Partition(ctx context.Context, cli entity.DockerCli, part entity.Partition) entity.Result
+
+	// HealPartition lets partitioned containers reach each other again
+	HealPartition(ctx context.Context, cli entity.DockerCli, part entity.Partition) entity.Result
+
+	// PauseContainer freezes all of the processes of a container
+	PauseContainer(ctx context.Context, cli entity.DockerCli, name string) entity.Result
+
+	// UnpauseContainer resumes the processes of a paused container
+	UnpauseContainer(ctx context.Context, cli entity.DockerCli, name string) entity.Result
+
+	// KillContainer sends a signal to the main process of a container
+	KillContainer(ctx context.Context, cli entity.DockerCli, kc entity.KillContainer) entity.Result
+
+	// RestartContainer stops a container and starts it again after a delay
+	RestartContainer(ctx context.Context, cli entity.DockerCli, rc entity.RestartContainer) entity.Result
+
+	// UpdateContainer changes the resource limits of a running container
+	UpdateContainer(ctx context.Context, cli entity.DockerCli, ur entity.UpdateResources) entity.Result
+
+	// StressContainer puts cpu and io load on a container
+	StressContainer(ctx context.Context, cli entity.DockerCli, st entity.Stress) entity.Result
+
+	// CreateSkewedContainer creates a container whose clock is shifted by libfaketime
+	CreateSkewedContainer(ctx context.Context, cli entity.DockerCli, sc entity.SkewedContainer) entity.Result
+
+	// ChangeClock changes the clock skew of a container created by CreateSkewedContainer
+	ChangeClock(ctx context.Context, cli entity.DockerCli, cc entity.ContainerClock) entity.Result
+
+	// SwarmCluster sets up a docker swarm on the hosts, or brings the swarm they are already in up to date
+	SwarmCluster(ctx context.Context, cli entity.DockerCli, swarm entity.Swarm) entity.Result
+
+	// LeaveSwarm makes the hosts leave the swarm they are in
+	LeaveSwarm(ctx context.Context, cli entity.DockerCli, swarm command.SetupSwarm) entity.Result
+
+	// CreateService creates a swarm service, which runs replicas of a container across the swarm
+	CreateService(ctx context.Context, cli entity.DockerCli, svc entity.SwarmService) entity.Result
+
+	// UpdateService replaces the container of a swarm service, along with how it is run
+	UpdateService(ctx context.Context, cli entity.DockerCli, svc entity.SwarmService) entity.Result
+
+	// ScaleService changes how many tasks a swarm service runs
+	ScaleService(ctx context.Context, cli entity.DockerCli, scale entity.ScaleService) entity.Result
+
+	// RemoveService removes a swarm service along with its tasks
+	RemoveService(ctx context.Context, cli entity.DockerCli, name string) entity.Result
+
 	PullImage(ctx context.Context, cli entity.DockerCli, imagePull command.PullImage) entity.Result
-	VolumeShare(ctx context.Context, cli entity.DockerCli, vs command.VolumeShare) entity.Result
+
+	// VolumeShare prepares the hosts to hold the global volumes of a backend
+	VolumeShare(ctx context.Context, cli entity.DockerCli, vs entity.VolumeShare) entity.Result
+
+	// RemoveGlobalVolume removes a global volume from each of its hosts, along with its data
+	RemoveGlobalVolume(ctx context.Context, cli entity.DockerCli, volume entity.Volume) entity.Result
+
+	// RemoveVolumeShare removes what the volume share of a backend put on the hosts
+	RemoveVolumeShare(ctx context.Context, cli entity.DockerCli, vs entity.VolumeShare) entity.Result
+
+	// ResizeVolume adds a global volume to more hosts, or removes it from some of them
+	ResizeVolume(ctx context.Context, cli entity.DockerCli, resize entity.VolumeResize) entity.Result
+
+	// VolumeStatus gives the state of a global volume
+	VolumeStatus(ctx context.Context, cli entity.DockerCli, volume entity.Volume) entity.Result
+
+	// ExportVolume archives the contents of a volume to the file source
+	ExportVolume(ctx context.Context, cli entity.DockerCli, export entity.VolumeExport) entity.Result
+
+	// ReleaseTest stops the emulation schedules of the test, closes the clients kept for it and
+	// releases the subnets and addresses given to its networks and containers, once it is over
+	ReleaseTest(testID string)
 
 	//CreateClient creates a new client for connecting to the docker daemon
 	CreateClient(cmd command.Command) (entity.Client, error)
@@ -77,13 +162,23 @@ var (
 const (
 	//GlusterContainerName is the name of the gluster container
 	GlusterContainerName = "gluster-container"
+
+	//NFSContainerName is the name of the nfs server container of the nfs volume backend
+	NFSContainerName = "nfs-container"
+
+	//RsyncContainerName is the name of the rsync daemon container of the rsync volume backend
+	RsyncContainerName = "rsync-container"
 )
 
 type dockerService struct {
-	repo   repository.DockerRepository
-	conf   config.Docker
-	log    logrus.Ext1FieldLogger
-	remote file.RemoteSources
+	repo      repository.DockerRepository
+	conf      config.Docker
+	log       logrus.Ext1FieldLogger
+	remote    file.RemoteSources
+	schedules *emulationSchedules
+	ipam      *addressManager
+	pool      *clientPool
+	creds     repository.CredentialStore
 }
 
 //NewDockerService creates a new DockerService
@@ -91,19 +186,28 @@ func NewDockerService(
 	repo repository.DockerRepository,
 	conf config.Docker,
 	remote file.RemoteSources,
+	creds repository.CredentialStore,
 	log logrus.Ext1FieldLogger) DockerService {
 
 	return dockerService{
-		conf:   conf,
-		repo:   repo,
-		remote: remote,
-		log:    log}
+		conf:      conf,
+		repo:      repo,
+		remote:    remote,
+		schedules: newEmulationSchedules(),
+		ipam:      newAddressManager(repository.NewIPAMRepository(conf.IPAMStateFile)),
+		pool:      newClientPool(conf.ClientIdleTimeout, conf.ClientHealthInterval, log),
+		creds:     creds,
+		log:       log}
 }
 
 func (ds dockerService) errorWhitelistHandler(err error, whitelist ...string) entity.Result {
 	if err == nil {
 		return entity.NewResult(nil, 1)
 	}
+	if entity.IsNotSupported(err) {
+		// the runtime of the host will never be able to do it
+		return entity.NewResult(err, 1).Fatal()
+	}
 	for _, entry := range whitelist {
 		if strings.Contains(err.Error(), entry) {
 			ds.log.WithField("error", err).Info("ignoring whitelisted error")
@@ -115,41 +219,65 @@ func (ds dockerService) errorWhitelistHandler(err error, whitelist ...string) en
 	return entity.NewResult(err, 1)
 }
 
-// CreateClient creates a new client for connecting to the docker daemon
+// engineResult gives the result of a call to the engine, the errors of the kinds given are
+// ignored, as what was asked for is already the case
+func (ds dockerService) engineResult(err error, ignored ...error) entity.Result {
+	for _, kind := range ignored {
+		if errors.Is(err, kind) {
+			ds.log.WithField("error", err).Info("ignoring whitelisted error")
+			return entity.NewResult(nil, 1).InjectMeta(map[string]interface{}{
+				"error": err,
+			})
+		}
+	}
+	return ds.errorWhitelistHandler(err)
+}
+
+// CreateClient creates a new client for connecting to the docker daemon, or the runtime
+// the command asks for
 func (ds dockerService) CreateClient(cmd command.Command) (entity.Client, error) {
-	return ds.CreateClient2(cmd.Target.IP, cmd.TestID())
+	runtime := ds.conf.Runtime
+	if chosen, ok := cmd.Meta[entity.RuntimeKey]; ok && len(chosen) > 0 {
+		runtime = chosen
+	}
+	return ds.clientFor(cmd.Target.IP, cmd.TestID(), runtime)
 }
 
 // CreateClient creates a new client for connecting to the docker daemon
 func (ds dockerService) CreateClient2(ip, testID string) (entity.Client, error) {
+	return ds.clientFor(ip, testID, ds.conf.Runtime)
+}
+
+// engineClient creates a new client for the docker engine API of the host. Targets with their own
+// transport are reached over it. Otherwise in local mode, it connects to the given socket, or to
+// the one of docker if none is given.
+func (ds dockerService) engineClient(ip, testID, localSocket string) (*client.Client, error) {
+	if _, ok := ds.transportHost(ip); ok {
+		return ds.transportClient(ip)
+	}
+	if hasTransportScheme(ip) {
+		return nil, fmt.Errorf("%w: %s", ErrTransportNotAllowed, ip)
+	}
 	if ds.conf.LocalMode {
+		if len(localSocket) > 0 {
+			return client.NewClientWithOpts(
+				client.WithAPIVersionNegotiation(),
+				client.WithHost("unix://"+localSocket),
+			)
+		}
 		return client.NewClientWithOpts(
 			client.WithAPIVersionNegotiation(),
 		)
 	}
-	dir := filepath.Join("/tmp", testID)
-	caCertFile := filepath.Join(dir, "ca.cert")
-	clientCertFile := filepath.Join(dir, "client.cert")
-	clientKeyFile := filepath.Join(dir, "client.key")
-
-	stat, err := os.Lstat(caCertFile)
-	if err != nil || stat.Size() == 0 {
-		return nil, fmt.Errorf("missing ca cert file")
-	}
-
-	stat, err = os.Lstat(clientCertFile)
-	if err != nil || stat.Size() == 0 {
-		return nil, fmt.Errorf("missing client key file")
-	}
-
-	stat, err = os.Lstat(clientKeyFile)
-	if err != nil || stat.Size() == 0 {
-		return nil, fmt.Errorf("missing client key file")
+	creds, err := ds.creds.Get(testID)
+	if err != nil {
+		return nil, err
 	}
+	defer creds.Wipe()
 	return client.NewClientWithOpts(
 		client.WithAPIVersionNegotiation(),
 		client.WithHost("tcp://"+ip+":"+ds.conf.DaemonPort),
-		ds.repo.WithTLSClientConfig(caCertFile, clientCertFile, clientKeyFile),
+		ds.repo.WithTLSClientConfig(creds, ip, ds.conf.TLSServerName),
 	)
 }
 
@@ -166,30 +294,16 @@ func (ds dockerService) withField(cli entity.DockerCli, key string, value interf
 
 //CreateContainer attempts to create a docker container
 func (ds dockerService) CreateContainer(ctx context.Context, cli entity.DockerCli,
-	dContainer command.Container) entity.Result {
+	dContainer entity.Container) entity.Result {
 
+	dContainer = dContainer.WithPrimaryNetwork()
 	ds.withFields(cli, logrus.Fields{"container": dContainer}).Trace("create container")
-	errChan := make(chan error)
+	errChan := make(chan error, 1) // buffered so that the pull never blocks after an early return
 
 	go func(image string) {
 		errChan <- ds.repo.EnsureImagePulled(ctx, cli, image, dContainer.Credentials)
 	}(dContainer.Image)
 
-	portSet, portMap, err := dContainer.GetPortBindings()
-	if err != nil {
-		return entity.NewFatalResult(err)
-	}
-
-	config := &container.Config{
-		Hostname:     dContainer.Name,
-		Domainname:   dContainer.Name,
-		ExposedPorts: portSet,
-		Env:          dContainer.GetEnv(),
-		Image:        dContainer.Image,
-		Entrypoint:   dContainer.GetEntryPoint(),
-		Labels:       cli.Labels,
-	}
-
 	mem, err := dContainer.GetMemory()
 	if err != nil {
 		return entity.NewFatalResult(err)
@@ -202,57 +316,102 @@ func (ds dockerService) CreateContainer(ctx context.Context, cli entity.DockerCl
 		})
 	}
 
-	hostConfig := &container.HostConfig{
-		PortBindings: portMap,
-		AutoRemove:   dContainer.AutoRemove,
-		LogConfig: container.LogConfig{
-			Type: ds.conf.LogDriver,
-			Config: map[string]string{
-				"labels": ds.conf.LogLabels,
-			},
+	spec := entity.ContainerSpec{
+		Name:       dContainer.Name,
+		Hostname:   dContainer.Name,
+		Domainname: dContainer.Name,
+		Image:      dContainer.Image,
+		Entrypoint: dContainer.GetEntryPoint(),
+		Env:        dContainer.GetEnv(),
+		Labels:     cli.Labels,
+		NanoCPUs:   int64(1000000000 * cpus),
+		Memory:     mem,
+		AutoRemove: dContainer.AutoRemove,
+		Ports:      dContainer.PortSpecs(),
+		Mounts:     dContainer.MountSpecs(),
+		DNS:        dContainer.DNS,
+		DNSSearch:  dContainer.DNSSearch,
+		DNSOptions: dContainer.DNSOptions,
+		LogDriver:  ds.conf.LogDriver,
+		LogOptions: map[string]string{
+			"labels": ds.conf.LogLabels,
 		},
-		Mounts: dContainer.GetMounts(),
 	}
-	hostConfig.NanoCPUs = int64(1000000000 * cpus)
-	hostConfig.Memory = mem
 
-	networkConfig := &network.NetworkingConfig{EndpointsConfig: map[string]*network.EndpointSettings{}}
+	addresses, err := ds.assignAddresses(cli, &dContainer)
+	if err != nil {
+		return entity.NewFatalResult(err).InjectMeta(map[string]interface{}{
+			"name": dContainer.Name,
+		})
+	}
+
 	if len(dContainer.Network) > 0 {
-		networkConfig.EndpointsConfig[dContainer.Network] = &network.EndpointSettings{
-			NetworkID: dContainer.Network,
-			IPAddress: dContainer.IP,
-			IPAMConfig: &network.EndpointIPAMConfig{
-				IPv4Address: dContainer.IP,
-			},
-		}
+		endpoint := endpointSpec(dContainer.Network, dContainer.IP, dContainer.IPv6, dContainer.Endpoint)
+		spec.Network = &endpoint
 	}
 
 	err = <-errChan
 	if err != nil {
+		ds.releaseContainer(cli, dContainer.Name)
 		return entity.NewErrorResult(err)
 	}
 
-	_, err = cli.ContainerCreate(ctx, config, hostConfig, networkConfig, dContainer.Name)
-	res := ds.errorWhitelistHandler(err, "already in use by container")
+	err = engineOf(cli.Client).CreateContainer(ctx, spec)
+	created := err == nil
+	res := ds.engineResult(err, entity.ErrAlreadyExists)
+	if res.IsSuccess() {
+		res = ds.attachNetworks(ctx, cli, dContainer, created)
+	} else {
+		ds.releaseContainer(cli, dContainer.Name)
+	}
 	if !res.IsSuccess() {
 		res = res.Fatal()
 	}
 	return res.InjectMeta(map[string]interface{}{
-		"image":   dContainer.Image,
-		"name":    dContainer.Name,
-		"network": dContainer.Network,
-		"type":    "CreateContainer",
+		"image":     dContainer.Image,
+		"name":      dContainer.Name,
+		"network":   dContainer.Network,
+		"networks":  dContainer.GetNetworks(),
+		"addresses": addresses,
+		"type":      "CreateContainer",
 	})
 }
 
+// attachNetworks connects the container to each of its additional networks. If any of them
+// fails, the container is removed again, if it was created by this command, so that it never
+// starts with only some of its networks.
+func (ds dockerService) attachNetworks(ctx context.Context, cli entity.DockerCli,
+	dContainer entity.Container, created bool) entity.Result {
+
+	for _, attachment := range dContainer.Networks {
+		res := ds.AttachNetwork(ctx, cli, attachment.ContainerNetwork(dContainer.Name))
+		if res.IsSuccess() {
+			continue
+		}
+		if created {
+			// removing the container releases its addresses as well
+			rmRes := ds.RemoveContainer(ctx, cli, dContainer.Name)
+			if !rmRes.IsSuccess() {
+				ds.withFields(cli, logrus.Fields{
+					"name":  dContainer.Name,
+					"error": rmRes.Error,
+				}).Error("failed to roll back the container")
+			}
+		}
+		return res.InjectMeta(map[string]interface{}{
+			"failedNetwork": attachment.Network,
+			"rolledBack":    created,
+		})
+	}
+	return entity.NewSuccessResult()
+}
+
 //StartContainer attempts to start an already created docker container
 func (ds dockerService) StartContainer(ctx context.Context, cli entity.DockerCli,
 	sc command.StartContainer) entity.Result {
 
 	ds.withFields(cli, logrus.Fields{"name": sc.Name}).Trace("starting container")
-	opts := types.ContainerStartOptions{}
-
-	err := cli.ContainerStart(ctx, sc.Name, opts)
+	err := engineOf(cli.Client).StartContainer(ctx, sc.Name)
 	if err != nil {
 		return entity.NewErrorResult(err).InjectMeta(map[string]interface{}{
 			"name": sc.Name,
@@ -292,11 +451,11 @@ func (ds dockerService) RemoveContainer(ctx context.Context, cli entity.DockerCl
 	for i := range names {
 		go func(name string) {
 			ds.withFields(cli, logrus.Fields{"name": name}).Debug("removing container")
-			errChan <- cli.ContainerRemove(ctx, name, types.ContainerRemoveOptions{
-				RemoveVolumes: false,
-				RemoveLinks:   false,
-				Force:         true,
-			})
+			err := engineOf(cli.Client).RemoveContainer(ctx, name, false)
+			if err == nil || errors.Is(err, entity.ErrNotFound) {
+				ds.releaseContainer(cli, name)
+			}
+			errChan <- err
 		}(names[i])
 	}
 	var err error
@@ -305,7 +464,7 @@ func (ds dockerService) RemoveContainer(ctx context.Context, cli entity.DockerCl
 		if e == nil {
 			continue
 		}
-		if strings.Contains(e.Error(), "No such container") {
+		if errors.Is(e, entity.ErrNotFound) {
 			continue //the container is already gone
 		}
 		err = fmt.Errorf("%v:%w", err, e)
@@ -316,39 +475,51 @@ func (ds dockerService) RemoveContainer(ctx context.Context, cli entity.DockerCl
 
 // CreateNetwork attempts to create a network
 func (ds dockerService) CreateNetwork(ctx context.Context, cli entity.DockerCli,
-	net command.Network) entity.Result {
-
-	networkCreate := types.NetworkCreate{
-		CheckDuplicate: true,
-		Attachable:     true,
-		Ingress:        false,
-		Internal:       false,
-		Labels:         cli.Labels,
-		IPAM: &network.IPAM{
-			Driver:  "default",
-			Options: nil,
-			Config: []network.IPAMConfig{
-				network.IPAMConfig{
-					Subnet:  net.Subnet,
-					Gateway: net.Gateway,
-				},
-			},
-		},
-		Options: map[string]string{},
+	net entity.Network) entity.Result {
+
+	net, added, err := ds.allocateNetwork(cli, net)
+	if err != nil {
+		return entity.NewFatalResult(err).InjectMeta(map[string]interface{}{
+			"name": net.Name,
+		})
 	}
-	if ds.conf.LocalMode || !net.Global {
-		networkCreate.Driver = "bridge"
-		networkCreate.Scope = "local"
-		networkCreate.Options["com.docker.network.bridge.name"] = net.Name
-	} else {
-		networkCreate.Driver = "overlay"
-		networkCreate.Scope = "swarm"
+
+	driver := net.GetDriver()
+	if ds.conf.LocalMode && driver == entity.OverlayDriver {
+		driver = entity.BridgeDriver
+	}
+	spec := entity.NetworkSpec{
+		Name:        net.Name,
+		Driver:      driver,
+		Global:      driver == entity.OverlayDriver,
+		Internal:    net.Internal,
+		EnableIPv6:  net.HasIPv6(),
+		Labels:      cli.Labels,
+		IPAMDriver:  net.IPAMDriver,
+		IPAMOptions: net.IPAMOptions,
+		Subnets:     net.AllSubnets(),
+		Parent:      net.Parent,
+		Mode:        net.Mode,
+		MTU:         net.MTU,
+		Encrypted:   net.Encrypted,
+		Options:     net.Options,
+	}
+	if driver == entity.BridgeDriver {
+		spec.BridgeName = net.Name
 	}
 	ds.withFields(cli, logrus.Fields{"name": net.Name,
-		"conf": networkCreate}).Debug("creating a network")
-	_, err := cli.NetworkCreate(ctx, net.Name, networkCreate)
+		"conf": spec}).Debug("creating a network")
+	err = engineOf(cli.Client).CreateNetwork(ctx, spec)
 
-	return ds.errorWhitelistHandler(err, "already exists")
+	res := ds.engineResult(err, entity.ErrAlreadyExists)
+	if !res.IsSuccess() && added {
+		ds.releaseNetwork(cli, net.Name)
+	}
+	return res.InjectMeta(map[string]interface{}{
+		"name":    net.Name,
+		"subnet":  net.Subnet,
+		"gateway": net.Gateway,
+	})
 }
 
 //RemoveNetwork attempts to remove a network
@@ -356,7 +527,23 @@ func (ds dockerService) RemoveNetwork(ctx context.Context, cli entity.DockerCli,
 	name string) entity.Result {
 
 	ds.withFields(cli, logrus.Fields{"name": name}).Debug("removing a network")
-	return entity.NewResult(cli.NetworkRemove(ctx, name))
+	err := engineOf(cli.Client).RemoveNetwork(ctx, name)
+	if err == nil {
+		ds.releaseNetwork(cli, name)
+	}
+	return entity.NewResult(err)
+}
+
+// endpointSpec gives the settings of a container on a network
+func endpointSpec(network string, ip string, ipv6 string, endpoint entity.Endpoint) entity.EndpointSpec {
+	return entity.EndpointSpec{
+		Network:    network,
+		IPv4:       ip,
+		IPv6:       ipv6,
+		MacAddress: endpoint.MacAddress,
+		Aliases:    endpoint.Aliases,
+		Links:      endpoint.Links,
+	}
 }
 
 func generateMacAddress() (string, error) {
@@ -371,152 +558,68 @@ func generateMacAddress() (string, error) {
 }
 
 func (ds dockerService) AttachNetwork(ctx context.Context, cli entity.DockerCli,
-	cmd command.ContainerNetwork) entity.Result {
+	cmd entity.ContainerNetwork) entity.Result {
 
 	ds.withField(cli, "cmd", cmd).Info("attaching a network")
-	macAddress, err := generateMacAddress()
+	ip, err := ds.assignAddress(cli, cmd.Network, cmd.Container, cmd.IP)
 	if err != nil {
-		return ds.errorWhitelistHandler(err)
+		return entity.NewFatalResult(err).InjectMeta(map[string]interface{}{
+			"container": cmd.Container,
+			"network":   cmd.Network,
+		})
 	}
-	err = cli.NetworkConnect(ctx, cmd.Network, cmd.Container, &network.EndpointSettings{
-		IPAMConfig: &network.EndpointIPAMConfig{
-			IPv4Address: cmd.IP,
-		},
-		MacAddress: macAddress,
+	settings := endpointSpec(cmd.Network, ip, cmd.IPv6, cmd.Endpoint)
+	if len(settings.MacAddress) == 0 {
+		macAddress, err := generateMacAddress()
+		if err != nil {
+			return ds.errorWhitelistHandler(err)
+		}
+		settings.MacAddress = macAddress
+	}
+	err = engineOf(cli.Client).ConnectNetwork(ctx, cmd.Container, settings)
+	res := ds.engineResult(err, entity.ErrAlreadyExists)
+	if !res.IsSuccess() {
+		ds.releaseAddress(cli, cmd.Network, cmd.Container)
+	}
+	return res.InjectMeta(map[string]interface{}{
+		"container": cmd.Container,
+		"network":   cmd.Network,
+		"address":   ip,
 	})
-	return ds.errorWhitelistHandler(err,
-		"is already attached to network",
-		"Address already in use")
 }
 
 func (ds dockerService) DetachNetwork(ctx context.Context, cli entity.DockerCli,
 	networkName string, containerName string) entity.Result {
 
-	err := cli.NetworkDisconnect(ctx, networkName, containerName, true)
-
-	return ds.errorWhitelistHandler(err, "is not connected to the network")
+	err := engineOf(cli.Client).DisconnectNetwork(ctx, containerName, networkName)
+	res := ds.engineResult(err, entity.ErrNotFound)
+	if res.IsSuccess() {
+		ds.releaseAddress(cli, networkName, containerName)
+	}
+	return res
 }
 
 func (ds dockerService) CreateVolume(ctx context.Context, ecli entity.DockerCli,
-	vol command.Volume) entity.Result {
+	vol entity.Volume) entity.Result {
 
 	if !vol.Global || ds.conf.LocalMode {
-		volConfig := volume.VolumeCreateBody{
-			Labels: vol.Labels,
+		err := engineOf(ecli.Client).CreateVolume(ctx, entity.VolumeSpec{
 			Name:   vol.Name,
+			Labels: vol.Labels,
+		})
+		if err != nil || vol.Seed == nil {
+			return entity.NewResult(err)
 		}
-
-		_, err := ecli.VolumeCreate(ctx, volConfig)
-		return entity.NewResult(err)
-	}
-
-	clients := make([]entity.Client, len(vol.Hosts))
-
-	for i, host := range vol.Hosts {
-		cli, err := ds.CreateClient2(host, ecli.TestID)
-		if err != nil {
-			return entity.NewErrorResult(err)
-		}
-		clients[i] = cli
-		ds.withField(ecli, "host", host).Info("created a client for volume share")
-	}
-
-	errChan := make(chan error)
-
-	brickDir := fmt.Sprintf("/var/bricks/%s", vol.Name)
-	for i := range vol.Hosts {
-		go func(i int) { //create the directory for the gluster bricks
-			errChan <- ds.repo.Exec(ctx, clients[i], GlusterContainerName, entity.Exec{
-				Cmd:        []string{"mkdir", "-p", brickDir},
-				Privileged: true,
-				Retries:    5,
-			})
-		}(i)
-	}
-
-	for range vol.Hosts {
-		err := <-errChan
-		if err != nil {
-			return entity.NewErrorResult(err)
-		}
-	}
-	err := ds.repo.Exec(ctx, clients[0], GlusterContainerName, entity.Exec{
-		Cmd:        []string{"gluster", "volume", "status", vol.Name},
-		Privileged: true,
-		Retries:    1,
-	}) //check if it already exists, if so, don't try to create it
-
-	if err != nil {
-		cmds := []string{"gluster", "volume", "create", vol.Name, "replica", fmt.Sprint(len(vol.Hosts))}
-		for i := range vol.Hosts {
-			cmds = append(cmds, fmt.Sprintf("%s:%s", ds.hostName(ecli, i), brickDir))
-		}
-		cmds = append(cmds, "force") //needed because it wants a separate partition by default
-
-		err = ds.repo.Exec(ctx, clients[0], GlusterContainerName, entity.Exec{
-			Cmd:        cmds,
-			Privileged: true,
-			Retries:    5,
-		}) //create the replica volume
-		if err != nil {
-			return entity.NewErrorResult(err)
-		}
-	}
-
-	err = ds.repo.Exec(ctx, clients[0], GlusterContainerName, entity.Exec{
-		Cmd:        []string{"gluster", "volume", "start", vol.Name},
-		Privileged: true,
-		Retries:    5,
-	})
-	if err != nil {
-		return entity.NewErrorResult(err)
-	}
-
-	err = ds.repo.Exec(ctx, clients[0], GlusterContainerName, entity.Exec{
-		Cmd:        []string{"gluster", "volume", "set", vol.Name, "ctime", "off"},
-		Privileged: true,
-		Retries:    5,
-	}) //compatibility
-	if err != nil {
-		return entity.NewErrorResult(err)
-	}
-
-	err = ds.repo.Exec(ctx, clients[0], GlusterContainerName, entity.Exec{
-		Cmd:        []string{"gluster", "volume", "set", vol.Name, "auth.allow", strings.Join(vol.Hosts, ",") + ",127.0.0.1"},
-		Privileged: true,
-		Retries:    5,
-	}) // restrict access by ip
-	if err != nil {
-		return entity.NewErrorResult(err)
-	}
-
-	for i := range clients {
-		go func(i int) {
-			_, err = clients[i].VolumeCreate(ctx, volume.VolumeCreateBody{
-				Driver: ds.conf.GlusterDriver,
-				Name:   vol.Name,
-				DriverOpts: map[string]string{
-					"glusteropts": fmt.Sprintf("--volfile-server=%s --volfile-id=/%s", ds.hostName(ecli, i), vol.Name),
-				},
-			})
-			errChan <- err
-		}(i)
-	}
-
-	for range clients {
-		err := <-errChan
-		if err != nil {
-			return entity.NewErrorResult(err)
-		}
+		return entity.NewResult(ds.seedVolume(ctx, ecli, vol.Name, *vol.Seed))
 	}
 
-	return entity.NewSuccessResult()
+	return ds.createGlobalVolume(ctx, ecli, vol)
 }
 
 func (ds dockerService) RemoveVolume(ctx context.Context, cli entity.DockerCli,
 	name string) entity.Result {
 
-	return entity.NewResult(cli.VolumeRemove(ctx, name, true))
+	return entity.NewResult(engineOf(cli.Client).RemoveVolume(ctx, name))
 }
 
 func (ds dockerService) PlaceFileInContainer(ctx context.Context, cli entity.DockerCli,
@@ -533,14 +636,24 @@ func (ds dockerService) PlaceFileInContainer(ctx context.Context, cli entity.Doc
 		})
 	}
 
+	err = ds.copyToContainer(ctx, cli, containerName, rdr, file.Meta.Filename, file.Destination)
+	return entity.NewResult(err).InjectMeta(map[string]interface{}{
+		"labels":    cli.Labels,
+		"container": containerName,
+	})
+}
+
+// copyToContainer copies the file in the tar archive to the destination in the container
+func (ds dockerService) copyToContainer(ctx context.Context, cli entity.DockerCli,
+	containerName string, rdr io.Reader, srcPath string, dstPath string) error {
+
 	srcInfo := archive.CopyInfo{ //appease the Docker Gods
-		Path:   file.Meta.Filename,
+		Path:   srcPath,
 		Exists: true,
 		IsDir:  false,
 	}
-	dstPath := file.Destination
 	if !srcInfo.IsDir && dstPath[len(dstPath)-1] == '/' {
-		dstPath += filepath.Base(file.Meta.Filename)
+		dstPath += filepath.Base(srcPath)
 	}
 
 	// Prepare destination copy info by stat-ing the container path.
@@ -573,7 +686,7 @@ func (ds dockerService) PlaceFileInContainer(ctx context.Context, cli entity.Doc
 	}).Trace("about to prepare the archive copy")
 	dstDir, preparedArchive, err := archive.PrepareArchiveCopy(rdr, srcInfo, dstInfo)
 	if err != nil {
-		return entity.NewErrorResult(err)
+		return err
 	}
 	defer preparedArchive.Close()
 	ds.withFields(cli, logrus.Fields{
@@ -581,145 +694,10 @@ func (ds dockerService) PlaceFileInContainer(ctx context.Context, cli entity.Doc
 		"container":  containerName,
 	}).Debug("got the destination for the file")
 
-	err = cli.CopyToContainer(ctx, containerName, dstDir, preparedArchive, types.CopyToContainerOptions{
+	return cli.CopyToContainer(ctx, containerName, dstDir, preparedArchive, types.CopyToContainerOptions{
 		AllowOverwriteDirWithFile: true,
 		CopyUIDGID:                false,
 	})
-
-	return entity.NewResult(err).InjectMeta(map[string]interface{}{
-		"labels":    cli.Labels,
-		"container": containerName,
-	})
-}
-
-func (ds dockerService) Emulation(ctx context.Context, cli entity.DockerCli,
-	netem command.Netconf) entity.Result {
-
-	netemImage := "gaiadocker/iproute2:latest"
-	errChan := make(chan error, 1)
-	go func() {
-		errChan <- ds.repo.EnsureImagePulled(ctx, cli, netemImage, command.Credentials{})
-	}()
-
-	net, err := ds.repo.GetNetworkByName(ctx, cli, netem.Network)
-	if err != nil {
-		return entity.NewErrorResult(err)
-	}
-
-	err = <-errChan
-	if err != nil {
-		return entity.NewErrorResult(err)
-	}
-
-	name := netem.Container + "-" + net.ID
-	netemCmd := fmt.Sprintf(
-		"tc qdisc add dev $(ip -o addr show to %s | sed -n 's/.*\\(eth[0-9]*\\).*/\\1/p') root netem",
-		net.IPAM.Config[0].Subnet)
-
-	if netem.Limit > 0 {
-		netemCmd += fmt.Sprintf(" limit %d", netem.Limit)
-	}
-
-	if netem.Loss > 0 {
-		netemCmd += fmt.Sprintf(" loss %.4f", netem.Loss)
-	}
-
-	if netem.Delay > 0 {
-		netemCmd += fmt.Sprintf(" delay %dus", netem.Delay)
-	}
-
-	if len(netem.Rate) > 0 {
-		netemCmd += fmt.Sprintf(" rate %s", netem.Rate)
-	}
-
-	if netem.Duplication > 0 {
-		netemCmd += fmt.Sprintf(" duplicate %.4f", netem.Duplication)
-	}
-
-	if netem.Corrupt > 0 {
-		netemCmd += fmt.Sprintf(" corrupt %.4f", netem.Duplication)
-	}
-
-	if netem.Reorder > 0 {
-		netemCmd += fmt.Sprintf(" reorder %.4f", netem.Reorder)
-	}
-
-	config := &container.Config{
-		Image:      netemImage,
-		Entrypoint: strslice.StrSlice([]string{"/bin/sh", "-c", netemCmd}),
-	}
-
-	hostConfig := &container.HostConfig{
-		AutoRemove:  true,
-		NetworkMode: container.NetworkMode(fmt.Sprintf("container:%s", netem.Container)),
-		CapAdd:      strslice.StrSlice([]string{"NET_ADMIN"}),
-	}
-
-	networkConfig := &network.NetworkingConfig{}
-
-	_, err = cli.ContainerCreate(ctx, config, hostConfig, networkConfig, name)
-	if err != nil {
-		return entity.NewErrorResult(err)
-	}
-	return ds.StartContainer(ctx, cli, command.StartContainer{Name: name})
-}
-
-func (ds dockerService) SwarmCluster(ctx context.Context, entryCLI entity.DockerCli,
-	dswarm command.SetupSwarm) entity.Result {
-
-	if ds.conf.LocalMode {
-		// Do nothing if it is in local mode
-		return entity.NewSuccessResult()
-	}
-
-	if len(dswarm.Hosts) == 0 {
-		return ErrNoHost
-	}
-	cli, err := ds.CreateClient2(dswarm.Hosts[0], entryCLI.TestID)
-	if err != nil {
-		ds.withField(entryCLI, "error", err).Error("creating the manager client")
-		return entity.NewErrorResult(err)
-	}
-	token, err := cli.SwarmInit(ctx, swarm.InitRequest{
-		ListenAddr:      fmt.Sprintf("0.0.0.0:%d", ds.conf.SwarmPort),
-		AdvertiseAddr:   fmt.Sprintf("%s:%d", dswarm.Hosts[0], ds.conf.SwarmPort),
-		ForceNewCluster: true,
-		Availability:    swarm.NodeAvailabilityActive,
-	})
-	if err != nil {
-		ds.withField(entryCLI, "error", err).Error("error with docker swarm init")
-		return entity.NewErrorResult(err)
-	}
-
-	details, err := cli.SwarmInspect(ctx)
-	if err != nil {
-		ds.withField(entryCLI, "error", err).Error("error with docker swarm inspect")
-		return entity.NewErrorResult(err)
-	}
-
-	ds.withField(entryCLI, "manager", token).Info("initializing docker swarm")
-	if len(dswarm.Hosts) == 1 {
-		return entity.NewSuccessResult()
-	}
-
-	for _, host := range dswarm.Hosts[1:] {
-		cli, err := ds.CreateClient2(host, entryCLI.TestID)
-		if err != nil {
-			return entity.NewErrorResult(err)
-		}
-		ds.withField(entryCLI, "token", details.JoinTokens.Worker).Info("adding worker to swarm")
-		err = cli.SwarmJoin(ctx, swarm.JoinRequest{
-			ListenAddr:    fmt.Sprintf("0.0.0.0:%d", ds.conf.SwarmPort),
-			AdvertiseAddr: fmt.Sprintf("%s:%d", host, ds.conf.SwarmPort),
-			RemoteAddrs:   []string{fmt.Sprintf("%s:%d", dswarm.Hosts[0], ds.conf.SwarmPort)},
-			JoinToken:     details.JoinTokens.Worker,
-			Availability:  swarm.NodeAvailabilityActive,
-		})
-		if err != nil {
-			return entity.NewErrorResult(err)
-		}
-	}
-	return entity.NewSuccessResult()
 }
 
 func (ds dockerService) PullImage(ctx context.Context, cli entity.DockerCli,
@@ -739,150 +717,12 @@ func (ds dockerService) PullImage(ctx context.Context, cli entity.DockerCli,
 	return entity.NewResult(err)
 }
 
-func (ds dockerService) mkConfigs() (*container.Config, *container.HostConfig, *network.NetworkingConfig, string) {
-	return &container.Config{
-			Hostname:   GlusterContainerName,
-			Domainname: GlusterContainerName,
-			Image:      ds.conf.GlusterImage,
-			Entrypoint: strslice.StrSlice([]string{"glusterd", "--no-daemon"}),
-		},
-		&container.HostConfig{
-			AutoRemove:  true,
-			NetworkMode: container.NetworkMode("host"),
-			CapAdd:      strslice.StrSlice([]string{"NET_ADMIN", "SYS_ADMIN"}),
-		}, &network.NetworkingConfig{}, GlusterContainerName
-}
-
-func (ds dockerService) hostName(ecli entity.DockerCli, index int) string {
-	return fmt.Sprintf("biome-%s-%d", ecli.Labels[command.TestIDKey], index)
-}
-
-func (ds dockerService) VolumeShare(ctx context.Context, ecli entity.DockerCli,
-	vs command.VolumeShare) entity.Result {
-	if ds.conf.LocalMode {
-		// Do nothing if it is in local mode
-		return entity.NewSuccessResult()
-	}
-	if len(vs.Hosts) == 0 {
-		return entity.NewFatalResult("given an empty volume share command")
-	}
-
-	clients := make([]entity.Client, len(vs.Hosts))
-
-	for i, host := range vs.Hosts {
-		cli, err := ds.CreateClient2(host, ecli.TestID)
-		if err != nil {
-			return entity.NewErrorResult(err)
-		}
-		clients[i] = cli
-		ds.withField(ecli, "host", host).Info("created a client for volume share")
-	}
-	errChan := make(chan error)
-
-	for i := range vs.Hosts {
-		go func(i int) {
-			errChan <- ds.repo.EnsureImagePulled(ctx, clients[i], ds.conf.GlusterImage, command.Credentials{})
-		}(i)
-	}
-
-	for range vs.Hosts {
-		err := <-errChan
-		if err != nil {
-			return entity.NewErrorResult(err)
-		}
-	}
-
-	config, hostConfig, networkConfig, name := ds.mkConfigs()
-
-	for i := range vs.Hosts {
-		go func(i int) {
-			_, err := clients[i].ContainerCreate(ctx, config, hostConfig, networkConfig, name)
-			errChan <- err
-		}(i)
-	}
-
-	for range vs.Hosts {
-		err := <-errChan
-		if err != nil {
-			return ds.errorWhitelistHandler(err, "already in use by container")
-		}
-	}
-
-	for i := range vs.Hosts {
-		go func(i int) {
-			errChan <- clients[i].ContainerStart(ctx, GlusterContainerName, types.ContainerStartOptions{})
-		}(i)
-	}
-
-	for i := range vs.Hosts {
-		err := <-errChan
-		if err != nil {
-			return entity.NewErrorResult(err).InjectMeta(map[string]interface{}{
-				"host": vs.Hosts[i],
-				"type": "StartContainer",
-			})
-		}
-	}
-	cnt := 0
-	for i := range vs.Hosts {
-		cnt += len(vs.Hosts)
-		go func(i int) {
-
-			for j := range vs.Hosts {
-				if i == j {
-					errChan <- ds.repo.Exec(ctx, clients[i], GlusterContainerName, entity.Exec{
-						Cmd: []string{"bash", "-c", fmt.Sprintf(`echo "%s  %s" >> /etc/hosts`,
-							"127.0.0.1", ds.hostName(ecli, j))},
-						Privileged: true,
-						Retries:    2,
-					})
-				} else {
-					errChan <- ds.repo.Exec(ctx, clients[i], GlusterContainerName, entity.Exec{
-						Cmd: []string{
-							"bash", "-c", fmt.Sprintf(`echo "%s  %s" >> /etc/hosts`,
-								vs.Hosts[j], ds.hostName(ecli, j))},
-						Privileged: true,
-						Retries:    2,
-					})
-				}
-
-			}
-		}(i)
-	}
-	for i := 0; i < cnt; i++ {
-		err := <-errChan
-		if err != nil {
-			return entity.NewErrorResult(err).InjectMeta(map[string]interface{}{
-				"type": "Exec",
-			})
-		}
-	}
-	cnt = 0
-	//for i := range vs.Hosts {
-	for j := range vs.Hosts {
-		if 0 == j {
-			continue
-		}
-		cnt++
-		go func(i int, j int) {
-			errChan <- ds.repo.Exec(ctx, clients[i], GlusterContainerName, entity.Exec{
-				Cmd:        []string{"gluster", "peer", "probe", ds.hostName(ecli, j)},
-				Privileged: true,
-				Retries:    20,
-				Delay:      100 * time.Millisecond,
-			})
-		}(0, j)
-	}
-	//}
-
-	for i := 0; i < cnt; i++ {
-		err := <-errChan
-		if err != nil {
-			return entity.NewErrorResult(err).InjectMeta(map[string]interface{}{
-				"type": "Exec",
-			})
-		}
-	}
-
-	return entity.NewSuccessResult()
+// ReleaseTest stops the emulation schedules of the test, then closes its clients and releases
+// its addresses
+func (ds dockerService) ReleaseTest(testID string) {
+	ctx, cancel := context.WithTimeout(context.Background(), scheduleStopTimeout)
+	defer cancel()
+	ds.schedules.cancelTest(ctx, testID)
+	ds.pool.evictTest(testID)
+	ds.releaseTest(testID)
 }
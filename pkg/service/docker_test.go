@@ -7,9 +7,15 @@
 package service
 
 import (
+	"archive/tar"
+	"bytes"
+	"context"
 	"fmt"
+	"io"
+	"io/ioutil"
 	//"strings"
 	"testing"
+	"time"
 
 	entityMock "github.com/whiteblock/genesis/mocks/pkg/entity"
 	externalsMock "github.com/whiteblock/genesis/mocks/pkg/externals"
@@ -17,12 +23,14 @@ import (
 	repoMock "github.com/whiteblock/genesis/mocks/pkg/repository"
 	"github.com/whiteblock/genesis/pkg/config"
 	"github.com/whiteblock/genesis/pkg/entity"
+	"github.com/whiteblock/genesis/pkg/repository"
 
 	"github.com/docker/docker/api/types"
 	"github.com/docker/docker/api/types/container"
 	"github.com/docker/docker/api/types/mount"
 	"github.com/docker/docker/api/types/network"
 	dockerVolume "github.com/docker/docker/api/types/volume"
+	"github.com/docker/docker/pkg/stdcopy"
 	"github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
@@ -32,7 +40,7 @@ import (
 )
 
 func TestNewDockerService(t *testing.T) {
-	assert.NotNil(t, NewDockerService(nil, config.Docker{}, nil, nil))
+	assert.NotNil(t, NewDockerService(nil, config.Docker{}, nil, nil, nil))
 }
 
 func TestDockerService_CreateContainer(t *testing.T) {
@@ -133,13 +141,13 @@ func TestDockerService_CreateContainer(t *testing.T) {
 		assert.Equal(t, testContainer.Image, args.String(2))
 	})
 
-	ds := NewDockerService(repo, config.Docker{}, nil, logrus.New())
+	ds := NewDockerService(repo, config.Docker{}, nil, nil, logrus.New())
 	res := ds.CreateContainer(nil, entity.DockerCli{
 		Client: cli,
 		Labels: map[string]string{
 			"FOO": "BAR",
 		},
-	}, testContainer)
+	}, entity.Container{Container: testContainer})
 	assert.NoError(t, res.Error)
 }
 
@@ -168,7 +176,7 @@ func TestDockerService_StartContainer_Success(t *testing.T) {
 		}).Maybe()
 
 	repo := new(repoMock.DockerRepository)
-	ds := NewDockerService(repo, config.Docker{}, nil, logrus.New())
+	ds := NewDockerService(repo, config.Docker{}, nil, nil, logrus.New())
 	res := ds.StartContainer(nil, entity.DockerCli{Client: cli}, scCommand)
 	assert.NoError(t, res.Error)
 	cli.AssertExpectations(t)
@@ -222,14 +230,14 @@ func TestDockerService_CreateNetwork_Success(t *testing.T) {
 	}).Twice()
 
 	repo := new(repoMock.DockerRepository)
-	ds := NewDockerService(repo, config.Docker{}, nil, logrus.New())
+	ds := NewDockerService(repo, config.Docker{}, nil, nil, logrus.New())
 
 	res := ds.CreateNetwork(nil, entity.DockerCli{
 		Client: cli,
 		Labels: map[string]string{
 			"FOO": "BAR",
 		},
-	}, testNetwork)
+	}, entity.Network{Network: testNetwork})
 	assert.NoError(t, res.Error)
 
 	testNetwork.Global = false
@@ -238,12 +246,187 @@ func TestDockerService_CreateNetwork_Success(t *testing.T) {
 		Labels: map[string]string{
 			"FOO": "BAR",
 		},
-	}, testNetwork)
+	}, entity.Network{Network: testNetwork})
 	assert.NoError(t, res.Error)
 
 	cli.AssertExpectations(t)
 }
 
+func TestDockerService_CreateContainer_Endpoint(t *testing.T) {
+	testContainer := entity.Container{
+		Container: command.Container{
+			Name:    "validator-3",
+			Network: "net-a",
+			Image:   "alpine",
+			Cpus:    "1",
+			Memory:  "1gb",
+		},
+		Endpoint: entity.Endpoint{
+			Aliases:    []string{"validator-3.net-a"},
+			MacAddress: "02:42:ac:11:00:03",
+			Links:      []string{"bootnode:boot"},
+		},
+		DNSSearch: []string{"net-a"},
+	}
+
+	cli := new(entityMock.Client)
+	cli.On("ContainerCreate", mock.Anything, mock.Anything, mock.Anything, mock.Anything,
+		testContainer.Name).Return(container.ContainerCreateCreatedBody{}, nil).Run(func(args mock.Arguments) {
+
+		config, ok := args.Get(1).(*container.Config)
+		require.True(t, ok)
+		assert.Equal(t, testContainer.MacAddress, config.MacAddress)
+
+		hostConfig, ok := args.Get(2).(*container.HostConfig)
+		require.True(t, ok)
+		assert.Equal(t, testContainer.DNSSearch, hostConfig.DNSSearch)
+
+		networkingConfig, ok := args.Get(3).(*network.NetworkingConfig)
+		require.True(t, ok)
+		netconf, ok := networkingConfig.EndpointsConfig[testContainer.Network]
+		require.True(t, ok)
+		assert.Equal(t, testContainer.Aliases, netconf.Aliases)
+		assert.Equal(t, testContainer.Links, netconf.Links)
+		assert.Equal(t, testContainer.MacAddress, netconf.MacAddress)
+	}).Once()
+
+	repo := new(repoMock.DockerRepository)
+	repo.On("EnsureImagePulled", mock.Anything, mock.Anything, testContainer.Image, mock.Anything).Return(nil).Once()
+
+	ds := NewDockerService(repo, config.Docker{}, nil, nil, logrus.New())
+	res := ds.CreateContainer(nil, entity.DockerCli{Client: cli, Labels: map[string]string{}}, testContainer)
+	assert.NoError(t, res.Error)
+	cli.AssertExpectations(t)
+	repo.AssertExpectations(t)
+}
+
+func TestDockerService_CreateNetwork_DualStack(t *testing.T) {
+	testNetwork := entity.Network{
+		Network: command.Network{
+			Name:    "testnet",
+			Gateway: "10.14.0.1",
+			Subnet:  "10.14.0.0/16",
+		},
+		Subnets: []entity.Subnet{{Subnet: "fd00:14::/64", Gateway: "fd00:14::1"}},
+	}
+	cli := new(entityMock.Client)
+	cli.On("NetworkCreate", mock.Anything, testNetwork.Name, mock.Anything).Return(
+		types.NetworkCreateResponse{}, nil).Run(func(args mock.Arguments) {
+
+		networkCreate, ok := args.Get(2).(types.NetworkCreate)
+		require.True(t, ok)
+		assert.True(t, networkCreate.EnableIPv6)
+		require.Len(t, networkCreate.IPAM.Config, 2)
+		assert.Equal(t, "10.14.0.0/16", networkCreate.IPAM.Config[0].Subnet)
+		assert.Equal(t, "fd00:14::/64", networkCreate.IPAM.Config[1].Subnet)
+		assert.Equal(t, "fd00:14::1", networkCreate.IPAM.Config[1].Gateway)
+	}).Once()
+
+	ds := NewDockerService(nil, config.Docker{}, nil, nil, logrus.New())
+	res := ds.CreateNetwork(nil, entity.DockerCli{Client: cli}, testNetwork)
+	assert.NoError(t, res.Error)
+	cli.AssertExpectations(t)
+}
+
+func TestDockerService_CreateNetwork_Drivers(t *testing.T) {
+	var tests = []struct {
+		net      entity.Network
+		driver   string
+		scope    string
+		internal bool
+		options  map[string]string
+	}{
+		{
+			net: entity.Network{
+				Network: command.Network{Name: "testnet"},
+				Driver:  entity.MacvlanDriver,
+				Parent:  "eth1",
+				Mode:    "bridge",
+				Options: map[string]string{"foo": "bar"},
+			},
+			driver:  "macvlan",
+			scope:   "local",
+			options: map[string]string{"parent": "eth1", "macvlan_mode": "bridge", "foo": "bar"},
+		},
+		{
+			net: entity.Network{
+				Network:  command.Network{Name: "testnet"},
+				Driver:   entity.IpvlanDriver,
+				Mode:     "l3",
+				Internal: true,
+			},
+			driver:   "ipvlan",
+			scope:    "local",
+			internal: true,
+			options:  map[string]string{"ipvlan_mode": "l3"},
+		},
+		{
+			net: entity.Network{
+				Network:   command.Network{Name: "testnet", Global: true},
+				Encrypted: true,
+				MTU:       1400,
+			},
+			driver:  "overlay",
+			scope:   "swarm",
+			options: map[string]string{"encrypted": "", "com.docker.network.driver.mtu": "1400"},
+		},
+		{
+			net: entity.Network{
+				Network: command.Network{Name: "testnet"},
+				MTU:     9000,
+			},
+			driver: "bridge",
+			scope:  "local",
+			options: map[string]string{
+				"com.docker.network.bridge.name": "testnet",
+				"com.docker.network.driver.mtu":  "9000",
+			},
+		},
+	}
+
+	for i, tt := range tests {
+		t.Run(fmt.Sprint(i), func(t *testing.T) {
+			cli := new(entityMock.Client)
+			cli.On("NetworkCreate", mock.Anything, "testnet", mock.Anything).Return(
+				types.NetworkCreateResponse{}, nil).Run(func(args mock.Arguments) {
+
+				networkCreate, ok := args.Get(2).(types.NetworkCreate)
+				require.True(t, ok)
+				assert.Equal(t, tt.driver, networkCreate.Driver)
+				assert.Equal(t, tt.scope, networkCreate.Scope)
+				assert.Equal(t, tt.internal, networkCreate.Internal)
+				assert.Equal(t, tt.options, networkCreate.Options)
+			}).Once()
+
+			ds := NewDockerService(nil, config.Docker{}, nil, nil, logrus.New())
+			res := ds.CreateNetwork(nil, entity.DockerCli{Client: cli}, tt.net)
+			assert.NoError(t, res.Error)
+			cli.AssertExpectations(t)
+		})
+	}
+}
+
+func TestDockerService_CreateNetwork_IPAMDriver(t *testing.T) {
+	cli := new(entityMock.Client)
+	cli.On("NetworkCreate", mock.Anything, "testnet", mock.Anything).Return(
+		types.NetworkCreateResponse{}, nil).Run(func(args mock.Arguments) {
+
+		networkCreate, ok := args.Get(2).(types.NetworkCreate)
+		require.True(t, ok)
+		assert.Equal(t, "custom", networkCreate.IPAM.Driver)
+		assert.Equal(t, map[string]string{"pool": "a"}, networkCreate.IPAM.Options)
+	}).Once()
+
+	ds := NewDockerService(nil, config.Docker{}, nil, nil, logrus.New())
+	res := ds.CreateNetwork(nil, entity.DockerCli{Client: cli}, entity.Network{
+		Network:     command.Network{Name: "testnet"},
+		IPAMDriver:  "custom",
+		IPAMOptions: map[string]string{"pool": "a"},
+	})
+	assert.NoError(t, res.Error)
+	cli.AssertExpectations(t)
+}
+
 func TestDockerService_CreateNetwork_Failure(t *testing.T) {
 	testNetwork := command.Network{
 		Name:   "testnet",
@@ -259,9 +442,9 @@ func TestDockerService_CreateNetwork_Failure(t *testing.T) {
 		types.NetworkCreateResponse{}, fmt.Errorf("error")).Once()
 
 	repo := new(repoMock.DockerRepository)
-	ds := NewDockerService(repo, config.Docker{}, nil, logrus.New())
+	ds := NewDockerService(repo, config.Docker{}, nil, nil, logrus.New())
 
-	res := ds.CreateNetwork(nil, entity.DockerCli{Client: cli}, testNetwork)
+	res := ds.CreateNetwork(nil, entity.DockerCli{Client: cli}, entity.Network{Network: testNetwork})
 	assert.Error(t, res.Error)
 
 	cli.AssertExpectations(t)
@@ -283,7 +466,7 @@ func TestDockerService_RemoveNetwork_Success(t *testing.T) {
 			}).Once()
 	}
 
-	ds := NewDockerService(nil, config.Docker{}, nil, logrus.New())
+	ds := NewDockerService(nil, config.Docker{}, nil, nil, logrus.New())
 
 	for _, net := range networks {
 		res := ds.RemoveNetwork(nil, entity.DockerCli{Client: cli}, net.Name)
@@ -297,7 +480,7 @@ func TestDockerService_RemoveNetwork_NetworkList_Failure(t *testing.T) {
 	cli := new(entityMock.Client)
 	cli.On("NetworkRemove", mock.Anything, mock.Anything).Return(fmt.Errorf("test")).Once()
 
-	ds := NewDockerService(nil, config.Docker{}, nil, logrus.New())
+	ds := NewDockerService(nil, config.Docker{}, nil, nil, logrus.New())
 
 	res := ds.RemoveNetwork(nil, entity.DockerCli{Client: cli}, "")
 	assert.Error(t, res.Error)
@@ -316,7 +499,7 @@ func TestDockerService_RemoveNetwork_NetworkRemove_Failure(t *testing.T) {
 		cli.On("NetworkRemove", mock.Anything, net.Name).Return(fmt.Errorf("err")).Once()
 	}
 
-	ds := NewDockerService(nil, config.Docker{}, nil, logrus.New())
+	ds := NewDockerService(nil, config.Docker{}, nil, nil, logrus.New())
 
 	for _, net := range networks {
 		res := ds.RemoveNetwork(nil, entity.DockerCli{Client: cli}, net.Name)
@@ -348,7 +531,7 @@ func TestDockerService_RemoveContainer(t *testing.T) {
 			}).Once()
 	}
 
-	ds := NewDockerService(nil, config.Docker{}, nil, logrus.New())
+	ds := NewDockerService(nil, config.Docker{}, nil, nil, logrus.New())
 
 	for _, cntr := range cntrs {
 		res := ds.RemoveContainer(nil, entity.DockerCli{Client: cli}, cntr.Names[0])
@@ -375,9 +558,9 @@ func TestDockerService_AttachNetwork(t *testing.T) {
 		require.NotNil(t, epSettings)
 	}).Once()
 
-	ds := NewDockerService(nil, config.Docker{}, nil, logrus.New())
+	ds := NewDockerService(nil, config.Docker{}, nil, nil, logrus.New())
 
-	res := ds.AttachNetwork(nil, entity.DockerCli{Client: cli}, cn)
+	res := ds.AttachNetwork(nil, entity.DockerCli{Client: cli}, entity.ContainerNetwork{ContainerNetwork: cn})
 	assert.NoError(t, res.Error)
 
 	cli.AssertExpectations(t)
@@ -398,7 +581,7 @@ func TestDockerService_DetachNetwork(t *testing.T) {
 		assert.True(t, args.Bool(3))
 	}).Once()
 
-	ds := NewDockerService(nil, config.Docker{}, nil, logrus.New())
+	ds := NewDockerService(nil, config.Docker{}, nil, nil, logrus.New())
 
 	res := ds.DetachNetwork(nil, entity.DockerCli{Client: cli}, netName, cntrName)
 	assert.NoError(t, res.Error)
@@ -430,12 +613,12 @@ func TestDockerService_CreateVolume(t *testing.T) {
 
 	repo := new(repoMock.DockerRepository)
 
-	ds := NewDockerService(repo, config.Docker{}, nil, logrus.New())
+	ds := NewDockerService(repo, config.Docker{}, nil, nil, logrus.New())
 
-	res := ds.CreateVolume(nil, entity.DockerCli{Client: cli}, command.Volume{
+	res := ds.CreateVolume(nil, entity.DockerCli{Client: cli}, entity.Volume{Volume: command.Volume{
 		Name:   "test_volume",
 		Labels: map[string]string{"foo": "bar"},
-	})
+	}})
 	assert.NoError(t, res.Error)
 
 	cli.AssertExpectations(t)
@@ -455,7 +638,7 @@ func TestDockerService_RemoveVolume(t *testing.T) {
 
 	repo := new(repoMock.DockerRepository)
 
-	ds := NewDockerService(repo, config.Docker{}, nil, logrus.New())
+	ds := NewDockerService(repo, config.Docker{}, nil, nil, logrus.New())
 
 	res := ds.RemoveVolume(nil, entity.DockerCli{Client: cli}, name)
 	assert.NoError(t, res.Error)
@@ -469,3 +652,802 @@ func TestRandomMacAddress(t *testing.T) {
 		t.Fatal(err)
 	}
 }
+
+func TestNetemArgs(t *testing.T) {
+	assert.Equal(t, "", netemArgs(command.Netconf{}))
+	assert.Equal(t, " limit 10 loss 2.5000 delay 100us rate 1mbit corrupt 1.0000",
+		netemArgs(command.Netconf{Limit: 10, Loss: 2.5, Delay: 100, Rate: "1mbit", Corrupt: 1}))
+}
+
+func TestDockerService_AttachNetwork_IPv6(t *testing.T) {
+	cli := new(entityMock.Client)
+	cli.On("NetworkConnect", mock.Anything, "test2", "test1", mock.Anything).Return(nil).Run(
+		func(args mock.Arguments) {
+
+			epSettings, ok := args.Get(3).(*network.EndpointSettings)
+			require.True(t, ok)
+			assert.Equal(t, "10.1.0.2", epSettings.IPAMConfig.IPv4Address)
+			assert.Equal(t, "fd00::2", epSettings.IPAMConfig.IPv6Address)
+		}).Once()
+
+	ds := NewDockerService(nil, config.Docker{}, nil, nil, logrus.New())
+	res := ds.AttachNetwork(nil, entity.DockerCli{Client: cli}, entity.ContainerNetwork{
+		ContainerNetwork: command.ContainerNetwork{Network: "test2", Container: "test1", IP: "10.1.0.2"},
+		IPv6:             "fd00::2",
+	})
+	assert.NoError(t, res.Error)
+	cli.AssertExpectations(t)
+}
+
+func TestDockerService_CreateContainer_Networks(t *testing.T) {
+	testContainer := entity.Container{
+		Container: command.Container{
+			Name:   "validator-1",
+			Image:  "alpine",
+			Cpus:   "1",
+			Memory: "1gb",
+		},
+		Networks: []entity.NetworkAttachment{
+			{Network: "net-a", IP: "10.0.0.2"},
+			{Network: "net-b", IP: "10.1.0.2", Endpoint: entity.Endpoint{Aliases: []string{"v1"}}},
+			{Network: "net-c"},
+		},
+	}
+
+	cli := new(entityMock.Client)
+	cli.On("ContainerCreate", mock.Anything, mock.Anything, mock.Anything, mock.Anything,
+		testContainer.Name).Return(container.ContainerCreateCreatedBody{}, nil).Run(func(args mock.Arguments) {
+
+		networkingConfig, ok := args.Get(3).(*network.NetworkingConfig)
+		require.True(t, ok)
+		require.Len(t, networkingConfig.EndpointsConfig, 1)
+		netconf, ok := networkingConfig.EndpointsConfig["net-a"]
+		require.True(t, ok)
+		assert.Equal(t, "10.0.0.2", netconf.IPAMConfig.IPv4Address)
+	}).Once()
+	cli.On("NetworkConnect", mock.Anything, "net-b", testContainer.Name, mock.Anything).Return(nil).Run(
+		func(args mock.Arguments) {
+			epSettings, ok := args.Get(3).(*network.EndpointSettings)
+			require.True(t, ok)
+			assert.Equal(t, "10.1.0.2", epSettings.IPAMConfig.IPv4Address)
+			assert.Equal(t, []string{"v1"}, epSettings.Aliases)
+		}).Once()
+	cli.On("NetworkConnect", mock.Anything, "net-c", testContainer.Name, mock.Anything).Return(nil).Once()
+
+	repo := new(repoMock.DockerRepository)
+	repo.On("EnsureImagePulled", mock.Anything, mock.Anything, testContainer.Image, mock.Anything).Return(nil).Once()
+
+	ds := NewDockerService(repo, config.Docker{}, nil, nil, logrus.New())
+	res := ds.CreateContainer(nil, entity.DockerCli{Client: cli, Labels: map[string]string{}}, testContainer)
+	assert.NoError(t, res.Error)
+	assert.Equal(t, []string{"net-a", "net-b", "net-c"}, res.Meta["networks"])
+	cli.AssertExpectations(t)
+	repo.AssertExpectations(t)
+}
+
+func TestDockerService_CreateContainer_Networks_Rollback(t *testing.T) {
+	testContainer := entity.Container{
+		Container: command.Container{
+			Name:    "validator-1",
+			Network: "net-a",
+			Image:   "alpine",
+			Cpus:    "1",
+			Memory:  "1gb",
+		},
+		Networks: []entity.NetworkAttachment{
+			{Network: "net-b"},
+			{Network: "net-c"},
+		},
+	}
+
+	cli := new(entityMock.Client)
+	cli.On("ContainerCreate", mock.Anything, mock.Anything, mock.Anything, mock.Anything,
+		testContainer.Name).Return(container.ContainerCreateCreatedBody{}, nil).Once()
+	cli.On("NetworkConnect", mock.Anything, "net-b", testContainer.Name, mock.Anything).Return(
+		fmt.Errorf("network net-b not found")).Once()
+	cli.On("ContainerRemove", mock.Anything, testContainer.Name, mock.Anything).Return(nil).Run(
+		func(args mock.Arguments) {
+			opts, ok := args.Get(2).(types.ContainerRemoveOptions)
+			require.True(t, ok)
+			assert.True(t, opts.Force)
+		}).Once()
+
+	repo := new(repoMock.DockerRepository)
+	repo.On("EnsureImagePulled", mock.Anything, mock.Anything, testContainer.Image, mock.Anything).Return(nil).Once()
+
+	ds := NewDockerService(repo, config.Docker{}, nil, nil, logrus.New())
+	res := ds.CreateContainer(nil, entity.DockerCli{Client: cli, Labels: map[string]string{}}, testContainer)
+	assert.Error(t, res.Error)
+	assert.True(t, res.IsFatal())
+	assert.Equal(t, "net-b", res.Meta["failedNetwork"])
+	assert.Equal(t, true, res.Meta["rolledBack"])
+	cli.AssertExpectations(t)
+	cli.AssertNotCalled(t, "NetworkConnect", mock.Anything, "net-c", mock.Anything, mock.Anything)
+}
+
+func TestDockerService_IPAM(t *testing.T) {
+	cli := new(entityMock.Client)
+	cli.On("NetworkCreate", mock.Anything, "net-a", mock.Anything).Return(
+		types.NetworkCreateResponse{}, nil).Run(func(args mock.Arguments) {
+
+		networkCreate, ok := args.Get(2).(types.NetworkCreate)
+		require.True(t, ok)
+		require.Len(t, networkCreate.IPAM.Config, 1)
+		assert.Equal(t, "10.10.0.0/24", networkCreate.IPAM.Config[0].Subnet)
+		assert.Equal(t, "10.10.0.1", networkCreate.IPAM.Config[0].Gateway)
+	}).Once()
+	cli.On("NetworkCreate", mock.Anything, "net-b", mock.Anything).Return(
+		types.NetworkCreateResponse{}, nil).Once()
+	cli.On("NetworkCreate", mock.Anything, "net-d", mock.Anything).Return(
+		types.NetworkCreateResponse{}, nil).Once()
+	cli.On("ContainerCreate", mock.Anything, mock.Anything, mock.Anything, mock.Anything,
+		"node-1").Return(container.ContainerCreateCreatedBody{}, nil).Run(func(args mock.Arguments) {
+
+		networkingConfig, ok := args.Get(3).(*network.NetworkingConfig)
+		require.True(t, ok)
+		assert.Equal(t, "10.10.0.2", networkingConfig.EndpointsConfig["net-a"].IPAMConfig.IPv4Address)
+	}).Once()
+	cli.On("NetworkConnect", mock.Anything, "net-b", "node-1", mock.Anything).Return(nil).Run(
+		func(args mock.Arguments) {
+			epSettings, ok := args.Get(3).(*network.EndpointSettings)
+			require.True(t, ok)
+			assert.Equal(t, "10.20.0.2", epSettings.IPAMConfig.IPv4Address)
+		}).Once()
+	cli.On("NetworkConnect", mock.Anything, "net-a", "node-2", mock.Anything).Return(nil).Run(
+		func(args mock.Arguments) {
+			epSettings, ok := args.Get(3).(*network.EndpointSettings)
+			require.True(t, ok)
+			assert.Equal(t, "10.10.0.3", epSettings.IPAMConfig.IPv4Address)
+		}).Once()
+	cli.On("ContainerRemove", mock.Anything, "node-1", mock.Anything).Return(nil).Once()
+	cli.On("NetworkConnect", mock.Anything, "net-a", "node-3", mock.Anything).Return(nil).Run(
+		func(args mock.Arguments) {
+			epSettings, ok := args.Get(3).(*network.EndpointSettings)
+			require.True(t, ok)
+			assert.Equal(t, "10.10.0.2", epSettings.IPAMConfig.IPv4Address)
+		}).Once()
+
+	repo := new(repoMock.DockerRepository)
+	repo.On("EnsureImagePulled", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+
+	ds := NewDockerService(repo, config.Docker{IPAMPools: []string{"10.10.0.0/16"}, IPAMSubnetSize: 24},
+		nil, nil, logrus.New())
+	dcli := entity.DockerCli{Client: cli, Labels: map[string]string{}, TestID: "test1"}
+
+	res := ds.CreateNetwork(nil, dcli, entity.Network{Network: command.Network{Name: "net-a"}})
+	require.NoError(t, res.Error)
+	assert.Equal(t, "10.10.0.0/24", res.Meta["subnet"])
+	assert.Equal(t, "10.10.0.1", res.Meta["gateway"])
+
+	res = ds.CreateNetwork(nil, dcli, entity.Network{Network: command.Network{Name: "net-b", Subnet: "10.20.0.0/24"}})
+	require.NoError(t, res.Error)
+
+	res = ds.CreateNetwork(nil, dcli, entity.Network{Network: command.Network{Name: "net-c", Subnet: "10.20.0.0/25"}})
+	assert.True(t, res.IsFatal())
+
+	res = ds.CreateContainer(nil, dcli, entity.Container{
+		Container: command.Container{Name: "node-1", Network: "net-a", Image: "alpine", Cpus: "1", Memory: "1gb"},
+		Networks:  []entity.NetworkAttachment{{Network: "net-b"}},
+	})
+	require.NoError(t, res.Error)
+	assert.Equal(t, map[string]string{"net-a": "10.10.0.2", "net-b": "10.20.0.2"}, res.Meta["addresses"])
+
+	res = ds.AttachNetwork(nil, dcli, entity.ContainerNetwork{
+		ContainerNetwork: command.ContainerNetwork{Container: "node-2", Network: "net-a", IP: "10.10.0.2"}})
+	assert.True(t, res.IsFatal())
+
+	res = ds.AttachNetwork(nil, dcli, entity.ContainerNetwork{
+		ContainerNetwork: command.ContainerNetwork{Container: "node-2", Network: "net-a"}})
+	require.NoError(t, res.Error)
+	assert.Equal(t, "10.10.0.3", res.Meta["address"])
+
+	res = ds.RemoveContainer(nil, dcli, "node-1")
+	require.NoError(t, res.Error)
+
+	res = ds.AttachNetwork(nil, dcli, entity.ContainerNetwork{
+		ContainerNetwork: command.ContainerNetwork{Container: "node-3", Network: "net-a"}})
+	require.NoError(t, res.Error)
+	assert.Equal(t, "10.10.0.2", res.Meta["address"])
+
+	ds.ReleaseTest("test1")
+	res = ds.CreateNetwork(nil, entity.DockerCli{Client: cli, Labels: map[string]string{}, TestID: "test2"},
+		entity.Network{Network: command.Network{Name: "net-d"}})
+	require.NoError(t, res.Error)
+	assert.Equal(t, "10.10.0.0/24", res.Meta["subnet"], "the subnets of a test are freed once it is over")
+
+	cli.AssertExpectations(t)
+}
+
+func TestDockerService_IPAM_PerHost(t *testing.T) {
+	cli := new(entityMock.Client)
+	cli.On("NetworkCreate", mock.Anything, "net-a", mock.Anything).Return(
+		types.NetworkCreateResponse{}, nil).Twice()
+	cli.On("NetworkRemove", mock.Anything, "net-a").Return(nil).Twice()
+	cli.On("NetworkConnect", mock.Anything, "net-a", mock.Anything, mock.Anything).Return(nil)
+
+	ds := NewDockerService(nil, config.Docker{IPAMPools: []string{"10.10.0.0/16"}, IPAMSubnetSize: 24},
+		nil, nil, logrus.New())
+	host1 := entity.DockerCli{Client: cli, TestID: "test1", IP: "192.168.1.1"}
+	host2 := entity.DockerCli{Client: cli, TestID: "test1", IP: "192.168.1.2"}
+
+	for _, dcli := range []entity.DockerCli{host1, host2} {
+		res := ds.CreateNetwork(nil, dcli, entity.Network{Network: command.Network{Name: "net-a"}})
+		require.NoError(t, res.Error)
+		assert.Equal(t, "10.10.0.0/24", res.Meta["subnet"])
+	}
+	res := ds.AttachNetwork(nil, host2, entity.ContainerNetwork{
+		ContainerNetwork: command.ContainerNetwork{Container: "node-1", Network: "net-a"}})
+	require.NoError(t, res.Error)
+	assert.Equal(t, "10.10.0.2", res.Meta["address"])
+
+	res = ds.RemoveNetwork(nil, host1, "net-a")
+	require.NoError(t, res.Error)
+	res = ds.AttachNetwork(nil, host2, entity.ContainerNetwork{
+		ContainerNetwork: command.ContainerNetwork{Container: "node-2", Network: "net-a"}})
+	require.NoError(t, res.Error)
+	assert.Equal(t, "10.10.0.3", res.Meta["address"], "the network is still in use on the other host")
+
+	res = ds.RemoveNetwork(nil, host2, "net-a")
+	require.NoError(t, res.Error)
+	res = ds.AttachNetwork(nil, host2, entity.ContainerNetwork{
+		ContainerNetwork: command.ContainerNetwork{Container: "node-3", Network: "net-a"}})
+	require.NoError(t, res.Error)
+	assert.Empty(t, res.Meta["address"], "the network is released once it is on no host")
+
+	cli.AssertExpectations(t)
+}
+
+func TestDockerService_AttachNetwork_Endpoint(t *testing.T) {
+	cli := new(entityMock.Client)
+	cli.On("NetworkConnect", mock.Anything, "net-a", "validator-3", mock.Anything).Return(nil).Run(
+		func(args mock.Arguments) {
+
+			epSettings, ok := args.Get(3).(*network.EndpointSettings)
+			require.True(t, ok)
+			assert.Equal(t, "02:42:ac:11:00:03", epSettings.MacAddress)
+			assert.Equal(t, []string{"validator-3.net-a"}, epSettings.Aliases)
+			assert.Equal(t, []string{"bootnode"}, epSettings.Links)
+		}).Once()
+
+	ds := NewDockerService(nil, config.Docker{}, nil, nil, logrus.New())
+	res := ds.AttachNetwork(nil, entity.DockerCli{Client: cli}, entity.ContainerNetwork{
+		ContainerNetwork: command.ContainerNetwork{Network: "net-a", Container: "validator-3"},
+		Endpoint: entity.Endpoint{
+			Aliases:    []string{"validator-3.net-a"},
+			MacAddress: "02:42:ac:11:00:03",
+			Links:      []string{"bootnode"},
+		},
+	})
+	assert.NoError(t, res.Error)
+	cli.AssertExpectations(t)
+}
+
+func TestLinkEmulationCmds(t *testing.T) {
+	cmds := linkEmulationCmds([]command.Netconf{
+		{Container: "node-1", Delay: 100},
+		{Container: "node-2", Loss: 10, Rate: "1mbit"},
+	}, [][]string{{"10.1.0.3"}, {"10.1.0.4", "fd00::4"}})
+
+	require.Len(t, cmds, 10)
+	assert.Equal(t, "(tc qdisc del dev $DEV root 2> /dev/null || true)", cmds[0])
+	assert.Equal(t, "tc qdisc add dev $DEV root handle 1: htb default 1", cmds[1])
+	assert.Equal(t, "tc class add dev $DEV parent 1: classid 1:a htb rate 10gbit", cmds[3])
+	assert.Equal(t, "tc qdisc add dev $DEV parent 1:a handle a: netem delay 100us", cmds[4])
+	assert.Equal(t, "tc filter add dev $DEV protocol ip parent 1: prio 1 u32 match ip dst 10.1.0.3/32 flowid 1:a",
+		cmds[5])
+	assert.Equal(t, "tc class add dev $DEV parent 1: classid 1:b htb rate 1mbit", cmds[6])
+	assert.Equal(t, "tc qdisc add dev $DEV parent 1:b handle b: netem loss 10.0000", cmds[7])
+	assert.Equal(t, "tc filter add dev $DEV protocol ip parent 1: prio 1 u32 match ip dst 10.1.0.4/32 flowid 1:b",
+		cmds[8])
+	assert.Equal(t, "tc filter add dev $DEV protocol ipv6 parent 1: prio 2 u32 match ip6 dst fd00::4/128 flowid 1:b",
+		cmds[9])
+}
+
+func TestNetemDevice(t *testing.T) {
+	dev := netemDevice(types.NetworkResource{IPAM: network.IPAM{
+		Config: []network.IPAMConfig{{Subnet: "10.1.0.0/16"}, {Subnet: "fd00::/64"}},
+	}})
+	assert.Contains(t, dev, "ip -o addr show to 10.1.0.0/16; ip -o addr show to fd00::/64")
+	assert.Contains(t, dev, "head -n 1")
+}
+
+func mockSidecar(t *testing.T, cli *entityMock.Client, name string, exitCode int64, stdout string,
+	checkConfig func(*container.Config, *container.HostConfig)) {
+
+	cli.On("ContainerCreate", mock.Anything, mock.Anything, mock.Anything, mock.Anything,
+		name).Return(container.ContainerCreateCreatedBody{}, nil).Run(func(args mock.Arguments) {
+
+		config, ok := args.Get(1).(*container.Config)
+		require.True(t, ok)
+		hostConfig, ok := args.Get(2).(*container.HostConfig)
+		require.True(t, ok)
+		if checkConfig != nil {
+			checkConfig(config, hostConfig)
+		}
+	}).Once()
+	cli.On("ContainerStart", mock.Anything, name, mock.Anything).Return(nil).Once()
+
+	resChan := make(chan container.ContainerWaitOKBody, 1)
+	resChan <- container.ContainerWaitOKBody{StatusCode: exitCode}
+	cli.On("ContainerWait", mock.Anything, name, mock.Anything).Return(
+		(<-chan container.ContainerWaitOKBody)(resChan), (<-chan error)(make(chan error))).Once()
+
+	var logs bytes.Buffer
+	_, err := stdcopy.NewStdWriter(&logs, stdcopy.Stdout).Write([]byte(stdout))
+	require.NoError(t, err)
+	cli.On("ContainerLogs", mock.Anything, name, mock.Anything).Return(
+		ioutil.NopCloser(&logs), nil).Once()
+	cli.On("ContainerRemove", mock.Anything, name, mock.Anything).Return(nil).Once()
+}
+
+func TestDockerService_Emulation(t *testing.T) {
+	testNetwork := types.NetworkResource{Name: "testnet", ID: "id1", IPAM: network.IPAM{
+		Config: []network.IPAMConfig{{Subnet: "10.1.0.0/16"}},
+	}}
+	conf := config.Docker{NetemImage: "netem"}
+	repo := new(repoMock.DockerRepository)
+	repo.On("EnsureImagePulled", mock.Anything, mock.Anything, conf.NetemImage, mock.Anything).Return(nil).Once()
+	repo.On("GetNetworkByName", mock.Anything, mock.Anything, testNetwork.Name).Return(testNetwork, nil).Once()
+
+	cli := new(entityMock.Client)
+	mockSidecar(t, cli, "node-0-id1", 0, "", func(config *container.Config, hostConfig *container.HostConfig) {
+		require.Len(t, config.Entrypoint, 3)
+		assert.Equal(t, conf.NetemImage, config.Image)
+		assert.Contains(t, config.Entrypoint[2], "tc qdisc replace dev $DEV root netem delay 100us")
+		assert.Contains(t, config.Entrypoint[2], "show to 10.1.0.0/16")
+		assert.Equal(t, container.NetworkMode("container:node-0"), hostConfig.NetworkMode)
+		assert.Contains(t, hostConfig.CapAdd, "NET_ADMIN")
+	})
+
+	ds := NewDockerService(repo, conf, nil, nil, logrus.New())
+	res := ds.Emulation(nil, entity.DockerCli{Client: cli}, command.Netconf{
+		Container: "node-0",
+		Network:   testNetwork.Name,
+		Delay:     100,
+	})
+	assert.NoError(t, res.Error)
+	cli.AssertExpectations(t)
+	repo.AssertExpectations(t)
+}
+
+func TestDockerService_RemoveEmulation_Failure(t *testing.T) {
+	testNetwork := types.NetworkResource{Name: "testnet", ID: "id1", IPAM: network.IPAM{
+		Config: []network.IPAMConfig{{Subnet: "10.1.0.0/16"}},
+	}}
+	repo := new(repoMock.DockerRepository)
+	repo.On("EnsureImagePulled", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil).Once()
+	repo.On("GetNetworkByName", mock.Anything, mock.Anything, testNetwork.Name).Return(testNetwork, nil).Once()
+
+	cli := new(entityMock.Client)
+	mockSidecar(t, cli, "node-0-id1", 1, "", func(config *container.Config, hostConfig *container.HostConfig) {
+		assert.Contains(t, config.Entrypoint[2], "tc qdisc del dev $DEV root")
+	})
+
+	ds := NewDockerService(repo, config.Docker{}, nil, nil, logrus.New())
+	res := ds.RemoveEmulation(nil, entity.DockerCli{Client: cli}, command.Netconf{
+		Container: "node-0",
+		Network:   testNetwork.Name,
+	})
+	assert.Error(t, res.Error)
+	cli.AssertExpectations(t)
+	repo.AssertExpectations(t)
+}
+
+func TestDockerService_ShowEmulation(t *testing.T) {
+	repo := new(repoMock.DockerRepository)
+	repo.On("EnsureImagePulled", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil).Once()
+
+	cli := new(entityMock.Client)
+	mockSidecar(t, cli, "node-0-qdiscs", 0, `[{"kind":"netem","handle":"8001:","root":true}]`,
+		func(config *container.Config, hostConfig *container.HostConfig) {
+			assert.Equal(t, "tc -j qdisc show", config.Entrypoint[2])
+		})
+
+	ds := NewDockerService(repo, config.Docker{}, nil, nil, logrus.New())
+	res := ds.ShowEmulation(nil, entity.DockerCli{Client: cli}, command.ContainerNetwork{Container: "node-0"})
+	require.NoError(t, res.Error)
+
+	qdiscs, ok := res.Meta["qdiscs"].([]interface{})
+	require.True(t, ok)
+	require.Len(t, qdiscs, 1)
+	assert.Equal(t, "netem", qdiscs[0].(map[string]interface{})["kind"])
+	cli.AssertExpectations(t)
+	repo.AssertExpectations(t)
+}
+
+func TestDockerService_LinkEmulation(t *testing.T) {
+	testNetwork := types.NetworkResource{Name: "testnet", ID: "id1", IPAM: network.IPAM{
+		Config: []network.IPAMConfig{{Subnet: "10.1.0.0/16"}},
+	}}
+	repo := new(repoMock.DockerRepository)
+	repo.On("EnsureImagePulled", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil).Once()
+	repo.On("GetNetworkByName", mock.Anything, mock.Anything, testNetwork.Name).Return(testNetwork, nil).Once()
+
+	cli := new(entityMock.Client)
+	cli.On("ContainerInspect", mock.Anything, "node-1").Return(types.ContainerJSON{
+		NetworkSettings: &types.NetworkSettings{
+			Networks: map[string]*network.EndpointSettings{
+				testNetwork.Name: &network.EndpointSettings{IPAddress: "10.1.0.3", GlobalIPv6Address: "fd00::3"},
+			},
+		},
+	}, nil).Once()
+	mockSidecar(t, cli, "node-0-id1", 0, "", func(config *container.Config, hostConfig *container.HostConfig) {
+		require.Len(t, config.Entrypoint, 3)
+		assert.Contains(t, config.Entrypoint[2], "match ip dst 10.1.0.3/32")
+		assert.Contains(t, config.Entrypoint[2], "match ip6 dst fd00::3/128")
+		assert.Equal(t, container.NetworkMode("container:node-0"), hostConfig.NetworkMode)
+	})
+
+	ds := NewDockerService(repo, config.Docker{}, nil, nil, logrus.New())
+	res := ds.LinkEmulation(nil, entity.DockerCli{Client: cli}, entity.LinkEmulation{
+		Container: "node-0",
+		Network:   testNetwork.Name,
+		Links:     []command.Netconf{{Container: "node-1", Delay: 100}},
+	})
+	assert.NoError(t, res.Error)
+	cli.AssertExpectations(t)
+	repo.AssertExpectations(t)
+}
+
+func TestDockerService_LinkEmulation_NotAttached(t *testing.T) {
+	cli := new(entityMock.Client)
+	cli.On("ContainerInspect", mock.Anything, "node-1").Return(types.ContainerJSON{
+		NetworkSettings: &types.NetworkSettings{},
+	}, nil).Once()
+
+	ds := NewDockerService(nil, config.Docker{}, nil, nil, logrus.New())
+	res := ds.LinkEmulation(nil, entity.DockerCli{Client: cli}, entity.LinkEmulation{
+		Container: "node-0",
+		Network:   "testnet",
+		Links:     []command.Netconf{{Container: "node-1", Delay: 100}},
+	})
+	assert.Error(t, res.Error)
+	cli.AssertExpectations(t)
+}
+
+func TestEmulationSchedules(t *testing.T) {
+	schedules := newEmulationSchedules()
+
+	canceled, err := schedules.cancel(context.Background(), scheduleKey("test", "chaos"))
+	assert.NoError(t, err)
+	assert.False(t, canceled)
+
+	ctx, rs := schedules.start(scheduleKey("test", "chaos"))
+	go func() {
+		<-ctx.Done()
+		schedules.finish(scheduleKey("test", "chaos"), rs)
+	}()
+
+	replacementCtx, replacement := schedules.start(scheduleKey("test", "chaos"))
+	assert.Error(t, ctx.Err(), "starting it again should stop the first one")
+	assert.NoError(t, replacementCtx.Err())
+
+	assert.Contains(t, schedules.running, scheduleKey("test", "chaos"),
+		"the first schedule should not unregister its replacement")
+
+	go func() {
+		<-replacementCtx.Done()
+		schedules.finish(scheduleKey("test", "chaos"), replacement)
+	}()
+	canceled, err = schedules.cancel(context.Background(), scheduleKey("test", "chaos"))
+	assert.NoError(t, err)
+	assert.True(t, canceled)
+	assert.Error(t, replacementCtx.Err())
+	assert.Len(t, schedules.running, 0)
+
+	ctx, rs = schedules.start(scheduleKey("test", "chaos"))
+	go func() {
+		<-ctx.Done()
+		schedules.finish(scheduleKey("test", "chaos"), rs)
+	}()
+	otherCtx, _ := schedules.start(scheduleKey("test2", "chaos"))
+	schedules.cancelTest(context.Background(), "test") // waits for it to finish
+	assert.Error(t, ctx.Err())
+	assert.NoError(t, otherCtx.Err(), "only the schedules of the test are stopped")
+	assert.Equal(t, []string{scheduleKey("test2", "chaos")}, func() (keys []string) {
+		for key := range schedules.running {
+			keys = append(keys, key)
+		}
+		return
+	}())
+}
+
+func TestDockerService_CancelEmulationSchedule_NoRestore(t *testing.T) {
+	testNetwork := types.NetworkResource{Name: "testnet", ID: "id1", IPAM: network.IPAM{
+		Config: []network.IPAMConfig{{Subnet: "10.1.0.0/16"}},
+	}}
+	repo := new(repoMock.DockerRepository)
+	repo.On("EnsureImagePulled", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	repo.On("GetNetworkByName", mock.Anything, mock.Anything, testNetwork.Name).Return(testNetwork, nil)
+
+	applied := make(chan struct{})
+	cli := new(entityMock.Client)
+	mockSidecar(t, cli, "node-0-id1", 0, "", func(config *container.Config, hostConfig *container.HostConfig) {
+		assert.Contains(t, config.Entrypoint[2], "tc qdisc replace dev $DEV root netem delay 100us")
+		close(applied)
+	})
+	mockSidecar(t, cli, "node-0-id1", 0, "", func(config *container.Config, hostConfig *container.HostConfig) {
+		assert.Contains(t, config.Entrypoint[2], "tc qdisc del dev $DEV root")
+	})
+
+	ds := NewDockerService(repo, config.Docker{ClientIdleTimeout: time.Minute, ClientHealthInterval: time.Minute},
+		nil, repository.NewCredentialStore(""), logrus.New()).(dockerService)
+	pooled, err := ds.pool.get(clientKey("10.0.0.2", "test", ds.conf.Runtime), "",
+		func() (entity.Client, error) { return cli, nil })
+	require.NoError(t, err)
+	pooled.Close()
+
+	dcli := entity.DockerCli{Client: cli, Labels: map[string]string{}, TestID: "test", IP: "10.0.0.2"}
+	res := ds.ScheduleEmulation(context.Background(), dcli, entity.EmulationSchedule{
+		Name:      "chaos",
+		Container: "node-0",
+		Network:   testNetwork.Name,
+		Steps:     []entity.EmulationStep{{Netconf: command.Netconf{Delay: 100}}},
+	})
+	require.NoError(t, res.Error)
+
+	select {
+	case <-applied:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the step of the schedule was never applied")
+	}
+	res = ds.CancelEmulationSchedule(context.Background(), dcli, "chaos")
+	require.NoError(t, res.Error)
+	assert.Equal(t, true, res.Meta["canceled"], "the schedule runs until it is canceled")
+	cli.AssertExpectations(t)
+}
+
+func TestSleepUntil(t *testing.T) {
+	assert.True(t, sleepUntil(context.Background(), time.Now().Add(time.Millisecond)))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.False(t, sleepUntil(ctx, time.Now().Add(time.Hour)))
+}
+
+func TestPartitionCmds(t *testing.T) {
+	cmds := partitionCmds([]string{"10.1.0.3", "10.1.0.4"})
+	require.Len(t, cmds, 7)
+	assert.Contains(t, cmds, "iptables -A GENESIS-PARTITION -s 10.1.0.3 -j DROP")
+	assert.Contains(t, cmds, "iptables -A GENESIS-PARTITION -d 10.1.0.4 -j DROP")
+}
+
+func TestPartitionCmds_IPv6(t *testing.T) {
+	cmds := partitionCmds([]string{"10.1.0.3", "fd00::3"})
+	require.Len(t, cmds, 10)
+	assert.Contains(t, cmds, "(ip6tables -N GENESIS-PARTITION 2> /dev/null || ip6tables -F GENESIS-PARTITION)")
+	assert.Contains(t, cmds, "ip6tables -A GENESIS-PARTITION -s fd00::3 -j DROP")
+	assert.Contains(t, cmds, "iptables -A GENESIS-PARTITION -d 10.1.0.3 -j DROP")
+
+	for _, cmd := range partitionCmds([]string{"10.1.0.3"}) {
+		assert.NotContains(t, cmd, "ip6tables")
+	}
+}
+
+func TestDockerService_Partition(t *testing.T) {
+	conf := config.Docker{IptablesImage: "iptables"}
+	repo := new(repoMock.DockerRepository)
+	repo.On("EnsureImagePulled", mock.Anything, mock.Anything, conf.IptablesImage, mock.Anything).Return(nil).Times(3)
+
+	cli := new(entityMock.Client)
+	ips := map[string]string{"node-0": "10.1.0.2", "node-1": "10.1.0.3", "node-2": "10.1.0.4"}
+	for name, ip := range ips {
+		cli.On("ContainerInspect", mock.Anything, name).Return(types.ContainerJSON{
+			NetworkSettings: &types.NetworkSettings{
+				Networks: map[string]*network.EndpointSettings{
+					"testnet": &network.EndpointSettings{IPAddress: ip},
+				},
+			},
+		}, nil).Once()
+	}
+	mockSidecar(t, cli, "node-0-partition", 0, "", func(config *container.Config, hostConfig *container.HostConfig) {
+		require.Len(t, config.Entrypoint, 3)
+		assert.Contains(t, config.Entrypoint[2], "-d 10.1.0.4 -j DROP")
+		assert.NotContains(t, config.Entrypoint[2], "10.1.0.3")
+		assert.Equal(t, container.NetworkMode("container:node-0"), hostConfig.NetworkMode)
+		assert.Contains(t, []string(hostConfig.CapAdd), "NET_ADMIN")
+	})
+	mockSidecar(t, cli, "node-1-partition", 0, "", func(config *container.Config, hostConfig *container.HostConfig) {
+		assert.Contains(t, config.Entrypoint[2], "-d 10.1.0.4 -j DROP")
+		assert.NotContains(t, config.Entrypoint[2], "10.1.0.2")
+	})
+	mockSidecar(t, cli, "node-2-partition", 0, "", func(config *container.Config, hostConfig *container.HostConfig) {
+		assert.Contains(t, config.Entrypoint[2], "-d 10.1.0.2 -j DROP")
+		assert.Contains(t, config.Entrypoint[2], "-d 10.1.0.3 -j DROP")
+	})
+
+	ds := NewDockerService(repo, conf, nil, nil, logrus.New())
+	res := ds.Partition(nil, entity.DockerCli{Client: cli}, entity.Partition{
+		Network: "testnet",
+		Groups:  [][]string{{"node-0", "node-1"}, {"node-2"}},
+	})
+	assert.NoError(t, res.Error)
+	cli.AssertExpectations(t)
+	repo.AssertExpectations(t)
+}
+
+func TestDockerService_HealPartition(t *testing.T) {
+	repo := new(repoMock.DockerRepository)
+	repo.On("EnsureImagePulled", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil).Twice()
+
+	cli := new(entityMock.Client)
+	mockSidecar(t, cli, "node-0-partition", 0, "", func(config *container.Config, hostConfig *container.HostConfig) {
+		assert.Contains(t, config.Entrypoint[2], "iptables -X GENESIS-PARTITION")
+	})
+	mockSidecar(t, cli, "node-1-partition", 1, "", nil)
+
+	ds := NewDockerService(repo, config.Docker{}, nil, nil, logrus.New())
+	res := ds.HealPartition(nil, entity.DockerCli{Client: cli}, entity.Partition{
+		Groups: [][]string{{"node-0", "node-1"}},
+	})
+	assert.Error(t, res.Error)
+	assert.Equal(t, "node-1", res.Meta["container"])
+	cli.AssertExpectations(t)
+	repo.AssertExpectations(t)
+}
+
+func TestDockerService_PauseContainer(t *testing.T) {
+	cli := new(entityMock.Client)
+	cli.On("ContainerPause", mock.Anything, "node-0").Return(nil).Once()
+	cli.On("ContainerUnpause", mock.Anything, "node-0").Return(fmt.Errorf("err")).Once()
+
+	ds := NewDockerService(nil, config.Docker{}, nil, nil, logrus.New())
+	res := ds.PauseContainer(nil, entity.DockerCli{Client: cli}, "node-0")
+	assert.NoError(t, res.Error)
+
+	res = ds.UnpauseContainer(nil, entity.DockerCli{Client: cli}, "node-0")
+	assert.Error(t, res.Error)
+	cli.AssertExpectations(t)
+}
+
+func TestDockerService_KillContainer(t *testing.T) {
+	cli := new(entityMock.Client)
+	cli.On("ContainerKill", mock.Anything, "node-0", "SIGTERM").Return(nil).Once()
+
+	ds := NewDockerService(nil, config.Docker{}, nil, nil, logrus.New())
+	res := ds.KillContainer(nil, entity.DockerCli{Client: cli}, entity.KillContainer{
+		Name:   "node-0",
+		Signal: "SIGTERM",
+	})
+	assert.NoError(t, res.Error)
+	cli.AssertExpectations(t)
+}
+
+func TestDockerService_RestartContainer(t *testing.T) {
+	timeout := 5 * time.Second
+	cli := new(entityMock.Client)
+	cli.On("ContainerStop", mock.Anything, "node-0", &timeout).Return(nil).Once()
+	cli.On("ContainerStart", mock.Anything, "node-0", mock.Anything).Return(nil).Once()
+
+	ds := NewDockerService(nil, config.Docker{}, nil, nil, logrus.New())
+	start := time.Now()
+	res := ds.RestartContainer(context.Background(), entity.DockerCli{Client: cli}, entity.RestartContainer{
+		Name:    "node-0",
+		Delay:   command.Duration{Time: command.Time{Duration: 10 * time.Millisecond}},
+		Timeout: command.Duration{Time: command.Time{Duration: timeout}},
+	})
+	assert.NoError(t, res.Error)
+	assert.True(t, time.Since(start) >= 10*time.Millisecond)
+	cli.AssertExpectations(t)
+}
+
+func TestDockerService_RestartContainer_Canceled(t *testing.T) {
+	cli := new(entityMock.Client)
+	cli.On("ContainerStop", mock.Anything, "node-0", (*time.Duration)(nil)).Return(nil).Once()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	ds := NewDockerService(nil, config.Docker{}, nil, nil, logrus.New())
+	res := ds.RestartContainer(ctx, entity.DockerCli{Client: cli}, entity.RestartContainer{
+		Name:  "node-0",
+		Delay: command.Duration{Time: command.Time{Duration: time.Hour}},
+	})
+	assert.Error(t, res.Error)
+	cli.AssertExpectations(t)
+}
+
+func TestDockerService_UpdateContainer(t *testing.T) {
+	cli := new(entityMock.Client)
+	cli.On("ContainerUpdate", mock.Anything, "node-0", mock.Anything).Return(
+		container.ContainerUpdateOKBody{}, nil).Run(func(args mock.Arguments) {
+
+		update, ok := args.Get(2).(container.UpdateConfig)
+		require.True(t, ok)
+		assert.Equal(t, int64(1500000000), update.NanoCPUs)
+		assert.Equal(t, int64(2048*1024*1024), update.Memory)
+		assert.Equal(t, 2*update.Memory, update.MemorySwap)
+	}).Once()
+
+	ds := NewDockerService(nil, config.Docker{}, nil, nil, logrus.New())
+	res := ds.UpdateContainer(nil, entity.DockerCli{Client: cli}, entity.UpdateResources{
+		Name:   "node-0",
+		Cpus:   "1.5",
+		Memory: "2GB",
+	})
+	assert.NoError(t, res.Error)
+
+	res = ds.UpdateContainer(nil, entity.DockerCli{Client: cli}, entity.UpdateResources{
+		Name: "node-0",
+		Cpus: "lots",
+	})
+	assert.True(t, res.IsFatal())
+	cli.AssertExpectations(t)
+}
+
+func TestStressArgs(t *testing.T) {
+	assert.Equal(t, []string{"stress-ng", "--cpu", "2", "--hdd", "1", "--hdd-bytes", "1G",
+		"--temp-path", "/data", "--timeout", "30s"}, stressArgs(entity.Stress{
+		CPU:      2,
+		HDD:      1,
+		HDDBytes: "1G",
+		Path:     "/data",
+		Duration: command.Duration{Time: command.Time{Duration: 30 * time.Second}},
+	}))
+}
+
+func TestDockerService_StressContainer(t *testing.T) {
+	conf := config.Docker{StressImage: "stress"}
+	repo := new(repoMock.DockerRepository)
+	repo.On("EnsureImagePulled", mock.Anything, mock.Anything, conf.StressImage, mock.Anything).Return(nil).Once()
+
+	cli := new(entityMock.Client)
+	mockSidecar(t, cli, "node-0-stress", 0, "", func(config *container.Config, hostConfig *container.HostConfig) {
+		assert.Equal(t, "stress-ng", config.Entrypoint[0])
+		assert.Equal(t, container.NetworkMode("container:node-0"), hostConfig.NetworkMode)
+		assert.Equal(t, container.PidMode("container:node-0"), hostConfig.PidMode)
+		assert.Equal(t, []string{"node-0"}, hostConfig.VolumesFrom)
+	})
+
+	ds := NewDockerService(repo, conf, nil, nil, logrus.New())
+	res := ds.StressContainer(nil, entity.DockerCli{Client: cli}, entity.Stress{
+		Container: "node-0",
+		IO:        4,
+		Path:      "/data",
+		Duration:  command.Duration{Time: command.Time{Duration: time.Minute}},
+	})
+	assert.NoError(t, res.Error)
+	cli.AssertExpectations(t)
+	repo.AssertExpectations(t)
+}
+
+func TestDockerService_ClockEnv(t *testing.T) {
+	ds := dockerService{conf: config.Docker{FaketimeLib: "/lib/faketime.so"}}
+	env := ds.clockEnv(map[string]string{"FOO": "bar", "LD_PRELOAD": "/lib/other.so"})
+	assert.Equal(t, "bar", env["FOO"])
+	assert.Equal(t, "/lib/faketime.so:/lib/other.so", env["LD_PRELOAD"])
+	assert.Equal(t, faketimeControlFile, env["FAKETIME_TIMESTAMP_FILE"])
+
+	env = ds.clockEnv(nil)
+	assert.Equal(t, "/lib/faketime.so", env["LD_PRELOAD"])
+}
+
+func TestDockerService_ChangeClock(t *testing.T) {
+	cli := new(entityMock.Client)
+	cli.On("ContainerStatPath", mock.Anything, "node-0", faketimeControlFile).Return(
+		types.ContainerPathStat{}, fmt.Errorf("not found")).Once()
+	cli.On("CopyToContainer", mock.Anything, "node-0", "/etc", mock.Anything, mock.Anything).Return(
+		nil).Run(func(args mock.Arguments) {
+
+		rdr, ok := args.Get(3).(io.Reader)
+		require.True(t, ok)
+		tr := tar.NewReader(rdr)
+		hdr, err := tr.Next()
+		require.NoError(t, err)
+		assert.Equal(t, "genesis-faketime", hdr.Name)
+		data, err := ioutil.ReadAll(tr)
+		require.NoError(t, err)
+		assert.Equal(t, "-30s x1.5\n", string(data))
+	}).Once()
+
+	ds := NewDockerService(nil, config.Docker{}, nil, nil, logrus.New())
+	res := ds.ChangeClock(context.Background(), entity.DockerCli{Client: cli}, entity.ContainerClock{
+		Container: "node-0",
+		Clock: entity.ClockSkew{
+			Offset: command.Duration{Time: command.Time{Duration: -30 * time.Second}},
+			Rate:   1.5,
+		},
+	})
+	assert.NoError(t, res.Error)
+	assert.Equal(t, "-30s x1.5", res.Meta["clock"])
+	cli.AssertExpectations(t)
+}
@@ -0,0 +1,301 @@
+/**
+ * Copyright 2019 Whiteblock Inc. All rights reserved.
+ * Use of this source code is governed by a BSD-style
+ * license that can be found in the LICENSE file.
+ */
+
+package service
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/whiteblock/genesis/pkg/entity"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/events"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/sirupsen/logrus"
+	"github.com/whiteblock/definition/command"
+)
+
+// ContainerEventPublisher sends out the events of the containers of the tests as they happen
+type ContainerEventPublisher interface {
+	// Publish sends out the event
+	Publish(event entity.ContainerEvent) error
+}
+
+// EventWatcher follows the events of the containers of each test, on the hosts the test runs on,
+// and acts on their crashes by the policy of the test
+type EventWatcher interface {
+	// Watch starts following the events of the containers of the test on the host since the
+	// given time, unless they are already followed. The time is when the command which needed
+	// them was run, so that a crash right after it is not missed. The policy of the test is the
+	// one it was first watched with.
+	Watch(testID string, host string, policy string, since time.Time)
+
+	// Failure gives the crash which failed the test, if there was one
+	Failure(testID string) *entity.ContainerEvent
+
+	// Stop stops following the events of the containers of the test
+	Stop(testID string)
+}
+
+// watchedTest is what is kept for a test while the events of its containers are followed
+type watchedTest struct {
+	policy   string
+	hosts    map[string]bool
+	restarts map[string]int
+	failure  *entity.ContainerEvent
+	ctx      context.Context
+	cancel   context.CancelFunc
+	wg       *sync.WaitGroup
+}
+
+// hostEvents is what is kept of the events of a host between the streams of them, so that the
+// stream which replaces a broken one carries on where it left off
+type hostEvents struct {
+	since  time.Time       // the time of the last event seen, which the next stream starts from
+	last   string          // the last event seen, which the next stream sees again
+	killed map[string]bool // the containers which are being stopped on purpose
+	oom    map[string]bool
+}
+
+// seen checks whether the event was already seen by a previous stream, recording it otherwise
+func (he *hostEvents) seen(msg events.Message) bool {
+	key := msg.ID + " " + msg.Action
+	at := time.Unix(0, msg.TimeNano)
+	if at.Before(he.since) || (at.Equal(he.since) && key == he.last) {
+		return true
+	}
+	he.since = at
+	he.last = key
+	return false
+}
+
+type eventWatcher struct {
+	restartLimit int
+	retryDelay   time.Duration
+	connect      func(host string, testID string) (entity.Client, error)
+	publisher    ContainerEventPublisher
+	log          logrus.Ext1FieldLogger
+
+	mux   *sync.Mutex
+	tests map[string]*watchedTest
+}
+
+// NewEventWatcher creates an EventWatcher which connects to the hosts with connect, and restarts
+// a container at most restartLimit times under the restart policy. The events are not sent out
+// when the publisher is nil.
+func NewEventWatcher(
+	restartLimit int,
+	connect func(host string, testID string) (entity.Client, error),
+	publisher ContainerEventPublisher,
+	log logrus.Ext1FieldLogger) EventWatcher {
+	return &eventWatcher{restartLimit: restartLimit, retryDelay: 5 * time.Second, connect: connect,
+		publisher: publisher, log: log, mux: &sync.Mutex{}, tests: map[string]*watchedTest{}}
+}
+
+// Watch starts following the events of the containers of the test on the host
+func (ew *eventWatcher) Watch(testID string, host string, policy string, since time.Time) {
+	if len(testID) == 0 || len(host) == 0 {
+		return
+	}
+	ew.mux.Lock()
+	defer ew.mux.Unlock()
+	test, ok := ew.tests[testID]
+	if !ok {
+		ctx, cancel := context.WithCancel(context.Background())
+		test = &watchedTest{policy: policy, hosts: map[string]bool{}, restarts: map[string]int{},
+			ctx: ctx, cancel: cancel, wg: &sync.WaitGroup{}}
+		ew.tests[testID] = test
+	}
+	if test.hosts[host] {
+		return
+	}
+	test.hosts[host] = true
+	test.wg.Add(1)
+	go ew.follow(test, testID, host, since)
+	ew.log.WithFields(logrus.Fields{"testID": testID, "host": host, "policy": test.policy}).Debug(
+		"following the events of the containers of a host")
+}
+
+// Failure gives the crash which failed the test
+func (ew *eventWatcher) Failure(testID string) *entity.ContainerEvent {
+	ew.mux.Lock()
+	defer ew.mux.Unlock()
+	test, ok := ew.tests[testID]
+	if !ok || test.failure == nil {
+		return nil
+	}
+	failure := *test.failure
+	return &failure
+}
+
+// Stop stops following the events of the containers of the test
+func (ew *eventWatcher) Stop(testID string) {
+	ew.mux.Lock()
+	test, ok := ew.tests[testID]
+	delete(ew.tests, testID)
+	ew.mux.Unlock()
+	if !ok {
+		return
+	}
+	test.cancel()
+	test.wg.Wait()
+}
+
+// follow follows the events of the host until the test is stopped, reconnecting when the stream
+// breaks. The new stream starts from the last event seen, so that none are missed while the host
+// was not reachable and the containers which were being stopped are known again.
+func (ew *eventWatcher) follow(test *watchedTest, testID string, host string, since time.Time) {
+	defer test.wg.Done()
+	state := &hostEvents{since: since, killed: map[string]bool{}, oom: map[string]bool{}}
+	for {
+		err := ew.stream(test, testID, host, state)
+		if test.ctx.Err() != nil {
+			return
+		}
+		ew.log.WithFields(logrus.Fields{"testID": testID, "host": host, "error": err}).Warn(
+			"lost the events of a host")
+		if entity.IsNotSupported(err) {
+			return // the runtime of the host will never give them
+		}
+		select {
+		case <-test.ctx.Done():
+			return
+		case <-time.After(ew.retryDelay):
+		}
+	}
+}
+
+func (ew *eventWatcher) stream(test *watchedTest, testID string, host string, state *hostEvents) error {
+	cli, err := ew.connect(host, testID)
+	if err != nil {
+		return err
+	}
+	defer cli.Close()
+
+	msgs, errs := cli.Events(test.ctx, types.EventsOptions{
+		Since: fmt.Sprintf("%d.%09d", state.since.Unix(), state.since.Nanosecond()),
+		Filters: filters.NewArgs(
+			filters.Arg("type", events.ContainerEventType),
+			filters.Arg("label", command.TestIDKey+"="+testID),
+			filters.Arg("event", "kill"),
+			filters.Arg("event", entity.DieContainerEvent),
+			filters.Arg("event", entity.OOMContainerEvent),
+			filters.Arg("event", entity.HealthContainerEvent),
+		)})
+
+	for {
+		var msg events.Message
+		select {
+		case err := <-errs:
+			return err
+		case msg = <-msgs:
+		}
+		if entity.IsHelper(msg.Actor.Attributes) || state.seen(msg) {
+			continue
+		}
+		event := entity.ContainerEvent{
+			TestID:    testID,
+			Host:      host,
+			Container: msg.Actor.Attributes["name"],
+			Action:    msg.Action,
+			Time:      time.Unix(0, msg.TimeNano),
+		}
+		switch {
+		case msg.Action == "kill":
+			state.killed[event.Container] = true
+			continue
+		case msg.Action == entity.OOMContainerEvent:
+			state.oom[event.Container] = true
+		case msg.Action == entity.DieContainerEvent:
+			event.ExitCode, _ = strconv.Atoi(msg.Actor.Attributes["exitCode"])
+			event.OOMKilled = state.oom[event.Container]
+			delete(state.oom, event.Container)
+			if state.killed[event.Container] {
+				delete(state.killed, event.Container)
+				continue
+			}
+		case strings.HasPrefix(msg.Action, entity.HealthContainerEvent):
+			event.Action = entity.HealthContainerEvent
+			event.Health = strings.TrimSpace(strings.TrimPrefix(msg.Action, entity.HealthContainerEvent+":"))
+		default:
+			continue
+		}
+		ew.handle(test.ctx, cli, test, event)
+	}
+}
+
+// handle reports the event, then acts on it by the policy of the test if it is a crash
+func (ew *eventWatcher) handle(ctx context.Context, cli entity.Client, test *watchedTest,
+	event entity.ContainerEvent) {
+
+	ew.publish(event)
+	if !event.Crashed() {
+		return
+	}
+	entry := ew.log.WithFields(logrus.Fields{"event": event, "policy": test.policy})
+	entry.Warn("a container of a test crashed")
+
+	switch test.policy {
+	case entity.FailEventPolicy:
+		ew.fail(test, event)
+	case entity.RestartEventPolicy:
+		ew.mux.Lock()
+		restarts := test.restarts[event.Container]
+		if restarts < ew.restartLimit {
+			test.restarts[event.Container]++
+		}
+		ew.mux.Unlock()
+		if restarts >= ew.restartLimit {
+			entry.Error("the container crashed too many times, failing the test")
+			ew.fail(test, event)
+			return
+		}
+		err := ew.restart(ctx, cli, event)
+		if err != nil {
+			entry.WithField("error", err).Error("failed to restart the container, failing the test")
+			ew.fail(test, event)
+			return
+		}
+		ew.publish(entity.ContainerEvent{TestID: event.TestID, Host: event.Host,
+			Container: event.Container, Action: entity.RestartContainerEvent, Time: time.Now(),
+			Restarts: restarts + 1})
+	}
+}
+
+// restart starts the crashed container again, an unhealthy one is stopped first
+func (ew *eventWatcher) restart(ctx context.Context, cli entity.Client, event entity.ContainerEvent) error {
+	if event.Action == entity.HealthContainerEvent {
+		err := cli.ContainerStop(ctx, event.Container, nil)
+		if err != nil {
+			return err
+		}
+	}
+	return cli.ContainerStart(ctx, event.Container, types.ContainerStartOptions{})
+}
+
+// fail fails the test with the crash, unless it already failed
+func (ew *eventWatcher) fail(test *watchedTest, event entity.ContainerEvent) {
+	ew.mux.Lock()
+	defer ew.mux.Unlock()
+	if test.failure == nil {
+		test.failure = &event
+	}
+}
+
+func (ew *eventWatcher) publish(event entity.ContainerEvent) {
+	if ew.publisher == nil {
+		return
+	}
+	err := ew.publisher.Publish(event)
+	if err != nil {
+		ew.log.WithFields(logrus.Fields{"event": event, "error": err}).Error("failed to publish the event")
+	}
+}
@@ -0,0 +1,192 @@
+/**
+ * Copyright 2019 Whiteblock Inc. All rights reserved.
+ * Use of this source code is governed by a BSD-style
+ * license that can be found in the LICENSE file.
+ */
+
+package service
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/whiteblock/genesis/pkg/entity"
+	"github.com/whiteblock/genesis/pkg/fake"
+
+	"github.com/docker/docker/api/types"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testEventPublisher struct {
+	mux    *sync.Mutex
+	events []entity.ContainerEvent
+}
+
+func (tep *testEventPublisher) Publish(event entity.ContainerEvent) error {
+	tep.mux.Lock()
+	defer tep.mux.Unlock()
+	tep.events = append(tep.events, event)
+	return nil
+}
+
+// actions gives the actions of the events of the container, in order
+func (tep *testEventPublisher) actions(container string) []string {
+	tep.mux.Lock()
+	defer tep.mux.Unlock()
+	out := []string{}
+	for _, event := range tep.events {
+		if event.Container == container {
+			out = append(out, event.Action)
+		}
+	}
+	return out
+}
+
+// watchedHost gives an engine with the running container node0 of the test, which is watched
+// with the policy
+func watchedHost(t *testing.T, policy string) (EventWatcher, *fake.Engine, *testEventPublisher) {
+	_, engines := swarmHosts(t, "10.0.0.2")
+	statsContainer(t, engines["10.0.0.2"], "node0", "test", 0)
+	publisher := &testEventPublisher{mux: &sync.Mutex{}}
+	watcher := NewEventWatcher(1, statsEngines(engines), publisher, logrus.New())
+	watcher.Watch("test", "10.0.0.2", policy, time.Now())
+	select {
+	case <-engines["10.0.0.2"].Subscribed(1):
+	case <-time.After(5 * time.Second):
+		t.Fatal("the events of the host were never followed")
+	}
+	return watcher, engines["10.0.0.2"], publisher
+}
+
+func TestEventWatcher_Notify(t *testing.T) {
+	watcher, engine, publisher := watchedHost(t, entity.NotifyEventPolicy)
+	defer watcher.Stop("test")
+
+	require.NoError(t, engine.SetHealth("node0", "healthy"))
+	require.NoError(t, engine.ContainerStop(context.Background(), "node0", nil))
+	require.NoError(t, engine.ContainerStart(context.Background(), "node0", types.ContainerStartOptions{}))
+	require.NoError(t, engine.Crash("node0", 137, true))
+
+	require.Eventually(t, func() bool { return len(publisher.actions("node0")) == 3 }, time.Second,
+		time.Millisecond)
+	assert.Equal(t, []string{entity.HealthContainerEvent, entity.OOMContainerEvent, entity.DieContainerEvent},
+		publisher.actions("node0"), "the container being stopped is not reported")
+	publisher.mux.Lock()
+	assert.Equal(t, "healthy", publisher.events[0].Health)
+	assert.True(t, publisher.events[2].OOMKilled)
+	assert.Equal(t, 137, publisher.events[2].ExitCode)
+	assert.Equal(t, "10.0.0.2", publisher.events[2].Host)
+	publisher.mux.Unlock()
+	assert.Nil(t, watcher.Failure("test"))
+}
+
+func TestEventWatcher_Fail(t *testing.T) {
+	watcher, engine, _ := watchedHost(t, entity.FailEventPolicy)
+	defer watcher.Stop("test")
+
+	require.NoError(t, engine.Crash("node0", 2, false))
+	require.Eventually(t, func() bool { return watcher.Failure("test") != nil }, time.Second,
+		time.Millisecond)
+	failure := watcher.Failure("test")
+	assert.Equal(t, "node0", failure.Container)
+	assert.Equal(t, 2, failure.ExitCode)
+	assert.Equal(t, "container node0 on 10.0.0.2 exited with code 2", failure.Error())
+
+	watcher.Stop("test")
+	assert.Nil(t, watcher.Failure("test"))
+}
+
+func TestEventWatcher_Helper(t *testing.T) {
+	watcher, engine, publisher := watchedHost(t, entity.FailEventPolicy)
+	defer watcher.Stop("test")
+
+	helperContainer(t, engine, "volume-helper-test-data", "test")
+	require.NoError(t, engine.Crash("volume-helper-test-data", 2, false))
+	require.NoError(t, engine.Crash("node0", 1, false))
+	require.Eventually(t, func() bool { return watcher.Failure("test") != nil }, time.Second,
+		time.Millisecond)
+	assert.Equal(t, "node0", watcher.Failure("test").Container, "a helper is not a node of the test")
+	assert.Empty(t, publisher.actions("volume-helper-test-data"))
+}
+
+func TestEventWatcher_Restart(t *testing.T) {
+	watcher, engine, publisher := watchedHost(t, entity.RestartEventPolicy)
+	defer watcher.Stop("test")
+
+	require.NoError(t, engine.SetHealth("node0", "unhealthy"))
+	require.Eventually(t, func() bool { return len(publisher.actions("node0")) == 2 }, time.Second,
+		time.Millisecond)
+	assert.Equal(t, []string{entity.HealthContainerEvent, entity.RestartContainerEvent},
+		publisher.actions("node0"))
+	info, err := engine.ContainerInspect(context.Background(), "node0")
+	require.NoError(t, err)
+	assert.True(t, info.State.Running)
+	assert.Nil(t, watcher.Failure("test"))
+
+	require.NoError(t, engine.Crash("node0", 1, false))
+	require.Eventually(t, func() bool { return watcher.Failure("test") != nil }, time.Second,
+		time.Millisecond, "the test fails once the container is restarted too many times")
+	assert.Equal(t, entity.DieContainerEvent, watcher.Failure("test").Action)
+}
+
+func TestEventWatcher_Since(t *testing.T) {
+	_, engines := swarmHosts(t, "10.0.0.2")
+	engine := engines["10.0.0.2"]
+	statsContainer(t, engine, "node0", "test", 0)
+	statsContainer(t, engine, "node1", "test", 0)
+	require.NoError(t, engine.Crash("node0", 1, false))
+	started := time.Now()
+	require.NoError(t, engine.Crash("node1", 2, false))
+
+	watcher := NewEventWatcher(1, statsEngines(engines), nil, logrus.New())
+	defer watcher.Stop("test")
+	watcher.Watch("test", "10.0.0.2", entity.FailEventPolicy, started)
+	require.Eventually(t, func() bool { return watcher.Failure("test") != nil }, time.Second,
+		time.Millisecond, "a crash before the events are followed is not missed")
+	assert.Equal(t, "node1", watcher.Failure("test").Container)
+}
+
+func TestEventWatcher_Reconnect(t *testing.T) {
+	_, engines := swarmHosts(t, "10.0.0.2")
+	engine := engines["10.0.0.2"]
+	statsContainer(t, engine, "node0", "test", 0)
+	publisher := &testEventPublisher{mux: &sync.Mutex{}}
+	watcher := NewEventWatcher(1, statsEngines(engines), publisher, logrus.New())
+	watcher.(*eventWatcher).retryDelay = 50 * time.Millisecond
+	defer watcher.Stop("test")
+	watcher.Watch("test", "10.0.0.2", entity.FailEventPolicy, time.Now())
+	<-engine.Subscribed(1)
+
+	require.NoError(t, engine.SetHealth("node0", "healthy"))
+	require.Eventually(t, func() bool { return len(publisher.actions("node0")) == 1 }, time.Second,
+		time.Millisecond)
+	engine.BreakEvents()
+	require.NoError(t, engine.ContainerStop(context.Background(), "node0", nil))
+	require.NoError(t, engine.ContainerStart(context.Background(), "node0", types.ContainerStartOptions{}))
+	require.NoError(t, engine.Crash("node0", 3, false))
+
+	require.Eventually(t, func() bool { return watcher.Failure("test") != nil }, time.Second,
+		time.Millisecond, "the crash while the host was not reachable is seen")
+	assert.Equal(t, 3, watcher.Failure("test").ExitCode, "the container being stopped is not a crash")
+	assert.Equal(t, []string{entity.HealthContainerEvent, entity.DieContainerEvent},
+		publisher.actions("node0"), "the events seen before are not seen again")
+}
+
+func TestEventWatcher_NotSupported(t *testing.T) {
+	connected := 0
+	mux := &sync.Mutex{}
+	watcher := NewEventWatcher(1, func(string, string) (entity.Client, error) {
+		mux.Lock()
+		defer mux.Unlock()
+		connected++
+		return nil, entity.NotSupportedError{Runtime: entity.ContainerdRuntime, Operation: "events"}
+	}, nil, logrus.New())
+	watcher.Watch("test", "10.0.0.2", entity.FailEventPolicy, time.Now())
+	watcher.Stop("test") // waits for the host to be given up on
+
+	assert.Equal(t, 1, connected, "the host is not connected to again")
+}
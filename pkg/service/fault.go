@@ -0,0 +1,157 @@
+/**
+ * Copyright 2019 Whiteblock Inc. All rights reserved.
+ * Use of this source code is governed by a BSD-style
+ * license that can be found in the LICENSE file.
+ */
+
+package service
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/whiteblock/genesis/pkg/entity"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/sirupsen/logrus"
+	"github.com/whiteblock/utility/utils"
+)
+
+// stressArgs converts the stress into the command line of stress-ng
+func stressArgs(st entity.Stress) []string {
+	args := []string{"stress-ng"}
+	if st.CPU > 0 {
+		args = append(args, "--cpu", strconv.Itoa(st.CPU))
+	}
+	if st.IO > 0 {
+		args = append(args, "--io", strconv.Itoa(st.IO))
+	}
+	if st.HDD > 0 {
+		args = append(args, "--hdd", strconv.Itoa(st.HDD))
+		if len(st.HDDBytes) > 0 {
+			args = append(args, "--hdd-bytes", st.HDDBytes)
+		}
+	}
+	if len(st.Path) > 0 {
+		args = append(args, "--temp-path", st.Path)
+	}
+	return append(args, "--timeout", fmt.Sprintf("%ds", int64(st.Duration.Seconds())))
+}
+
+// PauseContainer freezes all of the processes of the container
+func (ds dockerService) PauseContainer(ctx context.Context, cli entity.DockerCli, name string) entity.Result {
+	ds.withField(cli, "container", name).Info("pausing a container")
+	return entity.NewResult(cli.ContainerPause(ctx, name)).InjectMeta(map[string]interface{}{
+		"container": name,
+	})
+}
+
+// UnpauseContainer resumes the processes of a paused container
+func (ds dockerService) UnpauseContainer(ctx context.Context, cli entity.DockerCli, name string) entity.Result {
+	ds.withField(cli, "container", name).Info("unpausing a container")
+	return entity.NewResult(cli.ContainerUnpause(ctx, name)).InjectMeta(map[string]interface{}{
+		"container": name,
+	})
+}
+
+// KillContainer sends the signal to the main process of the container
+func (ds dockerService) KillContainer(ctx context.Context, cli entity.DockerCli,
+	kc entity.KillContainer) entity.Result {
+
+	ds.withFields(cli, logrus.Fields{
+		"container": kc.Name,
+		"signal":    kc.Signal,
+	}).Info("killing a container")
+	return entity.NewResult(cli.ContainerKill(ctx, kc.Name, kc.Signal)).InjectMeta(map[string]interface{}{
+		"container": kc.Name,
+		"signal":    kc.Signal,
+	})
+}
+
+// RestartContainer stops the container, and then starts it again once the delay has passed
+func (ds dockerService) RestartContainer(ctx context.Context, cli entity.DockerCli,
+	rc entity.RestartContainer) entity.Result {
+
+	meta := map[string]interface{}{
+		"container": rc.Name,
+		"delay":     rc.Delay.String(),
+	}
+	var timeout *time.Duration
+	if !rc.Timeout.Empty() {
+		timeout = &rc.Timeout.Duration
+	}
+	err := cli.ContainerStop(ctx, rc.Name, timeout)
+	if err != nil {
+		return entity.NewErrorResult(err).InjectMeta(meta)
+	}
+	ds.withFields(cli, logrus.Fields{
+		"container": rc.Name,
+		"delay":     rc.Delay.String(),
+	}).Info("stopped a container, waiting to start it again")
+
+	timer := time.NewTimer(rc.Delay.Duration)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+	case <-ctx.Done():
+		return entity.NewErrorResult(ctx.Err()).InjectMeta(meta)
+	}
+	err = cli.ContainerStart(ctx, rc.Name, types.ContainerStartOptions{})
+	return entity.NewResult(err).InjectMeta(meta)
+}
+
+// UpdateContainer changes the resource limits of the container while it is running
+func (ds dockerService) UpdateContainer(ctx context.Context, cli entity.DockerCli,
+	ur entity.UpdateResources) entity.Result {
+
+	var update container.UpdateConfig
+	if len(ur.Cpus) > 0 {
+		cpus, err := strconv.ParseFloat(ur.Cpus, 64)
+		if err != nil {
+			return entity.NewFatalResult(err).InjectMeta(map[string]interface{}{
+				"given": ur.Cpus,
+			})
+		}
+		update.NanoCPUs = int64(1000000000 * cpus)
+	}
+	if len(ur.Memory) > 0 {
+		mem, err := utils.Memconv(ur.Memory, utils.Mibi)
+		if err != nil {
+			return entity.NewFatalResult(err).InjectMeta(map[string]interface{}{
+				"given": ur.Memory,
+			})
+		}
+		update.Memory = mem
+		// docker gives twice the memory as swap on create, it has to move along with the
+		// memory, or raising the memory above the old swap limit fails
+		update.MemorySwap = 2 * mem
+	}
+
+	res, err := cli.ContainerUpdate(ctx, ur.Name, update)
+	return entity.NewResult(err).InjectMeta(map[string]interface{}{
+		"container": ur.Name,
+		"warnings":  res.Warnings,
+	})
+}
+
+// StressContainer puts load on the container, from a side car which shares its namespaces.
+// It returns once the load is over.
+func (ds dockerService) StressContainer(ctx context.Context, cli entity.DockerCli,
+	st entity.Stress) entity.Result {
+
+	_, err := ds.runSidecar(ctx, cli, sidecar{
+		Name:         st.Container + "-stress",
+		Image:        ds.conf.StressImage,
+		Target:       st.Container,
+		Cmd:          stressArgs(st),
+		SharePID:     true,
+		ShareVolumes: len(st.Path) > 0,
+	})
+	return entity.NewResult(err).InjectMeta(map[string]interface{}{
+		"container": st.Container,
+		"duration":  st.Duration.String(),
+	})
+}
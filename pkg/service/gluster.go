@@ -0,0 +1,549 @@
+/**
+ * Copyright 2019 Whiteblock Inc. All rights reserved.
+ * Use of this source code is governed by a BSD-style
+ * license that can be found in the LICENSE file.
+ */
+
+package service
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/whiteblock/genesis/pkg/entity"
+
+	"github.com/sirupsen/logrus"
+	"github.com/whiteblock/definition/command"
+)
+
+// glusterVolumes shares global volumes by replicating them across the hosts with glusterfs
+type glusterVolumes struct {
+	dockerService
+}
+
+// glusterOutput is what the gluster cli gives back when run with --xml
+type glusterOutput struct {
+	OpRet    int                   `xml:"opRet"`
+	OpErrstr string                `xml:"opErrstr"`
+	Volumes  []glusterVolumeInfo   `xml:"volInfo>volumes>volume"`
+	Status   []glusterVolumeStatus `xml:"volStatus>volumes>volume"`
+}
+
+type glusterVolumeInfo struct {
+	Name     string   `xml:"name"`
+	Status   string   `xml:"statusStr"`
+	Replicas int      `xml:"replicaCount"`
+	Bricks   []string `xml:"bricks>brick>name"`
+	Options  []struct {
+		Name  string `xml:"name"`
+		Value string `xml:"value"`
+	} `xml:"options>option"`
+}
+
+// option gives the value the option is set to on the volume
+func (info glusterVolumeInfo) option(name string) string {
+	for _, opt := range info.Options {
+		if opt.Name == name {
+			return opt.Value
+		}
+	}
+	return ""
+}
+
+type glusterVolumeStatus struct {
+	Nodes []struct {
+		Hostname  string `xml:"hostname"`
+		Path      string `xml:"path"`
+		Status    int    `xml:"status"`
+		Port      string `xml:"port"`
+		Pid       int    `xml:"pid"`
+		SizeTotal uint64 `xml:"sizeTotal"`
+		SizeFree  uint64 `xml:"sizeFree"`
+		Device    string `xml:"device"`
+		FsName    string `xml:"fsName"`
+	} `xml:"node"`
+}
+
+// glusterCmd is the gluster command with the given arguments, which never asks for confirmation
+func glusterCmd(args ...string) []string {
+	return append([]string{"gluster", "--mode=script"}, args...)
+}
+
+// brickDir is the directory which holds the brick of the volume on each host
+func brickDir(name string) string {
+	return fmt.Sprintf("/var/bricks/%s", name)
+}
+
+func (ds dockerService) glusterSpec() entity.ContainerSpec {
+	return entity.ContainerSpec{
+		Name:        GlusterContainerName,
+		Hostname:    GlusterContainerName,
+		Domainname:  GlusterContainerName,
+		Image:       ds.conf.GlusterImage,
+		Entrypoint:  []string{"glusterd", "--no-daemon"},
+		AutoRemove:  true,
+		NetworkMode: "host",
+		CapAdd:      []string{"NET_ADMIN", "SYS_ADMIN"},
+	}
+}
+
+// hostName is the name the gluster peers know the host by. It is derived from the address of
+// the host, so that it stays the same when hosts are added to or removed from the share.
+func (ds dockerService) hostName(ecli entity.DockerCli, host string) string {
+	return fmt.Sprintf("biome-%s-%s", ecli.Labels[command.TestIDKey],
+		strings.NewReplacer(".", "-", ":", "-").Replace(host))
+}
+
+func (ds dockerService) gluster(ctx context.Context, cli entity.Client, retries int, args ...string) error {
+	return ds.repo.Exec(ctx, cli, GlusterContainerName, entity.Exec{
+		Cmd:        glusterCmd(args...),
+		Privileged: true,
+		Retries:    retries,
+	})
+}
+
+// glusterXML runs the gluster command with xml output, and parses what it gives back
+func (ds dockerService) glusterXML(ctx context.Context, cli entity.Client, args ...string) (glusterOutput, error) {
+	var out glusterOutput
+	data, err := ds.repo.ExecOutput(ctx, cli, GlusterContainerName, entity.Exec{
+		Cmd:        glusterCmd(append(args, "--xml")...),
+		Privileged: true,
+	})
+	if err != nil {
+		return out, err
+	}
+	err = xml.Unmarshal(data, &out)
+	if err != nil {
+		return out, err
+	}
+	if out.OpRet != 0 {
+		return out, errors.New(out.OpErrstr)
+	}
+	return out, nil
+}
+
+func (ds dockerService) volumeInfo(ctx context.Context, cli entity.Client, name string) (glusterVolumeInfo, error) {
+	out, err := ds.glusterXML(ctx, cli, "volume", "info", name)
+	if err != nil {
+		return glusterVolumeInfo{}, err
+	}
+	if len(out.Volumes) == 0 {
+		return glusterVolumeInfo{}, fmt.Errorf("volume %s does not exist", name)
+	}
+	return out.Volumes[0], nil
+}
+
+// glusterVolume gathers the state of the volume and of each of its bricks
+func (ds dockerService) glusterVolume(ctx context.Context, cli entity.Client, name string) (entity.GlusterVolume, error) {
+	info, err := ds.volumeInfo(ctx, cli, name)
+	if err != nil {
+		return entity.GlusterVolume{}, err
+	}
+	out := entity.GlusterVolume{
+		Name:     info.Name,
+		Status:   info.Status,
+		Replicas: info.Replicas,
+		Bricks:   []entity.GlusterBrick{},
+	}
+	for _, addr := range strings.Split(info.option("auth.allow"), ",") {
+		if len(addr) > 0 && addr != "127.0.0.1" {
+			out.Allowed = append(out.Allowed, addr)
+		}
+	}
+	for _, brick := range info.Bricks {
+		parts := strings.SplitN(brick, ":", 2)
+		if len(parts) != 2 {
+			return out, fmt.Errorf("unexpected brick \"%s\"", brick)
+		}
+		out.Bricks = append(out.Bricks, entity.GlusterBrick{Host: parts[0], Path: parts[1]})
+	}
+	if info.Status != "Started" {
+		return out, nil // the bricks of a volume which is not started are all offline
+	}
+
+	status, err := ds.glusterXML(ctx, cli, "volume", "status", name, "detail")
+	if err != nil {
+		return out, err
+	}
+	if len(status.Status) == 0 {
+		return out, nil
+	}
+	for _, node := range status.Status[0].Nodes {
+		for i := range out.Bricks {
+			brick := &out.Bricks[i]
+			if brick.Host != node.Hostname || brick.Path != node.Path {
+				continue
+			}
+			brick.Online = node.Status == 1
+			brick.Port, _ = strconv.Atoi(node.Port)
+			if node.Pid > 0 {
+				brick.Pid = node.Pid
+			}
+			brick.SizeTotal = node.SizeTotal
+			brick.SizeFree = node.SizeFree
+			brick.Device = node.Device
+			brick.FsName = node.FsName
+		}
+	}
+	return out, nil
+}
+
+// Create creates a gluster volume with a replica on each of the hosts, and a docker volume on
+// each host which mounts it. A volume which already exists is left as it is.
+func (gv glusterVolumes) Create(ctx context.Context, ecli entity.DockerCli,
+	vol command.Volume) entity.Result {
+
+	clients, err := gv.hostClients(ecli, vol.Hosts)
+	if err != nil {
+		return entity.NewErrorResult(err)
+	}
+	defer closeClients(clients)
+
+	dir := brickDir(vol.Name)
+	err = forEachHost(vol.Hosts, func(i int, _ string) error { //create the directory for the gluster bricks
+		return gv.repo.Exec(ctx, clients[i], GlusterContainerName, entity.Exec{
+			Cmd:        []string{"mkdir", "-p", dir},
+			Privileged: true,
+			Retries:    5,
+		})
+	})
+	if err != nil {
+		return entity.NewErrorResult(err)
+	}
+
+	info, err := gv.volumeInfo(ctx, clients[0], vol.Name)
+	if err != nil {
+		args := []string{"volume", "create", vol.Name, "replica", fmt.Sprint(len(vol.Hosts))}
+		for _, host := range vol.Hosts {
+			args = append(args, fmt.Sprintf("%s:%s", gv.hostName(ecli, host), dir))
+		}
+		args = append(args, "force") //needed because it wants a separate partition by default
+
+		err = gv.gluster(ctx, clients[0], 5, args...) //create the replica volume
+		if err != nil {
+			return entity.NewErrorResult(err)
+		}
+	}
+
+	if info.Status != "Started" {
+		err = gv.gluster(ctx, clients[0], 5, "volume", "start", vol.Name)
+		if err != nil {
+			return entity.NewErrorResult(err)
+		}
+	}
+
+	err = gv.gluster(ctx, clients[0], 5, "volume", "set", vol.Name, "ctime", "off") //compatibility
+	if err != nil {
+		return entity.NewErrorResult(err)
+	}
+
+	err = gv.gluster(ctx, clients[0], 5, "volume", "set", vol.Name, "auth.allow",
+		strings.Join(vol.Hosts, ",")+",127.0.0.1") // restrict access by ip
+	if err != nil {
+		return entity.NewErrorResult(err)
+	}
+
+	err = forEachHost(vol.Hosts, func(i int, host string) error {
+		return gv.mountGlobalVolume(ctx, ecli, clients[i], host, vol.Name)
+	})
+	return entity.NewResult(err)
+}
+
+// mountGlobalVolume creates the docker volume which mounts the gluster volume on the host
+func (ds dockerService) mountGlobalVolume(ctx context.Context, ecli entity.DockerCli,
+	cli entity.Client, host string, name string) error {
+
+	return engineOf(cli).CreateVolume(ctx, entity.VolumeSpec{
+		Name:   name,
+		Driver: ds.conf.GlusterDriver,
+		Options: map[string]string{
+			"glusteropts": fmt.Sprintf("--volfile-server=%s --volfile-id=/%s", ds.hostName(ecli, host), name),
+		},
+	})
+}
+
+// removeBricks deletes what the bricks of the volume held on the host
+func (ds dockerService) removeBricks(ctx context.Context, cli entity.Client, name string) error {
+	return ds.repo.Exec(ctx, cli, GlusterContainerName, entity.Exec{
+		Cmd:        []string{"rm", "-rf", brickDir(name)},
+		Privileged: true,
+		Retries:    2,
+	})
+}
+
+// Remove removes the docker volumes on the hosts, then stops and deletes the gluster volume
+// behind them and removes its bricks. Whatever is already gone is skipped.
+func (gv glusterVolumes) Remove(ctx context.Context, ecli entity.DockerCli,
+	vol command.Volume) entity.Result {
+
+	clients, err := gv.hostClients(ecli, vol.Hosts)
+	if err != nil {
+		return entity.NewErrorResult(err)
+	}
+	defer closeClients(clients)
+
+	err = forEachHost(vol.Hosts, func(i int, _ string) error {
+		return gv.unmountGlobalVolume(ctx, clients[i], vol.Name)
+	})
+	if err != nil {
+		return entity.NewErrorResult(err)
+	}
+
+	info, err := gv.volumeInfo(ctx, clients[0], vol.Name)
+	if err == nil {
+		if info.Status == "Started" {
+			err = gv.gluster(ctx, clients[0], 5, "volume", "stop", vol.Name)
+			if err != nil {
+				return entity.NewErrorResult(err)
+			}
+		}
+		err = gv.gluster(ctx, clients[0], 5, "volume", "delete", vol.Name)
+		if err != nil {
+			return entity.NewErrorResult(err)
+		}
+		gv.withField(ecli, "volume", vol.Name).Info("deleted the gluster volume")
+	}
+
+	err = forEachHost(vol.Hosts, func(i int, _ string) error {
+		return gv.removeBricks(ctx, clients[i], vol.Name)
+	})
+	return entity.NewResult(err)
+}
+
+// Resize puts new replicas of the volume on the hosts to add, and takes them off of the hosts to
+// remove. The gluster commands run on the target of the command.
+func (gv glusterVolumes) Resize(ctx context.Context, ecli entity.DockerCli,
+	resize entity.VolumeResize) entity.Result {
+
+	info, err := gv.volumeInfo(ctx, ecli.Client, resize.Name)
+	if err != nil {
+		return entity.NewErrorResult(err)
+	}
+
+	dir := brickDir(resize.Name)
+	hasBrick := func(host string) bool {
+		for _, brick := range info.Bricks {
+			if brick == gv.hostName(ecli, host)+":"+dir {
+				return true
+			}
+		}
+		return false
+	}
+	add, remove := []string{}, []string{}
+	for _, host := range resize.Add {
+		if !hasBrick(host) && !containsHost(add, host) {
+			add = append(add, host)
+		}
+	}
+	for _, host := range resize.Remove {
+		if hasBrick(host) && !containsHost(remove, host) {
+			remove = append(remove, host)
+		}
+	}
+	if len(remove) >= len(info.Bricks)+len(add) {
+		return entity.NewFatalResult("cannot remove every replica of the volume, remove the volume instead")
+	}
+
+	addClients, err := gv.hostClients(ecli, add)
+	if err != nil {
+		return entity.NewErrorResult(err)
+	}
+	defer closeClients(addClients)
+	removeClients, err := gv.hostClients(ecli, remove)
+	if err != nil {
+		return entity.NewErrorResult(err)
+	}
+	defer closeClients(removeClients)
+
+	replicas := info.Replicas
+	if len(add) > 0 {
+		err = forEachHost(add, func(i int, _ string) error {
+			return gv.repo.Exec(ctx, addClients[i], GlusterContainerName, entity.Exec{
+				Cmd:        []string{"mkdir", "-p", dir},
+				Privileged: true,
+				Retries:    5,
+			})
+		})
+		if err != nil {
+			return entity.NewErrorResult(err)
+		}
+		replicas += len(add)
+		args := []string{"volume", "add-brick", resize.Name, "replica", fmt.Sprint(replicas)}
+		for _, host := range add {
+			args = append(args, gv.hostName(ecli, host)+":"+dir)
+		}
+		err = gv.gluster(ctx, ecli.Client, 5, append(args, "force")...)
+		if err != nil {
+			return entity.NewErrorResult(err)
+		}
+	}
+
+	if len(remove) > 0 {
+		err = forEachHost(remove, func(i int, _ string) error {
+			return gv.unmountGlobalVolume(ctx, removeClients[i], resize.Name)
+		})
+		if err != nil {
+			return entity.NewErrorResult(err)
+		}
+		replicas -= len(remove)
+		args := []string{"volume", "remove-brick", resize.Name, "replica", fmt.Sprint(replicas)}
+		for _, host := range remove {
+			args = append(args, gv.hostName(ecli, host)+":"+dir)
+		}
+		err = gv.gluster(ctx, ecli.Client, 5, append(args, "force")...)
+		if err != nil {
+			return entity.NewErrorResult(err)
+		}
+		err = forEachHost(remove, func(i int, _ string) error {
+			return gv.removeBricks(ctx, removeClients[i], resize.Name)
+		})
+		if err != nil {
+			return entity.NewErrorResult(err)
+		}
+	}
+
+	allowed := []string{}
+	for _, addr := range strings.Split(info.option("auth.allow"), ",") {
+		if len(addr) > 0 && !containsHost(remove, addr) && !containsHost(allowed, addr) {
+			allowed = append(allowed, addr)
+		}
+	}
+	for _, host := range add {
+		if !containsHost(allowed, host) {
+			allowed = append(allowed, host)
+		}
+	}
+	err = gv.gluster(ctx, ecli.Client, 5, "volume", "set", resize.Name, "auth.allow",
+		strings.Join(allowed, ","))
+	if err != nil {
+		return entity.NewErrorResult(err)
+	}
+
+	err = forEachHost(add, func(i int, host string) error {
+		return gv.mountGlobalVolume(ctx, ecli, addClients[i], host, resize.Name)
+	})
+	if err != nil {
+		return entity.NewErrorResult(err)
+	}
+	gv.withFields(ecli, logrus.Fields{
+		"volume":   resize.Name,
+		"added":    add,
+		"removed":  remove,
+		"replicas": replicas,
+	}).Info("resized the gluster volume")
+	return gv.Status(ctx, ecli, command.Volume{Name: resize.Name})
+}
+
+// Status gives the state of the gluster volume, and of each of its bricks
+func (gv glusterVolumes) Status(ctx context.Context, cli entity.DockerCli, vol command.Volume) entity.Result {
+	status, err := gv.glusterVolume(ctx, cli.Client, vol.Name)
+	if err != nil {
+		return entity.NewErrorResult(err)
+	}
+	return entity.NewSuccessResult().InjectMeta(map[string]interface{}{"volume": status})
+}
+
+// Share starts a gluster container on each of the hosts, lets them reach each other by
+// their host names and makes the first host probe the others into its pool. It can be given
+// the same hosts again, or more of them, to bring new hosts into the pool.
+func (gv glusterVolumes) Share(ctx context.Context, ecli entity.DockerCli,
+	vs command.VolumeShare) entity.Result {
+	clients, err := gv.hostClients(ecli, vs.Hosts)
+	if err != nil {
+		return entity.NewErrorResult(err)
+	}
+	defer closeClients(clients)
+
+	err = forEachHost(vs.Hosts, func(i int, _ string) error {
+		return gv.repo.EnsureImagePulled(ctx, clients[i], gv.conf.GlusterImage, command.Credentials{})
+	})
+	if err != nil {
+		return entity.NewErrorResult(err)
+	}
+
+	spec := gv.glusterSpec()
+	err = forEachHost(vs.Hosts, func(i int, _ string) error {
+		err := engineOf(clients[i]).CreateContainer(ctx, spec)
+		if errors.Is(err, entity.ErrAlreadyExists) {
+			return nil // it is already there from an earlier volume share
+		}
+		return err
+	})
+	if err != nil {
+		return entity.NewErrorResult(err).InjectMeta(map[string]interface{}{"type": "CreateContainer"})
+	}
+
+	err = forEachHost(vs.Hosts, func(i int, _ string) error {
+		return engineOf(clients[i]).StartContainer(ctx, GlusterContainerName)
+	})
+	if err != nil {
+		return entity.NewErrorResult(err).InjectMeta(map[string]interface{}{"type": "StartContainer"})
+	}
+
+	err = forEachHost(vs.Hosts, func(i int, _ string) error {
+		for j, host := range vs.Hosts {
+			addr := host
+			if i == j {
+				addr = "127.0.0.1"
+			}
+			err := gv.repo.Exec(ctx, clients[i], GlusterContainerName, entity.Exec{
+				Cmd: []string{"bash", "-c", fmt.Sprintf(
+					`grep -q " %[2]s$" /etc/hosts || echo "%[1]s  %[2]s" >> /etc/hosts`,
+					addr, gv.hostName(ecli, host))},
+				Privileged: true,
+				Retries:    2,
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return entity.NewErrorResult(err).InjectMeta(map[string]interface{}{"type": "Exec"})
+	}
+
+	err = forEachHost(vs.Hosts[1:], func(_ int, host string) error {
+		return gv.repo.Exec(ctx, clients[0], GlusterContainerName, entity.Exec{
+			Cmd:        glusterCmd("peer", "probe", gv.hostName(ecli, host)),
+			Privileged: true,
+			Retries:    20,
+			Delay:      100 * time.Millisecond,
+		})
+	})
+	if err != nil {
+		return entity.NewErrorResult(err).InjectMeta(map[string]interface{}{"type": "Exec"})
+	}
+	return entity.NewSuccessResult()
+}
+
+// Unshare detaches the other hosts from the pool of the first host, then removes the
+// gluster containers, which takes the state of the pool with them. The volumes on the hosts
+// should be removed first, as gluster will not detach a host which still holds bricks.
+func (gv glusterVolumes) Unshare(ctx context.Context, ecli entity.DockerCli,
+	vs command.VolumeShare) entity.Result {
+
+	clients, err := gv.hostClients(ecli, vs.Hosts)
+	if err != nil {
+		return entity.NewErrorResult(err)
+	}
+	defer closeClients(clients)
+
+	for _, host := range vs.Hosts[1:] {
+		err = gv.gluster(ctx, clients[0], 0, "peer", "detach", gv.hostName(ecli, host))
+		if err != nil {
+			gv.withFields(ecli, logrus.Fields{"host": host, "error": err}).Warn(
+				"failed to detach a gluster peer, its container will be removed anyway")
+		}
+	}
+
+	err = forEachHost(vs.Hosts, func(i int, _ string) error {
+		return gv.removeSideCar(ctx, clients[i], GlusterContainerName)
+	})
+	return entity.NewResult(err)
+}
@@ -0,0 +1,132 @@
+/**
+ * Copyright 2019 Whiteblock Inc. All rights reserved.
+ * Use of this source code is governed by a BSD-style
+ * license that can be found in the LICENSE file.
+ */
+
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/whiteblock/genesis/pkg/entity"
+	"github.com/whiteblock/genesis/pkg/fake"
+	"github.com/whiteblock/genesis/pkg/repository"
+
+	"github.com/docker/docker/api/types/filters"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/whiteblock/definition/command"
+)
+
+// glusterHosts is swarmHosts, with the gluster commands of the engines run by one pool
+func glusterHosts(t *testing.T, hosts ...string) (dockerService, map[string]*fake.Engine, *fake.Gluster) {
+	serv, engines := swarmHosts(t, hosts...)
+	ds := serv.(dockerService)
+	ds.repo = repository.NewDockerRepository(logrus.New())
+	ds.conf.GlusterImage = "gluster/gluster-centos"
+	ds.conf.GlusterDriver = "glusterfs"
+
+	pool := fake.NewGluster()
+	for _, engine := range engines {
+		pool.Serve(engine)
+	}
+	return ds, engines, pool
+}
+
+func hasVolume(t *testing.T, engine *fake.Engine, name string) bool {
+	vols, err := engine.VolumeList(context.Background(), filters.NewArgs())
+	require.NoError(t, err)
+	for _, vol := range vols.Volumes {
+		if vol.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func TestDockerService_GlusterLifecycle(t *testing.T) {
+	hosts := []string{"10.0.0.2", "10.0.0.3", "10.0.0.4", "10.0.0.5"}
+	ds, engines, pool := glusterHosts(t, hosts...)
+	cli := entity.DockerCli{Client: engines["10.0.0.2"], TestID: "test", IP: "10.0.0.2",
+		Labels: map[string]string{command.TestIDKey: "test"}}
+	brick := func(host string) string { return ds.hostName(cli, host) + ":/var/bricks/shared" }
+
+	for i := 0; i < 2; i++ {
+		res := ds.VolumeShare(context.Background(), cli, entity.VolumeShare{VolumeShare: command.VolumeShare{Hosts: hosts[:3]}})
+		require.NoError(t, res.Error, "sharing the volume again changes nothing")
+	}
+	assert.Equal(t, []string{ds.hostName(cli, "10.0.0.3"), ds.hostName(cli, "10.0.0.4")}, pool.Peers())
+
+	vol := entity.Volume{Volume: command.Volume{Name: "shared", Global: true, Hosts: hosts[:3]}}
+	for i := 0; i < 2; i++ {
+		res := ds.CreateVolume(context.Background(), cli, vol)
+		require.NoError(t, res.Error, "creating the volume again leaves it as it is")
+	}
+	bricks, ok := pool.Bricks("shared")
+	require.True(t, ok)
+	assert.Equal(t, []string{brick("10.0.0.2"), brick("10.0.0.3"), brick("10.0.0.4")}, bricks)
+	for _, host := range hosts[:3] {
+		assert.True(t, hasVolume(t, engines[host], "shared"), host)
+	}
+
+	pool.SetOffline(ds.hostName(cli, "10.0.0.4"), true)
+	res := ds.VolumeStatus(context.Background(), cli, entity.Volume{Volume: command.Volume{Name: "shared"}})
+	require.NoError(t, res.Error)
+	status := res.Meta["volume"].(entity.GlusterVolume)
+	assert.Equal(t, "Started", status.Status)
+	assert.Equal(t, 3, status.Replicas)
+	assert.Equal(t, hosts[:3], status.Allowed)
+	require.Len(t, status.Bricks, 3)
+	assert.Equal(t, entity.GlusterBrick{Host: ds.hostName(cli, "10.0.0.2"), Path: "/var/bricks/shared",
+		Online: true, Port: 49152, Pid: 100, SizeTotal: 10 << 30, SizeFree: 8 << 30, Device: "/dev/sda1",
+		FsName: "ext4"}, status.Bricks[0])
+	assert.False(t, status.Bricks[2].Online)
+	pool.SetOffline(ds.hostName(cli, "10.0.0.4"), false)
+
+	res = ds.VolumeShare(context.Background(), cli, entity.VolumeShare{VolumeShare: command.VolumeShare{Hosts: hosts}})
+	require.NoError(t, res.Error, "a host is brought into the share")
+	res = ds.ResizeVolume(context.Background(), cli, entity.VolumeResize{
+		Name: "shared", Add: []string{"10.0.0.5"}, Remove: []string{"10.0.0.3"}})
+	require.NoError(t, res.Error)
+	status = res.Meta["volume"].(entity.GlusterVolume)
+	assert.Equal(t, 3, status.Replicas)
+	assert.Equal(t, []string{"10.0.0.2", "10.0.0.4", "10.0.0.5"}, status.Allowed)
+	bricks, _ = pool.Bricks("shared")
+	assert.Equal(t, []string{brick("10.0.0.2"), brick("10.0.0.4"), brick("10.0.0.5")}, bricks)
+	assert.False(t, hasVolume(t, engines["10.0.0.3"], "shared"))
+	assert.True(t, hasVolume(t, engines["10.0.0.5"], "shared"))
+	assert.Contains(t, engines["10.0.0.3"].Execs(GlusterContainerName),
+		[]string{"rm", "-rf", "/var/bricks/shared"})
+
+	res = ds.ResizeVolume(context.Background(), cli, entity.VolumeResize{
+		Name: "shared", Remove: []string{"10.0.0.2"}})
+	assert.True(t, res.IsFatal(), "the host the volume is resized from stays")
+	res = ds.ResizeVolume(context.Background(), cli, entity.VolumeResize{
+		Name: "shared", Remove: []string{"10.0.0.4", "10.0.0.5"}})
+	require.NoError(t, res.Error)
+	assert.Equal(t, 1, res.Meta["volume"].(entity.GlusterVolume).Replicas)
+
+	vol.Hosts = []string{"10.0.0.2", "10.0.0.4", "10.0.0.5"}
+	for i := 0; i < 2; i++ {
+		res = ds.RemoveGlobalVolume(context.Background(), cli, vol)
+		require.NoError(t, res.Error, "removing the volume again does nothing")
+	}
+	_, ok = pool.Bricks("shared")
+	assert.False(t, ok)
+	assert.False(t, hasVolume(t, engines["10.0.0.2"], "shared"))
+	assert.Contains(t, engines["10.0.0.2"].Execs(GlusterContainerName),
+		[]string{"rm", "-rf", "/var/bricks/shared"})
+	assert.Error(t, ds.VolumeStatus(context.Background(), cli, entity.Volume{Volume: command.Volume{Name: "shared"}}).Error)
+
+	for i := 0; i < 2; i++ {
+		res = ds.RemoveVolumeShare(context.Background(), cli, entity.VolumeShare{VolumeShare: command.VolumeShare{Hosts: hosts}})
+		require.NoError(t, res.Error, "removing the share again does nothing")
+	}
+	assert.Empty(t, pool.Peers())
+	for _, host := range hosts {
+		assert.Nil(t, engines[host].Execs(GlusterContainerName), host)
+	}
+}
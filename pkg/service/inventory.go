@@ -0,0 +1,339 @@
+/**
+ * Copyright 2019 Whiteblock Inc. All rights reserved.
+ * Use of this source code is governed by a BSD-style
+ * license that can be found in the LICENSE file.
+ */
+
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/whiteblock/genesis/pkg/config"
+	"github.com/whiteblock/genesis/pkg/entity"
+
+	"github.com/docker/docker/api/types"
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	// ErrHostNotFound is returned when a host is not in the inventory
+	ErrHostNotFound = errors.New("the host is not in the inventory")
+
+	// ErrHostExists is returned when registering a host which is already in the inventory
+	ErrHostExists = errors.New("the host is already in the inventory")
+
+	// ErrHostInUse is returned when removing a host which still has containers placed on it
+	ErrHostInUse = errors.New("the host still has containers placed on it")
+)
+
+// HostInventory keeps track of the hosts which containers can be placed on, how much of them
+// is used, and places the containers whose commands do not give a target
+type HostInventory interface {
+	// Register adds the host to the inventory
+	Register(spec entity.HostSpec) error
+
+	// Deregister removes the host from the inventory
+	Deregister(address string) error
+
+	// Hosts gives all of the hosts of the inventory
+	Hosts() []entity.Host
+
+	// Stale gives the hosts whose facts are older than the given age, or have never been gathered
+	Stale(maxAge time.Duration) []string
+
+	// Refresh gathers the facts of the host with the client it connects to
+	Refresh(ctx context.Context, address string, connect func() (entity.Client, error))
+
+	// Place chooses a host for the container and reserves what it asked for
+	Place(req entity.PlacementRequest) (string, error)
+
+	// Locate gives the host a container of the test was placed on
+	Locate(testID string, container string) (string, bool)
+
+	// Release gives back what the container reserved on its host
+	Release(testID string, container string)
+
+	// ReleaseTest gives back what all of the containers of the test reserved, once it is over
+	ReleaseTest(testID string)
+}
+
+type hostInventory struct {
+	mux   sync.Mutex
+	order []string
+	hosts map[string]*entity.Host
+	log   logrus.Ext1FieldLogger
+}
+
+// NewHostInventory creates a new HostInventory, which starts with the hosts of the config
+func NewHostInventory(conf config.Inventory, log logrus.Ext1FieldLogger) HostInventory {
+	out := &hostInventory{hosts: map[string]*entity.Host{}, log: log}
+	for _, spec := range conf.Hosts {
+		out.Register(spec)
+	}
+	return out
+}
+
+// Register adds the host to the inventory
+func (hi *hostInventory) Register(spec entity.HostSpec) error {
+	if len(spec.Address) == 0 {
+		return errors.New("the host is missing its address")
+	}
+	if spec.CPUs < 0 || spec.Memory < 0 {
+		return errors.New("the capacity of a host cannot be negative")
+	}
+	hi.mux.Lock()
+	defer hi.mux.Unlock()
+	if _, exists := hi.hosts[spec.Address]; exists {
+		return ErrHostExists
+	}
+	hi.hosts[spec.Address] = &entity.Host{HostSpec: spec, Reservations: []entity.Reservation{}}
+	hi.order = append(hi.order, spec.Address)
+	hi.log.WithField("host", spec.Address).Info("registered a host")
+	return nil
+}
+
+// Deregister removes the host from the inventory
+func (hi *hostInventory) Deregister(address string) error {
+	hi.mux.Lock()
+	defer hi.mux.Unlock()
+	host, exists := hi.hosts[address]
+	if !exists {
+		return ErrHostNotFound
+	}
+	if len(host.Reservations) > 0 {
+		return ErrHostInUse
+	}
+	delete(hi.hosts, address)
+	for i := range hi.order {
+		if hi.order[i] == address {
+			hi.order = append(hi.order[:i], hi.order[i+1:]...)
+			break
+		}
+	}
+	hi.log.WithField("host", address).Info("removed a host")
+	return nil
+}
+
+// Hosts gives all of the hosts of the inventory
+func (hi *hostInventory) Hosts() []entity.Host {
+	hi.mux.Lock()
+	defer hi.mux.Unlock()
+	return hi.snapshot()
+}
+
+// snapshot copies the hosts in the order they were registered. The lock must be held.
+func (hi *hostInventory) snapshot() []entity.Host {
+	out := make([]entity.Host, 0, len(hi.order))
+	for _, address := range hi.order {
+		host := *hi.hosts[address]
+		host.Reservations = append([]entity.Reservation{}, host.Reservations...)
+		out = append(out, host)
+	}
+	return out
+}
+
+// Stale gives the hosts whose facts are older than the given age, or have never been gathered
+func (hi *hostInventory) Stale(maxAge time.Duration) []string {
+	hi.mux.Lock()
+	defer hi.mux.Unlock()
+	out := []string{}
+	for _, address := range hi.order {
+		gathered := hi.hosts[address].Facts.Gathered
+		if gathered.IsZero() || (maxAge > 0 && time.Since(gathered) > maxAge) {
+			out = append(out, address)
+		}
+	}
+	return out
+}
+
+// Refresh gathers the facts of the host with the client it connects to
+func (hi *hostInventory) Refresh(ctx context.Context, address string,
+	connect func() (entity.Client, error)) {
+
+	facts, err := gatherFacts(ctx, connect)
+	facts.Gathered = time.Now()
+	if err != nil {
+		hi.log.WithFields(logrus.Fields{"host": address, "error": err}).Warn(
+			"failed to gather the facts of a host")
+		facts.Error = err.Error()
+	}
+	hi.mux.Lock()
+	defer hi.mux.Unlock()
+	if host, exists := hi.hosts[address]; exists {
+		host.Facts = facts
+	}
+}
+
+func gatherFacts(ctx context.Context, connect func() (entity.Client, error)) (facts entity.HostFacts, err error) {
+	cli, err := connect()
+	if err != nil {
+		return
+	}
+	defer cli.Close()
+	info, err := cli.Info(ctx)
+	if err != nil {
+		return
+	}
+	facts.CPUs = float64(info.NCPU)
+	facts.Memory = info.MemTotal
+
+	imgs, err := cli.ImageList(ctx, types.ImageListOptions{})
+	if err != nil {
+		return
+	}
+	for _, img := range imgs {
+		facts.Images = append(facts.Images, img.RepoTags...)
+	}
+	return
+}
+
+// Place chooses a host for the container and reserves what it asked for
+func (hi *hostInventory) Place(req entity.PlacementRequest) (string, error) {
+	hi.mux.Lock()
+	defer hi.mux.Unlock()
+	for _, address := range hi.order {
+		if hi.hosts[address].Holds(req.TestID, req.Container) {
+			return address, nil // it was already placed, by an earlier attempt
+		}
+	}
+	address, err := schedule(hi.snapshot(), req)
+	if err != nil {
+		return "", err
+	}
+	host := hi.hosts[address]
+	host.Reservations = append(host.Reservations, req.Reservation)
+	hi.log.WithFields(logrus.Fields{
+		"host":      address,
+		"container": req.Container,
+		"testID":    req.TestID,
+	}).Info("placed a container")
+	return address, nil
+}
+
+// Locate gives the host a container of the test was placed on
+func (hi *hostInventory) Locate(testID string, container string) (string, bool) {
+	hi.mux.Lock()
+	defer hi.mux.Unlock()
+	for _, address := range hi.order {
+		if hi.hosts[address].Holds(testID, container) {
+			return address, true
+		}
+	}
+	return "", false
+}
+
+// Release gives back what the container reserved on its host
+func (hi *hostInventory) Release(testID string, container string) {
+	hi.mux.Lock()
+	defer hi.mux.Unlock()
+	for _, host := range hi.hosts {
+		for i, res := range host.Reservations {
+			if res.TestID == testID && res.Container == container {
+				host.Reservations = append(host.Reservations[:i], host.Reservations[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+// ReleaseTest gives back what all of the containers of the test reserved on their hosts
+func (hi *hostInventory) ReleaseTest(testID string) {
+	hi.mux.Lock()
+	defer hi.mux.Unlock()
+	for _, host := range hi.hosts {
+		kept := []entity.Reservation{}
+		for _, res := range host.Reservations {
+			if res.TestID != testID {
+				kept = append(kept, res)
+			}
+		}
+		host.Reservations = kept
+	}
+}
+
+// candidate is a host which the container fits on
+type candidate struct {
+	address  string
+	inGroup  int
+	leftover float64
+	hasImage bool
+}
+
+// schedule chooses the host for the container. Of the hosts which satisfy its constraints and
+// have room for it, the containers of its group are spread first, then it is packed onto the
+// host it leaves the least room on, preferring the hosts which already have its image.
+func schedule(hosts []entity.Host, req entity.PlacementRequest) (string, error) {
+	if len(hosts) == 0 {
+		return "", errors.New("there are no hosts in the inventory")
+	}
+	for _, name := range req.Affinity {
+		found := false
+		for _, host := range hosts {
+			found = found || host.Holds(req.TestID, name)
+		}
+		if !found {
+			return "", fmt.Errorf("the container %s has not been placed yet", name)
+		}
+	}
+	candidates := []candidate{}
+	for _, host := range hosts {
+		if !satisfies(host, req) {
+			continue
+		}
+		cpus, memory := host.Capacity()
+		freeCPUs, freeMemory := host.Free()
+		if freeCPUs < req.CPUs || freeMemory < req.Memory || cpus <= 0 || memory <= 0 {
+			continue
+		}
+		candidates = append(candidates, candidate{
+			address:  host.Address,
+			inGroup:  host.InGroup(req.TestID, req.Group),
+			leftover: (freeCPUs-req.CPUs)/cpus + float64(freeMemory-req.Memory)/float64(memory),
+			hasImage: host.HasImage(req.Image),
+		})
+	}
+	if len(candidates) == 0 {
+		return "", fmt.Errorf("no host has room for %s, which needs %v cpus and %d bytes of memory",
+			req.Container, req.CPUs, req.Memory)
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		a, b := candidates[i], candidates[j]
+		if len(req.Group) > 0 && a.inGroup != b.inGroup {
+			return a.inGroup < b.inGroup
+		}
+		if a.leftover != b.leftover {
+			return a.leftover < b.leftover
+		}
+		return a.hasImage && !b.hasImage
+	})
+	return candidates[0].address, nil
+}
+
+// satisfies checks the host against the constraints of the container, other than its size
+func satisfies(host entity.Host, req entity.PlacementRequest) bool {
+	if len(host.Facts.Error) > 0 {
+		return false
+	}
+	for key, value := range req.HostLabels {
+		if host.Labels[key] != value {
+			return false
+		}
+	}
+	for _, name := range req.Affinity {
+		if !host.Holds(req.TestID, name) {
+			return false
+		}
+	}
+	for _, name := range req.AntiAffinity {
+		if host.Holds(req.TestID, name) {
+			return false
+		}
+	}
+	return true
+}
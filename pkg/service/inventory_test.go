@@ -0,0 +1,175 @@
+/**
+ * Copyright 2019 Whiteblock Inc. All rights reserved.
+ * Use of this source code is governed by a BSD-style
+ * license that can be found in the LICENSE file.
+ */
+
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/whiteblock/genesis/pkg/config"
+	"github.com/whiteblock/genesis/pkg/entity"
+	"github.com/whiteblock/genesis/pkg/fake"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testInventory(t *testing.T, specs ...entity.HostSpec) HostInventory {
+	inv := NewHostInventory(config.Inventory{Hosts: specs}, logrus.New())
+	require.Len(t, inv.Hosts(), len(specs))
+	return inv
+}
+
+func placement(container string, cpus float64, memory int64) entity.PlacementRequest {
+	return entity.PlacementRequest{Reservation: entity.Reservation{
+		TestID:    "test",
+		Container: container,
+		CPUs:      cpus,
+		Memory:    memory,
+	}}
+}
+
+func TestHostInventory_Register(t *testing.T) {
+	inv := testInventory(t, entity.HostSpec{Address: "10.0.0.2", CPUs: 4, Memory: 1 << 30})
+
+	assert.Equal(t, ErrHostExists, inv.Register(entity.HostSpec{Address: "10.0.0.2"}))
+	assert.Error(t, inv.Register(entity.HostSpec{}))
+	assert.Error(t, inv.Register(entity.HostSpec{Address: "10.0.0.3", CPUs: -1}))
+	require.NoError(t, inv.Register(entity.HostSpec{Address: "10.0.0.3", CPUs: 2, Memory: 1 << 30}))
+
+	hosts := inv.Hosts()
+	require.Len(t, hosts, 2)
+	assert.Equal(t, "10.0.0.2", hosts[0].Address)
+	assert.Equal(t, "10.0.0.3", hosts[1].Address)
+
+	host, err := inv.Place(placement("tester", 1, 1<<20))
+	require.NoError(t, err)
+	assert.Equal(t, ErrHostInUse, inv.Deregister(host))
+	assert.Equal(t, ErrHostNotFound, inv.Deregister("10.0.0.4"))
+
+	inv.Release("test", "tester")
+	assert.NoError(t, inv.Deregister(host))
+	assert.Len(t, inv.Hosts(), 1)
+
+	host, err = inv.Place(placement("tester", 1, 1<<20))
+	require.NoError(t, err)
+	_, err = inv.Place(placement("other", 1, 1<<20))
+	require.NoError(t, err)
+	inv.ReleaseTest("test")
+	assert.NoError(t, inv.Deregister(host), "the reservations of the test are gone once it is over")
+}
+
+func TestHostInventory_Place_BinPacks(t *testing.T) {
+	inv := testInventory(t,
+		entity.HostSpec{Address: "big", CPUs: 16, Memory: 16 << 30},
+		entity.HostSpec{Address: "small", CPUs: 2, Memory: 2 << 30})
+
+	host, err := inv.Place(placement("first", 2, 2<<30))
+	require.NoError(t, err)
+	assert.Equal(t, "small", host, "the container fills the small host exactly")
+
+	host, err = inv.Place(placement("second", 2, 2<<30))
+	require.NoError(t, err)
+	assert.Equal(t, "big", host)
+
+	host, err = inv.Place(placement("second", 2, 2<<30))
+	require.NoError(t, err)
+	assert.Equal(t, "big", host, "placing the same container again keeps its host")
+	assert.Len(t, inv.Hosts()[0].Reservations, 1)
+
+	_, err = inv.Place(placement("third", 15, 1<<20))
+	assert.Error(t, err, "no host has 15 cpus left")
+
+	loc, ok := inv.Locate("test", "first")
+	assert.True(t, ok)
+	assert.Equal(t, "small", loc)
+	inv.Release("test", "first")
+	_, ok = inv.Locate("test", "first")
+	assert.False(t, ok)
+}
+
+func TestHostInventory_Place_Spread(t *testing.T) {
+	inv := testInventory(t,
+		entity.HostSpec{Address: "a", CPUs: 8, Memory: 8 << 30},
+		entity.HostSpec{Address: "b", CPUs: 8, Memory: 8 << 30},
+		entity.HostSpec{Address: "c", CPUs: 8, Memory: 8 << 30})
+
+	used := map[string]int{}
+	for _, name := range []string{"n0", "n1", "n2", "n3", "n4", "n5"} {
+		req := placement(name, 1, 1<<30)
+		req.Group = "nodes"
+		host, err := inv.Place(req)
+		require.NoError(t, err)
+		used[host]++
+	}
+	assert.Equal(t, map[string]int{"a": 2, "b": 2, "c": 2}, used)
+}
+
+func TestHostInventory_Place_Constraints(t *testing.T) {
+	inv := testInventory(t,
+		entity.HostSpec{Address: "a", CPUs: 8, Memory: 8 << 30, Labels: map[string]string{"zone": "east"}},
+		entity.HostSpec{Address: "b", CPUs: 8, Memory: 8 << 30, Labels: map[string]string{"zone": "west"}})
+
+	req := placement("db", 1, 1<<30)
+	req.HostLabels = map[string]string{"zone": "west"}
+	host, err := inv.Place(req)
+	require.NoError(t, err)
+	assert.Equal(t, "b", host)
+
+	req = placement("app", 1, 1<<30)
+	req.Affinity = []string{"db"}
+	host, err = inv.Place(req)
+	require.NoError(t, err)
+	assert.Equal(t, "b", host)
+
+	req = placement("replica", 1, 1<<30)
+	req.AntiAffinity = []string{"db"}
+	host, err = inv.Place(req)
+	require.NoError(t, err)
+	assert.Equal(t, "a", host)
+
+	req = placement("cache", 1, 1<<30)
+	req.Affinity = []string{"missing"}
+	_, err = inv.Place(req)
+	assert.Error(t, err)
+
+	req = placement("edge", 1, 1<<30)
+	req.HostLabels = map[string]string{"zone": "north"}
+	_, err = inv.Place(req)
+	assert.Error(t, err)
+}
+
+func TestHostInventory_Refresh(t *testing.T) {
+	inv := testInventory(t, entity.HostSpec{Address: "a"}, entity.HostSpec{Address: "b", CPUs: 1})
+	assert.Equal(t, []string{"a", "b"}, inv.Stale(time.Minute))
+
+	engine := fake.NewEngine("a")
+	engine.SetResources(8, 4<<30)
+	engine.AddImage("nginx")
+	inv.Refresh(context.Background(), "a", func() (entity.Client, error) { return engine, nil })
+	inv.Refresh(context.Background(), "b", func() (entity.Client, error) {
+		return nil, errors.New("unreachable")
+	})
+	assert.Empty(t, inv.Stale(time.Minute))
+	assert.Equal(t, []string{"a", "b"}, inv.Stale(time.Nanosecond))
+
+	hosts := inv.Hosts()
+	cpus, memory := hosts[0].Capacity()
+	assert.Equal(t, float64(8), cpus)
+	assert.Equal(t, int64(4<<30), memory)
+	assert.True(t, hosts[0].HasImage("nginx"))
+	assert.Equal(t, "unreachable", hosts[1].Facts.Error)
+
+	req := placement("web", 1, 1<<20)
+	req.Image = "nginx"
+	host, err := inv.Place(req)
+	require.NoError(t, err)
+	assert.Equal(t, "a", host, "a host whose facts could not be gathered is not used")
+}
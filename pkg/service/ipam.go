@@ -0,0 +1,183 @@
+/**
+ * Copyright 2019 Whiteblock Inc. All rights reserved.
+ * Use of this source code is governed by a BSD-style
+ * license that can be found in the LICENSE file.
+ */
+
+package service
+
+import (
+	"bytes"
+	"encoding/json"
+	"net"
+	"sync"
+
+	"github.com/whiteblock/genesis/pkg/entity"
+	"github.com/whiteblock/genesis/pkg/repository"
+
+	"github.com/sirupsen/logrus"
+)
+
+// addressManager gives out the subnets of the networks and the addresses of the containers,
+// keeping the allocations in the repository
+type addressManager struct {
+	mux   sync.Mutex
+	repo  repository.IPAMRepository
+	state entity.IPAMState
+}
+
+func newAddressManager(repo repository.IPAMRepository) *addressManager {
+	return &addressManager{repo: repo}
+}
+
+// update runs fn on the allocations, saving them if it succeeds and changed them. The allocations
+// are loaded the first time they are needed.
+func (am *addressManager) update(fn func(state entity.IPAMState) error) error {
+	am.mux.Lock()
+	defer am.mux.Unlock()
+	if am.state == nil {
+		state, err := am.repo.Load()
+		if err != nil {
+			return err
+		}
+		am.state = state
+	}
+	before, err := json.Marshal(am.state)
+	if err != nil {
+		return err
+	}
+	err = fn(am.state)
+	if err != nil {
+		return err
+	}
+	after, err := json.Marshal(am.state)
+	if err != nil || bytes.Equal(before, after) {
+		return err
+	}
+	return am.repo.Save(am.state)
+}
+
+// ipv4Subnet gives the first IPv4 subnet of the network, if it has one
+func ipv4Subnet(network entity.Network) (entity.Subnet, bool) {
+	for _, subnet := range network.AllSubnets() {
+		ip, _, err := net.ParseCIDR(subnet.Subnet)
+		if err == nil && ip.To4() != nil {
+			return subnet, true
+		}
+	}
+	return entity.Subnet{}, false
+}
+
+// allocateNetwork gives the network a subnet from the pools if it does not have an IPv4 one,
+// or records the subnet it was given otherwise, and records that it is on the host of the client.
+// It gives back the network with its subnet filled in, and whether the network was not known on
+// the host before.
+func (ds dockerService) allocateNetwork(cli entity.DockerCli,
+	network entity.Network) (entity.Network, bool, error) {
+
+	added := false
+	err := ds.ipam.update(func(state entity.IPAMState) error {
+		if subnet, ok := ipv4Subnet(network); ok {
+			_, err := state.ReserveSubnet(cli.TestID, network.Name, subnet.Subnet, subnet.Gateway)
+			if err != nil {
+				return err
+			}
+		} else if len(ds.conf.IPAMPools) > 0 {
+			alloc, err := state.AllocateSubnet(cli.TestID, network.Name, ds.conf.IPAMPools,
+				ds.conf.IPAMSubnetSize)
+			if err != nil {
+				return err
+			}
+			network.Subnet = alloc.Subnet
+			network.Gateway = alloc.Gateway
+		}
+		added = state.AddHost(cli.TestID, network.Name, cli.IP)
+		return nil
+	})
+	return network, added, err
+}
+
+// assignAddress gives the container an address on the network, if the network is managed
+func (ds dockerService) assignAddress(cli entity.DockerCli, network string,
+	container string, ip string) (string, error) {
+
+	err := ds.ipam.update(func(state entity.IPAMState) error {
+		var err error
+		ip, err = state.AssignAddress(cli.TestID, network, container, ip)
+		return err
+	})
+	return ip, err
+}
+
+// assignAddresses gives the container an address on each of its networks, filling them in
+func (ds dockerService) assignAddresses(cli entity.DockerCli,
+	dContainer *entity.Container) (map[string]string, error) {
+
+	var err error
+	addresses := map[string]string{}
+	if len(dContainer.Network) > 0 {
+		dContainer.IP, err = ds.assignAddress(cli, dContainer.Network, dContainer.Name, dContainer.IP)
+		if err != nil {
+			return nil, err
+		}
+		if len(dContainer.IP) > 0 {
+			addresses[dContainer.Network] = dContainer.IP
+		}
+	}
+	networks := make([]entity.NetworkAttachment, len(dContainer.Networks))
+	for i, attachment := range dContainer.Networks {
+		attachment.IP, err = ds.assignAddress(cli, attachment.Network, dContainer.Name, attachment.IP)
+		if err != nil {
+			ds.releaseContainer(cli, dContainer.Name)
+			return nil, err
+		}
+		if len(attachment.IP) > 0 {
+			addresses[attachment.Network] = attachment.IP
+		}
+		networks[i] = attachment
+	}
+	dContainer.Networks = networks
+	return addresses, nil
+}
+
+// release runs fn on the allocations, logging any failure, since there is nothing more
+// which can be done about it
+func (ds dockerService) release(cli entity.DockerCli, fn func(state entity.IPAMState)) {
+	err := ds.ipam.update(func(state entity.IPAMState) error {
+		fn(state)
+		return nil
+	})
+	if err != nil {
+		ds.withField(cli, "error", err).Error("failed to save the released addresses")
+	}
+}
+
+func (ds dockerService) releaseAddress(cli entity.DockerCli, network string, container string) {
+	ds.release(cli, func(state entity.IPAMState) {
+		state.ReleaseAddress(cli.TestID, network, container)
+	})
+}
+
+func (ds dockerService) releaseContainer(cli entity.DockerCli, container string) {
+	ds.withFields(cli, logrus.Fields{"name": container}).Trace("releasing the addresses of the container")
+	ds.release(cli, func(state entity.IPAMState) {
+		state.ReleaseContainer(cli.TestID, container)
+	})
+}
+
+// releaseNetwork releases the network on the host of the client, its subnet is only released
+// once it is not left on any host
+func (ds dockerService) releaseNetwork(cli entity.DockerCli, network string) {
+	ds.release(cli, func(state entity.IPAMState) {
+		state.ReleaseHost(cli.TestID, network, cli.IP)
+	})
+}
+
+// releaseTest releases the subnets and addresses of all of the networks of the test
+func (ds dockerService) releaseTest(testID string) {
+	cli := entity.DockerCli{TestID: testID}
+	ds.log.WithField("testID", testID).Trace("releasing the addresses of the test")
+	ds.release(cli, func(state entity.IPAMState) {
+		state.ReleaseTest(testID)
+	})
+}
@@ -0,0 +1,44 @@
+/**
+ * Copyright 2019 Whiteblock Inc. All rights reserved.
+ * Use of this source code is governed by a BSD-style
+ * license that can be found in the LICENSE file.
+ */
+
+package service
+
+import (
+	"fmt"
+	"testing"
+
+	repoMock "github.com/whiteblock/genesis/mocks/pkg/repository"
+	"github.com/whiteblock/genesis/pkg/entity"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAddressManager_Update(t *testing.T) {
+	repo := new(repoMock.IPAMRepository)
+	repo.On("Load").Return(entity.IPAMState{}, nil).Once()
+	repo.On("Save", mock.Anything).Return(fmt.Errorf("read-only file system")).Once()
+	am := newAddressManager(repo)
+
+	err := am.update(func(state entity.IPAMState) error {
+		state.ReleaseTest("test1")
+		return nil
+	})
+	assert.NoError(t, err, "nothing is saved when nothing changed")
+
+	err = am.update(func(state entity.IPAMState) error {
+		_, err := state.ReserveSubnet("test1", "net-a", "10.0.0.0/24", "")
+		return err
+	})
+	assert.Error(t, err)
+
+	err = am.update(func(state entity.IPAMState) error {
+		return fmt.Errorf("no free subnet")
+	})
+	require.Error(t, err)
+	repo.AssertExpectations(t)
+}
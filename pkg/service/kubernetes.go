@@ -0,0 +1,724 @@
+/**
+ * Copyright 2019 Whiteblock Inc. All rights reserved.
+ * Use of this source code is governed by a BSD-style
+ * license that can be found in the LICENSE file.
+ */
+
+package service
+
+import (
+	"archive/tar"
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/whiteblock/genesis/pkg/config"
+	"github.com/whiteblock/genesis/pkg/entity"
+	"github.com/whiteblock/genesis/pkg/file"
+	"github.com/whiteblock/genesis/pkg/repository"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/network"
+	"github.com/sirupsen/logrus"
+	"github.com/whiteblock/definition/command"
+)
+
+const (
+	// pendingPodKey is the key of the pod in the config map which holds a pod which
+	// has been created, but not started yet
+	pendingPodKey = "pod"
+
+	// maxConfigMapSize is the largest file which can be placed in a config map
+	maxConfigMapSize = 1 << 20
+)
+
+// kubePollInterval is how often the phase of a pod is checked while waiting for it to finish
+var kubePollInterval = time.Second
+
+// KubernetesService executes the orders on a Kubernetes cluster. Containers are pods, networks are
+// network policies which only let the pods on them reach each other, with Multus attachments if
+// enabled, volumes are persistent volume claims, files are config maps, and network emulation is
+// applied from ephemeral containers.
+type KubernetesService interface {
+	// CreateContainer prepares the pod of the container, it is only created once the
+	// container is started, like a docker container
+	CreateContainer(ctx context.Context, kc entity.KubeCli, container entity.Container) entity.Result
+
+	// StartContainer creates the prepared pod, waiting for it to finish if asked to
+	StartContainer(ctx context.Context, kc entity.KubeCli, sc command.StartContainer) entity.Result
+
+	// RemoveContainer removes the pods, along with everything which was created for them
+	RemoveContainer(ctx context.Context, kc entity.KubeCli, names ...string) entity.Result
+
+	// CreateNetwork creates the network policy of the network
+	CreateNetwork(ctx context.Context, kc entity.KubeCli, net entity.Network) entity.Result
+
+	// RemoveNetwork removes the network policy of the network
+	RemoveNetwork(ctx context.Context, kc entity.KubeCli, name string) entity.Result
+
+	// AttachNetwork puts a pod on a network
+	AttachNetwork(ctx context.Context, kc entity.KubeCli, cn entity.ContainerNetwork) entity.Result
+
+	// DetachNetwork takes a pod off of a network
+	DetachNetwork(ctx context.Context, kc entity.KubeCli, network string, container string) entity.Result
+
+	// CreateVolume creates a persistent volume claim
+	CreateVolume(ctx context.Context, kc entity.KubeCli, volume command.Volume) entity.Result
+
+	// RemoveVolume removes a persistent volume claim
+	RemoveVolume(ctx context.Context, kc entity.KubeCli, name string) entity.Result
+
+	// PlaceFileInContainer mounts the file into a pod which has not been started yet
+	PlaceFileInContainer(ctx context.Context, kc entity.KubeCli, containerName string,
+		file command.File) entity.Result
+
+	// Emulation applies network emulation to all of the traffic of a pod on a network
+	Emulation(ctx context.Context, kc entity.KubeCli, netem command.Netconf) entity.Result
+
+	// RemoveEmulation removes the network emulation of a pod on a network
+	RemoveEmulation(ctx context.Context, kc entity.KubeCli, netem command.Netconf) entity.Result
+}
+
+type kubernetesService struct {
+	repo   repository.KubernetesRepository
+	conf   config.Kubernetes
+	remote file.RemoteSources
+	log    logrus.Ext1FieldLogger
+}
+
+// NewKubernetesService creates a new KubernetesService
+func NewKubernetesService(
+	repo repository.KubernetesRepository,
+	conf config.Kubernetes,
+	remote file.RemoteSources,
+	log logrus.Ext1FieldLogger) KubernetesService {
+
+	return kubernetesService{
+		repo:   repo,
+		conf:   conf,
+		remote: remote,
+		log:    log}
+}
+
+func (ks kubernetesService) withFields(kc entity.KubeCli, fields logrus.Fields) *logrus.Entry {
+	for key, value := range kc.Labels {
+		fields[key] = value
+	}
+	return ks.log.WithFields(fields)
+}
+
+// kubePath gives the path of the resource in the namespace, or of the object if a name is given
+func kubePath(kc entity.KubeCli, group string, resource string, name ...string) string {
+	prefix := "/api/v1"
+	if len(group) > 0 {
+		prefix = "/apis/" + group
+	}
+	out := fmt.Sprintf("%s/namespaces/%s/%s", prefix, kc.Namespace, resource)
+	for _, part := range name {
+		out += "/" + part
+	}
+	return out
+}
+
+func podPath(kc entity.KubeCli, name ...string) string {
+	return kubePath(kc, "", "pods", name...)
+}
+
+func configMapPath(kc entity.KubeCli, name ...string) string {
+	return kubePath(kc, "", "configmaps", name...)
+}
+
+// pendingName gives the name of the config map which holds the pod until it is started
+func pendingName(podName string) string {
+	return podName + "-pending"
+}
+
+// ignoreNotFound drops the error if it is only because the object is already gone
+func ignoreNotFound(err error) error {
+	if repository.IsNotFound(err) {
+		return nil
+	}
+	return err
+}
+
+// create creates the object, an object which already exists is not an error, so that
+// the commands can be retried. Pods are created with createPod instead.
+func (ks kubernetesService) create(ctx context.Context, kc entity.KubeCli, path string,
+	obj interface{}) entity.Result {
+
+	err := ks.repo.Create(ctx, path, obj)
+	if repository.IsAlreadyExists(err) {
+		ks.withFields(kc, logrus.Fields{"error": err}).Info("ignoring whitelisted error")
+		return entity.NewSuccessResult().InjectMeta(map[string]interface{}{"error": err})
+	}
+	return entity.NewResult(err)
+}
+
+// createPod creates the pod. A pod which already exists is only taken as created if it is the
+// one of the test, from an earlier try of the command.
+func (ks kubernetesService) createPod(ctx context.Context, kc entity.KubeCli, pod entity.Pod) entity.Result {
+	err := ks.repo.Create(ctx, podPath(kc), pod)
+	if !repository.IsAlreadyExists(err) {
+		return entity.NewResult(err)
+	}
+	var existing entity.Pod
+	getErr := ks.repo.Get(ctx, podPath(kc, pod.Metadata.Name), &existing)
+	if getErr != nil {
+		return entity.NewErrorResult(getErr)
+	}
+	if len(kc.TestID) == 0 || existing.Metadata.Labels[kubeTestLabel] != kubeName(kc.TestID) {
+		return entity.NewFatalResult(err).InjectMeta(map[string]interface{}{"pod": pod.Metadata.Name})
+	}
+	ks.withFields(kc, logrus.Fields{"pod": pod.Metadata.Name}).Info("the pod was already created")
+	return entity.NewSuccessResult()
+}
+
+// getPending gets the pod which was prepared for the container, if it has not been started yet
+func (ks kubernetesService) getPending(ctx context.Context, kc entity.KubeCli,
+	container string) (entity.Pod, bool, error) {
+
+	var cm entity.ConfigMap
+	var pod entity.Pod
+	err := ks.repo.Get(ctx, configMapPath(kc, pendingName(kubeObjectName(kc, container))), &cm)
+	if repository.IsNotFound(err) {
+		return pod, false, nil
+	}
+	if err != nil {
+		return pod, false, err
+	}
+	return pod, true, json.Unmarshal([]byte(cm.Data[pendingPodKey]), &pod)
+}
+
+// pendingConfigMap gives the config map which holds the pod until it is started
+func pendingConfigMap(kc entity.KubeCli, pod entity.Pod) (entity.ConfigMap, error) {
+	data, err := json.Marshal(pod)
+	if err != nil {
+		return entity.ConfigMap{}, err
+	}
+	meta := kubeMeta(kc, pendingName(pod.Metadata.Name), nil)
+	meta.Labels[kubeContainerLabel] = pod.Metadata.Name
+	return entity.ConfigMap{
+		TypeMeta: entity.TypeMeta{APIVersion: "v1", Kind: "ConfigMap"},
+		Metadata: meta,
+		Data:     map[string]string{pendingPodKey: string(data)},
+	}, nil
+}
+
+// updatePending changes the pod which was prepared for the container. It gives back false,
+// without calling fn, if the container has already been started.
+func (ks kubernetesService) updatePending(ctx context.Context, kc entity.KubeCli, container string,
+	fn func(pod *entity.Pod) error) (bool, error) {
+
+	pod, ok, err := ks.getPending(ctx, kc, container)
+	if !ok || err != nil {
+		return ok, err
+	}
+	err = fn(&pod)
+	if err != nil {
+		return true, err
+	}
+	data, err := json.Marshal(pod)
+	if err != nil {
+		return true, err
+	}
+	return true, ks.repo.Patch(ctx, configMapPath(kc, pendingName(kubeObjectName(kc, container))),
+		repository.MergePatch,
+		map[string]interface{}{"data": map[string]string{pendingPodKey: string(data)}})
+}
+
+// CreateContainer prepares the pod of the container. Pods start as soon as they are created, so it
+// is kept in a config map until the container is started, which lets files and networks be added first.
+func (ks kubernetesService) CreateContainer(ctx context.Context, kc entity.KubeCli,
+	cntr entity.Container) entity.Result {
+
+	ks.withFields(kc, logrus.Fields{"container": cntr}).Trace("create pod")
+	pod, err := ks.buildPod(kc, cntr)
+	if err != nil {
+		return entity.NewFatalResult(err)
+	}
+	meta := map[string]interface{}{
+		"image":    cntr.Image,
+		"name":     cntr.Name,
+		"pod":      pod.Metadata.Name,
+		"networks": cntr.GetNetworks(),
+		"type":     "CreateContainer",
+	}
+	if !cntr.Credentials.Empty() {
+		secret, err := registrySecret(kc, cntr.Container)
+		if err != nil {
+			return entity.NewFatalResult(err).InjectMeta(meta)
+		}
+		res := ks.create(ctx, kc, kubePath(kc, "", "secrets"), secret)
+		if !res.IsSuccess() {
+			return res.InjectMeta(meta)
+		}
+	}
+	cm, err := pendingConfigMap(kc, pod)
+	if err != nil {
+		return entity.NewFatalResult(err).InjectMeta(meta)
+	}
+	res := ks.create(ctx, kc, configMapPath(kc), cm)
+	if !res.IsSuccess() {
+		res = res.Fatal()
+	}
+	return res.InjectMeta(meta)
+}
+
+// podResult waits for the pod to finish, giving back its result
+func (ks kubernetesService) podResult(ctx context.Context, kc entity.KubeCli,
+	sc command.StartContainer) entity.Result {
+
+	ctx, cancel := context.WithTimeout(ctx, sc.Timeout.Duration)
+	defer cancel()
+	ticker := time.NewTicker(kubePollInterval)
+	defer ticker.Stop()
+	for {
+		var pod entity.Pod
+		err := ks.repo.Get(ctx, podPath(kc, kubeObjectName(kc, sc.Name)), &pod)
+		if err != nil && ctx.Err() == nil {
+			return entity.NewErrorResult(err).InjectMeta(map[string]interface{}{
+				"name": sc.Name,
+				"type": "StartContainer",
+			})
+		}
+		if pod.Status.Phase == "Succeeded" || pod.Status.Phase == "Failed" {
+			for _, status := range pod.Status.ContainerStatuses {
+				term := status.State.Terminated
+				if status.Name == kubeName(sc.Name) && term != nil && term.ExitCode != 0 && !sc.IgnoreExitCode {
+					return entity.NewFatalResult(
+						fmt.Sprintf("Task %s exited with %d", sc.Name, term.ExitCode))
+				}
+			}
+			return entity.NewSuccessResult()
+		}
+		select {
+		case <-ctx.Done():
+			ks.withFields(kc, logrus.Fields{"name": sc.Name}).Debug("timeout was reached")
+			return entity.NewSuccessResult()
+		case <-ticker.C:
+		}
+	}
+}
+
+// StartContainer creates the pod which was prepared for the container
+func (ks kubernetesService) StartContainer(ctx context.Context, kc entity.KubeCli,
+	sc command.StartContainer) entity.Result {
+
+	ks.withFields(kc, logrus.Fields{"name": sc.Name}).Trace("starting pod")
+	pod, ok, err := ks.getPending(ctx, kc, sc.Name)
+	if err != nil {
+		return entity.NewErrorResult(err)
+	}
+	if ok {
+		res := ks.createPod(ctx, kc, pod)
+		if !res.IsSuccess() {
+			return res.InjectMeta(map[string]interface{}{
+				"name": sc.Name,
+				"type": "StartContainer",
+			})
+		}
+		err = ignoreNotFound(ks.repo.Delete(ctx, configMapPath(kc, pendingName(pod.Metadata.Name))))
+		if err != nil {
+			return entity.NewErrorResult(err)
+		}
+	} else {
+		err = ks.repo.Get(ctx, podPath(kc, kubeObjectName(kc, sc.Name)), &pod)
+		if err != nil {
+			return entity.NewFatalResult(err).InjectMeta(map[string]interface{}{
+				"name": sc.Name,
+				"type": "StartContainer",
+			})
+		}
+	}
+
+	if !sc.Attach {
+		return entity.NewSuccessResult()
+	}
+	return ks.podResult(ctx, kc, sc)
+}
+
+// RemoveContainer removes the pods, along with their config maps and registry secrets
+func (ks kubernetesService) RemoveContainer(ctx context.Context, kc entity.KubeCli,
+	names ...string) entity.Result {
+
+	var err error
+	for _, name := range names {
+		ks.withFields(kc, logrus.Fields{"name": name}).Debug("removing pod")
+		selector := url.QueryEscape(kubeContainerLabel + "=" + kubeObjectName(kc, name))
+		for _, path := range []string{
+			podPath(kc, kubeObjectName(kc, name)),
+			configMapPath(kc) + "?labelSelector=" + selector,
+			kubePath(kc, "", "secrets") + "?labelSelector=" + selector,
+		} {
+			e := ignoreNotFound(ks.repo.Delete(ctx, path))
+			if e != nil {
+				err = fmt.Errorf("%v:%w", err, e)
+			}
+		}
+	}
+	return entity.NewResult(err)
+}
+
+// multusConfig gives the CNI configuration of the network, the addresses are given out by host-local,
+// which also takes the addresses which are asked for by the pods
+func multusConfig(kc entity.KubeCli, net entity.Network) (string, error) {
+	subnet, ok := ipv4Subnet(net)
+	if !ok {
+		return "", fmt.Errorf("network \"%s\" needs an IPv4 subnet to be used with multus", net.Name)
+	}
+	rng := map[string]string{"subnet": subnet.Subnet}
+	if len(subnet.Gateway) > 0 {
+		rng["gateway"] = subnet.Gateway
+	}
+	conf := map[string]interface{}{
+		"cniVersion":   "0.3.1",
+		"name":         kubeObjectName(kc, net.Name),
+		"capabilities": map[string]bool{"ips": true, "mac": true},
+		"ipam": map[string]interface{}{
+			"type":   "host-local",
+			"ranges": [][]map[string]string{{rng}},
+		},
+	}
+	switch net.GetDriver() {
+	case entity.MacvlanDriver, entity.IpvlanDriver:
+		conf["type"] = net.GetDriver()
+		if len(net.Parent) > 0 {
+			conf["master"] = net.Parent
+		}
+		if len(net.Mode) > 0 {
+			conf["mode"] = net.Mode
+		}
+	default:
+		conf["type"] = "bridge"
+		conf["bridge"] = kubeBridgeName(kc, net.Name)
+	}
+	if net.MTU > 0 {
+		conf["mtu"] = net.MTU
+	}
+	data, err := json.Marshal(conf)
+	return string(data), err
+}
+
+// CreateNetwork creates a network policy which only lets the pods on the network reach each
+// other, and the attachment definition of the network if multus is used
+func (ks kubernetesService) CreateNetwork(ctx context.Context, kc entity.KubeCli,
+	net entity.Network) entity.Result {
+
+	selector := entity.LabelSelector{MatchLabels: map[string]string{kubeNetworkLabel(kc, net.Name): "true"}}
+	policy := entity.NetworkPolicy{
+		TypeMeta: entity.TypeMeta{APIVersion: "networking.k8s.io/v1", Kind: "NetworkPolicy"},
+		Metadata: kubeMeta(kc, kubeObjectName(kc, net.Name), net.Labels),
+		Spec: entity.NetworkPolicySpec{
+			PodSelector: selector,
+			Ingress:     []entity.NetworkPolicyIngressRule{{From: []entity.NetworkPolicyPeer{{PodSelector: &selector}}}},
+			PolicyTypes: []string{"Ingress"},
+		},
+	}
+	ks.withFields(kc, logrus.Fields{"name": net.Name}).Debug("creating a network policy")
+	res := ks.create(ctx, kc, kubePath(kc, "networking.k8s.io/v1", "networkpolicies"), policy)
+	if !res.IsSuccess() || !ks.conf.Multus {
+		return res
+	}
+
+	conf, err := multusConfig(kc, net)
+	if err != nil {
+		return entity.NewFatalResult(err)
+	}
+	return ks.create(ctx, kc, kubePath(kc, "k8s.cni.cncf.io/v1", "network-attachment-definitions"),
+		entity.NetworkAttachmentDefinition{
+			TypeMeta: entity.TypeMeta{APIVersion: "k8s.cni.cncf.io/v1", Kind: "NetworkAttachmentDefinition"},
+			Metadata: kubeMeta(kc, kubeObjectName(kc, net.Name), net.Labels),
+			Spec:     entity.NetworkAttachmentDefinitionSpec{Config: conf},
+		})
+}
+
+// RemoveNetwork removes the network policy of the network, and its attachment definition
+func (ks kubernetesService) RemoveNetwork(ctx context.Context, kc entity.KubeCli,
+	name string) entity.Result {
+
+	ks.withFields(kc, logrus.Fields{"name": name}).Debug("removing a network policy")
+	err := ks.repo.Delete(ctx, kubePath(kc, "networking.k8s.io/v1", "networkpolicies", kubeObjectName(kc, name)))
+	if err != nil || !ks.conf.Multus {
+		return entity.NewResult(err)
+	}
+	return entity.NewResult(ignoreNotFound(ks.repo.Delete(ctx,
+		kubePath(kc, "k8s.cni.cncf.io/v1", "network-attachment-definitions", kubeObjectName(kc, name)))))
+}
+
+// AttachNetwork puts the pod on the network. Interfaces cannot be added to a running pod,
+// so with multus, networks can only be attached before the container is started.
+func (ks kubernetesService) AttachNetwork(ctx context.Context, kc entity.KubeCli,
+	cn entity.ContainerNetwork) entity.Result {
+
+	ks.withFields(kc, logrus.Fields{"cmd": cn}).Info("attaching a network")
+	ok, err := ks.updatePending(ctx, kc, cn.Container, func(pod *entity.Pod) error {
+		return joinNetwork(kc, pod, cn.Network, cn.IP, cn.MacAddress, ks.conf.Multus)
+	})
+	if err != nil || ok {
+		return entity.NewResult(err)
+	}
+	if ks.conf.Multus {
+		return entity.NewFatalResult(fmt.Sprintf(
+			"cannot attach network \"%s\" to \"%s\", since it has already been started", cn.Network, cn.Container))
+	}
+	return entity.NewResult(ks.repo.Patch(ctx, podPath(kc, kubeObjectName(kc, cn.Container)),
+		repository.MergePatch, map[string]interface{}{"metadata": map[string]interface{}{
+			"labels": map[string]interface{}{kubeNetworkLabel(kc, cn.Network): "true"},
+		}}))
+}
+
+// DetachNetwork takes the pod off of the network
+func (ks kubernetesService) DetachNetwork(ctx context.Context, kc entity.KubeCli,
+	network string, container string) entity.Result {
+
+	ok, err := ks.updatePending(ctx, kc, container, func(pod *entity.Pod) error {
+		return leaveNetwork(kc, pod, network)
+	})
+	if err != nil || ok {
+		return entity.NewResult(err)
+	}
+	if ks.conf.Multus {
+		return entity.NewFatalResult(fmt.Sprintf(
+			"cannot detach network \"%s\" from \"%s\", since it has already been started", network, container))
+	}
+	err = ks.repo.Patch(ctx, podPath(kc, kubeObjectName(kc, container)), repository.MergePatch,
+		map[string]interface{}{"metadata": map[string]interface{}{
+			"labels": map[string]interface{}{kubeNetworkLabel(kc, network): nil},
+		}})
+	return entity.NewResult(ignoreNotFound(err))
+}
+
+// CreateVolume creates a persistent volume claim, global volumes need to be shared between nodes
+func (ks kubernetesService) CreateVolume(ctx context.Context, kc entity.KubeCli,
+	vol command.Volume) entity.Result {
+
+	pvc := entity.PersistentVolumeClaim{
+		TypeMeta: entity.TypeMeta{APIVersion: "v1", Kind: "PersistentVolumeClaim"},
+		Metadata: kubeMeta(kc, kubeObjectName(kc, vol.Name), vol.Labels),
+		Spec: entity.PersistentVolumeClaimSpec{
+			AccessModes: []string{"ReadWriteOnce"},
+			Resources: entity.ResourceRequirements{
+				Requests: map[string]string{"storage": ks.conf.VolumeSize},
+			},
+		},
+	}
+	if vol.Global {
+		pvc.Spec.AccessModes = []string{"ReadWriteMany"}
+	}
+	if len(ks.conf.StorageClass) > 0 {
+		pvc.Spec.StorageClassName = &ks.conf.StorageClass
+	}
+	return ks.create(ctx, kc, kubePath(kc, "", "persistentvolumeclaims"), pvc)
+}
+
+// RemoveVolume removes a persistent volume claim
+func (ks kubernetesService) RemoveVolume(ctx context.Context, kc entity.KubeCli, name string) entity.Result {
+	return entity.NewResult(ks.repo.Delete(ctx, kubePath(kc, "", "persistentvolumeclaims",
+		kubeObjectName(kc, name))))
+}
+
+// fileContents reads the file out of the archive it is fetched in
+func (ks kubernetesService) fileContents(kc entity.KubeCli, file command.File) ([]byte, error) {
+	rdr, err := ks.remote.GetTarReader(kc.Labels[command.DefinitionIDKey], file)
+	if err != nil {
+		return nil, err
+	}
+	tr := tar.NewReader(rdr)
+	_, err = tr.Next()
+	if err != nil {
+		return nil, err
+	}
+	data, err := ioutil.ReadAll(tr)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxConfigMapSize {
+		return nil, fmt.Errorf("file %s is too large to be placed in a pod", file.Meta.Filename)
+	}
+	return data, nil
+}
+
+// PlaceFileInContainer puts the file into a config map, and mounts it in the pod of the container.
+// Mounts cannot be added to a running pod, so this needs to happen before the container is started.
+func (ks kubernetesService) PlaceFileInContainer(ctx context.Context, kc entity.KubeCli,
+	containerName string, file command.File) entity.Result {
+
+	meta := map[string]interface{}{"container": containerName}
+	dst := file.Destination
+	if strings.HasSuffix(dst, "/") {
+		dst += filepath.Base(file.Meta.Filename)
+	}
+	data, err := ks.fileContents(kc, file)
+	if err != nil {
+		return entity.NewErrorResult(err).InjectMeta(meta)
+	}
+	sum := sha1.Sum([]byte(dst))
+	cmMeta := kubeMeta(kc, kubeObjectName(kc, containerName)+"-file-"+hex.EncodeToString(sum[:])[:10], nil)
+	cmMeta.Labels[kubeContainerLabel] = kubeObjectName(kc, containerName)
+	cm := entity.ConfigMap{
+		TypeMeta:   entity.TypeMeta{APIVersion: "v1", Kind: "ConfigMap"},
+		Metadata:   cmMeta,
+		BinaryData: map[string][]byte{"content": data},
+	}
+
+	ok, err := ks.updatePending(ctx, kc, containerName, func(pod *entity.Pod) error {
+		mode := file.Mode
+		pod.Spec.Volumes = append(pod.Spec.Volumes, entity.KubeVolume{
+			Name:      cm.Metadata.Name,
+			ConfigMap: &entity.ConfigMapVolumeSource{Name: cm.Metadata.Name, DefaultMode: &mode},
+		})
+		pod.Spec.Containers[0].VolumeMounts = append(pod.Spec.Containers[0].VolumeMounts, entity.VolumeMount{
+			Name:      cm.Metadata.Name,
+			MountPath: dst,
+			SubPath:   "content",
+		})
+		res := ks.create(ctx, kc, configMapPath(kc), cm)
+		return res.Error
+	})
+	if err != nil {
+		return entity.NewErrorResult(err).InjectMeta(meta)
+	}
+	if !ok {
+		return entity.NewFatalResult(fmt.Sprintf(
+			"cannot place a file in \"%s\", since it has already been started", containerName)).InjectMeta(meta)
+	}
+	return entity.NewSuccessResult().InjectMeta(meta)
+}
+
+// emulationDevice gives the script which finds the interface of the pod on the network, which is
+// eth0 unless the network is a multus attachment
+func (ks kubernetesService) emulationNetwork(ctx context.Context, kc entity.KubeCli,
+	networkName string) (types.NetworkResource, error) {
+
+	if !ks.conf.Multus {
+		return types.NetworkResource{}, nil
+	}
+	var nad entity.NetworkAttachmentDefinition
+	err := ks.repo.Get(ctx, kubePath(kc, "k8s.cni.cncf.io/v1", "network-attachment-definitions",
+		kubeObjectName(kc, networkName)), &nad)
+	if err != nil {
+		return types.NetworkResource{}, err
+	}
+	var conf struct {
+		IPAM struct {
+			Ranges [][]struct {
+				Subnet string `json:"subnet"`
+			} `json:"ranges"`
+		} `json:"ipam"`
+	}
+	err = json.Unmarshal([]byte(nad.Spec.Config), &conf)
+	if err != nil {
+		return types.NetworkResource{}, err
+	}
+	out := types.NetworkResource{Name: networkName}
+	for _, rng := range conf.IPAM.Ranges {
+		for _, subnet := range rng {
+			out.IPAM.Config = append(out.IPAM.Config, network.IPAMConfig{Subnet: subnet.Subnet})
+		}
+	}
+	return out, nil
+}
+
+// applyEmulation runs the commands against the interface of the pod on the network, from an
+// ephemeral container which shares the network namespace of the pod
+func (ks kubernetesService) applyEmulation(ctx context.Context, kc entity.KubeCli,
+	netem command.Netconf, cmds ...string) entity.Result {
+
+	meta := map[string]interface{}{
+		"container": netem.Container,
+		"network":   netem.Network,
+	}
+	net, err := ks.emulationNetwork(ctx, kc, netem.Network)
+	if err != nil {
+		return entity.NewErrorResult(err).InjectMeta(meta)
+	}
+	script := strings.Join(append([]string{"DEV=eth0"}, cmds...), " && ")
+	if len(net.IPAM.Config) > 0 {
+		script = emulationScript(net, cmds...)
+	}
+
+	name := "netem-" + strconv.FormatInt(time.Now().UnixNano(), 36)
+	ephemeral := entity.KubeContainer{
+		Name:            name,
+		Image:           ks.conf.NetemImage,
+		Command:         []string{"/bin/sh", "-c", script},
+		TargetContainer: kubeName(netem.Container),
+		SecurityContext: &entity.KubeSecurityContext{
+			Capabilities: &entity.KubeCapabilities{Add: []string{"NET_ADMIN"}},
+		},
+	}
+	ks.withFields(kc, logrus.Fields{"name": name, "script": script}).Debug("applying network emulation")
+	err = ks.repo.Patch(ctx, podPath(kc, kubeObjectName(kc, netem.Container), "ephemeralcontainers"),
+		repository.StrategicMergePatch, map[string]interface{}{
+			"spec": map[string]interface{}{"ephemeralContainers": []entity.KubeContainer{ephemeral}},
+		})
+	meta["ephemeralContainer"] = name
+	if err != nil {
+		return entity.NewErrorResult(err).InjectMeta(meta)
+	}
+	err = ks.waitEphemeral(ctx, kc, kubeObjectName(kc, netem.Container), name)
+	return entity.NewResult(err).InjectMeta(meta)
+}
+
+// waitEphemeral waits for the ephemeral container of the pod to exit, the error includes
+// the logs of the container if it failed
+func (ks kubernetesService) waitEphemeral(ctx context.Context, kc entity.KubeCli,
+	podName string, name string) error {
+
+	ctx, cancel := context.WithTimeout(ctx, ks.conf.NetemTimeout)
+	defer cancel()
+	ticker := time.NewTicker(kubePollInterval)
+	defer ticker.Stop()
+	for {
+		var pod entity.Pod
+		err := ks.repo.Get(ctx, podPath(kc, podName), &pod)
+		if err != nil && ctx.Err() == nil {
+			return err
+		}
+		for _, status := range pod.Status.EphemeralContainerStatuses {
+			term := status.State.Terminated
+			if status.Name != name || term == nil {
+				continue
+			}
+			if term.ExitCode == 0 {
+				return nil
+			}
+			var logs []byte
+			err = ks.repo.Get(ctx, podPath(kc, podName, "log")+"?container="+url.QueryEscape(name), &logs)
+			if err != nil {
+				ks.withFields(kc, logrus.Fields{"name": name, "error": err}).Warn(
+					"could not get the logs of the ephemeral container")
+			}
+			return fmt.Errorf("ephemeral container \"%s\" exited with %d: %s", name, term.ExitCode,
+				strings.TrimSpace(string(logs)))
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("ephemeral container \"%s\" did not finish: %v", name, ctx.Err())
+		case <-ticker.C:
+		}
+	}
+}
+
+// Emulation applies network emulation to all of the traffic of the pod on the network
+func (ks kubernetesService) Emulation(ctx context.Context, kc entity.KubeCli,
+	netem command.Netconf) entity.Result {
+
+	return ks.applyEmulation(ctx, kc, netem,
+		fmt.Sprintf("tc qdisc replace dev $DEV root netem%s", netemArgs(netem)))
+}
+
+// RemoveEmulation removes the network emulation from the pod on the network, if there is any
+func (ks kubernetesService) RemoveEmulation(ctx context.Context, kc entity.KubeCli,
+	netem command.Netconf) entity.Result {
+
+	return ks.applyEmulation(ctx, kc, netem, "(tc qdisc del dev $DEV root 2> /dev/null || true)")
+}
@@ -0,0 +1,341 @@
+/**
+ * Copyright 2019 Whiteblock Inc. All rights reserved.
+ * Use of this source code is governed by a BSD-style
+ * license that can be found in the LICENSE file.
+ */
+
+package service
+
+import (
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/whiteblock/genesis/pkg/entity"
+
+	"github.com/docker/distribution/reference"
+	"github.com/whiteblock/definition/command"
+)
+
+const (
+	// kubeLabelPrefix is the prefix of the labels which Genesis puts on the objects it creates
+	kubeLabelPrefix = "genesis.whiteblock.io/"
+
+	// kubeTestLabel holds the test an object belongs to
+	kubeTestLabel = kubeLabelPrefix + "test"
+
+	// kubeContainerLabel holds the container an object belongs to
+	kubeContainerLabel = kubeLabelPrefix + "container"
+
+	// kubeNetworkLabelPrefix is the prefix of the labels which put a pod on a network
+	kubeNetworkLabelPrefix = "network." + kubeLabelPrefix
+
+	// multusNetworksAnnotation selects the additional interfaces of a pod
+	multusNetworksAnnotation = "k8s.v1.cni.cncf.io/networks"
+)
+
+var (
+	kubeInvalidChars = regexp.MustCompile(`[^a-z0-9.-]+`)
+	kubeLabelValue   = regexp.MustCompile(`^(([A-Za-z0-9][-A-Za-z0-9_.]*)?[A-Za-z0-9])?$`)
+	kubeLabelKey     = regexp.MustCompile(`^([a-z0-9.-]{1,253}/)?[A-Za-z0-9]([-A-Za-z0-9_.]{0,61}[A-Za-z0-9])?$`)
+)
+
+// kubeName converts the name into a valid name for a Kubernetes object
+func kubeName(name string) string {
+	out := kubeInvalidChars.ReplaceAllString(strings.ToLower(name), "-")
+	out = strings.Trim(out, "-.")
+	if len(out) > 63 {
+		out = strings.Trim(out[:63], "-.")
+	}
+	return out
+}
+
+// kubeObjectName gives the name of an object of the test. The tests share the namespace, so the
+// names are prefixed by a hash of the test, which lets different tests use the same names.
+func kubeObjectName(kc entity.KubeCli, name string) string {
+	if len(kc.TestID) == 0 {
+		return kubeName(name)
+	}
+	sum := sha1.Sum([]byte(kc.TestID))
+	return kubeName("t" + hex.EncodeToString(sum[:])[:8] + "-" + name)
+}
+
+// kubeBridgeName gives the name of the bridge of the network of the test on the nodes, which has
+// to fit in the 15 characters of a linux interface name
+func kubeBridgeName(kc entity.KubeCli, network string) string {
+	sum := sha1.Sum([]byte(kc.TestID + "/" + network))
+	return "gen" + hex.EncodeToString(sum[:])[:12]
+}
+
+// kubeNetworkLabel gives the label which puts a pod of the test on the network
+func kubeNetworkLabel(kc entity.KubeCli, network string) string {
+	return kubeNetworkLabelPrefix + kubeObjectName(kc, network)
+}
+
+// kubeMeta gives the metadata of an object of the test. Labels which are not valid on
+// Kubernetes are kept as annotations instead.
+func kubeMeta(kc entity.KubeCli, name string, labels map[string]string) entity.ObjectMeta {
+	meta := entity.ObjectMeta{
+		Name:        kubeName(name),
+		Namespace:   kc.Namespace,
+		Labels:      map[string]string{},
+		Annotations: map[string]string{},
+	}
+	for _, set := range []map[string]string{kc.Labels, labels} {
+		for key, val := range set {
+			if kubeLabelKey.MatchString(key) && len(val) <= 63 && kubeLabelValue.MatchString(val) {
+				meta.Labels[key] = val
+			} else {
+				meta.Annotations[key] = val
+			}
+		}
+	}
+	if len(kc.TestID) > 0 {
+		meta.Labels[kubeTestLabel] = kubeName(kc.TestID)
+	}
+	return meta
+}
+
+// kubeResources converts the cpus and memory of the container into resource limits
+func kubeResources(cntr command.Container) (entity.ResourceRequirements, error) {
+	out := entity.ResourceRequirements{Limits: map[string]string{}}
+	if len(cntr.Cpus) > 0 {
+		cpus, err := strconv.ParseFloat(cntr.Cpus, 64)
+		if err != nil {
+			return out, err
+		}
+		if cpus > 0 {
+			out.Limits["cpu"] = fmt.Sprintf("%dm", int64(cpus*1000))
+		}
+	}
+	if len(cntr.Memory) > 0 {
+		mem, err := cntr.GetMemory()
+		if err != nil {
+			return out, err
+		}
+		if mem > 0 {
+			out.Limits["memory"] = strconv.FormatInt(mem, 10)
+		}
+	}
+	return out, nil
+}
+
+// kubePorts converts the port bindings of the container into container ports
+func kubePorts(cntr command.Container) []entity.ContainerPort {
+	out := []entity.ContainerPort{}
+	for protocol, ports := range map[string]map[int]int{"TCP": cntr.TCPPorts, "UDP": cntr.UDPPorts} {
+		for hostPort, containerPort := range ports {
+			out = append(out, entity.ContainerPort{
+				ContainerPort: containerPort,
+				HostPort:      hostPort,
+				Protocol:      protocol,
+			})
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Protocol != out[j].Protocol {
+			return out[i].Protocol < out[j].Protocol
+		}
+		return out[i].HostPort < out[j].HostPort
+	})
+	return out
+}
+
+// kubeEnv converts the environment of the container, sorted so that the pod is always the same
+func kubeEnv(cntr command.Container) []entity.EnvVar {
+	out := []entity.EnvVar{}
+	for key, val := range cntr.Environment {
+		out = append(out, entity.EnvVar{Name: key, Value: val})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+// kubeVolumes converts the volumes of the container into claims and the host volumes into host paths
+func kubeVolumes(kc entity.KubeCli, cntr command.Container) ([]entity.KubeVolume, []entity.VolumeMount) {
+	volumes := []entity.KubeVolume{}
+	mounts := []entity.VolumeMount{}
+	seen := map[string]bool{}
+	for _, vol := range cntr.Volumes {
+		name := kubeObjectName(kc, vol.Name)
+		if !seen[name] {
+			seen[name] = true
+			volumes = append(volumes, entity.KubeVolume{
+				Name:                  name,
+				PersistentVolumeClaim: &entity.PersistentVolumeClaimRef{ClaimName: name},
+			})
+		}
+		mounts = append(mounts, entity.VolumeMount{Name: name, MountPath: vol.Directory, ReadOnly: vol.ReadOnly})
+	}
+	keys := []string{}
+	for key := range cntr.HostVolumes {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		name := kubeName("host-" + key)
+		volumes = append(volumes, entity.KubeVolume{
+			Name: name,
+			HostPath: &entity.HostPathVolumeSource{
+				Path: fmt.Sprintf("/var/lib/docker/genesis/%s/%s", cntr.Name, key),
+				Type: "DirectoryOrCreate",
+			},
+		})
+		mounts = append(mounts, entity.VolumeMount{Name: name, MountPath: cntr.HostVolumes[key]})
+	}
+	return volumes, mounts
+}
+
+// registrySecret gives the secret which lets the pod pull its image with the credentials
+func registrySecret(kc entity.KubeCli, cntr command.Container) (entity.Secret, error) {
+	ref, err := reference.ParseNormalizedNamed(cntr.Image)
+	if err != nil {
+		return entity.Secret{}, err
+	}
+	auth := map[string]string{}
+	if len(cntr.RegistryToken) > 0 {
+		auth["registrytoken"] = cntr.RegistryToken
+	} else {
+		auth["username"] = cntr.Username
+		auth["password"] = cntr.Password
+		auth["auth"] = base64.StdEncoding.EncodeToString([]byte(cntr.Username + ":" + cntr.Password))
+	}
+	config, err := json.Marshal(map[string]interface{}{
+		"auths": map[string]interface{}{reference.Domain(ref): auth},
+	})
+	if err != nil {
+		return entity.Secret{}, err
+	}
+	meta := kubeMeta(kc, kubeObjectName(kc, cntr.Name+"-registry"), nil)
+	meta.Labels[kubeContainerLabel] = kubeObjectName(kc, cntr.Name)
+	return entity.Secret{
+		TypeMeta:   entity.TypeMeta{APIVersion: "v1", Kind: "Secret"},
+		Metadata:   meta,
+		Type:       "kubernetes.io/dockerconfigjson",
+		StringData: map[string]string{".dockerconfigjson": string(config)},
+	}, nil
+}
+
+// multusNetworks gets the additional interfaces of the pod
+func multusNetworks(pod entity.Pod) ([]entity.MultusNetwork, error) {
+	out := []entity.MultusNetwork{}
+	raw, ok := pod.Metadata.Annotations[multusNetworksAnnotation]
+	if !ok || len(raw) == 0 {
+		return out, nil
+	}
+	return out, json.Unmarshal([]byte(raw), &out)
+}
+
+// setMultusNetworks replaces the additional interfaces of the pod
+func setMultusNetworks(pod *entity.Pod, networks []entity.MultusNetwork) error {
+	if len(networks) == 0 {
+		delete(pod.Metadata.Annotations, multusNetworksAnnotation)
+		return nil
+	}
+	data, err := json.Marshal(networks)
+	if err != nil {
+		return err
+	}
+	if pod.Metadata.Annotations == nil {
+		pod.Metadata.Annotations = map[string]string{}
+	}
+	pod.Metadata.Annotations[multusNetworksAnnotation] = string(data)
+	return nil
+}
+
+// joinNetwork puts the pod on the network, as an additional interface if multus is used
+func joinNetwork(kc entity.KubeCli, pod *entity.Pod, network string, ip string, mac string,
+	multus bool) error {
+
+	pod.Metadata.Labels[kubeNetworkLabel(kc, network)] = "true"
+	if !multus {
+		return nil
+	}
+	networks, err := multusNetworks(*pod)
+	if err != nil {
+		return err
+	}
+	entry := entity.MultusNetwork{Name: kubeObjectName(kc, network), MAC: mac}
+	if len(ip) > 0 {
+		entry.IPs = []string{ip}
+	}
+	for i := range networks {
+		if networks[i].Name == entry.Name {
+			networks[i] = entry
+			return setMultusNetworks(pod, networks)
+		}
+	}
+	return setMultusNetworks(pod, append(networks, entry))
+}
+
+// leaveNetwork takes the pod off of the network
+func leaveNetwork(kc entity.KubeCli, pod *entity.Pod, network string) error {
+	delete(pod.Metadata.Labels, kubeNetworkLabel(kc, network))
+	networks, err := multusNetworks(*pod)
+	if err != nil {
+		return err
+	}
+	out := []entity.MultusNetwork{}
+	for _, entry := range networks {
+		if entry.Name != kubeObjectName(kc, network) {
+			out = append(out, entry)
+		}
+	}
+	return setMultusNetworks(pod, out)
+}
+
+// buildPod converts the container into a pod with a single container of the same name
+func (ks kubernetesService) buildPod(kc entity.KubeCli, cntr entity.Container) (entity.Pod, error) {
+	cntr = cntr.WithPrimaryNetwork()
+	resources, err := kubeResources(cntr.Container)
+	if err != nil {
+		return entity.Pod{}, err
+	}
+	volumes, mounts := kubeVolumes(kc, cntr.Container)
+	main := entity.KubeContainer{
+		Name:         kubeName(cntr.Name),
+		Image:        cntr.Image,
+		Env:          kubeEnv(cntr.Container),
+		Ports:        kubePorts(cntr.Container),
+		Resources:    resources,
+		VolumeMounts: mounts,
+	}
+	if len(cntr.EntryPoint) > 0 {
+		main.Command = []string{cntr.EntryPoint}
+		main.Args = cntr.Args
+	}
+
+	pod := entity.Pod{
+		TypeMeta: entity.TypeMeta{APIVersion: "v1", Kind: "Pod"},
+		Metadata: kubeMeta(kc, kubeObjectName(kc, cntr.Name), cntr.Labels),
+		Spec: entity.PodSpec{
+			Containers:    []entity.KubeContainer{main},
+			Volumes:       volumes,
+			RestartPolicy: "Never",
+		},
+	}
+	pod.Metadata.Labels[kubeContainerLabel] = pod.Metadata.Name
+	if !cntr.Credentials.Empty() {
+		pod.Spec.ImagePullSecrets = []entity.LocalObjectReference{{
+			Name: kubeObjectName(kc, cntr.Name+"-registry")}}
+	}
+	if len(cntr.Network) > 0 {
+		err = joinNetwork(kc, &pod, cntr.Network, cntr.IP, cntr.MacAddress, ks.conf.Multus)
+		if err != nil {
+			return pod, err
+		}
+	}
+	for _, attachment := range cntr.Networks {
+		err = joinNetwork(kc, &pod, attachment.Network, attachment.IP, attachment.MacAddress, ks.conf.Multus)
+		if err != nil {
+			return pod, err
+		}
+	}
+	return pod, nil
+}
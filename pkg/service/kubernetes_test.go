@@ -0,0 +1,464 @@
+/**
+ * Copyright 2019 Whiteblock Inc. All rights reserved.
+ * Use of this source code is governed by a BSD-style
+ * license that can be found in the LICENSE file.
+ */
+
+package service
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/whiteblock/genesis/pkg/config"
+	"github.com/whiteblock/genesis/pkg/entity"
+	"github.com/whiteblock/genesis/pkg/repository"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/whiteblock/definition/command"
+	"github.com/whiteblock/utility/common"
+)
+
+// fakeKubeAPI is an in memory stand in for the Kubernetes API server, which stores
+// the objects by their path. The ephemeral containers exit with exitCode, printing logs,
+// unless they are left running.
+type fakeKubeAPI struct {
+	mux     sync.Mutex
+	objects map[string]map[string]interface{}
+
+	exitCode int
+	logs     string
+	running  bool
+}
+
+func (f *fakeKubeAPI) get(path string) map[string]interface{} {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+	return f.objects[path]
+}
+
+func (f *fakeKubeAPI) set(path string, obj map[string]interface{}) {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+	f.objects[path] = obj
+}
+
+func mergePatch(dst map[string]interface{}, patch map[string]interface{}) {
+	for key, val := range patch {
+		sub, ok := val.(map[string]interface{})
+		if val == nil {
+			delete(dst, key)
+		} else if existing, isMap := dst[key].(map[string]interface{}); ok && isMap {
+			mergePatch(existing, sub)
+		} else {
+			dst[key] = val
+		}
+	}
+}
+
+func matchesSelector(obj map[string]interface{}, selector string) bool {
+	parts := strings.SplitN(selector, "=", 2)
+	labels, _ := obj["metadata"].(map[string]interface{})["labels"].(map[string]interface{})
+	return len(parts) == 2 && labels[parts[0]] == parts[1]
+}
+
+func (f *fakeKubeAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+	var body map[string]interface{}
+	json.NewDecoder(r.Body).Decode(&body)
+	path := r.URL.Path
+	notFound := func() {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"code":404,"reason":"NotFound","message":"not found"}`))
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		if strings.HasSuffix(path, "/log") {
+			w.Write([]byte(f.logs))
+			return
+		}
+		obj, ok := f.objects[path]
+		if !ok {
+			notFound()
+			return
+		}
+		json.NewEncoder(w).Encode(obj)
+	case http.MethodPost:
+		path += "/" + body["metadata"].(map[string]interface{})["name"].(string)
+		if _, ok := f.objects[path]; ok {
+			w.WriteHeader(http.StatusConflict)
+			w.Write([]byte(`{"code":409,"reason":"AlreadyExists","message":"already exists"}`))
+			return
+		}
+		f.objects[path] = body
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte("{}"))
+	case http.MethodPatch:
+		if strings.HasSuffix(path, "/ephemeralcontainers") {
+			path = strings.TrimSuffix(path, "/ephemeralcontainers")
+			obj, ok := f.objects[path]
+			if !ok {
+				notFound()
+				return
+			}
+			spec := obj["spec"].(map[string]interface{})
+			existing, _ := spec["ephemeralContainers"].([]interface{})
+			added := body["spec"].(map[string]interface{})["ephemeralContainers"].([]interface{})
+			spec["ephemeralContainers"] = append(existing, added...)
+			if !f.running {
+				status, _ := obj["status"].(map[string]interface{})
+				if status == nil {
+					status = map[string]interface{}{}
+					obj["status"] = status
+				}
+				statuses, _ := status["ephemeralContainerStatuses"].([]interface{})
+				for _, cntr := range added {
+					statuses = append(statuses, map[string]interface{}{
+						"name": cntr.(map[string]interface{})["name"],
+						"state": map[string]interface{}{
+							"terminated": map[string]interface{}{"exitCode": f.exitCode},
+						},
+					})
+				}
+				status["ephemeralContainerStatuses"] = statuses
+			}
+			w.Write([]byte("{}"))
+			return
+		}
+		obj, ok := f.objects[path]
+		if !ok {
+			notFound()
+			return
+		}
+		mergePatch(obj, body)
+		w.Write([]byte("{}"))
+	case http.MethodDelete:
+		if selector := r.URL.Query().Get("labelSelector"); len(selector) > 0 {
+			for key, obj := range f.objects {
+				if strings.HasPrefix(key, path+"/") && matchesSelector(obj, selector) {
+					delete(f.objects, key)
+				}
+			}
+			w.Write([]byte("{}"))
+			return
+		}
+		if _, ok := f.objects[path]; !ok {
+			notFound()
+			return
+		}
+		delete(f.objects, path)
+		w.Write([]byte("{}"))
+	}
+}
+
+type testRemoteSources struct {
+	data []byte
+
+	// uploads are the contents of the files put to the source, by id
+	uploads map[string][]byte
+}
+
+func (trs testRemoteSources) GetTarReader(testnetID string, file command.File) (io.Reader, error) {
+	buf := new(bytes.Buffer)
+	tw := tar.NewWriter(buf)
+	err := tw.WriteHeader(&tar.Header{Name: file.Meta.Filename, Mode: file.Mode, Size: int64(len(trs.data))})
+	if err != nil {
+		return nil, err
+	}
+	_, err = tw.Write(trs.data)
+	if err != nil {
+		return nil, err
+	}
+	return buf, tw.Close()
+}
+
+func (trs testRemoteSources) StreamTar(testnetID string, file command.File) (io.ReadCloser, error) {
+	rdr, err := trs.GetTarReader(testnetID, file)
+	return ioutil.NopCloser(rdr), err
+}
+
+func (trs testRemoteSources) PutFile(testnetID string, file command.File, content io.Reader) error {
+	data, err := ioutil.ReadAll(content)
+	if err != nil {
+		return err
+	}
+	trs.uploads[file.ID] = data
+	return nil
+}
+
+func newTestKubernetesService(t *testing.T, conf config.Kubernetes) (KubernetesService, *fakeKubeAPI, func()) {
+	api := &fakeKubeAPI{objects: map[string]map[string]interface{}{}}
+	server := httptest.NewServer(api)
+	conf.APIServer = server.URL
+	conf.VolumeSize = "1Gi"
+	conf.NetemImage = "iproute2"
+	if conf.NetemTimeout == 0 {
+		conf.NetemTimeout = time.Second
+	}
+	log := logrus.New()
+	log.SetOutput(ioutil.Discard)
+	ks := NewKubernetesService(repository.NewKubernetesRepository(conf, log), conf,
+		testRemoteSources{data: []byte("hello")}, log)
+	return ks, api, server.Close
+}
+
+func TestKubernetesService_ContainerLifecycle(t *testing.T) {
+	ks, api, done := newTestKubernetesService(t, config.Kubernetes{})
+	defer done()
+	kc := entity.KubeCli{Namespace: "genesis", TestID: "Test1", Labels: map[string]string{"org": "whiteblock"}}
+	ctx := context.Background()
+	podPath := "/api/v1/namespaces/genesis/pods/" + kubeObjectName(kc, "Node_0")
+	pendingPath := "/api/v1/namespaces/genesis/configmaps/" + kubeObjectName(kc, "Node_0") + "-pending"
+
+	cntr := entity.Container{Container: command.Container{
+		Name:        "Node_0",
+		Image:       "alpine",
+		EntryPoint:  "/bin/sh",
+		Args:        []string{"-c", "sleep 10"},
+		Environment: map[string]string{"B": "2", "A": "1"},
+		Cpus:        "1.5",
+		Network:     "net1",
+	}}
+	res := ks.CreateContainer(ctx, kc, cntr)
+	require.NoError(t, res.Error)
+	require.NotNil(t, api.get(pendingPath))
+
+	res = ks.PlaceFileInContainer(ctx, kc, "Node_0", command.File{
+		Mode:        0644,
+		Destination: "/etc/",
+		Meta:        common.Metadata{Filename: "genesis.json"},
+	})
+	require.NoError(t, res.Error)
+
+	res = ks.AttachNetwork(ctx, kc, entity.ContainerNetwork{ContainerNetwork: command.ContainerNetwork{
+		Container: "Node_0",
+		Network:   "net2",
+	}})
+	require.NoError(t, res.Error)
+
+	res = ks.StartContainer(ctx, kc, command.StartContainer{Name: "Node_0"})
+	require.NoError(t, res.Error)
+	assert.Nil(t, api.get(pendingPath))
+
+	raw := api.get(podPath)
+	require.NotNil(t, raw)
+	data, err := json.Marshal(raw)
+	require.NoError(t, err)
+	var pod entity.Pod
+	require.NoError(t, json.Unmarshal(data, &pod))
+
+	assert.Equal(t, "whiteblock", pod.Metadata.Labels["org"])
+	assert.Equal(t, "test1", pod.Metadata.Labels[kubeTestLabel])
+	assert.Equal(t, "true", pod.Metadata.Labels[kubeNetworkLabel(kc, "net1")])
+	assert.Equal(t, "true", pod.Metadata.Labels[kubeNetworkLabel(kc, "net2")])
+	require.Len(t, pod.Spec.Containers, 1)
+	main := pod.Spec.Containers[0]
+	assert.Equal(t, []string{"/bin/sh"}, main.Command)
+	assert.Equal(t, []string{"-c", "sleep 10"}, main.Args)
+	assert.Equal(t, []entity.EnvVar{{Name: "A", Value: "1"}, {Name: "B", Value: "2"}}, main.Env)
+	assert.Equal(t, "1500m", main.Resources.Limits["cpu"])
+	require.Len(t, main.VolumeMounts, 1)
+	assert.Equal(t, "/etc/genesis.json", main.VolumeMounts[0].MountPath)
+	require.NotNil(t, api.get("/api/v1/namespaces/genesis/configmaps/"+main.VolumeMounts[0].Name))
+
+	res = ks.PlaceFileInContainer(ctx, kc, "Node_0", command.File{Destination: "/etc/other"})
+	assert.Error(t, res.Error)
+	assert.True(t, res.IsFatal())
+
+	res = ks.DetachNetwork(ctx, kc, "net2", "Node_0")
+	require.NoError(t, res.Error)
+	labels := api.get(podPath)["metadata"].(map[string]interface{})["labels"]
+	assert.NotContains(t, labels, kubeNetworkLabel(kc, "net2"))
+
+	res = ks.Emulation(ctx, kc, command.Netconf{Container: "Node_0", Network: "net1", Delay: 100})
+	require.NoError(t, res.Error)
+	spec := api.get(podPath)["spec"].(map[string]interface{})
+	assert.Len(t, spec["ephemeralContainers"], 1)
+
+	res = ks.RemoveContainer(ctx, kc, "Node_0")
+	require.NoError(t, res.Error)
+	assert.Nil(t, api.get(podPath))
+	assert.Nil(t, api.get("/api/v1/namespaces/genesis/configmaps/"+main.VolumeMounts[0].Name))
+}
+
+func TestKubernetesService_Emulation_Failure(t *testing.T) {
+	kubePollInterval = time.Millisecond
+	defer func() { kubePollInterval = time.Second }()
+	ks, api, done := newTestKubernetesService(t, config.Kubernetes{NetemTimeout: 50 * time.Millisecond})
+	defer done()
+	kc := entity.KubeCli{Namespace: "genesis", TestID: "Test1"}
+	podPath := "/api/v1/namespaces/genesis/pods/" + kubeObjectName(kc, "Node_0")
+	netem := command.Netconf{Container: "Node_0", Network: "net1", Loss: 10}
+
+	api.set(podPath, map[string]interface{}{"spec": map[string]interface{}{}})
+	api.exitCode = 2
+	api.logs = "RTNETLINK answers: Operation not permitted\n"
+	res := ks.Emulation(context.Background(), kc, netem)
+	require.Error(t, res.Error)
+	assert.Contains(t, res.Error.Error(), "exited with 2")
+	assert.Contains(t, res.Error.Error(), "RTNETLINK answers: Operation not permitted")
+
+	api.mux.Lock()
+	api.running = true
+	api.mux.Unlock()
+	res = ks.Emulation(context.Background(), kc, netem)
+	require.Error(t, res.Error)
+	assert.Contains(t, res.Error.Error(), "did not finish")
+}
+
+func TestKubernetesService_SharedNamespace(t *testing.T) {
+	ks, api, done := newTestKubernetesService(t, config.Kubernetes{})
+	defer done()
+	ctx := context.Background()
+	tests := []entity.KubeCli{{Namespace: "genesis", TestID: "Test1"}, {Namespace: "genesis", TestID: "Test2"}}
+
+	for _, kc := range tests {
+		res := ks.CreateVolume(ctx, kc, command.Volume{Name: "data"})
+		require.NoError(t, res.Error)
+		res = ks.CreateContainer(ctx, kc, entity.Container{Container: command.Container{
+			Name: "node0", Image: "alpine", Volumes: []command.Mount{{Name: "data", Directory: "/data"}}}})
+		require.NoError(t, res.Error)
+		res = ks.StartContainer(ctx, kc, command.StartContainer{Name: "node0"})
+		require.NoError(t, res.Error)
+	}
+	assert.NotEqual(t, kubeObjectName(tests[0], "node0"), kubeObjectName(tests[1], "node0"))
+	for _, kc := range tests {
+		pod := api.get("/api/v1/namespaces/genesis/pods/" + kubeObjectName(kc, "node0"))
+		require.NotNil(t, pod)
+		assert.Equal(t, kubeName(kc.TestID),
+			pod["metadata"].(map[string]interface{})["labels"].(map[string]interface{})[kubeTestLabel])
+		assert.NotNil(t, api.get("/api/v1/namespaces/genesis/persistentvolumeclaims/"+kubeObjectName(kc, "data")))
+	}
+
+	res := ks.RemoveContainer(ctx, tests[0], "node0")
+	require.NoError(t, res.Error)
+	assert.Nil(t, api.get("/api/v1/namespaces/genesis/pods/"+kubeObjectName(tests[0], "node0")))
+	assert.NotNil(t, api.get("/api/v1/namespaces/genesis/pods/"+kubeObjectName(tests[1], "node0")))
+
+	res = ks.StartContainer(ctx, tests[1], command.StartContainer{Name: "node0"})
+	assert.NoError(t, res.Error, "a retried start adopts the pod of the same test")
+
+	other := entity.KubeCli{Namespace: "genesis"}
+	res = ks.CreateContainer(ctx, other, entity.Container{Container: command.Container{
+		Name: kubeObjectName(tests[1], "node0"), Image: "alpine"}})
+	require.NoError(t, res.Error)
+	res = ks.StartContainer(ctx, other, command.StartContainer{Name: kubeObjectName(tests[1], "node0")})
+	assert.True(t, res.IsFatal(), "the pod of another test is not taken")
+}
+
+func TestKubernetesService_StartContainer_Attach(t *testing.T) {
+	kubePollInterval = time.Millisecond
+	defer func() { kubePollInterval = time.Second }()
+
+	ks, api, done := newTestKubernetesService(t, config.Kubernetes{})
+	defer done()
+	kc := entity.KubeCli{Namespace: "genesis"}
+	ctx := context.Background()
+
+	res := ks.CreateContainer(ctx, kc, entity.Container{Container: command.Container{Name: "task", Image: "alpine"}})
+	require.NoError(t, res.Error)
+	res = ks.StartContainer(ctx, kc, command.StartContainer{Name: "task"})
+	require.NoError(t, res.Error)
+
+	pod := api.get("/api/v1/namespaces/genesis/pods/task")
+	pod["status"] = map[string]interface{}{
+		"phase": "Failed",
+		"containerStatuses": []interface{}{map[string]interface{}{
+			"name":  "task",
+			"state": map[string]interface{}{"terminated": map[string]interface{}{"exitCode": 3}},
+		}},
+	}
+	api.set("/api/v1/namespaces/genesis/pods/task", pod)
+
+	res = ks.StartContainer(ctx, kc, command.StartContainer{Name: "task", Attach: true,
+		Timeout: command.Timeout{Time: command.Time{Duration: time.Second}}})
+	assert.True(t, res.IsFatal())
+	assert.Contains(t, res.Error.Error(), "exited with 3")
+
+	res = ks.StartContainer(ctx, kc, command.StartContainer{Name: "task", Attach: true, IgnoreExitCode: true,
+		Timeout: command.Timeout{Time: command.Time{Duration: time.Second}}})
+	assert.NoError(t, res.Error)
+
+	res = ks.StartContainer(ctx, kc, command.StartContainer{Name: "missing"})
+	assert.True(t, res.IsFatal())
+}
+
+func TestKubernetesService_Network(t *testing.T) {
+	ks, api, done := newTestKubernetesService(t, config.Kubernetes{Multus: true})
+	defer done()
+	kc := entity.KubeCli{Namespace: "genesis"}
+	ctx := context.Background()
+
+	net := entity.Network{}
+	net.Name = "net1"
+	res := ks.CreateNetwork(ctx, kc, net)
+	assert.True(t, res.IsFatal())
+
+	net.Subnet = "10.1.0.0/24"
+	res = ks.CreateNetwork(ctx, kc, net)
+	require.NoError(t, res.Error)
+	assert.NotNil(t, api.get("/apis/networking.k8s.io/v1/namespaces/genesis/networkpolicies/net1"))
+	nad := api.get("/apis/k8s.cni.cncf.io/v1/namespaces/genesis/network-attachment-definitions/net1")
+	require.NotNil(t, nad)
+	assert.Contains(t, nad["spec"].(map[string]interface{})["config"], "10.1.0.0/24")
+
+	res = ks.CreateNetwork(ctx, kc, net)
+	assert.NoError(t, res.Error)
+
+	res = ks.CreateContainer(ctx, kc, entity.Container{Container: command.Container{
+		Name:    "node",
+		Image:   "alpine",
+		Network: "net1",
+		IP:      "10.1.0.5",
+	}})
+	require.NoError(t, res.Error)
+	res = ks.StartContainer(ctx, kc, command.StartContainer{Name: "node"})
+	require.NoError(t, res.Error)
+	annotations := api.get("/api/v1/namespaces/genesis/pods/node")["metadata"].(map[string]interface{})["annotations"]
+	assert.JSONEq(t, `[{"name":"net1","ips":["10.1.0.5"]}]`,
+		annotations.(map[string]interface{})[multusNetworksAnnotation].(string))
+
+	res = ks.AttachNetwork(ctx, kc, entity.ContainerNetwork{ContainerNetwork: command.ContainerNetwork{
+		Container: "node",
+		Network:   "net1",
+	}})
+	assert.True(t, res.IsFatal())
+
+	res = ks.RemoveNetwork(ctx, kc, "net1")
+	require.NoError(t, res.Error)
+	assert.Nil(t, api.get("/apis/networking.k8s.io/v1/namespaces/genesis/networkpolicies/net1"))
+	assert.Nil(t, api.get("/apis/k8s.cni.cncf.io/v1/namespaces/genesis/network-attachment-definitions/net1"))
+}
+
+func TestKubernetesService_Volume(t *testing.T) {
+	ks, api, done := newTestKubernetesService(t, config.Kubernetes{StorageClass: "nfs"})
+	defer done()
+	kc := entity.KubeCli{Namespace: "genesis"}
+
+	res := ks.CreateVolume(context.Background(), kc, command.Volume{Name: "data", Global: true})
+	require.NoError(t, res.Error)
+	pvc := api.get("/api/v1/namespaces/genesis/persistentvolumeclaims/data")
+	require.NotNil(t, pvc)
+	spec := pvc["spec"].(map[string]interface{})
+	assert.Equal(t, []interface{}{"ReadWriteMany"}, spec["accessModes"])
+	assert.Equal(t, "nfs", spec["storageClassName"])
+
+	res = ks.RemoveVolume(context.Background(), kc, "data")
+	require.NoError(t, res.Error)
+	res = ks.RemoveVolume(context.Background(), kc, "data")
+	assert.Error(t, res.Error)
+}
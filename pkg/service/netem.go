@@ -0,0 +1,272 @@
+/**
+ * Copyright 2019 Whiteblock Inc. All rights reserved.
+ * Use of this source code is governed by a BSD-style
+ * license that can be found in the LICENSE file.
+ */
+
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/whiteblock/genesis/pkg/entity"
+
+	"github.com/docker/docker/api/types"
+	"github.com/whiteblock/definition/command"
+)
+
+const (
+	// linkRateCeiling is the bandwidth given to traffic without a rate constraint
+	linkRateCeiling = "10gbit"
+
+	// linkClassOffset is the first htb class id used for the links
+	linkClassOffset = 10
+)
+
+// netemArgs converts the netconf into the arguments for a netem qdisc
+func netemArgs(netem command.Netconf) string {
+	out := ""
+	if netem.Limit > 0 {
+		out += fmt.Sprintf(" limit %d", netem.Limit)
+	}
+
+	if netem.Loss > 0 {
+		out += fmt.Sprintf(" loss %.4f", netem.Loss)
+	}
+
+	if netem.Delay > 0 {
+		out += fmt.Sprintf(" delay %dus", netem.Delay)
+	}
+
+	if len(netem.Rate) > 0 {
+		out += fmt.Sprintf(" rate %s", netem.Rate)
+	}
+
+	if netem.Duplication > 0 {
+		out += fmt.Sprintf(" duplicate %.4f", netem.Duplication)
+	}
+
+	if netem.Corrupt > 0 {
+		out += fmt.Sprintf(" corrupt %.4f", netem.Corrupt)
+	}
+
+	if netem.Reorder > 0 {
+		out += fmt.Sprintf(" reorder %.4f", netem.Reorder)
+	}
+	return out
+}
+
+// isIPv6 checks whether the address is an IPv6 address
+func isIPv6(addr string) bool {
+	ip := net.ParseIP(addr)
+	return ip != nil && ip.To4() == nil
+}
+
+// netemDevice gives the shell expression which finds the interface of the container on the given
+// network, by looking for an address in any of the subnets of the network, IPv4 or IPv6
+func netemDevice(network types.NetworkResource) string {
+	lookups := []string{}
+	for _, conf := range network.IPAM.Config {
+		if len(conf.Subnet) > 0 {
+			lookups = append(lookups, fmt.Sprintf("ip -o addr show to %s", conf.Subnet))
+		}
+	}
+	return fmt.Sprintf("$((%s) | sed -n 's/^[0-9]*: *\\([^ @]*\\).*/\\1/p' | head -n 1)",
+		strings.Join(lookups, "; "))
+}
+
+// emulationScript gives a script which runs the given commands against $DEV, the
+// interface of the container on the given network
+func emulationScript(network types.NetworkResource, cmds ...string) string {
+	return strings.Join(append([]string{
+		fmt.Sprintf("DEV=%s", netemDevice(network)),
+		`[ -n "$DEV" ]`,
+	}, cmds...), " && ")
+}
+
+// linkEmulationCmds builds a htb tree with a netem qdisc for each link, and u32 filters
+// which send the traffic to the class of the link by destination ip. Traffic to any other
+// destination falls through to the default class, which is left unimpaired. Whatever was
+// applied before is removed first, so that it can be applied again.
+func linkEmulationCmds(links []command.Netconf, ips [][]string) []string {
+	cmds := []string{
+		"(tc qdisc del dev $DEV root 2> /dev/null || true)",
+		"tc qdisc add dev $DEV root handle 1: htb default 1",
+		fmt.Sprintf("tc class add dev $DEV parent 1: classid 1:1 htb rate %s", linkRateCeiling),
+	}
+	for i, link := range links {
+		class := fmt.Sprintf("%x", i+linkClassOffset)
+		rate := link.Rate
+		if len(rate) == 0 {
+			rate = linkRateCeiling
+		}
+		link.Rate = "" // the bandwidth is constrained by the htb class instead
+		cmds = append(cmds,
+			fmt.Sprintf("tc class add dev $DEV parent 1: classid 1:%s htb rate %s", class, rate),
+			fmt.Sprintf("tc qdisc add dev $DEV parent 1:%s handle %s: netem%s", class, class, netemArgs(link)),
+		)
+		for _, ip := range ips[i] {
+			if isIPv6(ip) {
+				cmds = append(cmds, fmt.Sprintf(
+					"tc filter add dev $DEV protocol ipv6 parent 1: prio 2 u32 match ip6 dst %s/128 flowid 1:%s",
+					ip, class))
+			} else {
+				cmds = append(cmds, fmt.Sprintf(
+					"tc filter add dev $DEV protocol ip parent 1: prio 1 u32 match ip dst %s/32 flowid 1:%s",
+					ip, class))
+			}
+		}
+	}
+	return cmds
+}
+
+func (ds dockerService) getEmulationNetwork(ctx context.Context, cli entity.DockerCli,
+	networkName string) (types.NetworkResource, error) {
+
+	network, err := ds.repo.GetNetworkByName(ctx, cli, networkName)
+	if err != nil {
+		return network, err
+	}
+	for _, conf := range network.IPAM.Config {
+		if len(conf.Subnet) > 0 {
+			return network, nil
+		}
+	}
+	return network, fmt.Errorf("network \"%s\" does not have a subnet", networkName)
+}
+
+// runEmulation runs the given script in a side car which shares the network namespace of the container
+func (ds dockerService) runEmulation(ctx context.Context, cli entity.DockerCli,
+	containerName string, name string, script string) (string, error) {
+
+	return ds.runSidecar(ctx, cli, sidecar{
+		Name:   name,
+		Image:  ds.conf.NetemImage,
+		Target: containerName,
+		Cmd:    []string{"/bin/sh", "-c", script},
+		CapAdd: []string{"NET_ADMIN"},
+	})
+}
+
+// applyEmulation runs the given commands against the interface of the container on the network
+func (ds dockerService) applyEmulation(ctx context.Context, cli entity.DockerCli,
+	containerName string, networkName string, cmds ...string) entity.Result {
+
+	net, err := ds.getEmulationNetwork(ctx, cli, networkName)
+	if err != nil {
+		return entity.NewErrorResult(err)
+	}
+	_, err = ds.runEmulation(ctx, cli, containerName, containerName+"-"+net.ID, emulationScript(net, cmds...))
+	return entity.NewResult(err).InjectMeta(map[string]interface{}{
+		"container": containerName,
+		"network":   networkName,
+	})
+}
+
+// containerIPs gets the IPv4 and IPv6 addresses of a container on the given network
+func (ds dockerService) containerIPs(ctx context.Context, cli entity.DockerCli,
+	containerName string, networkName string) ([]string, error) {
+
+	cntr, err := cli.ContainerInspect(ctx, containerName)
+	if err != nil {
+		return nil, err
+	}
+	if cntr.NetworkSettings == nil {
+		return nil, fmt.Errorf("container \"%s\" does not have any networks", containerName)
+	}
+	out := []string{}
+	endpoint, ok := cntr.NetworkSettings.Networks[networkName]
+	if ok && endpoint != nil {
+		for _, ip := range []string{endpoint.IPAddress, endpoint.GlobalIPv6Address} {
+			if len(ip) > 0 {
+				out = append(out, ip)
+			}
+		}
+	}
+	if len(out) == 0 {
+		return nil, fmt.Errorf("container \"%s\" does not have an address on network \"%s\"",
+			containerName, networkName)
+	}
+	return out, nil
+}
+
+// Emulation applies network emulation to all of the traffic of the container on the network.
+// Applying it again replaces it, so any parameters which are left out are reset.
+func (ds dockerService) Emulation(ctx context.Context, cli entity.DockerCli,
+	netem command.Netconf) entity.Result {
+
+	return ds.applyEmulation(ctx, cli, netem.Container, netem.Network,
+		fmt.Sprintf("tc qdisc replace dev $DEV root netem%s", netemArgs(netem)))
+}
+
+// ChangeEmulation changes the parameters of the network emulation which is already applied
+func (ds dockerService) ChangeEmulation(ctx context.Context, cli entity.DockerCli,
+	netem command.Netconf) entity.Result {
+
+	return ds.applyEmulation(ctx, cli, netem.Container, netem.Network,
+		fmt.Sprintf("tc qdisc change dev $DEV root netem%s", netemArgs(netem)))
+}
+
+// RemoveEmulation removes the network emulation from the container on the network, if there is any
+func (ds dockerService) RemoveEmulation(ctx context.Context, cli entity.DockerCli,
+	netem command.Netconf) entity.Result {
+
+	return ds.applyEmulation(ctx, cli, netem.Container, netem.Network,
+		"(tc qdisc del dev $DEV root 2> /dev/null || true)")
+}
+
+// ShowEmulation lists the qdiscs which are applied to the container, only on the given network
+// if one is given
+func (ds dockerService) ShowEmulation(ctx context.Context, cli entity.DockerCli,
+	cn command.ContainerNetwork) entity.Result {
+
+	name := cn.Container + "-qdiscs"
+	script := "tc -j qdisc show"
+	if len(cn.Network) > 0 {
+		net, err := ds.getEmulationNetwork(ctx, cli, cn.Network)
+		if err != nil {
+			return entity.NewErrorResult(err)
+		}
+		name += "-" + net.ID
+		script = emulationScript(net, "tc -j qdisc show dev $DEV")
+	}
+
+	out, err := ds.runEmulation(ctx, cli, cn.Container, name, script)
+	if err != nil {
+		return entity.NewErrorResult(err)
+	}
+	var qdiscs []interface{}
+	err = json.Unmarshal([]byte(out), &qdiscs)
+	if err != nil {
+		return entity.NewFatalResult(err).InjectMeta(map[string]interface{}{
+			"output": out,
+		})
+	}
+	return entity.NewSuccessResult().InjectMeta(map[string]interface{}{
+		"container": cn.Container,
+		"network":   cn.Network,
+		"qdiscs":    qdiscs,
+	})
+}
+
+// LinkEmulation applies network emulation to the traffic from a container to each of the given peers
+func (ds dockerService) LinkEmulation(ctx context.Context, cli entity.DockerCli,
+	le entity.LinkEmulation) entity.Result {
+
+	ips := make([][]string, len(le.Links))
+	for i, link := range le.Links {
+		var err error
+		ips[i], err = ds.containerIPs(ctx, cli, link.Container, le.Network)
+		if err != nil {
+			return entity.NewErrorResult(err).InjectMeta(map[string]interface{}{
+				"container": link.Container,
+				"network":   le.Network,
+			})
+		}
+	}
+	return ds.applyEmulation(ctx, cli, le.Container, le.Network, linkEmulationCmds(le.Links, ips)...)
+}
@@ -0,0 +1,173 @@
+/**
+ * Copyright 2019 Whiteblock Inc. All rights reserved.
+ * Use of this source code is governed by a BSD-style
+ * license that can be found in the LICENSE file.
+ */
+
+package service
+
+import (
+	"context"
+	"fmt"
+	"path"
+
+	"github.com/whiteblock/genesis/pkg/entity"
+
+	"github.com/whiteblock/definition/command"
+)
+
+const (
+	// nfsExportDir is the directory of the nfs container which is exported
+	nfsExportDir = "/exports"
+
+	// nfsExportsVolume is the docker volume which holds what the nfs container exports
+	nfsExportsVolume = "genesis-nfs-exports"
+)
+
+// nfsVolumes shares global volumes by serving them from an nfs server on their first host,
+// which the docker daemons of the hosts mount with the local driver
+type nfsVolumes struct {
+	dockerService
+}
+
+func (nv nfsVolumes) driverOpts(server string, name string) map[string]string {
+	return map[string]string{
+		"type":   "nfs",
+		"o":      fmt.Sprintf("addr=%s,rw,nfsvers=4", server),
+		"device": ":/" + name,
+	}
+}
+
+// Share starts the nfs server on the first host. The other hosts only need to be able to mount
+// nfs.
+func (nv nfsVolumes) Share(ctx context.Context, ecli entity.DockerCli, vs command.VolumeShare) entity.Result {
+	cli, err := nv.CreateClient2(vs.Hosts[0], ecli.TestID)
+	if err != nil {
+		return entity.NewErrorResult(err)
+	}
+	defer cli.Close()
+
+	err = nv.startSideCar(ctx, cli, entity.ContainerSpec{
+		Name:        NFSContainerName,
+		Image:       nv.conf.NFSImage,
+		Env:         []string{"SHARED_DIRECTORY=" + nfsExportDir},
+		Privileged:  true,
+		NetworkMode: "host",
+		Mounts: []entity.MountSpec{{
+			Type:   entity.VolumeMountType,
+			Source: nfsExportsVolume,
+			Target: nfsExportDir,
+		}},
+	})
+	return entity.NewResult(err).InjectMeta(map[string]interface{}{"server": vs.Hosts[0]})
+}
+
+// Unshare removes the nfs server from the first host, along with everything it exported
+func (nv nfsVolumes) Unshare(ctx context.Context, ecli entity.DockerCli, vs command.VolumeShare) entity.Result {
+	cli, err := nv.CreateClient2(vs.Hosts[0], ecli.TestID)
+	if err != nil {
+		return entity.NewErrorResult(err)
+	}
+	defer cli.Close()
+
+	err = nv.removeSideCar(ctx, cli, NFSContainerName)
+	if err != nil {
+		return entity.NewErrorResult(err)
+	}
+	return entity.NewResult(nv.unmountGlobalVolume(ctx, cli, nfsExportsVolume))
+}
+
+// Create exports a directory for the volume from the nfs server on the first host, and creates
+// a docker volume which mounts it on each of the hosts
+func (nv nfsVolumes) Create(ctx context.Context, ecli entity.DockerCli, vol command.Volume) entity.Result {
+	clients, err := nv.hostClients(ecli, vol.Hosts)
+	if err != nil {
+		return entity.NewErrorResult(err)
+	}
+	defer closeClients(clients)
+
+	err = nv.repo.Exec(ctx, clients[0], NFSContainerName, entity.Exec{
+		Cmd:        []string{"mkdir", "-p", path.Join(nfsExportDir, vol.Name)},
+		Privileged: true,
+		Retries:    5,
+	})
+	if err != nil {
+		return entity.NewErrorResult(err)
+	}
+	err = forEachHost(vol.Hosts, func(i int, _ string) error {
+		return nv.createLocalVolume(ctx, clients[i], vol.Name, vol.Labels, nv.driverOpts(nv.hostAddress(vol.Hosts[0]), vol.Name))
+	})
+	return entity.NewResult(err)
+}
+
+// Remove removes the docker volumes, then the directory the nfs server on the first host
+// exported for the volume
+func (nv nfsVolumes) Remove(ctx context.Context, ecli entity.DockerCli, vol command.Volume) entity.Result {
+	clients, err := nv.hostClients(ecli, vol.Hosts)
+	if err != nil {
+		return entity.NewErrorResult(err)
+	}
+	defer closeClients(clients)
+
+	err = forEachHost(vol.Hosts, func(i int, _ string) error {
+		return nv.unmountGlobalVolume(ctx, clients[i], vol.Name)
+	})
+	if err != nil {
+		return entity.NewErrorResult(err)
+	}
+	return entity.NewResult(nv.repo.Exec(ctx, clients[0], NFSContainerName, entity.Exec{
+		Cmd:        []string{"rm", "-rf", path.Join(nfsExportDir, vol.Name)},
+		Privileged: true,
+		Retries:    2,
+	}))
+}
+
+// Resize mounts the volume on the hosts to add and unmounts it from the hosts to remove. The
+// target of the command is the nfs server.
+func (nv nfsVolumes) Resize(ctx context.Context, ecli entity.DockerCli, resize entity.VolumeResize) entity.Result {
+	add, err := nv.hostClients(ecli, resize.Add)
+	if err != nil {
+		return entity.NewErrorResult(err)
+	}
+	defer closeClients(add)
+	remove, err := nv.hostClients(ecli, resize.Remove)
+	if err != nil {
+		return entity.NewErrorResult(err)
+	}
+	defer closeClients(remove)
+
+	err = forEachHost(resize.Add, func(i int, _ string) error {
+		return nv.createLocalVolume(ctx, add[i], resize.Name, nil, nv.driverOpts(nv.hostAddress(ecli.IP), resize.Name))
+	})
+	if err != nil {
+		return entity.NewErrorResult(err)
+	}
+	err = forEachHost(resize.Remove, func(i int, _ string) error {
+		return nv.unmountGlobalVolume(ctx, remove[i], resize.Name)
+	})
+	return entity.NewResult(err).InjectMeta(map[string]interface{}{
+		"added":   resize.Add,
+		"removed": resize.Remove,
+	})
+}
+
+// Status checks that the nfs server, the target of the command, exports the volume, and which
+// of the hosts have it mounted
+func (nv nfsVolumes) Status(ctx context.Context, ecli entity.DockerCli, vol command.Volume) entity.Result {
+	err := nv.repo.Exec(ctx, ecli.Client, NFSContainerName, entity.Exec{
+		Cmd: []string{"test", "-d", path.Join(nfsExportDir, vol.Name)},
+	})
+	if err != nil {
+		return entity.NewErrorResult(fmt.Errorf("%s does not export the volume %s: %v", ecli.IP, vol.Name, err))
+	}
+	hosts, err := nv.mountedOn(ctx, ecli, vol.Hosts, vol.Name)
+	if err != nil {
+		return entity.NewErrorResult(err)
+	}
+	return entity.NewSuccessResult().InjectMeta(map[string]interface{}{"volume": entity.SharedVolume{
+		Name:    vol.Name,
+		Backend: entity.NFSVolumeBackend,
+		Source:  ecli.IP,
+		Hosts:   hosts,
+	}})
+}
@@ -0,0 +1,139 @@
+/**
+ * Copyright 2019 Whiteblock Inc. All rights reserved.
+ * Use of this source code is governed by a BSD-style
+ * license that can be found in the LICENSE file.
+ */
+
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/whiteblock/genesis/pkg/entity"
+)
+
+// partitionChain is the iptables chain which holds the rules of a partition
+const partitionChain = "GENESIS-PARTITION"
+
+// partitionCmds gives the iptables commands which drop all of the traffic between the container
+// and the given addresses. The rules are kept in a chain of their own, so that partitioning again
+// replaces them and healing does not touch any of the other rules. IPv6 addresses go through
+// ip6tables, which is only touched when there are any.
+func partitionCmds(ips []string) []string {
+	cmds := chainCmds("iptables")
+	v6Cmds := chainCmds("ip6tables")
+	for _, ip := range ips {
+		bin := "iptables"
+		if isIPv6(ip) {
+			bin = "ip6tables"
+			cmds = append(cmds, v6Cmds...)
+			v6Cmds = nil
+		}
+		cmds = append(cmds,
+			fmt.Sprintf("%s -A %s -s %s -j DROP", bin, partitionChain, ip),
+			fmt.Sprintf("%s -A %s -d %s -j DROP", bin, partitionChain, ip),
+		)
+	}
+	return cmds
+}
+
+// chainCmds gives the commands which set up an empty partition chain, hooked into INPUT and OUTPUT
+func chainCmds(bin string) []string {
+	return []string{
+		fmt.Sprintf("(%s -N %s 2> /dev/null || %s -F %s)", bin, partitionChain, bin, partitionChain),
+		fmt.Sprintf("(%s -C INPUT -j %s 2> /dev/null || %s -I INPUT -j %s)",
+			bin, partitionChain, bin, partitionChain),
+		fmt.Sprintf("(%s -C OUTPUT -j %s 2> /dev/null || %s -I OUTPUT -j %s)",
+			bin, partitionChain, bin, partitionChain),
+	}
+}
+
+// healCmds gives the iptables commands which remove the partition chains, if there are any
+func healCmds() []string {
+	cmds := []string{}
+	for _, bin := range []string{"iptables", "ip6tables"} {
+		cmds = append(cmds,
+			fmt.Sprintf("(%s -D INPUT -j %s 2> /dev/null || true)", bin, partitionChain),
+			fmt.Sprintf("(%s -D OUTPUT -j %s 2> /dev/null || true)", bin, partitionChain),
+			fmt.Sprintf("(%s -F %s 2> /dev/null || true)", bin, partitionChain),
+			fmt.Sprintf("(%s -X %s 2> /dev/null || true)", bin, partitionChain),
+		)
+	}
+	return cmds
+}
+
+// runIptables runs the given commands in a side car which shares the network namespace of the container
+func (ds dockerService) runIptables(ctx context.Context, cli entity.DockerCli,
+	containerName string, cmds []string) error {
+
+	_, err := ds.runSidecar(ctx, cli, sidecar{
+		Name:   containerName + "-partition",
+		Image:  ds.conf.IptablesImage,
+		Target: containerName,
+		Cmd:    []string{"/bin/sh", "-c", strings.Join(cmds, " && ")},
+		CapAdd: []string{"NET_ADMIN"},
+	})
+	return err
+}
+
+// Partition stops the containers of each group from exchanging any traffic with the containers
+// of the other groups on the network
+func (ds dockerService) Partition(ctx context.Context, cli entity.DockerCli,
+	part entity.Partition) entity.Result {
+
+	ips := map[string][]string{}
+	for _, cntr := range part.Containers() {
+		ip, err := ds.containerIPs(ctx, cli, cntr, part.Network)
+		if err != nil {
+			return entity.NewErrorResult(err).InjectMeta(map[string]interface{}{
+				"container": cntr,
+				"network":   part.Network,
+			})
+		}
+		ips[cntr] = ip
+	}
+
+	for i, group := range part.Groups {
+		others := []string{}
+		for j, other := range part.Groups {
+			if i == j {
+				continue
+			}
+			for _, cntr := range other {
+				others = append(others, ips[cntr]...)
+			}
+		}
+		for _, cntr := range group {
+			err := ds.runIptables(ctx, cli, cntr, partitionCmds(others))
+			if err != nil {
+				return entity.NewErrorResult(err).InjectMeta(map[string]interface{}{
+					"container": cntr,
+					"network":   part.Network,
+				})
+			}
+		}
+	}
+	return entity.NewSuccessResult().InjectMeta(map[string]interface{}{
+		"network": part.Network,
+		"groups":  part.Groups,
+	})
+}
+
+// HealPartition removes the partition from the containers, if they have one
+func (ds dockerService) HealPartition(ctx context.Context, cli entity.DockerCli,
+	part entity.Partition) entity.Result {
+
+	for _, cntr := range part.Containers() {
+		err := ds.runIptables(ctx, cli, cntr, healCmds())
+		if err != nil {
+			return entity.NewErrorResult(err).InjectMeta(map[string]interface{}{
+				"container": cntr,
+			})
+		}
+	}
+	return entity.NewSuccessResult().InjectMeta(map[string]interface{}{
+		"containers": part.Containers(),
+	})
+}
@@ -0,0 +1,259 @@
+/**
+ * Copyright 2019 Whiteblock Inc. All rights reserved.
+ * Use of this source code is governed by a BSD-style
+ * license that can be found in the LICENSE file.
+ */
+
+package service
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/whiteblock/genesis/pkg/entity"
+
+	"github.com/sirupsen/logrus"
+)
+
+// clientPingTimeout is how long the health check of a pooled client can take
+var clientPingTimeout = 5 * time.Second
+
+// pooledClient is a client handed out by the pool, closing it gives it back to the pool
+// instead of closing its connection
+type pooledClient struct {
+	entity.Client
+	entry *poolEntry
+	pool  *clientPool
+	once  *sync.Once
+}
+
+// Close gives the client back to the pool
+func (pc pooledClient) Close() error {
+	pc.once.Do(func() { pc.pool.release(pc.entry) })
+	return nil
+}
+
+// Engine gives the engine of the client which is pooled
+func (pc pooledClient) Engine() entity.Engine {
+	return engineOf(pc.Client)
+}
+
+// poolEntry is the client of a host for a test, along with who is using it
+type poolEntry struct {
+	key         string
+	fingerprint string
+	client      entity.Client
+	err         error
+	ready       chan struct{}
+	users       int
+	lastUsed    time.Time
+	lastChecked time.Time
+	evicted     bool
+}
+
+// clientPool keeps the clients of the hosts open between commands, so that each command
+// does not need a new connection and TLS handshake
+type clientPool struct {
+	mux     sync.Mutex
+	idle    time.Duration
+	health  time.Duration
+	entries map[string]*poolEntry
+	log     logrus.Ext1FieldLogger
+}
+
+func newClientPool(idle time.Duration, health time.Duration, log logrus.Ext1FieldLogger) *clientPool {
+	return &clientPool{idle: idle, health: health, entries: map[string]*poolEntry{}, log: log}
+}
+
+func clientKey(ip string, testID string, runtime string) string {
+	return runtime + "://" + ip + "/" + testID
+}
+
+// get gives out the client under the key, creating it if there is none, or if the one there
+// was created with different certs or fails its health check
+func (cp *clientPool) get(key string, fingerprint string,
+	create func() (entity.Client, error)) (entity.Client, error) {
+
+	if cp.idle == 0 {
+		return create()
+	}
+	for {
+		cp.mux.Lock()
+		entry, exists := cp.entries[key]
+		if exists && entry.fingerprint != fingerprint {
+			cp.log.WithField("key", key).Info("the certs have changed, replacing the client")
+			cp.evict(entry)
+			exists = false
+		}
+		if !exists {
+			entry = &poolEntry{key: key, fingerprint: fingerprint, ready: make(chan struct{}), users: 1}
+			cp.entries[key] = entry
+			cp.mux.Unlock()
+			return cp.create(entry, create)
+		}
+		entry.users++
+		cp.mux.Unlock()
+
+		<-entry.ready
+		if entry.err != nil {
+			cp.release(entry)
+			return nil, entry.err
+		}
+		if cp.healthy(entry) {
+			return cp.wrap(entry), nil
+		}
+		cp.mux.Lock()
+		cp.evict(entry)
+		cp.mux.Unlock()
+		cp.release(entry)
+	}
+}
+
+// create makes the client of a new entry, the entry is dropped if that fails
+func (cp *clientPool) create(entry *poolEntry, create func() (entity.Client, error)) (entity.Client, error) {
+	cli, err := create()
+
+	cp.mux.Lock()
+	entry.client = cli
+	entry.err = err
+	entry.lastChecked = time.Now()
+	if err != nil {
+		cp.evict(entry)
+	}
+	cp.mux.Unlock()
+	close(entry.ready)
+
+	if err != nil {
+		cp.release(entry)
+		return nil, err
+	}
+	return cp.wrap(entry), nil
+}
+
+func (cp *clientPool) wrap(entry *poolEntry) entity.Client {
+	return pooledClient{Client: entry.client, entry: entry, pool: cp, once: &sync.Once{}}
+}
+
+// healthy pings the client, if it has not been checked recently. Only one user checks it at
+// a time, the others carry on with it in the meantime.
+func (cp *clientPool) healthy(entry *poolEntry) bool {
+	cp.mux.Lock()
+	if entry.evicted {
+		cp.mux.Unlock()
+		return false
+	}
+	if time.Since(entry.lastChecked) < cp.health {
+		cp.mux.Unlock()
+		return true
+	}
+	entry.lastChecked = time.Now()
+	cp.mux.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), clientPingTimeout)
+	defer cancel()
+	_, err := entry.client.Ping(ctx)
+	if err != nil {
+		cp.log.WithFields(logrus.Fields{"key": entry.key, "error": err}).Warn(
+			"a pooled client failed its health check, replacing it")
+		return false
+	}
+	return true
+}
+
+// evict takes the entry out of the pool, its client is closed once nobody is using it. The lock
+// must be held.
+func (cp *clientPool) evict(entry *poolEntry) {
+	if cp.entries[entry.key] == entry {
+		delete(cp.entries, entry.key)
+	}
+	if entry.evicted {
+		return
+	}
+	entry.evicted = true
+	if entry.users == 0 {
+		cp.close(entry)
+	}
+}
+
+// close closes the connection of the entry. The lock must be held.
+func (cp *clientPool) close(entry *poolEntry) {
+	if entry.client == nil {
+		return
+	}
+	err := entry.client.Close()
+	if err != nil {
+		cp.log.WithFields(logrus.Fields{"key": entry.key, "error": err}).Warn("failed to close a client")
+	}
+}
+
+// release gives back the entry, which is closed once it has been idle for long enough
+func (cp *clientPool) release(entry *poolEntry) {
+	cp.mux.Lock()
+	defer cp.mux.Unlock()
+	entry.users--
+	entry.lastUsed = time.Now()
+	if entry.users > 0 {
+		return
+	}
+	if entry.evicted {
+		cp.close(entry)
+		return
+	}
+	time.AfterFunc(cp.idle, func() { cp.expire(entry) })
+}
+
+// expire evicts the entry if nobody has used it since it became idle
+func (cp *clientPool) expire(entry *poolEntry) {
+	cp.mux.Lock()
+	defer cp.mux.Unlock()
+	if entry.users > 0 || entry.evicted || time.Since(entry.lastUsed) < cp.idle {
+		return
+	}
+	cp.log.WithField("key", entry.key).Debug("closing an idle client")
+	cp.evict(entry)
+}
+
+// evictTest takes the clients of the test out of the pool, so that nothing made with its
+// credentials outlives it. The ones still in use are closed once they are given back.
+func (cp *clientPool) evictTest(testID string) {
+	cp.mux.Lock()
+	defer cp.mux.Unlock()
+	for key, entry := range cp.entries {
+		if strings.HasSuffix(key, "/"+testID) {
+			cp.evict(entry)
+		}
+	}
+}
+
+// closeClients gives back each of the clients which were created
+func closeClients(clients []entity.Client) {
+	for _, cli := range clients {
+		if cli != nil {
+			cli.Close()
+		}
+	}
+}
+
+// certFingerprint identifies the TLS credentials of the test, so that a client made with older
+// credentials is never reused
+func (ds dockerService) certFingerprint(testID string) string {
+	if ds.conf.LocalMode {
+		return ""
+	}
+	creds, err := ds.creds.Get(testID)
+	if err != nil {
+		return ""
+	}
+	defer creds.Wipe()
+	return creds.Fingerprint()
+}
+
+// clientFor gives out the pooled client of the host for the runtime
+func (ds dockerService) clientFor(ip string, testID string, runtime string) (entity.Client, error) {
+	fingerprint := ds.transportFingerprint(ip, testID)
+	return ds.pool.get(clientKey(ip, testID, runtime), fingerprint, func() (entity.Client, error) {
+		return ds.createClient(ip, testID, runtime)
+	})
+}
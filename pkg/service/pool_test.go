@@ -0,0 +1,197 @@
+/**
+ * Copyright 2019 Whiteblock Inc. All rights reserved.
+ * Use of this source code is governed by a BSD-style
+ * license that can be found in the LICENSE file.
+ */
+
+package service
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/whiteblock/genesis/pkg/config"
+	"github.com/whiteblock/genesis/pkg/entity"
+	"github.com/whiteblock/genesis/pkg/fake"
+	"github.com/whiteblock/genesis/pkg/repository"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type countingClient struct {
+	*fake.Engine
+	closed *int32
+}
+
+func (cc countingClient) Close() error {
+	atomic.AddInt32(cc.closed, 1)
+	return nil
+}
+
+type testClients struct {
+	created int32
+	closed  int32
+	engines []*fake.Engine
+	mux     sync.Mutex
+}
+
+func (tc *testClients) create() (entity.Client, error) {
+	atomic.AddInt32(&tc.created, 1)
+	engine := fake.NewEngine("")
+	tc.mux.Lock()
+	tc.engines = append(tc.engines, engine)
+	tc.mux.Unlock()
+	return countingClient{Engine: engine, closed: &tc.closed}, nil
+}
+
+func TestClientPool_Reuse(t *testing.T) {
+	pool := newClientPool(time.Minute, time.Minute, logrus.New())
+	clients := &testClients{}
+
+	wg := sync.WaitGroup{}
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			cli, err := pool.get("key", "", clients.create)
+			require.NoError(t, err)
+			cli.Close()
+			cli.Close()
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int32(1), atomic.LoadInt32(&clients.created))
+	assert.Equal(t, int32(0), atomic.LoadInt32(&clients.closed))
+
+	cli, err := pool.get("other", "", clients.create)
+	require.NoError(t, err)
+	defer cli.Close()
+	assert.Equal(t, int32(2), atomic.LoadInt32(&clients.created))
+}
+
+func TestClientPool_CertsChanged(t *testing.T) {
+	pool := newClientPool(time.Minute, time.Minute, logrus.New())
+	clients := &testClients{}
+
+	old, err := pool.get("key", "a", clients.create)
+	require.NoError(t, err)
+	cli, err := pool.get("key", "b", clients.create)
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&clients.created))
+	assert.Equal(t, int32(0), atomic.LoadInt32(&clients.closed), "the old client is still in use")
+
+	old.Close()
+	assert.Equal(t, int32(1), atomic.LoadInt32(&clients.closed))
+	cli.Close()
+	assert.Equal(t, int32(1), atomic.LoadInt32(&clients.closed))
+}
+
+func TestClientPool_HealthCheck(t *testing.T) {
+	pool := newClientPool(time.Minute, 0, logrus.New())
+	clients := &testClients{}
+
+	cli, err := pool.get("key", "", clients.create)
+	require.NoError(t, err)
+	cli.Close()
+
+	cli, err = pool.get("key", "", clients.create)
+	require.NoError(t, err)
+	cli.Close()
+	assert.Equal(t, int32(1), atomic.LoadInt32(&clients.created))
+
+	clients.engines[0].FailNext("Ping", fmt.Errorf("Cannot connect to the Docker daemon"))
+	cli, err = pool.get("key", "", clients.create)
+	require.NoError(t, err)
+	defer cli.Close()
+	assert.Equal(t, int32(2), atomic.LoadInt32(&clients.created))
+	assert.Equal(t, int32(1), atomic.LoadInt32(&clients.closed))
+}
+
+func TestClientPool_IdleEviction(t *testing.T) {
+	pool := newClientPool(10*time.Millisecond, time.Minute, logrus.New())
+	clients := &testClients{}
+
+	cli, err := pool.get("key", "", clients.create)
+	require.NoError(t, err)
+	time.Sleep(30 * time.Millisecond)
+	assert.Equal(t, int32(0), atomic.LoadInt32(&clients.closed), "a client in use is never evicted")
+	cli.Close()
+
+	require.Eventually(t, func() bool { return atomic.LoadInt32(&clients.closed) == 1 },
+		time.Second, 5*time.Millisecond)
+	cli, err = pool.get("key", "", clients.create)
+	require.NoError(t, err)
+	defer cli.Close()
+	assert.Equal(t, int32(2), atomic.LoadInt32(&clients.created))
+}
+
+func TestClientPool_EvictTest(t *testing.T) {
+	pool := newClientPool(time.Minute, time.Minute, logrus.New())
+	clients := &testClients{}
+
+	idle, err := pool.get(clientKey("10.0.0.2", "test", ""), "", clients.create)
+	require.NoError(t, err)
+	idle.Close()
+	inUse, err := pool.get(clientKey("10.0.0.3", "test", ""), "", clients.create)
+	require.NoError(t, err)
+	other, err := pool.get(clientKey("10.0.0.2", "test2", ""), "", clients.create)
+	require.NoError(t, err)
+	defer other.Close()
+
+	pool.evictTest("test")
+	assert.Equal(t, int32(1), atomic.LoadInt32(&clients.closed))
+	inUse.Close()
+	assert.Equal(t, int32(2), atomic.LoadInt32(&clients.closed), "it is closed once it is given back")
+
+	cli, err := pool.get(clientKey("10.0.0.2", "test2", ""), "", clients.create)
+	require.NoError(t, err)
+	defer cli.Close()
+	assert.Equal(t, int32(3), atomic.LoadInt32(&clients.created), "the clients of other tests are kept")
+}
+
+func TestClientPool_CreateFailure(t *testing.T) {
+	pool := newClientPool(time.Minute, time.Minute, logrus.New())
+	_, err := pool.get("key", "", func() (entity.Client, error) { return nil, fmt.Errorf("missing ca cert file") })
+	assert.Error(t, err)
+
+	clients := &testClients{}
+	cli, err := pool.get("key", "", clients.create)
+	require.NoError(t, err)
+	defer cli.Close()
+	assert.Equal(t, int32(1), atomic.LoadInt32(&clients.created))
+}
+
+func TestClientPool_Disabled(t *testing.T) {
+	pool := newClientPool(0, 0, logrus.New())
+	clients := &testClients{}
+	for i := 0; i < 2; i++ {
+		cli, err := pool.get("key", "", clients.create)
+		require.NoError(t, err)
+		cli.Close()
+	}
+	assert.Equal(t, int32(2), atomic.LoadInt32(&clients.created))
+	assert.Equal(t, int32(2), atomic.LoadInt32(&clients.closed))
+}
+
+func TestDockerService_CertFingerprint(t *testing.T) {
+	ds := dockerService{conf: config.Docker{}, creds: repository.NewCredentialStore("")}
+	assert.Equal(t, "", ds.certFingerprint("test"))
+
+	creds := entity.Credentials{CACert: []byte("ca"), ClientCert: []byte("cert"), ClientKey: []byte("key")}
+	require.NoError(t, ds.creds.Put("test", creds))
+	first := ds.certFingerprint("test")
+	assert.NotEqual(t, "", first)
+	assert.Equal(t, first, ds.certFingerprint("test"))
+
+	creds.ClientKey = []byte("new")
+	require.NoError(t, ds.creds.Put("test", creds))
+	assert.NotEqual(t, first, ds.certFingerprint("test"))
+
+	ds.conf.LocalMode = true
+	assert.Equal(t, "", ds.certFingerprint("test"))
+}
@@ -0,0 +1,217 @@
+/**
+ * Copyright 2019 Whiteblock Inc. All rights reserved.
+ * Use of this source code is governed by a BSD-style
+ * license that can be found in the LICENSE file.
+ */
+
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/whiteblock/genesis/pkg/entity"
+
+	"github.com/sirupsen/logrus"
+	"github.com/whiteblock/definition/command"
+)
+
+// rsyncVolumes shares global volumes by giving each host its own docker volume, and copying a
+// snapshot of the one on the first host to the others. Writes are not shared, so it suits data
+// which is read far more than it is written. Creating the volume again copies a new snapshot.
+type rsyncVolumes struct {
+	dockerService
+}
+
+// rsyncModules is where the daemon looks for the module of each volume it serves
+const rsyncModules = "/etc/rsyncd.d"
+
+// rsyncdConf only lets the hosts of the share read the modules of the daemon, which are only
+// added for the shared volumes, so that the other volumes of the host are not served
+func rsyncdConf(hosts []string) string {
+	return fmt.Sprintf("read only = true\nuse chroot = false\nuid = root\ngid = root\n"+
+		"hosts allow = %s\n&include %s\n", strings.Join(hosts, " "), rsyncModules)
+}
+
+// rsyncModule serves the data of the volume as a module of the same name
+func rsyncModule(name string) string {
+	return fmt.Sprintf("[%s]\n\tpath = /volumes/%s/_data\n", name, name)
+}
+
+// export adds the module of the volume to the daemon on the host, which is picked up by the
+// next connection to it
+func (rv rsyncVolumes) export(ctx context.Context, cli entity.Client, name string) error {
+	return rv.repo.Exec(ctx, cli, RsyncContainerName, entity.Exec{
+		Cmd: []string{"sh", "-c", `printf '%s' "$1" > "$2"`, "sh", rsyncModule(name),
+			fmt.Sprintf("%s/%s.conf", rsyncModules, name)},
+		Retries: 2,
+	})
+}
+
+// unexport removes the module of the volume from the daemon on the host. The daemon may already
+// be gone, taking its modules with it, so a failure is only logged.
+func (rv rsyncVolumes) unexport(ctx context.Context, cli entity.Client, name string) {
+	err := rv.repo.Exec(ctx, cli, RsyncContainerName, entity.Exec{
+		Cmd: []string{"rm", "-f", fmt.Sprintf("%s/%s.conf", rsyncModules, name)},
+	})
+	if err != nil {
+		rv.log.WithFields(logrus.Fields{"volume": name, "error": err}).Debug(
+			"could not remove the rsync module of the volume")
+	}
+}
+
+// pull copies the snapshot of the volume from the source onto the host
+func (rv rsyncVolumes) pull(ctx context.Context, cli entity.Client, source string, name string) error {
+	return rv.repo.Exec(ctx, cli, RsyncContainerName, entity.Exec{
+		Cmd: []string{"rsync", "-a", "--delete",
+			fmt.Sprintf("rsync://%s:%d/%s/", source, rv.conf.RsyncPort, name),
+			fmt.Sprintf("/volumes/%s/_data/", name)},
+		Privileged: true,
+		Retries:    2,
+	})
+}
+
+// Share starts an rsync daemon on each of the hosts, which serves the shared volumes on it to the
+// other hosts
+func (rv rsyncVolumes) Share(ctx context.Context, ecli entity.DockerCli, vs command.VolumeShare) entity.Result {
+	clients, err := rv.hostClients(ecli, vs.Hosts)
+	if err != nil {
+		return entity.NewErrorResult(err)
+	}
+	defer closeClients(clients)
+
+	conf := rsyncdConf(rv.hostAddresses(vs.Hosts))
+	err = forEachHost(vs.Hosts, func(i int, _ string) error {
+		err := rv.startSideCar(ctx, clients[i], entity.ContainerSpec{
+			Name:  RsyncContainerName,
+			Image: rv.conf.RsyncImage,
+			Env:   []string{"RSYNCD_CONF=" + conf},
+			Entrypoint: []string{"sh", "-c", fmt.Sprintf(
+				`mkdir -p %s && printf '%%s' "$RSYNCD_CONF" > /etc/rsyncd.conf && `+
+					`exec rsync --daemon --no-detach --port=%d`, rsyncModules, rv.conf.RsyncPort)},
+			AutoRemove:  true,
+			NetworkMode: "host",
+			Mounts: []entity.MountSpec{{
+				Type:   entity.BindMountType,
+				Source: rv.conf.VolumeRoot,
+				Target: "/volumes",
+			}},
+		})
+		if err != nil {
+			return err
+		}
+		return rv.repo.Exec(ctx, clients[i], RsyncContainerName, entity.Exec{ //the share may have grown
+			Cmd:     []string{"sh", "-c", `printf '%s' "$1" > /etc/rsyncd.conf`, "sh", conf},
+			Retries: 2,
+		})
+	})
+	return entity.NewResult(err)
+}
+
+// Unshare removes the rsync daemons, the volumes they copied stay
+func (rv rsyncVolumes) Unshare(ctx context.Context, ecli entity.DockerCli, vs command.VolumeShare) entity.Result {
+	clients, err := rv.hostClients(ecli, vs.Hosts)
+	if err != nil {
+		return entity.NewErrorResult(err)
+	}
+	defer closeClients(clients)
+
+	return entity.NewResult(forEachHost(vs.Hosts, func(i int, _ string) error {
+		return rv.removeSideCar(ctx, clients[i], RsyncContainerName)
+	}))
+}
+
+// Create creates the docker volume on each of the hosts, then copies the one on the first host
+// to the others
+func (rv rsyncVolumes) Create(ctx context.Context, ecli entity.DockerCli, vol command.Volume) entity.Result {
+	clients, err := rv.hostClients(ecli, vol.Hosts)
+	if err != nil {
+		return entity.NewErrorResult(err)
+	}
+	defer closeClients(clients)
+
+	err = forEachHost(vol.Hosts, func(i int, _ string) error {
+		return rv.createLocalVolume(ctx, clients[i], vol.Name, vol.Labels, nil)
+	})
+	if err != nil {
+		return entity.NewErrorResult(err)
+	}
+	err = rv.export(ctx, clients[0], vol.Name)
+	if err != nil {
+		return entity.NewErrorResult(err)
+	}
+	err = forEachHost(vol.Hosts[1:], func(i int, _ string) error {
+		return rv.pull(ctx, clients[i+1], rv.hostAddress(vol.Hosts[0]), vol.Name)
+	})
+	return entity.NewResult(err)
+}
+
+// Remove removes the docker volume from each of the hosts
+func (rv rsyncVolumes) Remove(ctx context.Context, ecli entity.DockerCli, vol command.Volume) entity.Result {
+	clients, err := rv.hostClients(ecli, vol.Hosts)
+	if err != nil {
+		return entity.NewErrorResult(err)
+	}
+	defer closeClients(clients)
+
+	return entity.NewResult(forEachHost(vol.Hosts, func(i int, _ string) error {
+		rv.unexport(ctx, clients[i], vol.Name)
+		return rv.unmountGlobalVolume(ctx, clients[i], vol.Name)
+	}))
+}
+
+// Resize copies the volume from the target of the command onto the hosts to add, and removes
+// it from the hosts to remove
+func (rv rsyncVolumes) Resize(ctx context.Context, ecli entity.DockerCli, resize entity.VolumeResize) entity.Result {
+	add, err := rv.hostClients(ecli, resize.Add)
+	if err != nil {
+		return entity.NewErrorResult(err)
+	}
+	defer closeClients(add)
+	remove, err := rv.hostClients(ecli, resize.Remove)
+	if err != nil {
+		return entity.NewErrorResult(err)
+	}
+	defer closeClients(remove)
+
+	if len(resize.Add) > 0 {
+		err = rv.export(ctx, ecli.Client, resize.Name)
+		if err != nil {
+			return entity.NewErrorResult(err)
+		}
+	}
+	err = forEachHost(resize.Add, func(i int, _ string) error {
+		err := rv.createLocalVolume(ctx, add[i], resize.Name, nil, nil)
+		if err != nil {
+			return err
+		}
+		return rv.pull(ctx, add[i], rv.hostAddress(ecli.IP), resize.Name)
+	})
+	if err != nil {
+		return entity.NewErrorResult(err)
+	}
+	err = forEachHost(resize.Remove, func(i int, _ string) error {
+		rv.unexport(ctx, remove[i], resize.Name)
+		return rv.unmountGlobalVolume(ctx, remove[i], resize.Name)
+	})
+	return entity.NewResult(err).InjectMeta(map[string]interface{}{
+		"added":   resize.Add,
+		"removed": resize.Remove,
+	})
+}
+
+// Status gives which of the hosts have a copy of the volume, the target of the command being
+// the one they copy from
+func (rv rsyncVolumes) Status(ctx context.Context, ecli entity.DockerCli, vol command.Volume) entity.Result {
+	hosts, err := rv.mountedOn(ctx, ecli, vol.Hosts, vol.Name)
+	if err != nil {
+		return entity.NewErrorResult(err)
+	}
+	return entity.NewSuccessResult().InjectMeta(map[string]interface{}{"volume": entity.SharedVolume{
+		Name:    vol.Name,
+		Backend: entity.RsyncVolumeBackend,
+		Source:  ecli.IP,
+		Hosts:   hosts,
+	}})
+}
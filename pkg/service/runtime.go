@@ -0,0 +1,94 @@
+/**
+ * Copyright 2019 Whiteblock Inc. All rights reserved.
+ * Use of this source code is governed by a BSD-style
+ * license that can be found in the LICENSE file.
+ */
+
+package service
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/whiteblock/genesis/pkg/entity"
+	"github.com/whiteblock/genesis/pkg/repository"
+)
+
+// podmanSocket gives the socket of the local podman, which serves its own API along with the docker
+// compatible one
+func (ds dockerService) podmanSocket() string {
+	if !ds.conf.Rootless {
+		return "/run/podman/podman.sock"
+	}
+	dir := os.Getenv("XDG_RUNTIME_DIR")
+	if len(dir) == 0 {
+		dir = filepath.Join("/run/user", fmt.Sprint(os.Getuid()))
+	}
+	return filepath.Join(dir, "podman", "podman.sock")
+}
+
+// isLocal checks whether the host is this machine
+func (ds dockerService) isLocal(ip string) bool {
+	if ds.conf.LocalMode {
+		return true
+	}
+	parsed := net.ParseIP(ip)
+	return parsed != nil && parsed.IsLoopback()
+}
+
+// commandRunner gives where the commands of nerdctl are run for the host. nerdctl has no API, so
+// it is run on the host itself, which is this machine or one which is reached over ssh.
+func (ds dockerService) commandRunner(ip string) (repository.CommandRunner, error) {
+	if host, ok := ds.transportHost(ip); ok && strings.HasPrefix(host, sshScheme) {
+		return repository.NewSSHRunner(host)
+	}
+	if hasTransportScheme(ip) {
+		return nil, fmt.Errorf("%w: %s", ErrTransportNotAllowed, ip)
+	}
+	if !ds.isLocal(ip) {
+		return nil, entity.NotSupportedError{Runtime: entity.ContainerdRuntime,
+			Operation: "connecting to a remote host other than over ssh"}
+	}
+	return repository.NewCommandRunner(), nil
+}
+
+// engineOf gives the runtime neutral engine of the client, the clients of docker and of anything
+// else which speaks its API are driven through the docker adapter
+func engineOf(cli entity.Client) entity.Engine {
+	if provider, ok := cli.(entity.EngineProvider); ok {
+		return provider.Engine()
+	}
+	return repository.NewDockerEngine(cli)
+}
+
+// createClient creates the client for the container runtime of the host. The containers,
+// networks and volumes are created through the Engine of the client, which drives the API of the
+// runtime itself: docker, the libpod API of podman, or nerdctl for containerd.
+func (ds dockerService) createClient(ip, testID, runtime string) (entity.Client, error) {
+	switch runtime {
+	case "", entity.DockerRuntime:
+		return ds.engineClient(ip, testID, "")
+	case entity.PodmanRuntime:
+		cli, err := ds.engineClient(ip, testID, ds.podmanSocket())
+		if err != nil {
+			return nil, err
+		}
+		engine, err := repository.NewPodmanEngine(cli.HTTPClient(), cli.DaemonHost(), ds.conf.Rootless)
+		if err != nil {
+			cli.Close()
+			return nil, err
+		}
+		return repository.NewPodmanClient(cli, engine), nil
+	case entity.ContainerdRuntime:
+		runner, err := ds.commandRunner(ip)
+		if err != nil {
+			return nil, err
+		}
+		return repository.NewNerdctlClient(runner, ds.conf.NerdctlPath,
+			ds.conf.ContainerdAddress, ds.conf.ContainerdNamespace), nil
+	}
+	return nil, fmt.Errorf("unknown container runtime \"%s\"", runtime)
+}
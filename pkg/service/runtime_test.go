@@ -0,0 +1,66 @@
+/**
+ * Copyright 2019 Whiteblock Inc. All rights reserved.
+ * Use of this source code is governed by a BSD-style
+ * license that can be found in the LICENSE file.
+ */
+
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/whiteblock/genesis/pkg/config"
+	"github.com/whiteblock/genesis/pkg/entity"
+	"github.com/whiteblock/genesis/pkg/repository"
+
+	"github.com/docker/docker/api/types/swarm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/whiteblock/definition/command"
+)
+
+func TestDockerService_CreateClient_Runtime(t *testing.T) {
+	ds := NewDockerService(nil, config.Docker{LocalMode: true, Runtime: entity.DockerRuntime}, nil, nil, nil)
+
+	cli, err := ds.CreateClient(command.Command{Target: command.Target{IP: "10.0.0.1"}})
+	require.NoError(t, err)
+	assert.Equal(t, "unix:///var/run/docker.sock", cli.DaemonHost())
+
+	cli, err = ds.CreateClient(command.Command{Meta: map[string]string{entity.RuntimeKey: entity.PodmanRuntime}})
+	require.NoError(t, err)
+	assert.True(t, entity.IsNotSupported(cli.SwarmJoin(context.Background(), swarm.JoinRequest{})))
+	assert.Equal(t, "unix:///run/podman/podman.sock", cli.DaemonHost())
+
+	cli, err = ds.CreateClient(command.Command{Meta: map[string]string{entity.RuntimeKey: entity.ContainerdRuntime}})
+	require.NoError(t, err)
+	assert.True(t, entity.IsNotSupported(cli.NetworkConnect(context.Background(), "net", "cntr", nil)))
+	assert.True(t, entity.IsNotSupported(engineOf(cli).ConnectNetwork(context.Background(), "cntr",
+		entity.EndpointSpec{Network: "net"})))
+
+	_, err = ds.CreateClient(command.Command{Meta: map[string]string{entity.RuntimeKey: "lxc"}})
+	assert.Error(t, err)
+
+	ds = NewDockerService(nil, config.Docker{LocalMode: true, Runtime: entity.PodmanRuntime, Rootless: true},
+		nil, nil, nil)
+	cli, err = ds.CreateClient2("10.0.0.1", "test")
+	require.NoError(t, err)
+	err = engineOf(cli).CreateNetwork(context.Background(), entity.NetworkSpec{Name: "net",
+		Driver: entity.MacvlanDriver})
+	assert.True(t, entity.IsNotSupported(err))
+
+	ds = NewDockerService(nil, config.Docker{Runtime: entity.ContainerdRuntime}, nil, repository.NewCredentialStore(""), nil)
+	_, err = ds.CreateClient2("10.0.0.1", "test")
+	assert.True(t, entity.IsNotSupported(err))
+
+	ds = NewDockerService(nil, config.Docker{Runtime: entity.ContainerdRuntime,
+		TransportAllowlist: []string{"ssh://root@10.0.0.2"}}, nil, repository.NewCredentialStore(""), nil)
+	cli, err = ds.CreateClient2("ssh://root@10.0.0.2", "test")
+	require.NoError(t, err)
+	err = engineOf(cli).CreateVolume(context.Background(), entity.VolumeSpec{Name: "data",
+		Options: map[string]string{"type": "nfs"}})
+	assert.True(t, entity.IsNotSupported(err))
+	_, err = ds.CreateClient2("ssh://root@10.0.0.3", "test")
+	assert.True(t, errors.Is(err, ErrTransportNotAllowed))
+}
@@ -0,0 +1,233 @@
+/**
+ * Copyright 2019 Whiteblock Inc. All rights reserved.
+ * Use of this source code is governed by a BSD-style
+ * license that can be found in the LICENSE file.
+ */
+
+package service
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/whiteblock/genesis/pkg/entity"
+
+	"github.com/sirupsen/logrus"
+	"github.com/whiteblock/definition/command"
+)
+
+// runningSchedule is an emulation schedule which has been started
+type runningSchedule struct {
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// stop cancels the schedule and waits for it to restore the emulation
+func (rs *runningSchedule) stop(ctx context.Context) error {
+	rs.cancel()
+	select {
+	case <-rs.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// emulationSchedules keeps track of the emulation schedules which are running
+type emulationSchedules struct {
+	mux     sync.Mutex
+	running map[string]*runningSchedule
+}
+
+func newEmulationSchedules() *emulationSchedules {
+	return &emulationSchedules{running: map[string]*runningSchedule{}}
+}
+
+func scheduleKey(testID string, name string) string {
+	return testID + "/" + name
+}
+
+// start registers a new schedule under the key, stopping the schedule which was there before
+func (es *emulationSchedules) start(key string) (context.Context, *runningSchedule) {
+	ctx, cancel := context.WithCancel(context.Background())
+	rs := &runningSchedule{cancel: cancel, done: make(chan struct{})}
+
+	es.mux.Lock()
+	prev, exists := es.running[key]
+	es.running[key] = rs
+	es.mux.Unlock()
+
+	if exists {
+		prev.stop(context.Background())
+	}
+	return ctx, rs
+}
+
+// finish unregisters the schedule, unless it has already been replaced
+func (es *emulationSchedules) finish(key string, rs *runningSchedule) {
+	es.mux.Lock()
+	if es.running[key] == rs {
+		delete(es.running, key)
+	}
+	es.mux.Unlock()
+	rs.cancel()
+	close(rs.done)
+}
+
+// cancel stops the schedule under the key, it gives back false if there was none running
+func (es *emulationSchedules) cancel(ctx context.Context, key string) (bool, error) {
+	es.mux.Lock()
+	rs, exists := es.running[key]
+	delete(es.running, key)
+	es.mux.Unlock()
+
+	if !exists {
+		return false, nil
+	}
+	return true, rs.stop(ctx)
+}
+
+// cancelTest stops all of the schedules of the test, waiting until the context is done for
+// them to restore the emulation
+func (es *emulationSchedules) cancelTest(ctx context.Context, testID string) {
+	es.mux.Lock()
+	stopping := []*runningSchedule{}
+	for key, rs := range es.running {
+		if strings.HasPrefix(key, testID+"/") {
+			stopping = append(stopping, rs)
+			delete(es.running, key)
+		}
+	}
+	es.mux.Unlock()
+
+	for _, rs := range stopping {
+		rs.cancel()
+	}
+	for _, rs := range stopping {
+		if rs.stop(ctx) != nil {
+			return
+		}
+	}
+}
+
+// scheduleStopTimeout is how long the schedules of a test which is over get to restore the
+// emulation
+var scheduleStopTimeout = 30 * time.Second
+
+// sleepUntil waits until the given time, it gives back false if the context is done first
+func sleepUntil(ctx context.Context, when time.Time) bool {
+	timer := time.NewTimer(time.Until(when))
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// scheduleClient creates a client for the schedule, since the client of the command which
+// started it is closed as soon as that command is done
+func (ds dockerService) scheduleClient(cli entity.DockerCli) (entity.DockerCli, error) {
+	client, err := ds.CreateClient2(cli.IP, cli.TestID)
+	if err != nil {
+		return cli, err
+	}
+	return entity.DockerCli{Client: client, Labels: cli.Labels, TestID: cli.TestID, IP: cli.IP}, nil
+}
+
+// applyScheduled applies the emulation with a client of its own. It is not tied to the
+// context of the schedule, so that a step is never interrupted half way through.
+func (ds dockerService) applyScheduled(cli entity.DockerCli, netem command.Netconf,
+	apply func(context.Context, entity.DockerCli, command.Netconf) entity.Result) entity.Result {
+
+	stepCli, err := ds.scheduleClient(cli)
+	if err != nil {
+		return entity.NewErrorResult(err)
+	}
+	defer stepCli.Close()
+	return apply(context.Background(), stepCli, netem)
+}
+
+// runEmulationSchedule applies each of the steps at its offset, and removes the emulation
+// once the schedule is over or has been canceled. A schedule without a restore is only over
+// once it is canceled.
+func (ds dockerService) runEmulationSchedule(ctx context.Context, cli entity.DockerCli,
+	sched entity.EmulationSchedule, rs *runningSchedule) {
+
+	defer ds.schedules.finish(scheduleKey(cli.TestID, sched.Name), rs)
+	netem := command.Netconf{Container: sched.Container, Network: sched.Network}
+	restore := func(reason string) {
+		res := ds.applyScheduled(cli, netem, ds.RemoveEmulation)
+		ds.withFields(cli, logrus.Fields{
+			"schedule": sched.Name,
+			"reason":   reason,
+			"error":    res.Error,
+		}).Info("restored the network conditions")
+	}
+
+	start := time.Now()
+	for i, step := range sched.Steps {
+		if !sleepUntil(ctx, start.Add(step.Offset.Duration)) {
+			restore("canceled")
+			return
+		}
+		conf := step.Netconf
+		conf.Container = sched.Container
+		conf.Network = sched.Network
+		res := ds.applyScheduled(cli, conf, ds.Emulation)
+		ds.withFields(cli, logrus.Fields{
+			"schedule": sched.Name,
+			"step":     i,
+			"error":    res.Error,
+		}).Info("applied a scheduled emulation step")
+	}
+
+	if sched.Restore.Empty() {
+		<-ctx.Done() // the emulation stays until the schedule is canceled
+		restore("canceled")
+		return
+	}
+	if sleepUntil(ctx, start.Add(sched.Restore.Duration)) {
+		restore("done")
+	} else {
+		restore("canceled")
+	}
+}
+
+// ScheduleEmulation starts applying the steps of the schedule in the background. Starting
+// a schedule with the name of one which is still running replaces it.
+func (ds dockerService) ScheduleEmulation(ctx context.Context, cli entity.DockerCli,
+	sched entity.EmulationSchedule) entity.Result {
+
+	steps := make([]entity.EmulationStep, len(sched.Steps))
+	copy(steps, sched.Steps)
+	sort.SliceStable(steps, func(i, j int) bool {
+		return steps[i].Offset.Duration < steps[j].Offset.Duration
+	})
+	sched.Steps = steps
+
+	schedCtx, rs := ds.schedules.start(scheduleKey(cli.TestID, sched.Name))
+	go ds.runEmulationSchedule(schedCtx, cli, sched, rs)
+
+	return entity.NewSuccessResult().InjectMeta(map[string]interface{}{
+		"schedule":  sched.Name,
+		"container": sched.Container,
+		"network":   sched.Network,
+		"steps":     len(sched.Steps),
+	})
+}
+
+// CancelEmulationSchedule stops the schedule and removes the emulation it applied
+func (ds dockerService) CancelEmulationSchedule(ctx context.Context, cli entity.DockerCli,
+	name string) entity.Result {
+
+	canceled, err := ds.schedules.cancel(ctx, scheduleKey(cli.TestID, name))
+	return entity.NewResult(err).InjectMeta(map[string]interface{}{
+		"schedule": name,
+		"canceled": canceled,
+	})
+}
@@ -0,0 +1,261 @@
+/**
+ * Copyright 2019 Whiteblock Inc. All rights reserved.
+ * Use of this source code is governed by a BSD-style
+ * license that can be found in the LICENSE file.
+ */
+
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/whiteblock/genesis/pkg/entity"
+
+	"github.com/docker/docker/api/types/filters"
+	"github.com/whiteblock/definition/command"
+)
+
+// SharedVolumeProvider is a way of sharing global volumes between the hosts of a test
+type SharedVolumeProvider interface {
+	// Share prepares the hosts to hold global volumes
+	Share(ctx context.Context, cli entity.DockerCli, vs command.VolumeShare) entity.Result
+
+	// Unshare removes what Share put on the hosts
+	Unshare(ctx context.Context, cli entity.DockerCli, vs command.VolumeShare) entity.Result
+
+	// Create creates the volume on each of its hosts. The first host holds the data, when the
+	// backend does not spread it over all of them.
+	Create(ctx context.Context, cli entity.DockerCli, vol command.Volume) entity.Result
+
+	// Remove removes the volume from each of its hosts, along with its data
+	Remove(ctx context.Context, cli entity.DockerCli, vol command.Volume) entity.Result
+
+	// Resize adds the volume to more hosts, or removes it from some of them. It is run from
+	// the target of the command, which keeps the volume.
+	Resize(ctx context.Context, cli entity.DockerCli, resize entity.VolumeResize) entity.Result
+
+	// Status gives the state of the volume
+	Status(ctx context.Context, cli entity.DockerCli, vol command.Volume) entity.Result
+}
+
+// sharedVolumes gives the provider of the backend, or of the backend of the config when none
+// is given
+func (ds dockerService) sharedVolumes(backend string) (SharedVolumeProvider, error) {
+	if len(backend) == 0 {
+		backend = ds.conf.SharedVolumeBackend
+	}
+	switch backend {
+	case entity.GlusterVolumeBackend, "":
+		return glusterVolumes{ds}, nil
+	case entity.NFSVolumeBackend:
+		return nfsVolumes{ds}, nil
+	case entity.RsyncVolumeBackend:
+		return rsyncVolumes{ds}, nil
+	}
+	return nil, fmt.Errorf("unknown shared volume backend \"%s\"", backend)
+}
+
+func (ds dockerService) createGlobalVolume(ctx context.Context, cli entity.DockerCli,
+	vol entity.Volume) entity.Result {
+
+	if len(vol.Hosts) == 0 {
+		return ErrNoHost
+	}
+	provider, err := ds.sharedVolumes(vol.Backend)
+	if err != nil {
+		return entity.NewFatalResult(err)
+	}
+	res := provider.Create(ctx, cli, vol.Volume)
+	if !res.IsSuccess() || vol.Seed == nil {
+		return res
+	}
+
+	clients, err := ds.hostClients(cli, vol.Hosts[:1])
+	if err != nil {
+		return entity.NewErrorResult(err)
+	}
+	defer closeClients(clients)
+	err = ds.seedVolume(ctx, entity.DockerCli{Client: clients[0], Labels: cli.Labels, TestID: cli.TestID,
+		IP: vol.Hosts[0]}, vol.Name, *vol.Seed)
+	if err != nil {
+		return entity.NewErrorResult(err)
+	}
+	if _, copies := provider.(rsyncVolumes); copies {
+		return provider.Create(ctx, cli, vol.Volume) // copy the seed to the other hosts
+	}
+	return res
+}
+
+// RemoveGlobalVolume removes a global volume from each of its hosts, along with its data
+func (ds dockerService) RemoveGlobalVolume(ctx context.Context, cli entity.DockerCli,
+	vol entity.Volume) entity.Result {
+
+	if !vol.Global || ds.conf.LocalMode {
+		return ds.RemoveVolume(ctx, cli, vol.Name)
+	}
+	if len(vol.Hosts) == 0 {
+		return ErrNoHost
+	}
+	provider, err := ds.sharedVolumes(vol.Backend)
+	if err != nil {
+		return entity.NewFatalResult(err)
+	}
+	return provider.Remove(ctx, cli, vol.Volume)
+}
+
+// VolumeShare prepares the hosts to hold the global volumes of the backend
+func (ds dockerService) VolumeShare(ctx context.Context, cli entity.DockerCli,
+	vs entity.VolumeShare) entity.Result {
+
+	if ds.conf.LocalMode {
+		// Do nothing if it is in local mode
+		return entity.NewSuccessResult()
+	}
+	if len(vs.Hosts) == 0 {
+		return entity.NewFatalResult("given an empty volume share command")
+	}
+	provider, err := ds.sharedVolumes(vs.Backend)
+	if err != nil {
+		return entity.NewFatalResult(err)
+	}
+	return provider.Share(ctx, cli, vs.VolumeShare)
+}
+
+// RemoveVolumeShare removes what the volume share of the backend put on the hosts
+func (ds dockerService) RemoveVolumeShare(ctx context.Context, cli entity.DockerCli,
+	vs entity.VolumeShare) entity.Result {
+
+	if ds.conf.LocalMode {
+		// Do nothing if it is in local mode
+		return entity.NewSuccessResult()
+	}
+	if len(vs.Hosts) == 0 {
+		return entity.NewFatalResult("given an empty volume share command")
+	}
+	provider, err := ds.sharedVolumes(vs.Backend)
+	if err != nil {
+		return entity.NewFatalResult(err)
+	}
+	return provider.Unshare(ctx, cli, vs.VolumeShare)
+}
+
+// ResizeVolume adds a global volume to more hosts, or removes it from some of them
+func (ds dockerService) ResizeVolume(ctx context.Context, cli entity.DockerCli,
+	resize entity.VolumeResize) entity.Result {
+
+	if ds.conf.LocalMode {
+		// Do nothing if it is in local mode
+		return entity.NewSuccessResult()
+	}
+	if containsHost(resize.Remove, cli.IP) {
+		return entity.NewFatalResult(fmt.Errorf(
+			"the volume is resized from %s, so it cannot be removed from the volume", cli.IP))
+	}
+	provider, err := ds.sharedVolumes(resize.Backend)
+	if err != nil {
+		return entity.NewFatalResult(err)
+	}
+	return provider.Resize(ctx, cli, resize)
+}
+
+// VolumeStatus gives the state of a global volume
+func (ds dockerService) VolumeStatus(ctx context.Context, cli entity.DockerCli, vol entity.Volume) entity.Result {
+	if ds.conf.LocalMode {
+		return entity.NewFatalResult("there are no global volumes in local mode")
+	}
+	provider, err := ds.sharedVolumes(vol.Backend)
+	if err != nil {
+		return entity.NewFatalResult(err)
+	}
+	return provider.Status(ctx, cli, vol.Volume)
+}
+
+// hostClients creates a client for each of the hosts, which the caller must close
+func (ds dockerService) hostClients(ecli entity.DockerCli, hosts []string) ([]entity.Client, error) {
+	clients := make([]entity.Client, len(hosts))
+	for i, host := range hosts {
+		cli, err := ds.CreateClient2(host, ecli.TestID)
+		if err != nil {
+			closeClients(clients)
+			return nil, err
+		}
+		clients[i] = cli
+		ds.withField(ecli, "host", host).Debug("created a client for a shared volume host")
+	}
+	return clients, nil
+}
+
+// startSideCar starts the container which serves the shared volumes on the host, unless it is
+// already running
+func (ds dockerService) startSideCar(ctx context.Context, cli entity.Client, spec entity.ContainerSpec) error {
+	err := ds.repo.EnsureImagePulled(ctx, cli, spec.Image, command.Credentials{})
+	if err != nil {
+		return err
+	}
+	engine := engineOf(cli)
+	err = engine.CreateContainer(ctx, spec)
+	if err != nil && !errors.Is(err, entity.ErrAlreadyExists) {
+		return err
+	}
+	return engine.StartContainer(ctx, spec.Name)
+}
+
+// removeSideCar removes the container which serves the shared volumes on the host
+func (ds dockerService) removeSideCar(ctx context.Context, cli entity.Client, name string) error {
+	err := engineOf(cli).RemoveContainer(ctx, name, true)
+	if errors.Is(err, entity.ErrNotFound) {
+		return nil
+	}
+	return err
+}
+
+// createLocalVolume creates the docker volume on the host, with the options of its driver
+func (ds dockerService) createLocalVolume(ctx context.Context, cli entity.Client, name string,
+	labels map[string]string, opts map[string]string) error {
+
+	return engineOf(cli).CreateVolume(ctx, entity.VolumeSpec{
+		Name:    name,
+		Labels:  labels,
+		Driver:  "local",
+		Options: opts,
+	})
+}
+
+// unmountGlobalVolume removes the docker volume which gives the host the global volume
+func (ds dockerService) unmountGlobalVolume(ctx context.Context, cli entity.Client, name string) error {
+	err := engineOf(cli).RemoveVolume(ctx, name)
+	if errors.Is(err, entity.ErrNotFound) {
+		return nil
+	}
+	return err
+}
+
+// mountedOn gives whether each of the hosts has the docker volume
+func (ds dockerService) mountedOn(ctx context.Context, ecli entity.DockerCli, hosts []string,
+	name string) (map[string]bool, error) {
+
+	clients, err := ds.hostClients(ecli, hosts)
+	if err != nil {
+		return nil, err
+	}
+	defer closeClients(clients)
+
+	mounted := make([]bool, len(hosts))
+	err = forEachHost(hosts, func(i int, _ string) error {
+		vols, err := clients[i].VolumeList(ctx, filters.NewArgs(filters.Arg("name", name)))
+		if err != nil {
+			return err
+		}
+		for _, vol := range vols.Volumes {
+			mounted[i] = mounted[i] || vol.Name == name
+		}
+		return nil
+	})
+	out := map[string]bool{}
+	for i, host := range hosts {
+		out[host] = mounted[i]
+	}
+	return out, err
+}
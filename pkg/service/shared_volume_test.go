@@ -0,0 +1,198 @@
+/**
+ * Copyright 2019 Whiteblock Inc. All rights reserved.
+ * Use of this source code is governed by a BSD-style
+ * license that can be found in the LICENSE file.
+ */
+
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/whiteblock/genesis/pkg/entity"
+	"github.com/whiteblock/genesis/pkg/fake"
+	"github.com/whiteblock/genesis/pkg/repository"
+
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/api/types/mount"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/whiteblock/definition/command"
+)
+
+// sharedVolumeHosts is swarmHosts, with the images of the nfs and rsync backends configured
+func sharedVolumeHosts(t *testing.T, backend string, hosts ...string) (dockerService, map[string]*fake.Engine) {
+	serv, engines := swarmHosts(t, hosts...)
+	ds := serv.(dockerService)
+	ds.repo = repository.NewDockerRepository(logrus.New())
+	ds.conf.SharedVolumeBackend = backend
+	ds.conf.NFSImage = "itsthenetwork/nfs-server-alpine"
+	ds.conf.RsyncImage = "eeacms/rsync"
+	ds.conf.RsyncPort = 8873
+	ds.conf.VolumeRoot = "/var/lib/docker/volumes"
+	return ds, engines
+}
+
+func volumeOpts(t *testing.T, engine *fake.Engine, name string) map[string]string {
+	vols, err := engine.VolumeList(context.Background(), filters.NewArgs(filters.Arg("name", name)))
+	require.NoError(t, err)
+	require.Len(t, vols.Volumes, 1)
+	return vols.Volumes[0].Options
+}
+
+func TestDockerService_NFSVolumes(t *testing.T) {
+	hosts := []string{"10.0.0.2", "10.0.0.3", "10.0.0.4"}
+	ds, engines := sharedVolumeHosts(t, entity.NFSVolumeBackend, hosts...)
+	cli := entity.DockerCli{Client: engines["10.0.0.2"], TestID: "test", IP: "10.0.0.2"}
+
+	for i := 0; i < 2; i++ {
+		res := ds.VolumeShare(context.Background(), cli, entity.VolumeShare{
+			VolumeShare: command.VolumeShare{Hosts: hosts[:2]}})
+		require.NoError(t, res.Error, "sharing the volume again changes nothing")
+		assert.Equal(t, "10.0.0.2", res.Meta["server"])
+	}
+	server, err := engines["10.0.0.2"].ContainerInspect(context.Background(), NFSContainerName)
+	require.NoError(t, err)
+	assert.True(t, server.State.Running)
+	assert.True(t, server.HostConfig.Privileged)
+	assert.Contains(t, server.Config.Env, "SHARED_DIRECTORY=/exports")
+	_, err = engines["10.0.0.3"].ContainerInspect(context.Background(), NFSContainerName)
+	assert.Error(t, err, "only the server runs the nfs container")
+
+	vol := entity.Volume{Volume: command.Volume{Name: "shared", Global: true, Hosts: hosts[:2]}}
+	res := ds.CreateVolume(context.Background(), cli, vol)
+	require.NoError(t, res.Error)
+	assert.Contains(t, engines["10.0.0.2"].Execs(NFSContainerName), []string{"mkdir", "-p", "/exports/shared"})
+	for _, host := range hosts[:2] {
+		assert.Equal(t, map[string]string{"type": "nfs", "o": "addr=10.0.0.2,rw,nfsvers=4",
+			"device": ":/shared"}, volumeOpts(t, engines[host], "shared"), host)
+	}
+
+	res = ds.ResizeVolume(context.Background(), cli, entity.VolumeResize{Name: "shared",
+		Backend: entity.NFSVolumeBackend, Add: []string{"10.0.0.4"}, Remove: []string{"10.0.0.3"}})
+	require.NoError(t, res.Error)
+	assert.Equal(t, "addr=10.0.0.2,rw,nfsvers=4", volumeOpts(t, engines["10.0.0.4"], "shared")["o"])
+	assert.False(t, hasVolume(t, engines["10.0.0.3"], "shared"))
+
+	res = ds.VolumeStatus(context.Background(), cli, entity.Volume{Volume: command.Volume{
+		Name: "shared", Hosts: hosts}})
+	require.NoError(t, res.Error)
+	assert.Equal(t, entity.SharedVolume{Name: "shared", Backend: entity.NFSVolumeBackend, Source: "10.0.0.2",
+		Hosts: map[string]bool{"10.0.0.2": true, "10.0.0.3": false, "10.0.0.4": true}}, res.Meta["volume"])
+
+	vol.Hosts = []string{"10.0.0.2", "10.0.0.4"}
+	res = ds.RemoveGlobalVolume(context.Background(), cli, vol)
+	require.NoError(t, res.Error)
+	assert.False(t, hasVolume(t, engines["10.0.0.4"], "shared"))
+	assert.Contains(t, engines["10.0.0.2"].Execs(NFSContainerName), []string{"rm", "-rf", "/exports/shared"})
+
+	for i := 0; i < 2; i++ {
+		res = ds.RemoveVolumeShare(context.Background(), cli, entity.VolumeShare{
+			VolumeShare: command.VolumeShare{Hosts: hosts[:2]}})
+		require.NoError(t, res.Error, "removing the share again does nothing")
+	}
+	_, err = engines["10.0.0.2"].ContainerInspect(context.Background(), NFSContainerName)
+	assert.Error(t, err)
+	assert.False(t, hasVolume(t, engines["10.0.0.2"], nfsExportsVolume))
+}
+
+func TestDockerService_RsyncVolumes(t *testing.T) {
+	hosts := []string{"10.0.0.2", "10.0.0.3", "10.0.0.4"}
+	ds, engines := sharedVolumeHosts(t, entity.GlusterVolumeBackend, hosts...)
+	cli := entity.DockerCli{Client: engines["10.0.0.2"], TestID: "test", IP: "10.0.0.2"}
+	share := entity.VolumeShare{VolumeShare: command.VolumeShare{Hosts: hosts}, Backend: entity.RsyncVolumeBackend}
+
+	res := ds.VolumeShare(context.Background(), cli, share)
+	require.NoError(t, res.Error)
+	for _, host := range hosts {
+		daemon, err := engines[host].ContainerInspect(context.Background(), RsyncContainerName)
+		require.NoError(t, err, host)
+		assert.Equal(t, []mount.Mount{{Type: mount.TypeBind, Source: "/var/lib/docker/volumes",
+			Target: "/volumes"}}, daemon.HostConfig.Mounts)
+		assert.Contains(t, daemon.Config.Env[0], "hosts allow = 10.0.0.2 10.0.0.3 10.0.0.4")
+		assert.NotContains(t, daemon.Config.Env[0], "path =", "no volume is served until it is shared")
+	}
+
+	vol := entity.Volume{Volume: command.Volume{Name: "shared", Global: true, Hosts: hosts[:2]},
+		Backend: entity.RsyncVolumeBackend}
+	res = ds.CreateVolume(context.Background(), cli, vol)
+	require.NoError(t, res.Error)
+	pull := []string{"rsync", "-a", "--delete", "rsync://10.0.0.2:8873/shared/",
+		"/volumes/shared/_data/"}
+	assert.Contains(t, engines["10.0.0.2"].Execs(RsyncContainerName), []string{"sh", "-c",
+		`printf '%s' "$1" > "$2"`, "sh", "[shared]\n\tpath = /volumes/shared/_data\n",
+		"/etc/rsyncd.d/shared.conf"}, "only the shared volume is served")
+	assert.Contains(t, engines["10.0.0.3"].Execs(RsyncContainerName), pull)
+	assert.NotContains(t, engines["10.0.0.2"].Execs(RsyncContainerName), pull)
+
+	res = ds.ResizeVolume(context.Background(), cli, entity.VolumeResize{Name: "shared",
+		Backend: entity.RsyncVolumeBackend, Add: []string{"10.0.0.4"}})
+	require.NoError(t, res.Error)
+	assert.Contains(t, engines["10.0.0.4"].Execs(RsyncContainerName), pull)
+
+	res = ds.VolumeStatus(context.Background(), cli, vol)
+	require.NoError(t, res.Error)
+	assert.Equal(t, entity.SharedVolume{Name: "shared", Backend: entity.RsyncVolumeBackend, Source: "10.0.0.2",
+		Hosts: map[string]bool{"10.0.0.2": true, "10.0.0.3": true}}, res.Meta["volume"])
+
+	vol.Hosts = hosts
+	res = ds.RemoveGlobalVolume(context.Background(), cli, vol)
+	require.NoError(t, res.Error)
+	for _, host := range hosts {
+		assert.False(t, hasVolume(t, engines[host], "shared"), host)
+		assert.Contains(t, engines[host].Execs(RsyncContainerName),
+			[]string{"rm", "-f", "/etc/rsyncd.d/shared.conf"}, host)
+	}
+	res = ds.RemoveVolumeShare(context.Background(), cli, share)
+	require.NoError(t, res.Error)
+	for _, host := range hosts {
+		_, err := engines[host].ContainerInspect(context.Background(), RsyncContainerName)
+		assert.Error(t, err, host)
+	}
+}
+
+func TestDockerService_SharedVolumes_Transports(t *testing.T) {
+	hosts := []string{"ssh://root@10.0.0.2", "10.0.0.3"}
+	for _, backend := range []string{entity.NFSVolumeBackend, entity.RsyncVolumeBackend} {
+		ds, engines := sharedVolumeHosts(t, backend, hosts...)
+		ds.conf.TransportAllowlist = hosts[:1]
+		cli := entity.DockerCli{Client: engines[hosts[0]], TestID: "test", IP: hosts[0]}
+
+		res := ds.VolumeShare(context.Background(), cli, entity.VolumeShare{
+			VolumeShare: command.VolumeShare{Hosts: hosts}})
+		require.NoError(t, res.Error, backend)
+		res = ds.CreateVolume(context.Background(), cli, entity.Volume{Volume: command.Volume{
+			Name: "shared", Global: true, Hosts: hosts}})
+		require.NoError(t, res.Error, backend)
+
+		if backend == entity.NFSVolumeBackend {
+			assert.Equal(t, "addr=10.0.0.2,rw,nfsvers=4", volumeOpts(t, engines["10.0.0.3"], "shared")["o"])
+			continue
+		}
+		daemon, err := engines[hosts[0]].ContainerInspect(context.Background(), RsyncContainerName)
+		require.NoError(t, err)
+		assert.Contains(t, daemon.Config.Env[0], "hosts allow = 10.0.0.2 10.0.0.3")
+		assert.Contains(t, engines["10.0.0.3"].Execs(RsyncContainerName), []string{"rsync", "-a", "--delete",
+			"rsync://10.0.0.2:8873/shared/", "/volumes/shared/_data/"})
+	}
+}
+
+func TestDockerService_SharedVolumeBackend(t *testing.T) {
+	ds, engines := sharedVolumeHosts(t, entity.RsyncVolumeBackend, "10.0.0.2")
+	cli := entity.DockerCli{Client: engines["10.0.0.2"], TestID: "test", IP: "10.0.0.2"}
+
+	res := ds.VolumeShare(context.Background(), cli, entity.VolumeShare{
+		VolumeShare: command.VolumeShare{Hosts: []string{"10.0.0.2"}}})
+	require.NoError(t, res.Error)
+	_, err := engines["10.0.0.2"].ContainerInspect(context.Background(), RsyncContainerName)
+	assert.NoError(t, err, "the backend of the config is used when none is given")
+
+	res = ds.VolumeShare(context.Background(), cli, entity.VolumeShare{
+		VolumeShare: command.VolumeShare{Hosts: []string{"10.0.0.2"}}, Backend: "ceph"})
+	assert.True(t, res.IsFatal())
+	res = ds.CreateVolume(context.Background(), cli, entity.Volume{Backend: "ceph",
+		Volume: command.Volume{Name: "shared", Global: true, Hosts: []string{"10.0.0.2"}}})
+	assert.True(t, res.IsFatal())
+}
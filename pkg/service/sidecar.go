@@ -0,0 +1,114 @@
+/**
+ * Copyright 2019 Whiteblock Inc. All rights reserved.
+ * Use of this source code is governed by a BSD-style
+ * license that can be found in the LICENSE file.
+ */
+
+package service
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/whiteblock/genesis/pkg/entity"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/pkg/stdcopy"
+	"github.com/sirupsen/logrus"
+	"github.com/whiteblock/definition/command"
+)
+
+// sidecar is a short lived container which joins the namespaces of another container
+type sidecar struct {
+	// Name is the name of the side car container
+	Name string
+	// Image is the image of the side car
+	Image string
+	// Target is the container whose namespaces are joined
+	Target string
+	// Cmd is the command to run
+	Cmd []string
+	// CapAdd are the capabilities given to the side car
+	CapAdd []string
+	// SharePID causes the side car to also join the pid namespace of the target
+	SharePID bool
+	// ShareVolumes causes the volumes of the target to be mounted in the side car
+	ShareVolumes bool
+}
+
+func (sc sidecar) spec() entity.ContainerSpec {
+	spec := entity.ContainerSpec{
+		Name:        sc.Name,
+		Image:       sc.Image,
+		Entrypoint:  sc.Cmd,
+		CapAdd:      sc.CapAdd,
+		NetworkMode: fmt.Sprintf("container:%s", sc.Target),
+	}
+	if sc.SharePID {
+		spec.PidMode = fmt.Sprintf("container:%s", sc.Target)
+	}
+	if sc.ShareVolumes {
+		spec.VolumesFrom = sc.Target
+	}
+	return spec
+}
+
+// runSidecar runs the side car until it exits, and gives back what it wrote to stdout
+func (ds dockerService) runSidecar(ctx context.Context, cli entity.DockerCli, sc sidecar) (string, error) {
+	ds.withFields(cli, logrus.Fields{
+		"name":   sc.Name,
+		"target": sc.Target,
+		"cmd":    strings.Join(sc.Cmd, " "),
+	}).Debug("running a side car")
+
+	err := ds.repo.EnsureImagePulled(ctx, cli, sc.Image, command.Credentials{})
+	if err != nil {
+		return "", err
+	}
+
+	engine := engineOf(cli.Client)
+	err = engine.CreateContainer(ctx, sc.spec())
+	if err != nil {
+		return "", err
+	}
+	defer func() {
+		err := engine.RemoveContainer(ctx, sc.Name, false)
+		if err != nil {
+			ds.withFields(cli, logrus.Fields{"name": sc.Name, "error": err}).Warn("failed to remove a side car")
+		}
+	}()
+
+	err = engine.StartContainer(ctx, sc.Name)
+	if err != nil {
+		return "", err
+	}
+
+	var exitCode int64
+	resChan, errChan := cli.ContainerWait(ctx, sc.Name, container.WaitConditionNotRunning)
+	select {
+	case res := <-resChan:
+		exitCode = res.StatusCode
+	case err := <-errChan:
+		return "", err
+	}
+
+	rdr, err := cli.ContainerLogs(ctx, sc.Name, types.ContainerLogsOptions{ShowStdout: true, ShowStderr: true})
+	if err != nil {
+		return "", err
+	}
+	defer rdr.Close()
+
+	var stdout, stderr bytes.Buffer
+	_, err = stdcopy.StdCopy(&stdout, &stderr, rdr)
+	if err != nil {
+		return "", err
+	}
+	if exitCode != 0 {
+		return stdout.String(), fmt.Errorf("side car \"%s\" exited with %d: %s",
+			sc.Name, exitCode, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.String(), nil
+}
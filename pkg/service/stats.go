@@ -0,0 +1,213 @@
+/**
+ * Copyright 2019 Whiteblock Inc. All rights reserved.
+ * Use of this source code is governed by a BSD-style
+ * license that can be found in the LICENSE file.
+ */
+
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/whiteblock/genesis/pkg/entity"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/sirupsen/logrus"
+	"github.com/whiteblock/definition/command"
+)
+
+// StatsPublisher sends out the samples of the resource usage of the containers as they are taken,
+// and the summary of it once the test is over
+type StatsPublisher interface {
+	// Publish sends out the samples taken of the containers of a test on one host
+	Publish(samples []entity.ContainerStats) error
+
+	// PublishSummary sends out the summary of the resource usage of the containers of a test
+	PublishSummary(summary entity.TestStatsSummary) error
+}
+
+// StatsSampler samples the resource usage of the containers of each test, on the hosts the test
+// runs on, and summarizes it once the test is over
+type StatsSampler interface {
+	// Track starts sampling the containers of the test on the host, unless they are already
+	// being sampled
+	Track(testID string, host string)
+
+	// Stop stops sampling the containers of the test, sends out the summary of the usage of
+	// each of them and gives it, by name
+	Stop(testID string) map[string]entity.ContainerStatsSummary
+}
+
+// sampledTest is what is kept for a test while its containers are being sampled
+type sampledTest struct {
+	hosts  map[string]bool
+	usage  map[string]*entity.ContainerStatsAccumulator
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     *sync.WaitGroup
+}
+
+type statsSampler struct {
+	interval  time.Duration
+	connect   func(host string, testID string) (entity.Client, error)
+	publisher StatsPublisher
+	log       logrus.Ext1FieldLogger
+
+	mux   *sync.Mutex
+	tests map[string]*sampledTest
+}
+
+// NewStatsSampler creates a StatsSampler which samples at the given interval, connecting to the
+// hosts with connect. Sampling is off when the interval is zero. The samples are not sent out
+// when the publisher is nil.
+func NewStatsSampler(
+	interval time.Duration,
+	connect func(host string, testID string) (entity.Client, error),
+	publisher StatsPublisher,
+	log logrus.Ext1FieldLogger) StatsSampler {
+	return &statsSampler{interval: interval, connect: connect, publisher: publisher, log: log,
+		mux: &sync.Mutex{}, tests: map[string]*sampledTest{}}
+}
+
+// Track starts sampling the containers of the test on the host
+func (ss *statsSampler) Track(testID string, host string) {
+	if ss.interval <= 0 || len(testID) == 0 || len(host) == 0 {
+		return
+	}
+	ss.mux.Lock()
+	defer ss.mux.Unlock()
+	test, ok := ss.tests[testID]
+	if !ok {
+		ctx, cancel := context.WithCancel(context.Background())
+		test = &sampledTest{hosts: map[string]bool{}, usage: map[string]*entity.ContainerStatsAccumulator{},
+			ctx: ctx, cancel: cancel, wg: &sync.WaitGroup{}}
+		ss.tests[testID] = test
+	}
+	if test.hosts[host] {
+		return
+	}
+	test.hosts[host] = true
+	test.wg.Add(1)
+	go ss.loop(test, testID, host)
+	ss.log.WithFields(logrus.Fields{"testID": testID, "host": host}).Debug("sampling the containers of a host")
+}
+
+// Stop stops sampling the containers of the test, and summarizes their usage
+func (ss *statsSampler) Stop(testID string) map[string]entity.ContainerStatsSummary {
+	ss.mux.Lock()
+	test, ok := ss.tests[testID]
+	delete(ss.tests, testID)
+	ss.mux.Unlock()
+	if !ok {
+		return nil
+	}
+	test.cancel()
+	test.wg.Wait()
+
+	out := map[string]entity.ContainerStatsSummary{}
+	for name, usage := range test.usage {
+		out[name] = usage.Summary()
+	}
+	if len(out) > 0 && ss.publisher != nil {
+		err := ss.publisher.PublishSummary(entity.TestStatsSummary{TestID: testID, Containers: out})
+		if err != nil {
+			ss.log.WithFields(logrus.Fields{"testID": testID, "error": err}).Error(
+				"failed to publish the summary")
+		}
+	}
+	return out
+}
+
+func (ss *statsSampler) loop(test *sampledTest, testID string, host string) {
+	defer test.wg.Done()
+	ticker := time.NewTicker(ss.interval)
+	defer ticker.Stop()
+	for {
+		samples, err := ss.sample(test.ctx, testID, host)
+		if test.ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			ss.log.WithFields(logrus.Fields{"testID": testID, "host": host, "error": err}).Warn(
+				"failed to sample the containers of a host")
+		}
+		if entity.IsNotSupported(err) {
+			return // the runtime of the host will never give them
+		}
+		ss.record(test, samples)
+		if len(samples) > 0 && ss.publisher != nil {
+			err = ss.publisher.Publish(samples)
+			if err != nil {
+				ss.log.WithFields(logrus.Fields{"testID": testID, "error": err}).Error(
+					"failed to publish the samples")
+			}
+		}
+		select {
+		case <-test.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (ss *statsSampler) record(test *sampledTest, samples []entity.ContainerStats) {
+	ss.mux.Lock()
+	defer ss.mux.Unlock()
+	for _, sample := range samples {
+		usage, ok := test.usage[sample.Container]
+		if !ok {
+			usage = &entity.ContainerStatsAccumulator{}
+			test.usage[sample.Container] = usage
+		}
+		usage.Add(sample)
+	}
+}
+
+// sample takes a sample of each of the running containers of the test on the host
+func (ss *statsSampler) sample(ctx context.Context, testID string, host string) ([]entity.ContainerStats, error) {
+	cli, err := ss.connect(host, testID)
+	if err != nil {
+		return nil, err
+	}
+	defer cli.Close()
+
+	cntrs, err := cli.ContainerList(ctx, types.ContainerListOptions{
+		Filters: filters.NewArgs(filters.Arg("label", command.TestIDKey+"="+testID)),
+	})
+	if err != nil {
+		return nil, err
+	}
+	out := []entity.ContainerStats{}
+	for _, cntr := range cntrs {
+		if len(cntr.Names) == 0 || entity.IsHelper(cntr.Labels) {
+			continue
+		}
+		name := strings.TrimPrefix(cntr.Names[0], "/")
+		stats, err := cli.ContainerStats(ctx, cntr.ID, false)
+		if err != nil {
+			if strings.Contains(err.Error(), "No such container") {
+				continue // it was removed since it was listed
+			}
+			return out, err
+		}
+		var raw types.StatsJSON
+		err = json.NewDecoder(stats.Body).Decode(&raw)
+		stats.Body.Close()
+		if err != nil {
+			return out, err
+		}
+		sample := entity.NewContainerStats(raw)
+		sample.TestID = testID
+		sample.Host = host
+		sample.Container = name
+		out = append(out, sample)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Container < out[j].Container })
+	return out, nil
+}
@@ -0,0 +1,139 @@
+/**
+ * Copyright 2019 Whiteblock Inc. All rights reserved.
+ * Use of this source code is governed by a BSD-style
+ * license that can be found in the LICENSE file.
+ */
+
+package service
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/whiteblock/genesis/pkg/entity"
+	"github.com/whiteblock/genesis/pkg/fake"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/whiteblock/definition/command"
+)
+
+type testStatsPublisher struct {
+	mux       *sync.Mutex
+	samples   []entity.ContainerStats
+	summaries []entity.TestStatsSummary
+}
+
+func (tsp *testStatsPublisher) Publish(samples []entity.ContainerStats) error {
+	tsp.mux.Lock()
+	defer tsp.mux.Unlock()
+	tsp.samples = append(tsp.samples, samples...)
+	return nil
+}
+
+func (tsp *testStatsPublisher) PublishSummary(summary entity.TestStatsSummary) error {
+	tsp.mux.Lock()
+	defer tsp.mux.Unlock()
+	tsp.summaries = append(tsp.summaries, summary)
+	return nil
+}
+
+// statsContainer runs a container for the test on the engine, which uses the given memory
+func statsContainer(t *testing.T, engine *fake.Engine, name string, testID string, memory uint64) {
+	ctx := context.Background()
+	engine.AddImage("alpine")
+	_, err := engine.ContainerCreate(ctx, &container.Config{Image: "alpine",
+		Labels: map[string]string{command.TestIDKey: testID}}, nil, nil, name)
+	require.NoError(t, err)
+	require.NoError(t, engine.ContainerStart(ctx, name, types.ContainerStartOptions{}))
+	var stats types.StatsJSON
+	stats.MemoryStats.Usage = memory
+	require.NoError(t, engine.SetStats(name, stats))
+}
+
+// helperContainer runs a container which Genesis runs for the test for its own use
+func helperContainer(t *testing.T, engine *fake.Engine, name string, testID string) {
+	ctx := context.Background()
+	engine.AddImage("alpine")
+	_, err := engine.ContainerCreate(ctx, &container.Config{Image: "alpine", Labels: map[string]string{
+		command.TestIDKey: testID, entity.HelperLabel: "true"}}, nil, nil, name)
+	require.NoError(t, err)
+	require.NoError(t, engine.ContainerStart(ctx, name, types.ContainerStartOptions{}))
+}
+
+func statsEngines(engines map[string]*fake.Engine) func(string, string) (entity.Client, error) {
+	return func(host string, testID string) (entity.Client, error) {
+		engine, ok := engines[host]
+		if !ok {
+			return nil, fmt.Errorf("no such host")
+		}
+		return engine, nil
+	}
+}
+
+func TestStatsSampler(t *testing.T) {
+	_, engines := swarmHosts(t, "10.0.0.2", "10.0.0.3")
+	statsContainer(t, engines["10.0.0.2"], "node0", "test", 100)
+	statsContainer(t, engines["10.0.0.3"], "node1", "test", 200)
+	statsContainer(t, engines["10.0.0.3"], "other", "another-test", 300)
+	helperContainer(t, engines["10.0.0.2"], "volume-helper-test-data", "test")
+
+	publisher := &testStatsPublisher{mux: &sync.Mutex{}}
+	sampler := NewStatsSampler(time.Millisecond, statsEngines(engines), publisher, logrus.New())
+	sampler.Track("test", "10.0.0.2")
+	sampler.Track("test", "10.0.0.3")
+	sampler.Track("test", "10.0.0.3")
+	time.Sleep(20 * time.Millisecond)
+
+	summaries := sampler.Stop("test")
+	require.Len(t, summaries, 2)
+	assert.Equal(t, "10.0.0.2", summaries["node0"].Host)
+	assert.Equal(t, float64(100), summaries["node0"].MemoryBytes.Max)
+	assert.Equal(t, "10.0.0.3", summaries["node1"].Host)
+	assert.Equal(t, float64(200), summaries["node1"].MemoryBytes.P50)
+	assert.True(t, summaries["node1"].Samples > 1)
+
+	publisher.mux.Lock()
+	published := len(publisher.samples)
+	for _, sample := range publisher.samples {
+		assert.Equal(t, "test", sample.TestID)
+		assert.NotEqual(t, "other", sample.Container)
+		assert.NotEqual(t, "volume-helper-test-data", sample.Container, "helpers are not sampled")
+	}
+	require.Len(t, publisher.summaries, 1)
+	assert.Equal(t, entity.TestStatsSummary{TestID: "test", Containers: summaries}, publisher.summaries[0])
+	publisher.mux.Unlock()
+	assert.Equal(t, summaries["node0"].Samples+summaries["node1"].Samples, published)
+	assert.Nil(t, sampler.Stop("test"), "the test is no longer sampled")
+}
+
+func TestStatsSampler_Off(t *testing.T) {
+	_, engines := swarmHosts(t, "10.0.0.2")
+	statsContainer(t, engines["10.0.0.2"], "node0", "test", 100)
+
+	sampler := NewStatsSampler(0, statsEngines(engines), nil, logrus.New())
+	sampler.Track("test", "10.0.0.2")
+	assert.Nil(t, sampler.Stop("test"))
+}
+
+func TestStatsSampler_NotSupported(t *testing.T) {
+	connected := 0
+	mux := &sync.Mutex{}
+	sampler := NewStatsSampler(time.Millisecond, func(string, string) (entity.Client, error) {
+		mux.Lock()
+		defer mux.Unlock()
+		connected++
+		return nil, entity.NotSupportedError{Runtime: entity.ContainerdRuntime, Operation: "stats"}
+	}, nil, logrus.New())
+	sampler.Track("test", "10.0.0.2")
+	time.Sleep(10 * time.Millisecond)
+
+	assert.Empty(t, sampler.Stop("test"))
+	assert.Equal(t, 1, connected, "the host is not sampled again")
+}
@@ -0,0 +1,285 @@
+/**
+ * Copyright 2019 Whiteblock Inc. All rights reserved.
+ * Use of this source code is governed by a BSD-style
+ * license that can be found in the LICENSE file.
+ */
+
+package service
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/whiteblock/genesis/pkg/entity"
+
+	"github.com/docker/docker/api/types/swarm"
+	"github.com/sirupsen/logrus"
+	"github.com/whiteblock/definition/command"
+)
+
+// SwarmCluster sets up a docker swarm on the hosts. The first host starts the swarm, unless it
+// already manages one, then the other managers join it one at a time, so that the managers agree
+// on each change, and the workers join all at once. Hosts which are already in the swarm are
+// left as they are, other than getting their role and labels.
+func (ds dockerService) SwarmCluster(ctx context.Context, entryCLI entity.DockerCli,
+	dswarm entity.Swarm) entity.Result {
+
+	if ds.conf.LocalMode {
+		// Do nothing if it is in local mode
+		return entity.NewSuccessResult()
+	}
+
+	if len(dswarm.Hosts) == 0 {
+		return ErrNoHost
+	}
+	cli, err := ds.CreateClient2(dswarm.Hosts[0], entryCLI.TestID)
+	if err != nil {
+		ds.withField(entryCLI, "error", err).Error("creating the manager client")
+		return entity.NewErrorResult(err)
+	}
+	defer cli.Close()
+
+	leaderID, err := ds.initSwarm(ctx, entryCLI, cli, dswarm.Hosts[0])
+	if err != nil {
+		ds.withField(entryCLI, "error", err).Error("error with docker swarm init")
+		return entity.NewErrorResult(err)
+	}
+
+	details, err := cli.SwarmInspect(ctx)
+	if err != nil {
+		ds.withField(entryCLI, "error", err).Error("error with docker swarm inspect")
+		return entity.NewErrorResult(err)
+	}
+	ds.withField(entryCLI, "swarm", details.ID).Info("initialized docker swarm")
+
+	leader := ds.hostAddress(dswarm.Hosts[0])
+	nodes := map[string]string{dswarm.Hosts[0]: leaderID}
+	for _, host := range dswarm.GetManagers()[1:] {
+		nodes[host], err = ds.joinSwarm(ctx, entryCLI, host, leader, leaderID, details.JoinTokens.Manager)
+		if err != nil {
+			return entity.NewErrorResult(err)
+		}
+	}
+
+	workers := dswarm.GetWorkers()
+	ids := make([]string, len(workers))
+	err = forEachHost(workers, func(i int, host string) (err error) {
+		ids[i], err = ds.joinSwarm(ctx, entryCLI, host, leader, leaderID, details.JoinTokens.Worker)
+		return
+	})
+	if err != nil {
+		return entity.NewErrorResult(err)
+	}
+	for i, host := range workers {
+		nodes[host] = ids[i]
+	}
+
+	for host, nodeID := range nodes {
+		err = ds.configureNode(ctx, cli, nodeID, containsHost(dswarm.GetManagers(), host),
+			dswarm.Labels[host])
+		if err != nil {
+			ds.withFields(entryCLI, logrus.Fields{"host": host, "error": err}).Error(
+				"failed to configure a swarm node")
+			return entity.NewErrorResult(err)
+		}
+	}
+	return entity.NewSuccessResult().InjectMeta(map[string]interface{}{
+		"swarm": details.ID,
+		"nodes": nodes,
+	})
+}
+
+// initSwarm starts a swarm on the host, unless it already manages one, and gives the ID of its node
+func (ds dockerService) initSwarm(ctx context.Context, entryCLI entity.DockerCli,
+	cli entity.Client, host string) (string, error) {
+
+	info, err := cli.Info(ctx)
+	if err != nil {
+		return "", err
+	}
+	switch {
+	case info.Swarm.LocalNodeState == swarm.LocalNodeStateActive && info.Swarm.ControlAvailable:
+		ds.withField(entryCLI, "host", host).Info("the host already manages a swarm, reusing it")
+		return info.Swarm.NodeID, nil
+	case info.Swarm.LocalNodeState != swarm.LocalNodeStateInactive:
+		return "", fmt.Errorf("%s cannot start a swarm, it is %s in a swarm it does not manage",
+			host, info.Swarm.LocalNodeState)
+	}
+	return cli.SwarmInit(ctx, swarm.InitRequest{
+		ListenAddr:    fmt.Sprintf("0.0.0.0:%d", ds.conf.SwarmPort),
+		AdvertiseAddr: fmt.Sprintf("%s:%d", ds.hostAddress(host), ds.conf.SwarmPort),
+		Availability:  swarm.NodeAvailabilityActive,
+	})
+}
+
+// joinSwarm joins the host to the swarm led by the given node, unless it is already in it, and
+// gives the ID of its node
+func (ds dockerService) joinSwarm(ctx context.Context, entryCLI entity.DockerCli, host string,
+	leader string, leaderID string, token string) (string, error) {
+
+	cli, err := ds.CreateClient2(host, entryCLI.TestID)
+	if err != nil {
+		return "", err
+	}
+	defer cli.Close()
+	info, err := cli.Info(ctx)
+	if err != nil {
+		return "", err
+	}
+	if info.Swarm.LocalNodeState == swarm.LocalNodeStateActive {
+		if !managedBy(info.Swarm, leaderID) {
+			return "", fmt.Errorf("%s is already in another swarm", host)
+		}
+		ds.withField(entryCLI, "host", host).Info("the host is already in the swarm")
+		return info.Swarm.NodeID, nil
+	}
+	if info.Swarm.LocalNodeState != swarm.LocalNodeStateInactive {
+		return "", fmt.Errorf("%s cannot join the swarm, it is %s", host, info.Swarm.LocalNodeState)
+	}
+
+	ds.withField(entryCLI, "host", host).Info("adding a node to the swarm")
+	err = cli.SwarmJoin(ctx, swarm.JoinRequest{
+		ListenAddr:    fmt.Sprintf("0.0.0.0:%d", ds.conf.SwarmPort),
+		AdvertiseAddr: fmt.Sprintf("%s:%d", ds.hostAddress(host), ds.conf.SwarmPort),
+		RemoteAddrs:   []string{fmt.Sprintf("%s:%d", leader, ds.conf.SwarmPort)},
+		JoinToken:     token,
+		Availability:  swarm.NodeAvailabilityActive,
+	})
+	if err != nil {
+		return "", err
+	}
+	info, err = cli.Info(ctx)
+	return info.Swarm.NodeID, err
+}
+
+// managedBy checks whether the given node is a manager of the swarm of the node, workers do not
+// know the ID of their swarm, only who their managers are
+func managedBy(info swarm.Info, managerID string) bool {
+	if info.NodeID == managerID {
+		return true
+	}
+	for _, peer := range info.RemoteManagers {
+		if peer.NodeID == managerID {
+			return true
+		}
+	}
+	return false
+}
+
+// configureNode gives the node its role and adds the labels to it, if it does not have them yet
+func (ds dockerService) configureNode(ctx context.Context, cli entity.Client, nodeID string,
+	manager bool, labels map[string]string) error {
+
+	node, _, err := cli.NodeInspectWithRaw(ctx, nodeID)
+	if err != nil {
+		return err
+	}
+	role := swarm.NodeRoleWorker
+	if manager {
+		role = swarm.NodeRoleManager
+	}
+	changed := node.Spec.Role != role
+	node.Spec.Role = role
+	if node.Spec.Labels == nil {
+		node.Spec.Labels = map[string]string{}
+	}
+	for key, value := range labels {
+		if existing, ok := node.Spec.Labels[key]; !ok || existing != value {
+			node.Spec.Labels[key] = value
+			changed = true
+		}
+	}
+	if !changed {
+		return nil
+	}
+	return cli.NodeUpdate(ctx, nodeID, node.Version, node.Spec)
+}
+
+// LeaveSwarm makes the hosts leave the swarm they are in. The workers leave first, then the
+// managers, which are forced to, since the swarm is being torn down. Hosts which are not in a
+// swarm are skipped.
+func (ds dockerService) LeaveSwarm(ctx context.Context, entryCLI entity.DockerCli,
+	dswarm command.SetupSwarm) entity.Result {
+
+	if ds.conf.LocalMode {
+		return entity.NewSuccessResult()
+	}
+	if len(dswarm.Hosts) == 0 {
+		return ErrNoHost
+	}
+
+	managers := make([]bool, len(dswarm.Hosts))
+	err := forEachHost(dswarm.Hosts, func(i int, host string) (err error) {
+		managers[i], err = ds.leaveSwarm(ctx, entryCLI, host, false)
+		return
+	})
+	if err != nil {
+		return entity.NewErrorResult(err)
+	}
+	remaining := []string{}
+	for i, host := range dswarm.Hosts {
+		if managers[i] {
+			remaining = append(remaining, host)
+		}
+	}
+	err = forEachHost(remaining, func(i int, host string) error {
+		_, err := ds.leaveSwarm(ctx, entryCLI, host, true)
+		return err
+	})
+	return entity.NewResult(err)
+}
+
+// leaveSwarm makes the host leave its swarm, a manager is only made to leave when it is forced
+// to. It gives whether the host was left in the swarm as a manager.
+func (ds dockerService) leaveSwarm(ctx context.Context, entryCLI entity.DockerCli, host string,
+	force bool) (bool, error) {
+
+	cli, err := ds.CreateClient2(host, entryCLI.TestID)
+	if err != nil {
+		return false, err
+	}
+	defer cli.Close()
+	info, err := cli.Info(ctx)
+	if err != nil {
+		return false, err
+	}
+	if info.Swarm.LocalNodeState == swarm.LocalNodeStateInactive {
+		return false, nil
+	}
+	if info.Swarm.ControlAvailable && !force {
+		return true, nil
+	}
+	ds.withField(entryCLI, "host", host).Info("leaving the swarm")
+	return false, cli.SwarmLeave(ctx, force)
+}
+
+// forEachHost runs the function for each of the hosts at the same time, and gives the first of
+// their errors
+func forEachHost(hosts []string, fn func(i int, host string) error) error {
+	errs := make([]error, len(hosts))
+	wg := sync.WaitGroup{}
+	for i := range hosts {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs[i] = fn(i, hosts[i])
+		}(i)
+	}
+	wg.Wait()
+	for i, err := range errs {
+		if err != nil {
+			return fmt.Errorf("%s: %v", hosts[i], err)
+		}
+	}
+	return nil
+}
+
+func containsHost(hosts []string, host string) bool {
+	for _, h := range hosts {
+		if h == host {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,228 @@
+/**
+ * Copyright 2019 Whiteblock Inc. All rights reserved.
+ * Use of this source code is governed by a BSD-style
+ * license that can be found in the LICENSE file.
+ */
+
+package service
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/whiteblock/genesis/pkg/entity"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/api/types/swarm"
+	"github.com/sirupsen/logrus"
+)
+
+// serviceHostname gives each task of a service a hostname of its own, from its slot
+const serviceHostname = "{{.Service.Name}}-{{.Task.Slot}}"
+
+// serviceSpec gives the spec of the swarm service, which runs its container the way
+// CreateContainer would
+func (ds dockerService) serviceSpec(cli entity.DockerCli, svc entity.SwarmService) (swarm.ServiceSpec, error) {
+	limits := &swarm.Resources{}
+	if len(svc.Cpus) > 0 {
+		cpus, err := strconv.ParseFloat(svc.Cpus, 64)
+		if err != nil {
+			return swarm.ServiceSpec{}, fmt.Errorf("invalid cpus \"%s\"", svc.Cpus)
+		}
+		limits.NanoCPUs = int64(1000000000 * cpus)
+	}
+	if len(svc.Memory) > 0 {
+		mem, err := svc.GetMemory()
+		if err != nil {
+			return swarm.ServiceSpec{}, fmt.Errorf("invalid memory \"%s\"", svc.Memory)
+		}
+		limits.MemoryBytes = mem
+	}
+
+	labels := map[string]string{}
+	for key, value := range svc.Labels {
+		labels[key] = value
+	}
+	for key, value := range cli.Labels {
+		labels[key] = value
+	}
+
+	networks := []swarm.NetworkAttachmentConfig{}
+	for _, net := range svc.GetNetworks() {
+		networks = append(networks, swarm.NetworkAttachmentConfig{Target: net})
+	}
+
+	ports := []swarm.PortConfig{}
+	for published, target := range svc.TCPPorts {
+		ports = append(ports, swarm.PortConfig{Protocol: swarm.PortConfigProtocolTCP,
+			TargetPort: uint32(target), PublishedPort: uint32(published)})
+	}
+	for published, target := range svc.UDPPorts {
+		ports = append(ports, swarm.PortConfig{Protocol: swarm.PortConfigProtocolUDP,
+			TargetPort: uint32(target), PublishedPort: uint32(published)})
+	}
+
+	replicas := svc.Replicas
+	spec := swarm.ServiceSpec{
+		Annotations: swarm.Annotations{Name: svc.Name, Labels: cli.Labels},
+		TaskTemplate: swarm.TaskSpec{
+			ContainerSpec: &swarm.ContainerSpec{
+				Image:    svc.Image,
+				Labels:   labels,
+				Command:  svc.GetEntryPoint(),
+				Hostname: serviceHostname,
+				Env:      svc.GetEnv(),
+				Mounts:   svc.GetMounts(),
+			},
+			Resources: &swarm.ResourceRequirements{Limits: limits},
+			Placement: &swarm.Placement{Constraints: svc.Constraints},
+			Networks:  networks,
+		},
+		Mode: swarm.ServiceMode{Replicated: &swarm.ReplicatedService{Replicas: &replicas}},
+		UpdateConfig: &swarm.UpdateConfig{
+			Parallelism:   svc.UpdatePolicy.Parallelism,
+			Delay:         svc.UpdatePolicy.Delay.Duration,
+			FailureAction: svc.UpdatePolicy.FailureAction,
+			Order:         svc.UpdatePolicy.Order,
+		},
+		EndpointSpec: &swarm.EndpointSpec{Ports: ports},
+	}
+	if len(ds.conf.LogDriver) > 0 {
+		spec.TaskTemplate.LogDriver = &swarm.Driver{
+			Name:    ds.conf.LogDriver,
+			Options: map[string]string{"labels": ds.conf.LogLabels},
+		}
+	}
+	return spec, nil
+}
+
+// registryAuth gives the encoded credentials of the service, for the nodes to pull its image with
+func (ds dockerService) registryAuth(svc entity.SwarmService) string {
+	if svc.Credentials.Empty() {
+		return ""
+	}
+	return ds.repo.RegistryAuth(svc.Credentials)
+}
+
+// serviceTasks gives the states of the tasks of the service which are meant to be running
+func (ds dockerService) serviceTasks(ctx context.Context, cli entity.DockerCli,
+	serviceID string) ([]entity.ServiceTask, error) {
+
+	tasks, err := cli.TaskList(ctx, types.TaskListOptions{Filters: filters.NewArgs(
+		filters.Arg("service", serviceID),
+		filters.Arg("desired-state", string(swarm.TaskStateRunning)),
+	)})
+	if err != nil {
+		return nil, err
+	}
+	out := make([]entity.ServiceTask, 0, len(tasks))
+	for _, task := range tasks {
+		out = append(out, entity.ServiceTask{
+			ID:           task.ID,
+			Slot:         task.Slot,
+			Node:         task.NodeID,
+			State:        string(task.Status.State),
+			DesiredState: string(task.DesiredState),
+			Error:        task.Status.Err,
+		})
+		if len(task.Status.Err) > 0 {
+			ds.withFields(cli, logrus.Fields{"task": task.ID, "error": task.Status.Err}).Warn(
+				"a task of the service has an error")
+		}
+	}
+	return out, nil
+}
+
+// serviceResult reports the states of the tasks of the service
+func (ds dockerService) serviceResult(ctx context.Context, cli entity.DockerCli, serviceID string) entity.Result {
+	tasks, err := ds.serviceTasks(ctx, cli, serviceID)
+	if err != nil {
+		return entity.NewErrorResult(err)
+	}
+	return entity.NewSuccessResult().InjectMeta(map[string]interface{}{
+		"service": serviceID,
+		"tasks":   tasks,
+	})
+}
+
+// CreateService creates a swarm service, which runs replicas of a container across the swarm
+func (ds dockerService) CreateService(ctx context.Context, cli entity.DockerCli,
+	svc entity.SwarmService) entity.Result {
+
+	spec, err := ds.serviceSpec(cli, svc)
+	if err != nil {
+		return entity.NewFatalResult(err)
+	}
+	resp, err := cli.ServiceCreate(ctx, spec, types.ServiceCreateOptions{
+		EncodedRegistryAuth: ds.registryAuth(svc),
+		QueryRegistry:       true,
+	})
+	if err != nil {
+		ds.withFields(cli, logrus.Fields{"service": svc.Name, "error": err}).Error(
+			"failed to create the service")
+		return ds.errorWhitelistHandler(err)
+	}
+	for _, warning := range resp.Warnings {
+		ds.withField(cli, "service", svc.Name).Warn(warning)
+	}
+	return ds.serviceResult(ctx, cli, resp.ID)
+}
+
+// UpdateService replaces the container of a swarm service, along with how it is run. The tasks
+// are replaced as its update policy says.
+func (ds dockerService) UpdateService(ctx context.Context, cli entity.DockerCli,
+	svc entity.SwarmService) entity.Result {
+
+	spec, err := ds.serviceSpec(cli, svc)
+	if err != nil {
+		return entity.NewFatalResult(err)
+	}
+	existing, _, err := cli.ServiceInspectWithRaw(ctx, svc.Name, types.ServiceInspectOptions{})
+	if err != nil {
+		return entity.NewErrorResult(err)
+	}
+	return ds.updateService(ctx, cli, existing, spec, ds.registryAuth(svc))
+}
+
+// ScaleService changes how many tasks a swarm service runs
+func (ds dockerService) ScaleService(ctx context.Context, cli entity.DockerCli,
+	scale entity.ScaleService) entity.Result {
+
+	existing, _, err := cli.ServiceInspectWithRaw(ctx, scale.Name, types.ServiceInspectOptions{})
+	if err != nil {
+		return entity.NewErrorResult(err)
+	}
+	if existing.Spec.Mode.Replicated == nil {
+		return entity.NewFatalResult(fmt.Errorf("the service %s is not replicated", scale.Name))
+	}
+	spec := existing.Spec
+	replicas := scale.Replicas
+	spec.Mode.Replicated = &swarm.ReplicatedService{Replicas: &replicas}
+	return ds.updateService(ctx, cli, existing, spec, "")
+}
+
+func (ds dockerService) updateService(ctx context.Context, cli entity.DockerCli, existing swarm.Service,
+	spec swarm.ServiceSpec, auth string) entity.Result {
+
+	resp, err := cli.ServiceUpdate(ctx, existing.ID, existing.Version, spec, types.ServiceUpdateOptions{
+		EncodedRegistryAuth: auth,
+		QueryRegistry:       len(auth) > 0,
+	})
+	if err != nil {
+		ds.withFields(cli, logrus.Fields{"service": existing.Spec.Name, "error": err}).Error(
+			"failed to update the service")
+		return ds.errorWhitelistHandler(err)
+	}
+	for _, warning := range resp.Warnings {
+		ds.withField(cli, "service", existing.Spec.Name).Warn(warning)
+	}
+	return ds.serviceResult(ctx, cli, existing.ID)
+}
+
+// RemoveService removes a swarm service along with its tasks
+func (ds dockerService) RemoveService(ctx context.Context, cli entity.DockerCli, name string) entity.Result {
+	ds.withField(cli, "service", name).Debug("removing a service")
+	return ds.errorWhitelistHandler(cli.ServiceRemove(ctx, name), "No such service")
+}
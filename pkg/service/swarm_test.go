@@ -0,0 +1,185 @@
+/**
+ * Copyright 2019 Whiteblock Inc. All rights reserved.
+ * Use of this source code is governed by a BSD-style
+ * license that can be found in the LICENSE file.
+ */
+
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/whiteblock/genesis/pkg/config"
+	"github.com/whiteblock/genesis/pkg/entity"
+	"github.com/whiteblock/genesis/pkg/fake"
+	"github.com/whiteblock/genesis/pkg/repository"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/swarm"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/whiteblock/definition/command"
+)
+
+// swarmHosts gives a service whose clients for the hosts are the given fake engines
+func swarmHosts(t *testing.T, hosts ...string) (DockerService, map[string]*fake.Engine) {
+	ds := NewDockerService(nil, config.Docker{
+		SwarmPort:            2477,
+		ClientIdleTimeout:    time.Minute,
+		ClientHealthInterval: time.Minute,
+	}, nil, repository.NewCredentialStore(""), logrus.New()).(dockerService)
+
+	engines := map[string]*fake.Engine{}
+	for _, host := range hosts {
+		engine := fake.NewEngine(host)
+		cli, err := ds.pool.get(clientKey(host, "test", ds.conf.Runtime), "",
+			func() (entity.Client, error) { return engine, nil })
+		require.NoError(t, err)
+		cli.Close()
+		engines[host] = engine
+	}
+	return ds, engines
+}
+
+func swarmState(t *testing.T, engine *fake.Engine) swarm.Info {
+	info, err := engine.Info(context.Background())
+	require.NoError(t, err)
+	return info.Swarm
+}
+
+func TestDockerService_SwarmCluster(t *testing.T) {
+	hosts := []string{"10.0.0.2", "10.0.0.3", "10.0.0.4", "10.0.0.5", "10.0.0.6"}
+	ds, engines := swarmHosts(t, hosts...)
+	cli := entity.DockerCli{TestID: "test"}
+	setup := entity.Swarm{
+		SetupSwarm: command.SetupSwarm{Hosts: hosts},
+		Managers:   3,
+		Labels:     map[string]map[string]string{"10.0.0.5": {"zone": "east"}},
+	}
+
+	res := ds.SwarmCluster(context.Background(), cli, setup)
+	require.NoError(t, res.Error)
+	nodes := res.Meta["nodes"].(map[string]string)
+	require.Len(t, nodes, 5)
+
+	leader := swarmState(t, engines["10.0.0.2"])
+	assert.Equal(t, 5, leader.Nodes)
+	assert.Equal(t, 3, leader.Managers)
+	for i, host := range hosts {
+		assert.Equal(t, i < 3, swarmState(t, engines[host]).ControlAvailable, host)
+	}
+	node, _, err := engines["10.0.0.2"].NodeInspectWithRaw(context.Background(), nodes["10.0.0.5"])
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"zone": "east"}, node.Spec.Labels)
+
+	setup.Managers = 1
+	res = ds.SwarmCluster(context.Background(), cli, setup)
+	require.NoError(t, res.Error, "setting up the swarm again reuses it")
+	assert.Equal(t, nodes, res.Meta["nodes"])
+	assert.Equal(t, 5, swarmState(t, engines["10.0.0.2"]).Nodes)
+	assert.False(t, swarmState(t, engines["10.0.0.3"]).ControlAvailable, "the extra managers are demoted")
+
+	res = ds.LeaveSwarm(context.Background(), cli, setup.SetupSwarm)
+	require.NoError(t, res.Error)
+	for _, host := range hosts {
+		assert.Equal(t, swarm.LocalNodeStateInactive, swarmState(t, engines[host]).LocalNodeState, host)
+	}
+	res = ds.LeaveSwarm(context.Background(), cli, setup.SetupSwarm)
+	assert.NoError(t, res.Error, "leaving again does nothing")
+}
+
+func TestDockerService_SwarmCluster_OtherSwarm(t *testing.T) {
+	ds, engines := swarmHosts(t, "10.0.0.2", "10.0.0.3", "10.0.0.4")
+	cli := entity.DockerCli{TestID: "test"}
+
+	res := ds.SwarmCluster(context.Background(), cli, entity.Swarm{
+		SetupSwarm: command.SetupSwarm{Hosts: []string{"10.0.0.4"}}})
+	require.NoError(t, res.Error)
+
+	res = ds.SwarmCluster(context.Background(), cli, entity.Swarm{
+		SetupSwarm: command.SetupSwarm{Hosts: []string{"10.0.0.2", "10.0.0.3", "10.0.0.4"}}})
+	assert.Error(t, res.Error, "a host which manages another swarm is not taken from it")
+	assert.Equal(t, 1, swarmState(t, engines["10.0.0.4"]).Nodes)
+
+	res = ds.SwarmCluster(context.Background(), cli, entity.Swarm{
+		SetupSwarm: command.SetupSwarm{Hosts: []string{"10.0.0.3"}}})
+	assert.Error(t, res.Error, "a worker cannot start a swarm")
+
+	res = ds.LeaveSwarm(context.Background(), cli, command.SetupSwarm{
+		Hosts: []string{"10.0.0.2", "10.0.0.3", "10.0.0.4"}})
+	require.NoError(t, res.Error)
+
+	engines["10.0.0.3"].FailNext("SwarmJoin", errors.New("unreachable"))
+	res = ds.SwarmCluster(context.Background(), cli, entity.Swarm{
+		SetupSwarm: command.SetupSwarm{Hosts: []string{"10.0.0.2", "10.0.0.3", "10.0.0.4"}}})
+	assert.Error(t, res.Error)
+	assert.Equal(t, 2, swarmState(t, engines["10.0.0.2"]).Nodes, "the other workers still join")
+}
+
+func TestDockerService_SwarmServices(t *testing.T) {
+	hosts := []string{"10.0.0.2", "10.0.0.3", "10.0.0.4"}
+	ds, engines := swarmHosts(t, hosts...)
+	res := ds.SwarmCluster(context.Background(), entity.DockerCli{TestID: "test"}, entity.Swarm{
+		SetupSwarm: command.SetupSwarm{Hosts: hosts},
+		Labels: map[string]map[string]string{
+			"10.0.0.3": {"zone": "east"},
+			"10.0.0.4": {"zone": "east"},
+		},
+	})
+	require.NoError(t, res.Error)
+	nodes := res.Meta["nodes"].(map[string]string)
+	cli := entity.DockerCli{Client: engines["10.0.0.2"], TestID: "test",
+		Labels: map[string]string{"testID": "test"}}
+
+	svc := entity.SwarmService{
+		Container:   command.Container{Name: "nodes", Image: "geth", Cpus: "1", Memory: "1GB"},
+		Replicas:    4,
+		Constraints: []string{"node.labels.zone==east"},
+		Networks:    []string{"overlay"},
+	}
+	res = ds.CreateService(context.Background(), cli, svc)
+	require.NoError(t, res.Error)
+	tasks := res.Meta["tasks"].([]entity.ServiceTask)
+	require.Len(t, tasks, 4)
+	perNode := map[string]int{}
+	for i, task := range tasks {
+		assert.Equal(t, i+1, task.Slot)
+		assert.Equal(t, string(swarm.TaskStateRunning), task.State)
+		perNode[task.Node]++
+	}
+	assert.Equal(t, map[string]int{nodes["10.0.0.3"]: 2, nodes["10.0.0.4"]: 2}, perNode)
+
+	created, _, err := cli.ServiceInspectWithRaw(context.Background(), "nodes", types.ServiceInspectOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, "test", created.Spec.Labels["testID"])
+	assert.Equal(t, int64(1000000000), created.Spec.TaskTemplate.Resources.Limits.NanoCPUs)
+	assert.Equal(t, "overlay", created.Spec.TaskTemplate.Networks[0].Target)
+
+	res = ds.CreateService(context.Background(), cli, svc)
+	assert.Error(t, res.Error, "the name is taken")
+
+	res = ds.ScaleService(context.Background(), cli, entity.ScaleService{Name: "nodes", Replicas: 2})
+	require.NoError(t, res.Error)
+	assert.Len(t, res.Meta["tasks"], 2)
+
+	first := res.Meta["tasks"].([]entity.ServiceTask)[0].ID
+	svc.Image = "geth:latest-2"
+	svc.Replicas = 2
+	svc.Constraints = []string{"node.labels.zone==west"}
+	svc.UpdatePolicy = entity.UpdatePolicy{Parallelism: 1, Order: "start-first"}
+	res = ds.UpdateService(context.Background(), cli, svc)
+	require.NoError(t, res.Error)
+	tasks = res.Meta["tasks"].([]entity.ServiceTask)
+	require.Len(t, tasks, 2)
+	assert.NotEqual(t, first, tasks[0].ID, "the tasks are replaced")
+	assert.Equal(t, string(swarm.TaskStatePending), tasks[0].State, "no node is in the west")
+	assert.NotEmpty(t, tasks[0].Error)
+
+	assert.NoError(t, ds.RemoveService(context.Background(), cli, "nodes").Error)
+	assert.NoError(t, ds.RemoveService(context.Background(), cli, "nodes").Error)
+	assert.Error(t, ds.ScaleService(context.Background(), cli, entity.ScaleService{Name: "nodes"}).Error)
+}
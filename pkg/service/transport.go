@@ -0,0 +1,176 @@
+/**
+ * Copyright 2019 Whiteblock Inc. All rights reserved.
+ * Use of this source code is governed by a BSD-style
+ * license that can be found in the LICENSE file.
+ */
+
+package service
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/whiteblock/genesis/pkg/config"
+	"github.com/whiteblock/genesis/pkg/entity"
+
+	"github.com/docker/cli/cli/connhelper"
+	"github.com/docker/docker/client"
+)
+
+// The schemes a target can have, to reach its daemon some other way than over tcp with the
+// credentials of the test
+const (
+	sshScheme  = "ssh://"
+	unixScheme = "unix://"
+	tcpScheme  = "tcp://"
+)
+
+// ErrTransportNotAllowed is returned when a command targets an ssh:// or unix:// address which
+// is not in the transport allowlist of the config
+var ErrTransportNotAllowed = errors.New("the target is not in the transport allowlist")
+
+// hasTransportScheme checks whether the target asks for a transport other than tcp
+func hasTransportScheme(target string) bool {
+	return strings.HasPrefix(target, sshScheme) || strings.HasPrefix(target, unixScheme)
+}
+
+// transportHost gives the address of the daemon the target reaches over its own transport, if it
+// is a named endpoint or an ssh:// or unix:// address of the allowlist. Any other target with
+// one of those schemes is not reached at all, since the daemon would be reached without TLS.
+func (ds dockerService) transportHost(target string) (string, bool) {
+	if endpoint, ok := ds.conf.Endpoints[target]; ok {
+		return endpoint.Host, true
+	}
+	if !hasTransportScheme(target) {
+		return "", false
+	}
+	for _, allowed := range ds.conf.TransportAllowlist {
+		if target == allowed {
+			return target, true
+		}
+	}
+	return "", false
+}
+
+// hostAddress gives the address of the machine the target is on, for the peers of its daemon
+// to reach it by
+func (ds dockerService) hostAddress(target string) string {
+	host, ok := ds.transportHost(target)
+	if !ok {
+		return target
+	}
+	u, err := url.Parse(host)
+	if err != nil || len(u.Hostname()) == 0 {
+		return target
+	}
+	return u.Hostname()
+}
+
+// hostAddresses gives the address of the machine each of the targets is on
+func (ds dockerService) hostAddresses(targets []string) []string {
+	out := make([]string, len(targets))
+	for i, target := range targets {
+		out[i] = ds.hostAddress(target)
+	}
+	return out
+}
+
+// transportClient creates a client for the daemon of a named endpoint, or of an allowed ssh://
+// or unix:// target
+func (ds dockerService) transportClient(target string) (*client.Client, error) {
+	if endpoint, ok := ds.conf.Endpoints[target]; ok {
+		return ds.endpointClient(endpoint)
+	}
+	return hostClient(target)
+}
+
+func hostClient(host string) (*client.Client, error) {
+	if !strings.HasPrefix(host, sshScheme) {
+		return client.NewClientWithOpts(
+			client.WithAPIVersionNegotiation(),
+			client.WithHost(host),
+		)
+	}
+	helper, err := connhelper.GetConnectionHelper(host)
+	if err != nil {
+		return nil, err
+	}
+	return client.NewClientWithOpts(
+		client.WithAPIVersionNegotiation(),
+		client.WithHTTPClient(&http.Client{Transport: &http.Transport{DialContext: helper.Dialer}}),
+		client.WithHost(helper.Host),
+		client.WithDialContext(helper.Dialer),
+	)
+}
+
+func (ds dockerService) endpointClient(endpoint config.Endpoint) (*client.Client, error) {
+	if !strings.HasPrefix(endpoint.Host, tcpScheme) || len(endpoint.CACert) == 0 {
+		return hostClient(endpoint.Host)
+	}
+	creds, err := endpointCredentials(endpoint)
+	if err != nil {
+		return nil, err
+	}
+	defer creds.Wipe()
+	u, err := url.Parse(endpoint.Host)
+	if err != nil {
+		return nil, err
+	}
+	return client.NewClientWithOpts(
+		client.WithAPIVersionNegotiation(),
+		client.WithHost(endpoint.Host),
+		ds.repo.WithTLSClientConfig(creds, u.Hostname(), endpoint.ServerName),
+	)
+}
+
+func endpointCredentials(endpoint config.Endpoint) (creds entity.Credentials, err error) {
+	files := []struct {
+		path string
+		out  *[]byte
+	}{
+		{path: endpoint.CACert, out: &creds.CACert},
+		{path: endpoint.Cert, out: &creds.ClientCert},
+		{path: endpoint.Key, out: &creds.ClientKey},
+	}
+	for _, file := range files {
+		*file.out, err = ioutil.ReadFile(file.path)
+		if err != nil {
+			creds.Wipe()
+			return entity.Credentials{}, fmt.Errorf("failed to read the tls material of %s: %v",
+				endpoint.Host, err)
+		}
+	}
+	return creds, nil
+}
+
+// transportFingerprint identifies what the client of the target is made with, so that it is
+// replaced when that changes
+func (ds dockerService) transportFingerprint(target string, testID string) string {
+	if _, ok := ds.transportHost(target); !ok {
+		if hasTransportScheme(target) {
+			return "" // it is never connected to
+		}
+		return ds.certFingerprint(testID)
+	}
+	endpoint, ok := ds.conf.Endpoints[target]
+	if !ok {
+		return ""
+	}
+	hash := sha256.New()
+	hash.Write([]byte(endpoint.Host))
+	if strings.HasPrefix(endpoint.Host, tcpScheme) && len(endpoint.CACert) > 0 {
+		creds, err := endpointCredentials(endpoint)
+		if err != nil {
+			return ""
+		}
+		hash.Write([]byte(creds.Fingerprint()))
+		creds.Wipe()
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
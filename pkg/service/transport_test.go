@@ -0,0 +1,110 @@
+/**
+ * Copyright 2019 Whiteblock Inc. All rights reserved.
+ * Use of this source code is governed by a BSD-style
+ * license that can be found in the LICENSE file.
+ */
+
+package service
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/whiteblock/genesis/pkg/config"
+	"github.com/whiteblock/genesis/pkg/repository"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDockerService_TransportHost(t *testing.T) {
+	ds := dockerService{conf: config.Docker{Endpoints: map[string]config.Endpoint{
+		"sidecar": {Host: "unix:///run/sidecar/docker.sock"},
+		"bastion": {Host: "ssh://genesis@bastion.example.com:2222"},
+	}, TransportAllowlist: []string{"ssh://root@10.0.0.2", "unix:///var/run/d.sk"}}}
+
+	for target, expected := range map[string]string{
+		"sidecar":              "unix:///run/sidecar/docker.sock",
+		"bastion":              "ssh://genesis@bastion.example.com:2222",
+		"ssh://root@10.0.0.2":  "ssh://root@10.0.0.2",
+		"unix:///var/run/d.sk": "unix:///var/run/d.sk",
+	} {
+		host, ok := ds.transportHost(target)
+		assert.True(t, ok, target)
+		assert.Equal(t, expected, host, target)
+	}
+	for _, target := range []string{"10.0.0.2", "ssh://root@10.0.0.3", "unix:///var/run/docker.sock"} {
+		_, ok := ds.transportHost(target)
+		assert.False(t, ok, target)
+	}
+
+	assert.Equal(t, "10.0.0.2", ds.hostAddress("10.0.0.2"))
+	assert.Equal(t, "10.0.0.2", ds.hostAddress("ssh://root@10.0.0.2"))
+	assert.Equal(t, "bastion.example.com", ds.hostAddress("bastion"))
+	assert.Equal(t, "sidecar", ds.hostAddress("sidecar"))
+}
+
+func TestDockerService_CreateClient2_Transports(t *testing.T) {
+	ds := NewDockerService(nil, config.Docker{
+		Endpoints: map[string]config.Endpoint{
+			"sidecar": {Host: "unix:///run/sidecar/docker.sock"},
+			"plain":   {Host: "tcp://10.0.0.4:2375"},
+		},
+		TransportAllowlist: []string{"unix:///var/run/other.sock", "ssh://genesis@10.0.0.5"},
+	}, nil, repository.NewCredentialStore(""), nil)
+
+	cli, err := ds.CreateClient2("unix:///var/run/other.sock", "test")
+	require.NoError(t, err)
+	assert.Equal(t, "unix:///var/run/other.sock", cli.DaemonHost())
+
+	cli, err = ds.CreateClient2("sidecar", "test")
+	require.NoError(t, err)
+	assert.Equal(t, "unix:///run/sidecar/docker.sock", cli.DaemonHost())
+
+	cli, err = ds.CreateClient2("plain", "test")
+	require.NoError(t, err)
+	assert.Equal(t, "tcp://10.0.0.4:2375", cli.DaemonHost())
+
+	cli, err = ds.CreateClient2("ssh://genesis@10.0.0.5", "test")
+	require.NoError(t, err)
+	assert.Equal(t, "http://docker", cli.DaemonHost())
+
+	for _, target := range []string{"unix:///var/run/docker.sock", "ssh://root@10.0.0.5"} {
+		_, err = ds.CreateClient2(target, "test")
+		assert.True(t, errors.Is(err, ErrTransportNotAllowed), target)
+	}
+
+	_, err = ds.CreateClient2("10.0.0.6", "test")
+	assert.Equal(t, repository.ErrNoCredentials, err, "a plain address still needs the credentials of the test")
+}
+
+func TestDockerService_TransportFingerprint(t *testing.T) {
+	dir, err := ioutil.TempDir("", "endpoint")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	endpoint := config.Endpoint{
+		Host:   "tcp://10.0.0.4:2376",
+		CACert: filepath.Join(dir, "ca.pem"),
+		Cert:   filepath.Join(dir, "cert.pem"),
+		Key:    filepath.Join(dir, "key.pem"),
+	}
+	ds := dockerService{conf: config.Docker{Endpoints: map[string]config.Endpoint{"daemon": endpoint}}}
+
+	_, err = ds.endpointClient(endpoint)
+	assert.Error(t, err)
+	assert.Equal(t, "", ds.transportFingerprint("daemon", "test"))
+
+	for _, file := range []string{endpoint.CACert, endpoint.Cert, endpoint.Key} {
+		require.NoError(t, ioutil.WriteFile(file, []byte(file), 0600))
+	}
+	first := ds.transportFingerprint("daemon", "test")
+	assert.NotEqual(t, "", first)
+	assert.Equal(t, first, ds.transportFingerprint("daemon", "other"))
+
+	require.NoError(t, ioutil.WriteFile(endpoint.Key, []byte("rotated"), 0600))
+	assert.NotEqual(t, first, ds.transportFingerprint("daemon", "test"))
+	assert.Equal(t, "", ds.transportFingerprint("ssh://genesis@10.0.0.5", "test"))
+}
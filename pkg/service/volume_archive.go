@@ -0,0 +1,186 @@
+/**
+ * Copyright 2019 Whiteblock Inc. All rights reserved.
+ * Use of this source code is governed by a BSD-style
+ * license that can be found in the LICENSE file.
+ */
+
+package service
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/whiteblock/genesis/pkg/entity"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/pkg/stdcopy"
+	"github.com/sirupsen/logrus"
+	"github.com/whiteblock/definition/command"
+)
+
+const (
+	// volumeHelperMount is where the volume helper mounts the volume
+	volumeHelperMount = "/volume"
+
+	// volumeHelperDir is the directory of the volume helper which holds the archive
+	volumeHelperDir = "/tmp"
+
+	// exportArchive is the name of the archive the volume helper makes of the volume
+	exportArchive = "volume.tar.gz"
+)
+
+// volumeHelperName gives the name of the short lived container which moves archives in and out
+// of the volume of the test. Tests may share hosts and volume names, so it is named after both.
+func volumeHelperName(testID string, vol string) string {
+	if len(testID) == 0 {
+		return "volume-helper-" + vol
+	}
+	return "volume-helper-" + testID + "-" + vol
+}
+
+// isGzip gives whether the archive is gzipped, going by its name
+func isGzip(name string) bool {
+	return strings.HasSuffix(name, ".gz") || strings.HasSuffix(name, ".tgz")
+}
+
+// runVolumeHelper runs a short lived container with the volume mounted until it exits. Before it
+// starts, prepare is given the chance to copy an archive into it, and after it exits, collect
+// is given the chance to copy an archive out of it.
+func (ds dockerService) runVolumeHelper(ctx context.Context, cli entity.DockerCli, vol string,
+	cmd []string, prepare func(name string) error, collect func(name string) error) error {
+
+	name := volumeHelperName(cli.TestID, vol)
+	ds.withFields(cli, logrus.Fields{
+		"name":   name,
+		"volume": vol,
+		"cmd":    strings.Join(cmd, " "),
+	}).Debug("running a volume helper")
+
+	err := ds.repo.EnsureImagePulled(ctx, cli, ds.conf.VolumeHelperImage, command.Credentials{})
+	if err != nil {
+		return err
+	}
+	err = ds.removeSideCar(ctx, cli, name) // left over from a run which was cut short
+	if err != nil {
+		return err
+	}
+	labels := map[string]string{entity.HelperLabel: "true"}
+	for key, val := range cli.Labels {
+		labels[key] = val
+	}
+	engine := engineOf(cli.Client)
+	err = engine.CreateContainer(ctx, entity.ContainerSpec{
+		Name:       name,
+		Image:      ds.conf.VolumeHelperImage,
+		Entrypoint: cmd,
+		Labels:     labels,
+		Mounts: []entity.MountSpec{{
+			Type:   entity.VolumeMountType,
+			Source: vol,
+			Target: volumeHelperMount,
+		}},
+	})
+	if err != nil {
+		return err
+	}
+	defer func() {
+		err := engine.RemoveContainer(ctx, name, false)
+		if err != nil {
+			ds.withFields(cli, logrus.Fields{"name": name, "error": err}).Warn("failed to remove a volume helper")
+		}
+	}()
+
+	if prepare != nil {
+		err = prepare(name)
+		if err != nil {
+			return err
+		}
+	}
+	err = engine.StartContainer(ctx, name)
+	if err != nil {
+		return err
+	}
+
+	var exitCode int64
+	resChan, errChan := cli.ContainerWait(ctx, name, container.WaitConditionNotRunning)
+	select {
+	case res := <-resChan:
+		exitCode = res.StatusCode
+	case err := <-errChan:
+		return err
+	}
+	if exitCode != 0 {
+		var stdout, stderr bytes.Buffer
+		rdr, err := cli.ContainerLogs(ctx, name, types.ContainerLogsOptions{ShowStderr: true})
+		if err == nil {
+			stdcopy.StdCopy(&stdout, &stderr, rdr)
+			rdr.Close()
+		}
+		return fmt.Errorf("volume helper \"%s\" exited with %d: %s",
+			name, exitCode, strings.TrimSpace(stderr.String()))
+	}
+	if collect == nil {
+		return nil
+	}
+	return collect(name)
+}
+
+// seedVolume extracts the archive from the file source into the volume
+func (ds dockerService) seedVolume(ctx context.Context, cli entity.DockerCli, vol string, seed command.File) error {
+	archive := path.Base(seed.Meta.Filename)
+	if len(seed.Meta.Filename) == 0 {
+		archive = path.Base(seed.ID)
+	}
+	seed.Destination = path.Join(volumeHelperDir, archive)
+	seed.Meta.Filename = archive
+
+	rdr, err := ds.remote.StreamTar(cli.Labels[command.DefinitionIDKey], seed)
+	if err != nil {
+		return err
+	}
+	defer rdr.Close()
+	flags := "-xf"
+	if isGzip(archive) {
+		flags = "-xzf"
+	}
+	return ds.runVolumeHelper(ctx, cli, vol, []string{"tar", flags, seed.Destination, "-C", volumeHelperMount},
+		func(name string) error {
+			return ds.copyToContainer(ctx, cli, name, rdr, archive, seed.Destination)
+		}, nil)
+}
+
+// ExportVolume archives the volume as a gzipped tar, and uploads it to the file source
+func (ds dockerService) ExportVolume(ctx context.Context, cli entity.DockerCli,
+	export entity.VolumeExport) entity.Result {
+
+	archive := path.Join(volumeHelperDir, exportArchive)
+	var size int64
+	err := ds.runVolumeHelper(ctx, cli, export.Name, []string{"tar", "-czf", archive, "-C", volumeHelperMount, "."},
+		nil, func(name string) error {
+			rdr, _, err := cli.CopyFromContainer(ctx, name, archive)
+			if err != nil {
+				return err
+			}
+			defer rdr.Close()
+			tr := tar.NewReader(rdr)
+			hdr, err := tr.Next()
+			if err != nil {
+				return err
+			}
+			size = hdr.Size
+			return ds.remote.PutFile(cli.Labels[command.DefinitionIDKey], export.File, tr)
+		})
+	if err != nil {
+		return entity.NewErrorResult(err).InjectMeta(map[string]interface{}{"volume": export.Name})
+	}
+	return entity.NewSuccessResult().InjectMeta(map[string]interface{}{
+		"volume": export.Name,
+		"file":   export.File.ID,
+		"bytes":  size,
+	})
+}
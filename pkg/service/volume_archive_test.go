@@ -0,0 +1,125 @@
+/**
+ * Copyright 2019 Whiteblock Inc. All rights reserved.
+ * Use of this source code is governed by a BSD-style
+ * license that can be found in the LICENSE file.
+ */
+
+package service
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/whiteblock/genesis/pkg/entity"
+	"github.com/whiteblock/genesis/pkg/fake"
+
+	"github.com/docker/docker/api/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/whiteblock/definition/command"
+	"github.com/whiteblock/utility/common"
+)
+
+// volumeHelpers records the commands the volume helpers of the engines were run with, by host
+func volumeHelpers(engines map[string]*fake.Engine, proc fake.Process) map[string][][]string {
+	mux := &sync.Mutex{}
+	ran := map[string][][]string{}
+	for host, engine := range engines {
+		host := host
+		engine.OnRun("alpine", func(name string, cmd []string) (int, []byte) {
+			mux.Lock()
+			ran[host] = append(ran[host], cmd)
+			mux.Unlock()
+			return proc(name, cmd)
+		})
+	}
+	return ran
+}
+
+func TestDockerService_SeedVolume(t *testing.T) {
+	hosts := []string{"10.0.0.2", "10.0.0.3"}
+	ds, engines := sharedVolumeHosts(t, entity.RsyncVolumeBackend, hosts...)
+	ds.conf.VolumeHelperImage = "alpine"
+	ds.remote = testRemoteSources{data: []byte("snapshot")}
+	cli := entity.DockerCli{Client: engines["10.0.0.2"], TestID: "test", IP: "10.0.0.2",
+		Labels: map[string]string{command.DefinitionIDKey: "def"}}
+
+	statsContainer(t, engines["10.0.0.2"], volumeHelperName("other", "data"), "other", 0)
+	var archive []byte
+	var helper string
+	var labels map[string]string
+	ran := volumeHelpers(engines, func(name string, cmd []string) (int, []byte) {
+		archive, _ = engines["10.0.0.2"].File(name, "/tmp/chain.tar.gz")
+		helper = name
+		if info, err := engines["10.0.0.2"].ContainerInspect(context.Background(), name); err == nil {
+			labels = info.Config.Labels
+		}
+		return 0, nil
+	})
+	seed := &command.File{ID: "snapshot-id", Meta: common.Metadata{Filename: "chain.tar.gz"}}
+	res := ds.CreateVolume(context.Background(), cli, entity.Volume{Volume: command.Volume{Name: "data"}, Seed: seed})
+	require.NoError(t, res.Error)
+	assert.Equal(t, [][]string{{"tar", "-xzf", "/tmp/chain.tar.gz", "-C", "/volume"}}, ran["10.0.0.2"])
+	assert.Equal(t, []byte("snapshot"), archive)
+	assert.True(t, hasVolume(t, engines["10.0.0.2"], "data"))
+	assert.Equal(t, "volume-helper-test-data", helper)
+	assert.Equal(t, map[string]string{command.DefinitionIDKey: "def", entity.HelperLabel: "true"}, labels)
+	_, err := engines["10.0.0.2"].ContainerInspect(context.Background(), volumeHelperName("test", "data"))
+	assert.Error(t, err, "the volume helper is removed once it is done")
+	_, err = engines["10.0.0.2"].ContainerInspect(context.Background(), volumeHelperName("other", "data"))
+	assert.NoError(t, err, "the volume helper of another test is left alone")
+
+	res = ds.VolumeShare(context.Background(), cli, entity.VolumeShare{VolumeShare: command.VolumeShare{Hosts: hosts}})
+	require.NoError(t, res.Error)
+	res = ds.CreateVolume(context.Background(), cli, entity.Volume{Volume: command.Volume{Name: "shared",
+		Global: true, Hosts: hosts}, Seed: &command.File{ID: "snapshot-id"}})
+	require.NoError(t, res.Error)
+	assert.Contains(t, ran["10.0.0.2"], []string{"tar", "-xf", "/tmp/snapshot-id", "-C", "/volume"})
+	assert.Empty(t, ran["10.0.0.3"], "only the first host is seeded")
+	pull := []string{"rsync", "-a", "--delete", "rsync://10.0.0.2:8873/shared/",
+		"/volumes/shared/_data/"}
+	pulls := 0
+	for _, exec := range engines["10.0.0.3"].Execs(RsyncContainerName) {
+		if assert.ObjectsAreEqual(pull, exec) {
+			pulls++
+		}
+	}
+	assert.Equal(t, 2, pulls, "the seed is copied to the other hosts")
+
+	volumeHelpers(engines, func(string, []string) (int, []byte) { return 2, nil })
+	res = ds.CreateVolume(context.Background(), cli, entity.Volume{Volume: command.Volume{Name: "data"}, Seed: seed})
+	assert.Error(t, res.Error)
+}
+
+func TestDockerService_ExportVolume(t *testing.T) {
+	ds, engines := sharedVolumeHosts(t, entity.GlusterVolumeBackend, "10.0.0.2")
+	ds.conf.VolumeHelperImage = "alpine"
+	uploads := map[string][]byte{}
+	ds.remote = testRemoteSources{uploads: uploads}
+	engine := engines["10.0.0.2"]
+	cli := entity.DockerCli{Client: engine, TestID: "test", IP: "10.0.0.2",
+		Labels: map[string]string{command.DefinitionIDKey: "def"}}
+
+	ran := volumeHelpers(engines, func(name string, cmd []string) (int, []byte) {
+		buf := new(bytes.Buffer)
+		tw := tar.NewWriter(buf)
+		tw.WriteHeader(&tar.Header{Name: "volume.tar.gz", Mode: 0644, Size: 7})
+		tw.Write([]byte("archive"))
+		tw.Close()
+		err := engine.CopyToContainer(context.Background(), name, "/tmp", buf, types.CopyToContainerOptions{})
+		if err != nil {
+			return 1, nil
+		}
+		return 0, nil
+	})
+	res := ds.ExportVolume(context.Background(), cli, entity.VolumeExport{Name: "data",
+		File: command.File{ID: "export-id"}})
+	require.NoError(t, res.Error)
+	assert.Equal(t, [][]string{{"tar", "-czf", "/tmp/volume.tar.gz", "-C", "/volume", "."}}, ran["10.0.0.2"])
+	assert.Equal(t, []byte("archive"), uploads["export-id"])
+	assert.Equal(t, int64(7), res.Meta["bytes"])
+	assert.Equal(t, "export-id", res.Meta["file"])
+}
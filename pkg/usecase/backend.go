@@ -0,0 +1,64 @@
+/**
+ * Copyright 2019 Whiteblock Inc. All rights reserved.
+ * Use of this source code is governed by a BSD-style
+ * license that can be found in the LICENSE file.
+ */
+
+package usecase
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/whiteblock/genesis/pkg/entity"
+
+	"github.com/sirupsen/logrus"
+	"github.com/whiteblock/definition/command"
+)
+
+type backendUseCase struct {
+	fallback string
+	backends map[string]DockerUseCase
+	log      logrus.Ext1FieldLogger
+}
+
+// NewBackendUseCase creates a DockerUseCase which sends each command to the backend given in
+// its meta, or to the fallback backend if the command does not choose one
+func NewBackendUseCase(
+	fallback string,
+	backends map[string]DockerUseCase,
+	log logrus.Ext1FieldLogger) DockerUseCase {
+	return &backendUseCase{fallback: fallback, backends: backends, log: log}
+}
+
+func (buc backendUseCase) backend(cmd command.Command) (DockerUseCase, entity.Result, bool) {
+	name := buc.fallback
+	if chosen, ok := cmd.Meta[entity.BackendKey]; ok && len(chosen) > 0 {
+		name = chosen
+	}
+	backend, ok := buc.backends[name]
+	if !ok {
+		return nil, entity.NewFatalResult(fmt.Sprintf("unknown backend \"%s\"", name)).InjectMeta(
+			map[string]interface{}{entity.BackendKey: name}), false
+	}
+	buc.log.WithFields(logrus.Fields{"command": cmd.ID, "backend": name}).Trace("chose a backend")
+	return backend, entity.Result{}, true
+}
+
+// Run runs the command on its backend
+func (buc backendUseCase) Run(ctx context.Context, cmd command.Command) entity.Result {
+	backend, res, ok := buc.backend(cmd)
+	if !ok {
+		return res
+	}
+	return backend.Run(ctx, cmd)
+}
+
+// Execute executes the command on its backend
+func (buc backendUseCase) Execute(ctx context.Context, cmd command.Command) entity.Result {
+	backend, res, ok := buc.backend(cmd)
+	if !ok {
+		return res
+	}
+	return backend.Execute(ctx, cmd)
+}
@@ -0,0 +1,88 @@
+/**
+ * Copyright 2019 Whiteblock Inc. All rights reserved.
+ * Use of this source code is governed by a BSD-style
+ * license that can be found in the LICENSE file.
+ */
+
+package usecase
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	mockService "github.com/whiteblock/genesis/mocks/pkg/service"
+	mockUseCase "github.com/whiteblock/genesis/mocks/pkg/usecase"
+	"github.com/whiteblock/genesis/pkg/entity"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/whiteblock/definition/command"
+)
+
+func TestBackendUseCase(t *testing.T) {
+	docker := new(mockUseCase.DockerUseCase)
+	docker.On("Run", mock.Anything, mock.Anything).Return(entity.NewSuccessResult()).Once()
+	kube := new(mockUseCase.DockerUseCase)
+	kube.On("Execute", mock.Anything, mock.Anything).Return(entity.NewSuccessResult()).Once()
+
+	buc := NewBackendUseCase(entity.DockerBackend, map[string]DockerUseCase{
+		entity.DockerBackend:     docker,
+		entity.KubernetesBackend: kube,
+	}, logrus.New())
+
+	res := buc.Run(context.Background(), command.Command{})
+	assert.NoError(t, res.Error)
+
+	res = buc.Execute(context.Background(), command.Command{
+		Meta: map[string]string{entity.BackendKey: entity.KubernetesBackend},
+	})
+	assert.NoError(t, res.Error)
+
+	res = buc.Run(context.Background(), command.Command{
+		Meta: map[string]string{entity.BackendKey: "lxc"},
+	})
+	assert.True(t, res.IsFatal())
+
+	docker.AssertExpectations(t)
+	kube.AssertExpectations(t)
+}
+
+func TestKubernetesUseCase_Unsupported(t *testing.T) {
+	kuc := NewKubernetesUseCase(nil, "genesis", logrus.New())
+	res := kuc.Execute(context.Background(), command.Command{
+		Order: command.Order{Type: command.SwarmInit},
+	})
+	assert.True(t, res.IsFatal())
+}
+
+func TestKubernetesUseCase_PauseAndResume(t *testing.T) {
+	serv := new(mockService.KubernetesService)
+	serv.On("RemoveContainer", mock.Anything, mock.Anything, "task0").Return(entity.NewSuccessResult()).Once()
+	buc := NewBackendUseCase(entity.DockerBackend, map[string]DockerUseCase{
+		entity.KubernetesBackend: NewKubernetesUseCase(serv, "genesis", logrus.New()),
+	}, logrus.New())
+	kube := map[string]string{entity.BackendKey: entity.KubernetesBackend}
+
+	res := buc.Run(context.Background(), command.Command{Meta: kube,
+		Order: command.Order{Type: command.Pauseexecution, Payload: "5s"}})
+	assert.NoError(t, res.Error)
+	assert.True(t, res.IsDelayed())
+	assert.Equal(t, 5*time.Second, res.Delay)
+
+	res = buc.Run(context.Background(), command.Command{Meta: kube,
+		Order: command.Order{Type: command.Pauseexecution, Payload: command.InfiniteTimeTerm}})
+	assert.True(t, res.IsTrap())
+
+	res = buc.Run(context.Background(), command.Command{Meta: kube,
+		Order: command.Order{Type: command.Resumeexecution, Payload: map[string]interface{}{
+			"tasks": []string{"task0"}}}})
+	assert.NoError(t, res.Error)
+
+	res = buc.Run(context.Background(), command.Command{Meta: kube,
+		Order: command.Order{Type: command.Pullimage, Payload: map[string]interface{}{"image": "alpine"}}})
+	assert.NoError(t, res.Error)
+
+	serv.AssertExpectations(t)
+}
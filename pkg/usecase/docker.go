@@ -47,6 +47,9 @@ var (
 	// ErrEmptyFieldNetwork missing network field
 	ErrEmptyFieldNetwork = entity.NewFatalResult("empty field \"network\"")
 
+	// ErrEmptyFieldLinks missing links field
+	ErrEmptyFieldLinks = entity.NewFatalResult("empty field \"links\"")
+
 	// ErrInvalidTargetIP target IP is not a dest IP or is malformed
 	ErrInvalidTargetIP = entity.NewFatalResult("invalid target ip")
 
@@ -175,16 +178,66 @@ func (duc dockerUseCase) Execute(ctx context.Context, cmd command.Command) entit
 		return duc.putFileInContainerShim(ctx, cli, cmd)
 	case command.Emulation:
 		return duc.emulationShim(ctx, cli, cmd)
+	case entity.Linkemulation:
+		return duc.linkEmulationShim(ctx, cli, cmd)
+	case entity.Changeemulation:
+		return duc.changeEmulationShim(ctx, cli, cmd)
+	case entity.Removeemulation:
+		return duc.removeEmulationShim(ctx, cli, cmd)
+	case entity.Showemulation:
+		return duc.showEmulationShim(ctx, cli, cmd)
+	case entity.Scheduleemulation:
+		return duc.scheduleEmulationShim(ctx, cli, cmd)
+	case entity.Cancelemulationschedule:
+		return duc.cancelEmulationScheduleShim(ctx, cli, cmd)
+	case entity.Partitionnetwork:
+		return duc.partitionShim(ctx, cli, cmd, false)
+	case entity.Healpartition:
+		return duc.partitionShim(ctx, cli, cmd, true)
+	case entity.Pausecontainer:
+		return duc.pauseContainerShim(ctx, cli, cmd, false)
+	case entity.Unpausecontainer:
+		return duc.pauseContainerShim(ctx, cli, cmd, true)
+	case entity.Killcontainer:
+		return duc.killContainerShim(ctx, cli, cmd)
+	case entity.Restartcontainer:
+		return duc.restartContainerShim(ctx, cli, cmd)
+	case entity.Updatecontainer:
+		return duc.updateContainerShim(ctx, cli, cmd)
+	case entity.Stresscontainer:
+		return duc.stressContainerShim(ctx, cli, cmd)
+	case entity.Createskewedcontainer:
+		return duc.createSkewedContainerShim(ctx, cli, cmd)
+	case entity.Changeclock:
+		return duc.changeClockShim(ctx, cli, cmd)
 	case command.SwarmInit:
 		res := duc.swarmSetupShim(ctx, cli, cmd)
 		if !res.IsSuccess() {
 			duc.diagnoseConnIssue(ctx, cli, cmd)
 		}
 		return res
+	case entity.Leaveswarm:
+		return duc.swarmLeaveShim(ctx, cli, cmd)
+	case entity.Createservice:
+		return duc.createServiceShim(ctx, cli, cmd, false)
+	case entity.Updateservice:
+		return duc.createServiceShim(ctx, cli, cmd, true)
+	case entity.Scaleservice:
+		return duc.scaleServiceShim(ctx, cli, cmd)
+	case entity.Removeservice:
+		return duc.removeServiceShim(ctx, cli, cmd)
 	case command.Pullimage:
 		return duc.pullImageShim(ctx, cli, cmd)
 	case command.Volumeshare:
 		return duc.volumeShareShim(ctx, cli, cmd)
+	case entity.Removevolumeshare:
+		return duc.removeVolumeShareShim(ctx, cli, cmd)
+	case entity.Resizevolume:
+		return duc.resizeVolumeShim(ctx, cli, cmd)
+	case entity.Volumestatus:
+		return duc.volumeStatusShim(ctx, cli, cmd)
+	case entity.Exportvolume:
+		return duc.exportVolumeShim(ctx, cli, cmd)
 	case command.Pauseexecution:
 		return duc.pauseExecutionShim(ctx, cli, cmd)
 	case command.Resumeexecution:
@@ -206,36 +259,113 @@ func (duc dockerUseCase) validationCheck(cmd command.Command) (result entity.Res
 }
 
 func (duc dockerUseCase) injectLabels(cli entity.Client, cmd command.Command) entity.DockerCli {
-	out := entity.DockerCli{Client: cli, Labels: map[string]string{}, TestID: cmd.TestID()}
+	out := entity.DockerCli{Client: cli, Labels: map[string]string{}, TestID: cmd.TestID(), IP: cmd.Target.IP}
 	duc.withField(cmd, "meta", cmd.Meta).Trace("got the meta from the command")
 	mergo.Map(&out.Labels, cmd.Meta)
 	return out
 }
 
+// injectContainerLabels is injectLabels, plus the labels which go on the container
+func (duc dockerUseCase) injectContainerLabels(cli entity.Client, cmd command.Command,
+	container command.Container) (entity.DockerCli, error) {
+
+	docker := duc.injectLabels(cli, cmd)
+	err := mergo.Map(&docker.Labels, container.Labels)
+	if err != nil {
+		return docker, err
+	}
+
+	docker.Labels["name"] = container.Name
+	duc.withField(cmd, "labels", docker.Labels).Trace("got the labels for the container")
+	return docker, nil
+}
+
 func (duc dockerUseCase) createContainerShim(ctx context.Context, cli entity.Client,
 	cmd command.Command) entity.Result {
 
-	var container command.Container
+	var container entity.Container
 	err := cmd.ParseOrderPayloadInto(&container)
 	if err != nil {
 		return entity.NewFatalResult(err)
 	}
-	err = validator.Container(container)
+	err = validator.Container(container.Container)
 	if err != nil {
 		return entity.NewFatalResult(err)
 	}
-
-	docker := duc.injectLabels(cli, cmd)
-	err = mergo.Map(&docker.Labels, container.Labels)
+	err = validator.IPv6(container.IPv6)
+	if err != nil {
+		return entity.NewFatalResult(err)
+	}
+	err = validator.Endpoint(container.Endpoint)
+	if err != nil {
+		return entity.NewFatalResult(err)
+	}
+	err = validator.NetworkAttachments(container)
 	if err != nil {
 		return entity.NewFatalResult(err)
 	}
 
-	docker.Labels["name"] = container.Name
-	duc.withField(cmd, "labels", docker.Labels).Trace("got the labels for the container")
+	docker, err := duc.injectContainerLabels(cli, cmd, container.Container)
+	if err != nil {
+		return entity.NewFatalResult(err)
+	}
 	return duc.service.CreateContainer(ctx, docker, container)
 }
 
+func (duc dockerUseCase) createSkewedContainerShim(ctx context.Context, cli entity.Client,
+	cmd command.Command) entity.Result {
+
+	var payload entity.SkewedContainer
+	err := cmd.ParseOrderPayloadInto(&payload)
+	if err != nil {
+		return entity.NewFatalResult(err)
+	}
+	err = validator.Container(payload.Container.Container)
+	if err != nil {
+		return entity.NewFatalResult(err)
+	}
+	err = validator.IPv6(payload.IPv6)
+	if err != nil {
+		return entity.NewFatalResult(err)
+	}
+	err = validator.Endpoint(payload.Endpoint)
+	if err != nil {
+		return entity.NewFatalResult(err)
+	}
+	err = validator.NetworkAttachments(payload.Container)
+	if err != nil {
+		return entity.NewFatalResult(err)
+	}
+	err = validator.ClockSkew(payload.Clock)
+	if err != nil {
+		return entity.NewFatalResult(err)
+	}
+
+	docker, err := duc.injectContainerLabels(cli, cmd, payload.Container.Container)
+	if err != nil {
+		return entity.NewFatalResult(err)
+	}
+	return duc.service.CreateSkewedContainer(ctx, docker, payload)
+}
+
+func (duc dockerUseCase) changeClockShim(ctx context.Context, cli entity.Client,
+	cmd command.Command) entity.Result {
+
+	var payload entity.ContainerClock
+	err := cmd.ParseOrderPayloadInto(&payload)
+	if err != nil {
+		return entity.NewFatalResult(err)
+	}
+	if len(payload.Container) == 0 {
+		return ErrEmptyFieldContainer
+	}
+	err = validator.ClockSkew(payload.Clock)
+	if err != nil {
+		return entity.NewFatalResult(err)
+	}
+	return duc.service.ChangeClock(ctx, duc.injectLabels(cli, cmd), payload)
+}
+
 func (duc dockerUseCase) startContainerShim(ctx context.Context, cli entity.Client,
 	cmd command.Command) entity.Result {
 
@@ -266,11 +396,15 @@ func (duc dockerUseCase) removeContainerShim(ctx context.Context, cli entity.Cli
 
 func (duc dockerUseCase) createNetworkShim(ctx context.Context, cli entity.Client,
 	cmd command.Command) entity.Result {
-	var net command.Network
+	var net entity.Network
 	err := cmd.ParseOrderPayloadInto(&net)
 	if err != nil {
 		return entity.NewFatalResult(err)
 	}
+	err = validator.Network(net)
+	if err != nil {
+		return entity.NewFatalResult(err)
+	}
 	docker := duc.injectLabels(cli, cmd)
 	err = mergo.Map(&docker.Labels, net.Labels)
 	if err != nil {
@@ -281,7 +415,7 @@ func (duc dockerUseCase) createNetworkShim(ctx context.Context, cli entity.Clien
 
 func (duc dockerUseCase) attachNetworkShim(ctx context.Context, cli entity.Client,
 	cmd command.Command) entity.Result {
-	var payload command.ContainerNetwork
+	var payload entity.ContainerNetwork
 	err := cmd.ParseOrderPayloadInto(&payload)
 	if err != nil {
 		return entity.NewErrorResult(err)
@@ -292,6 +426,14 @@ func (duc dockerUseCase) attachNetworkShim(ctx context.Context, cli entity.Clien
 	if len(payload.Network) == 0 {
 		return ErrEmptyFieldNetwork
 	}
+	err = validator.IPv6(payload.IPv6)
+	if err != nil {
+		return entity.NewFatalResult(err)
+	}
+	err = validator.Endpoint(payload.Endpoint)
+	if err != nil {
+		return entity.NewFatalResult(err)
+	}
 	return duc.service.AttachNetwork(ctx, duc.injectLabels(cli, cmd), payload)
 }
 
@@ -329,11 +471,15 @@ func (duc dockerUseCase) removeNetworkShim(ctx context.Context, cli entity.Clien
 func (duc dockerUseCase) createVolumeShim(ctx context.Context, cli entity.Client,
 	cmd command.Command) entity.Result {
 
-	var payload command.Volume
+	var payload entity.Volume
 	err := cmd.ParseOrderPayloadInto(&payload)
 	if err != nil {
 		return entity.NewFatalResult(err)
 	}
+	err = validator.Volume(payload)
+	if err != nil {
+		return entity.NewFatalResult(err)
+	}
 	docker := duc.injectLabels(cli, cmd)
 	err = mergo.Map(&docker.Labels, payload.Labels)
 	if err != nil {
@@ -345,7 +491,7 @@ func (duc dockerUseCase) createVolumeShim(ctx context.Context, cli entity.Client
 func (duc dockerUseCase) removeVolumeShim(ctx context.Context, cli entity.Client,
 	cmd command.Command) entity.Result {
 
-	var payload command.SimpleName
+	var payload entity.Volume
 	err := cmd.ParseOrderPayloadInto(&payload)
 	if err != nil {
 		return entity.NewFatalResult(err)
@@ -353,6 +499,13 @@ func (duc dockerUseCase) removeVolumeShim(ctx context.Context, cli entity.Client
 	if payload.Name == "" {
 		return ErrEmptyFieldName
 	}
+	err = validator.VolumeBackend(payload.Backend)
+	if err != nil {
+		return entity.NewFatalResult(err)
+	}
+	if payload.Global {
+		return duc.service.RemoveGlobalVolume(ctx, duc.injectLabels(cli, cmd), payload)
+	}
 	return duc.service.RemoveVolume(ctx, duc.injectLabels(cli, cmd), payload.Name)
 }
 func (duc dockerUseCase) putFileInContainerShim(ctx context.Context, cli entity.Client,
@@ -381,10 +534,224 @@ func (duc dockerUseCase) emulationShim(ctx context.Context, cli entity.Client,
 	return duc.service.Emulation(ctx, duc.injectLabels(cli, cmd), payload)
 }
 
+func (duc dockerUseCase) parseNetconf(cmd command.Command) (command.Netconf, entity.Result, bool) {
+	var payload command.Netconf
+	err := cmd.ParseOrderPayloadInto(&payload)
+	if err != nil {
+		return payload, entity.NewFatalResult(err), false
+	}
+	if len(payload.Container) == 0 {
+		return payload, ErrEmptyFieldContainer, false
+	}
+	if len(payload.Network) == 0 {
+		return payload, ErrEmptyFieldNetwork, false
+	}
+	return payload, entity.Result{}, true
+}
+
+func (duc dockerUseCase) changeEmulationShim(ctx context.Context, cli entity.Client,
+	cmd command.Command) entity.Result {
+
+	payload, res, ok := duc.parseNetconf(cmd)
+	if !ok {
+		return res
+	}
+	return duc.service.ChangeEmulation(ctx, duc.injectLabels(cli, cmd), payload)
+}
+
+func (duc dockerUseCase) removeEmulationShim(ctx context.Context, cli entity.Client,
+	cmd command.Command) entity.Result {
+
+	payload, res, ok := duc.parseNetconf(cmd)
+	if !ok {
+		return res
+	}
+	return duc.service.RemoveEmulation(ctx, duc.injectLabels(cli, cmd), payload)
+}
+
+func (duc dockerUseCase) showEmulationShim(ctx context.Context, cli entity.Client,
+	cmd command.Command) entity.Result {
+
+	var payload command.ContainerNetwork
+	err := cmd.ParseOrderPayloadInto(&payload)
+	if err != nil {
+		return entity.NewFatalResult(err)
+	}
+	if len(payload.Container) == 0 {
+		return ErrEmptyFieldContainer
+	}
+	return duc.service.ShowEmulation(ctx, duc.injectLabels(cli, cmd), payload)
+}
+
+func (duc dockerUseCase) linkEmulationShim(ctx context.Context, cli entity.Client,
+	cmd command.Command) entity.Result {
+
+	var payload entity.LinkEmulation
+	err := cmd.ParseOrderPayloadInto(&payload)
+	if err != nil {
+		return entity.NewFatalResult(err)
+	}
+	if len(payload.Container) == 0 {
+		return ErrEmptyFieldContainer
+	}
+	if len(payload.Network) == 0 {
+		return ErrEmptyFieldNetwork
+	}
+	if len(payload.Links) == 0 {
+		return ErrEmptyFieldLinks
+	}
+	for _, link := range payload.Links {
+		if len(link.Container) == 0 {
+			return ErrEmptyFieldContainer
+		}
+	}
+	return duc.service.LinkEmulation(ctx, duc.injectLabels(cli, cmd), payload)
+}
+
+func (duc dockerUseCase) scheduleEmulationShim(ctx context.Context, cli entity.Client,
+	cmd command.Command) entity.Result {
+
+	var payload entity.EmulationSchedule
+	err := cmd.ParseOrderPayloadInto(&payload)
+	if err != nil {
+		return entity.NewFatalResult(err)
+	}
+	if len(payload.Name) == 0 {
+		return ErrEmptyFieldName
+	}
+	if len(payload.Container) == 0 {
+		return ErrEmptyFieldContainer
+	}
+	if len(payload.Network) == 0 {
+		return ErrEmptyFieldNetwork
+	}
+	err = validator.EmulationSchedule(payload)
+	if err != nil {
+		return entity.NewFatalResult(err)
+	}
+	return duc.service.ScheduleEmulation(ctx, duc.injectLabels(cli, cmd), payload)
+}
+
+func (duc dockerUseCase) cancelEmulationScheduleShim(ctx context.Context, cli entity.Client,
+	cmd command.Command) entity.Result {
+
+	var payload command.SimpleName
+	err := cmd.ParseOrderPayloadInto(&payload)
+	if err != nil {
+		return entity.NewFatalResult(err)
+	}
+	if len(payload.Name) == 0 {
+		return ErrEmptyFieldName
+	}
+	return duc.service.CancelEmulationSchedule(ctx, duc.injectLabels(cli, cmd), payload.Name)
+}
+
+func (duc dockerUseCase) partitionShim(ctx context.Context, cli entity.Client,
+	cmd command.Command, heal bool) entity.Result {
+
+	var payload entity.Partition
+	err := cmd.ParseOrderPayloadInto(&payload)
+	if err != nil {
+		return entity.NewFatalResult(err)
+	}
+	if !heal && len(payload.Network) == 0 {
+		return ErrEmptyFieldNetwork
+	}
+	err = validator.Partition(payload, heal)
+	if err != nil {
+		return entity.NewFatalResult(err)
+	}
+	if heal {
+		return duc.service.HealPartition(ctx, duc.injectLabels(cli, cmd), payload)
+	}
+	return duc.service.Partition(ctx, duc.injectLabels(cli, cmd), payload)
+}
+
+func (duc dockerUseCase) pauseContainerShim(ctx context.Context, cli entity.Client,
+	cmd command.Command, unpause bool) entity.Result {
+
+	var payload command.SimpleName
+	err := cmd.ParseOrderPayloadInto(&payload)
+	if err != nil {
+		return entity.NewFatalResult(err)
+	}
+	if len(payload.Name) == 0 {
+		return ErrEmptyFieldName
+	}
+	if unpause {
+		return duc.service.UnpauseContainer(ctx, duc.injectLabels(cli, cmd), payload.Name)
+	}
+	return duc.service.PauseContainer(ctx, duc.injectLabels(cli, cmd), payload.Name)
+}
+
+func (duc dockerUseCase) killContainerShim(ctx context.Context, cli entity.Client,
+	cmd command.Command) entity.Result {
+
+	var payload entity.KillContainer
+	err := cmd.ParseOrderPayloadInto(&payload)
+	if err != nil {
+		return entity.NewFatalResult(err)
+	}
+	if len(payload.Name) == 0 {
+		return ErrEmptyFieldName
+	}
+	return duc.service.KillContainer(ctx, duc.injectLabels(cli, cmd), payload)
+}
+
+func (duc dockerUseCase) restartContainerShim(ctx context.Context, cli entity.Client,
+	cmd command.Command) entity.Result {
+
+	var payload entity.RestartContainer
+	err := cmd.ParseOrderPayloadInto(&payload)
+	if err != nil {
+		return entity.NewFatalResult(err)
+	}
+	if len(payload.Name) == 0 {
+		return ErrEmptyFieldName
+	}
+	if payload.Delay.IsInfinite() || payload.Timeout.IsInfinite() {
+		return entity.NewFatalResult("the delay and timeout of a restart must be finite")
+	}
+	return duc.service.RestartContainer(ctx, duc.injectLabels(cli, cmd), payload)
+}
+
+func (duc dockerUseCase) updateContainerShim(ctx context.Context, cli entity.Client,
+	cmd command.Command) entity.Result {
+
+	var payload entity.UpdateResources
+	err := cmd.ParseOrderPayloadInto(&payload)
+	if err != nil {
+		return entity.NewFatalResult(err)
+	}
+	err = validator.UpdateResources(payload)
+	if err != nil {
+		return entity.NewFatalResult(err)
+	}
+	return duc.service.UpdateContainer(ctx, duc.injectLabels(cli, cmd), payload)
+}
+
+func (duc dockerUseCase) stressContainerShim(ctx context.Context, cli entity.Client,
+	cmd command.Command) entity.Result {
+
+	var payload entity.Stress
+	err := cmd.ParseOrderPayloadInto(&payload)
+	if err != nil {
+		return entity.NewFatalResult(err)
+	}
+	if len(payload.Container) == 0 {
+		return ErrEmptyFieldContainer
+	}
+	err = validator.Stress(payload)
+	if err != nil {
+		return entity.NewFatalResult(err)
+	}
+	return duc.service.StressContainer(ctx, duc.injectLabels(cli, cmd), payload)
+}
+
 func (duc dockerUseCase) swarmSetupShim(ctx context.Context, cli entity.Client,
 	cmd command.Command) entity.Result {
 
-	var payload command.SetupSwarm
+	var payload entity.Swarm
 	err := cmd.ParseOrderPayloadInto(&payload)
 	if err != nil {
 		return entity.NewFatalResult(err)
@@ -392,9 +759,73 @@ func (duc dockerUseCase) swarmSetupShim(ctx context.Context, cli entity.Client,
 	if len(payload.Hosts) == 0 {
 		return ErrEmptyFieldHosts
 	}
+	err = validator.Swarm(payload)
+	if err != nil {
+		return entity.NewFatalResult(err)
+	}
 	return duc.service.SwarmCluster(ctx, duc.injectLabels(cli, cmd), payload)
 }
 
+func (duc dockerUseCase) swarmLeaveShim(ctx context.Context, cli entity.Client,
+	cmd command.Command) entity.Result {
+
+	var payload command.SetupSwarm
+	err := cmd.ParseOrderPayloadInto(&payload)
+	if err != nil {
+		return entity.NewFatalResult(err)
+	}
+	if len(payload.Hosts) == 0 {
+		return ErrEmptyFieldHosts
+	}
+	return duc.service.LeaveSwarm(ctx, duc.injectLabels(cli, cmd), payload)
+}
+
+func (duc dockerUseCase) createServiceShim(ctx context.Context, cli entity.Client,
+	cmd command.Command, update bool) entity.Result {
+
+	var payload entity.SwarmService
+	err := cmd.ParseOrderPayloadInto(&payload)
+	if err != nil {
+		return entity.NewFatalResult(err)
+	}
+	err = validator.SwarmService(payload)
+	if err != nil {
+		return entity.NewFatalResult(err)
+	}
+	if update {
+		return duc.service.UpdateService(ctx, duc.injectLabels(cli, cmd), payload)
+	}
+	return duc.service.CreateService(ctx, duc.injectLabels(cli, cmd), payload)
+}
+
+func (duc dockerUseCase) scaleServiceShim(ctx context.Context, cli entity.Client,
+	cmd command.Command) entity.Result {
+
+	var payload entity.ScaleService
+	err := cmd.ParseOrderPayloadInto(&payload)
+	if err != nil {
+		return entity.NewFatalResult(err)
+	}
+	if payload.Name == "" {
+		return ErrEmptyFieldName
+	}
+	return duc.service.ScaleService(ctx, duc.injectLabels(cli, cmd), payload)
+}
+
+func (duc dockerUseCase) removeServiceShim(ctx context.Context, cli entity.Client,
+	cmd command.Command) entity.Result {
+
+	var payload command.SimpleName
+	err := cmd.ParseOrderPayloadInto(&payload)
+	if err != nil {
+		return entity.NewFatalResult(err)
+	}
+	if payload.Name == "" {
+		return ErrEmptyFieldName
+	}
+	return duc.service.RemoveService(ctx, duc.injectLabels(cli, cmd), payload.Name)
+}
+
 func (duc dockerUseCase) pullImageShim(ctx context.Context, cli entity.Client,
 	cmd command.Command) entity.Result {
 
@@ -412,7 +843,7 @@ func (duc dockerUseCase) pullImageShim(ctx context.Context, cli entity.Client,
 func (duc dockerUseCase) volumeShareShim(ctx context.Context, cli entity.Client,
 	cmd command.Command) entity.Result {
 
-	var payload command.VolumeShare
+	var payload entity.VolumeShare
 	err := cmd.ParseOrderPayloadInto(&payload)
 	if err != nil {
 		return entity.NewFatalResult(err)
@@ -420,22 +851,97 @@ func (duc dockerUseCase) volumeShareShim(ctx context.Context, cli entity.Client,
 	if len(payload.Hosts) == 0 {
 		return ErrEmptyFieldHosts
 	}
+	err = validator.VolumeBackend(payload.Backend)
+	if err != nil {
+		return entity.NewFatalResult(err)
+	}
 	return duc.service.VolumeShare(ctx, duc.injectLabels(cli, cmd), payload)
 }
 
+func (duc dockerUseCase) removeVolumeShareShim(ctx context.Context, cli entity.Client,
+	cmd command.Command) entity.Result {
+
+	var payload entity.VolumeShare
+	err := cmd.ParseOrderPayloadInto(&payload)
+	if err != nil {
+		return entity.NewFatalResult(err)
+	}
+	if len(payload.Hosts) == 0 {
+		return ErrEmptyFieldHosts
+	}
+	err = validator.VolumeBackend(payload.Backend)
+	if err != nil {
+		return entity.NewFatalResult(err)
+	}
+	return duc.service.RemoveVolumeShare(ctx, duc.injectLabels(cli, cmd), payload)
+}
+
+func (duc dockerUseCase) resizeVolumeShim(ctx context.Context, cli entity.Client,
+	cmd command.Command) entity.Result {
+
+	var payload entity.VolumeResize
+	err := cmd.ParseOrderPayloadInto(&payload)
+	if err != nil {
+		return entity.NewFatalResult(err)
+	}
+	err = validator.VolumeResize(payload)
+	if err != nil {
+		return entity.NewFatalResult(err)
+	}
+	return duc.service.ResizeVolume(ctx, duc.injectLabels(cli, cmd), payload)
+}
+
+func (duc dockerUseCase) volumeStatusShim(ctx context.Context, cli entity.Client,
+	cmd command.Command) entity.Result {
+
+	var payload entity.Volume
+	err := cmd.ParseOrderPayloadInto(&payload)
+	if err != nil {
+		return entity.NewFatalResult(err)
+	}
+	if payload.Name == "" {
+		return ErrEmptyFieldName
+	}
+	err = validator.VolumeBackend(payload.Backend)
+	if err != nil {
+		return entity.NewFatalResult(err)
+	}
+	return duc.service.VolumeStatus(ctx, duc.injectLabels(cli, cmd), payload)
+}
+
+func (duc dockerUseCase) exportVolumeShim(ctx context.Context, cli entity.Client,
+	cmd command.Command) entity.Result {
+
+	var payload entity.VolumeExport
+	err := cmd.ParseOrderPayloadInto(&payload)
+	if err != nil {
+		return entity.NewFatalResult(err)
+	}
+	err = validator.VolumeExport(payload)
+	if err != nil {
+		return entity.NewFatalResult(err)
+	}
+	return duc.service.ExportVolume(ctx, duc.injectLabels(cli, cmd), payload)
+}
+
 func (duc dockerUseCase) pauseExecutionShim(ctx context.Context, cli entity.Client,
 	cmd command.Command) entity.Result {
+	return pauseExecution(duc.withField(cmd, "cmd", cmd), cmd)
+}
 
+// pauseExecution delays the next round of the test, or traps it if the pause is infinite. It
+// does not depend on the backend, since nothing is run for it.
+func pauseExecution(log logrus.Ext1FieldLogger, cmd command.Command) entity.Result {
 	var payload command.Duration
 	err := cmd.ParseOrderPayloadInto(&payload)
 	if err != nil {
 		return entity.NewFatalResult(err)
 	}
 	if payload.IsInfinite() {
-		duc.withField(cmd, "cmd", cmd).Info("trapping since pause is infinite")
+		log.Info("trapping since pause is infinite")
 		return entity.NewTrapResult()
 	}
-	duc.withField(cmd, "cmd", cmd).Info("pausing execution")
+	log.Info("pausing execution")
 	return entity.NewDelayResult(payload.Duration)
 }
 
@@ -10,6 +10,7 @@ import (
 	"context"
 	"fmt"
 	"testing"
+	"time"
 
 	mockService "github.com/whiteblock/genesis/mocks/pkg/service"
 	"github.com/whiteblock/genesis/pkg/entity"
@@ -543,7 +544,8 @@ func TestDockerUseCase_Execute_AttachNetwork_Success(t *testing.T) {
 			require.Len(t, args, 3)
 			assert.NotNil(t, args.Get(0))
 			assert.NotNil(t, args.Get(1))
-			assert.Equal(t, testCmd.Order.Payload, args.Get(2))
+			assert.Equal(t, entity.ContainerNetwork{ContainerNetwork: testCmd.Order.Payload.(command.ContainerNetwork)},
+				args.Get(2))
 		}).Once()
 
 	usecase := NewDockerUseCase(service, logrus.New())
@@ -837,3 +839,724 @@ func TestDockerUseCase_Execute_PutFileInContainer_NoName_Fail(t *testing.T) {
 	assert.Error(t, res.Error)
 	service.AssertExpectations(t)
 }
+
+func TestDockerUseCase_Execute_LinkEmulation(t *testing.T) {
+	service := new(mockService.DockerService)
+	service.On("CreateClient", mock.Anything, mock.Anything).Return(nil, nil).Once()
+	service.On("LinkEmulation", mock.Anything, mock.Anything, mock.Anything).Return(
+		entity.Result{Type: entity.SuccessType}).Run(func(args mock.Arguments) {
+
+		require.Len(t, args, 3)
+		le, ok := args.Get(2).(entity.LinkEmulation)
+		require.True(t, ok)
+		assert.Equal(t, "node-0", le.Container)
+		require.Len(t, le.Links, 2)
+		assert.Equal(t, "node-2", le.Links[1].Container)
+		assert.Equal(t, 100, le.Links[1].Delay)
+	}).Once()
+
+	usecase := NewDockerUseCase(service, logrus.New())
+
+	res := usecase.Execute(context.TODO(), command.Command{
+		ID:     "TEST",
+		Target: testTarget,
+		Order: command.Order{
+			Type: entity.Linkemulation,
+			Payload: entity.LinkEmulation{
+				Container: "node-0",
+				Network:   "testnet",
+				Links: []command.Netconf{
+					{Container: "node-1", Loss: 2},
+					{Container: "node-2", Delay: 100, Rate: "1mbit"},
+				},
+			},
+		},
+	})
+	assert.NoError(t, res.Error)
+	service.AssertExpectations(t)
+}
+
+func TestDockerUseCase_Execute_LinkEmulation_Failure(t *testing.T) {
+	var tests = []entity.LinkEmulation{
+		{Network: "testnet", Links: []command.Netconf{{Container: "node-1"}}},
+		{Container: "node-0", Links: []command.Netconf{{Container: "node-1"}}},
+		{Container: "node-0", Network: "testnet"},
+		{Container: "node-0", Network: "testnet", Links: []command.Netconf{{Delay: 100}}},
+	}
+
+	for i, tt := range tests {
+		t.Run(fmt.Sprint(i), func(t *testing.T) {
+			service := new(mockService.DockerService)
+			service.On("CreateClient", mock.Anything, mock.Anything).Return(nil, nil).Once()
+
+			usecase := NewDockerUseCase(service, logrus.New())
+
+			res := usecase.Execute(context.TODO(), command.Command{
+				ID:     "TEST",
+				Target: testTarget,
+				Order: command.Order{
+					Type:    entity.Linkemulation,
+					Payload: tt,
+				},
+			})
+			assert.True(t, res.IsFatal())
+			service.AssertExpectations(t)
+		})
+	}
+}
+
+func TestDockerUseCase_Execute_ChangeAndRemoveEmulation(t *testing.T) {
+	var tests = []struct {
+		orderType command.OrderType
+		method    string
+	}{
+		{orderType: entity.Changeemulation, method: "ChangeEmulation"},
+		{orderType: entity.Removeemulation, method: "RemoveEmulation"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.method, func(t *testing.T) {
+			service := new(mockService.DockerService)
+			service.On("CreateClient", mock.Anything, mock.Anything).Return(nil, nil).Twice()
+			service.On(tt.method, mock.Anything, mock.Anything, mock.Anything).Return(
+				entity.Result{Type: entity.SuccessType}).Once()
+
+			usecase := NewDockerUseCase(service, logrus.New())
+
+			res := usecase.Execute(context.TODO(), command.Command{
+				ID:     "TEST",
+				Target: testTarget,
+				Order: command.Order{
+					Type:    tt.orderType,
+					Payload: command.Netconf{Container: "node-0", Network: "testnet", Delay: 100},
+				},
+			})
+			assert.NoError(t, res.Error)
+
+			res = usecase.Execute(context.TODO(), command.Command{
+				ID:     "TEST",
+				Target: testTarget,
+				Order: command.Order{
+					Type:    tt.orderType,
+					Payload: command.Netconf{Container: "node-0"},
+				},
+			})
+			assert.True(t, res.IsFatal())
+			service.AssertExpectations(t)
+		})
+	}
+}
+
+func TestDockerUseCase_Execute_ShowEmulation(t *testing.T) {
+	service := new(mockService.DockerService)
+	service.On("CreateClient", mock.Anything, mock.Anything).Return(nil, nil).Twice()
+	service.On("ShowEmulation", mock.Anything, mock.Anything, command.ContainerNetwork{
+		Container: "node-0"}).Return(entity.Result{Type: entity.SuccessType}).Once()
+
+	usecase := NewDockerUseCase(service, logrus.New())
+
+	res := usecase.Execute(context.TODO(), command.Command{
+		ID:     "TEST",
+		Target: testTarget,
+		Order: command.Order{
+			Type:    entity.Showemulation,
+			Payload: command.ContainerNetwork{Container: "node-0"},
+		},
+	})
+	assert.NoError(t, res.Error)
+
+	res = usecase.Execute(context.TODO(), command.Command{
+		ID:     "TEST",
+		Target: testTarget,
+		Order: command.Order{
+			Type:    entity.Showemulation,
+			Payload: command.ContainerNetwork{Network: "testnet"},
+		},
+	})
+	assert.True(t, res.IsFatal())
+	service.AssertExpectations(t)
+}
+
+func TestDockerUseCase_Execute_ScheduleEmulation(t *testing.T) {
+	service := new(mockService.DockerService)
+	service.On("CreateClient", mock.Anything, mock.Anything).Return(nil, nil).Once()
+	service.On("ScheduleEmulation", mock.Anything, mock.MatchedBy(func(cli entity.DockerCli) bool {
+		return cli.IP == testTarget.IP
+	}), mock.Anything).Return(entity.Result{Type: entity.SuccessType}).Run(func(args mock.Arguments) {
+		sched := args.Get(2).(entity.EmulationSchedule)
+		require.Len(t, sched.Steps, 2)
+		assert.Equal(t, "node-0", sched.Container)
+		assert.Equal(t, 30*time.Second, sched.Steps[1].Offset.Duration)
+		assert.Equal(t, 100.0, sched.Steps[0].Netconf.Loss)
+		assert.Equal(t, time.Minute, sched.Restore.Duration)
+	}).Once()
+
+	usecase := NewDockerUseCase(service, logrus.New())
+
+	res := usecase.Execute(context.TODO(), command.Command{
+		ID:     "TEST",
+		Target: testTarget,
+		Order: command.Order{
+			Type: entity.Scheduleemulation,
+			Payload: map[string]interface{}{
+				"name":      "chaos",
+				"container": "node-0",
+				"network":   "testnet",
+				"steps": []interface{}{
+					map[string]interface{}{"offset": "0s", "netconf": map[string]interface{}{"loss": 100.0}},
+					map[string]interface{}{"offset": "30s", "netconf": map[string]interface{}{"delay": 100000}},
+				},
+				"restore": "1m",
+			},
+		},
+	})
+	assert.NoError(t, res.Error)
+	service.AssertExpectations(t)
+}
+
+func TestDockerUseCase_Execute_ScheduleEmulation_Failure(t *testing.T) {
+	step := map[string]interface{}{"offset": "30s", "netconf": map[string]interface{}{"loss": 100.0}}
+	tests := []map[string]interface{}{
+		{"container": "node-0", "network": "testnet", "steps": []interface{}{step}},
+		{"name": "chaos", "network": "testnet", "steps": []interface{}{step}},
+		{"name": "chaos", "container": "node-0", "steps": []interface{}{step}},
+		{"name": "chaos", "container": "node-0", "network": "testnet"},
+		{"name": "chaos", "container": "node-0", "network": "testnet", "steps": []interface{}{step},
+			"restore": "10s"},
+	}
+	missing := []entity.Result{ErrEmptyFieldName, ErrEmptyFieldContainer, ErrEmptyFieldNetwork}
+	for i, payload := range tests {
+		t.Run(fmt.Sprint(i), func(t *testing.T) {
+			service := new(mockService.DockerService)
+			service.On("CreateClient", mock.Anything, mock.Anything).Return(nil, nil).Once()
+
+			usecase := NewDockerUseCase(service, logrus.New())
+			res := usecase.Execute(context.TODO(), command.Command{
+				ID:     "TEST",
+				Target: testTarget,
+				Order: command.Order{
+					Type:    entity.Scheduleemulation,
+					Payload: payload,
+				},
+			})
+			assert.True(t, res.IsFatal())
+			if i < len(missing) {
+				assert.Equal(t, missing[i], res)
+			}
+			service.AssertExpectations(t)
+		})
+	}
+}
+
+func TestDockerUseCase_Execute_CancelEmulationSchedule(t *testing.T) {
+	service := new(mockService.DockerService)
+	service.On("CreateClient", mock.Anything, mock.Anything).Return(nil, nil).Twice()
+	service.On("CancelEmulationSchedule", mock.Anything, mock.Anything, "chaos").Return(
+		entity.Result{Type: entity.SuccessType}).Once()
+
+	usecase := NewDockerUseCase(service, logrus.New())
+
+	res := usecase.Execute(context.TODO(), command.Command{
+		ID:     "TEST",
+		Target: testTarget,
+		Order: command.Order{
+			Type:    entity.Cancelemulationschedule,
+			Payload: command.SimpleName{Name: "chaos"},
+		},
+	})
+	assert.NoError(t, res.Error)
+
+	res = usecase.Execute(context.TODO(), command.Command{
+		ID:     "TEST",
+		Target: testTarget,
+		Order: command.Order{
+			Type:    entity.Cancelemulationschedule,
+			Payload: command.SimpleName{},
+		},
+	})
+	assert.True(t, res.IsFatal())
+	service.AssertExpectations(t)
+}
+
+func TestDockerUseCase_Execute_Partition(t *testing.T) {
+	part := entity.Partition{Network: "testnet", Groups: [][]string{{"node-0", "node-1"}, {"node-2"}}}
+	service := new(mockService.DockerService)
+	service.On("CreateClient", mock.Anything, mock.Anything).Return(nil, nil).Times(4)
+	service.On("Partition", mock.Anything, mock.Anything, part).Return(
+		entity.Result{Type: entity.SuccessType}).Once()
+	service.On("HealPartition", mock.Anything, mock.Anything, entity.Partition{
+		Groups: [][]string{{"node-0"}}}).Return(entity.Result{Type: entity.SuccessType}).Once()
+
+	usecase := NewDockerUseCase(service, logrus.New())
+
+	res := usecase.Execute(context.TODO(), command.Command{
+		ID:     "TEST",
+		Target: testTarget,
+		Order:  command.Order{Type: entity.Partitionnetwork, Payload: part},
+	})
+	assert.NoError(t, res.Error)
+
+	res = usecase.Execute(context.TODO(), command.Command{
+		ID:     "TEST",
+		Target: testTarget,
+		Order: command.Order{
+			Type:    entity.Healpartition,
+			Payload: entity.Partition{Groups: [][]string{{"node-0"}}},
+		},
+	})
+	assert.NoError(t, res.Error)
+
+	res = usecase.Execute(context.TODO(), command.Command{
+		ID:     "TEST",
+		Target: testTarget,
+		Order: command.Order{
+			Type:    entity.Partitionnetwork,
+			Payload: entity.Partition{Network: "testnet", Groups: [][]string{{"node-0"}}},
+		},
+	})
+	assert.True(t, res.IsFatal())
+
+	res = usecase.Execute(context.TODO(), command.Command{
+		ID:     "TEST",
+		Target: testTarget,
+		Order:  command.Order{Type: entity.Partitionnetwork, Payload: entity.Partition{Groups: part.Groups}},
+	})
+	assert.Equal(t, ErrEmptyFieldNetwork, res)
+	service.AssertExpectations(t)
+}
+
+func TestDockerUseCase_Execute_Faults(t *testing.T) {
+	tests := []struct {
+		orderType command.OrderType
+		payload   interface{}
+		method    string
+		arg       interface{}
+	}{
+		{
+			orderType: entity.Pausecontainer,
+			payload:   command.SimpleName{Name: "node-0"},
+			method:    "PauseContainer",
+			arg:       "node-0",
+		},
+		{
+			orderType: entity.Unpausecontainer,
+			payload:   command.SimpleName{Name: "node-0"},
+			method:    "UnpauseContainer",
+			arg:       "node-0",
+		},
+		{
+			orderType: entity.Killcontainer,
+			payload:   entity.KillContainer{Name: "node-0", Signal: "SIGKILL"},
+			method:    "KillContainer",
+			arg:       entity.KillContainer{Name: "node-0", Signal: "SIGKILL"},
+		},
+		{
+			orderType: entity.Restartcontainer,
+			payload:   map[string]interface{}{"name": "node-0", "delay": "10s"},
+			method:    "RestartContainer",
+			arg: entity.RestartContainer{
+				Name:  "node-0",
+				Delay: command.Duration{Time: command.Time{Duration: 10 * time.Second}},
+			},
+		},
+		{
+			orderType: entity.Updatecontainer,
+			payload:   entity.UpdateResources{Name: "node-0", Cpus: "0.5"},
+			method:    "UpdateContainer",
+			arg:       entity.UpdateResources{Name: "node-0", Cpus: "0.5"},
+		},
+		{
+			orderType: entity.Stresscontainer,
+			payload:   map[string]interface{}{"container": "node-0", "cpu": 2, "duration": "30s"},
+			method:    "StressContainer",
+			arg: entity.Stress{
+				Container: "node-0",
+				CPU:       2,
+				Duration:  command.Duration{Time: command.Time{Duration: 30 * time.Second}},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(string(test.orderType), func(t *testing.T) {
+			service := new(mockService.DockerService)
+			service.On("CreateClient", mock.Anything, mock.Anything).Return(nil, nil).Twice()
+			service.On(test.method, mock.Anything, mock.Anything, test.arg).Return(
+				entity.Result{Type: entity.SuccessType}).Once()
+
+			usecase := NewDockerUseCase(service, logrus.New())
+			res := usecase.Execute(context.TODO(), command.Command{
+				ID:     "TEST",
+				Target: testTarget,
+				Order:  command.Order{Type: test.orderType, Payload: test.payload},
+			})
+			assert.NoError(t, res.Error)
+
+			res = usecase.Execute(context.TODO(), command.Command{
+				ID:     "TEST",
+				Target: testTarget,
+				Order:  command.Order{Type: test.orderType, Payload: map[string]interface{}{}},
+			})
+			assert.True(t, res.IsFatal())
+			service.AssertExpectations(t)
+		})
+	}
+}
+
+func TestDockerUseCase_Execute_CreateSkewedContainer(t *testing.T) {
+	service := new(mockService.DockerService)
+	service.On("CreateClient", mock.Anything, mock.Anything).Return(nil, nil).Twice()
+	service.On("CreateSkewedContainer", mock.Anything, mock.MatchedBy(func(cli entity.DockerCli) bool {
+		return cli.Labels["name"] == "node-0"
+	}), mock.Anything).Return(entity.Result{Type: entity.SuccessType}).Run(func(args mock.Arguments) {
+		sc := args.Get(2).(entity.SkewedContainer)
+		assert.Equal(t, "node-0", sc.Name)
+		assert.Equal(t, "alpine", sc.Image)
+		assert.Equal(t, -5*time.Minute, sc.Clock.Offset.Duration)
+	}).Once()
+
+	usecase := NewDockerUseCase(service, logrus.New())
+	res := usecase.Execute(context.TODO(), command.Command{
+		ID:     "TEST",
+		Target: testTarget,
+		Order: command.Order{
+			Type: entity.Createskewedcontainer,
+			Payload: map[string]interface{}{
+				"name":   "node-0",
+				"image":  "alpine",
+				"cpus":   "1",
+				"memory": "1GB",
+				"clock":  map[string]interface{}{"offset": "-5m"},
+			},
+		},
+	})
+	assert.NoError(t, res.Error)
+
+	res = usecase.Execute(context.TODO(), command.Command{
+		ID:     "TEST",
+		Target: testTarget,
+		Order: command.Order{
+			Type: entity.Createskewedcontainer,
+			Payload: map[string]interface{}{
+				"name":   "node-0",
+				"image":  "alpine",
+				"cpus":   "1",
+				"memory": "1GB",
+				"clock":  map[string]interface{}{"rate": -1},
+			},
+		},
+	})
+	assert.True(t, res.IsFatal())
+	service.AssertExpectations(t)
+}
+
+func TestDockerUseCase_Execute_ChangeClock(t *testing.T) {
+	service := new(mockService.DockerService)
+	service.On("CreateClient", mock.Anything, mock.Anything).Return(nil, nil).Twice()
+	service.On("ChangeClock", mock.Anything, mock.Anything, entity.ContainerClock{
+		Container: "node-0",
+		Clock:     entity.ClockSkew{Rate: 2},
+	}).Return(entity.Result{Type: entity.SuccessType}).Once()
+
+	usecase := NewDockerUseCase(service, logrus.New())
+	res := usecase.Execute(context.TODO(), command.Command{
+		ID:     "TEST",
+		Target: testTarget,
+		Order: command.Order{
+			Type:    entity.Changeclock,
+			Payload: entity.ContainerClock{Container: "node-0", Clock: entity.ClockSkew{Rate: 2}},
+		},
+	})
+	assert.NoError(t, res.Error)
+
+	res = usecase.Execute(context.TODO(), command.Command{
+		ID:     "TEST",
+		Target: testTarget,
+		Order: command.Order{
+			Type:    entity.Changeclock,
+			Payload: entity.ContainerClock{Clock: entity.ClockSkew{Rate: 2}},
+		},
+	})
+	assert.True(t, res.IsFatal())
+	service.AssertExpectations(t)
+}
+
+func TestDockerUseCase_Execute_CreateNetwork_DualStack(t *testing.T) {
+	service := new(mockService.DockerService)
+	service.On("CreateClient", mock.Anything, mock.Anything).Return(nil, nil).Twice()
+	service.On("CreateNetwork", mock.Anything, mock.Anything, entity.Network{
+		Network:    command.Network{Name: "testnet", Subnet: "10.1.0.0/16"},
+		EnableIPv6: true,
+		Subnets:    []entity.Subnet{{Subnet: "fd00::/64", Gateway: "fd00::1"}},
+	}).Return(entity.Result{Type: entity.SuccessType}).Once()
+
+	usecase := NewDockerUseCase(service, logrus.New())
+	res := usecase.Execute(context.TODO(), command.Command{
+		ID:     "TEST",
+		Target: testTarget,
+		Order: command.Order{
+			Type: command.Createnetwork,
+			Payload: map[string]interface{}{
+				"name":       "testnet",
+				"subnet":     "10.1.0.0/16",
+				"enableIPv6": true,
+				"subnets":    []interface{}{map[string]interface{}{"subnet": "fd00::/64", "gateway": "fd00::1"}},
+			},
+		},
+	})
+	assert.NoError(t, res.Error)
+
+	res = usecase.Execute(context.TODO(), command.Command{
+		ID:     "TEST",
+		Target: testTarget,
+		Order: command.Order{
+			Type: command.Createnetwork,
+			Payload: map[string]interface{}{
+				"name":    "testnet",
+				"subnets": []interface{}{map[string]interface{}{"subnet": "fd00::/64", "gateway": "fd01::1"}},
+			},
+		},
+	})
+	assert.True(t, res.IsFatal())
+	service.AssertExpectations(t)
+}
+
+func TestDockerUseCase_Execute_AttachNetwork_IPv6(t *testing.T) {
+	service := new(mockService.DockerService)
+	service.On("CreateClient", mock.Anything, mock.Anything).Return(nil, nil).Twice()
+	service.On("AttachNetwork", mock.Anything, mock.Anything, entity.ContainerNetwork{
+		ContainerNetwork: command.ContainerNetwork{Container: "tester", Network: "testnet"},
+		IPv6:             "fd00::2",
+	}).Return(entity.Result{Type: entity.SuccessType}).Once()
+
+	usecase := NewDockerUseCase(service, logrus.New())
+	res := usecase.Execute(context.TODO(), command.Command{
+		ID:     "TEST",
+		Target: testTarget,
+		Order: command.Order{
+			Type:    command.Attachnetwork,
+			Payload: map[string]interface{}{"container": "tester", "network": "testnet", "ipv6": "fd00::2"},
+		},
+	})
+	assert.NoError(t, res.Error)
+
+	res = usecase.Execute(context.TODO(), command.Command{
+		ID:     "TEST",
+		Target: testTarget,
+		Order: command.Order{
+			Type:    command.Attachnetwork,
+			Payload: map[string]interface{}{"container": "tester", "network": "testnet", "ipv6": "10.1.0.2"},
+		},
+	})
+	assert.True(t, res.IsFatal())
+	service.AssertExpectations(t)
+}
+
+func TestDockerUseCase_Execute_AttachNetwork_Endpoint(t *testing.T) {
+	service := new(mockService.DockerService)
+	service.On("CreateClient", mock.Anything, mock.Anything).Return(nil, nil).Twice()
+	service.On("AttachNetwork", mock.Anything, mock.Anything, entity.ContainerNetwork{
+		ContainerNetwork: command.ContainerNetwork{Container: "validator-3", Network: "net-a"},
+		Endpoint: entity.Endpoint{
+			Aliases:    []string{"validator-3.net-a"},
+			MacAddress: "02:42:ac:11:00:03",
+		},
+	}).Return(entity.Result{Type: entity.SuccessType}).Once()
+
+	usecase := NewDockerUseCase(service, logrus.New())
+	res := usecase.Execute(context.TODO(), command.Command{
+		ID:     "TEST",
+		Target: testTarget,
+		Order: command.Order{
+			Type: command.Attachnetwork,
+			Payload: map[string]interface{}{
+				"container":  "validator-3",
+				"network":    "net-a",
+				"aliases":    []string{"validator-3.net-a"},
+				"macAddress": "02:42:ac:11:00:03",
+			},
+		},
+	})
+	assert.NoError(t, res.Error)
+
+	res = usecase.Execute(context.TODO(), command.Command{
+		ID:     "TEST",
+		Target: testTarget,
+		Order: command.Order{
+			Type: command.Attachnetwork,
+			Payload: map[string]interface{}{
+				"container":  "validator-3",
+				"network":    "net-a",
+				"macAddress": "not a mac",
+			},
+		},
+	})
+	assert.True(t, res.IsFatal())
+	service.AssertExpectations(t)
+}
+
+func TestDockerUseCase_Execute_CreateContainer_Networks(t *testing.T) {
+	service := new(mockService.DockerService)
+	service.On("CreateClient", mock.Anything, mock.Anything).Return(nil, nil).Twice()
+	service.On("CreateContainer", mock.Anything, mock.Anything, mock.MatchedBy(func(cntr entity.Container) bool {
+		return assert.Equal(t, []entity.NetworkAttachment{
+			{Network: "net-a", IP: "10.0.0.2"},
+			{Network: "net-b", Endpoint: entity.Endpoint{Aliases: []string{"foo"}}},
+		}, cntr.Networks)
+	})).Return(entity.Result{Type: entity.SuccessType}).Once()
+
+	usecase := NewDockerUseCase(service, logrus.New())
+	payload := map[string]interface{}{
+		"name":   "foo",
+		"image":  "bar",
+		"cpus":   "2.0",
+		"memory": "2GB",
+		"networks": []map[string]interface{}{
+			{"network": "net-a", "ip": "10.0.0.2"},
+			{"network": "net-b", "aliases": []string{"foo"}},
+		},
+	}
+	res := usecase.Execute(context.TODO(), command.Command{
+		ID:     "TEST",
+		Target: testTarget,
+		Order:  command.Order{Type: command.Createcontainer, Payload: payload},
+	})
+	assert.NoError(t, res.Error)
+
+	payload["networks"] = []map[string]interface{}{{"network": "net-a"}, {"network": "net-a"}}
+	res = usecase.Execute(context.TODO(), command.Command{
+		ID:     "TEST",
+		Target: testTarget,
+		Order:  command.Order{Type: command.Createcontainer, Payload: payload},
+	})
+	assert.True(t, res.IsFatal())
+	service.AssertExpectations(t)
+}
+
+func TestDockerUseCase_Execute_Swarm(t *testing.T) {
+	setup := entity.Swarm{
+		SetupSwarm: command.SetupSwarm{Hosts: []string{"10.0.0.2", "10.0.0.3", "10.0.0.4"}},
+		Managers:   3,
+		Labels:     map[string]map[string]string{"10.0.0.3": {"zone": "east"}},
+	}
+	service := new(mockService.DockerService)
+	service.On("CreateClient", mock.Anything, mock.Anything).Return(nil, nil).Twice()
+	service.On("SwarmCluster", mock.Anything, mock.Anything, setup).Return(
+		entity.Result{Type: entity.SuccessType}).Once()
+	service.On("LeaveSwarm", mock.Anything, mock.Anything, setup.SetupSwarm).Return(
+		entity.Result{Type: entity.SuccessType}).Once()
+
+	usecase := NewDockerUseCase(service, logrus.New())
+
+	res := usecase.Execute(context.TODO(), command.Command{
+		ID:     "TEST",
+		Target: testTarget,
+		Order:  command.Order{Type: command.SwarmInit, Payload: setup},
+	})
+	assert.NoError(t, res.Error)
+
+	res = usecase.Execute(context.TODO(), command.Command{
+		ID:     "TEST",
+		Target: testTarget,
+		Order:  command.Order{Type: entity.Leaveswarm, Payload: setup.SetupSwarm},
+	})
+	assert.NoError(t, res.Error)
+	service.AssertExpectations(t)
+}
+
+func TestDockerUseCase_Execute_Services(t *testing.T) {
+	svc := entity.SwarmService{
+		Container: command.Container{Name: "nodes", Image: "geth"},
+		Replicas:  3,
+	}
+	service := new(mockService.DockerService)
+	service.On("CreateClient", mock.Anything, mock.Anything).Return(nil, nil).Times(5)
+	service.On("CreateService", mock.Anything, mock.Anything, svc).Return(
+		entity.Result{Type: entity.SuccessType}).Once()
+	service.On("UpdateService", mock.Anything, mock.Anything, svc).Return(
+		entity.Result{Type: entity.SuccessType}).Once()
+	service.On("ScaleService", mock.Anything, mock.Anything, entity.ScaleService{Name: "nodes", Replicas: 5}).Return(
+		entity.Result{Type: entity.SuccessType}).Once()
+	service.On("RemoveService", mock.Anything, mock.Anything, "nodes").Return(
+		entity.Result{Type: entity.SuccessType}).Once()
+
+	usecase := NewDockerUseCase(service, logrus.New())
+	for orderType, payload := range map[command.OrderType]interface{}{
+		entity.Createservice: svc,
+		entity.Updateservice: svc,
+		entity.Scaleservice:  entity.ScaleService{Name: "nodes", Replicas: 5},
+		entity.Removeservice: command.SimpleName{Name: "nodes"},
+	} {
+		res := usecase.Execute(context.TODO(), command.Command{
+			ID:     "TEST",
+			Target: testTarget,
+			Order:  command.Order{Type: orderType, Payload: payload},
+		})
+		assert.NoError(t, res.Error, orderType)
+	}
+
+	res := usecase.Execute(context.TODO(), command.Command{
+		ID:     "TEST",
+		Target: testTarget,
+		Order:  command.Order{Type: entity.Createservice, Payload: entity.SwarmService{Replicas: 1}},
+	})
+	assert.True(t, res.IsFatal())
+	service.AssertExpectations(t)
+}
+
+func TestDockerUseCase_Execute_GlusterVolumes(t *testing.T) {
+	vol := entity.Volume{
+		Volume:  command.Volume{Name: "shared", Global: true, Hosts: []string{"10.0.0.2", "10.0.0.3"}},
+		Backend: entity.NFSVolumeBackend,
+	}
+	share := entity.VolumeShare{VolumeShare: command.VolumeShare{Hosts: vol.Hosts}}
+	resize := entity.VolumeResize{Name: "shared", Add: []string{"10.0.0.4"}}
+	export := entity.VolumeExport{Name: "shared", File: command.File{ID: "snapshot"}}
+
+	service := new(mockService.DockerService)
+	service.On("CreateClient", mock.Anything, mock.Anything).Return(nil, nil).Times(9)
+	service.On("RemoveGlobalVolume", mock.Anything, mock.Anything, vol).Return(
+		entity.Result{Type: entity.SuccessType}).Once()
+	service.On("RemoveVolume", mock.Anything, mock.Anything, "local").Return(
+		entity.Result{Type: entity.SuccessType}).Once()
+	service.On("RemoveVolumeShare", mock.Anything, mock.Anything, share).Return(
+		entity.Result{Type: entity.SuccessType}).Once()
+	service.On("ResizeVolume", mock.Anything, mock.Anything, resize).Return(
+		entity.Result{Type: entity.SuccessType}).Once()
+	service.On("VolumeStatus", mock.Anything, mock.Anything,
+		entity.Volume{Volume: command.Volume{Name: "shared"}}).Return(
+		entity.Result{Type: entity.SuccessType}).Once()
+	service.On("ExportVolume", mock.Anything, mock.Anything, export).Return(
+		entity.Result{Type: entity.SuccessType}).Once()
+
+	usecase := NewDockerUseCase(service, logrus.New())
+	for _, order := range []command.Order{
+		{Type: command.Removevolume, Payload: vol},
+		{Type: command.Removevolume, Payload: command.SimpleName{Name: "local"}},
+		{Type: entity.Removevolumeshare, Payload: share},
+		{Type: entity.Resizevolume, Payload: resize},
+		{Type: entity.Volumestatus, Payload: command.SimpleName{Name: "shared"}},
+		{Type: entity.Exportvolume, Payload: export},
+	} {
+		res := usecase.Execute(context.TODO(), command.Command{
+			ID:     "TEST",
+			Target: testTarget,
+			Order:  order,
+		})
+		assert.NoError(t, res.Error, order.Type)
+	}
+
+	for _, order := range []command.Order{
+		{Type: entity.Resizevolume, Payload: entity.VolumeResize{Name: "shared"}},
+		{Type: command.Createvolume, Payload: entity.Volume{Backend: "ceph"}},
+		{Type: entity.Exportvolume, Payload: entity.VolumeExport{Name: "shared"}},
+	} {
+		res := usecase.Execute(context.TODO(), command.Command{
+			ID:     "TEST",
+			Target: testTarget,
+			Order:  order,
+		})
+		assert.True(t, res.IsFatal(), order.Type)
+	}
+	service.AssertExpectations(t)
+}
@@ -0,0 +1,269 @@
+/**
+ * Copyright 2019 Whiteblock Inc. All rights reserved.
+ * Use of this source code is governed by a BSD-style
+ * license that can be found in the LICENSE file.
+ */
+
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/whiteblock/genesis/pkg/entity"
+	"github.com/whiteblock/genesis/pkg/service"
+	"github.com/whiteblock/genesis/pkg/validator"
+
+	"github.com/imdario/mergo"
+	"github.com/sirupsen/logrus"
+	"github.com/whiteblock/definition/command"
+)
+
+type kubernetesUseCase struct {
+	service   service.KubernetesService
+	namespace string
+	log       logrus.Ext1FieldLogger
+}
+
+// NewKubernetesUseCase creates a DockerUseCase which executes the commands on a Kubernetes
+// cluster instead, in the given namespace
+func NewKubernetesUseCase(
+	service service.KubernetesService,
+	namespace string,
+	log logrus.Ext1FieldLogger) DockerUseCase {
+	return &kubernetesUseCase{service: service, namespace: namespace, log: log}
+}
+
+// Run is equivalent to Execute, the target of the command is not used, since the
+// cluster does the scheduling
+func (kuc kubernetesUseCase) Run(ctx context.Context, cmd command.Command) entity.Result {
+	kuc.log.WithFields(logrus.Fields{"command": cmd.ID}).Trace("running command")
+	return kuc.Execute(ctx, cmd)
+}
+
+// Execute executes the command with the given context
+func (kuc kubernetesUseCase) Execute(ctx context.Context, cmd command.Command) entity.Result {
+	kuc.log.WithFields(logrus.Fields{
+		"command": cmd.ID,
+		"type":    cmd.Order.Type,
+	}).Trace("routing a command")
+	switch command.OrderType(strings.ToLower(string(cmd.Order.Type))) {
+	case command.Createcontainer:
+		return kuc.createContainerShim(ctx, cmd)
+	case command.Startcontainer:
+		return kuc.startContainerShim(ctx, cmd)
+	case command.Removecontainer:
+		return kuc.removeContainerShim(ctx, cmd)
+	case command.Createnetwork:
+		return kuc.createNetworkShim(ctx, cmd)
+	case command.Attachnetwork:
+		return kuc.attachNetworkShim(ctx, cmd)
+	case command.Detachnetwork:
+		return kuc.detachNetworkShim(ctx, cmd)
+	case command.Removenetwork:
+		return kuc.removeNetworkShim(ctx, cmd)
+	case command.Createvolume:
+		return kuc.createVolumeShim(ctx, cmd)
+	case command.Removevolume:
+		return kuc.removeVolumeShim(ctx, cmd)
+	case command.Putfileincontainer:
+		return kuc.putFileInContainerShim(ctx, cmd)
+	case command.Emulation, entity.Changeemulation:
+		return kuc.emulationShim(ctx, cmd, false)
+	case entity.Removeemulation:
+		return kuc.emulationShim(ctx, cmd, true)
+	case command.Pullimage:
+		return kuc.pullImageShim(ctx, cmd)
+	case command.Pauseexecution:
+		return pauseExecution(kuc.log.WithFields(logrus.Fields{"command": cmd.ID, "cmd": cmd}), cmd)
+	case command.Resumeexecution:
+		return kuc.resumeExecutionShim(ctx, cmd)
+	}
+	return entity.NewFatalResult(fmt.Sprintf(
+		"command type \"%s\" is not supported by the kubernetes backend", cmd.Order.Type)).InjectMeta(
+		map[string]interface{}{"type": cmd.Order.Type})
+}
+
+func (kuc kubernetesUseCase) injectLabels(cmd command.Command) entity.KubeCli {
+	out := entity.KubeCli{Namespace: kuc.namespace, Labels: map[string]string{}, TestID: cmd.TestID()}
+	mergo.Map(&out.Labels, cmd.Meta)
+	delete(out.Labels, entity.BackendKey)
+	return out
+}
+
+func (kuc kubernetesUseCase) createContainerShim(ctx context.Context, cmd command.Command) entity.Result {
+	var container entity.Container
+	err := cmd.ParseOrderPayloadInto(&container)
+	if err != nil {
+		return entity.NewFatalResult(err)
+	}
+	err = validator.Container(container.Container)
+	if err != nil {
+		return entity.NewFatalResult(err)
+	}
+	err = validator.NetworkAttachments(container)
+	if err != nil {
+		return entity.NewFatalResult(err)
+	}
+	return kuc.service.CreateContainer(ctx, kuc.injectLabels(cmd), container)
+}
+
+func (kuc kubernetesUseCase) startContainerShim(ctx context.Context, cmd command.Command) entity.Result {
+	var sc command.StartContainer
+	err := cmd.ParseOrderPayloadInto(&sc)
+	if err != nil {
+		return entity.NewFatalResult(err)
+	}
+	if len(sc.Name) == 0 {
+		return ErrEmptyFieldName
+	}
+	return kuc.service.StartContainer(ctx, kuc.injectLabels(cmd), sc)
+}
+
+func (kuc kubernetesUseCase) removeContainerShim(ctx context.Context, cmd command.Command) entity.Result {
+	var payload command.SimpleName
+	err := cmd.ParseOrderPayloadInto(&payload)
+	if err != nil {
+		return entity.NewFatalResult(err)
+	}
+	if payload.Name == "" {
+		return ErrEmptyFieldName
+	}
+	return kuc.service.RemoveContainer(ctx, kuc.injectLabels(cmd), payload.Name)
+}
+
+func (kuc kubernetesUseCase) createNetworkShim(ctx context.Context, cmd command.Command) entity.Result {
+	var net entity.Network
+	err := cmd.ParseOrderPayloadInto(&net)
+	if err != nil {
+		return entity.NewFatalResult(err)
+	}
+	err = validator.Network(net)
+	if err != nil {
+		return entity.NewFatalResult(err)
+	}
+	return kuc.service.CreateNetwork(ctx, kuc.injectLabels(cmd), net)
+}
+
+func (kuc kubernetesUseCase) attachNetworkShim(ctx context.Context, cmd command.Command) entity.Result {
+	var payload entity.ContainerNetwork
+	err := cmd.ParseOrderPayloadInto(&payload)
+	if err != nil {
+		return entity.NewErrorResult(err)
+	}
+	if len(payload.Container) == 0 {
+		return ErrEmptyFieldContainer
+	}
+	if len(payload.Network) == 0 {
+		return ErrEmptyFieldNetwork
+	}
+	return kuc.service.AttachNetwork(ctx, kuc.injectLabels(cmd), payload)
+}
+
+func (kuc kubernetesUseCase) detachNetworkShim(ctx context.Context, cmd command.Command) entity.Result {
+	var payload command.ContainerNetwork
+	err := cmd.ParseOrderPayloadInto(&payload)
+	if err != nil {
+		return entity.NewErrorResult(err)
+	}
+	if len(payload.Container) == 0 {
+		return ErrEmptyFieldContainer
+	}
+	if len(payload.Network) == 0 {
+		return ErrEmptyFieldNetwork
+	}
+	return kuc.service.DetachNetwork(ctx, kuc.injectLabels(cmd), payload.Network, payload.Container)
+}
+
+func (kuc kubernetesUseCase) removeNetworkShim(ctx context.Context, cmd command.Command) entity.Result {
+	var payload command.SimpleName
+	err := cmd.ParseOrderPayloadInto(&payload)
+	if err != nil {
+		return entity.NewFatalResult(err)
+	}
+	if payload.Name == "" {
+		return ErrEmptyFieldName
+	}
+	return kuc.service.RemoveNetwork(ctx, kuc.injectLabels(cmd), payload.Name)
+}
+
+func (kuc kubernetesUseCase) createVolumeShim(ctx context.Context, cmd command.Command) entity.Result {
+	var payload command.Volume
+	err := cmd.ParseOrderPayloadInto(&payload)
+	if err != nil {
+		return entity.NewFatalResult(err)
+	}
+	return kuc.service.CreateVolume(ctx, kuc.injectLabels(cmd), payload)
+}
+
+func (kuc kubernetesUseCase) removeVolumeShim(ctx context.Context, cmd command.Command) entity.Result {
+	var payload command.SimpleName
+	err := cmd.ParseOrderPayloadInto(&payload)
+	if err != nil {
+		return entity.NewFatalResult(err)
+	}
+	if payload.Name == "" {
+		return ErrEmptyFieldName
+	}
+	return kuc.service.RemoveVolume(ctx, kuc.injectLabels(cmd), payload.Name)
+}
+
+func (kuc kubernetesUseCase) putFileInContainerShim(ctx context.Context, cmd command.Command) entity.Result {
+	var payload command.FileAndContainer
+	err := cmd.ParseOrderPayloadInto(&payload)
+	if err != nil {
+		return entity.NewFatalResult(err)
+	}
+	if len(payload.ContainerName) == 0 {
+		return ErrEmptyFieldContainer
+	}
+	return kuc.service.PlaceFileInContainer(ctx, kuc.injectLabels(cmd), payload.ContainerName, payload.File)
+}
+
+func (kuc kubernetesUseCase) emulationShim(ctx context.Context, cmd command.Command,
+	remove bool) entity.Result {
+
+	var payload command.Netconf
+	err := cmd.ParseOrderPayloadInto(&payload)
+	if err != nil {
+		return entity.NewFatalResult(err)
+	}
+	if len(payload.Container) == 0 {
+		return ErrEmptyFieldContainer
+	}
+	if len(payload.Network) == 0 {
+		return ErrEmptyFieldNetwork
+	}
+	if remove {
+		return kuc.service.RemoveEmulation(ctx, kuc.injectLabels(cmd), payload)
+	}
+	return kuc.service.Emulation(ctx, kuc.injectLabels(cmd), payload)
+}
+
+// pullImageShim only checks the image, the nodes of the cluster pull it when a pod needs it
+func (kuc kubernetesUseCase) pullImageShim(ctx context.Context, cmd command.Command) entity.Result {
+	var payload command.PullImage
+	err := cmd.ParseOrderPayloadInto(&payload)
+	if err != nil {
+		return entity.NewFatalResult(err)
+	}
+	if len(payload.Image) == 0 {
+		return ErrEmptyFieldImage
+	}
+	kuc.log.WithFields(logrus.Fields{"command": cmd.ID, "image": payload.Image}).Debug(
+		"leaving the image to be pulled by the cluster")
+	return entity.NewSuccessResult()
+}
+
+func (kuc kubernetesUseCase) resumeExecutionShim(ctx context.Context, cmd command.Command) entity.Result {
+	var payload command.ResumeExecution
+	err := cmd.ParseOrderPayloadInto(&payload)
+	if err != nil {
+		return entity.NewFatalResult(err)
+	}
+	if len(payload.Tasks) == 0 {
+		return entity.NewSuccessResult()
+	}
+	return kuc.service.RemoveContainer(ctx, kuc.injectLabels(cmd), payload.Tasks...)
+}
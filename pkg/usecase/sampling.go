@@ -0,0 +1,49 @@
+/**
+ * Copyright 2019 Whiteblock Inc. All rights reserved.
+ * Use of this source code is governed by a BSD-style
+ * license that can be found in the LICENSE file.
+ */
+
+package usecase
+
+import (
+	"context"
+
+	"github.com/whiteblock/genesis/pkg/entity"
+	"github.com/whiteblock/genesis/pkg/service"
+
+	"github.com/sirupsen/logrus"
+	"github.com/whiteblock/definition/command"
+)
+
+type samplingUseCase struct {
+	inner   DockerUseCase
+	sampler service.StatsSampler
+	log     logrus.Ext1FieldLogger
+}
+
+// NewSamplingUseCase creates a DockerUseCase which has the resource usage of the containers of
+// a test sampled, on each of the hosts the test successfully runs commands on
+func NewSamplingUseCase(
+	inner DockerUseCase,
+	sampler service.StatsSampler,
+	log logrus.Ext1FieldLogger) DockerUseCase {
+	return &samplingUseCase{inner: inner, sampler: sampler, log: log}
+}
+
+// Run runs the command, then samples its host
+func (suc samplingUseCase) Run(ctx context.Context, cmd command.Command) entity.Result {
+	return suc.track(cmd, suc.inner.Run(ctx, cmd))
+}
+
+// Execute executes the command, then samples its host
+func (suc samplingUseCase) Execute(ctx context.Context, cmd command.Command) entity.Result {
+	return suc.track(cmd, suc.inner.Execute(ctx, cmd))
+}
+
+func (suc samplingUseCase) track(cmd command.Command, res entity.Result) entity.Result {
+	if res.IsSuccess() && len(cmd.Target.IP) > 0 && cmd.Target.IP != "0.0.0.0" {
+		suc.sampler.Track(cmd.TestID(), cmd.Target.IP)
+	}
+	return res
+}
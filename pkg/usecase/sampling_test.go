@@ -0,0 +1,44 @@
+/**
+ * Copyright 2019 Whiteblock Inc. All rights reserved.
+ * Use of this source code is governed by a BSD-style
+ * license that can be found in the LICENSE file.
+ */
+
+package usecase
+
+import (
+	"context"
+	"testing"
+
+	mockService "github.com/whiteblock/genesis/mocks/pkg/service"
+	mockUseCase "github.com/whiteblock/genesis/mocks/pkg/usecase"
+	"github.com/whiteblock/genesis/pkg/entity"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/whiteblock/definition/command"
+)
+
+func TestSamplingUseCase(t *testing.T) {
+	inner := new(mockUseCase.DockerUseCase)
+	inner.On("Run", mock.Anything, mock.Anything).Return(entity.NewSuccessResult()).Once()
+	inner.On("Execute", mock.Anything, mock.Anything).Return(entity.NewFatalResult("err")).Once()
+	inner.On("Run", mock.Anything, mock.Anything).Return(entity.NewSuccessResult()).Once()
+
+	sampler := new(mockService.StatsSampler)
+	sampler.On("Track", "", "10.0.0.2").Return().Once()
+
+	suc := NewSamplingUseCase(inner, sampler, logrus.New())
+	res := suc.Run(context.Background(), command.Command{Target: command.Target{IP: "10.0.0.2"}})
+	assert.NoError(t, res.Error)
+
+	res = suc.Execute(context.Background(), command.Command{Target: command.Target{IP: "10.0.0.3"}})
+	assert.True(t, res.IsFatal(), "failed commands do not have their host sampled")
+
+	res = suc.Run(context.Background(), command.Command{Target: command.Target{IP: "0.0.0.0"}})
+	assert.NoError(t, res.Error)
+
+	inner.AssertExpectations(t)
+	sampler.AssertExpectations(t)
+}
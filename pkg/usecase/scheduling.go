@@ -0,0 +1,133 @@
+/**
+ * Copyright 2019 Whiteblock Inc. All rights reserved.
+ * Use of this source code is governed by a BSD-style
+ * license that can be found in the LICENSE file.
+ */
+
+package usecase
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/whiteblock/genesis/pkg/entity"
+	"github.com/whiteblock/genesis/pkg/service"
+
+	"github.com/sirupsen/logrus"
+	"github.com/whiteblock/definition/command"
+)
+
+type schedulingUseCase struct {
+	inner     DockerUseCase
+	service   service.DockerService
+	inventory service.HostInventory
+	refresh   time.Duration
+	log       logrus.Ext1FieldLogger
+}
+
+// NewSchedulingUseCase creates a DockerUseCase which gives a target to the commands which do not
+// have one. The containers they create are placed on the hosts of the inventory, and the commands
+// on those containers are sent to the host they were placed on.
+func NewSchedulingUseCase(
+	inner DockerUseCase,
+	service service.DockerService,
+	inventory service.HostInventory,
+	refresh time.Duration,
+	log logrus.Ext1FieldLogger) DockerUseCase {
+	return &schedulingUseCase{inner: inner, service: service, inventory: inventory,
+		refresh: refresh, log: log}
+}
+
+// Run runs the command, on the host it is placed on if it has no target
+func (suc schedulingUseCase) Run(ctx context.Context, cmd command.Command) entity.Result {
+	return suc.schedule(ctx, cmd, suc.inner.Run)
+}
+
+// Execute executes the command, on the host it is placed on if it has no target
+func (suc schedulingUseCase) Execute(ctx context.Context, cmd command.Command) entity.Result {
+	return suc.schedule(ctx, cmd, suc.inner.Execute)
+}
+
+func (suc schedulingUseCase) schedule(ctx context.Context, cmd command.Command,
+	run func(context.Context, command.Command) entity.Result) entity.Result {
+
+	if len(cmd.Target.IP) > 0 && cmd.Target.IP != "0.0.0.0" {
+		return run(ctx, cmd)
+	}
+	orderType := command.OrderType(strings.ToLower(string(cmd.Order.Type)))
+	if orderType == command.Createcontainer {
+		return suc.place(ctx, cmd, run)
+	}
+
+	var payload struct {
+		Name      string `json:"name"`
+		Container string `json:"container"`
+	}
+	if cmd.ParseOrderPayloadInto(&payload) != nil {
+		return run(ctx, cmd)
+	}
+	for _, name := range []string{payload.Container, payload.Name} {
+		host, ok := suc.inventory.Locate(cmd.TestID(), name)
+		if len(name) == 0 || !ok {
+			continue
+		}
+		cmd.Target.IP = host
+		res := run(ctx, cmd)
+		if orderType == command.Removecontainer && res.IsSuccess() {
+			suc.inventory.Release(cmd.TestID(), name)
+		}
+		return res.InjectMeta(map[string]interface{}{entity.HostKey: host})
+	}
+	return run(ctx, cmd)
+}
+
+func (suc schedulingUseCase) place(ctx context.Context, cmd command.Command,
+	run func(context.Context, command.Command) entity.Result) entity.Result {
+
+	if len(suc.inventory.Hosts()) == 0 {
+		return run(ctx, cmd)
+	}
+	var container command.Container
+	err := cmd.ParseOrderPayloadInto(&container)
+	if err != nil {
+		return entity.NewFatalResult(err)
+	}
+	if len(container.Name) == 0 {
+		return ErrEmptyFieldName
+	}
+	req, err := entity.NewPlacementRequest(cmd, container)
+	if err != nil {
+		return entity.NewFatalResult(err)
+	}
+	suc.refreshFacts(ctx, cmd.TestID())
+
+	host, err := suc.inventory.Place(req)
+	if err != nil {
+		suc.log.WithFields(logrus.Fields{"command": cmd.ID, "error": err}).Error(
+			"failed to place a container")
+		return entity.NewFatalResult(err).InjectMeta(map[string]interface{}{"name": container.Name})
+	}
+	cmd.Target.IP = host
+	res := run(ctx, cmd)
+	if !res.IsSuccess() {
+		suc.inventory.Release(req.TestID, req.Container)
+	}
+	return res.InjectMeta(map[string]interface{}{entity.HostKey: host})
+}
+
+// refreshFacts gathers the facts of the hosts which are out of date, with the credentials of the test
+func (suc schedulingUseCase) refreshFacts(ctx context.Context, testID string) {
+	wg := sync.WaitGroup{}
+	for _, address := range suc.inventory.Stale(suc.refresh) {
+		wg.Add(1)
+		go func(address string) {
+			defer wg.Done()
+			suc.inventory.Refresh(ctx, address, func() (entity.Client, error) {
+				return suc.service.CreateClient2(address, testID)
+			})
+		}(address)
+	}
+	wg.Wait()
+}
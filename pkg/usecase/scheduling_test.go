@@ -0,0 +1,113 @@
+/**
+ * Copyright 2019 Whiteblock Inc. All rights reserved.
+ * Use of this source code is governed by a BSD-style
+ * license that can be found in the LICENSE file.
+ */
+
+package usecase
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	mockService "github.com/whiteblock/genesis/mocks/pkg/service"
+	mockUseCase "github.com/whiteblock/genesis/mocks/pkg/usecase"
+	"github.com/whiteblock/genesis/pkg/config"
+	"github.com/whiteblock/genesis/pkg/entity"
+	"github.com/whiteblock/genesis/pkg/fake"
+	"github.com/whiteblock/genesis/pkg/service"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/whiteblock/definition/command"
+)
+
+func targets(ip string) interface{} {
+	return mock.MatchedBy(func(cmd command.Command) bool { return cmd.Target.IP == ip })
+}
+
+func TestSchedulingUseCase(t *testing.T) {
+	inventory := service.NewHostInventory(config.Inventory{Hosts: []entity.HostSpec{
+		{Address: "10.0.0.2", CPUs: 2, Memory: 1 << 30},
+		{Address: "10.0.0.3", CPUs: 8, Memory: 8 << 30},
+	}}, logrus.New())
+
+	serv := new(mockService.DockerService)
+	serv.On("CreateClient2", mock.Anything, mock.Anything).Return(
+		func(ip string, testID string) entity.Client { return fake.NewEngine(ip) }, nil).Twice()
+
+	inner := new(mockUseCase.DockerUseCase)
+	inner.On("Run", mock.Anything, targets("10.0.0.2")).Return(entity.NewSuccessResult()).Times(3)
+	inner.On("Run", mock.Anything, targets("10.0.0.9")).Return(entity.NewSuccessResult()).Once()
+	inner.On("Run", mock.Anything, targets("10.0.0.3")).Return(
+		entity.NewFatalResult("failed")).Once()
+
+	suc := NewSchedulingUseCase(inner, serv, inventory, time.Minute, logrus.New())
+
+	res := suc.Run(context.Background(), command.Command{
+		Target: command.Target{IP: "0.0.0.0"},
+		Order: command.Order{
+			Type:    command.Createcontainer,
+			Payload: map[string]interface{}{"name": "tester", "cpus": "2", "memory": "512MB"},
+		},
+	})
+	require.True(t, res.IsSuccess())
+	assert.Equal(t, "10.0.0.2", res.Meta[entity.HostKey])
+
+	res = suc.Run(context.Background(), command.Command{
+		Order: command.Order{
+			Type:    command.Startcontainer,
+			Payload: map[string]interface{}{"name": "tester"},
+		},
+	})
+	require.True(t, res.IsSuccess())
+	assert.Equal(t, "10.0.0.2", res.Meta[entity.HostKey])
+
+	res = suc.Run(context.Background(), command.Command{
+		Target: command.Target{IP: "10.0.0.9"},
+		Order:  command.Order{Type: command.Startcontainer},
+	})
+	require.True(t, res.IsSuccess(), "commands with a target are passed through")
+
+	res = suc.Run(context.Background(), command.Command{
+		Order: command.Order{
+			Type:    command.Createcontainer,
+			Payload: map[string]interface{}{"name": "other", "cpus": "1"},
+		},
+	})
+	assert.True(t, res.IsFatal())
+	_, placed := inventory.Locate("", "other")
+	assert.False(t, placed, "the reservation of a container which failed to be created is released")
+
+	res = suc.Run(context.Background(), command.Command{
+		Order: command.Order{
+			Type:    command.Removecontainer,
+			Payload: map[string]interface{}{"name": "tester"},
+		},
+	})
+	require.True(t, res.IsSuccess())
+	_, placed = inventory.Locate("", "tester")
+	assert.False(t, placed)
+
+	serv.AssertExpectations(t)
+	inner.AssertExpectations(t)
+}
+
+func TestSchedulingUseCase_EmptyInventory(t *testing.T) {
+	inner := new(mockUseCase.DockerUseCase)
+	inner.On("Execute", mock.Anything, targets("")).Return(entity.NewSuccessResult()).Once()
+
+	suc := NewSchedulingUseCase(inner, nil,
+		service.NewHostInventory(config.Inventory{}, logrus.New()), time.Minute, logrus.New())
+	res := suc.Execute(context.Background(), command.Command{
+		Order: command.Order{
+			Type:    command.Createcontainer,
+			Payload: map[string]interface{}{"name": "tester"},
+		},
+	})
+	assert.True(t, res.IsSuccess())
+	inner.AssertExpectations(t)
+}
@@ -0,0 +1,69 @@
+/**
+ * Copyright 2019 Whiteblock Inc. All rights reserved.
+ * Use of this source code is governed by a BSD-style
+ * license that can be found in the LICENSE file.
+ */
+
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/whiteblock/genesis/pkg/entity"
+	"github.com/whiteblock/genesis/pkg/service"
+
+	"github.com/sirupsen/logrus"
+	"github.com/whiteblock/definition/command"
+)
+
+type watchingUseCase struct {
+	inner   DockerUseCase
+	watcher service.EventWatcher
+	policy  string
+	log     logrus.Ext1FieldLogger
+}
+
+// NewWatchingUseCase creates a DockerUseCase which follows the events of the containers of a
+// test, on each of the hosts the test successfully runs commands on. Once a crash fails the test,
+// its commands are no longer run. The policy is used for the tests which do not set their own.
+func NewWatchingUseCase(
+	inner DockerUseCase,
+	watcher service.EventWatcher,
+	policy string,
+	log logrus.Ext1FieldLogger) DockerUseCase {
+	return &watchingUseCase{inner: inner, watcher: watcher, policy: policy, log: log}
+}
+
+// Run runs the command, unless the test has failed, then watches its host
+func (wuc watchingUseCase) Run(ctx context.Context, cmd command.Command) entity.Result {
+	return wuc.guard(cmd, func() entity.Result { return wuc.inner.Run(ctx, cmd) })
+}
+
+// Execute executes the command, unless the test has failed, then watches its host
+func (wuc watchingUseCase) Execute(ctx context.Context, cmd command.Command) entity.Result {
+	return wuc.guard(cmd, func() entity.Result { return wuc.inner.Execute(ctx, cmd) })
+}
+
+func (wuc watchingUseCase) guard(cmd command.Command, run func() entity.Result) entity.Result {
+	policy := wuc.policy
+	if chosen, ok := cmd.Meta[entity.EventPolicyKey]; ok && len(chosen) > 0 {
+		policy = chosen
+	}
+	if !entity.IsEventPolicy(policy) {
+		return entity.NewFatalResult(fmt.Errorf(`unknown event policy "%s"`, policy))
+	}
+	if crash := wuc.watcher.Failure(cmd.TestID()); crash != nil {
+		wuc.log.WithFields(logrus.Fields{"command": cmd.ID, "crash": *crash}).Debug(
+			"not running the command of a failed test")
+		return crash.Result()
+	}
+
+	started := time.Now()
+	res := run()
+	if res.IsSuccess() && len(cmd.Target.IP) > 0 && cmd.Target.IP != "0.0.0.0" {
+		wuc.watcher.Watch(cmd.TestID(), cmd.Target.IP, policy, started)
+	}
+	return res
+}
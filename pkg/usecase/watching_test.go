@@ -0,0 +1,53 @@
+/**
+ * Copyright 2019 Whiteblock Inc. All rights reserved.
+ * Use of this source code is governed by a BSD-style
+ * license that can be found in the LICENSE file.
+ */
+
+package usecase
+
+import (
+	"context"
+	"testing"
+
+	mockService "github.com/whiteblock/genesis/mocks/pkg/service"
+	mockUseCase "github.com/whiteblock/genesis/mocks/pkg/usecase"
+	"github.com/whiteblock/genesis/pkg/entity"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/whiteblock/definition/command"
+)
+
+func TestWatchingUseCase(t *testing.T) {
+	inner := new(mockUseCase.DockerUseCase)
+	inner.On("Run", mock.Anything, mock.Anything).Return(entity.NewSuccessResult()).Twice()
+
+	crash := entity.ContainerEvent{Container: "node0", Host: "10.0.0.2", Action: entity.DieContainerEvent,
+		ExitCode: 1}
+	watcher := new(mockService.EventWatcher)
+	watcher.On("Failure", "").Return(nil).Twice()
+	watcher.On("Failure", "").Return(&crash).Once()
+	watcher.On("Watch", "", "10.0.0.2", entity.NotifyEventPolicy, mock.AnythingOfType("time.Time")).Return().Once()
+	watcher.On("Watch", "", "10.0.0.2", entity.RestartEventPolicy, mock.AnythingOfType("time.Time")).Return().Once()
+
+	wuc := NewWatchingUseCase(inner, watcher, entity.NotifyEventPolicy, logrus.New())
+	res := wuc.Run(context.Background(), command.Command{Target: command.Target{IP: "10.0.0.2"}})
+	assert.NoError(t, res.Error)
+
+	res = wuc.Run(context.Background(), command.Command{Target: command.Target{IP: "10.0.0.2"},
+		Meta: map[string]string{entity.EventPolicyKey: entity.RestartEventPolicy}})
+	assert.NoError(t, res.Error)
+
+	res = wuc.Run(context.Background(), command.Command{Target: command.Target{IP: "10.0.0.2"}})
+	assert.True(t, res.IsFatal(), "the commands of a failed test are not run")
+	assert.Equal(t, crash, res.Meta["crash"])
+
+	res = wuc.Execute(context.Background(), command.Command{
+		Meta: map[string]string{entity.EventPolicyKey: "ignore"}})
+	assert.True(t, res.IsFatal())
+
+	inner.AssertExpectations(t)
+	watcher.AssertExpectations(t)
+}
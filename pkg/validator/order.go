@@ -8,9 +8,17 @@ package validator
 
 import (
 	"errors"
+	"fmt"
+	"net"
+	"regexp"
 	"strconv"
+	"strings"
+	"time"
+
+	"github.com/whiteblock/genesis/pkg/entity"
 
 	"github.com/whiteblock/definition/command"
+	"github.com/whiteblock/utility/utils"
 )
 
 var (
@@ -19,6 +27,12 @@ var (
 
 	// ErrMissingImage means missing image field
 	ErrMissingImage = errors.New(`missing field "image"`)
+
+	// ErrMissingSteps means missing steps field
+	ErrMissingSteps = errors.New(`missing field "steps"`)
+
+	// ErrMissingGroups means missing groups field
+	ErrMissingGroups = errors.New(`missing field "groups"`)
 )
 
 // Container validates a container command payload
@@ -42,3 +56,371 @@ func Container(cntr command.Container) error {
 	}
 	return nil
 }
+
+// EmulationSchedule validates the steps of an emulation schedule payload
+func EmulationSchedule(sched entity.EmulationSchedule) error {
+	if len(sched.Steps) == 0 {
+		return ErrMissingSteps
+	}
+	var last time.Duration
+	for i, step := range sched.Steps {
+		if step.Offset.IsInfinite() || step.Offset.Duration < 0 {
+			return fmt.Errorf("step %d has an invalid offset", i)
+		}
+		if step.Offset.Duration > last {
+			last = step.Offset.Duration
+		}
+	}
+	if sched.Restore.Empty() {
+		return nil
+	}
+	if sched.Restore.IsInfinite() || sched.Restore.Duration <= last {
+		return errors.New("restore must come after the last step")
+	}
+	return nil
+}
+
+// Partition validates the groups of a partition payload. A heal only needs the containers,
+// which may be given as a single group.
+func Partition(part entity.Partition, heal bool) error {
+	if len(part.Groups) == 0 {
+		return ErrMissingGroups
+	}
+	if !heal && len(part.Groups) < 2 {
+		return errors.New("a partition needs at least two groups")
+	}
+	seen := map[string]bool{}
+	for i, group := range part.Groups {
+		if len(group) == 0 {
+			return fmt.Errorf("group %d is empty", i)
+		}
+		for _, cntr := range group {
+			if len(cntr) == 0 {
+				return fmt.Errorf("group %d has a container without a name", i)
+			}
+			if seen[cntr] {
+				return fmt.Errorf("container \"%s\" is given more than once", cntr)
+			}
+			seen[cntr] = true
+		}
+	}
+	return nil
+}
+
+// UpdateResources validates a resource update payload
+func UpdateResources(ur entity.UpdateResources) error {
+	if len(ur.Name) == 0 {
+		return ErrMissingName
+	}
+	if len(ur.Cpus) == 0 && len(ur.Memory) == 0 {
+		return errors.New("there are no resources to update")
+	}
+	if len(ur.Cpus) > 0 {
+		_, err := strconv.ParseFloat(ur.Cpus, 64)
+		if err != nil {
+			return err
+		}
+	}
+	if len(ur.Memory) > 0 {
+		_, err := utils.Memconv(ur.Memory, utils.Mibi)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Stress validates the workers and duration of a stress payload
+func Stress(st entity.Stress) error {
+	if st.CPU < 0 || st.IO < 0 || st.HDD < 0 {
+		return errors.New("the number of workers cannot be negative")
+	}
+	if st.CPU+st.IO+st.HDD == 0 {
+		return errors.New("there are no workers")
+	}
+	if st.Duration.IsInfinite() || st.Duration.Duration < time.Second {
+		return errors.New("the duration must be at least a second")
+	}
+	return nil
+}
+
+// Swarm validates a swarm setup payload
+func Swarm(sw entity.Swarm) error {
+	if sw.Managers < 0 || sw.Managers > len(sw.Hosts) {
+		return fmt.Errorf("there cannot be %d managers of a swarm of %d hosts", sw.Managers, len(sw.Hosts))
+	}
+	seen := map[string]bool{}
+	for _, host := range sw.Hosts {
+		if seen[host] {
+			return fmt.Errorf("host \"%s\" is given more than once", host)
+		}
+		seen[host] = true
+	}
+	for host := range sw.Labels {
+		if !seen[host] {
+			return fmt.Errorf("there are labels for \"%s\", which is not in the swarm", host)
+		}
+	}
+	return nil
+}
+
+// SwarmService validates a swarm service payload
+func SwarmService(svc entity.SwarmService) error {
+	if len(svc.Name) == 0 {
+		return ErrMissingName
+	}
+	if len(svc.Image) == 0 {
+		return ErrMissingImage
+	}
+	if len(svc.Cpus) > 0 {
+		_, err := strconv.ParseFloat(svc.Cpus, 64)
+		if err != nil {
+			return err
+		}
+	}
+	if len(svc.Memory) > 0 {
+		_, err := utils.Memconv(svc.Memory, utils.Mibi)
+		if err != nil {
+			return err
+		}
+	}
+	for _, constraint := range svc.Constraints {
+		if !strings.Contains(constraint, "==") && !strings.Contains(constraint, "!=") {
+			return fmt.Errorf("invalid constraint \"%s\", expected key==value or key!=value", constraint)
+		}
+	}
+	policy := svc.UpdatePolicy
+	if policy.Delay.IsInfinite() || policy.Delay.Duration < 0 {
+		return errors.New("the update delay must be finite and not negative")
+	}
+	if len(policy.FailureAction) > 0 &&
+		!containsString([]string{"pause", "continue", "rollback"}, policy.FailureAction) {
+		return fmt.Errorf("unknown update failure action \"%s\"", policy.FailureAction)
+	}
+	if len(policy.Order) > 0 && !containsString([]string{"stop-first", "start-first"}, policy.Order) {
+		return fmt.Errorf("unknown update order \"%s\"", policy.Order)
+	}
+	return nil
+}
+
+// VolumeBackend validates the backend of a global volume, which may be left to the config
+func VolumeBackend(backend string) error {
+	switch backend {
+	case "", entity.GlusterVolumeBackend, entity.NFSVolumeBackend, entity.RsyncVolumeBackend:
+		return nil
+	}
+	return fmt.Errorf("unknown shared volume backend \"%s\"", backend)
+}
+
+// Volume validates the options of a new volume
+func Volume(vol entity.Volume) error {
+	if err := VolumeBackend(vol.Backend); err != nil {
+		return err
+	}
+	if vol.Seed != nil && len(vol.Seed.ID) == 0 {
+		return errors.New("the seed of the volume is missing its file id")
+	}
+	return nil
+}
+
+// VolumeExport validates a volume export payload
+func VolumeExport(export entity.VolumeExport) error {
+	if len(export.Name) == 0 {
+		return ErrMissingName
+	}
+	if len(export.File.ID) == 0 {
+		return errors.New("missing the id of the file to export to")
+	}
+	return nil
+}
+
+// VolumeResize validates a volume resize payload
+func VolumeResize(resize entity.VolumeResize) error {
+	if len(resize.Name) == 0 {
+		return ErrMissingName
+	}
+	if err := VolumeBackend(resize.Backend); err != nil {
+		return err
+	}
+	if len(resize.Add) == 0 && len(resize.Remove) == 0 {
+		return errors.New("there are no hosts to add or remove")
+	}
+	for _, host := range resize.Add {
+		if containsString(resize.Remove, host) {
+			return fmt.Errorf("host \"%s\" cannot be both added and removed", host)
+		}
+	}
+	return nil
+}
+
+// ClockSkew validates a clock skew
+func ClockSkew(cs entity.ClockSkew) error {
+	if cs.Offset.IsInfinite() {
+		return errors.New("the clock offset must be finite")
+	}
+	if cs.Rate < 0 {
+		return errors.New("the clock rate cannot be negative")
+	}
+	return nil
+}
+
+// IPv6 validates an IPv6 address, if one is given
+func IPv6(addr string) error {
+	if len(addr) == 0 {
+		return nil
+	}
+	ip := net.ParseIP(addr)
+	if ip == nil || ip.To4() != nil {
+		return fmt.Errorf("\"%s\" is not an IPv6 address", addr)
+	}
+	return nil
+}
+
+// Network validates a network payload
+func Network(network entity.Network) error {
+	if len(network.Name) == 0 {
+		return ErrMissingName
+	}
+	for _, subnet := range network.AllSubnets() {
+		if len(subnet.Subnet) == 0 {
+			continue
+		}
+		_, ipNet, err := net.ParseCIDR(subnet.Subnet)
+		if err != nil {
+			return err
+		}
+		if len(subnet.Gateway) > 0 && !ipNet.Contains(net.ParseIP(subnet.Gateway)) {
+			return fmt.Errorf("gateway \"%s\" is not in subnet \"%s\"", subnet.Gateway, subnet.Subnet)
+		}
+	}
+	return networkDriver(network)
+}
+
+var (
+	// networkModes are the modes of each of the drivers which have them
+	networkModes = map[string][]string{
+		entity.MacvlanDriver: {"bridge", "vepa", "passthru", "private"},
+		entity.IpvlanDriver:  {"l2", "l3", "l3s"},
+	}
+
+	// networkOptions are the driver options which have a field of their own
+	networkOptions = []string{
+		"parent",
+		"macvlan_mode",
+		"ipvlan_mode",
+		"encrypted",
+		"com.docker.network.driver.mtu",
+		"com.docker.network.bridge.name",
+	}
+)
+
+// networkDriver checks that the options of the network go with its driver
+func networkDriver(network entity.Network) error {
+	driver := network.GetDriver()
+	_, hasModes := networkModes[driver]
+
+	if len(network.Parent) > 0 && !hasModes {
+		return fmt.Errorf("a parent interface cannot be given for a %s network", driver)
+	}
+	if len(network.Mode) > 0 {
+		if !hasModes {
+			return fmt.Errorf("a mode cannot be given for a %s network", driver)
+		}
+		if !containsString(networkModes[driver], network.Mode) {
+			return fmt.Errorf("\"%s\" is not a %s mode", network.Mode, driver)
+		}
+	}
+	if network.Encrypted && driver != entity.OverlayDriver {
+		return fmt.Errorf("a %s network cannot be encrypted", driver)
+	}
+	if network.MTU < 0 || network.MTU > 65535 {
+		return fmt.Errorf("invalid mtu %d", network.MTU)
+	}
+	if network.MTU > 0 && driver != entity.BridgeDriver && driver != entity.OverlayDriver {
+		return fmt.Errorf("the mtu of a %s network cannot be set", driver)
+	}
+	if driver == entity.OverlayDriver && !network.Global {
+		return errors.New("an overlay network has to be global")
+	}
+	if hasModes && network.Global {
+		return fmt.Errorf("a %s network cannot be global", driver)
+	}
+	for key := range network.Options {
+		if containsString(networkOptions, key) {
+			return fmt.Errorf("the option \"%s\" has a field of its own", key)
+		}
+	}
+	return nil
+}
+
+func containsString(list []string, str string) bool {
+	for _, item := range list {
+		if item == str {
+			return true
+		}
+	}
+	return false
+}
+
+var (
+	// nameRegexp matches a name or alias which can be resolved by the embedded DNS server
+	nameRegexp = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_.-]*$`)
+)
+
+// Endpoint validates the settings of a container on a network
+func Endpoint(endpoint entity.Endpoint) error {
+	for _, alias := range endpoint.Aliases {
+		if !nameRegexp.MatchString(alias) {
+			return fmt.Errorf("invalid alias \"%s\"", alias)
+		}
+	}
+	if len(endpoint.MacAddress) > 0 {
+		mac, err := net.ParseMAC(endpoint.MacAddress)
+		if err != nil {
+			return err
+		}
+		if len(mac) != 6 || mac[0]&1 == 1 {
+			return fmt.Errorf("\"%s\" is not a unicast ethernet address", endpoint.MacAddress)
+		}
+	}
+	for _, link := range endpoint.Links {
+		parts := strings.Split(link, ":")
+		if len(parts) > 2 {
+			return fmt.Errorf("invalid link \"%s\"", link)
+		}
+		for _, part := range parts {
+			if !nameRegexp.MatchString(part) {
+				return fmt.Errorf("invalid link \"%s\"", link)
+			}
+		}
+	}
+	return nil
+}
+
+// NetworkAttachments validates the additional networks of a container, each network may
+// only be given once
+func NetworkAttachments(cntr entity.Container) error {
+	seen := map[string]bool{cntr.Network: len(cntr.Network) > 0}
+	for i, attachment := range cntr.Networks {
+		if len(attachment.Network) == 0 {
+			return fmt.Errorf("network attachment %d has no network", i)
+		}
+		if seen[attachment.Network] {
+			return fmt.Errorf("network \"%s\" is given more than once", attachment.Network)
+		}
+		seen[attachment.Network] = true
+
+		if len(attachment.IP) > 0 && net.ParseIP(attachment.IP) == nil {
+			return fmt.Errorf("\"%s\" is not an IP address", attachment.IP)
+		}
+		err := IPv6(attachment.IPv6)
+		if err != nil {
+			return err
+		}
+		err = Endpoint(attachment.Endpoint)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
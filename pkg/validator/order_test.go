@@ -7,7 +7,11 @@
 package validator
 
 import (
+	"strconv"
 	"testing"
+	"time"
+
+	"github.com/whiteblock/genesis/pkg/entity"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/whiteblock/definition/command"
@@ -67,3 +71,249 @@ func TestOrderValidator_ValidateContainer_BadImage(t *testing.T) {
 	}
 	assert.Error(t, Container(testContainer))
 }
+
+func TestOrderValidator_EmulationSchedule(t *testing.T) {
+	steps := []entity.EmulationStep{
+		{Offset: command.Duration{Time: command.Time{Duration: 30 * time.Second}}},
+		{Offset: command.Duration{}},
+	}
+	sched := entity.EmulationSchedule{Name: "t", Container: "t", Network: "t", Steps: steps}
+	assert.NoError(t, EmulationSchedule(sched))
+
+	sched.Restore = command.Duration{Time: command.Time{Duration: time.Minute}}
+	assert.NoError(t, EmulationSchedule(sched))
+
+	sched.Restore = command.Duration{Time: command.Time{Duration: 10 * time.Second}}
+	assert.Error(t, EmulationSchedule(sched))
+
+	sched.Restore = command.InfiniteDuration
+	assert.Error(t, EmulationSchedule(sched))
+}
+
+func TestOrderValidator_EmulationSchedule_Missing(t *testing.T) {
+	steps := []entity.EmulationStep{{}}
+	assert.NoError(t, EmulationSchedule(entity.EmulationSchedule{Steps: steps}),
+		"the other fields are checked by the usecase")
+	assert.Equal(t, ErrMissingSteps, EmulationSchedule(entity.EmulationSchedule{
+		Name: "t", Container: "t", Network: "t"}))
+	assert.Error(t, EmulationSchedule(entity.EmulationSchedule{
+		Name: "t", Container: "t", Network: "t",
+		Steps: []entity.EmulationStep{{Offset: command.Duration{Time: command.Time{Duration: -time.Second}}}}}))
+}
+
+func TestOrderValidator_Partition(t *testing.T) {
+	part := entity.Partition{Network: "t", Groups: [][]string{{"a", "b"}, {"c"}}}
+	assert.NoError(t, Partition(part, false))
+	assert.NoError(t, Partition(entity.Partition{Groups: [][]string{{"a"}}}, true))
+
+	assert.Error(t, Partition(entity.Partition{Network: "t", Groups: [][]string{{"a"}, {""}}}, false))
+	assert.Equal(t, ErrMissingGroups, Partition(entity.Partition{Network: "t"}, true))
+	assert.Error(t, Partition(entity.Partition{Network: "t", Groups: [][]string{{"a"}}}, false))
+	assert.Error(t, Partition(entity.Partition{Network: "t", Groups: [][]string{{"a"}, {}}}, false))
+	assert.Error(t, Partition(entity.Partition{Network: "t", Groups: [][]string{{"a"}, {"a"}}}, false))
+}
+
+func TestOrderValidator_UpdateResources(t *testing.T) {
+	assert.NoError(t, UpdateResources(entity.UpdateResources{Name: "t", Cpus: "1.5"}))
+	assert.NoError(t, UpdateResources(entity.UpdateResources{Name: "t", Memory: "2GB"}))
+
+	assert.Equal(t, ErrMissingName, UpdateResources(entity.UpdateResources{Cpus: "1.5"}))
+	assert.Error(t, UpdateResources(entity.UpdateResources{Name: "t"}))
+	assert.Error(t, UpdateResources(entity.UpdateResources{Name: "t", Cpus: "fdsfsfsfe"}))
+	assert.Error(t, UpdateResources(entity.UpdateResources{Name: "t", Memory: "fdwe2"}))
+}
+
+func TestOrderValidator_Stress(t *testing.T) {
+	minute := command.Duration{Time: command.Time{Duration: time.Minute}}
+	assert.NoError(t, Stress(entity.Stress{Container: "t", CPU: 1, Duration: minute}))
+
+	assert.Error(t, Stress(entity.Stress{Container: "t", Duration: minute}))
+	assert.Error(t, Stress(entity.Stress{Container: "t", CPU: 2, IO: -1, Duration: minute}))
+	assert.Error(t, Stress(entity.Stress{Container: "t", CPU: 1}))
+	assert.Error(t, Stress(entity.Stress{Container: "t", CPU: 1, Duration: command.InfiniteDuration}))
+}
+
+func TestOrderValidator_ClockSkew(t *testing.T) {
+	assert.NoError(t, ClockSkew(entity.ClockSkew{}))
+	assert.NoError(t, ClockSkew(entity.ClockSkew{
+		Offset: command.Duration{Time: command.Time{Duration: -time.Minute}},
+		Rate:   0.5,
+	}))
+	assert.Error(t, ClockSkew(entity.ClockSkew{Offset: command.InfiniteDuration}))
+	assert.Error(t, ClockSkew(entity.ClockSkew{Rate: -1}))
+}
+
+func TestOrderValidator_IPv6(t *testing.T) {
+	assert.NoError(t, IPv6(""))
+	assert.NoError(t, IPv6("fd00::2"))
+	assert.Error(t, IPv6("10.1.0.2"))
+	assert.Error(t, IPv6("fd00::zz"))
+}
+
+func TestOrderValidator_Network(t *testing.T) {
+	net := entity.Network{
+		Network: command.Network{Name: "t", Subnet: "10.1.0.0/16", Gateway: "10.1.0.1"},
+		Subnets: []entity.Subnet{{Subnet: "fd00::/64", Gateway: "fd00::1"}},
+	}
+	assert.NoError(t, Network(net))
+	assert.NoError(t, Network(entity.Network{Network: command.Network{Name: "t"}}))
+
+	assert.Equal(t, ErrMissingName, Network(entity.Network{}))
+	assert.Error(t, Network(entity.Network{Network: command.Network{Name: "t", Subnet: "10.1.0.0"}}))
+	assert.Error(t, Network(entity.Network{
+		Network: command.Network{Name: "t"},
+		Subnets: []entity.Subnet{{Subnet: "fd00::/64", Gateway: "10.1.0.1"}},
+	}))
+}
+
+func TestOrderValidator_Network_Drivers(t *testing.T) {
+	var tests = []struct {
+		net   entity.Network
+		valid bool
+	}{
+		{net: entity.Network{Driver: entity.MacvlanDriver, Parent: "eth1", Mode: "vepa"}, valid: true},
+		{net: entity.Network{Driver: entity.IpvlanDriver, Mode: "l3s", Internal: true}, valid: true},
+		{net: entity.Network{Network: command.Network{Global: true}, Encrypted: true, MTU: 1400}, valid: true},
+		{net: entity.Network{Driver: "weave", Options: map[string]string{"foo": "bar"}}, valid: true},
+		{net: entity.Network{MTU: 9000, Internal: true}, valid: true},
+
+		{net: entity.Network{Parent: "eth1"}, valid: false},
+		{net: entity.Network{Mode: "bridge"}, valid: false},
+		{net: entity.Network{Driver: entity.MacvlanDriver, Mode: "l3"}, valid: false},
+		{net: entity.Network{Driver: entity.IpvlanDriver, Mode: "vepa"}, valid: false},
+		{net: entity.Network{Encrypted: true}, valid: false},
+		{net: entity.Network{MTU: -1}, valid: false},
+		{net: entity.Network{MTU: 70000}, valid: false},
+		{net: entity.Network{Driver: entity.MacvlanDriver, MTU: 1400}, valid: false},
+		{net: entity.Network{Driver: entity.OverlayDriver}, valid: false},
+		{net: entity.Network{Network: command.Network{Global: true}, Driver: entity.MacvlanDriver}, valid: false},
+		{net: entity.Network{Options: map[string]string{"parent": "eth1"}}, valid: false},
+	}
+
+	for i, tt := range tests {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			tt.net.Name = "t"
+			if tt.valid {
+				assert.NoError(t, Network(tt.net))
+			} else {
+				assert.Error(t, Network(tt.net))
+			}
+		})
+	}
+}
+
+func TestOrderValidator_Endpoint(t *testing.T) {
+	assert.NoError(t, Endpoint(entity.Endpoint{}))
+	assert.NoError(t, Endpoint(entity.Endpoint{
+		Aliases:    []string{"validator-3.net-a", "v3"},
+		MacAddress: "02:42:ac:11:00:03",
+		Links:      []string{"bootnode", "node-1:peer"},
+	}))
+
+	assert.Error(t, Endpoint(entity.Endpoint{Aliases: []string{"-bad"}}))
+	assert.Error(t, Endpoint(entity.Endpoint{Aliases: []string{"has space"}}))
+	assert.Error(t, Endpoint(entity.Endpoint{MacAddress: "02:42:ac:11:00"}))
+	assert.Error(t, Endpoint(entity.Endpoint{MacAddress: "01:00:5e:00:00:01"}))
+	assert.Error(t, Endpoint(entity.Endpoint{MacAddress: "02:42:ac:11:00:03:00:00"}))
+	assert.Error(t, Endpoint(entity.Endpoint{Links: []string{"a:b:c"}}))
+	assert.Error(t, Endpoint(entity.Endpoint{Links: []string{"a:"}}))
+}
+
+func TestOrderValidator_NetworkAttachments(t *testing.T) {
+	cntr := entity.Container{
+		Container: command.Container{Network: "net-a"},
+		Networks: []entity.NetworkAttachment{
+			{Network: "net-b", IP: "10.1.0.2", IPv6: "fd00::2"},
+			{Network: "net-c", Endpoint: entity.Endpoint{Aliases: []string{"v1"}}},
+		},
+	}
+	assert.NoError(t, NetworkAttachments(cntr))
+	assert.NoError(t, NetworkAttachments(entity.Container{}))
+
+	for _, attachment := range []entity.NetworkAttachment{
+		{},
+		{Network: "net-a"},
+		{Network: "net-b"},
+		{Network: "net-d", IP: "10.1.0"},
+		{Network: "net-d", IPv6: "10.1.0.2"},
+		{Network: "net-d", Endpoint: entity.Endpoint{Aliases: []string{"-v1"}}},
+	} {
+		bad := cntr
+		bad.Networks = append(append([]entity.NetworkAttachment{}, cntr.Networks...), attachment)
+		assert.Error(t, NetworkAttachments(bad), attachment.Network)
+	}
+}
+
+func TestOrderValidator_Swarm(t *testing.T) {
+	hosts := command.SetupSwarm{Hosts: []string{"10.0.0.2", "10.0.0.3", "10.0.0.4"}}
+	assert.NoError(t, Swarm(entity.Swarm{SetupSwarm: hosts}))
+	assert.NoError(t, Swarm(entity.Swarm{SetupSwarm: hosts, Managers: 3,
+		Labels: map[string]map[string]string{"10.0.0.3": {"zone": "east"}}}))
+
+	assert.Error(t, Swarm(entity.Swarm{SetupSwarm: hosts, Managers: 4}))
+	assert.Error(t, Swarm(entity.Swarm{SetupSwarm: hosts, Managers: -1}))
+	assert.Error(t, Swarm(entity.Swarm{SetupSwarm: command.SetupSwarm{Hosts: []string{"a", "a"}}}))
+	assert.Error(t, Swarm(entity.Swarm{SetupSwarm: hosts,
+		Labels: map[string]map[string]string{"10.0.0.5": {"zone": "east"}}}))
+}
+
+func TestOrderValidator_SwarmService(t *testing.T) {
+	svc := entity.SwarmService{
+		Container:   command.Container{Name: "t", Image: "t", Cpus: "0.5", Memory: "512MB"},
+		Replicas:    3,
+		Constraints: []string{"node.labels.zone==east", "node.role!=manager"},
+		UpdatePolicy: entity.UpdatePolicy{Parallelism: 1, FailureAction: "rollback", Order: "start-first",
+			Delay: command.Duration{Time: command.Time{Duration: time.Second}}},
+	}
+	assert.NoError(t, SwarmService(svc))
+
+	bad := svc
+	bad.Name = ""
+	assert.Equal(t, ErrMissingName, SwarmService(bad))
+	bad = svc
+	bad.Image = ""
+	assert.Equal(t, ErrMissingImage, SwarmService(bad))
+	bad = svc
+	bad.Constraints = []string{"node.labels.zone"}
+	assert.Error(t, SwarmService(bad))
+	bad = svc
+	bad.UpdatePolicy.FailureAction = "retry"
+	assert.Error(t, SwarmService(bad))
+	bad = svc
+	bad.UpdatePolicy.Order = "random"
+	assert.Error(t, SwarmService(bad))
+	bad = svc
+	bad.Cpus = "lots"
+	assert.Error(t, SwarmService(bad))
+}
+
+func TestOrderValidator_VolumeResize(t *testing.T) {
+	assert.NoError(t, VolumeResize(entity.VolumeResize{Name: "shared", Add: []string{"10.0.0.5"}}))
+	assert.NoError(t, VolumeResize(entity.VolumeResize{Name: "shared", Add: []string{"10.0.0.5"},
+		Remove: []string{"10.0.0.3"}}))
+
+	assert.Equal(t, ErrMissingName, VolumeResize(entity.VolumeResize{Add: []string{"10.0.0.5"}}))
+	assert.Error(t, VolumeResize(entity.VolumeResize{Name: "shared"}))
+	assert.Error(t, VolumeResize(entity.VolumeResize{Name: "shared", Add: []string{"10.0.0.5"},
+		Remove: []string{"10.0.0.5"}}))
+}
+
+func TestOrderValidator_VolumeBackend(t *testing.T) {
+	for _, backend := range []string{"", entity.GlusterVolumeBackend, entity.NFSVolumeBackend,
+		entity.RsyncVolumeBackend} {
+		assert.NoError(t, VolumeBackend(backend), backend)
+	}
+	assert.Error(t, VolumeBackend("ceph"))
+	assert.Error(t, VolumeResize(entity.VolumeResize{Name: "shared", Backend: "ceph",
+		Add: []string{"10.0.0.5"}}))
+}
+
+func TestOrderValidator_Volume(t *testing.T) {
+	assert.NoError(t, Volume(entity.Volume{Seed: &command.File{ID: "snapshot"}}))
+	assert.Error(t, Volume(entity.Volume{Seed: &command.File{}}))
+	assert.Error(t, Volume(entity.Volume{Backend: "ceph"}))
+
+	assert.NoError(t, VolumeExport(entity.VolumeExport{Name: "data", File: command.File{ID: "export"}}))
+	assert.Equal(t, ErrMissingName, VolumeExport(entity.VolumeExport{File: command.File{ID: "export"}}))
+	assert.Error(t, VolumeExport(entity.VolumeExport{Name: "data"}))
+}
@@ -193,6 +193,7 @@ func dockerTest(clean bool) {
 			file.NewRemoteSources(
 				conf,
 				conf.GetLogger()),
+			repository.NewCredentialStore(conf.Docker.CredentialDir),
 			conf.GetLogger()),
 		conf.GetLogger())
 